@@ -59,6 +59,19 @@ const (
 	// SSRF protection.
 	RequestHeaderName = "X-Vault-Request"
 
+	// HeaderIndex is the header carrying the storage index a response was
+	// served at, and which a request requires the serving node to have
+	// reached.
+	HeaderIndex = "X-Vault-Index"
+
+	// HeaderForward is the header used to request that a standby node
+	// forwards the request to the active node.
+	HeaderForward = "X-Vault-Forward"
+
+	// HeaderInconsistent is the header specifying what a standby node
+	// should do when it has not reached the required storage index in time.
+	HeaderInconsistent = "X-Vault-Inconsistent"
+
 	TLSErrorString = "This error usually means that the server is running with TLS disabled\n" +
 		"but the client is configured to use TLS. Please either enable TLS\n" +
 		"on the server or run the client with -address set to an address\n" +
@@ -1548,6 +1561,45 @@ func (c *Client) WithResponseCallbacks(callbacks ...ResponseCallback) *Client {
 	return &c2
 }
 
+// RecordState returns a response callback that records the storage index
+// reported by the server into state. The recorded value can be passed to
+// RequireState so that subsequent requests observe at least the same state,
+// even when served by a standby node.
+func RecordState(state *string) ResponseCallback {
+	return func(resp *Response) {
+		*state = resp.Header.Get(HeaderIndex)
+	}
+}
+
+// RequireState returns a request callback that requires the serving node to
+// have reached each of the given storage indexes, as recorded by RecordState.
+func RequireState(states ...string) RequestCallback {
+	return func(req *Request) {
+		for _, s := range states {
+			if s != "" {
+				req.Headers.Add(HeaderIndex, s)
+			}
+		}
+	}
+}
+
+// ForwardInconsistent returns a request callback that asks a standby node
+// to forward the request to the active node when it has not reached the
+// storage index required by RequireState, instead of failing it.
+func ForwardInconsistent() RequestCallback {
+	return func(req *Request) {
+		req.Headers.Set(HeaderInconsistent, "forward-active-node")
+	}
+}
+
+// ForwardAlways returns a request callback that asks a standby node to
+// forward the request to the active node rather than serving it locally.
+func ForwardAlways() RequestCallback {
+	return func(req *Request) {
+		req.Headers.Set(HeaderForward, "active-node")
+	}
+}
+
 // withConfiguredTimeout wraps the context with a timeout from the client configuration.
 func (c *Client) withConfiguredTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
 	timeout := c.ClientTimeout()
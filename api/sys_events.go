@@ -0,0 +1,49 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strings"
+)
+
+// SubscribeEvents returns a channel that outputs the JSON encoded events
+// matching the given event type pattern, as streamed by the server. The
+// channel is closed once the context is cancelled or the server ends the
+// stream.
+func (c *Sys) SubscribeEvents(ctx context.Context, pattern string) (chan string, error) {
+	r := c.c.NewRequest(http.MethodGet, "/v1/sys/events/subscribe/"+pattern)
+	r.Headers.Set("Accept", "text/event-stream")
+
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	eventCh := make(chan string, 64)
+
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+
+		defer close(eventCh)
+		defer resp.Body.Close()
+
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+
+			select {
+			case eventCh <- strings.TrimSpace(strings.TrimPrefix(line, "data:")):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return eventCh, nil
+}
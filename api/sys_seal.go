@@ -111,6 +111,19 @@ type SealStatusResponse struct {
 	RecoverySeal bool     `json:"recovery_seal"`
 	StorageType  string   `json:"storage_type,omitempty"`
 	Warnings     []string `json:"warnings,omitempty"`
+
+	// Seals reports the health of each seal when several seals are
+	// configured.
+	Seals []SealBackendStatus `json:"seals,omitempty"`
+}
+
+type SealBackendStatus struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Priority        int    `json:"priority"`
+	Healthy         bool   `json:"healthy"`
+	LastHealthCheck string `json:"last_health_check,omitempty"`
+	UnhealthySince  string `json:"unhealthy_since,omitempty"`
 }
 
 type UnsealOpts struct {
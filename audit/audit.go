@@ -6,6 +6,7 @@ package audit
 import (
 	"context"
 
+	log "github.com/hashicorp/go-hclog"
 	"github.com/openbao/openbao/sdk/v2/helper/salt"
 	"github.com/openbao/openbao/sdk/v2/logical"
 )
@@ -56,6 +57,10 @@ type BackendConfig struct {
 
 	// Config is the opaque user configuration provided when mounting
 	Config map[string]string
+
+	// Logger is used to report problems which do not prevent entries from
+	// being logged. It may be nil.
+	Logger log.Logger
 }
 
 // Factory is the factory function to create an audit backend.
@@ -15,7 +15,9 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	log "github.com/hashicorp/go-hclog"
 	"github.com/openbao/openbao/audit"
 	"github.com/openbao/openbao/sdk/v2/helper/salt"
 	"github.com/openbao/openbao/sdk/v2/logical"
@@ -108,9 +110,24 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 
 	}
 
+	rotate, err := parseRotateConfig(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+	if rotate.enabled() && (path == "stdout" || path == "discard") {
+		return nil, fmt.Errorf("rotation is not supported when writing to %q", path)
+	}
+
+	logger := conf.Logger
+	if logger == nil {
+		logger = log.NewNullLogger()
+	}
+
 	b := &Backend{
 		path:       path,
 		mode:       mode,
+		rotate:     rotate,
+		logger:     logger,
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		salt:       new(atomic.Value),
@@ -155,9 +172,9 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 
 // Backend is the audit backend for the file-based audit store.
 //
-// NOTE: This audit backend is currently very simple: it appends to a file.
-// It doesn't do anything more at the moment to assist with rotation
-// or reset the write cursor, this should be done in the future.
+// It appends to a file, which is optionally rotated once it reaches a given
+// size or age. Without rotation, the file can be rotated externally and
+// reopened by sending SIGHUP.
 type Backend struct {
 	path string
 
@@ -168,6 +185,19 @@ type Backend struct {
 	f        *os.File
 	mode     os.FileMode
 
+	// rotate is the rotation configuration; bytesWritten and opened track
+	// the size and age of the current file and are protected by fileLock.
+	rotate       rotateConfig
+	bytesWritten int64
+	opened       time.Time
+
+	// cleanupLock serializes the compression and pruning of rotated files,
+	// which run in the background; cleanupWg tracks those still running.
+	cleanupLock sync.Mutex
+	cleanupWg   sync.WaitGroup
+
+	logger log.Logger
+
 	saltMutex  sync.RWMutex
 	salt       *atomic.Value
 	saltConfig *salt.Config
@@ -240,7 +270,16 @@ func (b *Backend) log(ctx context.Context, buf *bytes.Buffer, writer io.Writer)
 		writer = b.f
 	}
 
-	if _, err := reader.WriteTo(writer); err == nil {
+	if b.shouldRotate(buf.Len()) {
+		if err := b.rotateFile(); err != nil {
+			b.fileLock.Unlock()
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+		writer = b.f
+	}
+
+	if n, err := reader.WriteTo(writer); err == nil {
+		b.bytesWritten += n
 		b.fileLock.Unlock()
 		return nil
 	} else if b.path == "stdout" {
@@ -260,7 +299,8 @@ func (b *Backend) log(ctx context.Context, buf *bytes.Buffer, writer io.Writer)
 	}
 
 	reader.Seek(0, io.SeekStart)
-	_, err := reader.WriteTo(writer)
+	n, err := reader.WriteTo(b.f)
+	b.bytesWritten += n
 	b.fileLock.Unlock()
 	return err
 }
@@ -316,6 +356,14 @@ func (b *Backend) open() error {
 		return err
 	}
 
+	// Account for entries written before the file was reopened, so that
+	// the size limit applies across restarts
+	b.bytesWritten = 0
+	if info, err := b.f.Stat(); err == nil {
+		b.bytesWritten = info.Size()
+	}
+	b.opened = now()
+
 	// Change the file mode in case the log file already existed. We special
 	// case /dev/null since we can't chmod it and bypass if the mode is zero
 	switch b.path {
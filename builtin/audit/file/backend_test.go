@@ -4,10 +4,14 @@
 package file
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -183,3 +187,292 @@ func BenchmarkAuditFile_request(b *testing.B) {
 		}
 	})
 }
+
+func testRotateBackend(t *testing.T, config map[string]string) (*Backend, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	config["file_path"] = filepath.Join(dir, "audit.log")
+
+	b, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config:     config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b.(*Backend), dir
+}
+
+func testLogRequests(t *testing.T, b *Backend, count int) {
+	t.Helper()
+
+	ctx := namespace.RootContext(nil)
+	for i := 0; i < count; i++ {
+		in := &logical.LogInput{
+			Request: &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "secret/" + strconv.Itoa(i),
+			},
+		}
+		if err := b.LogRequest(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Wait for rotated files to be compressed and pruned
+	b.cleanupWg.Wait()
+}
+
+func TestAuditFile_rotateBytes(t *testing.T) {
+	b, dir := testRotateBackend(t, map[string]string{
+		"rotate_bytes": "1024",
+	})
+
+	testLogRequests(t, b, 50)
+
+	rotated, err := filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) == 0 {
+		t.Fatal("expected the file to have been rotated")
+	}
+
+	// Every file must stay under the limit, and contain only complete
+	// entries
+	entries := 0
+	for _, path := range append(rotated, filepath.Join(dir, "audit.log")) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 1024 {
+			t.Fatalf("file %q is %d bytes, exceeding the rotation size", path, len(data))
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("file %q contains a partial entry: %v", path, err)
+			}
+			entries++
+		}
+	}
+	if entries != 50 {
+		t.Fatalf("expected 50 entries, got %d", entries)
+	}
+}
+
+func TestAuditFile_rotateDuration(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	b, dir := testRotateBackend(t, map[string]string{
+		"rotate_duration": "1h",
+	})
+
+	testLogRequests(t, b, 2)
+	current = current.Add(30 * time.Minute)
+	testLogRequests(t, b, 2)
+
+	rotated, err := filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 0 {
+		t.Fatalf("expected no rotation, got %v", rotated)
+	}
+
+	current = current.Add(31 * time.Minute)
+	testLogRequests(t, b, 1)
+
+	rotated, err = filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("expected one rotated file, got %v", rotated)
+	}
+}
+
+func TestAuditFile_rotateCompressAndPrune(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	b, dir := testRotateBackend(t, map[string]string{
+		"rotate_duration":  "1m",
+		"rotate_max_files": "2",
+		"rotate_compress":  "true",
+	})
+
+	for i := 0; i < 5; i++ {
+		testLogRequests(t, b, 1)
+		current = current.Add(time.Minute)
+	}
+
+	uncompressed, err := filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uncompressed) != 0 {
+		t.Fatalf("expected rotated files to be compressed, got %v", uncompressed)
+	}
+
+	compressed, err := filepath.Glob(filepath.Join(dir, "audit-*.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) != 2 {
+		t.Fatalf("expected two rotated files to be kept, got %v", compressed)
+	}
+
+	f, err := os.Open(compressed[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "secret/0") {
+		t.Fatalf("unexpected contents of rotated file: %s", data)
+	}
+}
+
+func TestAuditFile_rotatePruneOtherDevices(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	b, dir := testRotateBackend(t, map[string]string{
+		"rotate_duration":  "1m",
+		"rotate_max_files": "1",
+	})
+
+	// Files of another device in the same directory, which the glob
+	// audit-*.log would match
+	others := []string{
+		filepath.Join(dir, "audit-pki.log"),
+		filepath.Join(dir, "audit-pki-1700000000000000000.log"),
+	}
+	for _, path := range others {
+		if err := os.WriteFile(path, []byte("other"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		testLogRequests(t, b, 1)
+		current = current.Add(time.Minute)
+	}
+
+	for _, path := range others {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("file of another device was removed: %v", err)
+		}
+	}
+
+	rotated, err := filepath.Glob(filepath.Join(dir, "audit-[0-9]*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("expected one rotated file to be kept, got %v", rotated)
+	}
+}
+
+func TestAuditFile_rotateCompressFailure(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	b, dir := testRotateBackend(t, map[string]string{
+		"rotate_duration": "1m",
+		"rotate_compress": "true",
+	})
+
+	testLogRequests(t, b, 1)
+	current = current.Add(time.Minute)
+
+	// Block the temporary file compression writes to
+	rotatedPath := filepath.Join(dir, "audit-"+strconv.FormatInt(current.UnixNano(), 10)+".log")
+	if err := os.Mkdir(rotatedPath+".gz.tmp", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	// The entry is still written, so it is not reported as a failure
+	testLogRequests(t, b, 1)
+
+	if _, err := os.Stat(rotatedPath); err != nil {
+		t.Fatalf("expected the uncompressed rotated file to be kept: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "secret/0") {
+		t.Fatalf("expected the entry to be written after rotation, got %s", data)
+	}
+}
+
+func TestAuditFile_rotateCleanupInBackground(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	b, dir := testRotateBackend(t, map[string]string{
+		"rotate_duration": "1m",
+		"rotate_compress": "true",
+	})
+
+	testLogRequests(t, b, 1)
+	current = current.Add(time.Minute)
+
+	// Entries are written while a cleanup is still running
+	b.cleanupLock.Lock()
+	ctx := namespace.RootContext(nil)
+	in := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "secret/1",
+		},
+	}
+	if err := b.LogRequest(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+
+	rotatedPath := filepath.Join(dir, "audit-"+strconv.FormatInt(current.UnixNano(), 10)+".log")
+	if _, err := os.Stat(rotatedPath); err != nil {
+		t.Fatalf("expected the rotated file not to be compressed yet: %v", err)
+	}
+
+	b.cleanupLock.Unlock()
+	b.cleanupWg.Wait()
+
+	if _, err := os.Stat(rotatedPath + ".gz"); err != nil {
+		t.Fatalf("expected the rotated file to be compressed: %v", err)
+	}
+}
+
+func TestAuditFile_rotateStdout(t *testing.T) {
+	_, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config: map[string]string{
+			"file_path":    "stdout",
+			"rotate_bytes": "1024",
+		},
+	})
+	if err == nil {
+		t.Fatal("expected rotation of stdout to be rejected")
+	}
+}
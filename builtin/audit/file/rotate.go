@@ -0,0 +1,270 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package file
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/openbao/openbao/sdk/v2/helper/parseutil"
+)
+
+var now = time.Now
+
+// rotateConfig controls the rotation of the audit log file. Rotation is
+// disabled when both bytes and duration are zero.
+type rotateConfig struct {
+	// bytes is the size after which the file is rotated
+	bytes int64
+
+	// duration is the interval after which the file is rotated
+	duration time.Duration
+
+	// maxFiles is the number of rotated files to keep; zero keeps all of
+	// them
+	maxFiles int
+
+	// compress enables gzip compression of rotated files
+	compress bool
+}
+
+func (r rotateConfig) enabled() bool {
+	return r.bytes > 0 || r.duration > 0
+}
+
+func parseRotateConfig(config map[string]string) (rotateConfig, error) {
+	var r rotateConfig
+
+	if raw, ok := config["rotate_bytes"]; ok {
+		bytes, err := parseutil.ParseCapacityString(raw)
+		if err != nil {
+			return r, fmt.Errorf("error parsing rotate_bytes: %w", err)
+		}
+		r.bytes = int64(bytes)
+	}
+
+	if raw, ok := config["rotate_duration"]; ok {
+		duration, err := parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return r, fmt.Errorf("error parsing rotate_duration: %w", err)
+		}
+		if duration < 0 {
+			return r, errors.New("rotate_duration must not be negative")
+		}
+		r.duration = duration
+	}
+
+	if raw, ok := config["rotate_max_files"]; ok {
+		maxFiles, err := strconv.Atoi(raw)
+		if err != nil {
+			return r, fmt.Errorf("error parsing rotate_max_files: %w", err)
+		}
+		if maxFiles < 0 {
+			return r, errors.New("rotate_max_files must not be negative")
+		}
+		r.maxFiles = maxFiles
+	}
+
+	if raw, ok := config["rotate_compress"]; ok {
+		compress, err := strconv.ParseBool(raw)
+		if err != nil {
+			return r, fmt.Errorf("error parsing rotate_compress: %w", err)
+		}
+		r.compress = compress
+	}
+
+	return r, nil
+}
+
+// shouldRotate returns whether the file must be rotated before writing an
+// entry of the given size. An entry is never split across files, and a
+// file always receives at least one entry even if it exceeds the size
+// limit on its own.
+//
+// The file lock must be held before calling this
+func (b *Backend) shouldRotate(size int) bool {
+	if !b.rotate.enabled() || b.f == nil || b.bytesWritten == 0 {
+		return false
+	}
+	if b.rotate.bytes > 0 && b.bytesWritten+int64(size) > b.rotate.bytes {
+		return true
+	}
+	if b.rotate.duration > 0 && now().Sub(b.opened) >= b.rotate.duration {
+		return true
+	}
+	return false
+}
+
+// rotateFile closes the current file, moves it aside and opens a new file
+// in its place. Compressing and pruning rotated files may take a while for
+// large files, so it happens in the background rather than while entries
+// are waiting on the file lock.
+//
+// The file lock must be held before calling this
+func (b *Backend) rotateFile() error {
+	if b.f != nil {
+		err := b.f.Close()
+		b.f = nil
+		if err != nil {
+			return err
+		}
+	}
+
+	// Never overwrite a previously rotated file, even if the clock did not
+	// advance since the last rotation
+	ts := now().UnixNano()
+	rotatedPath := fmt.Sprintf(b.rotatedNamePattern(), strconv.FormatInt(ts, 10))
+	for fileExists(rotatedPath) || fileExists(rotatedPath+".gz") {
+		ts++
+		rotatedPath = fmt.Sprintf(b.rotatedNamePattern(), strconv.FormatInt(ts, 10))
+	}
+	if err := os.Rename(b.path, rotatedPath); err != nil {
+		return err
+	}
+
+	if err := b.open(); err != nil {
+		return err
+	}
+
+	b.cleanupWg.Add(1)
+	go func() {
+		defer b.cleanupWg.Done()
+		if err := b.cleanupRotatedFile(rotatedPath); err != nil {
+			b.logger.Warn("failed to clean up rotated audit logs", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// cleanupRotatedFile compresses the given rotated file and prunes rotated
+// files as configured. Cleanups of the same device are serialized, so that
+// a file is never pruned while it is being compressed.
+func (b *Backend) cleanupRotatedFile(rotatedPath string) error {
+	b.cleanupLock.Lock()
+	defer b.cleanupLock.Unlock()
+
+	var result error
+	// The file may already have been pruned by an earlier cleanup if
+	// several rotations happened in quick succession.
+	if b.rotate.compress && fileExists(rotatedPath) {
+		if err := compressFile(rotatedPath, b.mode); err != nil {
+			result = multierror.Append(result, fmt.Errorf("error compressing %q: %w", rotatedPath, err))
+		}
+	}
+	if err := b.pruneRotatedFiles(); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	return result
+}
+
+// rotatedNamePattern returns the pattern of rotated file names, where the
+// verb is replaced by the time of rotation. The extension of the file is
+// kept so that audit.log is rotated to audit-<timestamp>.log.
+func (b *Backend) rotatedNamePattern() string {
+	ext := filepath.Ext(b.path)
+	return strings.ReplaceAll(strings.TrimSuffix(b.path, ext), "%", "%%") + "-%s" + ext
+}
+
+// rotatedNameRegexp matches the base names of the files rotated from the
+// current file, compressed or not. The timestamp is anchored to the exact
+// format written by rotateFile, so that files of other audit devices in the
+// same directory, such as audit-pki.log next to audit.log, never match.
+func (b *Backend) rotatedNameRegexp() *regexp.Regexp {
+	ext := filepath.Ext(b.path)
+	base := filepath.Base(strings.TrimSuffix(b.path, ext))
+	return regexp.MustCompile("^" + regexp.QuoteMeta(base) + "-[0-9]{19}" + regexp.QuoteMeta(ext) + `(\.gz)?$`)
+}
+
+// pruneRotatedFiles removes the oldest rotated files beyond the number to
+// keep.
+//
+// The cleanup lock must be held before calling this
+func (b *Backend) pruneRotatedFiles() error {
+	if b.rotate.maxFiles == 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(b.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	re := b.rotatedNameRegexp()
+	var matches []string
+	for _, entry := range entries {
+		if !entry.IsDir() && re.MatchString(entry.Name()) {
+			matches = append(matches, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	if len(matches) <= b.rotate.maxFiles {
+		return nil
+	}
+
+	// Timestamps have the same number of digits, so sorting by name sorts
+	// from oldest to newest.
+	sort.Strings(matches)
+
+	var result error
+	for _, file := range matches[:len(matches)-b.rotate.maxFiles] {
+		if err := os.Remove(file); err != nil {
+			result = multierror.Append(result, fmt.Errorf("error removing %q: %w", file, err))
+		}
+	}
+	return result
+}
+
+// compressFile replaces the file at path by a gzip compressed copy with a
+// .gz extension.
+func compressFile(path string, mode os.FileMode) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmpPath := path + ".gz.tmp"
+	out, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path+".gz"); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -6,6 +6,7 @@ package userpass
 import (
 	"context"
 	"errors"
+	"net/http"
 	"strings"
 	"time"
 
@@ -49,6 +50,12 @@ func pathUsersList(b *backend) *framework.Path {
 }
 
 func pathUsers(b *backend) *framework.Path {
+	responseNoContent := map[int][]framework.Response{
+		http.StatusNoContent: {{
+			Description: "No Content",
+		}},
+	}
+
 	p := &framework.Path{
 		Pattern: "users/" + framework.GenericNameRegex("username"),
 
@@ -100,16 +107,19 @@ func pathUsers(b *backend) *framework.Path {
 
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.DeleteOperation: &framework.PathOperation{
-				Callback: b.pathUserDelete,
+				Callback:  b.pathUserDelete,
+				Responses: responseNoContent,
 			},
 			logical.ReadOperation: &framework.PathOperation{
 				Callback: b.pathUserRead,
 			},
 			logical.UpdateOperation: &framework.PathOperation{
-				Callback: b.pathUserWrite,
+				Callback:  b.pathUserWrite,
+				Responses: responseNoContent,
 			},
 			logical.CreateOperation: &framework.PathOperation{
-				Callback: b.pathUserWrite,
+				Callback:  b.pathUserWrite,
+				Responses: responseNoContent,
 			},
 		},
 
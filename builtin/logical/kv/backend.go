@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/golang/protobuf/proto"
@@ -33,6 +35,16 @@ const (
 	defaultMaxVersions uint32 = 10
 )
 
+// Event types published when secrets change.
+const (
+	eventTypeDataWrite      logical.EventType = "kv-v2/data-write"
+	eventTypeDataPatch      logical.EventType = "kv-v2/data-patch"
+	eventTypeDataDelete     logical.EventType = "kv-v2/data-delete"
+	eventTypeDataUndelete   logical.EventType = "kv-v2/data-undelete"
+	eventTypeDataDestroy    logical.EventType = "kv-v2/data-destroy"
+	eventTypeMetadataDelete logical.EventType = "kv-v2/metadata-delete"
+)
+
 // versionedKVBackend implements logical.Backend
 type versionedKVBackend struct {
 	*framework.Backend
@@ -40,6 +52,11 @@ type versionedKVBackend struct {
 	// keyEncryptedWrapper is a cached version of the EncryptedKeyStorageWrapper
 	keyEncryptedWrapper *keysutil.EncryptedKeyStorageWrapper
 
+	// indexEncryptedWrapper is a cached version of the
+	// EncryptedKeyStorageWrapper used for the index of secrets by custom
+	// metadata
+	indexEncryptedWrapper *keysutil.EncryptedKeyStorageWrapper
+
 	// salt is the cached version of the salt used to create paths for version
 	// data storage paths.
 	salt *salt.Salt
@@ -68,6 +85,15 @@ type versionedKVBackend struct {
 	// upgradeCancelFunc is used to be able to shut down the upgrade checking
 	// goroutine from cleanup
 	upgradeCancelFunc context.CancelFunc
+
+	// schemasCache holds the compiled data schemas by key prefix
+	schemasCache map[string]*dataSchema
+	schemasLock  sync.RWMutex
+
+	// indexing is an atomic value denoting if the backend is in the process
+	// of indexing the secrets written before the index of secrets by custom
+	// metadata was introduced.
+	indexing *uint32
 }
 
 // ReportedVersion is used to report a specific version to Vault.
@@ -99,6 +125,7 @@ func VersionedKVFactory(ctx context.Context, conf *logical.BackendConfig) (logic
 
 	b := &versionedKVBackend{
 		upgrading:         new(uint32),
+		indexing:          new(uint32),
 		globalConfigLock:  new(sync.RWMutex),
 		upgradeCancelFunc: upgradeCancelFunc,
 	}
@@ -135,6 +162,15 @@ func VersionedKVFactory(ctx context.Context, conf *logical.BackendConfig) (logic
 				pathDetailedMetadata(b),
 				pathDestroy(b),
 				pathSubkeys(b),
+				pathBatch(b),
+				pathSchemaList(b),
+				pathSchema(b),
+				pathSchemaCheck(b),
+				pathCopy(b),
+				pathMove(b),
+				pathSearch(b),
+				pathExport(b),
+				pathImport(b),
 			},
 			pathsDelete(b),
 
@@ -161,6 +197,10 @@ func VersionedKVFactory(ctx context.Context, conf *logical.BackendConfig) (logic
 		}
 	}
 
+	if err := b.buildSearchIndex(upgradeCtx, conf.StorageView); err != nil {
+		return nil, err
+	}
+
 	return b, nil
 }
 
@@ -228,11 +268,16 @@ func (b *versionedKVBackend) Invalidate(ctx context.Context, key string) {
 	case path.Join(b.storagePrefix, "policy/metadata"):
 		b.l.Lock()
 		b.keyEncryptedWrapper = nil
+		b.indexEncryptedWrapper = nil
 		b.l.Unlock()
 	case path.Join(b.storagePrefix, configPath):
 		b.globalConfigLock.Lock()
 		b.globalConfig = nil
 		b.globalConfigLock.Unlock()
+	case path.Join(b.storagePrefix, schemasPath):
+		b.schemasLock.Lock()
+		b.schemasCache = nil
+		b.schemasLock.Unlock()
 	}
 }
 
@@ -407,8 +452,10 @@ func (b *versionedKVBackend) getKeyMetadata(ctx context.Context, s logical.Stora
 	return meta, nil
 }
 
-// writeKeyMetadata writes a metadata object to storage.
-func (b *versionedKVBackend) writeKeyMetadata(ctx context.Context, s logical.Storage, meta *KeyMetadata) error {
+// writeKeyMetadata writes a metadata object to storage, updating the index of
+// secrets by custom metadata. previous is the custom metadata of the key as
+// currently stored, which is nil for new keys.
+func (b *versionedKVBackend) writeKeyMetadata(ctx context.Context, s logical.Storage, meta *KeyMetadata, previous map[string]string) error {
 	wrapper, err := b.getKeyEncryptor(ctx, s)
 	if err != nil {
 		return err
@@ -429,7 +476,24 @@ func (b *versionedKVBackend) writeKeyMetadata(ctx context.Context, s logical.Sto
 		return err
 	}
 
-	return nil
+	return b.updateSearchIndex(ctx, s, meta.Key, previous, meta.CustomMetadata)
+}
+
+// deleteKeyMetadata deletes a metadata object from storage, along with its
+// entries in the index of secrets by custom metadata.
+func (b *versionedKVBackend) deleteKeyMetadata(ctx context.Context, s logical.Storage, meta *KeyMetadata) error {
+	wrapper, err := b.getKeyEncryptor(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	es := wrapper.Wrap(s)
+
+	if err := es.Delete(ctx, meta.Key); err != nil {
+		return err
+	}
+
+	return b.updateSearchIndex(ctx, s, meta.Key, meta.CustomMetadata, nil)
 }
 
 func ptypesTimestampToString(t *timestamp.Timestamp) string {
@@ -440,6 +504,26 @@ func ptypesTimestampToString(t *timestamp.Timestamp) string {
 	return ptypes.TimestampString(t)
 }
 
+// validateKeyPath returns an error unless the given path is a valid key,
+// with no empty, "." or ".." segment. Such paths would otherwise change
+// meaning once joined to a prefix, and escape it.
+func validateKeyPath(key string) error {
+	if key == "" {
+		return errors.New("path must not be empty")
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		switch segment {
+		case "":
+			return fmt.Errorf("path %q must not contain empty segments", key)
+		case ".", "..":
+			return fmt.Errorf("path %q must not contain %q segments", key, segment)
+		}
+	}
+
+	return nil
+}
+
 var backendHelp string = `
 This backend provides a versioned key-value store. The kv backend reads and
 writes arbitrary secrets to the storage backend. The secrets are
@@ -478,4 +562,46 @@ you may or may not be able to access certain paths.
 
     ^subkeys/.*$
         Read the subkeys within the data from the KV store without their associated values
+
+    ^batch$
+        Atomically write, patch and delete several secrets in the KV store.
+
+    ^schema/.*$
+        Configures the JSON Schema which data under a key prefix must satisfy.
+
+    ^schema-check$
+        Checks data against the JSON Schemas of a key without writing it.
+
+    ^copy$
+        Copies secrets along with their metadata and versions.
+
+    ^move$
+        Moves secrets along with their metadata and versions.
+
+    ^search$
+        Searches secrets by their custom metadata and timestamps.
+
+    ^export$
+        Exports secrets along with their metadata and versions to an encrypted archive.
+
+    ^import$
+        Imports secrets from an archive produced by export.
 `
+
+// sendKeyEvent publishes an event about a change to the given key. Data
+// events are published on the key's data path so that only subscribers
+// allowed to read the secret receive them.
+func (b *versionedKVBackend) sendKeyEvent(ctx context.Context, eventType logical.EventType, prefix, key string, versions ...int) {
+	metadata := map[string]string{
+		"path": key,
+	}
+	if len(versions) > 0 {
+		strVersions := make([]string, 0, len(versions))
+		for _, v := range versions {
+			strVersions = append(strVersions, strconv.Itoa(v))
+		}
+		metadata["versions"] = strings.Join(strVersions, ",")
+	}
+
+	b.SendEvent(ctx, eventType, prefix+key, metadata)
+}
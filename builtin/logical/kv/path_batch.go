@@ -0,0 +1,396 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	batchOperationPut    = "put"
+	batchOperationPatch  = "patch"
+	batchOperationDelete = "delete"
+)
+
+// pathBatch returns the path configuration for applying several write, patch
+// and delete operations atomically.
+func pathBatch(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "batch$",
+		Fields: map[string]*framework.FieldSchema{
+			"operations": {
+				Type: framework.TypeSlice,
+				Description: `List of operations to apply, in order. Each operation is an object
+with an "operation" of "put", "patch" or "delete", the "path" of the secret
+and, except for deletes, the "data" to write or patch in. Puts and patches
+take an "options" object supporting "cas" like the data endpoint.`,
+				Required: true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathBatchWrite()),
+			},
+		},
+
+		HelpSynopsis:    batchHelpSyn,
+		HelpDescription: batchHelpDesc,
+	}
+}
+
+// batchOperation is a single operation of a batch request, along with the
+// data endpoint fields it was given.
+type batchOperation struct {
+	operation string
+	key       string
+	data      *framework.FieldData
+	meta      *KeyMetadata
+}
+
+// parseBatchOperations validates the raw operations of a batch request.
+func parseBatchOperations(b *versionedKVBackend, raw []interface{}) ([]*batchOperation, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("no operations provided")
+	}
+
+	fields := pathData(b).Fields
+	ops := make([]*batchOperation, 0, len(raw))
+	for i, opRaw := range raw {
+		opMap, ok := opRaw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operation %d: expected an object", i)
+		}
+
+		op := &batchOperation{}
+		op.operation, _ = opMap["operation"].(string)
+		op.key, _ = opMap["path"].(string)
+		if op.key == "" {
+			return nil, fmt.Errorf("operation %d: missing path", i)
+		}
+
+		fieldRaw := make(map[string]interface{}, len(opMap))
+		for k, v := range opMap {
+			switch k {
+			case "operation":
+			case "path", "data", "options":
+				fieldRaw[k] = v
+			default:
+				return nil, fmt.Errorf("operation %d: unknown field %q", i, k)
+			}
+		}
+		op.data = &framework.FieldData{
+			Raw:    fieldRaw,
+			Schema: fields,
+		}
+		if err := op.data.Validate(); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+
+		switch op.operation {
+		case batchOperationPut, batchOperationPatch:
+			if _, ok := op.data.GetOk("data"); !ok {
+				return nil, fmt.Errorf("operation %d: no data provided", i)
+			}
+		case batchOperationDelete:
+			if _, ok := fieldRaw["data"]; ok {
+				return nil, fmt.Errorf("operation %d: data is not supported for deletes", i)
+			}
+		default:
+			return nil, fmt.Errorf("operation %d: unknown operation %q", i, op.operation)
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// pathBatchWrite applies all operations of a batch within a single storage
+// transaction. Each operation is authorized against the client's policies as
+// if it had been sent to the data endpoint; if any of them is denied or fails,
+// none of them is applied.
+func (b *versionedKVBackend) pathBatchWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		ops, err := parseBatchOperations(b, data.Get("operations").([]interface{}))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		txnStorage, ok := req.Storage.(logical.TransactionalStorage)
+		if !ok {
+			return logical.ErrorResponse("batch operations require a storage backend supporting transactions"), logical.ErrInvalidRequest
+		}
+
+		sysView, ok := b.System().(logical.ExtendedSystemView)
+		if !ok {
+			return nil, errors.New("batch operations are not supported by this plugin environment")
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make([]string, 0, len(ops))
+		for _, op := range ops {
+			keys = append(keys, op.key)
+		}
+		for _, lock := range locksutil.LocksForKeys(b.locks, keys) {
+			lock.Lock()
+			defer lock.Unlock()
+		}
+
+		txn, err := txnStorage.BeginTx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer txn.Rollback(ctx)
+
+		resp := &logical.Response{}
+		results := make([]interface{}, 0, len(ops))
+		for i, op := range ops {
+			meta, err := b.getKeyMetadata(ctx, txn, op.key)
+			if err != nil {
+				return nil, err
+			}
+
+			var aclOp logical.Operation
+			switch op.operation {
+			case batchOperationPut:
+				aclOp = logical.UpdateOperation
+				if meta == nil {
+					aclOp = logical.CreateOperation
+				}
+			case batchOperationPatch:
+				aclOp = logical.PatchOperation
+			case batchOperationDelete:
+				aclOp = logical.DeleteOperation
+			}
+
+			allowed, err := sysView.CheckSubRequest(ctx, aclOp, "data/"+op.key, op.data.Raw)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return logical.ErrorResponse("operation %d: permission denied on %q", i, op.key), logical.ErrPermissionDenied
+			}
+
+			result, warning, err := b.applyBatchOperation(ctx, txn, config, op, meta)
+			if err != nil {
+				var userErr errutil.UserError
+				if errors.As(err, &userErr) {
+					return logical.ErrorResponse("operation %d: %s", i, userErr.Err), logical.ErrInvalidRequest
+				}
+				return nil, err
+			}
+			if warning != "" {
+				// A failed attempt to clean up old versions will be retried on
+				// next write attempt, prefer a warning over an error resp
+				resp.AddWarning(warning)
+			}
+			results = append(results, result)
+		}
+
+		if err := txn.Commit(ctx); err != nil {
+			return nil, err
+		}
+
+		for _, op := range ops {
+			if op.meta == nil {
+				continue
+			}
+			switch op.operation {
+			case batchOperationPut:
+				b.sendKeyEvent(ctx, eventTypeDataWrite, "data/", op.key, int(op.meta.CurrentVersion))
+			case batchOperationPatch:
+				b.sendKeyEvent(ctx, eventTypeDataPatch, "data/", op.key, int(op.meta.CurrentVersion))
+			case batchOperationDelete:
+				b.sendKeyEvent(ctx, eventTypeDataDelete, "data/", op.key, int(op.meta.CurrentVersion))
+			}
+		}
+
+		resp.Data = map[string]interface{}{
+			"responses": results,
+		}
+		return resp, nil
+	}
+}
+
+// applyBatchOperation applies a single operation of a batch to the given
+// transaction. Errors which are the client's fault are returned as
+// errutil.UserError. On success, op.meta is set to the key metadata if the
+// operation changed anything.
+func (b *versionedKVBackend) applyBatchOperation(ctx context.Context, s logical.Storage, config *Configuration, op *batchOperation, meta *KeyMetadata) (map[string]interface{}, string, error) {
+	result := map[string]interface{}{
+		"operation": op.operation,
+		"path":      op.key,
+	}
+
+	var marshaledData []byte
+	switch op.operation {
+	case batchOperationPut:
+		if meta == nil {
+			meta = &KeyMetadata{
+				Key:      op.key,
+				Versions: map[uint64]*VersionMetadata{},
+			}
+		}
+		if err := validateNotReference(meta); err != nil {
+			return nil, "", errutil.UserError{Err: err.Error()}
+		}
+		if err := validateCheckAndSetOption(op.data, config, meta); err != nil {
+			return nil, "", errutil.UserError{Err: err.Error()}
+		}
+
+		var err error
+		marshaledData, err = json.Marshal(op.data.Get("data").(map[string]interface{}))
+		if err != nil {
+			return nil, "", err
+		}
+
+	case batchOperationPatch:
+		if meta == nil {
+			return nil, "", errutil.UserError{Err: "cannot patch a secret which does not exist"}
+		}
+		if err := validateNotReference(meta); err != nil {
+			return nil, "", errutil.UserError{Err: err.Error()}
+		}
+		if err := validateCheckAndSetOption(op.data, config, meta); err != nil {
+			return nil, "", errutil.UserError{Err: err.Error()}
+		}
+
+		versionData, err := b.currentVersionData(ctx, s, meta)
+		if err != nil {
+			return nil, "", err
+		}
+		if versionData == nil {
+			return nil, "", errutil.UserError{Err: "cannot patch a secret whose current version is deleted or destroyed"}
+		}
+
+		marshaledData, err = framework.HandlePatchOperation(op.data, versionData, dataPatchPreprocessor())
+		if err != nil {
+			return nil, "", err
+		}
+
+	case batchOperationDelete:
+		// Like the data endpoint, deleting a secret whose current version is
+		// already gone is a no-op.
+		if meta == nil {
+			return result, "", nil
+		}
+		lv := meta.Versions[meta.CurrentVersion]
+		if lv == nil || lv.Destroyed {
+			return result, "", nil
+		}
+		if lv.DeletionTime != nil {
+			deletionTime, err := ptypes.Timestamp(lv.DeletionTime)
+			if err != nil {
+				return nil, "", err
+			}
+			if deletionTime.Before(time.Now()) {
+				return result, "", nil
+			}
+		}
+
+		lv.DeletionTime = ptypes.TimestampNow()
+		if err := b.writeKeyMetadata(ctx, s, meta, meta.CustomMetadata); err != nil {
+			return nil, "", err
+		}
+
+		op.meta = meta
+		result["version"] = meta.CurrentVersion
+		result["deletion_time"] = ptypesTimestampToString(lv.DeletionTime)
+		return result, "", nil
+	}
+
+	violations, _, err := b.schemaViolations(ctx, s, op.key, marshaledData)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(violations) > 0 {
+		return nil, "", errutil.UserError{Err: schemaViolationsError(violations)}
+	}
+
+	vm, warning, err := b.writeVersion(ctx, s, config, meta, meta.CustomMetadata, marshaledData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	op.meta = meta
+	result["version"] = meta.CurrentVersion
+	result["created_time"] = ptypesTimestampToString(vm.CreatedTime)
+	result["deletion_time"] = ptypesTimestampToString(vm.DeletionTime)
+	result["destroyed"] = vm.Destroyed
+	result["custom_metadata"] = meta.CustomMetadata
+	return result, warning, nil
+}
+
+// currentVersionData returns the data of the current version of a key, or nil
+// if that version is deleted or destroyed.
+func (b *versionedKVBackend) currentVersionData(ctx context.Context, s logical.Storage, meta *KeyMetadata) (map[string]interface{}, error) {
+	vm := meta.Versions[meta.CurrentVersion]
+	if vm == nil || vm.Destroyed {
+		return nil, nil
+	}
+	if vm.DeletionTime != nil {
+		deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+		if err != nil {
+			return nil, err
+		}
+		if deletionTime.Before(time.Now()) {
+			return nil, nil
+		}
+	}
+
+	versionKey, err := b.getVersionKey(ctx, meta.Key, meta.CurrentVersion, s)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := s.Get(ctx, versionKey)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, errors.New("could not find version data")
+	}
+
+	version := &Version{}
+	if err := proto.Unmarshal(raw.Value, version); err != nil {
+		return nil, err
+	}
+
+	var versionData map[string]interface{}
+	if err := json.Unmarshal(version.Data, &versionData); err != nil {
+		return nil, err
+	}
+
+	return versionData, nil
+}
+
+const (
+	batchHelpSyn  = `Atomically write, patch and delete several secrets in the Key-Value Store.`
+	batchHelpDesc = `
+This path takes a list of operations, each of which is a put, patch or delete
+of the secret at a given path. The operations are applied in order within a
+single storage transaction: either all of them succeed, or none of them is
+applied.
+
+Each operation requires the same policy as the equivalent request to the data
+endpoint, i.e. "create" or "update", "patch" or "delete" on the "data/" path of
+the secret. Put and patch operations accept the same "data" and "options"
+objects as the data endpoint, so a check-and-set version can be given for each
+secret.
+
+This endpoint requires a storage backend supporting transactions.
+`
+)
@@ -0,0 +1,94 @@
+package kv
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestVersionedKV_Batch_InvalidOperations(t *testing.T) {
+	b, storage := getBackend(t)
+
+	testCases := map[string]struct {
+		operations []interface{}
+		err        string
+	}{
+		"empty": {
+			operations: []interface{}{},
+			err:        "no operations provided",
+		},
+		"missing path": {
+			operations: []interface{}{
+				map[string]interface{}{"operation": "put", "data": map[string]interface{}{"a": "b"}},
+			},
+			err: "operation 0: missing path",
+		},
+		"unknown operation": {
+			operations: []interface{}{
+				map[string]interface{}{"operation": "destroy", "path": "foo"},
+			},
+			err: `operation 0: unknown operation "destroy"`,
+		},
+		"unknown field": {
+			operations: []interface{}{
+				map[string]interface{}{"operation": "delete", "path": "foo", "version": 1},
+			},
+			err: `operation 0: unknown field "version"`,
+		},
+		"put without data": {
+			operations: []interface{}{
+				map[string]interface{}{"operation": "put", "path": "foo", "data": map[string]interface{}{"a": "b"}},
+				map[string]interface{}{"operation": "put", "path": "bar"},
+			},
+			err: "operation 1: no data provided",
+		},
+		"delete with data": {
+			operations: []interface{}{
+				map[string]interface{}{"operation": "delete", "path": "foo", "data": map[string]interface{}{"a": "b"}},
+			},
+			err: "operation 0: data is not supported for deletes",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "batch",
+				Storage:   storage,
+				Data: map[string]interface{}{
+					"operations": tc.operations,
+				},
+			})
+			if err != logical.ErrInvalidRequest {
+				t.Fatalf("expected invalid request, got err: %v, resp: %#v", err, resp)
+			}
+			if resp == nil || !resp.IsError() || resp.Error().Error() != tc.err {
+				t.Fatalf("expected error %q, got resp: %#v", tc.err, resp)
+			}
+		})
+	}
+}
+
+func TestVersionedKV_Batch_RequiresTransactions(t *testing.T) {
+	b, storage := getBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "batch",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"operations": []interface{}{
+				map[string]interface{}{"operation": "put", "path": "foo", "data": map[string]interface{}{"a": "b"}},
+			},
+		},
+	})
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request, got err: %v, resp: %#v", err, resp)
+	}
+	if resp == nil || !strings.Contains(resp.Error().Error(), "supporting transactions") {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+}
@@ -0,0 +1,348 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// pathCopy returns the path configuration for copying secrets along with
+// their metadata and versions.
+func pathCopy(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "copy$",
+		Fields:  relocateFields(),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathRelocateWrite(false)),
+			},
+		},
+
+		HelpSynopsis:    copyHelpSyn,
+		HelpDescription: copyHelpDesc,
+	}
+}
+
+// pathMove returns the path configuration for moving secrets along with
+// their metadata and versions.
+func pathMove(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "move$",
+		Fields:  relocateFields(),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathRelocateWrite(true)),
+			},
+		},
+
+		HelpSynopsis:    moveHelpSyn,
+		HelpDescription: moveHelpDesc,
+	}
+}
+
+func relocateFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"source": {
+			Type:        framework.TypeString,
+			Description: "Location of the secret, or with recursive, of the secrets to relocate.",
+			Required:    true,
+		},
+		"destination": {
+			Type:        framework.TypeString,
+			Description: "Location to relocate the secret, or with recursive, the secrets to.",
+			Required:    true,
+		},
+		"recursive": {
+			Type:        framework.TypeBool,
+			Description: "If true, source and destination are treated as directories, and all secrets within source are relocated.",
+		},
+	}
+}
+
+// relocation is a secret to be copied or moved.
+type relocation struct {
+	source      string
+	destination string
+	meta        *KeyMetadata
+}
+
+// relocationCheck is an operation the client must be allowed to perform for
+// a secret to be relocated.
+type relocationCheck struct {
+	op   logical.Operation
+	path string
+}
+
+// pathRelocateWrite copies, or if move is set, moves secrets along with their
+// metadata and all of their versions within a single storage transaction.
+// The client must be allowed to read the data and metadata of each source,
+// to create the data and metadata of each destination and, when moving, to
+// delete the metadata of each source.
+func (b *versionedKVBackend) pathRelocateWrite(move bool) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		source := data.Get("source").(string)
+		destination := data.Get("destination").(string)
+		recursive := data.Get("recursive").(bool)
+
+		if source == "" || destination == "" {
+			return logical.ErrorResponse("missing source or destination"), logical.ErrInvalidRequest
+		}
+		if recursive {
+			source = strings.TrimSuffix(source, "/") + "/"
+			destination = strings.TrimSuffix(destination, "/") + "/"
+			if strings.HasPrefix(destination, source) || strings.HasPrefix(source, destination) {
+				return logical.ErrorResponse("source and destination may not be within one another"), logical.ErrInvalidRequest
+			}
+		} else if source == destination {
+			return logical.ErrorResponse("source and destination must differ"), logical.ErrInvalidRequest
+		}
+
+		txnStorage, ok := req.Storage.(logical.TransactionalStorage)
+		if !ok {
+			return logical.ErrorResponse("relocating secrets requires a storage backend supporting transactions"), logical.ErrInvalidRequest
+		}
+
+		sysView, ok := b.System().(logical.ExtendedSystemView)
+		if !ok {
+			return nil, errors.New("relocating secrets is not supported by this plugin environment")
+		}
+
+		wrapper, err := b.getKeyEncryptor(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		sources := []string{source}
+		if recursive {
+			sources, err = listKeysRecursive(ctx, wrapper.Wrap(req.Storage), source)
+			if err != nil {
+				return nil, err
+			}
+			if len(sources) == 0 {
+				return logical.ErrorResponse("no secrets found within %q", source), logical.ErrInvalidRequest
+			}
+		}
+
+		relocations := make([]*relocation, 0, len(sources))
+		keys := make([]string, 0, 2*len(sources))
+		for _, key := range sources {
+			r := &relocation{
+				source:      key,
+				destination: destination + strings.TrimPrefix(key, source),
+			}
+			if !recursive {
+				r.destination = destination
+			}
+			relocations = append(relocations, r)
+			keys = append(keys, r.source, r.destination)
+		}
+
+		for _, lock := range locksutil.LocksForKeys(b.locks, keys) {
+			lock.Lock()
+			defer lock.Unlock()
+		}
+
+		txn, err := txnStorage.BeginTx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer txn.Rollback(ctx)
+
+		results := make([]interface{}, 0, len(relocations))
+		for _, r := range relocations {
+			checks := []relocationCheck{
+				{logical.ReadOperation, "data/" + r.source},
+				{logical.ReadOperation, "metadata/" + r.source},
+				{logical.CreateOperation, "data/" + r.destination},
+				{logical.CreateOperation, "metadata/" + r.destination},
+			}
+			if move {
+				checks = append(checks, relocationCheck{logical.DeleteOperation, "metadata/" + r.source})
+			}
+			for _, check := range checks {
+				allowed, err := sysView.CheckSubRequest(ctx, check.op, check.path, nil)
+				if err != nil {
+					return nil, err
+				}
+				if !allowed {
+					return logical.ErrorResponse("permission denied on %q", check.path), logical.ErrPermissionDenied
+				}
+			}
+
+			meta, err := b.getKeyMetadata(ctx, txn, r.source)
+			if err != nil {
+				return nil, err
+			}
+			if meta == nil {
+				if recursive {
+					// Deleted since listing the source
+					continue
+				}
+				return logical.ErrorResponse("no secret found at %q", r.source), logical.ErrInvalidRequest
+			}
+
+			existing, err := b.getKeyMetadata(ctx, txn, r.destination)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				return logical.ErrorResponse("a secret already exists at %q", r.destination), logical.ErrInvalidRequest
+			}
+
+			// The destination must satisfy its schemas like any write would.
+			versionData, err := b.currentVersionData(ctx, txn, meta)
+			if err != nil {
+				return nil, err
+			}
+			if versionData != nil {
+				marshaledData, err := json.Marshal(versionData)
+				if err != nil {
+					return nil, err
+				}
+				violations, _, err := b.schemaViolations(ctx, txn, r.destination, marshaledData)
+				if err != nil {
+					return nil, err
+				}
+				if len(violations) > 0 {
+					return logical.ErrorResponse("%q: %s", r.source, schemaViolationsError(violations)), logical.ErrInvalidRequest
+				}
+			}
+
+			for id := range meta.Versions {
+				if err := b.relocateVersion(ctx, txn, r.source, r.destination, id, move); err != nil {
+					return nil, err
+				}
+			}
+
+			r.meta = proto.Clone(meta).(*KeyMetadata)
+			r.meta.Key = r.destination
+			if err := b.writeKeyMetadata(ctx, txn, r.meta, nil); err != nil {
+				return nil, err
+			}
+			if move {
+				if err := b.deleteKeyMetadata(ctx, txn, meta); err != nil {
+					return nil, err
+				}
+			}
+
+			results = append(results, map[string]interface{}{
+				"source":      r.source,
+				"destination": r.destination,
+				"version":     r.meta.CurrentVersion,
+			})
+		}
+
+		if err := txn.Commit(ctx); err != nil {
+			return nil, err
+		}
+
+		for _, r := range relocations {
+			if r.meta == nil {
+				continue
+			}
+			b.sendKeyEvent(ctx, eventTypeDataWrite, "data/", r.destination, int(r.meta.CurrentVersion))
+			if move {
+				b.sendKeyEvent(ctx, eventTypeMetadataDelete, "metadata/", r.source)
+			}
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"keys": results,
+			},
+		}, nil
+	}
+}
+
+// relocateVersion copies the data of a version of the source key to the same
+// version of the destination key, deleting the original if move is set.
+// Versions without data, i.e. destroyed ones, are skipped.
+func (b *versionedKVBackend) relocateVersion(ctx context.Context, s logical.Storage, source, destination string, version uint64, move bool) error {
+	sourceKey, err := b.getVersionKey(ctx, source, version, s)
+	if err != nil {
+		return err
+	}
+
+	raw, err := s.Get(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return nil
+	}
+
+	destinationKey, err := b.getVersionKey(ctx, destination, version, s)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Put(ctx, &logical.StorageEntry{
+		Key:   destinationKey,
+		Value: raw.Value,
+	}); err != nil {
+		return err
+	}
+
+	if move {
+		return s.Delete(ctx, sourceKey)
+	}
+	return nil
+}
+
+// listKeysRecursive returns all keys within the given prefix.
+func listKeysRecursive(ctx context.Context, s logical.Storage, prefix string) ([]string, error) {
+	var keys []string
+	frontier := []string{prefix}
+	for len(frontier) > 0 {
+		current := frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+
+		children, err := s.List(ctx, current)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %q: %w", current, err)
+		}
+		for _, child := range children {
+			if strings.HasSuffix(child, "/") {
+				frontier = append(frontier, current+child)
+			} else {
+				keys = append(keys, current+child)
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+const (
+	copyHelpSyn  = `Copy secrets along with their metadata and versions.`
+	copyHelpDesc = `
+This path copies the secret at "source" to "destination", including its
+metadata, custom metadata and every version still stored. With "recursive",
+every secret within the "source" directory is copied to the same relative path
+within the "destination" directory. All secrets are copied in a single storage
+transaction, and none is copied if any destination already exists.
+
+The token must be allowed to read the data and metadata of each source, and to
+create the data and metadata of each destination.
+`
+
+	moveHelpSyn  = `Move secrets along with their metadata and versions.`
+	moveHelpDesc = `
+This path moves the secret at "source" to "destination", including its
+metadata, custom metadata and every version still stored. With "recursive",
+every secret within the "source" directory is moved to the same relative path
+within the "destination" directory. All secrets are moved in a single storage
+transaction, and none is moved if any destination already exists.
+
+The token must be allowed to read the data and metadata of each source, to
+delete the metadata of each source, and to create the data and metadata of each
+destination.
+`
+)
@@ -14,6 +14,7 @@ import (
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/mitchellh/mapstructure"
 	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
 	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
 )
@@ -107,6 +108,25 @@ func (b *versionedKVBackend) pathDataRead() framework.OperationFunc {
 		}
 
 		key := data.Get("path").(string)
+		verParam := data.Get("version").(int)
+
+		// Follow the references starting at the key, reading the secret of
+		// another mount on the client's behalf if they lead there.
+		target, err := b.resolveReferences(ctx, req, key)
+		if err != nil {
+			var userErr errutil.UserError
+			switch {
+			case errors.As(err, &userErr):
+				return logical.ErrorResponse(userErr.Err), logical.ErrInvalidRequest
+			case errors.Is(err, logical.ErrPermissionDenied):
+				return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+			}
+			return nil, err
+		}
+		if target.remote != nil {
+			return b.readRemoteReference(ctx, target, verParam)
+		}
+		key = target.key
 
 		lock := locksutil.LockForKey(b.locks, key)
 		lock.RLock()
@@ -119,9 +139,11 @@ func (b *versionedKVBackend) pathDataRead() framework.OperationFunc {
 		if meta == nil {
 			return nil, nil
 		}
+		if meta.Reference != nil {
+			return nil, fmt.Errorf("reference at %q changed while resolving it", key)
+		}
 
 		verNum := meta.CurrentVersion
-		verParam := data.Get("version").(int)
 		if verParam > 0 {
 			verNum = uint64(verParam)
 		}
@@ -144,6 +166,11 @@ func (b *versionedKVBackend) pathDataRead() framework.OperationFunc {
 				},
 			},
 		}
+		if target.followed {
+			metadata := resp.Data["metadata"].(map[string]interface{})
+			metadata["resolved_path"] = key
+			metadata["resolved_mount"] = ""
+		}
 
 		// If the version has been deleted return metadata with a 404
 		if vm.DeletionTime != nil {
@@ -318,55 +345,24 @@ func (b *versionedKVBackend) pathDataWrite() framework.OperationFunc {
 			}
 		}
 
-		err = validateCheckAndSetOption(data, config, meta)
-		if err != nil {
+		if err := validateNotReference(meta); err != nil {
 			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 		}
 
-		// Create a version key for the new version
-		versionKey, err := b.getVersionKey(ctx, key, meta.CurrentVersion+1, req.Storage)
-		if err != nil {
-			return nil, err
-		}
-		version := &Version{
-			Data:        marshaledData,
-			CreatedTime: ptypes.TimestampNow(),
-		}
-
-		ctime, err := ptypes.Timestamp(version.CreatedTime)
+		err = validateCheckAndSetOption(data, config, meta)
 		if err != nil {
-			return logical.ErrorResponse("unexpected error converting %T(%v) to time.Time: %v", version.CreatedTime, version.CreatedTime, err), logical.ErrInvalidRequest
-		}
-
-		if !config.IsDeleteVersionAfterDisabled() {
-			if dtime, ok := deletionTime(ctime, deleteVersionAfter(config), deleteVersionAfter(meta)); ok {
-				dt, err := ptypes.TimestampProto(dtime)
-				if err != nil {
-					return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
-				}
-				version.DeletionTime = dt
-			}
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 		}
 
-		buf, err := proto.Marshal(version)
+		violations, _, err := b.schemaViolations(ctx, req.Storage, key, marshaledData)
 		if err != nil {
 			return nil, err
 		}
-
-		// Write the new version
-		if err := req.Storage.Put(ctx, &logical.StorageEntry{
-			Key:   versionKey,
-			Value: buf,
-		}); err != nil {
-			return nil, err
+		if len(violations) > 0 {
+			return logical.ErrorResponse(schemaViolationsError(violations)), logical.ErrInvalidRequest
 		}
 
-		// Add version to the key metadata and calculate version to delete
-		// based on the max_versions specified by either the secret's key
-		// metadata or the engine's config
-		vm, versionToDelete := meta.AddVersion(version.CreatedTime, version.DeletionTime, config.MaxVersions)
-
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		vm, warning, err := b.writeVersion(ctx, req.Storage, config, meta, meta.CustomMetadata, marshaledData)
 		if err != nil {
 			return nil, err
 		}
@@ -381,7 +377,6 @@ func (b *versionedKVBackend) pathDataWrite() framework.OperationFunc {
 			},
 		}
 
-		warning := b.cleanupOldVersions(ctx, req.Storage, key, versionToDelete)
 		if warning != "" {
 			// A failed attempt to clean up old versions will be retried on
 			// next write attempt, prefer a warning over an error resp
@@ -397,10 +392,70 @@ func (b *versionedKVBackend) pathDataWrite() framework.OperationFunc {
 			req.Storage = originalStorage
 		}
 
+		b.sendKeyEvent(ctx, eventTypeDataWrite, "data/", key, int(meta.CurrentVersion))
+
 		return resp, nil
 	}
 }
 
+// writeVersion stores data as a new version of the key described by meta,
+// updates and persists meta accordingly and cleans up versions beyond the
+// maximum. Failures of the latter are returned as a warning, as they will be
+// retried on the next write. previous is the custom metadata of the key as
+// currently stored.
+func (b *versionedKVBackend) writeVersion(ctx context.Context, s logical.Storage, config *Configuration, meta *KeyMetadata, previous map[string]string, data []byte) (*VersionMetadata, string, error) {
+	// Create a version key for the new version
+	versionKey, err := b.getVersionKey(ctx, meta.Key, meta.CurrentVersion+1, s)
+	if err != nil {
+		return nil, "", err
+	}
+	version := &Version{
+		Data:        data,
+		CreatedTime: ptypes.TimestampNow(),
+	}
+
+	ctime, err := ptypes.Timestamp(version.CreatedTime)
+	if err != nil {
+		return nil, "", fmt.Errorf("unexpected error converting %T(%v) to time.Time: %w", version.CreatedTime, version.CreatedTime, err)
+	}
+
+	// Set the deletion_time for the new version based on delete_version_after value if set
+	// on either the secret's key metadata or the engine's config
+	if !config.IsDeleteVersionAfterDisabled() {
+		if dtime, ok := deletionTime(ctime, deleteVersionAfter(config), deleteVersionAfter(meta)); ok {
+			dt, err := ptypes.TimestampProto(dtime)
+			if err != nil {
+				return nil, "", fmt.Errorf("error setting deletion_time: converting %v to protobuf: %w", dtime, err)
+			}
+			version.DeletionTime = dt
+		}
+	}
+
+	buf, err := proto.Marshal(version)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Write the new version
+	if err := s.Put(ctx, &logical.StorageEntry{
+		Key:   versionKey,
+		Value: buf,
+	}); err != nil {
+		return nil, "", err
+	}
+
+	// Add version to the key metadata and calculate version to delete
+	// based on the max_versions specified by either the secret's key
+	// metadata or the engine's config
+	vm, versionToDelete := meta.AddVersion(version.CreatedTime, version.DeletionTime, config.MaxVersions)
+
+	if err := b.writeKeyMetadata(ctx, s, meta, previous); err != nil {
+		return nil, "", err
+	}
+
+	return vm, b.cleanupOldVersions(ctx, s, meta.Key, versionToDelete), nil
+}
+
 // patchPreprocessor is passed to framework.HandlePatchOperation within the
 // pathDataPatch handler. The framework.HandlePatchOperation abstraction
 // expects only the resource data to be provided. The "data" key must be lifted
@@ -468,6 +523,10 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 			return nil, err
 		}
 
+		if err := validateNotReference(meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
 		err = validateCheckAndSetOption(data, config, meta)
 		if err != nil {
 			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -538,51 +597,15 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 			return nil, err
 		}
 
-		newVersion := &Version{
-			Data:        patchedBytes,
-			CreatedTime: ptypes.TimestampNow(),
-		}
-
-		ctime, err := ptypes.Timestamp(newVersion.CreatedTime)
-		if err != nil {
-			return logical.ErrorResponse("unexpected error converting %T(%v) to time.Time: %v", newVersion.CreatedTime, newVersion.CreatedTime, err), logical.ErrInvalidRequest
-		}
-
-		// Set the deletion_time for the new version based on delete_version_after value if set
-		// on either the secret's key metadata or the engine's config
-		if !config.IsDeleteVersionAfterDisabled() {
-			if dtime, ok := deletionTime(ctime, deleteVersionAfter(config), deleteVersionAfter(meta)); ok {
-				dt, err := ptypes.TimestampProto(dtime)
-				if err != nil {
-					return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
-				}
-				newVersion.DeletionTime = dt
-			}
-		}
-
-		buf, err := proto.Marshal(newVersion)
+		violations, _, err := b.schemaViolations(ctx, req.Storage, key, patchedBytes)
 		if err != nil {
 			return nil, err
 		}
-
-		newVersionKey, err := b.getVersionKey(ctx, key, meta.CurrentVersion+1, req.Storage)
-		if err != nil {
-			return nil, err
-		}
-
-		if err := req.Storage.Put(ctx, &logical.StorageEntry{
-			Key:   newVersionKey,
-			Value: buf,
-		}); err != nil {
-			return nil, err
+		if len(violations) > 0 {
+			return logical.ErrorResponse(schemaViolationsError(violations)), logical.ErrInvalidRequest
 		}
 
-		// Add version to the key metadata and calculate version to delete
-		// based on the max_versions specified by either the secret's key
-		// metadata or the engine's config
-		newVersionMetadata, versionToDelete := meta.AddVersion(newVersion.CreatedTime, newVersion.DeletionTime, config.MaxVersions)
-
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		newVersionMetadata, warning, err := b.writeVersion(ctx, req.Storage, config, meta, meta.CustomMetadata, patchedBytes)
 		if err != nil {
 			return nil, err
 		}
@@ -597,7 +620,6 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 			},
 		}
 
-		warning := b.cleanupOldVersions(ctx, req.Storage, key, versionToDelete)
 		if warning != "" {
 			// A failed attempt to clean up old versions will be retried on
 			// next patch attempt, prefer a warning over an error resp
@@ -613,6 +635,8 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 			req.Storage = originalStorage
 		}
 
+		b.sendKeyEvent(ctx, eventTypeDataPatch, "data/", key, int(meta.CurrentVersion))
+
 		return resp, nil
 	}
 }
@@ -665,7 +689,7 @@ func (b *versionedKVBackend) pathDataDelete() framework.OperationFunc {
 
 		lv.DeletionTime = ptypes.TimestampNow()
 
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		err = b.writeKeyMetadata(ctx, req.Storage, meta, meta.CustomMetadata)
 		if err != nil {
 			return nil, err
 		}
@@ -679,6 +703,8 @@ func (b *versionedKVBackend) pathDataDelete() framework.OperationFunc {
 			req.Storage = originalStorage
 		}
 
+		b.sendKeyEvent(ctx, eventTypeDataDelete, "data/", key, int(meta.CurrentVersion))
+
 		return nil, nil
 	}
 }
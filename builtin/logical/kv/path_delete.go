@@ -121,7 +121,7 @@ func (b *versionedKVBackend) pathUndeleteWrite() framework.OperationFunc {
 				}
 			}
 		}
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		err = b.writeKeyMetadata(ctx, req.Storage, meta, meta.CustomMetadata)
 		if err != nil {
 			return nil, err
 		}
@@ -135,6 +135,8 @@ func (b *versionedKVBackend) pathUndeleteWrite() framework.OperationFunc {
 			req.Storage = originalStorage
 		}
 
+		b.sendKeyEvent(ctx, eventTypeDataUndelete, "data/", key, versions...)
+
 		return nil, nil
 	}
 }
@@ -195,7 +197,7 @@ func (b *versionedKVBackend) pathDeleteWrite() framework.OperationFunc {
 			lv.DeletionTime = ptypes.TimestampNow()
 		}
 
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		err = b.writeKeyMetadata(ctx, req.Storage, meta, meta.CustomMetadata)
 		if err != nil {
 			return nil, err
 		}
@@ -209,6 +211,8 @@ func (b *versionedKVBackend) pathDeleteWrite() framework.OperationFunc {
 			req.Storage = originalStorage
 		}
 
+		b.sendKeyEvent(ctx, eventTypeDataDelete, "data/", key, versions...)
+
 		return nil, nil
 	}
 }
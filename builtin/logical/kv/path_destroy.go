@@ -81,7 +81,7 @@ func (b *versionedKVBackend) pathDestroyWrite() framework.OperationFunc {
 		}
 
 		// Write the metadata key before deleting the versions
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		err = b.writeKeyMetadata(ctx, req.Storage, meta, meta.CustomMetadata)
 		if err != nil {
 			return nil, err
 		}
@@ -108,6 +108,8 @@ func (b *versionedKVBackend) pathDestroyWrite() framework.OperationFunc {
 			req.Storage = originalStorage
 		}
 
+		b.sendKeyEvent(ctx, eventTypeDataDestroy, "data/", key, versions...)
+
 		return nil, nil
 	}
 }
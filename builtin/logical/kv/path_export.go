@@ -0,0 +1,827 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// archiveVersion is the version of the format of export archives.
+const archiveVersion = 1
+
+// pathExport returns the path configuration for exporting secrets to an
+// encrypted archive.
+func pathExport(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "export$",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Directory of the secrets to export. Defaults to the whole mount.",
+			},
+			"pgp_keys": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Base64-encoded PGP public keys to encrypt the archive to.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "Passphrase to encrypt the archive with.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathExportWrite()),
+			},
+		},
+
+		HelpSynopsis:    exportHelpSyn,
+		HelpDescription: exportHelpDesc,
+	}
+}
+
+// pathImport returns the path configuration for importing secrets from an
+// archive produced by the export path.
+func pathImport(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "import$",
+		Fields: map[string]*framework.FieldSchema{
+			"archive": {
+				Type:        framework.TypeString,
+				Description: "Base64-encoded archive to import.",
+				Required:    true,
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "Passphrase the archive was encrypted with.",
+			},
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Directory to import the secrets to. Defaults to the directory they were exported from.",
+			},
+			"flatten": {
+				Type:        framework.TypeBool,
+				Description: "If true, only the latest version of each secret is imported, as a new version.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathImportWrite()),
+			},
+		},
+
+		HelpSynopsis:    importHelpSyn,
+		HelpDescription: importHelpDesc,
+	}
+}
+
+// exportArchive is the content of an export archive, before encryption.
+type exportArchive struct {
+	Version     int               `json:"version"`
+	Prefix      string            `json:"prefix"`
+	CreatedTime time.Time         `json:"created_time"`
+	Secrets     []*exportedSecret `json:"secrets"`
+}
+
+// exportedSecret is a secret within an export archive, with its path relative
+// to the prefix of the archive.
+type exportedSecret struct {
+	Path               string             `json:"path"`
+	CurrentVersion     uint64             `json:"current_version"`
+	OldestVersion      uint64             `json:"oldest_version"`
+	CreatedTime        *time.Time         `json:"created_time,omitempty"`
+	UpdatedTime        *time.Time         `json:"updated_time,omitempty"`
+	MaxVersions        uint32             `json:"max_versions,omitempty"`
+	CasRequired        bool               `json:"cas_required,omitempty"`
+	DeleteVersionAfter string             `json:"delete_version_after,omitempty"`
+	CustomMetadata     map[string]string  `json:"custom_metadata,omitempty"`
+	Reference          string             `json:"reference,omitempty"`
+	ReferenceMount     string             `json:"reference_mount,omitempty"`
+	Versions           []*exportedVersion `json:"versions"`
+}
+
+// exportedVersion is a version of a secret within an export archive. Data is
+// absent for destroyed versions.
+type exportedVersion struct {
+	Version      uint64          `json:"version"`
+	Data         json.RawMessage `json:"data,omitempty"`
+	CreatedTime  *time.Time      `json:"created_time,omitempty"`
+	DeletionTime *time.Time      `json:"deletion_time,omitempty"`
+	Destroyed    bool            `json:"destroyed,omitempty"`
+}
+
+// pathExportWrite returns an archive of the secrets within a directory along
+// with their metadata and all of their versions, encrypted either to PGP keys
+// or with a passphrase. The client must be allowed to read the data and
+// metadata of each secret.
+func (b *versionedKVBackend) pathExportWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		prefix := data.Get("path").(string)
+		pgpKeys := data.Get("pgp_keys").([]string)
+		passphrase := data.Get("passphrase").(string)
+
+		if (len(pgpKeys) == 0) == (passphrase == "") {
+			return logical.ErrorResponse("exactly one of pgp_keys or passphrase must be provided"), logical.ErrInvalidRequest
+		}
+		if prefix != "" {
+			prefix = strings.TrimSuffix(prefix, "/") + "/"
+		}
+
+		entities, err := parsePGPKeys(pgpKeys)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		sysView, ok := b.System().(logical.ExtendedSystemView)
+		if !ok {
+			return nil, errors.New("exporting secrets is not supported by this plugin environment")
+		}
+
+		// Create a read-only transaction if we can, so that the archive is
+		// consistent. We do not need to commit this as we're not writing to
+		// storage.
+		if txnStorage, ok := req.Storage.(logical.TransactionalStorage); ok {
+			txn, err := txnStorage.BeginReadOnlyTx(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			defer txn.Rollback(ctx)
+			req.Storage = txn
+		}
+
+		wrapper, err := b.getKeyEncryptor(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		keys, err := listKeysRecursive(ctx, wrapper.Wrap(req.Storage), prefix)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return logical.ErrorResponse("no secrets found within %q", prefix), logical.ErrInvalidRequest
+		}
+		sort.Strings(keys)
+
+		archive := &exportArchive{
+			Version:     archiveVersion,
+			Prefix:      prefix,
+			CreatedTime: time.Now().UTC(),
+			Secrets:     make([]*exportedSecret, 0, len(keys)),
+		}
+		for _, key := range keys {
+			for _, check := range []relocationCheck{
+				{logical.ReadOperation, "data/" + key},
+				{logical.ReadOperation, "metadata/" + key},
+			} {
+				allowed, err := sysView.CheckSubRequest(ctx, check.op, check.path, nil)
+				if err != nil {
+					return nil, err
+				}
+				if !allowed {
+					return logical.ErrorResponse("permission denied on %q", check.path), logical.ErrPermissionDenied
+				}
+			}
+
+			meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+			if err != nil {
+				return nil, err
+			}
+			if meta == nil {
+				continue
+			}
+
+			secret, err := b.exportSecret(ctx, req.Storage, meta, strings.TrimPrefix(key, prefix))
+			if err != nil {
+				return nil, err
+			}
+			archive.Secrets = append(archive.Secrets, secret)
+		}
+
+		plaintext, err := json.Marshal(archive)
+		if err != nil {
+			return nil, err
+		}
+
+		ciphertext, err := encryptArchive(plaintext, entities, passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"archive": base64.StdEncoding.EncodeToString(ciphertext),
+				"secrets": len(archive.Secrets),
+			},
+		}
+		if len(entities) > 0 {
+			fingerprints := make([]string, 0, len(entities))
+			for _, entity := range entities {
+				fingerprints = append(fingerprints, fmt.Sprintf("%x", entity.PrimaryKey.Fingerprint))
+			}
+			resp.Data["fingerprints"] = fingerprints
+		}
+
+		return resp, nil
+	}
+}
+
+// exportSecret returns the archived form of the secret described by meta,
+// stored at path within the archive.
+func (b *versionedKVBackend) exportSecret(ctx context.Context, s logical.Storage, meta *KeyMetadata, path string) (*exportedSecret, error) {
+	secret := &exportedSecret{
+		Path:           path,
+		CurrentVersion: meta.CurrentVersion,
+		OldestVersion:  meta.OldestVersion,
+		CreatedTime:    timestampToTime(meta.CreatedTime),
+		UpdatedTime:    timestampToTime(meta.UpdatedTime),
+		MaxVersions:    meta.MaxVersions,
+		CasRequired:    meta.CasRequired,
+		CustomMetadata: meta.CustomMetadata,
+		Versions:       make([]*exportedVersion, 0, len(meta.Versions)),
+	}
+	if meta.DeleteVersionAfter != nil {
+		deleteVersionAfter, err := ptypes.Duration(meta.DeleteVersionAfter)
+		if err != nil {
+			return nil, err
+		}
+		secret.DeleteVersionAfter = deleteVersionAfter.String()
+	}
+	if meta.Reference != nil {
+		secret.Reference = meta.Reference.Path
+		secret.ReferenceMount = meta.Reference.Mount
+	}
+
+	for id, vm := range meta.Versions {
+		version := &exportedVersion{
+			Version:      id,
+			CreatedTime:  timestampToTime(vm.CreatedTime),
+			DeletionTime: timestampToTime(vm.DeletionTime),
+			Destroyed:    vm.Destroyed,
+		}
+
+		versionKey, err := b.getVersionKey(ctx, meta.Key, id, s)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := s.Get(ctx, versionKey)
+		if err != nil {
+			return nil, err
+		}
+		if raw != nil {
+			v := &Version{}
+			if err := proto.Unmarshal(raw.Value, v); err != nil {
+				return nil, fmt.Errorf("failed to decode version %d of %q: %w", id, meta.Key, err)
+			}
+			version.Data = v.Data
+		}
+
+		secret.Versions = append(secret.Versions, version)
+	}
+	sort.Slice(secret.Versions, func(i, j int) bool {
+		return secret.Versions[i].Version < secret.Versions[j].Version
+	})
+
+	return secret, nil
+}
+
+// pathImportWrite imports the secrets of an archive produced by the export
+// path within a single storage transaction. By default, secrets are imported
+// with all of their versions and may not already exist. With flatten, only
+// the latest version of each secret is written, as a new version of any
+// existing secret. The client must be allowed to create, or when flattening
+// onto existing secrets, to update the data and metadata of each secret.
+func (b *versionedKVBackend) pathImportWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		passphrase := data.Get("passphrase").(string)
+		flatten := data.Get("flatten").(bool)
+
+		raw, err := base64.StdEncoding.DecodeString(data.Get("archive").(string))
+		if err != nil {
+			return logical.ErrorResponse("failed to decode archive: %s", err), logical.ErrInvalidRequest
+		}
+
+		plaintext, err := decryptArchive(raw, passphrase)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		archive := &exportArchive{}
+		if err := json.Unmarshal(plaintext, archive); err != nil {
+			return logical.ErrorResponse("failed to parse archive: %s", err), logical.ErrInvalidRequest
+		}
+		if archive.Version != archiveVersion {
+			return logical.ErrorResponse("unsupported archive version %d", archive.Version), logical.ErrInvalidRequest
+		}
+		if len(archive.Secrets) == 0 {
+			return logical.ErrorResponse("archive contains no secrets"), logical.ErrInvalidRequest
+		}
+
+		prefix := archive.Prefix
+		if p, ok := data.GetOk("path"); ok {
+			prefix = p.(string)
+		}
+		prefix = strings.Trim(prefix, "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+
+		// Paths are validated as a whole, so that neither the archive nor
+		// the prefix can move secrets outside of the prefix, past the
+		// checks of the client's policy below.
+		keys := make([]string, 0, len(archive.Secrets))
+		for _, secret := range archive.Secrets {
+			if err := validateKeyPath(prefix + secret.Path); err != nil {
+				return logical.ErrorResponse("archive contains an invalid path: %s", err), logical.ErrInvalidRequest
+			}
+			if err := validateCustomMetadata(secret.CustomMetadata); err != nil {
+				return logical.ErrorResponse("%q: %s", secret.Path, err), logical.ErrInvalidRequest
+			}
+			keys = append(keys, prefix+secret.Path)
+		}
+
+		txnStorage, ok := req.Storage.(logical.TransactionalStorage)
+		if !ok {
+			return logical.ErrorResponse("importing secrets requires a storage backend supporting transactions"), logical.ErrInvalidRequest
+		}
+
+		sysView, ok := b.System().(logical.ExtendedSystemView)
+		if !ok {
+			return nil, errors.New("importing secrets is not supported by this plugin environment")
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, lock := range locksutil.LocksForKeys(b.locks, keys) {
+			lock.Lock()
+			defer lock.Unlock()
+		}
+
+		txn, err := txnStorage.BeginTx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer txn.Rollback(ctx)
+
+		imported := make([]*KeyMetadata, 0, len(archive.Secrets))
+		results := make([]interface{}, 0, len(archive.Secrets))
+		var warnings []string
+		for i, secret := range archive.Secrets {
+			key := keys[i]
+
+			existing, err := b.getKeyMetadata(ctx, txn, key)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil && !flatten {
+				return logical.ErrorResponse("a secret already exists at %q", key), logical.ErrInvalidRequest
+			}
+
+			op := logical.CreateOperation
+			if existing != nil {
+				op = logical.UpdateOperation
+			}
+			for _, check := range []relocationCheck{
+				{op, "data/" + key},
+				{op, "metadata/" + key},
+			} {
+				allowed, err := sysView.CheckSubRequest(ctx, check.op, check.path, nil)
+				if err != nil {
+					return nil, err
+				}
+				if !allowed {
+					return logical.ErrorResponse("permission denied on %q", check.path), logical.ErrPermissionDenied
+				}
+			}
+
+			var meta *KeyMetadata
+			var warning string
+			if flatten {
+				meta, warning, err = b.importFlattened(ctx, txn, config, key, existing, secret)
+			} else {
+				meta, err = b.importVersions(ctx, txn, key, secret)
+			}
+			if err != nil {
+				var userErr *importError
+				if errors.As(err, &userErr) {
+					return logical.ErrorResponse("%q: %s", key, userErr.msg), logical.ErrInvalidRequest
+				}
+				return nil, err
+			}
+			if warning != "" {
+				warnings = append(warnings, warning)
+			}
+
+			imported = append(imported, meta)
+			results = append(results, map[string]interface{}{
+				"path":    key,
+				"version": meta.CurrentVersion,
+			})
+		}
+
+		if err := txn.Commit(ctx); err != nil {
+			return nil, err
+		}
+
+		for _, meta := range imported {
+			b.sendKeyEvent(ctx, eventTypeDataWrite, "data/", meta.Key, int(meta.CurrentVersion))
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"keys": results,
+			},
+		}
+		for _, warning := range warnings {
+			resp.AddWarning(warning)
+		}
+
+		return resp, nil
+	}
+}
+
+// importError is an error caused by the content of an archive being imported.
+type importError struct {
+	msg string
+}
+
+func (e *importError) Error() string {
+	return e.msg
+}
+
+// importVersions writes the archived secret at key along with its metadata
+// and all of its versions, preserving their numbers.
+func (b *versionedKVBackend) importVersions(ctx context.Context, s logical.Storage, key string, secret *exportedSecret) (*KeyMetadata, error) {
+	meta := &KeyMetadata{
+		Key:            key,
+		Versions:       make(map[uint64]*VersionMetadata, len(secret.Versions)),
+		CurrentVersion: secret.CurrentVersion,
+		OldestVersion:  secret.OldestVersion,
+	}
+	if err := applyExportedMetadata(meta, secret); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if meta.CreatedTime, err = timeToTimestamp(secret.CreatedTime); err != nil {
+		return nil, err
+	}
+	if meta.UpdatedTime, err = timeToTimestamp(secret.UpdatedTime); err != nil {
+		return nil, err
+	}
+
+	for _, version := range secret.Versions {
+		if version.Version == 0 || version.Version > secret.CurrentVersion {
+			return nil, &importError{msg: fmt.Sprintf("invalid version %d", version.Version)}
+		}
+
+		vm := &VersionMetadata{Destroyed: version.Destroyed}
+		if vm.CreatedTime, err = timeToTimestamp(version.CreatedTime); err != nil {
+			return nil, err
+		}
+		if vm.DeletionTime, err = timeToTimestamp(version.DeletionTime); err != nil {
+			return nil, err
+		}
+		meta.Versions[version.Version] = vm
+
+		if version.Data == nil {
+			continue
+		}
+
+		// The current data must satisfy its schemas like any write would.
+		if version.Version == secret.CurrentVersion {
+			if err := b.validateImportedData(ctx, s, key, version.Data); err != nil {
+				return nil, err
+			}
+		}
+
+		buf, err := proto.Marshal(&Version{
+			Data:         version.Data,
+			CreatedTime:  vm.CreatedTime,
+			DeletionTime: vm.DeletionTime,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		versionKey, err := b.getVersionKey(ctx, key, version.Version, s)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Put(ctx, &logical.StorageEntry{
+			Key:   versionKey,
+			Value: buf,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.writeKeyMetadata(ctx, s, meta, nil); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// importFlattened writes the data of the latest version of the archived
+// secret as a new version of the secret at key, creating it if existing is
+// nil, and applies the archived metadata to it. Secrets whose latest version
+// is deleted or destroyed only have their metadata imported, which is
+// reported as a warning.
+func (b *versionedKVBackend) importFlattened(ctx context.Context, s logical.Storage, config *Configuration, key string, existing *KeyMetadata, secret *exportedSecret) (*KeyMetadata, string, error) {
+	previous := existing.GetCustomMetadata()
+	meta := existing
+	if meta == nil {
+		now := ptypes.TimestampNow()
+		meta = &KeyMetadata{
+			Key:         key,
+			Versions:    map[uint64]*VersionMetadata{},
+			CreatedTime: now,
+			UpdatedTime: now,
+		}
+	}
+	if err := applyExportedMetadata(meta, secret); err != nil {
+		return nil, "", err
+	}
+
+	var latest *exportedVersion
+	for _, version := range secret.Versions {
+		if version.Version == secret.CurrentVersion {
+			latest = version
+		}
+	}
+
+	if latest == nil || latest.Data == nil || latest.Destroyed || (latest.DeletionTime != nil && !latest.DeletionTime.After(time.Now())) {
+		if err := b.writeKeyMetadata(ctx, s, meta, previous); err != nil {
+			return nil, "", err
+		}
+		if secret.Reference != "" {
+			return meta, "", nil
+		}
+		return meta, fmt.Sprintf("%q: the latest version is deleted or destroyed, only the metadata was imported", key), nil
+	}
+
+	if err := validateNotReference(meta); err != nil {
+		return nil, "", &importError{msg: err.Error()}
+	}
+	if err := b.validateImportedData(ctx, s, key, latest.Data); err != nil {
+		return nil, "", err
+	}
+
+	_, warning, err := b.writeVersion(ctx, s, config, meta, previous, latest.Data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return meta, warning, nil
+}
+
+// validateImportedData checks that data is a JSON object satisfying the
+// schemas of key.
+func (b *versionedKVBackend) validateImportedData(ctx context.Context, s logical.Storage, key string, data json.RawMessage) error {
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return &importError{msg: fmt.Sprintf("invalid data: %s", err)}
+	}
+
+	violations, _, err := b.schemaViolations(ctx, s, key, data)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &importError{msg: schemaViolationsError(violations)}
+	}
+
+	return nil
+}
+
+// applyExportedMetadata sets the settings, custom metadata and reference of
+// the archived secret on meta.
+func applyExportedMetadata(meta *KeyMetadata, secret *exportedSecret) error {
+	meta.MaxVersions = secret.MaxVersions
+	meta.CasRequired = secret.CasRequired
+	meta.CustomMetadata = secret.CustomMetadata
+	meta.DeleteVersionAfter = nil
+	meta.Reference = nil
+
+	if secret.DeleteVersionAfter != "" {
+		deleteVersionAfter, err := time.ParseDuration(secret.DeleteVersionAfter)
+		if err != nil {
+			return &importError{msg: fmt.Sprintf("invalid delete_version_after: %s", err)}
+		}
+		meta.DeleteVersionAfter = ptypes.DurationProto(deleteVersionAfter)
+	}
+
+	if secret.Reference != "" {
+		if secret.Reference == meta.Key && secret.ReferenceMount == "" {
+			return &importError{msg: "a secret cannot reference itself"}
+		}
+		meta.Reference = &SecretReference{
+			Path:  secret.Reference,
+			Mount: secret.ReferenceMount,
+		}
+	}
+
+	return nil
+}
+
+// parsePGPKeys parses the given base64-encoded PGP public keys.
+func parsePGPKeys(pgpKeys []string) ([]*openpgp.Entity, error) {
+	entities := make([]*openpgp.Entity, 0, len(pgpKeys))
+	for _, pgpKey := range pgpKeys {
+		raw, err := base64.StdEncoding.DecodeString(pgpKey)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding given PGP key: %w", err)
+		}
+		entity, err := openpgp.ReadEntity(packet.NewReader(bytes.NewReader(raw)))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing given PGP key: %w", err)
+		}
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
+
+// encryptArchive encrypts the archive to the given PGP keys if any, or else
+// with the passphrase, as an OpenPGP message.
+func encryptArchive(plaintext []byte, entities []*openpgp.Entity, passphrase string) ([]byte, error) {
+	config := &packet.Config{
+		DefaultCipher: packet.CipherAES256,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	var w io.WriteCloser
+	var err error
+	if len(entities) > 0 {
+		w, err = openpgp.Encrypt(buf, entities, nil, nil, config)
+	} else {
+		w, err = openpgp.SymmetricallyEncrypt(buf, []byte(passphrase), nil, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error setting up encryption for archive: %w", err)
+	}
+
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("error encrypting archive: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("error encrypting archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// errArchiveEncryptedToKeys is returned when importing an archive which
+// was encrypted to PGP keys and not decrypted by the client first.
+var errArchiveEncryptedToKeys = errors.New("the archive is encrypted to PGP keys and must be decrypted with the private key before being imported, e.g. with the -pgp-key flag of bao kv import")
+
+// decryptArchive decrypts an archive encrypted with the passphrase. Archives
+// encrypted to PGP keys are decrypted by the client holding the private key,
+// as "bao kv import" does, and are accepted as is once decrypted.
+func decryptArchive(raw []byte, passphrase string) ([]byte, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return raw, nil
+	}
+
+	if bytes.HasPrefix(trimmed, []byte("-----BEGIN PGP")) {
+		block, err := armor.Decode(bytes.NewReader(trimmed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode archive armor: %w", err)
+		}
+		raw, err = io.ReadAll(block.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode archive armor: %w", err)
+		}
+	}
+
+	// Tell apart archives encrypted to PGP keys before asking for a
+	// passphrase, which would not help decrypting them.
+	if encryptedToKeysOnly(raw) {
+		return nil, errArchiveEncryptedToKeys
+	}
+	if passphrase == "" {
+		return nil, errors.New("a passphrase is required to decrypt the archive")
+	}
+
+	prompted := false
+	md, err := openpgp.ReadMessage(bytes.NewReader(raw), nil, func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if prompted {
+			return nil, errors.New("incorrect passphrase")
+		}
+		prompted = true
+		return []byte(passphrase), nil
+	}, nil)
+	switch {
+	case errors.Is(err, pgperrors.ErrKeyIncorrect):
+		return nil, errArchiveEncryptedToKeys
+	case err != nil:
+		return nil, fmt.Errorf("failed to decrypt archive: %w", err)
+	}
+
+	plaintext, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt archive: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// encryptedToKeysOnly returns whether the OpenPGP message starts with
+// session keys encrypted to public keys, and none encrypted with a
+// passphrase.
+func encryptedToKeysOnly(raw []byte) bool {
+	toKeys := false
+	packets := packet.NewReader(bytes.NewReader(raw))
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return false
+		}
+		switch p.(type) {
+		case *packet.EncryptedKey:
+			toKeys = true
+		case *packet.SymmetricKeyEncrypted:
+			return false
+		default:
+			return toKeys
+		}
+	}
+}
+
+// timestampToTime converts ts to a time, returning nil if it is unset.
+func timestampToTime(ts *timestamp.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// timeToTimestamp converts t to a timestamp, returning nil if it is unset.
+func timeToTimestamp(t *time.Time) (*timestamp.Timestamp, error) {
+	if t == nil {
+		return nil, nil
+	}
+	ts, err := ptypes.TimestampProto(*t)
+	if err != nil {
+		return nil, &importError{msg: err.Error()}
+	}
+	return ts, nil
+}
+
+const (
+	exportHelpSyn  = `Export secrets to an encrypted archive.`
+	exportHelpDesc = `
+This path returns an archive of every secret within the "path" directory, or
+within the whole mount if unset, including their metadata, custom metadata and
+every version still stored. The archive is an OpenPGP message encrypted either
+to the given "pgp_keys" or with the given "passphrase", and is returned
+base64-encoded.
+
+The token must be allowed to read the data and metadata of each secret.
+`
+
+	importHelpSyn  = `Import secrets from an archive.`
+	importHelpDesc = `
+This path imports the secrets of an archive returned by the export path into
+the "path" directory, or the directory they were exported from if unset.
+Archives encrypted with a passphrase are decrypted with "passphrase"; archives
+encrypted to PGP keys must be decrypted by the holder of one of the keys first,
+as "bao kv import -pgp-key" does.
+
+By default, secrets are imported with all of their versions and metadata, and
+none is imported if any already exists. With "flatten", only the latest version
+of each secret is imported, as a new version of any existing secret. All
+secrets are imported in a single storage transaction.
+
+The token must be allowed to create, or when flattening onto existing secrets,
+to update the data and metadata of each secret.
+`
+)
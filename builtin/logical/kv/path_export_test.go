@@ -0,0 +1,236 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestVersionedKV_Export_Encryption(t *testing.T) {
+	plaintext := []byte(`{"version":1,"prefix":"app/","secrets":[]}`)
+
+	ciphertext, err := encryptArchive(plaintext, nil, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, []byte("prefix")) {
+		t.Fatal("archive is not encrypted")
+	}
+
+	decrypted, err := decryptArchive(ciphertext, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("expected %s, got %s", plaintext, decrypted)
+	}
+
+	for _, passphrase := range []string{"", "battery staple"} {
+		if _, err := decryptArchive(ciphertext, passphrase); err == nil {
+			t.Fatalf("expected decryption with %q to fail", passphrase)
+		}
+	}
+
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err = encryptArchive(plaintext, []*openpgp.Entity{entity}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	armored := bytes.NewBuffer(nil)
+	w, err := armor.Encode(armored, "PGP MESSAGE", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(ciphertext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Archives encrypted to PGP keys are recognized whether a passphrase
+	// is given or not, and whether they are armored or not.
+	for _, raw := range [][]byte{ciphertext, armored.Bytes()} {
+		for _, passphrase := range []string{"", "correct horse"} {
+			if _, err := decryptArchive(raw, passphrase); !errors.Is(err, errArchiveEncryptedToKeys) {
+				t.Fatalf("expected archive encrypted to PGP keys to be rejected, got: %v", err)
+			}
+		}
+	}
+
+	md, err := openpgp.ReadMessage(bytes.NewReader(ciphertext), openpgp.EntityList{entity}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if _, err := buf.ReadFrom(md.UnverifiedBody); err != nil {
+		t.Fatal(err)
+	}
+
+	// Archives decrypted by the holder of the key are accepted as is.
+	decrypted, err = decryptArchive(buf.Bytes(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("expected %s, got %s", plaintext, decrypted)
+	}
+}
+
+func TestVersionedKV_Export_Versions(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+
+	for _, password := range []string{"hunter1", "hunter2", "hunter3"} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/app/db",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"password": password,
+				},
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("data CreateOperation request failed, err: %v, resp %#v", err, resp)
+		}
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/app/db",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_versions":         5,
+			"delete_version_after": "1h",
+			"custom_metadata": map[string]interface{}{
+				"owner": "payments",
+			},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata UpdateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "destroy/app/db",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"versions": []int{1},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("destroy UpdateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	meta, err := kv.getKeyMetadata(context.Background(), storage, "app/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := kv.exportSecret(context.Background(), storage, meta, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.CurrentVersion != 3 || len(secret.Versions) != 3 || secret.DeleteVersionAfter != "1h0m0s" {
+		t.Fatalf("unexpected exported secret: %#v", secret)
+	}
+	if !secret.Versions[0].Destroyed || secret.Versions[0].Data != nil {
+		t.Fatalf("expected destroyed version without data: %#v", secret.Versions[0])
+	}
+
+	// Round trip the secret through its archived form.
+	marshaled, err := json.Marshal(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret = &exportedSecret{}
+	if err := json.Unmarshal(marshaled, secret); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := kv.importVersions(context.Background(), storage, "copy/db", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.CurrentVersion != 3 || imported.MaxVersions != 5 || !reflect.DeepEqual(imported.CustomMetadata, meta.CustomMetadata) {
+		t.Fatalf("unexpected imported metadata: %#v", imported)
+	}
+
+	for version, password := range map[int]interface{}{1: nil, 2: "hunter2", 3: "hunter3"} {
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "data/copy/db",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"version": version,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if password == nil {
+			if resp.Data["data"] != nil {
+				t.Fatalf("expected version %d to be destroyed: %#v", version, resp.Data)
+			}
+			continue
+		}
+		if p := resp.Data["data"].(map[string]interface{})["password"]; p != password {
+			t.Fatalf("expected version %d to be %v, got %v", version, password, p)
+		}
+	}
+}
+
+func TestVersionedKV_Import_InvalidPaths(t *testing.T) {
+	b, storage := getBackend(t)
+
+	testCases := map[string]struct {
+		prefix string
+		path   string
+	}{
+		"parent segment":            {prefix: "team/", path: "../admin/x"},
+		"parent segment in prefix":  {prefix: "team/../admin/", path: "x"},
+		"dot segment":               {prefix: "team/", path: "./x"},
+		"empty segment":             {prefix: "team/", path: "a//x"},
+		"trailing slash":            {prefix: "team/", path: "x/"},
+		"empty path without prefix": {path: ""},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			archive, err := json.Marshal(&exportArchive{
+				Version: archiveVersion,
+				Prefix:  tc.prefix,
+				Secrets: []*exportedSecret{{Path: tc.path, CurrentVersion: 1, OldestVersion: 1}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "import",
+				Storage:   storage,
+				Data: map[string]interface{}{
+					"archive": base64.StdEncoding.EncodeToString(archive),
+				},
+			})
+			if err != logical.ErrInvalidRequest || resp == nil || !strings.Contains(resp.Error().Error(), "invalid path") {
+				t.Fatalf("expected invalid path to be rejected, err: %v, resp %#v", err, resp)
+			}
+		})
+	}
+}
@@ -55,6 +55,21 @@ A negative duration will cause an error.
 				Description: `
 User-provided key-value pairs that are used to describe arbitrary and
 version-agnostic information about a secret.
+`,
+			},
+			"reference": {
+				Type: framework.TypeString,
+				Description: `
+Path of the secret this secret refers to. Reads of this secret's data return
+the data of the referenced secret instead. An empty string removes the
+reference.
+`,
+			},
+			"reference_mount": {
+				Type: framework.TypeString,
+				Description: `
+Path of the KV v2 mount containing the referenced secret, if not the mount of
+this secret.
 `,
 			},
 			"after": {
@@ -299,6 +314,8 @@ func (b *versionedKVBackend) metadataResponseData(meta *KeyMetadata) (map[string
 		"cas_required":         meta.CasRequired,
 		"delete_version_after": deleteVersionAfter.String(),
 		"custom_metadata":      meta.CustomMetadata,
+		"reference":            meta.GetReference().GetPath(),
+		"reference_mount":      meta.GetReference().GetMount(),
 	}, nil
 }
 
@@ -434,9 +451,11 @@ func (b *versionedKVBackend) pathMetadataWrite() framework.OperationFunc {
 		casRaw, cOk := data.GetOk("cas_required")
 		deleteVersionAfterRaw, dvaOk := data.GetOk("delete_version_after")
 		customMetadataRaw, cmOk := data.GetOk("custom_metadata")
+		_, rOk := data.GetOk("reference")
+		_, rmOk := data.GetOk("reference_mount")
 
 		// Fast path validation
-		if !mOk && !cOk && !dvaOk && !cmOk {
+		if !mOk && !cOk && !dvaOk && !cmOk && !rOk && !rmOk {
 			return nil, nil
 		}
 
@@ -460,6 +479,14 @@ func (b *versionedKVBackend) pathMetadataWrite() framework.OperationFunc {
 			}
 		}
 
+		var reference *SecretReference
+		if rOk || rmOk {
+			reference, err = parseReference(key, data)
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
 		var resp *logical.Response
 		if cOk && config.CasRequired && !casRaw.(bool) {
 			resp = &logical.Response{}
@@ -495,6 +522,7 @@ func (b *versionedKVBackend) pathMetadataWrite() framework.OperationFunc {
 				UpdatedTime: now,
 			}
 		}
+		previous := meta.CustomMetadata
 
 		if mOk {
 			meta.MaxVersions = uint32(maxRaw.(int))
@@ -508,8 +536,11 @@ func (b *versionedKVBackend) pathMetadataWrite() framework.OperationFunc {
 		if cmOk {
 			meta.CustomMetadata = customMetadataMap
 		}
+		if rOk || rmOk {
+			meta.Reference = reference
+		}
 
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		err = b.writeKeyMetadata(ctx, req.Storage, meta, previous)
 		if err == nil {
 			// Commit our transaction if we created one! We're done making
 			// modifications to storage.
@@ -631,7 +662,7 @@ func (b *versionedKVBackend) pathMetadataPatch() framework.OperationFunc {
 			return nil, err
 		}
 
-		if err = b.writeKeyMetadata(ctx, req.Storage, patchedMetadata); err != nil {
+		if err = b.writeKeyMetadata(ctx, req.Storage, patchedMetadata, meta.CustomMetadata); err != nil {
 			return nil, err
 		}
 
@@ -689,16 +720,8 @@ func (b *versionedKVBackend) pathMetadataDelete() framework.OperationFunc {
 			}
 		}
 
-		// Get an encrypted key storage object
-		wrapper, err := b.getKeyEncryptor(ctx, req.Storage)
-		if err != nil {
-			return nil, err
-		}
-
-		es := wrapper.Wrap(req.Storage)
-
-		// Use encrypted key storage to delete the key
-		err = es.Delete(ctx, key)
+		// Delete the key metadata and its index entries
+		err = b.deleteKeyMetadata(ctx, req.Storage, meta)
 		if err == nil {
 			// Commit our transaction if we created one! We're done making
 			// modifications to storage.
@@ -708,6 +731,8 @@ func (b *versionedKVBackend) pathMetadataDelete() framework.OperationFunc {
 				}
 				req.Storage = originalStorage
 			}
+
+			b.sendKeyEvent(ctx, eventTypeMetadataDelete, "metadata/", key)
 		}
 		return nil, err
 	}
@@ -0,0 +1,234 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// maxReferenceDepth is the maximum number of references followed when reading
+// a secret, across all mounts.
+const maxReferenceDepth = 10
+
+// ctxKeyReferenceChain is the context key under which a read following a
+// reference to another mount passes the secrets resolved so far, so that
+// loops spanning several mounts are detected too.
+type ctxKeyReferenceChain struct{}
+
+// referenceTarget is where the references starting at a key lead to.
+type referenceTarget struct {
+	// key is the last key resolved within this mount.
+	key string
+
+	// remote is the reference from key to a secret of another mount, if any.
+	remote *SecretReference
+
+	// chain identifies each secret resolved so far, including those of other
+	// mounts.
+	chain []string
+
+	// followed is whether any reference was followed.
+	followed bool
+}
+
+// parseReference returns the reference described by the reference and
+// reference_mount fields of a metadata write, or nil if reference is empty.
+func parseReference(key string, data *framework.FieldData) (*SecretReference, error) {
+	reference := strings.Trim(data.Get("reference").(string), "/")
+	mount := strings.Trim(data.Get("reference_mount").(string), "/")
+
+	if reference == "" {
+		if mount != "" {
+			return nil, errors.New("reference_mount requires a reference")
+		}
+		return nil, nil
+	}
+	if mount == "" && reference == key {
+		return nil, errors.New("a secret cannot reference itself")
+	}
+
+	ref := &SecretReference{
+		Mount: mount,
+		Path:  reference,
+	}
+	if err := validateReference(ref); err != nil {
+		return nil, err
+	}
+
+	return ref, nil
+}
+
+// validateReference returns an error unless both the path and the mount of
+// the reference are valid key paths, so that neither can escape the data/
+// prefix of the referenced mount once joined to it.
+func validateReference(ref *SecretReference) error {
+	if err := validateKeyPath(ref.Path); err != nil {
+		return fmt.Errorf("invalid reference: %w", err)
+	}
+	if ref.Mount != "" {
+		if err := validateKeyPath(ref.Mount); err != nil {
+			return fmt.Errorf("invalid reference_mount: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateNotReference returns an error if the key described by meta is a
+// reference, as references have no data of their own.
+func validateNotReference(meta *KeyMetadata) error {
+	if meta.GetReference() != nil {
+		return fmt.Errorf("secret is a reference to %q; remove the reference to write data", referencePath(meta.Reference))
+	}
+	return nil
+}
+
+// referencePath returns the path of the secret the reference points to, with
+// the mount path included if it is in another mount.
+func referencePath(ref *SecretReference) string {
+	if ref.Mount == "" {
+		return ref.Path
+	}
+	return path.Join(ref.Mount, ref.Path)
+}
+
+// resolveReferences follows the references starting at key, as long as they
+// are within this mount. The client must be allowed to read the data of each
+// secret referenced. Errors which are the client's fault are returned as
+// errutil.UserError.
+func (b *versionedKVBackend) resolveReferences(ctx context.Context, req *logical.Request, key string) (*referenceTarget, error) {
+	target := &referenceTarget{key: key}
+
+	chain, _ := ctx.Value(ctxKeyReferenceChain{}).([]string)
+	if err := target.visit(chain, req.MountPoint+key); err != nil {
+		return nil, err
+	}
+
+	for {
+		lock := locksutil.LockForKey(b.locks, target.key)
+		lock.RLock()
+		meta, err := b.getKeyMetadata(ctx, req.Storage, target.key)
+		lock.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil || meta.Reference == nil {
+			return target, nil
+		}
+		if err := validateReference(meta.Reference); err != nil {
+			return nil, errutil.UserError{Err: err.Error()}
+		}
+
+		if len(target.chain) > maxReferenceDepth {
+			return nil, errutil.UserError{Err: fmt.Sprintf("more than %d references to follow", maxReferenceDepth)}
+		}
+		target.followed = true
+		if meta.Reference.Mount != "" {
+			target.remote = meta.Reference
+			return target, nil
+		}
+
+		sysView, ok := b.System().(logical.ExtendedSystemView)
+		if !ok {
+			return nil, errors.New("resolving references is not supported by this plugin environment")
+		}
+		allowed, err := sysView.CheckSubRequest(ctx, logical.ReadOperation, "data/"+meta.Reference.Path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, fmt.Errorf("permission denied on %q: %w", meta.Reference.Path, logical.ErrPermissionDenied)
+		}
+
+		if err := target.visit(target.chain, req.MountPoint+meta.Reference.Path); err != nil {
+			return nil, err
+		}
+		target.key = meta.Reference.Path
+	}
+}
+
+// visit appends the given secret to the chain, failing if it has already
+// been resolved.
+func (t *referenceTarget) visit(chain []string, id string) error {
+	for _, visited := range chain {
+		if visited == id {
+			return errutil.UserError{Err: fmt.Sprintf("reference loop detected at %q", id)}
+		}
+	}
+
+	t.chain = append(chain[:len(chain):len(chain)], id)
+	return nil
+}
+
+// readRemoteReference reads the secret of another mount the target refers
+// to, on behalf of the client, which must be allowed to read it.
+func (b *versionedKVBackend) readRemoteReference(ctx context.Context, target *referenceTarget, version int) (*logical.Response, error) {
+	sysView, ok := b.System().(logical.ExtendedSystemView)
+	if !ok {
+		return nil, errors.New("resolving references is not supported by this plugin environment")
+	}
+
+	if err := checkReferenceMount(ctx, sysView, target.remote.Mount); err != nil {
+		return nil, err
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      path.Join(target.remote.Mount, "data", target.remote.Path),
+		Data:      map[string]interface{}{},
+	}
+	if version > 0 {
+		req.Data["version"] = version
+	}
+
+	resp, err := sysView.HandleSubRequest(context.WithValue(ctx, ctxKeyReferenceChain{}, target.chain), req)
+	if err != nil {
+		return resp, err
+	}
+
+	// Report which secret was read, unless the other mount followed a
+	// reference to yet another mount and did so already.
+	if resp != nil && resp.Data != nil {
+		if metadata, ok := resp.Data["metadata"].(map[string]interface{}); ok {
+			if mount, _ := metadata["resolved_mount"].(string); mount == "" {
+				if resolved, _ := metadata["resolved_path"].(string); resolved == "" {
+					metadata["resolved_path"] = target.remote.Path
+				}
+				metadata["resolved_mount"] = target.remote.Mount
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// checkReferenceMount returns an error unless the given mount is a KV version
+// 2 mount, as only those resolve references and return secrets in the form
+// expected of a read. The client must have access to the mount.
+func checkReferenceMount(ctx context.Context, sysView logical.ExtendedSystemView, mount string) error {
+	resp, err := sysView.HandleSubRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "sys/internal/ui/mounts/" + mount,
+	})
+	if err != nil {
+		return err
+	}
+	if resp == nil || resp.IsError() {
+		return fmt.Errorf("cannot look up reference mount %q: %w", mount, logical.ErrPermissionDenied)
+	}
+
+	mountPath, _ := resp.Data["path"].(string)
+	mountType, _ := resp.Data["type"].(string)
+	options, _ := resp.Data["options"].(map[string]string)
+	if strings.Trim(mountPath, "/") != mount || mountType != "kv" || options["version"] != "2" {
+		return errutil.UserError{Err: fmt.Sprintf("reference mount %q is not a KV version 2 mount", mount)}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package kv
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestVersionedKV_Reference_Metadata(t *testing.T) {
+	b, storage := getBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "metadata/app/db",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"reference":       "shared/db",
+			"reference_mount": "/other/",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/app/db",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %v, resp %#v", err, resp)
+	}
+	if resp.Data["reference"] != "shared/db" || resp.Data["reference_mount"] != "other" {
+		t.Fatalf("unexpected reference: %#v", resp.Data)
+	}
+
+	// References have no data of their own.
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/app/db",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"password": "hunter2",
+			},
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !strings.Contains(resp.Error().Error(), "is a reference") {
+		t.Fatalf("expected write to reference to fail, err: %v, resp %#v", err, resp)
+	}
+
+	// Removing the reference allows writing data again.
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/app/db",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"reference": "",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata UpdateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/app/db",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"password": "hunter2",
+			},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Reference_Invalid(t *testing.T) {
+	b, storage := getBackend(t)
+
+	testCases := map[string]map[string]interface{}{
+		"self": {
+			"reference": "app/db",
+		},
+		"mount without reference": {
+			"reference_mount": "other",
+		},
+		"parent segment": {
+			"reference": "../config",
+		},
+		"parent segment in other mount": {
+			"reference":       "../../database/creds/x",
+			"reference_mount": "secret",
+		},
+		"dot segment": {
+			"reference": "app/./other",
+		},
+		"empty segment": {
+			"reference": "shared//db",
+		},
+		"parent segment in mount": {
+			"reference":       "creds/x",
+			"reference_mount": "secret/../database",
+		},
+	}
+
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "metadata/app/db",
+				Storage:   storage,
+				Data:      data,
+			})
+			if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+				t.Fatalf("expected invalid request, err: %v, resp %#v", err, resp)
+			}
+		})
+	}
+}
+
+func TestVersionedKV_Reference_InvalidStored(t *testing.T) {
+	b, storage := getBackend(t)
+
+	// References stored before they were validated are not followed.
+	meta := &KeyMetadata{
+		Key:       "app/db",
+		Versions:  map[uint64]*VersionMetadata{},
+		Reference: &SecretReference{Mount: "secret", Path: "../../database/creds/x"},
+	}
+	if err := b.(*versionedKVBackend).writeKeyMetadata(context.Background(), storage, meta, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/app/db",
+		Storage:   storage,
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !strings.Contains(resp.Error().Error(), "invalid reference") {
+		t.Fatalf("expected read of invalid reference to fail, err: %v, resp %#v", err, resp)
+	}
+}
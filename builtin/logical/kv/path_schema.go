@@ -0,0 +1,450 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/xeipuuv/gojsonreference"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// schemasPath is the location where the data schemas are stored.
+const schemasPath string = "schemas"
+
+// pathSchema returns the path configuration for CRUD operations on the data
+// schemas of key prefixes.
+func pathSchema(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "schema/" + framework.MatchAllRegex("prefix"),
+		Fields: map[string]*framework.FieldSchema{
+			"prefix": {
+				Type:        framework.TypeString,
+				Description: "Prefix of the keys whose data must satisfy the schema.",
+			},
+			"schema": {
+				Type:        framework.TypeString,
+				Description: "JSON Schema document which the data written to keys under the prefix must satisfy.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathSchemaWrite()),
+			},
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathSchemaWrite()),
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathSchemaRead()),
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathSchemaDelete()),
+			},
+		},
+
+		ExistenceCheck: b.schemaExistenceCheck(),
+
+		HelpSynopsis:    schemaHelpSyn,
+		HelpDescription: schemaHelpDesc,
+	}
+}
+
+// pathSchemaList returns the path configuration for listing the prefixes with
+// a data schema.
+func pathSchemaList(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "schema/?$",
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathSchemaListRead()),
+			},
+		},
+
+		HelpSynopsis:    schemaHelpSyn,
+		HelpDescription: schemaHelpDesc,
+	}
+}
+
+// pathSchemaCheck returns the path configuration for checking data against
+// the schemas of a key without writing it.
+func pathSchemaCheck(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "schema-check$",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret the data would be written to.",
+				Required:    true,
+			},
+			"data": {
+				Type:        framework.TypeMap,
+				Description: "The data to check.",
+				Required:    true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathSchemaCheckWrite()),
+			},
+		},
+
+		HelpSynopsis:    schemaCheckHelpSyn,
+		HelpDescription: schemaCheckHelpDesc,
+	}
+}
+
+func (b *versionedKVBackend) schemaExistenceCheck() framework.ExistenceFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+		schemas, err := b.schemas(ctx, req.Storage)
+		if err != nil {
+			return false, err
+		}
+
+		_, ok := schemas[data.Get("prefix").(string)]
+		return ok, nil
+	}
+}
+
+func (b *versionedKVBackend) pathSchemaRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		prefix := data.Get("prefix").(string)
+
+		schemas, err := b.schemas(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		schema, ok := schemas[prefix]
+		if !ok {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"prefix": prefix,
+				"schema": schema.document,
+			},
+		}, nil
+	}
+}
+
+func (b *versionedKVBackend) pathSchemaListRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		schemas, err := b.schemas(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		prefixes := make([]string, 0, len(schemas))
+		for prefix := range schemas {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+
+		return logical.ListResponse(prefixes), nil
+	}
+}
+
+func (b *versionedKVBackend) pathSchemaWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		prefix := data.Get("prefix").(string)
+		document := data.Get("schema").(string)
+		if document == "" {
+			return logical.ErrorResponse("missing schema"), logical.ErrInvalidRequest
+		}
+
+		if _, err := compileSchema(document); err != nil {
+			return logical.ErrorResponse("invalid schema: %s", err), logical.ErrInvalidRequest
+		}
+
+		return nil, b.updateSchemas(ctx, req.Storage, func(documents map[string]string) {
+			documents[prefix] = document
+		})
+	}
+}
+
+func (b *versionedKVBackend) pathSchemaDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		prefix := data.Get("prefix").(string)
+
+		return nil, b.updateSchemas(ctx, req.Storage, func(documents map[string]string) {
+			delete(documents, prefix)
+		})
+	}
+}
+
+func (b *versionedKVBackend) pathSchemaCheckWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := data.Get("path").(string)
+		if key == "" {
+			return logical.ErrorResponse("missing path"), logical.ErrInvalidRequest
+		}
+
+		dataRaw, ok := data.GetOk("data")
+		if !ok {
+			return logical.ErrorResponse("no data provided"), logical.ErrInvalidRequest
+		}
+		marshaledData, err := json.Marshal(dataRaw.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+
+		violations, prefixes, err := b.schemaViolations(ctx, req.Storage, key, marshaledData)
+		if err != nil {
+			return nil, err
+		}
+		if prefixes == nil {
+			prefixes = []string{}
+		}
+		if violations == nil {
+			violations = []string{}
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"valid":    len(violations) == 0,
+				"prefixes": prefixes,
+				"errors":   violations,
+			},
+		}, nil
+	}
+}
+
+// dataSchema is a compiled JSON Schema along with the document it was
+// compiled from.
+type dataSchema struct {
+	document string
+	schema   *gojsonschema.Schema
+}
+
+// violations returns a description of each way in which the JSON encoded
+// data fails to satisfy the schema.
+func (s *dataSchema) violations(data []byte) []string {
+	result, err := s.schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var violations []string
+	for _, resultErr := range result.Errors() {
+		violations = append(violations, resultErr.String())
+	}
+	return violations
+}
+
+// compileSchema compiles a JSON Schema document, which must not reference
+// any other document.
+func compileSchema(document string) (*dataSchema, error) {
+	loader := gojsonschema.NewSchemaLoader()
+	loader.Validate = true
+
+	schema, err := loader.Compile(localSchemaLoader{gojsonschema.NewStringLoader(document)})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dataSchema{
+		document: document,
+		schema:   schema,
+	}, nil
+}
+
+// localSchemaLoader loads a schema document while refusing to load the
+// documents it references, which would make the backend fetch arbitrary
+// URLs or files.
+type localSchemaLoader struct {
+	gojsonschema.JSONLoader
+}
+
+func (l localSchemaLoader) LoaderFactory() gojsonschema.JSONLoaderFactory {
+	return remoteSchemaLoaderFactory{}
+}
+
+type remoteSchemaLoaderFactory struct{}
+
+func (remoteSchemaLoaderFactory) New(source string) gojsonschema.JSONLoader {
+	return remoteSchemaLoader{source: source}
+}
+
+type remoteSchemaLoader struct {
+	source string
+}
+
+func (l remoteSchemaLoader) JsonSource() interface{} {
+	return l.source
+}
+
+func (l remoteSchemaLoader) LoadJSON() (interface{}, error) {
+	return nil, fmt.Errorf("references to other documents are not supported: %q", l.source)
+}
+
+func (l remoteSchemaLoader) JsonReference() (gojsonreference.JsonReference, error) {
+	return gojsonreference.NewJsonReference(l.source)
+}
+
+func (l remoteSchemaLoader) LoaderFactory() gojsonschema.JSONLoaderFactory {
+	return remoteSchemaLoaderFactory{}
+}
+
+// matchingSchemaPrefixes returns the sorted prefixes whose schema applies to
+// the given key.
+func matchingSchemaPrefixes(schemas map[string]*dataSchema, key string) []string {
+	var prefixes []string
+	for prefix := range schemas {
+		if strings.HasPrefix(key, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// schemas returns the compiled data schemas by prefix, loading them from
+// storage if they haven't been cached yet.
+func (b *versionedKVBackend) schemas(ctx context.Context, s logical.Storage) (map[string]*dataSchema, error) {
+	b.schemasLock.RLock()
+	if b.schemasCache != nil {
+		defer b.schemasLock.RUnlock()
+		return b.schemasCache, nil
+	}
+	b.schemasLock.RUnlock()
+
+	b.schemasLock.Lock()
+	defer b.schemasLock.Unlock()
+
+	// Verify this hasn't already changed
+	if b.schemasCache != nil {
+		return b.schemasCache, nil
+	}
+
+	documents, err := b.schemaDocuments(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[string]*dataSchema, len(documents))
+	for prefix, document := range documents {
+		schema, err := compileSchema(document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema for prefix %q: %w", prefix, err)
+		}
+		schemas[prefix] = schema
+	}
+
+	b.schemasCache = schemas
+
+	return schemas, nil
+}
+
+// schemaDocuments reads the data schema documents by prefix from storage.
+func (b *versionedKVBackend) schemaDocuments(ctx context.Context, s logical.Storage) (map[string]string, error) {
+	raw, err := s.Get(ctx, path.Join(b.storagePrefix, schemasPath))
+	if err != nil {
+		return nil, err
+	}
+
+	documents := map[string]string{}
+	if raw != nil {
+		if err := raw.DecodeJSON(&documents); err != nil {
+			return nil, err
+		}
+	}
+
+	return documents, nil
+}
+
+// updateSchemas applies the given update to the stored data schema documents
+// and clears the cached schemas.
+func (b *versionedKVBackend) updateSchemas(ctx context.Context, s logical.Storage, update func(map[string]string)) error {
+	b.schemasLock.Lock()
+	defer b.schemasLock.Unlock()
+
+	// Create a transaction if we can.
+	if txnStorage, ok := s.(logical.TransactionalStorage); ok {
+		txn, err := txnStorage.BeginTx(ctx)
+		if err != nil {
+			return err
+		}
+
+		defer txn.Rollback(ctx)
+		s = txn
+	}
+
+	documents, err := b.schemaDocuments(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	update(documents)
+
+	entry, err := logical.StorageEntryJSON(path.Join(b.storagePrefix, schemasPath), documents)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return err
+	}
+
+	// Commit our transaction if we created one! We're done making
+	// modifications to storage.
+	if txn, ok := s.(logical.Transaction); ok {
+		if err := txn.Commit(ctx); err != nil {
+			return err
+		}
+	}
+
+	b.schemasCache = nil
+
+	return nil
+}
+
+// schemaViolations returns a description of each way in which the JSON
+// encoded data fails to satisfy the schemas applying to the given key, along
+// with the prefixes of these schemas.
+func (b *versionedKVBackend) schemaViolations(ctx context.Context, s logical.Storage, key string, data []byte) ([]string, []string, error) {
+	schemas, err := b.schemas(ctx, s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prefixes := matchingSchemaPrefixes(schemas, key)
+	var violations []string
+	for _, prefix := range prefixes {
+		for _, violation := range schemas[prefix].violations(data) {
+			violations = append(violations, fmt.Sprintf("%s: %s", prefix, violation))
+		}
+	}
+
+	return violations, prefixes, nil
+}
+
+// schemaViolationsError formats schema violations for an error response.
+func schemaViolationsError(violations []string) string {
+	return "data does not satisfy the schemas of the secret: " + strings.Join(violations, "; ")
+}
+
+const (
+	schemaHelpSyn  = `Configure the JSON Schema which data under a key prefix must satisfy.`
+	schemaHelpDesc = `
+This path takes a key prefix and a JSON Schema document. The data of every
+write or patch to a key starting with the prefix must satisfy the schema, or
+the request fails listing the violations. When several prefixes match a key,
+the data must satisfy all of their schemas. Use a prefix ending with "/" to
+only match the keys within a directory.
+
+Schemas may not reference other documents. Existing secrets are not checked
+when a schema is written.
+`
+
+	schemaCheckHelpSyn  = `Check data against the JSON Schemas of a key without writing it.`
+	schemaCheckHelpDesc = `
+This path takes the path of a secret and data, and reports whether the data
+satisfies the JSON Schemas configured for the prefixes of the path, listing
+the violations if not. Nothing is written.
+`
+)
@@ -0,0 +1,276 @@
+package kv
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const testDatabaseSchema = `{
+	"type": "object",
+	"properties": {
+		"username": {"type": "string"},
+		"password": {"$ref": "#/definitions/password"}
+	},
+	"required": ["username", "password"],
+	"additionalProperties": false,
+	"definitions": {
+		"password": {"type": "string", "minLength": 8}
+	}
+}`
+
+func TestVersionedKV_Schema_CRUD(t *testing.T) {
+	b, storage := getBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "schema/db/",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"schema": testDatabaseSchema,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("schema CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "schema/db/",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("schema ReadOperation request failed, err: %v, resp %#v", err, resp)
+	}
+	if resp.Data["prefix"] != "db/" || resp.Data["schema"] != testDatabaseSchema {
+		t.Fatalf("unexpected schema: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "schema/",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("schema ListOperation request failed, err: %v, resp %#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"db/"}) {
+		t.Fatalf("unexpected keys: %#v", resp.Data["keys"])
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "schema/db/",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("schema DeleteOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "schema/db/",
+		Storage:   storage,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("expected deleted schema, err: %v, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Schema_Invalid(t *testing.T) {
+	b, storage := getBackend(t)
+
+	testCases := map[string]string{
+		"not json":        `{"type": `,
+		"invalid keyword": `{"type": "no-such-type"}`,
+		"remote ref":      `{"$ref": "https://example.com/schema.json"}`,
+		"file ref":        `{"properties": {"a": {"$ref": "file:///etc/passwd"}}}`,
+	}
+
+	for name, schema := range testCases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "schema/db/",
+				Storage:   storage,
+				Data: map[string]interface{}{
+					"schema": schema,
+				},
+			})
+			if err != logical.ErrInvalidRequest || resp == nil || !strings.HasPrefix(resp.Error().Error(), "invalid schema") {
+				t.Fatalf("expected invalid schema, err: %v, resp %#v", err, resp)
+			}
+		})
+	}
+}
+
+func TestVersionedKV_Schema_Enforced(t *testing.T) {
+	b, storage := getBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "schema/db/",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"schema": testDatabaseSchema,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("schema CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	// Writes violating the schema are rejected, listing all violations.
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/db/app",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"username": "app",
+				"db_pass":  "hunter22",
+			},
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil {
+		t.Fatalf("expected invalid request, err: %v, resp %#v", err, resp)
+	}
+	for _, violation := range []string{"password is required", "Additional property db_pass is not allowed"} {
+		if !strings.Contains(resp.Error().Error(), violation) {
+			t.Fatalf("expected %q in error: %v", violation, resp.Error())
+		}
+	}
+
+	// Keys outside of the prefix are not affected.
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/dbx/app",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"db_pass": "hunter22",
+			},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/db/app",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"username": "app",
+				"password": "hunter22",
+			},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	// Patches are checked against the patched data.
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "data/db/app",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"password": "short",
+			},
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !strings.Contains(resp.Error().Error(), "String length must be greater than or equal to 8") {
+		t.Fatalf("expected invalid request, err: %v, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "data/db/app",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"password": "correct horse",
+			},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data PatchOperation request failed, err: %v, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_SchemaCheck(t *testing.T) {
+	b, storage := getBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "schema/db/",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"schema": testDatabaseSchema,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("schema CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "schema-check",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"path": "db/app",
+			"data": map[string]interface{}{
+				"username": "app",
+			},
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("schema-check request failed, err: %v, resp %#v", err, resp)
+	}
+	expected := map[string]interface{}{
+		"valid":    false,
+		"prefixes": []string{"db/"},
+		"errors":   []string{"db/: (root): password is required"},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("unexpected response: %#v", resp.Data)
+	}
+
+	// Nothing was written.
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/db/app",
+		Storage:   storage,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("expected no secret, err: %v, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "schema-check",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"path": "other/app",
+			"data": map[string]interface{}{
+				"db_pass": "hunter22",
+			},
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("schema-check request failed, err: %v, resp %#v", err, resp)
+	}
+	expected = map[string]interface{}{
+		"valid":    true,
+		"prefixes": []string{},
+		"errors":   []string{},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("unexpected response: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,489 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"github.com/openbao/openbao/sdk/v2/helper/keysutil"
+	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
+	"github.com/openbao/openbao/sdk/v2/helper/pluginutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	// indexPrefix is the prefix where the index of secrets by custom metadata
+	// is stored.
+	indexPrefix string = "index/"
+
+	// indexStatusPath is the path recording that secrets written before the
+	// index was introduced have been indexed.
+	indexStatusPath string = "index-status"
+)
+
+// pathSearch returns the path configuration for searching secrets by their
+// metadata.
+func pathSearch(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "search$",
+		Fields: map[string]*framework.FieldSchema{
+			"custom_metadata": {
+				Type:        framework.TypeMap,
+				Description: "Custom metadata key-value pairs which must all be set on matching secrets.",
+			},
+			"min_version_age": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Minimum time since the current version of matching secrets was written.",
+			},
+			"max_version_age": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum time since the current version of matching secrets was written.",
+			},
+			"updated_after": {
+				Type:        framework.TypeTime,
+				Description: "Matching secrets must have been last updated after this time.",
+			},
+			"updated_before": {
+				Type:        framework.TypeTime,
+				Description: "Matching secrets must have been last updated before this time.",
+			},
+			"after": {
+				Type:        framework.TypeString,
+				Description: `Optional entry to begin listing after, not required to exist.`,
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Description: `Optional number of entries to return; defaults to all entries.`,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathSearchWrite()),
+			},
+		},
+
+		HelpSynopsis:    searchHelpSyn,
+		HelpDescription: searchHelpDesc,
+	}
+}
+
+// searchFilter holds the criteria secrets must match to be returned by a
+// search, besides their custom metadata.
+type searchFilter struct {
+	now           time.Time
+	minVersionAge time.Duration
+	maxVersionAge time.Duration
+	updatedAfter  time.Time
+	updatedBefore time.Time
+}
+
+// pathSearchWrite returns the paths of the secrets matching the given
+// criteria, in order, which the client is allowed to list.
+func (b *versionedKVBackend) pathSearchWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		after := data.Get("after").(string)
+		limit := data.Get("limit").(int)
+		if limit <= 0 {
+			limit = -1
+		}
+
+		customMetadata, err := parseCustomMetadata(data.Get("custom_metadata").(map[string]interface{}), false)
+		if err != nil {
+			return logical.ErrorResponse("%s: %s", customMetadataValidationErrorPrefix, err.Error()), logical.ErrInvalidRequest
+		}
+
+		filter := &searchFilter{
+			now:           time.Now(),
+			minVersionAge: time.Duration(data.Get("min_version_age").(int)) * time.Second,
+			maxVersionAge: time.Duration(data.Get("max_version_age").(int)) * time.Second,
+			updatedAfter:  data.Get("updated_after").(time.Time),
+			updatedBefore: data.Get("updated_before").(time.Time),
+		}
+		if filter.minVersionAge < 0 || filter.maxVersionAge < 0 {
+			return logical.ErrorResponse("version ages cannot be negative"), logical.ErrInvalidRequest
+		}
+
+		sysView, ok := b.System().(logical.ExtendedSystemView)
+		if !ok {
+			return nil, errors.New("searching secrets is not supported by this plugin environment")
+		}
+
+		if len(customMetadata) > 0 && atomic.LoadUint32(b.indexing) == 1 {
+			// As when upgrading, give the index a chance to complete on
+			// mounts which were just enabled.
+			time.Sleep(15 * time.Millisecond)
+
+			if atomic.LoadUint32(b.indexing) == 1 {
+				return logical.ErrorResponse("Indexing secrets by custom metadata. Searching by custom metadata will be available shortly."), logical.ErrInvalidRequest
+			}
+		}
+
+		// Create a read-only transaction if we can. We do not need to commit
+		// this as we're not writing to storage.
+		if txnStorage, ok := req.Storage.(logical.TransactionalStorage); ok {
+			txn, err := txnStorage.BeginReadOnlyTx(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			defer txn.Rollback(ctx)
+			req.Storage = txn
+		}
+
+		candidates, err := b.searchCandidates(ctx, req.Storage, customMetadata)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := []string{}
+		listable := map[string]bool{}
+		for _, key := range candidates {
+			if limit > 0 && len(keys) >= limit {
+				break
+			}
+			if key <= after {
+				continue
+			}
+
+			meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+			if err != nil {
+				return nil, err
+			}
+			if meta == nil || !filter.matches(meta, customMetadata) {
+				continue
+			}
+
+			// Only return secrets the client would see listing their
+			// directory.
+			dir := strings.TrimSuffix(key, path.Base(key))
+			allowed, ok := listable[dir]
+			if !ok {
+				allowed, err = sysView.CheckSubRequest(ctx, logical.ListOperation, "metadata/"+dir, nil)
+				if err != nil {
+					return nil, err
+				}
+				listable[dir] = allowed
+			}
+			if allowed {
+				keys = append(keys, key)
+			}
+		}
+
+		return logical.ListResponse(keys), nil
+	}
+}
+
+// searchCandidates returns the keys of the secrets having all of the given
+// custom metadata according to the index, or of all secrets if none is
+// given, sorted.
+func (b *versionedKVBackend) searchCandidates(ctx context.Context, s logical.Storage, customMetadata map[string]string) ([]string, error) {
+	if len(customMetadata) == 0 {
+		wrapper, err := b.getKeyEncryptor(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+
+		keys, err := listKeysRecursive(ctx, wrapper.Wrap(s), "")
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(keys)
+		return keys, nil
+	}
+
+	wrapper, err := b.getIndexEncryptor(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	is := wrapper.Wrap(s)
+
+	var candidates map[string]bool
+	for k, v := range customMetadata {
+		prefix := indexEntryPrefix(k, v)
+		entries, err := listKeysRecursive(ctx, is, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		matching := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			key := strings.TrimPrefix(entry, prefix)
+			if candidates == nil || candidates[key] {
+				matching[key] = true
+			}
+		}
+		candidates = matching
+	}
+
+	keys := make([]string, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// matches returns whether the secret described by meta has the given custom
+// metadata and matches the filter.
+func (f *searchFilter) matches(meta *KeyMetadata, customMetadata map[string]string) bool {
+	for k, v := range customMetadata {
+		if value, ok := meta.CustomMetadata[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	if !f.updatedAfter.IsZero() || !f.updatedBefore.IsZero() {
+		updated, err := ptypes.Timestamp(meta.UpdatedTime)
+		if err != nil {
+			return false
+		}
+		if !f.updatedAfter.IsZero() && !updated.After(f.updatedAfter) {
+			return false
+		}
+		if !f.updatedBefore.IsZero() && !updated.Before(f.updatedBefore) {
+			return false
+		}
+	}
+
+	if f.minVersionAge > 0 || f.maxVersionAge > 0 {
+		vm := meta.Versions[meta.CurrentVersion]
+		if vm == nil {
+			return false
+		}
+		created, err := ptypes.Timestamp(vm.CreatedTime)
+		if err != nil {
+			return false
+		}
+		age := f.now.Sub(created)
+		if f.minVersionAge > 0 && age < f.minVersionAge {
+			return false
+		}
+		if f.maxVersionAge > 0 && age > f.maxVersionAge {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getIndexEncryptor returns the storage wrapper encrypting the paths of the
+// index entries, which contain custom metadata and keys.
+func (b *versionedKVBackend) getIndexEncryptor(ctx context.Context, s logical.Storage) (*keysutil.EncryptedKeyStorageWrapper, error) {
+	b.l.RLock()
+	if b.indexEncryptedWrapper != nil {
+		defer b.l.RUnlock()
+		return b.indexEncryptedWrapper, nil
+	}
+	b.l.RUnlock()
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.indexEncryptedWrapper != nil {
+		return b.indexEncryptedWrapper, nil
+	}
+
+	policy, err := b.policy(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := keysutil.NewEncryptedKeyStorageWrapper(keysutil.EncryptedKeyStorageConfig{
+		Policy: policy,
+		Prefix: path.Join(b.storagePrefix, indexPrefix),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the value
+	b.indexEncryptedWrapper = e
+
+	return b.indexEncryptedWrapper, nil
+}
+
+// indexEntryPrefix returns the prefix of the index entries of the secrets
+// having the given custom metadata. The key and value are encoded as they may
+// contain slashes.
+func indexEntryPrefix(k, v string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(k)) + "/" + base64.RawURLEncoding.EncodeToString([]byte(v)) + "/"
+}
+
+// updateSearchIndex updates the index entries of the given key after its
+// custom metadata changed from previous to current.
+func (b *versionedKVBackend) updateSearchIndex(ctx context.Context, s logical.Storage, key string, previous, current map[string]string) error {
+	if len(previous) == 0 && len(current) == 0 {
+		return nil
+	}
+
+	wrapper, err := b.getIndexEncryptor(ctx, s)
+	if err != nil {
+		return err
+	}
+	is := wrapper.Wrap(s)
+
+	for k, v := range previous {
+		if value, ok := current[k]; ok && value == v {
+			continue
+		}
+		if err := is.Delete(ctx, indexEntryPrefix(k, v)+key); err != nil {
+			return fmt.Errorf("failed to remove index entry: %w", err)
+		}
+	}
+	for k, v := range current {
+		if value, ok := previous[k]; ok && value == v {
+			continue
+		}
+		if err := is.Put(ctx, &logical.StorageEntry{
+			Key:   indexEntryPrefix(k, v) + key,
+			Value: []byte{},
+		}); err != nil {
+			return fmt.Errorf("failed to write index entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// buildSearchIndex indexes the secrets written before the index was
+// introduced, once, in the background as upgrading does. Secrets written since
+// are indexed as they are written. Searches by custom metadata are refused
+// until the index is complete.
+func (b *versionedKVBackend) buildSearchIndex(ctx context.Context, s logical.Storage) error {
+	// Don't run if the plugin is in metadata mode or on a DR secondary.
+	if pluginutil.InMetadataMode() || b.System().ReplicationState().HasState(consts.ReplicationDRSecondary) {
+		return nil
+	}
+
+	statusPath := path.Join(b.storagePrefix, indexStatusPath)
+	status, err := s.Get(ctx, statusPath)
+	if err != nil {
+		return err
+	}
+	if status != nil {
+		return nil
+	}
+
+	if !atomic.CompareAndSwapUint32(b.indexing, 0, 1) {
+		return errors.New("indexing already in process")
+	}
+
+	// If we are a replication secondary or performance standby, wait until
+	// the primary has finished indexing.
+	if b.perfSecondaryCheck() {
+		b.Logger().Info("indexing not running on performance replication secondary or performance standby")
+
+		go func() {
+			for {
+				time.Sleep(time.Second)
+
+				if ctx.Err() != nil {
+					atomic.StoreUint32(b.indexing, 0)
+					return
+				}
+
+				status, err := s.Get(ctx, statusPath)
+				if err != nil {
+					b.Logger().Error("checking index status resulted in error", "error", err)
+				}
+				if status != nil {
+					break
+				}
+			}
+
+			atomic.StoreUint32(b.indexing, 0)
+		}()
+
+		return nil
+	}
+
+	// Because this is a long running process we need a new context.
+	ctx = context.Background()
+
+	go func() {
+		for {
+			err := b.indexExistingKeys(ctx, s, statusPath)
+			switch {
+			case err == nil:
+				atomic.StoreUint32(b.indexing, 0)
+				return
+			case err.Error() == logical.ErrSetupReadOnly.Error():
+				time.Sleep(10 * time.Millisecond)
+			default:
+				b.Logger().Error("indexing resulted in error", "error", err)
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// indexExistingKeys writes the index entries of all keys, then records that
+// the index is complete.
+func (b *versionedKVBackend) indexExistingKeys(ctx context.Context, s logical.Storage, statusPath string) error {
+	wrapper, err := b.getKeyEncryptor(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	keys, err := listKeysRecursive(ctx, wrapper.Wrap(s), "")
+	if err != nil {
+		return err
+	}
+
+	b.Logger().Info("indexing keys", "num_keys", len(keys))
+	for i, key := range keys {
+		if b.Logger().IsDebug() && i%500 == 0 {
+			b.Logger().Debug("indexing keys", "progress", fmt.Sprintf("%d/%d", i, len(keys)))
+		}
+		if err := b.indexKey(ctx, s, key); err != nil {
+			return err
+		}
+	}
+	b.Logger().Info("indexing keys finished")
+
+	return s.Put(ctx, &logical.StorageEntry{
+		Key:   statusPath,
+		Value: []byte("done"),
+	})
+}
+
+// indexKey writes the index entries of the given key.
+func (b *versionedKVBackend) indexKey(ctx context.Context, s logical.Storage, key string) error {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		return nil
+	}
+
+	return b.updateSearchIndex(ctx, s, key, nil, meta.CustomMetadata)
+}
+
+const (
+	searchHelpSyn  = `Search secrets by their metadata.`
+	searchHelpDesc = `
+This path returns the paths of the secrets having all of the given
+"custom_metadata" key-value pairs, whose current version was written within
+"min_version_age" and "max_version_age" ago, and which were last updated
+between "updated_after" and "updated_before". All criteria are optional.
+
+Only secrets the token is allowed to list the directory of are returned. The
+results are sorted and can be paginated with "after" and "limit", as when
+listing secrets.
+`
+)
@@ -0,0 +1,202 @@
+package kv
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/openbao/openbao/sdk/v2/helper/logging"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestVersionedKV_SearchIndex(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+
+	writeMetadata := func(key string, customMetadata map[string]interface{}) {
+		t.Helper()
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "metadata/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"custom_metadata": customMetadata,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("metadata CreateOperation request failed, err: %v, resp %#v", err, resp)
+		}
+	}
+	assertCandidates := func(customMetadata map[string]string, expected []string) {
+		t.Helper()
+
+		keys, err := kv.searchCandidates(context.Background(), storage, customMetadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("expected %v for %v, got %v", expected, customMetadata, keys)
+		}
+	}
+
+	writeMetadata("payments/db", map[string]interface{}{"owner": "payments", "env": "prod"})
+	writeMetadata("payments/api", map[string]interface{}{"owner": "payments", "env": "dev/1"})
+	writeMetadata("search/db", map[string]interface{}{"owner": "search", "env": "prod"})
+
+	assertCandidates(map[string]string{"owner": "payments"}, []string{"payments/api", "payments/db"})
+	assertCandidates(map[string]string{"env": "prod"}, []string{"payments/db", "search/db"})
+	assertCandidates(map[string]string{"owner": "payments", "env": "prod"}, []string{"payments/db"})
+	assertCandidates(map[string]string{"env": "dev/1"}, []string{"payments/api"})
+	assertCandidates(map[string]string{"owner": "nobody"}, []string{})
+
+	// Changed metadata replaces the previous index entries.
+	writeMetadata("payments/db", map[string]interface{}{"owner": "search"})
+	assertCandidates(map[string]string{"owner": "payments"}, []string{"payments/api"})
+	assertCandidates(map[string]string{"owner": "search"}, []string{"payments/db", "search/db"})
+	assertCandidates(map[string]string{"env": "prod"}, []string{"search/db"})
+
+	// Deleted secrets are removed from the index.
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "metadata/search/db",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata DeleteOperation request failed, err: %v, resp %#v", err, resp)
+	}
+	assertCandidates(map[string]string{"owner": "search"}, []string{"payments/db"})
+
+	// Without custom metadata, all secrets are candidates.
+	assertCandidates(nil, []string{"payments/api", "payments/db"})
+}
+
+func TestVersionedKV_SearchIndexBuild(t *testing.T) {
+	b, storage := getBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "metadata/payments/db",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"custom_metadata": map[string]interface{}{"owner": "payments"},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	// Forget the index, as if the secret had been written before it was
+	// introduced.
+	if err := logical.ClearView(context.Background(), logical.NewStorageView(storage, "test/"+indexPrefix)); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Delete(context.Background(), "test/"+indexStatusPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// The index is built in the background when the backend is set up.
+	b, err = VersionedKVFactory(context.Background(), &logical.BackendConfig{
+		Logger:      logging.NewVaultLogger(log.Trace),
+		System:      &logical.StaticSystemView{},
+		StorageView: storage,
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := b.(*versionedKVBackend)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for atomic.LoadUint32(kv.indexing) == 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout expired waiting for the index")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	keys, err := kv.searchCandidates(context.Background(), storage, map[string]string{"owner": "payments"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"payments/db"}) {
+		t.Fatalf("expected the existing secret to be indexed, got %v", keys)
+	}
+
+	status, err := storage.Get(context.Background(), "test/"+indexStatusPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == nil {
+		t.Fatal("expected the index to be recorded as complete")
+	}
+}
+
+func TestVersionedKV_SearchFilter(t *testing.T) {
+	now := time.Now()
+	timestamp := func(d time.Duration) *VersionMetadata {
+		ts, err := ptypes.TimestampProto(now.Add(-d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &VersionMetadata{CreatedTime: ts}
+	}
+
+	meta := &KeyMetadata{
+		Key:            "app/db",
+		CurrentVersion: 2,
+		Versions: map[uint64]*VersionMetadata{
+			1: timestamp(48 * time.Hour),
+			2: timestamp(24 * time.Hour),
+		},
+		UpdatedTime:    timestamp(time.Hour).CreatedTime,
+		CustomMetadata: map[string]string{"owner": "payments"},
+	}
+
+	testCases := map[string]struct {
+		filter         searchFilter
+		customMetadata map[string]string
+		matches        bool
+	}{
+		"no criteria": {
+			matches: true,
+		},
+		"custom metadata": {
+			customMetadata: map[string]string{"owner": "payments"},
+			matches:        true,
+		},
+		"other custom metadata": {
+			customMetadata: map[string]string{"owner": "search"},
+		},
+		"old enough version": {
+			filter:  searchFilter{minVersionAge: 12 * time.Hour},
+			matches: true,
+		},
+		"too recent version": {
+			filter: searchFilter{minVersionAge: 36 * time.Hour},
+		},
+		"too old version": {
+			filter: searchFilter{maxVersionAge: 12 * time.Hour},
+		},
+		"updated within range": {
+			filter:  searchFilter{updatedAfter: now.Add(-2 * time.Hour), updatedBefore: now},
+			matches: true,
+		},
+		"updated before range": {
+			filter: searchFilter{updatedAfter: now.Add(-30 * time.Minute)},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.filter.now = now
+			if matches := tc.filter.matches(meta, tc.customMetadata); matches != tc.matches {
+				t.Fatalf("expected match to be %v", tc.matches)
+			}
+		})
+	}
+}
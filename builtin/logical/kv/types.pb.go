@@ -188,6 +188,9 @@ type KeyMetadata struct {
 	// CustomMetadata is a map of string key-value pairs used to store
 	// user-provided information about the secret.
 	CustomMetadata map[string]string `protobuf:"bytes,10,rep,name=custom_metadata,json=customMetadata,proto3" json:"custom_metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Reference, if set, makes this key a reference to another secret,
+	// which reads of the key's data resolve to.
+	Reference *SecretReference `protobuf:"bytes,11,opt,name=reference,proto3" json:"reference,omitempty"`
 }
 
 func (x *KeyMetadata) Reset() {
@@ -292,6 +295,71 @@ func (x *KeyMetadata) GetCustomMetadata() map[string]string {
 	return nil
 }
 
+func (x *KeyMetadata) GetReference() *SecretReference {
+	if x != nil {
+		return x.Reference
+	}
+	return nil
+}
+
+type SecretReference struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Mount is the path of the mount containing the referenced secret. If
+	// empty, the secret is in the same mount as the reference.
+	Mount string `protobuf:"bytes,1,opt,name=mount,proto3" json:"mount,omitempty"`
+	// Path is the path of the referenced secret within its mount.
+	Path string `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+}
+
+func (x *SecretReference) Reset() {
+	*x = SecretReference{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_builtin_logical_kv_types_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SecretReference) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretReference) ProtoMessage() {}
+
+func (x *SecretReference) ProtoReflect() protoreflect.Message {
+	mi := &file_builtin_logical_kv_types_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretReference.ProtoReflect.Descriptor instead.
+func (*SecretReference) Descriptor() ([]byte, []int) {
+	return file_builtin_logical_kv_types_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SecretReference) GetMount() string {
+	if x != nil {
+		return x.Mount
+	}
+	return ""
+}
+
+func (x *SecretReference) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
 type Version struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -311,7 +379,7 @@ type Version struct {
 func (x *Version) Reset() {
 	*x = Version{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_builtin_logical_kv_types_proto_msgTypes[3]
+		mi := &file_builtin_logical_kv_types_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -324,7 +392,7 @@ func (x *Version) String() string {
 func (*Version) ProtoMessage() {}
 
 func (x *Version) ProtoReflect() protoreflect.Message {
-	mi := &file_builtin_logical_kv_types_proto_msgTypes[3]
+	mi := &file_builtin_logical_kv_types_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -337,7 +405,7 @@ func (x *Version) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Version.ProtoReflect.Descriptor instead.
 func (*Version) Descriptor() ([]byte, []int) {
-	return file_builtin_logical_kv_types_proto_rawDescGZIP(), []int{3}
+	return file_builtin_logical_kv_types_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Version) GetData() []byte {
@@ -376,7 +444,7 @@ type UpgradeInfo struct {
 func (x *UpgradeInfo) Reset() {
 	*x = UpgradeInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_builtin_logical_kv_types_proto_msgTypes[4]
+		mi := &file_builtin_logical_kv_types_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -389,7 +457,7 @@ func (x *UpgradeInfo) String() string {
 func (*UpgradeInfo) ProtoMessage() {}
 
 func (x *UpgradeInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_builtin_logical_kv_types_proto_msgTypes[4]
+	mi := &file_builtin_logical_kv_types_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -402,7 +470,7 @@ func (x *UpgradeInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpgradeInfo.ProtoReflect.Descriptor instead.
 func (*UpgradeInfo) Descriptor() ([]byte, []int) {
-	return file_builtin_logical_kv_types_proto_rawDescGZIP(), []int{4}
+	return file_builtin_logical_kv_types_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *UpgradeInfo) GetStartedTime() *timestamppb.Timestamp {
@@ -449,7 +517,7 @@ var file_builtin_logical_kv_types_proto_rawDesc = []byte{
 	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
 	0x52, 0x0c, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1c,
 	0x0a, 0x09, 0x64, 0x65, 0x73, 0x74, 0x72, 0x6f, 0x79, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
-	0x08, 0x52, 0x09, 0x64, 0x65, 0x73, 0x74, 0x72, 0x6f, 0x79, 0x65, 0x64, 0x22, 0x9e, 0x05, 0x0a,
+	0x08, 0x52, 0x09, 0x64, 0x65, 0x73, 0x74, 0x72, 0x6f, 0x79, 0x65, 0x64, 0x22, 0xd1, 0x05, 0x0a,
 	0x0b, 0x4b, 0x65, 0x79, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x10, 0x0a, 0x03,
 	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x39,
 	0x0a, 0x08, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
@@ -482,16 +550,23 @@ var file_builtin_logical_kv_types_proto_rawDesc = []byte{
 	0x03, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x6b, 0x76, 0x2e, 0x4b, 0x65, 0x79, 0x4d, 0x65, 0x74, 0x61,
 	0x64, 0x61, 0x74, 0x61, 0x2e, 0x43, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x4d, 0x65, 0x74, 0x61, 0x64,
 	0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0e, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d,
-	0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x1a, 0x50, 0x0a, 0x0d, 0x56, 0x65, 0x72, 0x73,
-	0x69, 0x6f, 0x6e, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x29, 0x0a, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6b, 0x76, 0x2e,
-	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52,
-	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x41, 0x0a, 0x13, 0x43, 0x75,
-	0x73, 0x74, 0x6f, 0x6d, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72,
-	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
-	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x9d, 0x01,
+	0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x31, 0x0a, 0x09, 0x72, 0x65, 0x66, 0x65,
+	0x72, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x6b, 0x76,
+	0x2e, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65,
+	0x52, 0x09, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x1a, 0x50, 0x0a, 0x0d, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x29,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e,
+	0x6b, 0x76, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61,
+	0x74, 0x61, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x41, 0x0a,
+	0x13, 0x43, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01,
+	0x22, 0x3b, 0x0a, 0x0f, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65,
+	0x6e, 0x63, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74,
+	0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x22, 0x9d, 0x01,
 	0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74,
 	0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x3d, 0x0a,
 	0x0c, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20,
@@ -526,36 +601,38 @@ func file_builtin_logical_kv_types_proto_rawDescGZIP() []byte {
 	return file_builtin_logical_kv_types_proto_rawDescData
 }
 
-var file_builtin_logical_kv_types_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_builtin_logical_kv_types_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_builtin_logical_kv_types_proto_goTypes = []interface{}{
 	(*Configuration)(nil),         // 0: kv.Configuration
 	(*VersionMetadata)(nil),       // 1: kv.VersionMetadata
 	(*KeyMetadata)(nil),           // 2: kv.KeyMetadata
-	(*Version)(nil),               // 3: kv.Version
-	(*UpgradeInfo)(nil),           // 4: kv.UpgradeInfo
-	nil,                           // 5: kv.KeyMetadata.VersionsEntry
-	nil,                           // 6: kv.KeyMetadata.CustomMetadataEntry
-	(*durationpb.Duration)(nil),   // 7: google.protobuf.Duration
-	(*timestamppb.Timestamp)(nil), // 8: google.protobuf.Timestamp
+	(*SecretReference)(nil),       // 3: kv.SecretReference
+	(*Version)(nil),               // 4: kv.Version
+	(*UpgradeInfo)(nil),           // 5: kv.UpgradeInfo
+	nil,                           // 6: kv.KeyMetadata.VersionsEntry
+	nil,                           // 7: kv.KeyMetadata.CustomMetadataEntry
+	(*durationpb.Duration)(nil),   // 8: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
 }
 var file_builtin_logical_kv_types_proto_depIdxs = []int32{
-	7,  // 0: kv.Configuration.delete_version_after:type_name -> google.protobuf.Duration
-	8,  // 1: kv.VersionMetadata.created_time:type_name -> google.protobuf.Timestamp
-	8,  // 2: kv.VersionMetadata.deletion_time:type_name -> google.protobuf.Timestamp
-	5,  // 3: kv.KeyMetadata.versions:type_name -> kv.KeyMetadata.VersionsEntry
-	8,  // 4: kv.KeyMetadata.created_time:type_name -> google.protobuf.Timestamp
-	8,  // 5: kv.KeyMetadata.updated_time:type_name -> google.protobuf.Timestamp
-	7,  // 6: kv.KeyMetadata.delete_version_after:type_name -> google.protobuf.Duration
-	6,  // 7: kv.KeyMetadata.custom_metadata:type_name -> kv.KeyMetadata.CustomMetadataEntry
-	8,  // 8: kv.Version.created_time:type_name -> google.protobuf.Timestamp
-	8,  // 9: kv.Version.deletion_time:type_name -> google.protobuf.Timestamp
-	8,  // 10: kv.UpgradeInfo.started_time:type_name -> google.protobuf.Timestamp
-	1,  // 11: kv.KeyMetadata.VersionsEntry.value:type_name -> kv.VersionMetadata
-	12, // [12:12] is the sub-list for method output_type
-	12, // [12:12] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	8,  // 0: kv.Configuration.delete_version_after:type_name -> google.protobuf.Duration
+	9,  // 1: kv.VersionMetadata.created_time:type_name -> google.protobuf.Timestamp
+	9,  // 2: kv.VersionMetadata.deletion_time:type_name -> google.protobuf.Timestamp
+	6,  // 3: kv.KeyMetadata.versions:type_name -> kv.KeyMetadata.VersionsEntry
+	9,  // 4: kv.KeyMetadata.created_time:type_name -> google.protobuf.Timestamp
+	9,  // 5: kv.KeyMetadata.updated_time:type_name -> google.protobuf.Timestamp
+	8,  // 6: kv.KeyMetadata.delete_version_after:type_name -> google.protobuf.Duration
+	7,  // 7: kv.KeyMetadata.custom_metadata:type_name -> kv.KeyMetadata.CustomMetadataEntry
+	3,  // 8: kv.KeyMetadata.reference:type_name -> kv.SecretReference
+	9,  // 9: kv.Version.created_time:type_name -> google.protobuf.Timestamp
+	9,  // 10: kv.Version.deletion_time:type_name -> google.protobuf.Timestamp
+	9,  // 11: kv.UpgradeInfo.started_time:type_name -> google.protobuf.Timestamp
+	1,  // 12: kv.KeyMetadata.VersionsEntry.value:type_name -> kv.VersionMetadata
+	13, // [13:13] is the sub-list for method output_type
+	13, // [13:13] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_builtin_logical_kv_types_proto_init() }
@@ -601,7 +678,7 @@ func file_builtin_logical_kv_types_proto_init() {
 			}
 		}
 		file_builtin_logical_kv_types_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Version); i {
+			switch v := v.(*SecretReference); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -613,6 +690,18 @@ func file_builtin_logical_kv_types_proto_init() {
 			}
 		}
 		file_builtin_logical_kv_types_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Version); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_builtin_logical_kv_types_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*UpgradeInfo); i {
 			case 0:
 				return &v.state
@@ -631,7 +720,7 @@ func file_builtin_logical_kv_types_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_builtin_logical_kv_types_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
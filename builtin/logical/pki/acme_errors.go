@@ -24,6 +24,9 @@ var ErrAccountDoesNotExist = errors.New("The request specified an account that d
 
 var ErrAcmeDisabled = errors.New("ACME feature is disabled")
 
+// See RFC 9773, ACME Renewal Information (ARI) Extension.
+var ErrAlreadyReplaced = errors.New("The request specified a predecessor certificate which has already been replaced")
+
 var (
 	ErrAlreadyRevoked          = errors.New("The request specified a certificate to be revoked that has already been revoked")
 	ErrBadCSR                  = errors.New("The CSR is unacceptable")
@@ -53,6 +56,7 @@ var (
 // Mapping of err->name; see table in RFC 8555 Section 6.7. Errors.
 var errIdMappings = map[error]string{
 	ErrAccountDoesNotExist:     "accountDoesNotExist",
+	ErrAlreadyReplaced:         "alreadyReplaced",
 	ErrAlreadyRevoked:          "alreadyRevoked",
 	ErrBadCSR:                  "badCSR",
 	ErrBadNonce:                "badNonce",
@@ -81,6 +85,7 @@ var errIdMappings = map[error]string{
 // Mapping of err->status codes; see table in RFC 8555 Section 6.7. Errors.
 var errCodeMappings = map[error]int{
 	ErrAccountDoesNotExist:     http.StatusBadRequest, // See RFC 8555 Section 7.3.1. Finding an Account URL Given a Key.
+	ErrAlreadyReplaced:         http.StatusConflict,
 	ErrAlreadyRevoked:          http.StatusBadRequest,
 	ErrBadCSR:                  http.StatusBadRequest,
 	ErrBadNonce:                http.StatusBadRequest,
@@ -198,6 +198,10 @@ type acmeOrder struct {
 	CertificateExpiry       time.Time           `json:"cert-expiry"`
 	// The actual issuer UUID that issued the certificate, blank if an order exists but no certificate was issued.
 	IssuerId issuerID `json:"issuer-id"`
+	// The ARI certificate identifier of the certificate this order replaces, if any.
+	Replaces string `json:"replaces,omitempty"`
+	// Custom metadata to store alongside the issued certificate, if any.
+	CertMetadata map[string]string `json:"cert-metadata,omitempty"`
 }
 
 func (o acmeOrder) getIdentifierDNSValues() []string {
@@ -556,6 +560,8 @@ type acmeCertEntry struct {
 	Serial  string `json:"-"`
 	Account string `json:"-"`
 	Order   string `json:"order"`
+	// The order which replaced this certificate, blank until one was finalized.
+	ReplacedBy string `json:"replaced-by,omitempty"`
 }
 
 func (a *acmeState) TrackIssuedCert(ac *acmeContext, accountId string, serial string, orderId string) error {
@@ -600,6 +606,22 @@ func (a *acmeState) GetIssuedCert(ac *acmeContext, accountId string, serial stri
 	return &cert, nil
 }
 
+func (a *acmeState) MarkIssuedCertReplaced(ac *acmeContext, cert *acmeCertEntry, orderId string) error {
+	path := getAcmeSerialToAccountTrackerPath(cert.Account, cert.Serial)
+	cert.ReplacedBy = orderId
+
+	json, err := logical.StorageEntryJSON(path, cert)
+	if err != nil {
+		return fmt.Errorf("error serializing acme cert entry: %w", err)
+	}
+
+	if err = ac.sc.Storage.Put(ac.sc.Context, json); err != nil {
+		return fmt.Errorf("error writing acme cert entry: %w", err)
+	}
+
+	return nil
+}
+
 func (a *acmeState) SaveEab(sc *storageContext, eab *eabType) error {
 	json, err := logical.StorageEntryJSON(path.Join(acmeEabPrefix, eab.KeyID), eab)
 	if err != nil {
@@ -32,6 +32,10 @@ const (
 	noRole       = 0
 	roleOptional = 1
 	roleRequired = 2
+
+	// Event types published when certificates are issued or revoked.
+	eventTypeCertIssue  logical.EventType = "pki/issue"
+	eventTypeCertRevoke logical.EventType = "pki/revoke"
 )
 
 /*
@@ -76,7 +80,9 @@ func Backend(conf *logical.BackendConfig) *backend {
 
 		PathsSpecial: &logical.Paths{
 			Unauthenticated: []string{
-				"cert/*",
+				"cert/+",
+				"cert/+/raw",
+				"cert/+/raw/pem",
 				"ca/pem",
 				"ca_chain",
 				"ca",
@@ -107,7 +113,10 @@ func Backend(conf *logical.BackendConfig) *backend {
 				clusterConfigPath,
 				"crls/",
 				"certs/",
+				certMetadataPrefix,
 				acmePathPrefix,
+				scepPathPrefix,
+				cmpPathPrefix,
 			},
 
 			Root: []string{
@@ -181,8 +190,10 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathFetchCRLViaCertPath(&b),
 			pathFetchValidRaw(&b),
 			pathFetchValid(&b),
+			pathFetchCertMetadata(&b),
 			pathFetchListCerts(&b),
 			pathFetchListCertsDetailed(&b),
+			pathSearchCerts(&b),
 
 			// OCSP APIs
 			buildPathOcspGet(&b),
@@ -196,6 +207,18 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathAcmeConfig(&b),
 			pathAcmeEabList(&b),
 			pathAcmeEabDelete(&b),
+			pathAcmeForceRenewal(&b),
+
+			// EST
+			pathEstConfig(&b),
+
+			// SCEP
+			pathScepConfig(&b),
+			pathScepChallenge(&b),
+
+			// CMP
+			pathCmpConfig(&b),
+			pathCmpSecret(&b),
 		},
 
 		Secrets: []*framework.Secret{
@@ -223,6 +246,7 @@ func Backend(conf *logical.BackendConfig) *backend {
 	acmePaths = append(acmePaths, pathAcmeChallenge(&b)...)
 	acmePaths = append(acmePaths, pathAcmeAuthorization(&b)...)
 	acmePaths = append(acmePaths, pathAcmeRevoke(&b)...)
+	acmePaths = append(acmePaths, pathAcmeRenewalInfo(&b)...)
 	acmePaths = append(acmePaths, pathAcmeNewEab(&b)...) // auth'd API that lives underneath the various /acme paths
 
 	for _, acmePath := range acmePaths {
@@ -244,9 +268,28 @@ func Backend(conf *logical.BackendConfig) *backend {
 		b.PathsSpecial.Unauthenticated = append(b.PathsSpecial.Unauthenticated, acmePrefix+"acme/order/+")
 		b.PathsSpecial.Unauthenticated = append(b.PathsSpecial.Unauthenticated, acmePrefix+"acme/order/+/finalize")
 		b.PathsSpecial.Unauthenticated = append(b.PathsSpecial.Unauthenticated, acmePrefix+"acme/order/+/cert")
+		b.PathsSpecial.Unauthenticated = append(b.PathsSpecial.Unauthenticated, acmePrefix+"acme/renewal-info/+")
 		// We specifically do NOT add acme/new-eab to this as it should be auth'd
 	}
 
+	// Add EST paths to backend; they authenticate clients themselves
+	b.Backend.Paths = append(b.Backend.Paths, pathEst(&b)...)
+	for _, estPrefix := range []string{".well-known/est/", ".well-known/est/+/"} {
+		for _, estOp := range []string{"cacerts", "csrattrs", "simpleenroll", "simplereenroll", "serverkeygen"} {
+			b.PathsSpecial.Unauthenticated = append(b.PathsSpecial.Unauthenticated, estPrefix+estOp)
+		}
+	}
+
+	// Add SCEP paths to backend; PKIOperation messages are authenticated by
+	// their challenge password or the certificate they renew
+	b.Backend.Paths = append(b.Backend.Paths, pathScep(&b)...)
+	b.PathsSpecial.Unauthenticated = append(b.PathsSpecial.Unauthenticated, "scep", "scep/pkiclient.exe")
+
+	// Add CMP paths to backend; messages are authenticated by their
+	// protection, with a shared secret or a signature
+	b.Backend.Paths = append(b.Backend.Paths, pathCmp(&b)...)
+	b.PathsSpecial.Unauthenticated = append(b.PathsSpecial.Unauthenticated, ".well-known/cmp", ".well-known/cmp/p/+")
+
 	b.tidyCASGuard = new(uint32)
 	b.tidyCancelCAS = new(uint32)
 	b.tidyStatus = &tidyStatus{state: tidyStatusInactive}
@@ -311,6 +354,12 @@ type backend struct {
 	acmeState       *acmeState
 	acmeAccountLock sync.RWMutex // (Write) Locked on Tidy, (Read) Locked on Account Creation
 	// TODO: Stress test this - eg. creating an order while an account is being revoked
+
+	// Lock around the consumption of SCEP challenges
+	scepChallengeLock sync.Mutex
+
+	// Lock around the consumption of CMP shared secrets
+	cmpLock sync.Mutex
 }
 
 type roleOperation func(ctx context.Context, req *logical.Request, data *framework.FieldData, role *roleEntry) (*logical.Response, error)
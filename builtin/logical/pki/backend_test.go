@@ -3907,6 +3907,7 @@ func TestReadWriteDeleteRoles(t *testing.T) {
 		"issuer_ref":                         "default",
 		"cn_validations":                     []interface{}{"email", "hostname"},
 		"allowed_user_ids":                   []interface{}{},
+		"allowed_cert_metadata":              []interface{}{},
 	}
 
 	if diff := deep.Equal(expectedData, resp.Data); len(diff) > 0 {
@@ -7144,18 +7145,43 @@ func pathShouldBeUnauthedWriteOnly(t *testing.T, client *api.Client, path string
 	}
 }
 
+func pathShouldBeUnauthedReadWrite(t *testing.T, client *api.Client, path string, token string) {
+	client.SetToken("")
+	resp, err := client.Logical().ReadWithContext(ctx, path)
+	if err != nil && isPermDenied(err) {
+		t.Fatalf("unexpected failure to read %v while unauthed: %v / %v", path, err, resp)
+	}
+	resp, err = client.Logical().WriteWithContext(ctx, path, map[string]interface{}{})
+	if err != nil && isPermDenied(err) {
+		t.Fatalf("unexpected failure to write %v while unauthed: %v / %v", path, err, resp)
+	}
+
+	// These should all be denied.
+	resp, err = client.Logical().DeleteWithContext(ctx, path)
+	if err == nil || !isDeniedOp(err) {
+		t.Fatalf("unexpected failure during delete on read-write path %v while unauthed: %v / %v", path, err, resp)
+	}
+	resp, err = client.Logical().JSONMergePatch(ctx, path, map[string]interface{}{})
+	if err == nil || !isDeniedOp(err) {
+		t.Fatalf("unexpected failure during patch on read-write path %v while unauthed: %v / %v", path, err, resp)
+	}
+	client.SetToken(token)
+}
+
 type pathAuthChecker int
 
 const (
 	shouldBeAuthed pathAuthChecker = iota
 	shouldBeUnauthedReadList
 	shouldBeUnauthedWriteOnly
+	shouldBeUnauthedReadWrite
 )
 
 var pathAuthChckerMap = map[pathAuthChecker]pathAuthCheckerFunc{
 	shouldBeAuthed:            pathShouldBeAuthed,
 	shouldBeUnauthedReadList:  pathShouldBeUnauthedReadList,
 	shouldBeUnauthedWriteOnly: pathShouldBeUnauthedWriteOnly,
+	shouldBeUnauthedReadWrite: pathShouldBeUnauthedReadWrite,
 }
 
 func TestProperAuthing(t *testing.T) {
@@ -7218,6 +7244,7 @@ func TestProperAuthing(t *testing.T) {
 		"cert/" + serial:                         shouldBeUnauthedReadList,
 		"cert/" + serial + "/raw":                shouldBeUnauthedReadList,
 		"cert/" + serial + "/raw/pem":            shouldBeUnauthedReadList,
+		"cert/" + serial + "/metadata":           shouldBeAuthed,
 		"cert/crl":                               shouldBeUnauthedReadList,
 		"cert/crl/raw":                           shouldBeUnauthedReadList,
 		"cert/crl/raw/pem":                       shouldBeUnauthedReadList,
@@ -7226,12 +7253,16 @@ func TestProperAuthing(t *testing.T) {
 		"cert/delta-crl/raw/pem":                 shouldBeUnauthedReadList,
 		"certs":                                  shouldBeAuthed,
 		"certs/detailed":                         shouldBeAuthed,
+		"certs/search":                           shouldBeAuthed,
 		"certs/revoked":                          shouldBeAuthed,
 		"config/acme":                            shouldBeAuthed,
 		"config/auto-tidy":                       shouldBeAuthed,
 		"config/ca":                              shouldBeAuthed,
 		"config/cluster":                         shouldBeAuthed,
 		"config/crl":                             shouldBeAuthed,
+		"config/est":                             shouldBeAuthed,
+		"config/cmp":                             shouldBeAuthed,
+		"config/scep":                            shouldBeAuthed,
 		"config/issuers":                         shouldBeAuthed,
 		"config/keys":                            shouldBeAuthed,
 		"config/urls":                            shouldBeAuthed,
@@ -7310,6 +7341,13 @@ func TestProperAuthing(t *testing.T) {
 		"tidy-status":                            shouldBeAuthed,
 		"eab":                                    shouldBeAuthed,
 		"eab/" + eabKid:                          shouldBeAuthed,
+		"scep":                                   shouldBeUnauthedReadWrite,
+		"scep/pkiclient.exe":                     shouldBeUnauthedReadWrite,
+		"scep/challenge":                         shouldBeAuthed,
+		".well-known/cmp":                        shouldBeUnauthedWriteOnly,
+		".well-known/cmp/p/test":                 shouldBeUnauthedWriteOnly,
+		"cmp/secret":                             shouldBeAuthed,
+		"acme/force-renewal":                     shouldBeAuthed,
 	}
 
 	// Add ACME based paths to the test suite
@@ -7326,11 +7364,21 @@ func TestProperAuthing(t *testing.T) {
 		paths[acmePrefix+"acme/order/13b80844-e60d-42d2-b7e9-152a8e834b90"] = shouldBeUnauthedWriteOnly
 		paths[acmePrefix+"acme/order/13b80844-e60d-42d2-b7e9-152a8e834b90/finalize"] = shouldBeUnauthedWriteOnly
 		paths[acmePrefix+"acme/order/13b80844-e60d-42d2-b7e9-152a8e834b90/cert"] = shouldBeUnauthedWriteOnly
+		paths[acmePrefix+"acme/renewal-info/aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE"] = shouldBeUnauthedReadList
 
 		// Make sure this new-eab path is auth'd
 		paths[acmePrefix+"acme/new-eab"] = shouldBeAuthed
 	}
 
+	// Add EST based paths to the test suite
+	for _, estPrefix := range []string{"", "test/"} {
+		paths[".well-known/est/"+estPrefix+"cacerts"] = shouldBeUnauthedReadList
+		paths[".well-known/est/"+estPrefix+"csrattrs"] = shouldBeUnauthedReadList
+		paths[".well-known/est/"+estPrefix+"simpleenroll"] = shouldBeUnauthedWriteOnly
+		paths[".well-known/est/"+estPrefix+"simplereenroll"] = shouldBeUnauthedWriteOnly
+		paths[".well-known/est/"+estPrefix+"serverkeygen"] = shouldBeUnauthedWriteOnly
+	}
+
 	for path, checkerType := range paths {
 		checker := pathAuthChckerMap[checkerType]
 		checker(t, client, "pki/"+path, token)
@@ -7383,9 +7431,15 @@ func TestProperAuthing(t *testing.T) {
 		if strings.Contains(raw_path, "acme/") && strings.Contains(raw_path, "{challenge_type}") {
 			raw_path = strings.ReplaceAll(raw_path, "{challenge_type}", "http-01")
 		}
+		if strings.Contains(raw_path, "acme/") && strings.Contains(raw_path, "{cert_id}") {
+			raw_path = strings.ReplaceAll(raw_path, "{cert_id}", "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE")
+		}
 		if strings.Contains(raw_path, "acme/") && strings.Contains(raw_path, "{order_id}") {
 			raw_path = strings.ReplaceAll(raw_path, "{order_id}", "13b80844-e60d-42d2-b7e9-152a8e834b90")
 		}
+		if (strings.Contains(raw_path, "est/") || strings.Contains(raw_path, "cmp/")) && strings.Contains(raw_path, "{label}") {
+			raw_path = strings.ReplaceAll(raw_path, "{label}", "test")
+		}
 		if strings.Contains(raw_path, "eab") && strings.Contains(raw_path, "{key_id}") {
 			raw_path = strings.ReplaceAll(raw_path, "{key_id}", eabKid)
 		}
@@ -92,7 +92,16 @@ func generateCABundle(sc *storageContext, input *inputBundle, data *certutil.Cre
 			return nil, err
 		}
 
-		return certutil.CreateCertificateWithKeyGenerator(data, randomSource, existingKeyGeneratorFromBytes(keyEntry))
+		return certutil.CreateCertificateWithKeyGenerator(data, randomSource, existingKeyGeneratorFromBytes(sc, keyEntry))
+	}
+
+	if managedKeyRequested(input) {
+		keyEntry, err := sc.importManagedKeyForGeneration(input)
+		if err != nil {
+			return nil, err
+		}
+
+		return certutil.CreateCertificateWithKeyGenerator(data, randomSource, existingKeyGeneratorFromBytes(sc, keyEntry))
 	}
 
 	return certutil.CreateCertificateWithRandomSource(data, randomSource)
@@ -110,7 +119,16 @@ func generateCSRBundle(sc *storageContext, input *inputBundle, data *certutil.Cr
 			return nil, err
 		}
 
-		return certutil.CreateCSRWithKeyGenerator(data, addBasicConstraints, randomSource, existingKeyGeneratorFromBytes(key))
+		return certutil.CreateCSRWithKeyGenerator(data, addBasicConstraints, randomSource, existingKeyGeneratorFromBytes(sc, key))
+	}
+
+	if managedKeyRequested(input) {
+		key, err := sc.importManagedKeyForGeneration(input)
+		if err != nil {
+			return nil, err
+		}
+
+		return certutil.CreateCSRWithKeyGenerator(data, addBasicConstraints, randomSource, existingKeyGeneratorFromBytes(sc, key))
 	}
 
 	return certutil.CreateCSRWithRandomSource(data, addBasicConstraints, randomSource)
@@ -146,6 +164,13 @@ func (sc *storageContext) getKeyTypeAndBitsForRole(data *framework.FieldData) (s
 		}
 		pubKey = existingPubKey
 	}
+	if managedKeyRequestedFromFieldData(data) {
+		_, managedPubKey, err := sc.getManagedKeyPublicKey(data)
+		if err != nil {
+			return "", 0, errors.New("failed to lookup public key from managed key: " + err.Error())
+		}
+		pubKey = managedPubKey
+	}
 
 	privateKeyType, keyBits, err := getKeyTypeAndBitsFromPublicKeyForRole(pubKey)
 	return string(privateKeyType), keyBits, err
@@ -193,8 +218,18 @@ func (sc *storageContext) getExistingKeyFromRef(keyRef string) (*keyEntry, error
 	return sc.fetchKeyById(keyId)
 }
 
-func existingKeyGeneratorFromBytes(key *keyEntry) certutil.KeyGenerator {
+func existingKeyGeneratorFromBytes(sc *storageContext, key *keyEntry) certutil.KeyGenerator {
 	return func(_ string, _ int, container certutil.ParsedPrivateKeyContainer, _ io.Reader) error {
+		if key.isManagedPrivateKey() {
+			signer, err := sc.getManagedKeySigner(key)
+			if err != nil {
+				return err
+			}
+
+			container.SetParsedPrivateKey(signer, key.PrivateKeyType, nil)
+			return nil
+		}
+
 		signer, _, pemBytes, err := getSignerFromKeyEntryBytes(key)
 		if err != nil {
 			return err
@@ -227,6 +262,7 @@ func buildSignVerbatimRole(data *framework.FieldData, role *roleEntry) *roleEntr
 		AllowedSerialNumbers:      []string{"*"},
 		AllowedURISANs:            []string{"*"},
 		AllowedUserIDs:            []string{"*"},
+		AllowedCertMetadata:       []string{"*"},
 		CNValidations:             []string{"disabled"},
 		GenerateLease:             new(bool),
 		// If adding new fields to be read, update the field list within addSignVerbatimRoleFields
@@ -135,7 +135,7 @@ func (sc *storageContext) fetchCAInfoByIssuerId(issuerId issuerID, usage issuerU
 		return nil, errutil.InternalError{Err: fmt.Sprintf("error while attempting to use issuer %v: %v", issuerId, err)}
 	}
 
-	parsedBundle, err := bundle.ToParsedCertBundle()
+	parsedBundle, err := sc.parseCABundle(entry, bundle)
 	if err != nil {
 		return nil, errutil.InternalError{Err: err.Error()}
 	}
@@ -710,6 +710,64 @@ func validateUserId(data *inputBundle, userId string) bool {
 	return false
 }
 
+const (
+	maxCertMetadataKeys        = 64
+	maxCertMetadataKeyLength   = 128
+	maxCertMetadataValueLength = 512
+)
+
+// validateCertMetadata ensures the given certificate metadata fits within
+// the size limits and that its keys are allowed by the role. As metadata is
+// stored alongside the certificate, roles not storing certificates cannot
+// accept any.
+func validateCertMetadata(role *roleEntry, metadata map[string]string) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	if role.NoStore {
+		return errutil.UserError{Err: "cert_metadata cannot be set when the role has no_store enabled"}
+	}
+
+	if len(metadata) > maxCertMetadataKeys {
+		return errutil.UserError{Err: fmt.Sprintf("cert_metadata may contain at most %d keys", maxCertMetadataKeys)}
+	}
+
+	for key, value := range metadata {
+		if len(key) == 0 {
+			return errutil.UserError{Err: "cert_metadata keys cannot be empty"}
+		}
+		if len(key) > maxCertMetadataKeyLength {
+			return errutil.UserError{Err: fmt.Sprintf("cert_metadata key %q is longer than %d bytes", key, maxCertMetadataKeyLength)}
+		}
+		if len(value) > maxCertMetadataValueLength {
+			return errutil.UserError{Err: fmt.Sprintf("cert_metadata value of key %q is longer than %d bytes", key, maxCertMetadataValueLength)}
+		}
+		if !validateCertMetadataKey(role, key) {
+			return errutil.UserError{Err: fmt.Sprintf("cert_metadata key %q not allowed by this role", key)}
+		}
+	}
+
+	return nil
+}
+
+// Returns bool stating whether the given certificate metadata key is allowed
+// by the role.
+func validateCertMetadataKey(role *roleEntry, key string) bool {
+	for _, rolePattern := range role.AllowedCertMetadata {
+		if rolePattern == "" {
+			continue
+		}
+
+		if rolePattern == key || (strings.Contains(rolePattern, "*") && glob.Glob(rolePattern, key)) {
+			return true
+		}
+	}
+
+	// No matches.
+	return false
+}
+
 func validateSerialNumber(data *inputBundle, serialNumber string) string {
 	valid := false
 	if len(data.role.AllowedSerialNumbers) > 0 {
@@ -870,6 +928,21 @@ func signCert(b *backend,
 		return nil, nil, errutil.UserError{Err: fmt.Sprintf("certificate request could not be parsed: %v", err)}
 	}
 
+	return signCertRequest(b, data, caSign, csr, isCA, useCSRValues, false)
+}
+
+// signCertRequest signs the given certificate request with the role of the
+// input bundle. The signature of the request is not checked when
+// skipSignatureCheck is set, for requests such as CRMF certificate templates
+// whose proof of possession was verified otherwise.
+func signCertRequest(b *backend,
+	data *inputBundle,
+	caSign *certutil.CAInfoBundle,
+	csr *x509.CertificateRequest,
+	isCA bool,
+	useCSRValues bool,
+	skipSignatureCheck bool) (*certutil.ParsedCertBundle, []string, error,
+) {
 	if csr.PublicKeyAlgorithm == x509.UnknownPublicKeyAlgorithm || csr.PublicKey == nil {
 		return nil, nil, errutil.UserError{Err: "Refusing to sign CSR with empty PublicKey. This usually means the SubjectPublicKeyInfo field has an OID not recognized by Go, such as 1.2.840.113549.1.1.10 for rsaPSS."}
 	}
@@ -976,6 +1049,7 @@ func signCert(b *backend,
 		// set for KeyBits when KeyType was set to any. This also enforces the
 		// docs saying when key_type=any, we only enforce our specified minimums
 		// for signing operations
+		var err error
 		if data.role.KeyBits, data.role.SignatureBits, err = certutil.ValidateDefaultOrValueKeyTypeSignatureLength(
 			actualKeyType, 0, data.role.SignatureBits); err != nil {
 			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("unknown internal error updating default values: %v", err)}
@@ -1029,6 +1103,7 @@ func signCert(b *backend,
 
 	creation.Params.IsCA = isCA
 	creation.Params.UseCSRValues = useCSRValues
+	creation.SkipCSRSignatureCheck = skipSignatureCheck
 
 	if isCA {
 		creation.Params.PermittedDNSDomains = data.apiData.Get("permitted_dns_domains").([]string)
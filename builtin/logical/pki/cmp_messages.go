@@ -0,0 +1,361 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// CMP message bodies (RFC 4210 Section 5.1.2). The CMP ASN.1 module uses
+// explicit tagging, while the CRMF one (RFC 4211) uses implicit tagging.
+const (
+	cmpBodyIR       = 0
+	cmpBodyIP       = 1
+	cmpBodyCR       = 2
+	cmpBodyCP       = 3
+	cmpBodyP10CR    = 4
+	cmpBodyKUR      = 7
+	cmpBodyKUP      = 8
+	cmpBodyRR       = 11
+	cmpBodyRP       = 12
+	cmpBodyPKIConf  = 19
+	cmpBodyError    = 23
+	cmpBodyCertConf = 24
+	cmpBodyPollReq  = 25
+)
+
+// CMP PKI statuses and failure information bits (RFC 4210 Section 5.2.3)
+const (
+	cmpStatusAccepted  = 0
+	cmpStatusRejection = 2
+
+	cmpFailBadAlg             = 0
+	cmpFailBadMessageCheck    = 1
+	cmpFailBadRequest         = 2
+	cmpFailBadCertId          = 4
+	cmpFailBadDataFormat      = 5
+	cmpFailBadPOP             = 9
+	cmpFailCertRevoked        = 10
+	cmpFailWrongIntegrity     = 12
+	cmpFailBadRecipientNonce  = 13
+	cmpFailBadCertTemplate    = 19
+	cmpFailSignerNotTrusted   = 20
+	cmpFailTransactionIdInUse = 21
+	cmpFailUnsupportedVersion = 22
+	cmpFailNotAuthorized      = 23
+	cmpFailSystemFailure      = 25
+)
+
+var (
+	oidCmpImplicitConfirm = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 4, 13}
+	oidExtensionRequest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
+)
+
+// cmpMessage is a PKIMessage, whose header and body are kept raw as they are
+// covered by its protection.
+type cmpMessage struct {
+	Header     asn1.RawValue
+	Body       asn1.RawValue
+	Protection asn1.BitString  `asn1:"explicit,optional,tag:0"`
+	ExtraCerts []asn1.RawValue `asn1:"explicit,optional,tag:1"`
+}
+
+// cmpProtectedPart is the ProtectedPart the protection of a PKIMessage is
+// computed over.
+type cmpProtectedPart struct {
+	Header asn1.RawValue
+	Body   asn1.RawValue
+}
+
+type cmpHeader struct {
+	PVNO          int
+	Sender        asn1.RawValue
+	Recipient     asn1.RawValue
+	MessageTime   time.Time                `asn1:"generalized,explicit,optional,tag:0"`
+	ProtectionAlg pkix.AlgorithmIdentifier `asn1:"explicit,optional,tag:1"`
+	SenderKID     []byte                   `asn1:"explicit,optional,tag:2"`
+	RecipKID      []byte                   `asn1:"explicit,optional,tag:3"`
+	TransactionID []byte                   `asn1:"explicit,optional,tag:4"`
+	SenderNonce   []byte                   `asn1:"explicit,optional,tag:5"`
+	RecipNonce    []byte                   `asn1:"explicit,optional,tag:6"`
+	FreeText      []asn1.RawValue          `asn1:"explicit,optional,tag:7"`
+	GeneralInfo   []cmpInfoTypeAndValue    `asn1:"explicit,optional,tag:8"`
+}
+
+type cmpInfoTypeAndValue struct {
+	InfoType  asn1.ObjectIdentifier
+	InfoValue asn1.RawValue `asn1:"optional"`
+}
+
+type cmpCertReqMsg struct {
+	CertReq asn1.RawValue
+	POPO    asn1.RawValue   `asn1:"optional"`
+	RegInfo []asn1.RawValue `asn1:"optional"`
+}
+
+type cmpCertRequest struct {
+	CertReqId    *big.Int
+	CertTemplate cmpCertTemplate
+	Controls     []asn1.RawValue `asn1:"optional"`
+}
+
+// cmpCertTemplate is a CRMF CertTemplate. Its issuer and subject names are
+// explicitly tagged, and kept with their tag by encoding/asn1.
+type cmpCertTemplate struct {
+	Version      int              `asn1:"optional,tag:0"`
+	SerialNumber *big.Int         `asn1:"optional,tag:1"`
+	SigningAlg   asn1.RawValue    `asn1:"optional,tag:2"`
+	Issuer       asn1.RawValue    `asn1:"optional,explicit,tag:3"`
+	Validity     asn1.RawValue    `asn1:"optional,tag:4"`
+	Subject      asn1.RawValue    `asn1:"optional,explicit,tag:5"`
+	PublicKey    asn1.RawValue    `asn1:"optional,tag:6"`
+	IssuerUID    asn1.RawValue    `asn1:"optional,tag:7"`
+	SubjectUID   asn1.RawValue    `asn1:"optional,tag:8"`
+	Extensions   []pkix.Extension `asn1:"optional,tag:9"`
+}
+
+// cmpPOPOSigningKey is the signature proof of possession of a CertReqMsg,
+// which is computed over its CertRequest when poposkInput is absent.
+type cmpPOPOSigningKey struct {
+	POPOSKInput         asn1.RawValue `asn1:"optional,tag:0"`
+	AlgorithmIdentifier pkix.AlgorithmIdentifier
+	Signature           asn1.BitString
+}
+
+type cmpRevDetails struct {
+	CertDetails     cmpCertTemplate
+	CRLEntryDetails []pkix.Extension `asn1:"optional"`
+}
+
+type cmpCertStatus struct {
+	CertHash   []byte
+	CertReqId  *big.Int
+	StatusInfo cmpPKIStatusInfo         `asn1:"optional"`
+	HashAlg    pkix.AlgorithmIdentifier `asn1:"explicit,optional,tag:0"`
+}
+
+type cmpPollReq struct {
+	CertReqId *big.Int
+}
+
+type cmpPKIStatusInfo struct {
+	Status       int
+	StatusString []asn1.RawValue `asn1:"optional"`
+	FailInfo     asn1.BitString  `asn1:"optional"`
+}
+
+type cmpCertRepMessage struct {
+	CAPubs   []asn1.RawValue `asn1:"explicit,optional,tag:1"`
+	Response []cmpCertResponse
+}
+
+type cmpCertResponse struct {
+	CertReqId        *big.Int
+	Status           cmpPKIStatusInfo
+	CertifiedKeyPair cmpCertifiedKeyPair `asn1:"optional"`
+}
+
+type cmpCertifiedKeyPair struct {
+	// Certificate is the certificate choice of CertOrEncCert, explicitly
+	// tagged with [0]
+	Certificate asn1.RawValue
+}
+
+type cmpRevRepContent struct {
+	Status []cmpPKIStatusInfo
+}
+
+type cmpErrorMsgContent struct {
+	PKIStatusInfo cmpPKIStatusInfo
+}
+
+// cmpFailure is a failure to handle a CMP request, returned to the client
+// as an error message.
+type cmpFailure struct {
+	failInfo int
+	reason   string
+}
+
+func (f *cmpFailure) Error() string {
+	return f.reason
+}
+
+func newCmpFailure(failInfo int, format string, args ...interface{}) *cmpFailure {
+	return &cmpFailure{
+		failInfo: failInfo,
+		reason:   fmt.Sprintf(format, args...),
+	}
+}
+
+// cmpFreeText encodes the given text as a PKIFreeText.
+func cmpFreeText(text string) []asn1.RawValue {
+	return []asn1.RawValue{{Tag: asn1.TagUTF8String, Bytes: []byte(text)}}
+}
+
+// cmpFailInfo encodes the given failure information bit as a PKIFailureInfo.
+func cmpFailInfo(bit int) asn1.BitString {
+	bytes := make([]byte, bit/8+1)
+	bytes[bit/8] = 0x80 >> (bit % 8)
+	return asn1.BitString{Bytes: bytes, BitLength: bit + 1}
+}
+
+// cmpBody wraps the DER of the content of a body with its explicit tag.
+func cmpBody(bodyType int, content []byte) asn1.RawValue {
+	return asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        bodyType,
+		IsCompound: true,
+		Bytes:      content,
+	}
+}
+
+// cmpDirectoryName encodes the given DER Name as a directoryName
+// GeneralName.
+func cmpDirectoryName(name []byte) asn1.RawValue {
+	return asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        4,
+		IsCompound: true,
+		Bytes:      name,
+	}
+}
+
+// hasImplicitConfirm returns whether the header requests or grants implicit
+// confirmation of the issued certificates.
+func (h *cmpHeader) hasImplicitConfirm() bool {
+	for _, info := range h.GeneralInfo {
+		if info.InfoType.Equal(oidCmpImplicitConfirm) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseCmpMessage decodes the given PKIMessage and its header.
+func parseCmpMessage(der []byte) (*cmpMessage, *cmpHeader, error) {
+	var msg cmpMessage
+	if rest, err := asn1.Unmarshal(der, &msg); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse message: %w", err)
+	} else if len(rest) > 0 {
+		return nil, nil, errors.New("trailing data after message")
+	}
+
+	var header cmpHeader
+	if rest, err := asn1.Unmarshal(msg.Header.FullBytes, &header); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse message header: %w", err)
+	} else if len(rest) > 0 {
+		return nil, nil, errors.New("trailing data after message header")
+	}
+
+	if msg.Body.Class != asn1.ClassContextSpecific || !msg.Body.IsCompound {
+		return nil, nil, errors.New("invalid message body")
+	}
+
+	return &msg, &header, nil
+}
+
+// parseCertReqMessages decodes the single CertReqMsg of an ir, cr or kur
+// body, as the Lightweight CMP profile only allows one (RFC 9483 Section
+// 4.1.1).
+func parseCertReqMessages(content []byte) (*cmpCertReqMsg, *cmpCertRequest, error) {
+	var msgs []cmpCertReqMsg
+	if rest, err := asn1.Unmarshal(content, &msgs); err != nil || len(rest) > 0 {
+		return nil, nil, newCmpFailure(cmpFailBadDataFormat, "failed to parse certificate requests")
+	}
+	if len(msgs) != 1 {
+		return nil, nil, newCmpFailure(cmpFailBadRequest, "exactly one certificate request is expected, got %d", len(msgs))
+	}
+
+	var certReq cmpCertRequest
+	if rest, err := asn1.Unmarshal(msgs[0].CertReq.FullBytes, &certReq); err != nil || len(rest) > 0 {
+		return nil, nil, newCmpFailure(cmpFailBadDataFormat, "failed to parse certificate request")
+	}
+
+	return &msgs[0], &certReq, nil
+}
+
+// subjectPublicKeyInfo returns the DER SubjectPublicKeyInfo of the
+// certificate template, which is implicitly tagged in the template.
+func (t *cmpCertTemplate) subjectPublicKeyInfo() ([]byte, error) {
+	if len(t.PublicKey.FullBytes) == 0 {
+		return nil, errors.New("the certificate template has no public key")
+	}
+
+	return asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSequence,
+		IsCompound: true,
+		Bytes:      t.PublicKey.Bytes,
+	})
+}
+
+// toCertificateRequest builds the certificate request equivalent to the
+// certificate template, with its subject, public key and extensions. The
+// request is built in DER and parsed back, so that its subject alternative
+// names are parsed as those of a CSR; it is not signed though, its proof of
+// possession being verified with the CertReqMsg.
+func (t *cmpCertTemplate) toCertificateRequest() (*x509.CertificateRequest, error) {
+	spki, err := t.subjectPublicKeyInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	subject := asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true}
+	if len(t.Subject.Bytes) > 0 {
+		subject = asn1.RawValue{FullBytes: t.Subject.Bytes}
+	}
+
+	var attributes []asn1.RawValue
+	if len(t.Extensions) > 0 {
+		attribute, err := asn1.Marshal(struct {
+			Type   asn1.ObjectIdentifier
+			Values [][]pkix.Extension `asn1:"set"`
+		}{
+			Type:   oidExtensionRequest,
+			Values: [][]pkix.Extension{t.Extensions},
+		})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, asn1.RawValue{FullBytes: attribute})
+	}
+
+	tbs, err := asn1.Marshal(struct {
+		Version    int
+		Subject    asn1.RawValue
+		PublicKey  asn1.RawValue
+		Attributes []asn1.RawValue `asn1:"tag:0"`
+	}{
+		Subject:    subject,
+		PublicKey:  asn1.RawValue{FullBytes: spki},
+		Attributes: attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := asn1.Marshal(struct {
+		TBS                asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          asn1.BitString
+	}{
+		TBS: asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidSignatureSHA256WithRSA,
+			Parameters: asn1.NullRawValue,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificateRequest(der)
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+)
+
+// cmpMaxPBMIterations bounds the iteration count of password-based MAC
+// protection, which is chosen by the client.
+const cmpMaxPBMIterations = 100000
+
+var (
+	oidPasswordBasedMac = asn1.ObjectIdentifier{1, 2, 840, 113533, 7, 66, 13}
+
+	oidDigestSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidDigestSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidDigestSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 8, 1, 2}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidSignatureSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureSHA384WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSignatureSHA512WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSignatureECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidSignatureEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+	// cmpDigestAlgorithms are the one-way functions of password-based MAC
+	// protection, and the hash algorithms of certificate confirmations
+	cmpDigestAlgorithms = map[string]crypto.Hash{
+		oidDigestSHA1.String():   crypto.SHA1,
+		oidDigestSHA256.String(): crypto.SHA256,
+		oidDigestSHA384.String(): crypto.SHA384,
+		oidDigestSHA512.String(): crypto.SHA512,
+	}
+
+	cmpMACAlgorithms = map[string]crypto.Hash{
+		oidHMACWithSHA1.String():   crypto.SHA1,
+		oidHMACWithSHA256.String(): crypto.SHA256,
+		oidHMACWithSHA384.String(): crypto.SHA384,
+		oidHMACWithSHA512.String(): crypto.SHA512,
+	}
+
+	// cmpSignatureAlgorithms are the signature algorithms of signature
+	// protection and proofs of possession (RFC 9481 Section 3)
+	cmpSignatureAlgorithms = map[string]x509.SignatureAlgorithm{
+		oidSignatureSHA256WithRSA.String():   x509.SHA256WithRSA,
+		oidSignatureSHA384WithRSA.String():   x509.SHA384WithRSA,
+		oidSignatureSHA512WithRSA.String():   x509.SHA512WithRSA,
+		oidSignatureECDSAWithSHA256.String(): x509.ECDSAWithSHA256,
+		oidSignatureECDSAWithSHA384.String(): x509.ECDSAWithSHA384,
+		oidSignatureECDSAWithSHA512.String(): x509.ECDSAWithSHA512,
+		oidSignatureEd25519.String():         x509.PureEd25519,
+	}
+)
+
+// cmpPBMParameter are the parameters of password-based MAC protection (RFC
+// 4210 Section 5.1.3.1).
+type cmpPBMParameter struct {
+	Salt           []byte
+	OWF            pkix.AlgorithmIdentifier
+	IterationCount int
+	MAC            pkix.AlgorithmIdentifier
+}
+
+// cmpProtection is the verified protection of a request, which its response
+// is protected with as well.
+type cmpProtection struct {
+	// reference and secret are the shared secret of password-based MAC
+	// protection
+	reference []byte
+	secret    []byte
+	pbm       *cmpPBMParameter
+
+	// signer and chain are the certificates of signature protection
+	signer *x509.Certificate
+	chain  []*x509.Certificate
+}
+
+// mac computes the password-based MAC of the given data.
+func (p *cmpPBMParameter) mac(secret []byte, data []byte) ([]byte, error) {
+	owf, ok := cmpDigestAlgorithms[p.OWF.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported one-way function %v", p.OWF.Algorithm)
+	}
+	macHash, ok := cmpMACAlgorithms[p.MAC.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported MAC algorithm %v", p.MAC.Algorithm)
+	}
+	if p.IterationCount < 1 || p.IterationCount > cmpMaxPBMIterations {
+		return nil, fmt.Errorf("iteration count must be between 1 and %d", cmpMaxPBMIterations)
+	}
+
+	h := owf.New()
+	h.Write(secret)
+	h.Write(p.Salt)
+	key := h.Sum(nil)
+	for i := 1; i < p.IterationCount; i++ {
+		h.Reset()
+		h.Write(key)
+		key = h.Sum(nil)
+	}
+
+	m := hmac.New(macHash.New, key)
+	m.Write(data)
+	return m.Sum(nil), nil
+}
+
+// verifyCmpProtection verifies the protection of the given message, either
+// a password-based MAC with the secret lookupSecret returns for the sender
+// KID, or a signature with the first of its extra certificates. The trust in
+// the signer certificate is left to the caller.
+func verifyCmpProtection(msg *cmpMessage, header *cmpHeader, lookupSecret func(reference []byte) ([]byte, error)) (*cmpProtection, error) {
+	if len(header.ProtectionAlg.Algorithm) == 0 || msg.Protection.BitLength == 0 {
+		return nil, newCmpFailure(cmpFailBadMessageCheck, "unprotected messages are not accepted")
+	}
+
+	protectedPart, err := asn1.Marshal(cmpProtectedPart{Header: msg.Header, Body: msg.Body})
+	if err != nil {
+		return nil, err
+	}
+
+	if header.ProtectionAlg.Algorithm.Equal(oidPasswordBasedMac) {
+		var pbm cmpPBMParameter
+		if rest, err := asn1.Unmarshal(header.ProtectionAlg.Parameters.FullBytes, &pbm); err != nil || len(rest) > 0 {
+			return nil, newCmpFailure(cmpFailBadDataFormat, "failed to parse the password-based MAC parameters")
+		}
+		if len(header.SenderKID) == 0 {
+			return nil, newCmpFailure(cmpFailBadMessageCheck, "MAC-protected messages require a sender KID")
+		}
+
+		secret, err := lookupSecret(header.SenderKID)
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil {
+			return nil, newCmpFailure(cmpFailBadMessageCheck, "unknown or expired secret reference")
+		}
+
+		mac, err := pbm.mac(secret, protectedPart)
+		if err != nil {
+			return nil, newCmpFailure(cmpFailBadAlg, "%s", err)
+		}
+		if !hmac.Equal(mac, msg.Protection.RightAlign()) {
+			return nil, newCmpFailure(cmpFailBadMessageCheck, "invalid message protection")
+		}
+
+		return &cmpProtection{
+			reference: header.SenderKID,
+			secret:    secret,
+			pbm:       &pbm,
+		}, nil
+	}
+
+	signatureAlgorithm, ok := cmpSignatureAlgorithms[header.ProtectionAlg.Algorithm.String()]
+	if !ok {
+		return nil, newCmpFailure(cmpFailBadAlg, "unsupported protection algorithm %v", header.ProtectionAlg.Algorithm)
+	}
+	if len(msg.ExtraCerts) == 0 {
+		return nil, newCmpFailure(cmpFailBadMessageCheck, "signature-protected messages require the signer certificate in their extra certificates")
+	}
+
+	var chain []*x509.Certificate
+	for _, rawCert := range msg.ExtraCerts {
+		cert, err := x509.ParseCertificate(rawCert.FullBytes)
+		if err != nil {
+			return nil, newCmpFailure(cmpFailBadDataFormat, "failed to parse extra certificate: %s", err)
+		}
+		chain = append(chain, cert)
+	}
+
+	if err := chain[0].CheckSignature(signatureAlgorithm, protectedPart, msg.Protection.RightAlign()); err != nil {
+		return nil, newCmpFailure(cmpFailBadMessageCheck, "invalid message protection: %s", err)
+	}
+
+	return &cmpProtection{
+		signer: chain[0],
+		chain:  chain[1:],
+	}, nil
+}
+
+// protect computes the protection of the given response header and body,
+// with the shared secret of the request when it was MAC-protected, and with
+// the CA key otherwise.
+func (p *cmpProtection) protect(header *cmpHeader, body asn1.RawValue, caSigner crypto.Signer) (asn1.RawValue, asn1.BitString, error) {
+	var pbm cmpPBMParameter
+	if p != nil && p.secret != nil {
+		pbm = *p.pbm
+		pbm.Salt = make([]byte, 16)
+		if _, err := rand.Read(pbm.Salt); err != nil {
+			return asn1.RawValue{}, asn1.BitString{}, err
+		}
+		params, err := asn1.Marshal(pbm)
+		if err != nil {
+			return asn1.RawValue{}, asn1.BitString{}, err
+		}
+		header.ProtectionAlg = pkix.AlgorithmIdentifier{
+			Algorithm:  oidPasswordBasedMac,
+			Parameters: asn1.RawValue{FullBytes: params},
+		}
+		header.SenderKID = p.reference
+	} else {
+		algorithm, _, err := cmpSignatureAlgorithm(caSigner)
+		if err != nil {
+			return asn1.RawValue{}, asn1.BitString{}, err
+		}
+		header.ProtectionAlg = algorithm
+	}
+
+	rawHeader, err := asn1.Marshal(*header)
+	if err != nil {
+		return asn1.RawValue{}, asn1.BitString{}, fmt.Errorf("failed to encode CMP header: %w", err)
+	}
+	headerValue := asn1.RawValue{FullBytes: rawHeader}
+	protectedPart, err := asn1.Marshal(cmpProtectedPart{Header: headerValue, Body: body})
+	if err != nil {
+		return asn1.RawValue{}, asn1.BitString{}, err
+	}
+
+	var protection []byte
+	if p != nil && p.secret != nil {
+		protection, err = pbm.mac(p.secret, protectedPart)
+	} else {
+		protection, err = cmpSign(caSigner, protectedPart)
+	}
+	if err != nil {
+		return asn1.RawValue{}, asn1.BitString{}, fmt.Errorf("failed to protect CMP response: %w", err)
+	}
+
+	return headerValue, asn1.BitString{Bytes: protection, BitLength: len(protection) * 8}, nil
+}
+
+// cmpSignatureAlgorithm returns the signature algorithm the given CA key
+// signs CMP responses with, and its hash.
+func cmpSignatureAlgorithm(signer crypto.Signer) (pkix.AlgorithmIdentifier, crypto.Hash, error) {
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey:
+		return pkix.AlgorithmIdentifier{Algorithm: oidSignatureSHA256WithRSA, Parameters: asn1.NullRawValue}, crypto.SHA256, nil
+	case *ecdsa.PublicKey:
+		switch pub.Curve.Params().BitSize {
+		case 384:
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA384}, crypto.SHA384, nil
+		case 521:
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA512}, crypto.SHA512, nil
+		default:
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA256}, crypto.SHA256, nil
+		}
+	case ed25519.PublicKey:
+		return pkix.AlgorithmIdentifier{Algorithm: oidSignatureEd25519}, crypto.Hash(0), nil
+	default:
+		return pkix.AlgorithmIdentifier{}, 0, fmt.Errorf("unsupported CA key type %T", pub)
+	}
+}
+
+// cmpSign signs the given data with the CA key.
+func cmpSign(signer crypto.Signer, data []byte) ([]byte, error) {
+	_, hash, err := cmpSignatureAlgorithm(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := data
+	if hash != crypto.Hash(0) {
+		h := hash.New()
+		h.Write(data)
+		digest = h.Sum(nil)
+	}
+
+	return signer.Sign(rand.Reader, digest, hash)
+}
+
+// cmpCertHash returns the hash of the given certificate, as confirmed by a
+// certConf message: by default, the hash of its signature algorithm (RFC 9480
+// Section 2.10).
+func cmpCertHash(cert *x509.Certificate, hashAlg pkix.AlgorithmIdentifier) ([]byte, error) {
+	var hash crypto.Hash
+	if len(hashAlg.Algorithm) > 0 {
+		var ok bool
+		if hash, ok = cmpDigestAlgorithms[hashAlg.Algorithm.String()]; !ok {
+			return nil, newCmpFailure(cmpFailBadAlg, "unsupported hash algorithm %v", hashAlg.Algorithm)
+		}
+	} else {
+		switch cert.SignatureAlgorithm {
+		case x509.SHA256WithRSA, x509.SHA256WithRSAPSS, x509.ECDSAWithSHA256:
+			hash = crypto.SHA256
+		case x509.SHA384WithRSA, x509.SHA384WithRSAPSS, x509.ECDSAWithSHA384:
+			hash = crypto.SHA384
+		case x509.SHA512WithRSA, x509.SHA512WithRSAPSS, x509.ECDSAWithSHA512, x509.PureEd25519:
+			hash = crypto.SHA512
+		default:
+			return nil, fmt.Errorf("unsupported certificate signature algorithm %v", cert.SignatureAlgorithm)
+		}
+	}
+
+	h := hash.New()
+	h.Write(cert.Raw)
+	return h.Sum(nil), nil
+}
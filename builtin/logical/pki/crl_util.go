@@ -613,6 +613,10 @@ func revokeCert(sc *storageContext, config *crlConfig, cert *x509.Certificate) (
 	}
 	sc.Backend.ifCountEnabledIncrementTotalRevokedCertificatesCount(certsCounted, revEntry.Key)
 
+	sc.Backend.SendEvent(sc.Context, eventTypeCertRevoke, "cert/"+hyphenSerial, map[string]string{
+		"serial_number": colonSerial,
+	})
+
 	// From here on out, the certificate has been revoked locally. Any other
 	// persistence issues might still err, but any other failure messages
 	// should be added as warnings to the revocation.
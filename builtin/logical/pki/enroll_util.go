@@ -0,0 +1,199 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/certutil"
+	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// signEnrollmentCsr signs the CSR of a device enrollment protocol (EST, SCEP
+// or CMP) with the given role and issuer, as the sign-verbatim endpoint does
+// when verbatim is set and the sign endpoint does otherwise, and stores the
+// certificate unless the role sets no_store. Errors caused by the CSR are
+// returned as errutil.UserError.
+func signEnrollmentCsr(b *backend, sc *storageContext, r *logical.Request, role *roleEntry, issuer *issuerEntry, verbatim bool, csr *x509.CertificateRequest) (*certutil.ParsedCertBundle, error) {
+	return signEnrollmentRequest(b, sc, r, role, issuer, verbatim, csr, false)
+}
+
+// signEnrollmentTemplate is signEnrollmentCsr for the unsigned requests built
+// from CRMF certificate templates, whose proof of possession must have been
+// verified by the caller.
+func signEnrollmentTemplate(b *backend, sc *storageContext, r *logical.Request, role *roleEntry, issuer *issuerEntry, verbatim bool, csr *x509.CertificateRequest) (*certutil.ParsedCertBundle, error) {
+	return signEnrollmentRequest(b, sc, r, role, issuer, verbatim, csr, true)
+}
+
+func signEnrollmentRequest(b *backend, sc *storageContext, r *logical.Request, role *roleEntry, issuer *issuerEntry, verbatim bool, csr *x509.CertificateRequest, skipSignatureCheck bool) (*certutil.ParsedCertBundle, error) {
+	if !role.NoStore && b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+		return nil, logical.ErrReadOnly
+	}
+
+	data := &framework.FieldData{
+		Raw:    map[string]interface{}{},
+		Schema: getCsrSignVerbatimSchemaFields(),
+	}
+
+	signingBundle, _, err := sc.fetchCAInfoWithIssuer(issuer.ID.String(), IssuanceUsage)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading CA %s: %w", issuer.ID.String(), err)
+	}
+
+	input := &inputBundle{
+		req:     r,
+		apiData: data,
+		role:    role,
+	}
+	parsedBundle, _, err := signCertRequest(b, input, signingBundle, csr, false, verbatim, skipSignatureCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	if !role.NoStore {
+		if err := storeCertificate(sc, parsedBundle); err != nil {
+			return nil, err
+		}
+	}
+
+	return parsedBundle, nil
+}
+
+// enrollmentLabelRegex matches the labels of the paths of device enrollment
+// protocols, as matched by framework.GenericNameRegex.
+var enrollmentLabelRegex = regexp.MustCompile(`^\w(([\w-.]+)?\w)?$`)
+
+// getEnrollmentPathPolicyRole returns the role to issue certificates with
+// under the given path policy of a device enrollment protocol, which has the
+// format of the ACME default directory policy. A nil role is returned for the
+// "forbid" policy.
+func getEnrollmentPathPolicyRole(sc *storageContext, policy string) (*roleEntry, error) {
+	policyType, err := getDefaultDirectoryPolicyType(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	switch policyType {
+	case Forbid:
+		return nil, nil
+	case SignVerbatim:
+		return buildSignVerbatimRoleWithNoData(&roleEntry{}), nil
+	case Role:
+		roleName, err := getDefaultDirectoryPolicyRole(policy)
+		if err != nil {
+			return nil, err
+		}
+
+		role, err := sc.Backend.getRole(sc.Context, sc.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, fmt.Errorf("role %q does not exist", roleName)
+		}
+		return role, nil
+	default:
+		return nil, errors.New("unknown path policy " + policy)
+	}
+}
+
+// getEnrollmentIssuer returns the issuer of a device enrollment protocol,
+// which must be usable for issuance. The default issuer is used when no
+// issuer is given.
+func getEnrollmentIssuer(sc *storageContext, issuerName string) (*issuerEntry, error) {
+	if issuerName == "" {
+		issuerName = defaultRef
+	}
+	issuerId, err := sc.resolveIssuerReference(issuerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve enrollment issuer %q: %w", issuerName, err)
+	}
+
+	issuer, err := sc.fetchIssuerById(issuerId)
+	if err != nil {
+		return nil, fmt.Errorf("issuer failed to load: %w", err)
+	}
+
+	if !issuer.Usage.HasUsage(IssuanceUsage) || len(issuer.KeyID) == 0 {
+		return nil, fmt.Errorf("enrollment issuer %q is missing proper issuance usage or key", issuerName)
+	}
+
+	return issuer, nil
+}
+
+// validateEnrollmentCsr checks the signature of an enrollment CSR, and that it
+// does not request a CA certificate.
+func validateEnrollmentCsr(csr *x509.CertificateRequest) error {
+	if err := csr.CheckSignature(); err != nil {
+		return fmt.Errorf("invalid CSR signature: %w", err)
+	}
+
+	return validateEnrollmentRequest(csr)
+}
+
+// validateEnrollmentRequest checks that an enrollment request does not
+// request a CA certificate.
+func validateEnrollmentRequest(csr *x509.CertificateRequest) error {
+	for _, ext := range csr.Extensions {
+		if ext.Id.Equal(certutil.ExtensionBasicConstraintsOID) {
+			isCa, _, err := certutil.ParseBasicConstraintExtension(ext)
+			if err != nil || isCa {
+				return errors.New("refusing to accept CSR with a CA Basic Constraints extension")
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkRenewableCertificate checks that the given certificate, presented by a
+// device to renew it, was issued by one of the issuers of the mount, and is
+// neither expired nor revoked. The reason the certificate cannot be renewed
+// is returned, or an empty string if it can.
+func checkRenewableCertificate(sc *storageContext, cert *x509.Certificate) (string, error) {
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return "the certificate to renew is not valid at this time", nil
+	}
+
+	issuerIds, err := sc.listIssuers()
+	if err != nil {
+		return "", fmt.Errorf("failed to list issuers: %w", err)
+	}
+
+	var issued bool
+	for _, issuerId := range issuerIds {
+		issuer, err := sc.fetchIssuerById(issuerId)
+		if err != nil {
+			return "", err
+		}
+		issuerCert, err := issuer.GetCertificate()
+		if err != nil {
+			return "", err
+		}
+		if cert.CheckSignatureFrom(issuerCert) == nil {
+			issued = true
+			break
+		}
+	}
+	if !issued {
+		return "the certificate to renew was not issued by this mount", nil
+	}
+
+	revoked, err := fetchCertBySerialBigInt(sc, revokedPath, cert.SerialNumber)
+	if err != nil {
+		return "", err
+	}
+	if revoked != nil {
+		return "the certificate to renew is revoked", nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+type estContext struct {
+	sc     *storageContext
+	config *estConfigEntry
+	// label is the EST label of the request path, empty for the default path
+	label    string
+	role     *roleEntry
+	issuer   *issuerEntry
+	verbatim bool
+}
+
+type estOperation func(estCtx *estContext, r *logical.Request, data *framework.FieldData) (*logical.Response, error)
+
+// estWrapper the basic wrapper of all EST handlers. It resolves the role and
+// issuer the request path maps to, and authenticates the client through the
+// configured auth methods when requested.
+func (b *backend) estWrapper(authenticate bool, op estOperation) framework.OperationFunc {
+	return func(ctx context.Context, r *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		sc := b.makeStorageContext(ctx, r.Storage)
+
+		config, err := sc.getEstConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch EST configuration: %w", err)
+		}
+		if !config.Enabled {
+			return estErrorResponse(http.StatusNotFound, "EST is not enabled on this mount"), nil
+		}
+
+		label := ""
+		if labelRaw, ok := data.GetOk("label"); ok {
+			label = labelRaw.(string)
+		}
+
+		policy := config.DefaultPathPolicy
+		if label != "" {
+			var ok bool
+			policy, ok = config.LabelToPathPolicy[label]
+			if !ok {
+				return estErrorResponse(http.StatusNotFound, "unknown EST label %q", label), nil
+			}
+		}
+
+		role, err := getEnrollmentPathPolicyRole(sc, policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the role of EST path policy %q: %w", policy, err)
+		}
+		if role == nil {
+			return estErrorResponse(http.StatusNotFound, "EST is forbidden on this path"), nil
+		}
+
+		issuer, err := getEnrollmentIssuer(sc, role.Issuer)
+		if err != nil {
+			return nil, err
+		}
+
+		if authenticate {
+			if resp := b.estAuthenticate(ctx, r, config); resp != nil {
+				return resp, nil
+			}
+		}
+
+		policyType, _ := getDefaultDirectoryPolicyType(policy)
+		return op(&estContext{
+			sc:       sc,
+			config:   config,
+			label:    label,
+			role:     role,
+			issuer:   issuer,
+			verbatim: policyType == SignVerbatim,
+		}, r, data)
+	}
+}
+
+// estAuthenticate authenticates the EST client by logging in to one of the
+// configured auth methods, with the HTTP Basic credentials or the TLS client
+// certificate of the request. The resulting policies must allow the request
+// on its EST path. A response is returned when the client is not allowed.
+func (b *backend) estAuthenticate(ctx context.Context, r *logical.Request, config *estConfigEntry) *logical.Response {
+	sysView, ok := b.System().(logical.ExtendedSystemView)
+	if !ok {
+		return estErrorResponse(http.StatusInternalServerError, "EST authentication is not supported by this plugin environment")
+	}
+
+	login := &logical.Request{
+		Operation:  logical.UpdateOperation,
+		Connection: r.Connection,
+		Data:       map[string]interface{}{},
+	}
+
+	var accessor string
+	username, password, hasBasicAuth := (&http.Request{Header: http.Header(r.Headers)}).BasicAuth()
+	switch {
+	case hasBasicAuth && config.Authenticators.Userpass != nil:
+		if username == "" || strings.Contains(username, "/") {
+			return estErrorResponse(http.StatusUnauthorized, "invalid username")
+		}
+		accessor = config.Authenticators.Userpass.Accessor
+		login.Path = "login/" + username
+		login.Data["password"] = password
+	case estPeerCertificate(r) != nil && config.Authenticators.Cert != nil:
+		accessor = config.Authenticators.Cert.Accessor
+		login.Path = "login"
+		if config.Authenticators.Cert.CertRole != "" {
+			login.Data["name"] = config.Authenticators.Cert.CertRole
+		}
+	default:
+		resp := estErrorResponse(http.StatusUnauthorized, "authentication required")
+		if config.Authenticators.Userpass != nil {
+			resp.Data[logical.HTTPWWWAuthenticateHeader] = `Basic realm="est"`
+		}
+		return resp
+	}
+
+	if _, err := sysView.LoginSubRequest(ctx, accessor, login, r.Operation, r.Path); err != nil {
+		switch {
+		case errors.Is(err, logical.ErrPermissionDenied):
+			return estErrorResponse(http.StatusForbidden, "permission denied")
+		case errors.Is(err, logical.ErrRateLimitQuotaExceeded):
+			return estErrorResponse(http.StatusTooManyRequests, "rate limit quota exceeded")
+		}
+		b.Logger().Warn("failed to authenticate EST client", "error", err)
+		return estErrorResponse(http.StatusInternalServerError, "failed to authenticate client")
+	}
+
+	return nil
+}
+
+// estErrorResponse returns a plain text EST error, as EST clients do not
+// understand the JSON errors of the API.
+func estErrorResponse(status int, format string, args ...interface{}) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  status,
+			logical.HTTPContentType: "text/plain",
+			logical.HTTPRawBody:     []byte(fmt.Sprintf(format, args...) + "\n"),
+		},
+	}
+}
+
+// estBase64Response returns the given DER content base64-encoded, as required
+// by RFC 7030 (and RFC 8951) for all the EST responses.
+func estBase64Response(contentType string, der []byte) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPContentType: contentType,
+			logical.HTTPRawBody:     estBase64Encode(der),
+		},
+	}
+}
+
+// estBase64Encode encodes the given data in base64, in lines of 64
+// characters.
+func estBase64Encode(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var builder strings.Builder
+	for len(encoded) > 64 {
+		builder.WriteString(encoded[:64])
+		builder.WriteString("\n")
+		encoded = encoded[64:]
+	}
+	builder.WriteString(encoded)
+	builder.WriteString("\n")
+
+	return []byte(builder.String())
+}
@@ -172,6 +172,19 @@ Any values are added with OID 0.9.2342.19200300.100.1.1.`,
 		},
 	}
 
+	fields["cert_metadata"] = &framework.FieldSchema{
+		Type: framework.TypeKVPairs,
+		Description: `Custom metadata to store alongside the issued
+certificate, as key-value pairs. Restricted by
+allowed_cert_metadata. This is returned when
+reading the certificate metadata and can be
+searched on, but it is not placed in the
+certificate itself.`,
+		DisplayAttrs: &framework.DisplayAttributes{
+			Name: "Certificate Metadata",
+		},
+	}
+
 	fields = addIssuerRefField(fields)
 
 	return fields
@@ -361,6 +374,7 @@ RSA key-type issuer. Defaults to false.`,
 	}
 
 	fields = addKeyRefNameFields(fields)
+	fields = addManagedKeyFields(fields)
 
 	return fields
 }
@@ -23,6 +23,10 @@ func comparePublicKey(key *keyEntry, publicKey crypto.PublicKey) (bool, error) {
 }
 
 func getPublicKey(key *keyEntry) (crypto.PublicKey, error) {
+	if key.isManagedPrivateKey() {
+		return getManagedKeyEntryPublicKey(key)
+	}
+
 	signer, _, _, err := getSignerFromKeyEntryBytes(key)
 	if err != nil {
 		return nil, err
@@ -34,6 +38,9 @@ func getSignerFromKeyEntryBytes(key *keyEntry) (crypto.Signer, certutil.BlockTyp
 	if key.PrivateKeyType == certutil.UnknownPrivateKey {
 		return nil, certutil.UnknownBlock, nil, errutil.InternalError{Err: fmt.Sprintf("unsupported unknown private key type for key: %s (%s)", key.ID, key.Name)}
 	}
+	if key.isManagedPrivateKey() {
+		return nil, certutil.UnknownBlock, nil, errutil.InternalError{Err: fmt.Sprintf("private key of key %s (%s) is held by a managed key", key.ID, key.Name)}
+	}
 
 	bytes, blockType, blk, err := getSignerFromBytes([]byte(key.PrivateKey))
 	if err != nil {
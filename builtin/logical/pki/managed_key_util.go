@@ -0,0 +1,276 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/certutil"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	managedKeyNameArg = "managed_key_name"
+	managedKeyIdArg   = "managed_key_id"
+)
+
+func addManagedKeyFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields[managedKeyNameArg] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `The name of the managed key to use when the exported
+type is kms. When kms type is the key type, this field or managed_key_id
+is required. Ignored for other types.`,
+	}
+	fields[managedKeyIdArg] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `The UUID of the managed key to use when the exported
+type is kms. When kms type is the key type, this field or managed_key_name
+is required. Ignored for other types.`,
+	}
+	return fields
+}
+
+// managedKeySystemView returns the system view of the mount, which must
+// support managed keys.
+func (sc *storageContext) managedKeySystemView() (logical.ManagedKeySystemView, error) {
+	view, ok := sc.Backend.System().(logical.ManagedKeySystemView)
+	if !ok {
+		return nil, errutil.UserError{Err: "managed keys are not supported by this mount"}
+	}
+	return view, nil
+}
+
+// withManagedSigningKey passes the managed signing key, referenced by UUID
+// or name, to f.
+func (sc *storageContext) withManagedSigningKey(keyUUID, keyName string, f logical.ManagedSigningKeyConsumer) error {
+	view, err := sc.managedKeySystemView()
+	if err != nil {
+		return err
+	}
+	if keyUUID != "" {
+		return view.WithManagedSigningKeyByUUID(sc.Context, keyUUID, sc.Backend.backendUUID, f)
+	}
+	return view.WithManagedSigningKeyByName(sc.Context, keyName, sc.Backend.backendUUID, f)
+}
+
+// managedKeyRequested returns whether the request asks for a key held by a
+// managed key.
+func managedKeyRequested(input *inputBundle) bool {
+	return managedKeyRequestedFromFieldData(input.apiData)
+}
+
+func managedKeyRequestedFromFieldData(data *framework.FieldData) bool {
+	exportedStr, ok := data.GetOk("exported")
+	if !ok {
+		return false
+	}
+	return exportedStr.(string) == "kms"
+}
+
+// getManagedKeyPublicKey returns the UUID and public key of the managed key
+// referenced by the request, generating the key in its KMS first when it
+// does not exist yet and the managed key allows it.
+func (sc *storageContext) getManagedKeyPublicKey(data *framework.FieldData) (string, crypto.PublicKey, error) {
+	managedKeyName := data.Get(managedKeyNameArg).(string)
+	managedKeyUUID := data.Get(managedKeyIdArg).(string)
+	if managedKeyName == "" && managedKeyUUID == "" {
+		return "", nil, errutil.UserError{Err: fmt.Sprintf("one of %s or %s is required for kms keys", managedKeyNameArg, managedKeyIdArg)}
+	}
+
+	var publicKey crypto.PublicKey
+	err := sc.withManagedSigningKey(managedKeyUUID, managedKeyName, func(ctx context.Context, key logical.ManagedSigningKey) error {
+		if !key.AllowsAll([]logical.KeyUsage{logical.KeyUsageSign}) {
+			return fmt.Errorf("managed key %q does not allow signing", key.Name())
+		}
+
+		present, err := key.Present(ctx)
+		if err != nil {
+			return err
+		}
+		if !present {
+			lifecycle, ok := key.(logical.ManagedKeyLifecycle)
+			if !ok {
+				return fmt.Errorf("managed key %q does not exist in its KMS", key.Name())
+			}
+			if _, err := lifecycle.GenerateKey(ctx); err != nil {
+				return err
+			}
+		}
+
+		managedKeyUUID = key.UUID()
+		publicKey, err = key.GetPublicKey(ctx)
+		return err
+	})
+	if err != nil {
+		return "", nil, errutil.UserError{Err: fmt.Sprintf("failed to use managed key: %v", err)}
+	}
+	return managedKeyUUID, publicKey, nil
+}
+
+// importManagedKey registers the managed key referenced by the request as
+// a key of the mount. Only the public key is stored; the private key never
+// leaves the KMS.
+func (sc *storageContext) importManagedKey(data *framework.FieldData, keyName string) (*keyEntry, bool, error) {
+	managedKeyUUID, publicKey, err := sc.getManagedKeyPublicKey(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	privateKeyType := getPrivateKeyTypeFromPublicKey(publicKey)
+	if privateKeyType == certutil.UnknownPrivateKey {
+		return nil, false, errutil.UserError{Err: fmt.Sprintf("unsupported public key type %T for managed key", publicKey)}
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return sc.importKeyEntry(keyEntry{
+		Name:           keyName,
+		PrivateKeyType: privateKeyType,
+		ManagedKeyUUID: managedKeyUUID,
+		PublicKey:      string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, publicKey)
+}
+
+func getPrivateKeyTypeFromPublicKey(publicKey crypto.PublicKey) certutil.PrivateKeyType {
+	keyType, _, err := getKeyTypeAndBitsFromPublicKeyForRole(publicKey)
+	if err != nil {
+		return certutil.UnknownPrivateKey
+	}
+	return keyType
+}
+
+// getManagedKeyEntryPublicKey returns the public key stored for a managed
+// key.
+func getManagedKeyEntryPublicKey(key *keyEntry) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(key.PublicKey))
+	if block == nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("no public key stored for managed key: %s (%s)", key.ID, key.Name)}
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// managedKeySigner implements crypto.Signer for a key held by a managed
+// key. As managed keys may only be used within the scope of the system view
+// call, each signature resolves the managed key again.
+type managedKeySigner struct {
+	sc        *storageContext
+	keyUUID   string
+	publicKey crypto.PublicKey
+}
+
+var _ crypto.Signer = (*managedKeySigner)(nil)
+
+// getManagedKeySigner returns a signer backed by the managed key holding the
+// private key of the key entry.
+func (sc *storageContext) getManagedKeySigner(key *keyEntry) (crypto.Signer, error) {
+	if !key.isManagedPrivateKey() {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("key %s (%s) is not a managed key", key.ID, key.Name)}
+	}
+
+	publicKey, err := getManagedKeyEntryPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &managedKeySigner{
+		sc:        sc,
+		keyUUID:   key.ManagedKeyUUID,
+		publicKey: publicKey,
+	}, nil
+}
+
+func (s *managedKeySigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+func (s *managedKeySigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var signature []byte
+	err := s.sc.withManagedSigningKey(s.keyUUID, "", func(ctx context.Context, key logical.ManagedSigningKey) error {
+		var err error
+		signature, err = key.Sign(ctx, digest, rand, opts)
+		return err
+	})
+	return signature, err
+}
+
+// findManagedKeyEntry returns the managed key entry with the given public
+// key. Bundles generated with a managed key carry no private key, so the
+// existing entry is used in place of importing one.
+func (sc *storageContext) findManagedKeyEntry(publicKey crypto.PublicKey) (*keyEntry, error) {
+	knownKeys, err := sc.listKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, identifier := range knownKeys {
+		existingKey, err := sc.fetchKeyById(identifier)
+		if err != nil {
+			return nil, err
+		}
+		if !existingKey.isManagedPrivateKey() {
+			continue
+		}
+
+		equal, err := comparePublicKey(existingKey, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			return existingKey, nil
+		}
+	}
+
+	return nil, errors.New("generated bundle has no private key and no matching managed key exists")
+}
+
+// parseCABundle parses the bundle of the issuer, attaching a signer backed
+// by the managed key when the private key of the issuer is held by one.
+func (sc *storageContext) parseCABundle(issuer *issuerEntry, bundle *certutil.CertBundle) (*certutil.ParsedCertBundle, error) {
+	parsedBundle, err := bundle.ToParsedCertBundle()
+	if err != nil {
+		return nil, err
+	}
+	if parsedBundle.PrivateKey != nil || issuer.KeyID == keyID("") {
+		return parsedBundle, nil
+	}
+
+	key, err := sc.fetchKeyById(issuer.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if !key.isManagedPrivateKey() {
+		return parsedBundle, nil
+	}
+
+	signer, err := sc.getManagedKeySigner(key)
+	if err != nil {
+		return nil, err
+	}
+	parsedBundle.PrivateKey = signer
+	parsedBundle.PrivateKeyType = key.PrivateKeyType
+	return parsedBundle, nil
+}
+
+// importManagedKeyForGeneration registers the managed key requested for a
+// new CA certificate or CSR, under the requested key name.
+func (sc *storageContext) importManagedKeyForGeneration(input *inputBundle) (*keyEntry, error) {
+	keyName, err := getKeyName(sc, input.apiData)
+	if err != nil {
+		return nil, err
+	}
+
+	key, _, err := sc.importManagedKey(input.apiData, keyName)
+	return key, err
+}
@@ -46,11 +46,12 @@ func patternAcmeDirectory(b *backend, pattern string) *framework.Path {
 
 func (b *backend) acmeDirectoryHandler(acmeCtx *acmeContext, r *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
 	rawBody, err := json.Marshal(map[string]interface{}{
-		"newNonce":   acmeCtx.baseUrl.JoinPath("new-nonce").String(),
-		"newAccount": acmeCtx.baseUrl.JoinPath("new-account").String(),
-		"newOrder":   acmeCtx.baseUrl.JoinPath("new-order").String(),
-		"revokeCert": acmeCtx.baseUrl.JoinPath("revoke-cert").String(),
-		"keyChange":  acmeCtx.baseUrl.JoinPath("key-change").String(),
+		"newNonce":    acmeCtx.baseUrl.JoinPath("new-nonce").String(),
+		"newAccount":  acmeCtx.baseUrl.JoinPath("new-account").String(),
+		"newOrder":    acmeCtx.baseUrl.JoinPath("new-order").String(),
+		"revokeCert":  acmeCtx.baseUrl.JoinPath("revoke-cert").String(),
+		"keyChange":   acmeCtx.baseUrl.JoinPath("key-change").String(),
+		"renewalInfo": acmeCtx.baseUrl.JoinPath("renewal-info").String(),
 		// This is purposefully missing newAuthz as we don't support pre-authorization
 		"meta": map[string]interface{}{
 			"externalAccountRequired": acmeCtx.eabPolicy.IsExternalAccountRequired(),
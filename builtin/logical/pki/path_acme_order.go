@@ -260,6 +260,16 @@ func (b *backend) acmeFinalizeOrderHandler(ac *acmeContext, _ *logical.Request,
 		return nil, err
 	}
 
+	var replacedCert *acmeCertEntry
+	if order.Replaces != "" {
+		// Another order replacing the same certificate may have been
+		// finalized since this one was created.
+		replacedCert, err = b.getAcmeReplacedCert(ac, order.AccountId, order.Identifiers, order.Replaces, order.OrderId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	signedCertBundle, issuerId, err := issueCertFromCsr(ac, csr)
 	if err != nil {
 		return nil, err
@@ -271,11 +281,22 @@ func (b *backend) acmeFinalizeOrderHandler(ac *acmeContext, _ *logical.Request,
 		return nil, err
 	}
 
+	if err := ac.sc.storeCertMetadata(hyphenSerialNumber, order.CertMetadata); err != nil {
+		return nil, err
+	}
+
 	if err := b.acmeState.TrackIssuedCert(ac, order.AccountId, hyphenSerialNumber, order.OrderId); err != nil {
 		b.Logger().Warn("orphaned generated ACME certificate due to error saving account->cert->order reference", "serial_number", hyphenSerialNumber, "error", err)
 		return nil, err
 	}
 
+	if replacedCert != nil {
+		if err := b.acmeState.MarkIssuedCertReplaced(ac, replacedCert, order.OrderId); err != nil {
+			b.Logger().Warn("failed marking ACME certificate as replaced", "serial_number", replacedCert.Serial, "order", order.OrderId, "error", err)
+			return nil, err
+		}
+	}
+
 	order.Status = ACMEOrderValid
 	order.CertificateSerialNumber = hyphenSerialNumber
 	order.CertificateExpiry = signedCertBundle.Certificate.NotAfter
@@ -738,6 +759,31 @@ func (b *backend) acmeNewOrderHandler(ac *acmeContext, _ *logical.Request, _ *fr
 		return nil, err
 	}
 
+	// Per RFC 9773 -> 5. Extensions to the Order Object, the order may
+	// identify the certificate it replaces, which must have been issued to
+	// this account.
+	var replaces string
+	if rawReplaces, present := data["replaces"]; present {
+		var ok bool
+		replaces, ok = rawReplaces.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid type (%T) for field 'replaces': %w", rawReplaces, ErrMalformed)
+		}
+
+		if _, err := b.getAcmeReplacedCert(ac, account.KeyId, identifiers, replaces, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	certMetadata, err := parseOrderCertMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCertMetadata(ac.role, certMetadata); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+	}
+
 	// Per RFC 8555 -> 7.1.3. Order Objects
 	// For pending orders, the authorizations that the client needs to complete before the
 	// requested certificate can be issued (see Section 7.5), including
@@ -771,6 +817,8 @@ func (b *backend) acmeNewOrderHandler(ac *acmeContext, _ *logical.Request, _ *fr
 		Expires:          time.Now().Add(24 * time.Hour), // TODO: Readjust this based on authz and/or config
 		Identifiers:      identifiers,
 		AuthorizationIds: authorizationIds,
+		Replaces:         replaces,
+		CertMetadata:     certMetadata,
 	}
 
 	err = b.acmeState.SaveOrder(ac, order)
@@ -834,6 +882,14 @@ func formatOrderResponse(acmeCtx *acmeContext, order *acmeOrder) *logical.Respon
 		},
 	}
 
+	if order.Replaces != "" {
+		resp.Data["replaces"] = order.Replaces
+	}
+
+	if len(order.CertMetadata) > 0 {
+		resp.Data["certMetadata"] = order.CertMetadata
+	}
+
 	// Only reply with the certificate URL if we are in a valid order state.
 	if order.Status == ACMEOrderValid {
 		resp.Data["certificate"] = baseOrderUrl + "/cert"
@@ -915,6 +971,32 @@ func parseOptRFC3339Field(data map[string]interface{}, keyName string) (time.Tim
 	return timeVal, nil
 }
 
+// parseOrderCertMetadata parses the custom metadata to attach to the issued
+// certificate, given as a non-standard certMetadata object of strings on the
+// order.
+func parseOrderCertMetadata(data map[string]interface{}) (map[string]string, error) {
+	rawMetadata, present := data["certMetadata"]
+	if !present {
+		return nil, nil
+	}
+
+	mapMetadata, ok := rawMetadata.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid type (%T) for field 'certMetadata': %w", rawMetadata, ErrMalformed)
+	}
+
+	certMetadata := make(map[string]string, len(mapMetadata))
+	for key, rawValue := range mapMetadata {
+		value, ok := rawValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid type (%T) for value of key %q in 'certMetadata': %w", rawValue, key, ErrMalformed)
+		}
+		certMetadata[key] = value
+	}
+
+	return certMetadata, nil
+}
+
 func parseOrderIdentifiers(data map[string]interface{}) ([]*ACMEIdentifier, error) {
 	rawIdentifiers, present := data["identifiers"]
 	if !present {
@@ -4,12 +4,20 @@
 package pki
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	"net"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/logical"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
 )
 
 // TestACME_ValidateIdentifiersAgainstRole Verify the ACME order creation
@@ -140,3 +148,63 @@ func buildTestRole(t *testing.T, config map[string]interface{}) *roleEntry {
 
 	return role
 }
+
+// TestAcmeOrderCertMetadata validates that custom metadata given on new
+// orders is restricted by the directory role and stored alongside the issued
+// certificate.
+func TestAcmeOrderCertMetadata(t *testing.T) {
+	t.Parallel()
+
+	cluster, client, _ := setupAcmeBackend(t)
+	defer cluster.Cleanup()
+	testCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	_, err := client.Logical().JSONMergePatch(testCtx, "pki/roles/test-role", map[string]interface{}{
+		"allowed_cert_metadata": []string{"service"},
+	})
+	require.NoError(t, err, "failed updating role")
+
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "failed creating ec key")
+	acmeClient := getAcmeClientForCluster(t, cluster, "/v1/pki/roles/test-role/acme/", accountKey)
+	acct, err := acmeClient.Register(testCtx, &acme.Account{}, func(tosURL string) bool { return true })
+	require.NoError(t, err, "failed registering account")
+
+	problem := newAcmeOrderWithFields(t, acmeClient, map[string]interface{}{
+		"certMetadata": map[string]interface{}{"owner": "alice"},
+	}, http.StatusBadRequest)
+	require.Equal(t, "urn:ietf:params:acme:error:malformed", problem["type"])
+	problem = newAcmeOrderWithFields(t, acmeClient, map[string]interface{}{
+		"certMetadata": map[string]interface{}{"service": 42},
+	}, http.StatusBadRequest)
+	require.Equal(t, "urn:ietf:params:acme:error:malformed", problem["type"])
+
+	order := newAcmeOrderWithFields(t, acmeClient, map[string]interface{}{
+		"certMetadata": map[string]interface{}{"service": "web"},
+	}, http.StatusCreated)
+	require.Equal(t, map[string]interface{}{"service": "web"}, order["certMetadata"])
+
+	acmeOrder, err := acmeClient.GetOrder(testCtx, order["location"].(string))
+	require.NoError(t, err, "failed fetching order")
+	markAuthorizationSuccess(t, client, acmeClient, acct, acmeOrder)
+
+	csrKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "failed generated key for CSR")
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{"*.localdomain"}}, csrKey)
+	require.NoError(t, err, "failed generating csr")
+	certs, _, err := acmeClient.CreateOrderCert(testCtx, acmeOrder.FinalizeURL, csr, true)
+	require.NoError(t, err, "failed finalizing order")
+	cert, err := x509.ParseCertificate(certs[0])
+	require.NoError(t, err, "failed parsing acme cert")
+
+	resp, err := client.Logical().ReadWithContext(testCtx, "pki/cert/"+serialFromCert(cert)+"/metadata")
+	require.NoError(t, err, "failed reading certificate metadata")
+	require.Equal(t, map[string]interface{}{"service": "web"}, resp.Data["cert_metadata"])
+
+	resp, err = client.Logical().WriteWithContext(testCtx, "pki/certs/search", map[string]interface{}{
+		"cert_metadata": map[string]interface{}{"service": "web"},
+	})
+	require.NoError(t, err, "failed searching certificates")
+	require.Equal(t, []interface{}{serialFromCert(cert)}, resp.Data["keys"])
+}
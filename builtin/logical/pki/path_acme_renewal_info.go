@@ -0,0 +1,520 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	acmeRenewalPrefix       = acmePathPrefix + "renewal/"
+	acmeRenewalSerialPrefix = acmeRenewalPrefix + "serials/"
+	acmeRenewalIssuerPrefix = acmeRenewalPrefix + "issuers/"
+
+	// Revoked certificates are to be replaced right away, within the
+	// following window from their revocation.
+	acmeRevokedRenewalWindow = time.Minute
+
+	pathAcmeForceRenewalHelpSyn  = "Force ACME clients to renew certificates early"
+	pathAcmeForceRenewalHelpDesc = "Make the renewal information of the given certificates, or of all the certificates issued so far by the given issuer, suggest ACME clients supporting it to renew them right away; for example after the compromise of their keys."
+)
+
+// acmeForcedRenewalEntry is an early renewal forced on a certificate or on
+// all the certificates issued by an issuer before ForcedAt.
+type acmeForcedRenewalEntry struct {
+	ForcedAt time.Time     `json:"forced_at"`
+	Window   time.Duration `json:"window"`
+}
+
+type acmeRenewalWindow struct {
+	Start time.Time
+	End   time.Time
+	// Forced is set when the window does not derive from the lifetime of
+	// the certificate, but from its revocation or a forced renewal.
+	Forced bool
+}
+
+func pathAcmeRenewalInfo(b *backend) []*framework.Path {
+	return buildAcmeFrameworkPaths(b, patternAcmeRenewalInfo, "/renewal-info/"+framework.GenericNameRegex("cert_id"))
+}
+
+func patternAcmeRenewalInfo(b *backend, pattern string) *framework.Path {
+	fields := map[string]*framework.FieldSchema{}
+	addFieldsForACMEPath(fields, pattern)
+	fields["cert_id"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "The ARI identifier of the certificate, made of its authority key identifier and serial number",
+		Required:    true,
+	}
+
+	return &framework.Path{
+		Pattern: pattern,
+		Fields:  fields,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback:                    b.acmeWrapper(b.acmeRenewalInfoHandler),
+				ForwardPerformanceSecondary: false,
+				ForwardPerformanceStandby:   true,
+			},
+		},
+
+		HelpSynopsis:    pathAcmeHelpSync,
+		HelpDescription: pathAcmeHelpDesc,
+	}
+}
+
+func (b *backend) acmeRenewalInfoHandler(acmeCtx *acmeContext, _ *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	certId := fields.Get("cert_id").(string)
+
+	cert, err := fetchAcmeCertById(acmeCtx.sc, certId)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		// Unknown certificates are reported as not found, rather than as
+		// malformed requests.
+		body := ErrorResponse{
+			StatusCode: http.StatusNotFound,
+			Type:       ErrorPrefix + errIdMappings[ErrMalformed],
+			Detail:     fmt.Sprintf("no certificate with identifier %s was issued by this mount", certId),
+		}
+		return body.Marshal()
+	}
+
+	config, err := acmeCtx.getAcmeState().getConfigWithUpdate(acmeCtx.sc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ACME configuration: %w", err)
+	}
+
+	window, err := getAcmeRenewalWindow(acmeCtx.sc, config, cert)
+	if err != nil {
+		return nil, err
+	}
+
+	renewalInfo := map[string]interface{}{
+		"suggestedWindow": map[string]interface{}{
+			"start": window.Start.UTC().Format(time.RFC3339),
+			"end":   window.End.UTC().Format(time.RFC3339),
+		},
+	}
+	if window.Forced && config.ARIExplanationURL != "" {
+		renewalInfo["explanationURL"] = config.ARIExplanationURL
+	}
+
+	rawBody, err := json.Marshal(renewalInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding response: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/json",
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     rawBody,
+		},
+		Headers: map[string][]string{
+			"Retry-After": {strconv.FormatInt(int64(config.ARIRetryAfter.Seconds()), 10)},
+		},
+	}, nil
+}
+
+// getAcmeRenewalWindow computes the renewal window suggested for the given
+// certificate: the configured share of its lifetime, unless it was revoked or
+// its renewal was forced.
+func getAcmeRenewalWindow(sc *storageContext, config *acmeConfigEntry, cert *x509.Certificate) (*acmeRenewalWindow, error) {
+	revokedEntry, err := fetchCertBySerialBigInt(sc, revokedPath, cert.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if revokedEntry != nil {
+		var revInfo revocationInfo
+		if err := revokedEntry.DecodeJSON(&revInfo); err != nil {
+			return nil, fmt.Errorf("error decoding revocation entry for serial %s: %w", serialFromCert(cert), err)
+		}
+
+		revokedAt := revInfo.RevocationTimeUTC
+		if revokedAt.IsZero() {
+			revokedAt = time.Unix(revInfo.RevocationTime, 0)
+		}
+
+		return &acmeRenewalWindow{
+			Start:  revokedAt,
+			End:    revokedAt.Add(acmeRevokedRenewalWindow),
+			Forced: true,
+		}, nil
+	}
+
+	forced, err := getAcmeForcedRenewal(sc, cert)
+	if err != nil {
+		return nil, err
+	}
+	if forced != nil {
+		return &acmeRenewalWindow{
+			Start:  forced.ForcedAt,
+			End:    forced.ForcedAt.Add(forced.Window),
+			Forced: true,
+		}, nil
+	}
+
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return &acmeRenewalWindow{
+		Start: cert.NotBefore.Add(lifetime / 100 * time.Duration(config.ARIWindowStart)),
+		End:   cert.NotBefore.Add(lifetime / 100 * time.Duration(config.ARIWindowEnd)),
+	}, nil
+}
+
+// getAcmeForcedRenewal returns the earliest renewal forced on the given
+// certificate, either directly or through its issuer, or nil if there is none.
+func getAcmeForcedRenewal(sc *storageContext, cert *x509.Certificate) (*acmeForcedRenewalEntry, error) {
+	var earliest *acmeForcedRenewalEntry
+
+	forced, err := getAcmeForcedRenewalEntry(sc, acmeRenewalSerialPrefix+normalizeSerialFromBigInt(cert.SerialNumber))
+	if err != nil {
+		return nil, err
+	}
+	if forced != nil {
+		earliest = forced
+	}
+
+	issuerIds, err := sc.Storage.List(sc.Context, acmeRenewalIssuerPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing forced issuer renewals: %w", err)
+	}
+
+	for _, issuerId := range issuerIds {
+		forced, err := getAcmeForcedRenewalEntry(sc, acmeRenewalIssuerPrefix+issuerId)
+		if err != nil {
+			return nil, err
+		}
+		if forced == nil || !cert.NotBefore.Before(forced.ForcedAt) {
+			continue
+		}
+		if earliest != nil && !forced.ForcedAt.Before(earliest.ForcedAt) {
+			continue
+		}
+
+		issuer, err := sc.fetchIssuerById(issuerID(issuerId))
+		if err != nil {
+			// The issuer may have been deleted since its renewal was forced.
+			continue
+		}
+		issuerCert, err := issuer.GetCertificate()
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(cert.AuthorityKeyId, issuerCert.SubjectKeyId) || cert.CheckSignatureFrom(issuerCert) != nil {
+			continue
+		}
+
+		earliest = forced
+	}
+
+	return earliest, nil
+}
+
+func getAcmeForcedRenewalEntry(sc *storageContext, path string) (*acmeForcedRenewalEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading forced renewal %s: %w", path, err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var forced acmeForcedRenewalEntry
+	if err := entry.DecodeJSON(&forced); err != nil {
+		return nil, fmt.Errorf("failed decoding forced renewal %s: %w", path, err)
+	}
+
+	return &forced, nil
+}
+
+// parseAcmeCertId parses an ARI certificate identifier, the base64url
+// encodings of the authority key identifier and of the DER encoded serial
+// number of a certificate separated by a dot.
+func parseAcmeCertId(certId string) ([]byte, *big.Int, error) {
+	rawKeyId, rawSerial, found := strings.Cut(certId, ".")
+	if !found {
+		return nil, nil, fmt.Errorf("%w: certificate identifier %s lacks a '.' separator", ErrMalformed, certId)
+	}
+
+	keyId, err := base64.RawURLEncoding.DecodeString(rawKeyId)
+	if err != nil || len(keyId) == 0 {
+		return nil, nil, fmt.Errorf("%w: invalid authority key identifier in certificate identifier %s", ErrMalformed, certId)
+	}
+
+	serialBytes, err := base64.RawURLEncoding.DecodeString(rawSerial)
+	if err != nil || len(serialBytes) == 0 || serialBytes[0]&0x80 != 0 {
+		return nil, nil, fmt.Errorf("%w: invalid serial number in certificate identifier %s", ErrMalformed, certId)
+	}
+
+	return keyId, new(big.Int).SetBytes(serialBytes), nil
+}
+
+// getAcmeCertId builds the ARI certificate identifier of the given
+// certificate.
+func getAcmeCertId(cert *x509.Certificate) string {
+	serialBytes := cert.SerialNumber.Bytes()
+	if len(serialBytes) == 0 || serialBytes[0]&0x80 != 0 {
+		// Keep the DER encoding of the serial number positive.
+		serialBytes = append([]byte{0}, serialBytes...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(cert.AuthorityKeyId) + "." + base64.RawURLEncoding.EncodeToString(serialBytes)
+}
+
+// fetchAcmeCertById returns the certificate stored by this mount with the
+// given ARI certificate identifier, or nil if there is none.
+func fetchAcmeCertById(sc *storageContext, certId string) (*x509.Certificate, error) {
+	keyId, serial, err := parseAcmeCertId(certId)
+	if err != nil {
+		return nil, err
+	}
+
+	certEntry, err := fetchCertBySerialBigInt(sc, "certs/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil {
+		return nil, nil
+	}
+
+	cert, err := x509.ParseCertificate(certEntry.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing certificate %s: %w", serialFromBigInt(serial), err)
+	}
+
+	if !bytes.Equal(cert.AuthorityKeyId, keyId) {
+		return nil, nil
+	}
+
+	return cert, nil
+}
+
+// getAcmeReplacedCert validates the certificate an order replaces: it must
+// have been issued to the same account, share an identifier with the order
+// and not have been replaced already.
+func (b *backend) getAcmeReplacedCert(ac *acmeContext, accountId string, identifiers []*ACMEIdentifier, certId string, orderId string) (*acmeCertEntry, error) {
+	cert, err := fetchAcmeCertById(ac.sc, certId)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("%w: no certificate with identifier %s to replace", ErrMalformed, certId)
+	}
+
+	acmeEntry, err := b.acmeState.GetIssuedCert(ac, accountId, serialFromCert(cert))
+	if err != nil {
+		return nil, fmt.Errorf("unable to replace certificate: %v: %w", err, ErrMalformed)
+	}
+
+	if acmeEntry.ReplacedBy != "" && acmeEntry.ReplacedBy != orderId {
+		return nil, fmt.Errorf("%w: certificate %s was replaced by order %s", ErrAlreadyReplaced, certId, acmeEntry.ReplacedBy)
+	}
+
+	sharesIdentifier := slices.ContainsFunc(identifiers, func(identifier *ACMEIdentifier) bool {
+		switch identifier.Type {
+		case ACMEDNSIdentifier:
+			return slices.Contains(cert.DNSNames, identifier.OriginalValue)
+		case ACMEIPIdentifier:
+			ip := net.ParseIP(identifier.Value)
+			return slices.ContainsFunc(cert.IPAddresses, ip.Equal)
+		default:
+			return false
+		}
+	})
+	if !sharesIdentifier {
+		return nil, fmt.Errorf("%w: the order shares no identifier with the certificate %s it replaces", ErrMalformed, certId)
+	}
+
+	return acmeEntry, nil
+}
+
+func pathAcmeForceRenewal(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/force-renewal",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"serial_numbers": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Serial numbers of the certificates to renew, in hyphen-separated or colon-separated hexadecimal`,
+			},
+			"issuer_ref": {
+				Type:        framework.TypeString,
+				Description: `Reference to an existing issuer name or issuer id; all the certificates it issued so far are to be renewed`,
+			},
+			"renewal_window": {
+				Type:        framework.TypeDurationSecond,
+				Description: `The duration of the renewal window suggested to ACME clients, starting now; defaults to one hour`,
+				Default:     "1h",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathAcmeForceRenewal,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "force",
+					OperationSuffix: "acme-renewal",
+				},
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathAcmeForceRenewalHelpSyn,
+		HelpDescription: pathAcmeForceRenewalHelpDesc,
+	}
+}
+
+func (b *backend) pathAcmeForceRenewal(ctx context.Context, r *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, r.Storage)
+
+	serials := data.Get("serial_numbers").([]string)
+	issuerRef := data.Get("issuer_ref").(string)
+	if len(serials) == 0 && issuerRef == "" {
+		return logical.ErrorResponse("either serial_numbers or issuer_ref must be given"), nil
+	}
+
+	window := time.Duration(data.Get("renewal_window").(int)) * time.Second
+	if window <= 0 {
+		return logical.ErrorResponse("renewal_window must be positive"), nil
+	}
+
+	for index, serial := range serials {
+		certEntry, err := fetchCertBySerial(sc, "certs/", serial)
+		if err != nil {
+			return nil, err
+		}
+		if certEntry == nil {
+			return logical.ErrorResponse("certificate with serial %s not found", serial), nil
+		}
+		if _, err := x509.ParseCertificate(certEntry.Value); err != nil {
+			return logical.ErrorResponse("unable to parse certificate with serial %s: %v", serial, err), nil
+		}
+		serials[index] = denormalizeSerial(serial)
+	}
+
+	var issuerId issuerID
+	if issuerRef != "" {
+		var err error
+		issuerId, err = sc.resolveIssuerReference(issuerRef)
+		if err != nil {
+			return logical.ErrorResponse("unable to resolve issuer %s: %v", issuerRef, err), nil
+		}
+	}
+
+	// Stale forced renewals are removed as new ones are stored, which
+	// bounds their number
+	if err := tidyAcmeForcedRenewals(sc); err != nil {
+		return nil, err
+	}
+
+	forced := &acmeForcedRenewalEntry{
+		ForcedAt: time.Now(),
+		Window:   window,
+	}
+
+	var paths []string
+	for _, serial := range serials {
+		paths = append(paths, acmeRenewalSerialPrefix+normalizeSerial(serial))
+	}
+	if issuerId != "" {
+		paths = append(paths, acmeRenewalIssuerPrefix+issuerId.String())
+	}
+
+	for _, path := range paths {
+		json, err := logical.StorageEntryJSON(path, forced)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating storage entry: %w", err)
+		}
+		if err := sc.Storage.Put(sc.Context, json); err != nil {
+			return nil, fmt.Errorf("failed writing storage entry: %w", err)
+		}
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"forced_at":      forced.ForcedAt.Format(time.RFC3339),
+			"renewal_window": int64(window.Seconds()),
+			"serial_numbers": serials,
+		},
+	}
+	if issuerId != "" {
+		resp.Data["issuer_id"] = issuerId
+	}
+
+	return resp, nil
+}
+
+// tidyAcmeForcedRenewals removes the forced renewals of certificates which
+// expired or were tidied, and of issuers which were deleted.
+func tidyAcmeForcedRenewals(sc *storageContext) error {
+	serials, err := sc.Storage.List(sc.Context, acmeRenewalSerialPrefix)
+	if err != nil {
+		return fmt.Errorf("failed listing forced certificate renewals: %w", err)
+	}
+
+	now := time.Now()
+	for _, serial := range serials {
+		certEntry, err := fetchCertBySerial(sc, "certs/", serial)
+		if err != nil {
+			return err
+		}
+
+		stale := certEntry == nil
+		if !stale {
+			cert, err := x509.ParseCertificate(certEntry.Value)
+			if err != nil {
+				return fmt.Errorf("failed parsing certificate %s: %w", serial, err)
+			}
+			stale = now.After(cert.NotAfter)
+		}
+
+		if stale {
+			if err := sc.Storage.Delete(sc.Context, acmeRenewalSerialPrefix+serial); err != nil {
+				return err
+			}
+		}
+	}
+
+	issuerIds, err := sc.Storage.List(sc.Context, acmeRenewalIssuerPrefix)
+	if err != nil {
+		return fmt.Errorf("failed listing forced issuer renewals: %w", err)
+	}
+
+	knownIssuers, err := sc.listIssuers()
+	if err != nil {
+		return err
+	}
+
+	for _, issuerId := range issuerIds {
+		if !slices.Contains(knownIssuers, issuerID(issuerId)) {
+			if err := sc.Storage.Delete(sc.Context, acmeRenewalIssuerPrefix+issuerId); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+
+	"github.com/openbao/openbao/api/v2"
+)
+
+func TestAcmeCertId(t *testing.T) {
+	t.Parallel()
+
+	// Example of RFC 9773 Section 4.1.
+	cert := &x509.Certificate{
+		AuthorityKeyId: []byte{0x69, 0x88, 0x5b, 0x6b, 0x87, 0x46, 0x40, 0x41, 0xe1, 0xb3, 0x7b, 0x84, 0x7b, 0xa0, 0xae, 0x2c, 0xde, 0x01, 0xc8, 0xd4},
+		SerialNumber:   big.NewInt(0x87654321),
+	}
+	certId := getAcmeCertId(cert)
+	require.Equal(t, "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE", certId)
+
+	keyId, serial, err := parseAcmeCertId(certId)
+	require.NoError(t, err)
+	require.Equal(t, cert.AuthorityKeyId, keyId)
+	require.Equal(t, cert.SerialNumber, serial)
+
+	for _, badCertId := range []string{"", "aYhba4dGQEHhs3uEe6CuLN4ByNQ", ".AIdlQyE", "aYhba4dGQEHhs3uEe6CuLN4ByNQ.", "aYhba4dGQEHhs3uEe6CuLN4ByNQ.h2VDIQ", "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdl+yE"} {
+		_, _, err := parseAcmeCertId(badCertId)
+		require.ErrorIs(t, err, ErrMalformed, "expected %q to be rejected", badCertId)
+	}
+}
+
+// TestAcmeRenewalInfo validates the renewal windows returned by the ARI
+// endpoint, their configuration and forced renewals, and the replacement of
+// certificates by new orders.
+func TestAcmeRenewalInfo(t *testing.T) {
+	t.Parallel()
+
+	cluster, client, _ := setupAcmeBackend(t)
+	defer cluster.Cleanup()
+	testCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "failed creating ec key")
+	acmeClient := getAcmeClientForCluster(t, cluster, "/v1/pki/acme/", accountKey)
+
+	// The directory advertises the renewal information endpoint.
+	dir, err := acmeClient.Discover(testCtx)
+	require.NoError(t, err, "failed acme discovery call")
+	dirResp, err := client.Logical().ReadRawWithContext(testCtx, "pki/acme/directory")
+	require.NoError(t, err, "failed reading ACME directory")
+	var directory map[string]interface{}
+	require.NoError(t, json.NewDecoder(dirResp.Body).Decode(&directory))
+	_ = dirResp.Body.Close()
+	require.Equal(t, strings.TrimSuffix(dir.NonceURL, "new-nonce")+"renewal-info", directory["renewalInfo"])
+
+	_, certs := doACMEWorkflow(t, client, acmeClient)
+	cert, err := x509.ParseCertificate(certs[0])
+	require.NoError(t, err, "failed parsing acme cert")
+	certId := getAcmeCertId(cert)
+
+	// By default, the window spans from 66% to 80% of the certificate lifetime.
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	info, retryAfter := requireAcmeRenewalInfo(t, client, certId)
+	requireAcmeRenewalWindow(t, info, cert.NotBefore.Add(lifetime/100*66), cert.NotBefore.Add(lifetime/100*80))
+	require.Empty(t, info["explanationURL"])
+	require.Equal(t, "21600", retryAfter)
+
+	// Unknown certificates are not found, malformed identifiers rejected.
+	unknownCert := &x509.Certificate{AuthorityKeyId: cert.AuthorityKeyId, SerialNumber: big.NewInt(42)}
+	requireAcmeRenewalInfoError(t, client, getAcmeCertId(unknownCert), http.StatusNotFound, "malformed")
+	otherKeyCert := &x509.Certificate{AuthorityKeyId: []byte{0x42}, SerialNumber: cert.SerialNumber}
+	requireAcmeRenewalInfoError(t, client, getAcmeCertId(otherKeyCert), http.StatusNotFound, "malformed")
+	requireAcmeRenewalInfoError(t, client, "not-a-cert-id", http.StatusBadRequest, "malformed")
+
+	// The window and retry period are configurable.
+	_, err = client.Logical().WriteWithContext(testCtx, "pki/config/acme", map[string]interface{}{
+		"ari_window_start": 80,
+		"ari_window_end":   50,
+	})
+	require.Error(t, err, "expected inverted renewal window to be rejected")
+	resp, err := client.Logical().WriteWithContext(testCtx, "pki/config/acme", map[string]interface{}{
+		"ari_window_start":    10,
+		"ari_window_end":      20,
+		"ari_retry_after":     "1h",
+		"ari_explanation_url": "https://example.com/incident",
+	})
+	require.NoError(t, err, "failed updating ACME configuration")
+	require.Equal(t, json.Number("10"), resp.Data["ari_window_start"])
+	require.Equal(t, json.Number("20"), resp.Data["ari_window_end"])
+	require.Equal(t, json.Number("3600"), resp.Data["ari_retry_after"])
+
+	info, retryAfter = requireAcmeRenewalInfo(t, client, certId)
+	requireAcmeRenewalWindow(t, info, cert.NotBefore.Add(lifetime/100*10), cert.NotBefore.Add(lifetime/100*20))
+	require.Empty(t, info["explanationURL"], "explanation URLs are only given for forced renewals")
+	require.Equal(t, "3600", retryAfter)
+
+	// Forcing the renewal of the certificate makes its window start now.
+	_, err = client.Logical().WriteWithContext(testCtx, "pki/acme/force-renewal", map[string]interface{}{})
+	require.Error(t, err, "expected force renewal without certificates to be rejected")
+	_, err = client.Logical().WriteWithContext(testCtx, "pki/acme/force-renewal", map[string]interface{}{
+		"serial_numbers": "01:02:03",
+	})
+	require.Error(t, err, "expected force renewal of an unknown certificate to be rejected")
+
+	resp, err = client.Logical().WriteWithContext(testCtx, "pki/acme/force-renewal", map[string]interface{}{
+		"serial_numbers": serialFromCert(cert),
+	})
+	require.NoError(t, err, "failed forcing certificate renewal")
+	forcedAt, err := time.Parse(time.RFC3339, resp.Data["forced_at"].(string))
+	require.NoError(t, err, "failed parsing forced_at")
+
+	info, _ = requireAcmeRenewalInfo(t, client, certId)
+	requireAcmeRenewalWindow(t, info, forcedAt, forcedAt.Add(time.Hour))
+	require.Equal(t, "https://example.com/incident", info["explanationURL"])
+
+	// A new order replaces the certificate.
+	order := newAcmeOrderReplacing(t, acmeClient, certId, http.StatusCreated)
+	require.Equal(t, certId, order["replaces"])
+	acct, err := acmeClient.GetReg(testCtx, "")
+	require.NoError(t, err, "failed looking up account")
+	acmeOrder, err := acmeClient.GetOrder(testCtx, order["location"].(string))
+	require.NoError(t, err, "failed fetching replacing order")
+	markAuthorizationSuccess(t, client, acmeClient, acct, acmeOrder)
+
+	csrKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "failed generated key for CSR")
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{"*.localdomain"}}, csrKey)
+	require.NoError(t, err, "failed generating csr")
+	replacingCerts, _, err := acmeClient.CreateOrderCert(testCtx, acmeOrder.FinalizeURL, csr, true)
+	require.NoError(t, err, "failed finalizing replacing order")
+	replacingCert, err := x509.ParseCertificate(replacingCerts[0])
+	require.NoError(t, err, "failed parsing replacing cert")
+
+	// The certificate cannot be replaced twice, and only certificates of
+	// the account sharing identifiers with the order can be replaced.
+	problem := newAcmeOrderReplacing(t, acmeClient, certId, http.StatusConflict)
+	require.Equal(t, "urn:ietf:params:acme:error:alreadyReplaced", problem["type"])
+	problem = newAcmeOrderReplacing(t, acmeClient, getAcmeCertId(unknownCert), http.StatusBadRequest)
+	require.Equal(t, "urn:ietf:params:acme:error:malformed", problem["type"])
+
+	_, err = client.Logical().WriteWithContext(testCtx, "pki/roles/other", map[string]interface{}{
+		"allow_any_name": true,
+	})
+	require.NoError(t, err, "failed creating role")
+	resp, err = client.Logical().WriteWithContext(testCtx, "pki/issue/other", map[string]interface{}{
+		"common_name": "other.example.com",
+		"ttl":         "1h",
+	})
+	require.NoError(t, err, "failed issuing certificate")
+	otherCert := parseCert(t, resp.Data["certificate"].(string))
+	problem = newAcmeOrderReplacing(t, acmeClient, getAcmeCertId(otherCert), http.StatusBadRequest)
+	require.Equal(t, "urn:ietf:params:acme:error:malformed", problem["type"])
+
+	// Forcing the renewal of all the certificates of the issuer applies to
+	// the replacing certificate.
+	resp, err = client.Logical().WriteWithContext(testCtx, "pki/acme/force-renewal", map[string]interface{}{
+		"issuer_ref":     "default",
+		"renewal_window": "2h",
+	})
+	require.NoError(t, err, "failed forcing issuer renewal")
+	require.NotEmpty(t, resp.Data["issuer_id"])
+	forcedAt, err = time.Parse(time.RFC3339, resp.Data["forced_at"].(string))
+	require.NoError(t, err, "failed parsing forced_at")
+
+	info, _ = requireAcmeRenewalInfo(t, client, getAcmeCertId(replacingCert))
+	requireAcmeRenewalWindow(t, info, forcedAt, forcedAt.Add(2*time.Hour))
+
+	// Revoked certificates are to be renewed right away.
+	err = acmeClient.RevokeCert(testCtx, nil, replacingCerts[0], acme.CRLReasonUnspecified)
+	require.NoError(t, err, "failed revoking certificate")
+	certResp, err := client.Logical().ReadWithContext(testCtx, "pki/cert/"+serialFromCert(replacingCert))
+	require.NoError(t, err, "failed reading certificate")
+	revocationTime, err := certResp.Data["revocation_time"].(json.Number).Int64()
+	require.NoError(t, err, "failed parsing revocation_time")
+	revokedAt := time.Unix(revocationTime, 0)
+
+	info, _ = requireAcmeRenewalInfo(t, client, getAcmeCertId(replacingCert))
+	requireAcmeRenewalWindow(t, info, revokedAt, revokedAt.Add(time.Minute))
+	require.Equal(t, "https://example.com/incident", info["explanationURL"])
+}
+
+func requireAcmeRenewalInfo(t *testing.T, client *api.Client, certId string) (map[string]interface{}, string) {
+	t.Helper()
+
+	resp, err := client.Logical().ReadRawWithContext(context.Background(), "pki/acme/renewal-info/"+certId)
+	require.NoError(t, err, "failed reading renewal information of %s", certId)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var info map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info), "failed decoding renewal information")
+
+	return info, resp.Header.Get("Retry-After")
+}
+
+func requireAcmeRenewalInfoError(t *testing.T, client *api.Client, certId string, statusCode int, problemType string) {
+	t.Helper()
+
+	resp, err := client.Logical().ReadRawWithContext(context.Background(), "pki/acme/renewal-info/"+certId)
+	require.Error(t, err, "expected reading renewal information of %s to fail", certId)
+	defer resp.Body.Close()
+	require.Equal(t, statusCode, resp.StatusCode)
+	require.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+	var problem map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem), "failed decoding problem")
+	require.Equal(t, "urn:ietf:params:acme:error:"+problemType, problem["type"])
+}
+
+func requireAcmeRenewalWindow(t *testing.T, info map[string]interface{}, start time.Time, end time.Time) {
+	t.Helper()
+
+	window, ok := info["suggestedWindow"].(map[string]interface{})
+	require.True(t, ok, "missing suggestedWindow in %v", info)
+	require.Equal(t, start.UTC().Format(time.RFC3339), window["start"])
+	require.Equal(t, end.UTC().Format(time.RFC3339), window["end"])
+}
+
+// newAcmeOrderReplacing creates a new order for *.localdomain replacing the
+// given certificate. It returns the decoded response, along with the order
+// location.
+func newAcmeOrderReplacing(t *testing.T, acmeClient *acme.Client, certId string, statusCode int) map[string]interface{} {
+	t.Helper()
+	return newAcmeOrderWithFields(t, acmeClient, map[string]interface{}{"replaces": certId}, statusCode)
+}
+
+// newAcmeOrderWithFields creates a new order for *.localdomain with the given
+// additional fields, signing the request itself as the ACME client lacks
+// support for them. It returns the decoded response, along with the order
+// location.
+func newAcmeOrderWithFields(t *testing.T, acmeClient *acme.Client, fields map[string]interface{}, statusCode int) map[string]interface{} {
+	t.Helper()
+	testCtx := context.Background()
+
+	dir, err := acmeClient.Discover(testCtx)
+	require.NoError(t, err, "failed acme discovery call")
+	acct, err := acmeClient.GetReg(testCtx, "")
+	require.NoError(t, err, "failed looking up account")
+
+	nonceResp, err := acmeClient.HTTPClient.Head(dir.NonceURL)
+	require.NoError(t, err, "failed fetching nonce")
+	_ = nonceResp.Body.Close()
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: acmeClient.Key}, &jose.SignerOptions{
+		ExtraHeaders: map[jose.HeaderKey]interface{}{
+			"kid":   acct.URI,
+			"nonce": nonceResp.Header.Get("Replay-Nonce"),
+			"url":   dir.OrderURL,
+		},
+	})
+	require.NoError(t, err, "failed creating JWS signer")
+
+	order := map[string]interface{}{
+		"identifiers": []map[string]interface{}{{"type": "dns", "value": "*.localdomain"}},
+	}
+	for k, v := range fields {
+		order[k] = v
+	}
+	payload, err := json.Marshal(order)
+	require.NoError(t, err, "failed encoding order")
+	jws, err := signer.Sign(payload)
+	require.NoError(t, err, "failed signing order")
+
+	resp, err := acmeClient.HTTPClient.Post(dir.OrderURL, "application/jose+json", bytes.NewBufferString(jws.FullSerialize()))
+	require.NoError(t, err, "failed posting order")
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "failed reading order response")
+	require.Equal(t, statusCode, resp.StatusCode, "unexpected response: %s", string(body))
+
+	var orderResp map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &orderResp), "failed decoding order response")
+	orderResp["location"] = resp.Header.Get("Location")
+
+	return orderResp
+}
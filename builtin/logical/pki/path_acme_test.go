@@ -1305,7 +1305,7 @@ func setupAcmeBackendOnClusterAtPath(t *testing.T, cluster *vault.TestCluster, c
 
 	// Allow certain headers to pass through for ACME support
 	_, err = client.WithNamespace(namespace).Logical().WriteWithContext(context.Background(), "sys/mounts/"+mountName+"/tune", map[string]interface{}{
-		"allowed_response_headers": []string{"Last-Modified", "Replay-Nonce", "Link", "Location"},
+		"allowed_response_headers": []string{"Last-Modified", "Replay-Nonce", "Link", "Location", "Retry-After"},
 		"max_lease_ttl":            "920000h",
 	})
 	require.NoError(t, err, "failed tuning mount response headers")
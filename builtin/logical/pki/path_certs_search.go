@@ -0,0 +1,302 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func pathSearchCerts(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "certs/search$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+			OperationVerb:   "search",
+			OperationSuffix: "certs",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"cert_metadata": {
+				Type:        framework.TypeKVPairs,
+				Description: `Custom metadata key-value pairs which must all be set on matching certificates.`,
+			},
+			"issuer_ref": {
+				Type:        framework.TypeString,
+				Description: `Reference to an existing issuer, either by its ID or name, which must have issued matching certificates.`,
+			},
+			"issued_after": {
+				Type:        framework.TypeTime,
+				Description: `Matching certificates must have a NotBefore date after this time.`,
+			},
+			"issued_before": {
+				Type:        framework.TypeTime,
+				Description: `Matching certificates must have a NotBefore date before this time.`,
+			},
+			"expires_after": {
+				Type:        framework.TypeTime,
+				Description: `Matching certificates must have a NotAfter date after this time.`,
+			},
+			"expires_before": {
+				Type:        framework.TypeTime,
+				Description: `Matching certificates must have a NotAfter date before this time.`,
+			},
+			"revoked": {
+				Type:        framework.TypeBool,
+				Description: `If set, whether matching certificates must be revoked (true) or not (false). Defaults to returning both.`,
+			},
+			"after": {
+				Type:        framework.TypeString,
+				Description: `Optional entry to begin listing after, not required to exist.`,
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Description: `Optional number of entries to return; defaults to all entries.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathSearchCertsWrite,
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: "OK",
+						Fields: map[string]*framework.FieldSchema{
+							"keys": {
+								Type:        framework.TypeStringSlice,
+								Description: `Serial numbers of the matching certificates`,
+								Required:    true,
+							},
+							"key_info": {
+								Type:        framework.TypeMap,
+								Description: `Key info with certificate details`,
+								Required:    false,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    pathSearchCertsHelpSyn,
+		HelpDescription: pathSearchCertsHelpDesc,
+	}
+}
+
+// certSearchFilter holds the criteria stored certificates must match to be
+// returned by a search.
+type certSearchFilter struct {
+	metadata      map[string]string
+	issuer        *x509.Certificate
+	issuedAfter   time.Time
+	issuedBefore  time.Time
+	expiresAfter  time.Time
+	expiresBefore time.Time
+	revoked       *bool
+}
+
+// certSearchPageSize is the number of stored certificates listed at a time
+// while searching.
+const certSearchPageSize = 1000
+
+func (b *backend) pathSearchCertsWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	after := normalizeSerial(data.Get("after").(string))
+	limit := data.Get("limit").(int)
+	if limit <= 0 {
+		limit = -1
+	}
+
+	filter := &certSearchFilter{
+		metadata:      data.Get("cert_metadata").(map[string]string),
+		issuedAfter:   data.Get("issued_after").(time.Time),
+		issuedBefore:  data.Get("issued_before").(time.Time),
+		expiresAfter:  data.Get("expires_after").(time.Time),
+		expiresBefore: data.Get("expires_before").(time.Time),
+	}
+	if revokedRaw, ok := data.GetOk("revoked"); ok {
+		revoked := revokedRaw.(bool)
+		filter.revoked = &revoked
+	}
+
+	// Use a read-only transaction if available, so all certificates are
+	// matched against a consistent snapshot of storage.
+	if txnStorage, ok := req.Storage.(logical.TransactionalStorage); ok {
+		readOnlyTxn, err := txnStorage.BeginReadOnlyTx(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start read-only transaction: %w", err)
+		}
+
+		defer readOnlyTxn.Rollback(ctx)
+		req.Storage = readOnlyTxn
+	}
+
+	sc := b.makeStorageContext(ctx, req.Storage)
+
+	if issuerRef := data.Get("issuer_ref").(string); len(issuerRef) > 0 {
+		issuerId, err := sc.resolveIssuerReference(issuerRef)
+		if err != nil {
+			switch err.(type) {
+			case errutil.UserError:
+				return logical.ErrorResponse(err.Error()), nil
+			default:
+				return nil, err
+			}
+		}
+
+		issuer, err := sc.fetchIssuerById(issuerId)
+		if err != nil {
+			return nil, err
+		}
+
+		filter.issuer, err = issuer.GetCertificate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only certificates with metadata can match a metadata filter, so avoid
+	// scanning every stored certificate in that case.
+	prefix := "certs/"
+	if len(filter.metadata) > 0 {
+		prefix = certMetadataPrefix
+	}
+
+	keys := []string{}
+	keyInfo := make(map[string]interface{})
+	for limit <= 0 || len(keys) < limit {
+		// Page through storage rather than listing every certificate at
+		// once; filtering means a page may yield fewer matches than asked.
+		candidates, err := req.Storage.ListPage(ctx, prefix, after, certSearchPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		// Not every storage backend returns entries in lexicographical
+		// order, which the after cursor relies on.
+		sort.Strings(candidates)
+
+		for _, candidate := range candidates {
+			if limit > 0 && len(keys) >= limit {
+				break
+			}
+			if candidate <= after {
+				continue
+			}
+
+			info, err := filter.matches(sc, candidate)
+			if err != nil {
+				return nil, err
+			}
+			if info == nil {
+				continue
+			}
+
+			serial := denormalizeSerial(candidate)
+			keys = append(keys, serial)
+			keyInfo[serial] = info
+		}
+
+		if len(candidates) < certSearchPageSize {
+			break
+		}
+		after = candidates[len(candidates)-1]
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+// matches returns the details of the certificate stored under the given
+// serial number if it matches the filter, or nil otherwise.
+func (f *certSearchFilter) matches(sc *storageContext, serial string) (map[string]interface{}, error) {
+	metadata, err := sc.fetchCertMetadata(serial)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range f.metadata {
+		if value, ok := metadata[k]; !ok || value != v {
+			return nil, nil
+		}
+	}
+
+	certEntry, err := sc.Storage.Get(sc.Context, "certs/"+serial)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching certificate %s: %w", serial, err)
+	}
+	if certEntry == nil || len(certEntry.Value) == 0 {
+		// The metadata may outlive its certificate until the next tidy.
+		return nil, nil
+	}
+
+	cert, err := x509.ParseCertificate(certEntry.Value)
+	if err != nil {
+		// Invalid certificates are left to tidy_invalid_certs.
+		return nil, nil
+	}
+
+	if !f.issuedAfter.IsZero() && !cert.NotBefore.After(f.issuedAfter) {
+		return nil, nil
+	}
+	if !f.issuedBefore.IsZero() && !cert.NotBefore.Before(f.issuedBefore) {
+		return nil, nil
+	}
+	if !f.expiresAfter.IsZero() && !cert.NotAfter.After(f.expiresAfter) {
+		return nil, nil
+	}
+	if !f.expiresBefore.IsZero() && !cert.NotAfter.Before(f.expiresBefore) {
+		return nil, nil
+	}
+
+	if f.issuer != nil {
+		if !bytes.Equal(cert.RawIssuer, f.issuer.RawSubject) || cert.CheckSignatureFrom(f.issuer) != nil {
+			return nil, nil
+		}
+	}
+
+	revokedEntry, err := sc.Storage.Get(sc.Context, revokedPath+serial)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching revocation status of %s: %w", serial, err)
+	}
+	revoked := revokedEntry != nil
+	if f.revoked != nil && *f.revoked != revoked {
+		return nil, nil
+	}
+
+	info := map[string]interface{}{
+		"common_name": cert.Subject.CommonName,
+		"issuer":      cert.Issuer.String(),
+		"not_before":  cert.NotBefore,
+		"not_after":   cert.NotAfter,
+		"revoked":     revoked,
+	}
+	if len(metadata) > 0 {
+		info["cert_metadata"] = metadata
+	}
+
+	return info, nil
+}
+
+const pathSearchCertsHelpSyn = `
+Search stored certificates by their metadata, issuer, validity and revocation status.
+`
+
+const pathSearchCertsHelpDesc = `
+This endpoint returns the serial numbers of the stored certificates having
+all of the given "cert_metadata" key-value pairs, issued by "issuer_ref",
+valid from between "issued_after" and "issued_before", expiring between
+"expires_after" and "expires_before", and which are revoked or not per
+"revoked". All criteria are optional.
+
+The results are sorted and can be paginated with "after" and "limit", as
+when listing certificates.
+`
@@ -0,0 +1,350 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCertMetadata validates that custom metadata is accepted on issuance
+// subject to the role, and returned when reading the certificate metadata.
+func TestCertMetadata(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root R1",
+		"key_type":    "ec",
+		"ttl":         "48h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+
+	resp, err = CBWrite(b, s, "roles/web", map[string]interface{}{
+		"allow_any_name":        true,
+		"key_type":              "ec",
+		"allowed_cert_metadata": "service,team-*",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed creating role")
+	resp, err = CBRead(b, s, "roles/web")
+	requireSuccessNonNilResponse(t, resp, err, "failed reading role")
+	require.Equal(t, []string{"service", "team-*"}, resp.Data["allowed_cert_metadata"])
+
+	resp, err = CBWrite(b, s, "roles/nostore", map[string]interface{}{
+		"allow_any_name":        true,
+		"key_type":              "ec",
+		"no_store":              true,
+		"allowed_cert_metadata": "*",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed creating role")
+
+	// Keys must be allowed by the role, which must store certificates, and
+	// values must fit within the limits.
+	for _, tc := range []struct {
+		role     string
+		metadata map[string]interface{}
+	}{
+		{"web", map[string]interface{}{"owner": "alice"}},
+		{"web", map[string]interface{}{"service": strings.Repeat("a", maxCertMetadataValueLength+1)}},
+		{"nostore", map[string]interface{}{"service": "api"}},
+	} {
+		resp, err = CBWrite(b, s, "issue/"+tc.role, map[string]interface{}{
+			"common_name":   "example.com",
+			"cert_metadata": tc.metadata,
+		})
+		require.Error(t, err, "expected metadata %v to be rejected by role %s", tc.metadata, tc.role)
+		require.True(t, resp.IsError())
+	}
+
+	resp, err = CBWrite(b, s, "issue/web", map[string]interface{}{
+		"common_name": "web.example.com",
+		"cert_metadata": map[string]interface{}{
+			"service":   "web",
+			"team-name": "frontend",
+		},
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed issuing certificate")
+	serial := resp.Data["serial_number"].(string)
+
+	resp, err = CBRead(b, s, "cert/"+serial+"/metadata")
+	requireSuccessNonNilResponse(t, resp, err, "failed reading certificate metadata")
+	require.Equal(t, serial, resp.Data["serial_number"])
+	require.Equal(t, map[string]string{"service": "web", "team-name": "frontend"}, resp.Data["cert_metadata"])
+
+	// The unauthenticated certificate path does not return the metadata.
+	resp, err = CBRead(b, s, "cert/"+serial)
+	requireSuccessNonNilResponse(t, resp, err, "failed reading certificate")
+	require.NotContains(t, resp.Data, "cert_metadata")
+
+	// Metadata is optional.
+	resp, err = CBWrite(b, s, "issue/web", map[string]interface{}{
+		"common_name": "plain.example.com",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed issuing certificate")
+
+	resp, err = CBRead(b, s, "cert/"+resp.Data["serial_number"].(string)+"/metadata")
+	requireSuccessNonNilResponse(t, resp, err, "failed reading certificate metadata")
+	require.Empty(t, resp.Data["cert_metadata"])
+
+	resp, err = CBRead(b, s, "cert/01:02:03/metadata")
+	require.NoError(t, err)
+	require.Nil(t, resp, "expected no metadata for an unknown certificate")
+
+	// Signing a CSR through a role or verbatim accepts metadata too.
+	_, _, csrPem := generateCSR(t, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "signed.example.com"},
+	}, "ec", 256)
+	resp, err = CBWrite(b, s, "sign/web", map[string]interface{}{
+		"csr":           csrPem,
+		"common_name":   "signed.example.com",
+		"cert_metadata": "service=signer",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed signing certificate")
+
+	resp, err = CBRead(b, s, "cert/"+resp.Data["serial_number"].(string)+"/metadata")
+	requireSuccessNonNilResponse(t, resp, err, "failed reading certificate metadata")
+	require.Equal(t, map[string]string{"service": "signer"}, resp.Data["cert_metadata"])
+
+	resp, err = CBWrite(b, s, "sign-verbatim", map[string]interface{}{
+		"csr":           csrPem,
+		"cert_metadata": "owner=alice",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed signing certificate verbatim")
+
+	resp, err = CBRead(b, s, "cert/"+resp.Data["serial_number"].(string)+"/metadata")
+	requireSuccessNonNilResponse(t, resp, err, "failed reading certificate metadata")
+	require.Equal(t, map[string]string{"owner": "alice"}, resp.Data["cert_metadata"])
+
+	// Tidying up an expired certificate removes its metadata.
+	resp, err = CBWrite(b, s, "issue/web", map[string]interface{}{
+		"common_name":   "expiring.example.com",
+		"ttl":           "2s",
+		"cert_metadata": "service=expiring",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed issuing certificate")
+	expiringSerial := resp.Data["serial_number"].(string)
+
+	time.Sleep(3 * time.Second)
+	_, err = CBWrite(b, s, "tidy", map[string]interface{}{
+		"tidy_cert_store": true,
+		"safety_buffer":   "1s",
+	})
+	require.NoError(t, err)
+
+	for {
+		time.Sleep(125 * time.Millisecond)
+
+		resp, err = CBRead(b, s, "tidy-status")
+		requireSuccessNonNilResponse(t, resp, err, "failed reading tidy status")
+		state := resp.Data["state"].(string)
+		if state == "Finished" {
+			break
+		}
+		if state == "Error" {
+			t.Fatalf("unexpected state for tidy operation: Error:\nStatus: %v", resp.Data)
+		}
+	}
+
+	entry, err := s.Get(context.Background(), certMetadataPrefix+normalizeSerial(expiringSerial))
+	require.NoError(t, err)
+	require.Nil(t, entry, "expected metadata of tidied certificate to be removed")
+}
+
+// TestSearchCerts validates filtering stored certificates by metadata,
+// issuer, validity and revocation status.
+func TestSearchCerts(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "issuers/generate/root/internal", map[string]interface{}{
+		"common_name": "Root R1",
+		"issuer_name": "r1",
+		"key_type":    "ec",
+		"ttl":         "48h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+	rootSerial := resp.Data["serial_number"].(string)
+
+	resp, err = CBWrite(b, s, "issuers/generate/root/internal", map[string]interface{}{
+		"common_name": "Root R2",
+		"issuer_name": "r2",
+		"key_type":    "ec",
+		"ttl":         "48h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+	otherRootSerial := resp.Data["serial_number"].(string)
+
+	resp, err = CBWrite(b, s, "roles/web", map[string]interface{}{
+		"allow_any_name":        true,
+		"key_type":              "ec",
+		"allowed_cert_metadata": "*",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed creating role")
+
+	issue := func(issuer string, ttl string, metadata map[string]interface{}) string {
+		resp, err := CBWrite(b, s, "issuer/"+issuer+"/issue/web", map[string]interface{}{
+			"common_name":   "example.com",
+			"ttl":           ttl,
+			"cert_metadata": metadata,
+		})
+		requireSuccessNonNilResponse(t, resp, err, "failed issuing certificate")
+		return resp.Data["serial_number"].(string)
+	}
+	webSerial := issue("r1", "1h", map[string]interface{}{"service": "web", "env": "prod"})
+	webStagingSerial := issue("r1", "10h", map[string]interface{}{"service": "web", "env": "staging"})
+	dbSerial := issue("r2", "10h", map[string]interface{}{"service": "db", "env": "prod"})
+	plainSerial := issue("r1", "10h", nil)
+
+	_, err = CBWrite(b, s, "revoke", map[string]interface{}{
+		"serial_number": webStagingSerial,
+	})
+	require.NoError(t, err, "failed revoking certificate")
+
+	search := func(filter map[string]interface{}) []string {
+		resp, err := CBWrite(b, s, "certs/search", filter)
+		requireSuccessNonNilResponse(t, resp, err, "failed searching certificates")
+		keys, _ := resp.Data["keys"].([]string)
+		return keys
+	}
+
+	require.ElementsMatch(t, []string{rootSerial, otherRootSerial, webSerial, webStagingSerial, dbSerial, plainSerial}, search(nil))
+	require.ElementsMatch(t, []string{webSerial, webStagingSerial}, search(map[string]interface{}{
+		"cert_metadata": "service=web",
+	}))
+	require.ElementsMatch(t, []string{webSerial, dbSerial}, search(map[string]interface{}{
+		"cert_metadata": "env=prod",
+	}))
+	require.ElementsMatch(t, []string{webSerial}, search(map[string]interface{}{
+		"cert_metadata": map[string]interface{}{"service": "web", "env": "prod"},
+	}))
+	require.Empty(t, search(map[string]interface{}{
+		"cert_metadata": "service=mail",
+	}))
+
+	// The issuer of roots is themselves.
+	require.ElementsMatch(t, []string{otherRootSerial, dbSerial}, search(map[string]interface{}{
+		"issuer_ref": "r2",
+	}))
+	require.ElementsMatch(t, []string{webSerial, webStagingSerial}, search(map[string]interface{}{
+		"issuer_ref":    "r1",
+		"cert_metadata": "service=web",
+	}))
+	_, err = CBWrite(b, s, "certs/search", map[string]interface{}{
+		"issuer_ref": "unknown",
+	})
+	require.Error(t, err, "expected unknown issuer to be rejected")
+
+	require.ElementsMatch(t, []string{webSerial}, search(map[string]interface{}{
+		"cert_metadata":  "service=web",
+		"expires_before": time.Now().Add(2 * time.Hour).Format(time.RFC3339),
+	}))
+	require.ElementsMatch(t, []string{webStagingSerial, dbSerial, plainSerial}, search(map[string]interface{}{
+		"expires_after":  time.Now().Add(2 * time.Hour).Format(time.RFC3339),
+		"expires_before": time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+	}))
+	require.Empty(t, search(map[string]interface{}{
+		"issued_after": time.Now().Add(time.Hour).Format(time.RFC3339),
+	}))
+	require.ElementsMatch(t, []string{webSerial, dbSerial}, search(map[string]interface{}{
+		"cert_metadata": "env=prod",
+		"issued_after":  time.Now().Add(-time.Hour).Format(time.RFC3339),
+		"issued_before": time.Now().Add(time.Hour).Format(time.RFC3339),
+	}))
+
+	require.ElementsMatch(t, []string{webStagingSerial}, search(map[string]interface{}{
+		"revoked": true,
+	}))
+	require.ElementsMatch(t, []string{webSerial}, search(map[string]interface{}{
+		"cert_metadata": "service=web",
+		"revoked":       false,
+	}))
+
+	// Matching certificates are detailed and can be paginated.
+	resp, err = CBWrite(b, s, "certs/search", map[string]interface{}{
+		"cert_metadata": "service=web",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed searching certificates")
+	info := resp.Data["key_info"].(map[string]interface{})[webStagingSerial].(map[string]interface{})
+	require.Equal(t, "example.com", info["common_name"])
+	require.Equal(t, true, info["revoked"])
+	require.Equal(t, map[string]string{"service": "web", "env": "staging"}, info["cert_metadata"])
+
+	page := search(map[string]interface{}{
+		"cert_metadata": "env=prod",
+		"limit":         1,
+	})
+	require.Len(t, page, 1)
+	next := search(map[string]interface{}{
+		"cert_metadata": "env=prod",
+		"after":         page[0],
+	})
+	require.Len(t, next, 1)
+	require.ElementsMatch(t, []string{webSerial, dbSerial}, append(page, next...))
+}
+
+// TestSearchCerts_Pagination validates that searches spanning several pages
+// of storage return sorted results and honor after and limit.
+func TestSearchCerts_Pagination(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root R1",
+		"key_type":    "ec",
+		"ttl":         "48h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+	rootSerial := resp.Data["serial_number"].(string)
+
+	// Store copies of the root under enough serial numbers to span more
+	// than two pages.
+	ctx := context.Background()
+	entry, err := s.Get(ctx, "certs/"+normalizeSerial(rootSerial))
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+
+	expected := []string{rootSerial}
+	count := 2*certSearchPageSize + 5
+	for i := 0; i < count; i++ {
+		serial := fmt.Sprintf("00:00:%02x:%02x", i>>8, i&0xff)
+		require.NoError(t, s.Put(ctx, &logical.StorageEntry{
+			Key:   "certs/" + normalizeSerial(serial),
+			Value: entry.Value,
+		}))
+		expected = append(expected, serial)
+	}
+	sort.Strings(expected)
+
+	search := func(filter map[string]interface{}) []string {
+		resp, err := CBWrite(b, s, "certs/search", filter)
+		requireSuccessNonNilResponse(t, resp, err, "failed searching certificates")
+		keys, _ := resp.Data["keys"].([]string)
+		return keys
+	}
+
+	require.Equal(t, expected, search(nil))
+	require.Empty(t, search(map[string]interface{}{
+		"issued_after": time.Now().Add(time.Hour).Format(time.RFC3339),
+	}))
+
+	// Pages crossing storage page boundaries are contiguous.
+	limit := certSearchPageSize + 10
+	first := search(map[string]interface{}{
+		"limit": limit,
+	})
+	require.Equal(t, expected[:limit], first)
+	rest := search(map[string]interface{}{
+		"after": first[len(first)-1],
+	})
+	require.Equal(t, expected[limit:], rest)
+}
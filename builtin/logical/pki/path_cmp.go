@@ -0,0 +1,822 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/certutil"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	pathCmpHelpSyn  = `An endpoint implementing the CMP protocol`
+	pathCmpHelpDesc = `This API endpoint implements the Lightweight CMP profile
+ defined in RFC 9483 over HTTP, with its own authentication and argument
+ syntax that does not follow conventional OpenBao operations. A CMP client
+ tool or library should be used to interact with these endpoints.`
+
+	cmpContentType = "application/pkixcmp"
+
+	// cmpMaxRequestSize bounds the size of the CMP messages
+	cmpMaxRequestSize = 64 * 1024
+
+	cmpNonceLength = 16
+)
+
+type cmpContext struct {
+	sc     *storageContext
+	config *cmpConfigEntry
+	// label is the CMP label of the request path, empty for the default path
+	label    string
+	role     *roleEntry
+	issuer   *issuerEntry
+	verbatim bool
+	caCert   *x509.Certificate
+	caSigner crypto.Signer
+	// caChain is the DER certificate chain of the issuer
+	caChain [][]byte
+}
+
+// cmpRequest is a decoded CMP request, with its protection once verified.
+type cmpRequest struct {
+	msg        *cmpMessage
+	header     *cmpHeader
+	protection *cmpProtection
+}
+
+// cmpResponse is the body of a CMP response, and the information of its
+// header.
+type cmpResponse struct {
+	body            asn1.RawValue
+	senderNonce     []byte
+	implicitConfirm bool
+	// withChain is set for the responses including the certificate chain
+	// of the issuer in their extra certificates, as certificate responses
+	// do regardless of their protection
+	withChain bool
+}
+
+func pathCmp(b *backend) []*framework.Path {
+	var paths []*framework.Path
+	for _, pattern := range []string{
+		`\.well-known/cmp`,
+		`\.well-known/cmp/p/` + framework.GenericNameRegex("label"),
+	} {
+		fields := map[string]*framework.FieldSchema{}
+		if pattern != `\.well-known/cmp` {
+			fields["label"] = &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The CMP label, mapped to a role by the label_to_path_policy of the CMP configuration",
+			}
+		}
+
+		paths = append(paths, &framework.Path{
+			Pattern: pattern,
+			Fields:  fields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.cmpWrapper(b.cmpHandler),
+					ForwardPerformanceSecondary: false,
+					ForwardPerformanceStandby:   true,
+				},
+			},
+
+			HelpSynopsis:    pathCmpHelpSyn,
+			HelpDescription: pathCmpHelpDesc,
+		})
+	}
+
+	return paths
+}
+
+type cmpOperation func(cmpCtx *cmpContext, r *logical.Request, data *framework.FieldData) (*logical.Response, error)
+
+// cmpWrapper resolves the role and issuer the request path maps to, and
+// loads the CA signing bundle before calling the given CMP handler.
+func (b *backend) cmpWrapper(op cmpOperation) framework.OperationFunc {
+	return func(ctx context.Context, r *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		sc := b.makeStorageContext(ctx, r.Storage)
+
+		config, err := sc.getCmpConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch CMP configuration: %w", err)
+		}
+		if !config.Enabled {
+			return cmpErrorResponse(http.StatusNotFound, "CMP is not enabled on this mount"), nil
+		}
+
+		label := ""
+		if labelRaw, ok := data.GetOk("label"); ok {
+			label = labelRaw.(string)
+		}
+
+		policy := config.DefaultPathPolicy
+		if label != "" {
+			var ok bool
+			policy, ok = config.LabelToPathPolicy[label]
+			if !ok {
+				return cmpErrorResponse(http.StatusNotFound, "unknown CMP label %q", label), nil
+			}
+		}
+
+		role, err := getEnrollmentPathPolicyRole(sc, policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the role of CMP path policy %q: %w", policy, err)
+		}
+		if role == nil {
+			return cmpErrorResponse(http.StatusNotFound, "CMP is forbidden on this path"), nil
+		}
+
+		issuer, err := getEnrollmentIssuer(sc, role.Issuer)
+		if err != nil {
+			return nil, err
+		}
+
+		signingBundle, _, err := sc.fetchCAInfoWithIssuer(issuer.ID.String(), IssuanceUsage)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading CA %s: %w", issuer.ID.String(), err)
+		}
+
+		var caChain [][]byte
+		for _, certPem := range issuer.CAChain {
+			block, _ := pem.Decode([]byte(certPem))
+			if block == nil {
+				return nil, fmt.Errorf("failed to decode the CA chain of issuer %v", issuer.ID)
+			}
+			caChain = append(caChain, block.Bytes)
+		}
+
+		policyType, _ := getDefaultDirectoryPolicyType(policy)
+		return op(&cmpContext{
+			sc:       sc,
+			config:   config,
+			label:    label,
+			role:     role,
+			issuer:   issuer,
+			verbatim: policyType == SignVerbatim,
+			caCert:   signingBundle.Certificate,
+			caSigner: signingBundle.PrivateKey,
+			caChain:  caChain,
+		}, r, data)
+	}
+}
+
+func (b *backend) cmpHandler(cmpCtx *cmpContext, r *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	if r.HTTPRequest == nil || r.HTTPRequest.Body == nil {
+		return cmpErrorResponse(http.StatusUnsupportedMediaType, "no message in request body, expected %s content", cmpContentType), nil
+	}
+	defer r.HTTPRequest.Body.Close()
+
+	der, err := io.ReadAll(io.LimitReader(r.HTTPRequest.Body, cmpMaxRequestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(der) > cmpMaxRequestSize {
+		return cmpErrorResponse(http.StatusRequestEntityTooLarge, "request is too large"), nil
+	}
+
+	msg, header, err := parseCmpMessage(der)
+	if err != nil {
+		return cmpErrorResponse(http.StatusBadRequest, "%s", err), nil
+	}
+
+	req := &cmpRequest{
+		msg:    msg,
+		header: header,
+	}
+	senderNonce := make([]byte, cmpNonceLength)
+	if _, err := rand.Read(senderNonce); err != nil {
+		return nil, err
+	}
+
+	resp, err := b.cmpHandleRequest(cmpCtx, r, req, senderNonce)
+	if err != nil {
+		var failure *cmpFailure
+		if !errors.As(err, &failure) {
+			return nil, err
+		}
+
+		b.Logger().Debug("refusing CMP request", "failure", failure.reason)
+		resp, err = cmpErrorMessage(failure, senderNonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b.cmpRespond(cmpCtx, req, resp)
+}
+
+// cmpHandleRequest handles the given request, after checking its header.
+// Failures to be returned to the client as error messages are returned as
+// *cmpFailure errors.
+func (b *backend) cmpHandleRequest(cmpCtx *cmpContext, r *logical.Request, req *cmpRequest, senderNonce []byte) (*cmpResponse, error) {
+	if req.header.PVNO != 2 && req.header.PVNO != 3 {
+		return nil, newCmpFailure(cmpFailUnsupportedVersion, "unsupported protocol version %d", req.header.PVNO)
+	}
+	if len(req.header.TransactionID) == 0 || len(req.header.SenderNonce) == 0 {
+		return nil, newCmpFailure(cmpFailBadRequest, "messages must have a transaction ID and a sender nonce")
+	}
+
+	switch req.msg.Body.Tag {
+	case cmpBodyIR, cmpBodyCR, cmpBodyP10CR, cmpBodyKUR:
+		return b.cmpEnroll(cmpCtx, r, req, senderNonce)
+	case cmpBodyRR:
+		return b.cmpRevoke(cmpCtx, req, senderNonce)
+	case cmpBodyCertConf:
+		return b.cmpConfirm(cmpCtx, req, senderNonce)
+	case cmpBodyPollReq:
+		return b.cmpPoll(cmpCtx, req, senderNonce)
+	default:
+		return nil, newCmpFailure(cmpFailBadRequest, "unsupported message body type %d", req.msg.Body.Tag)
+	}
+}
+
+// cmpEnroll handles ir, cr and p10cr messages, which may be MAC-protected
+// with a shared secret or signed with a trusted certificate, and kur
+// messages, which must be signed with the certificate to update. Requests
+// signed with a certificate of this mount rather than a trusted one may only
+// request the subject and subject alternative names of that certificate.
+func (b *backend) cmpEnroll(cmpCtx *cmpContext, r *logical.Request, req *cmpRequest, senderNonce []byte) (*cmpResponse, error) {
+	sc := cmpCtx.sc
+	bodyType := req.msg.Body.Tag
+
+	protection, err := verifyCmpProtection(req.msg, req.header, sc.getCmpSecret)
+	if err != nil {
+		return nil, err
+	}
+	req.protection = protection
+
+	useSignerNames := false
+	if bodyType == cmpBodyKUR {
+		if protection.signer == nil {
+			return nil, newCmpFailure(cmpFailWrongIntegrity, "key update requests must be signed with the certificate to update")
+		}
+		reason, err := checkRenewableCertificate(sc, protection.signer)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			return nil, newCmpFailure(cmpFailSignerNotTrusted, "%s", reason)
+		}
+		useSignerNames = true
+	} else if protection.signer != nil {
+		useSignerNames, err = checkCmpSigner(cmpCtx, protection)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transaction, err := sc.getCmpTransaction(req.header.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	if transaction != nil {
+		return nil, newCmpFailure(cmpFailTransactionIdInUse, "transaction ID is already in use")
+	}
+
+	var csr *x509.CertificateRequest
+	certReqId := big.NewInt(-1)
+	if bodyType == cmpBodyP10CR {
+		csr, err = x509.ParseCertificateRequest(req.msg.Body.Bytes)
+		if err != nil {
+			return nil, newCmpFailure(cmpFailBadDataFormat, "failed to parse CSR: %s", err)
+		}
+		if err := validateEnrollmentCsr(csr); err != nil {
+			return nil, newCmpFailure(cmpFailBadPOP, "%s", err)
+		}
+		if useSignerNames && (!bytes.Equal(csr.RawSubject, protection.signer.RawSubject) || !sameSubjectAltNames(csr, protection.signer)) {
+			return nil, newCmpFailure(cmpFailBadCertTemplate, "the subject and subject alternative names of the CSR must match the signer certificate")
+		}
+	} else {
+		var certReq *cmpCertRequest
+		csr, certReq, err = parseCmpCertRequest(req.msg.Body.Bytes, protection.signer, useSignerNames)
+		if err != nil {
+			return nil, err
+		}
+		certReqId = certReq.CertReqId
+	}
+
+	// Shared secrets are single use, they can only protect the later
+	// messages of their transaction
+	if protection.secret != nil {
+		available, err := b.consumeCmpSecret(sc, protection.reference)
+		if err != nil {
+			return nil, err
+		}
+		if !available {
+			return nil, newCmpFailure(cmpFailBadMessageCheck, "unknown or expired secret reference")
+		}
+	}
+
+	var parsedBundle *certutil.ParsedCertBundle
+	if bodyType == cmpBodyP10CR {
+		parsedBundle, err = signEnrollmentCsr(b, sc, r, cmpCtx.role, cmpCtx.issuer, cmpCtx.verbatim, csr)
+	} else {
+		parsedBundle, err = signEnrollmentTemplate(b, sc, r, cmpCtx.role, cmpCtx.issuer, cmpCtx.verbatim, csr)
+	}
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, newCmpFailure(cmpFailBadCertTemplate, "%s", err)
+		default:
+			return nil, err
+		}
+	}
+
+	responseType := cmpBodyCP
+	switch bodyType {
+	case cmpBodyIR:
+		responseType = cmpBodyIP
+	case cmpBodyKUR:
+		responseType = cmpBodyKUP
+	}
+
+	implicitConfirm := req.header.hasImplicitConfirm()
+	if !implicitConfirm {
+		transaction := &cmpTransactionEntry{
+			ResponseType: responseType,
+			CertReqId:    certReqId.Int64(),
+			SerialNumber: serialFromCert(parsedBundle.Certificate),
+			SenderNonce:  senderNonce,
+			Expiration:   time.Now().Add(cmpTransactionTTL),
+		}
+		if protection.secret != nil {
+			transaction.SecretReference = protection.reference
+			transaction.Secret = string(protection.secret)
+		}
+		if err := sc.putCmpTransaction(req.header.TransactionID, transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := buildCmpCertResponse(cmpCtx, req, responseType, certReqId, parsedBundle.CertificateBytes, senderNonce)
+	if err != nil {
+		return nil, err
+	}
+	resp.implicitConfirm = implicitConfirm
+
+	return resp, nil
+}
+
+// parseCmpCertRequest parses the single certificate request of an ir, cr or
+// kur body, and verifies its signature proof of possession. The certificate
+// request equivalent to its template is returned; with useSignerNames, as for
+// key updates, it has the subject and subject alternative names of the
+// signer certificate instead.
+func parseCmpCertRequest(content []byte, signer *x509.Certificate, useSignerNames bool) (*x509.CertificateRequest, *cmpCertRequest, error) {
+	msg, certReq, err := parseCertReqMessages(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := certReq.CertTemplate
+	if useSignerNames {
+		template.Subject = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 5, IsCompound: true, Bytes: signer.RawSubject}
+		template.Extensions = nil
+		for _, ext := range signer.Extensions {
+			if ext.Id.Equal(certutil.ExtensionSubjectAltNameOID) {
+				template.Extensions = append(template.Extensions, ext)
+			}
+		}
+	}
+
+	csr, err := template.toCertificateRequest()
+	if err != nil {
+		return nil, nil, newCmpFailure(cmpFailBadCertTemplate, "invalid certificate template: %s", err)
+	}
+	if csr.PublicKey == nil {
+		return nil, nil, newCmpFailure(cmpFailBadCertTemplate, "unsupported public key algorithm in certificate template")
+	}
+	if err := validateEnrollmentRequest(csr); err != nil {
+		return nil, nil, newCmpFailure(cmpFailBadCertTemplate, "%s", err)
+	}
+
+	if msg.POPO.Class != asn1.ClassContextSpecific || msg.POPO.Tag != 1 {
+		return nil, nil, newCmpFailure(cmpFailBadPOP, "only signature proofs of possession are supported")
+	}
+	var popo cmpPOPOSigningKey
+	if rest, err := asn1.UnmarshalWithParams(msg.POPO.FullBytes, &popo, "tag:1"); err != nil || len(rest) > 0 {
+		return nil, nil, newCmpFailure(cmpFailBadDataFormat, "failed to parse proof of possession")
+	}
+	if len(popo.POPOSKInput.FullBytes) > 0 {
+		return nil, nil, newCmpFailure(cmpFailBadPOP, "proofs of possession with a poposkInput are not supported")
+	}
+	signatureAlgorithm, ok := cmpSignatureAlgorithms[popo.AlgorithmIdentifier.Algorithm.String()]
+	if !ok {
+		return nil, nil, newCmpFailure(cmpFailBadAlg, "unsupported proof of possession algorithm %v", popo.AlgorithmIdentifier.Algorithm)
+	}
+	if err := (&x509.Certificate{PublicKey: csr.PublicKey}).CheckSignature(signatureAlgorithm, msg.CertReq.FullBytes, popo.Signature.RightAlign()); err != nil {
+		return nil, nil, newCmpFailure(cmpFailBadPOP, "invalid proof of possession: %s", err)
+	}
+
+	return csr, certReq, nil
+}
+
+// checkCmpSigner checks that the certificate signing a request chains to one
+// of the trusted certificates of the configuration, or else that it was
+// issued by this mount and is still valid. In the latter case, true is
+// returned as the request may only be for the names of the signer: any
+// certificate of the mount, e.g. one enrolled through ACME, could otherwise
+// be used to obtain certificates for other names.
+func checkCmpSigner(cmpCtx *cmpContext, protection *cmpProtection) (bool, error) {
+	trusted, err := parseCmpTrustedCertificates(cmpCtx.config.TrustedCertificates)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse CMP trusted certificates: %w", err)
+	}
+	if len(trusted) > 0 {
+		roots := x509.NewCertPool()
+		for _, cert := range trusted {
+			roots.AddCert(cert)
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range protection.chain {
+			intermediates.AddCert(cert)
+		}
+
+		if _, err := protection.signer.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}); err == nil {
+			return false, nil
+		}
+	}
+
+	reason, err := checkRenewableCertificate(cmpCtx.sc, protection.signer)
+	if err != nil {
+		return false, err
+	}
+	if reason == "" {
+		return true, nil
+	}
+
+	return false, newCmpFailure(cmpFailSignerNotTrusted, "the signer certificate is not trusted")
+}
+
+// cmpRevoke handles rr messages, which must be signed with the certificate
+// to revoke.
+func (b *backend) cmpRevoke(cmpCtx *cmpContext, req *cmpRequest, senderNonce []byte) (*cmpResponse, error) {
+	sc := cmpCtx.sc
+
+	if req.header.ProtectionAlg.Algorithm.Equal(oidPasswordBasedMac) {
+		return nil, newCmpFailure(cmpFailWrongIntegrity, "revocation requests must be signed with the certificate to revoke")
+	}
+	protection, err := verifyCmpProtection(req.msg, req.header, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.protection = protection
+	signer := protection.signer
+
+	var revDetails []cmpRevDetails
+	if rest, err := asn1.Unmarshal(req.msg.Body.Bytes, &revDetails); err != nil || len(rest) > 0 {
+		return nil, newCmpFailure(cmpFailBadDataFormat, "failed to parse revocation request")
+	}
+	if len(revDetails) != 1 {
+		return nil, newCmpFailure(cmpFailBadRequest, "exactly one revocation request is expected, got %d", len(revDetails))
+	}
+
+	template := revDetails[0].CertDetails
+	if template.SerialNumber == nil || template.SerialNumber.Cmp(signer.SerialNumber) != 0 ||
+		(len(template.Issuer.Bytes) > 0 && !bytes.Equal(template.Issuer.Bytes, signer.RawIssuer)) {
+		return nil, newCmpFailure(cmpFailNotAuthorized, "only the certificate signing a revocation request can be revoked")
+	}
+
+	certEntry, err := fetchCertBySerialBigInt(sc, "certs/", signer.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil || !bytes.Equal(certEntry.Value, signer.Raw) {
+		return nil, newCmpFailure(cmpFailBadCertId, "the certificate to revoke was not issued by this mount")
+	}
+
+	revoked, err := fetchCertBySerialBigInt(sc, revokedPath, signer.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if revoked != nil {
+		return nil, newCmpFailure(cmpFailCertRevoked, "the certificate is already revoked")
+	}
+
+	if err := b.cmpRevokeCertificate(sc, signer); err != nil {
+		return nil, err
+	}
+
+	content, err := asn1.Marshal(cmpRevRepContent{
+		Status: []cmpPKIStatusInfo{{Status: cmpStatusAccepted}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &cmpResponse{
+		body:        cmpBody(cmpBodyRP, content),
+		senderNonce: senderNonce,
+	}, nil
+}
+
+// cmpRevokeCertificate revokes the given certificate, as the revoke endpoint
+// does.
+func (b *backend) cmpRevokeCertificate(sc *storageContext, cert *x509.Certificate) error {
+	config, err := sc.Backend.crlBuilder.getConfigWithUpdate(sc)
+	if err != nil {
+		return fmt.Errorf("error revoking serial: %s: failed reading config: %w", serialFromCert(cert), err)
+	}
+
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
+
+	resp, err := revokeCert(sc, config, cert)
+	if err != nil {
+		return err
+	}
+	if resp != nil && resp.IsError() {
+		return newCmpFailure(cmpFailBadRequest, "%s", resp.Error())
+	}
+	if resp != nil && resp.Data == nil && len(resp.Warnings) > 0 {
+		// The certificate was not revoked, as it expired
+		return newCmpFailure(cmpFailBadRequest, "%s", resp.Warnings[0])
+	}
+
+	return nil
+}
+
+// cmpConfirm handles certConf messages, confirming or rejecting the
+// certificate issued in their transaction. Rejected certificates are revoked.
+func (b *backend) cmpConfirm(cmpCtx *cmpContext, req *cmpRequest, senderNonce []byte) (*cmpResponse, error) {
+	sc := cmpCtx.sc
+
+	transaction, err := b.cmpContinueTransaction(cmpCtx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []cmpCertStatus
+	if rest, err := asn1.Unmarshal(req.msg.Body.Bytes, &statuses); err != nil || len(rest) > 0 {
+		return nil, newCmpFailure(cmpFailBadDataFormat, "failed to parse certificate confirmation")
+	}
+	if len(statuses) != 1 {
+		return nil, newCmpFailure(cmpFailBadRequest, "exactly one certificate status is expected, got %d", len(statuses))
+	}
+	status := statuses[0]
+	if status.CertReqId == nil || !status.CertReqId.IsInt64() || status.CertReqId.Int64() != transaction.CertReqId {
+		return nil, newCmpFailure(cmpFailBadCertId, "unknown certificate request ID")
+	}
+
+	cert, err := fetchCmpTransactionCertificate(sc, transaction)
+	if err != nil {
+		return nil, err
+	}
+	certHash, err := cmpCertHash(cert, status.HashAlg)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(certHash, status.CertHash) {
+		return nil, newCmpFailure(cmpFailBadCertId, "the confirmed certificate hash does not match the issued certificate")
+	}
+
+	if status.StatusInfo.Status != cmpStatusAccepted {
+		b.Logger().Debug("revoking certificate rejected by CMP client", "serial_number", transaction.SerialNumber)
+		if err := b.cmpRevokeCertificate(sc, cert); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := sc.deleteCmpTransaction(req.header.TransactionID); err != nil {
+		return nil, err
+	}
+
+	return &cmpResponse{
+		body:        cmpBody(cmpBodyPKIConf, asn1.NullBytes),
+		senderNonce: senderNonce,
+	}, nil
+}
+
+// cmpPoll handles pollReq messages. Certificates are issued right away, so
+// polling only returns the certificate of a transaction waiting for its
+// confirmation again.
+func (b *backend) cmpPoll(cmpCtx *cmpContext, req *cmpRequest, senderNonce []byte) (*cmpResponse, error) {
+	sc := cmpCtx.sc
+
+	transaction, err := b.cmpContinueTransaction(cmpCtx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var polls []cmpPollReq
+	if rest, err := asn1.Unmarshal(req.msg.Body.Bytes, &polls); err != nil || len(rest) > 0 {
+		return nil, newCmpFailure(cmpFailBadDataFormat, "failed to parse poll request")
+	}
+	if len(polls) != 1 {
+		return nil, newCmpFailure(cmpFailBadRequest, "exactly one poll request is expected, got %d", len(polls))
+	}
+	if polls[0].CertReqId == nil || !polls[0].CertReqId.IsInt64() || polls[0].CertReqId.Int64() != transaction.CertReqId {
+		return nil, newCmpFailure(cmpFailBadCertId, "unknown certificate request ID")
+	}
+
+	cert, err := fetchCmpTransactionCertificate(sc, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.SenderNonce = senderNonce
+	if err := sc.putCmpTransaction(req.header.TransactionID, transaction); err != nil {
+		return nil, err
+	}
+
+	return buildCmpCertResponse(cmpCtx, req, transaction.ResponseType, big.NewInt(transaction.CertReqId), cert.Raw, senderNonce)
+}
+
+// cmpContinueTransaction loads the transaction of a certConf or pollReq
+// message, and verifies that the message is protected as the request which
+// started it, and answers its last response.
+func (b *backend) cmpContinueTransaction(cmpCtx *cmpContext, req *cmpRequest) (*cmpTransactionEntry, error) {
+	transaction, err := cmpCtx.sc.getCmpTransaction(req.header.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	if transaction == nil {
+		return nil, newCmpFailure(cmpFailBadRequest, "unknown transaction")
+	}
+
+	protection, err := verifyCmpProtection(req.msg, req.header, func(reference []byte) ([]byte, error) {
+		if transaction.Secret == "" || !bytes.Equal(reference, transaction.SecretReference) {
+			return nil, nil
+		}
+		return []byte(transaction.Secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.protection = protection
+
+	if protection.signer != nil {
+		if transaction.Secret != "" {
+			return nil, newCmpFailure(cmpFailWrongIntegrity, "messages must be protected with the shared secret of their transaction")
+		}
+		if _, err := checkCmpSigner(cmpCtx, protection); err != nil {
+			return nil, err
+		}
+	}
+
+	if !bytes.Equal(req.header.RecipNonce, transaction.SenderNonce) {
+		return nil, newCmpFailure(cmpFailBadRecipientNonce, "the recipient nonce does not match the last response")
+	}
+
+	return transaction, nil
+}
+
+func fetchCmpTransactionCertificate(sc *storageContext, transaction *cmpTransactionEntry) (*x509.Certificate, error) {
+	certEntry, err := fetchCertBySerial(sc, "certs/", transaction.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil {
+		return nil, newCmpFailure(cmpFailBadCertId, "the certificate of the transaction no longer exists")
+	}
+
+	return x509.ParseCertificate(certEntry.Value)
+}
+
+// buildCmpCertResponse builds an ip, cp or kup body with the given certificate.
+// Clients enrolling with a shared secret have no trust anchor yet, so the
+// root certificate of the issuer is included for them (RFC 9483 Section
+// 4.1.1).
+func buildCmpCertResponse(cmpCtx *cmpContext, req *cmpRequest, responseType int, certReqId *big.Int, cert []byte, senderNonce []byte) (*cmpResponse, error) {
+	content := cmpCertRepMessage{
+		Response: []cmpCertResponse{{
+			CertReqId: certReqId,
+			Status:    cmpPKIStatusInfo{Status: cmpStatusAccepted},
+			CertifiedKeyPair: cmpCertifiedKeyPair{
+				Certificate: asn1.RawValue{
+					Class:      asn1.ClassContextSpecific,
+					Tag:        0,
+					IsCompound: true,
+					Bytes:      cert,
+				},
+			},
+		}},
+	}
+	if req.protection != nil && req.protection.secret != nil && len(cmpCtx.caChain) > 0 {
+		content.CAPubs = []asn1.RawValue{{FullBytes: cmpCtx.caChain[len(cmpCtx.caChain)-1]}}
+	}
+
+	der, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CMP response: %w", err)
+	}
+
+	return &cmpResponse{
+		body:        cmpBody(responseType, der),
+		senderNonce: senderNonce,
+		withChain:   true,
+	}, nil
+}
+
+// cmpErrorMessage builds an error body with the given failure.
+func cmpErrorMessage(failure *cmpFailure, senderNonce []byte) (*cmpResponse, error) {
+	content, err := asn1.Marshal(cmpErrorMsgContent{
+		PKIStatusInfo: cmpPKIStatusInfo{
+			Status:       cmpStatusRejection,
+			StatusString: cmpFreeText(failure.reason),
+			FailInfo:     cmpFailInfo(failure.failInfo),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CMP error message: %w", err)
+	}
+
+	return &cmpResponse{
+		body:        cmpBody(cmpBodyError, content),
+		senderNonce: senderNonce,
+	}, nil
+}
+
+// cmpRespond builds the response message with the given body, protected as
+// the request was when it was MAC-protected, and signed by the CA otherwise.
+func (b *backend) cmpRespond(cmpCtx *cmpContext, req *cmpRequest, resp *cmpResponse) (*logical.Response, error) {
+	pvno := req.header.PVNO
+	if pvno != 3 {
+		pvno = 2
+	}
+
+	header := &cmpHeader{
+		PVNO:          pvno,
+		Sender:        cmpDirectoryName(cmpCtx.caCert.RawSubject),
+		Recipient:     req.header.Sender,
+		MessageTime:   time.Now().UTC().Truncate(time.Second),
+		TransactionID: req.header.TransactionID,
+		SenderNonce:   resp.senderNonce,
+		RecipNonce:    req.header.SenderNonce,
+	}
+	if resp.implicitConfirm {
+		header.GeneralInfo = []cmpInfoTypeAndValue{{
+			InfoType:  oidCmpImplicitConfirm,
+			InfoValue: asn1.NullRawValue,
+		}}
+	}
+
+	macProtected := req.protection != nil && req.protection.secret != nil
+	if !macProtected {
+		header.SenderKID = cmpCtx.caCert.SubjectKeyId
+	}
+
+	rawHeader, protection, err := req.protection.protect(header, resp.body, cmpCtx.caSigner)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := cmpMessage{
+		Header:     rawHeader,
+		Body:       resp.body,
+		Protection: protection,
+	}
+	if resp.withChain || !macProtected {
+		// The signer certificate comes first in the extra certificates of
+		// signed messages
+		for _, cert := range cmpCtx.caChain {
+			msg.ExtraCerts = append(msg.ExtraCerts, asn1.RawValue{FullBytes: cert})
+		}
+	}
+
+	der, err := asn1.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CMP response: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPContentType: cmpContentType,
+			logical.HTTPRawBody:     der,
+		},
+	}, nil
+}
+
+// cmpErrorResponse returns a plain text error, for requests which cannot be
+// answered with a CMP message.
+func cmpErrorResponse(status int, format string, args ...interface{}) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  status,
+			logical.HTTPContentType: "text/plain",
+			logical.HTTPRawBody:     []byte(fmt.Sprintf(format, args...) + "\n"),
+		},
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	cmpPathPrefix         = "cmp/"
+	cmpSecretPrefix       = cmpPathPrefix + "secrets/"
+	cmpTransactionPrefix  = cmpPathPrefix + "transactions/"
+	cmpSecretLength       = 16
+	cmpReferenceLength    = 8
+	cmpTransactionTTL     = 24 * time.Hour
+	pathCmpSecretHelpSyn  = "Generate a one-time CMP shared secret"
+	pathCmpSecretHelpDesc = "Generate a shared secret with its reference, to be given to a CMP client in order to enroll a single certificate with MAC-protected requests. It expires after the secret_ttl of the CMP configuration."
+)
+
+type cmpSecretEntry struct {
+	Secret     string    `json:"secret"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// cmpTransactionEntry is a CMP transaction waiting for the confirmation of
+// its certificate.
+type cmpTransactionEntry struct {
+	// ResponseType is the body type of the response with the certificate
+	ResponseType int    `json:"response_type"`
+	CertReqId    int64  `json:"cert_req_id"`
+	SerialNumber string `json:"serial_number"`
+	// SenderNonce is the nonce of the last response, expected as the
+	// recipient nonce of the next request
+	SenderNonce []byte `json:"sender_nonce"`
+	// SecretReference and Secret are the shared secret the transaction
+	// was MAC-protected with, if any
+	SecretReference []byte    `json:"secret_reference,omitempty"`
+	Secret          string    `json:"secret,omitempty"`
+	Expiration      time.Time `json:"expiration"`
+}
+
+func pathCmpSecret(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "cmp/secret",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"reference": {
+				Type:        framework.TypeString,
+				Description: "The reference of the secret, the sender KID of the requests protected with it; a random reference is generated when it is not given",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathCmpSecretGenerate,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "generate",
+					OperationSuffix: "cmp-secret",
+				},
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathCmpSecretHelpSyn,
+		HelpDescription: pathCmpSecretHelpDesc,
+	}
+}
+
+func (b *backend) pathCmpSecretGenerate(ctx context.Context, r *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, r.Storage)
+
+	config, err := sc.getCmpConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return logical.ErrorResponse("CMP is not enabled on this mount"), nil
+	}
+
+	// Expired secrets and transactions are removed as new secrets are
+	// generated, which bounds their number
+	if err := b.tidyCmpStorage(sc); err != nil {
+		return nil, err
+	}
+
+	reference := data.Get("reference").(string)
+	if reference == "" {
+		referenceBytes := make([]byte, cmpReferenceLength)
+		if _, err := rand.Read(referenceBytes); err != nil {
+			return nil, fmt.Errorf("failed to generate reference: %w", err)
+		}
+		reference = hex.EncodeToString(referenceBytes)
+	}
+
+	secretBytes := make([]byte, cmpSecretLength)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := hex.EncodeToString(secretBytes)
+
+	expiration := time.Now().Add(config.SecretTTL)
+	json, err := logical.StorageEntryJSON(cmpSecretPrefix+cmpStorageKey([]byte(reference)), &cmpSecretEntry{
+		Secret:     secret,
+		Expiration: expiration,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed creating storage entry: %w", err)
+	}
+	if err := sc.Storage.Put(sc.Context, json); err != nil {
+		return nil, fmt.Errorf("failed writing storage entry: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"reference":  reference,
+			"secret":     secret,
+			"expiration": expiration.Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// getCmpSecret returns the shared secret with the given reference, or nil
+// when it does not exist or has expired.
+func (sc *storageContext) getCmpSecret(reference []byte) ([]byte, error) {
+	entry, err := sc.Storage.Get(sc.Context, cmpSecretPrefix+cmpStorageKey(reference))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var secret cmpSecretEntry
+	if err := entry.DecodeJSON(&secret); err != nil {
+		return nil, fmt.Errorf("failed to decode CMP secret: %w", err)
+	}
+	if time.Now().After(secret.Expiration) {
+		return nil, nil
+	}
+
+	return []byte(secret.Secret), nil
+}
+
+// consumeCmpSecret removes the shared secret with the given reference once
+// it has been used to request a certificate, returning whether it was still
+// available.
+func (b *backend) consumeCmpSecret(sc *storageContext, reference []byte) (bool, error) {
+	b.cmpLock.Lock()
+	defer b.cmpLock.Unlock()
+
+	key := cmpSecretPrefix + cmpStorageKey(reference)
+	entry, err := sc.Storage.Get(sc.Context, key)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+
+	return true, sc.Storage.Delete(sc.Context, key)
+}
+
+// tidyCmpStorage removes the expired secrets and transactions.
+func (b *backend) tidyCmpStorage(sc *storageContext) error {
+	b.cmpLock.Lock()
+	defer b.cmpLock.Unlock()
+
+	now := time.Now()
+	for _, prefix := range []string{cmpSecretPrefix, cmpTransactionPrefix} {
+		keys, err := sc.Storage.List(sc.Context, prefix)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			entry, err := sc.Storage.Get(sc.Context, prefix+key)
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				continue
+			}
+
+			var expiring struct {
+				Expiration time.Time `json:"expiration"`
+			}
+			if err := entry.DecodeJSON(&expiring); err != nil {
+				return fmt.Errorf("failed to decode CMP entry %v: %w", key, err)
+			}
+			if now.After(expiring.Expiration) {
+				if err := sc.Storage.Delete(sc.Context, prefix+key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (sc *storageContext) getCmpTransaction(transactionID []byte) (*cmpTransactionEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, cmpTransactionPrefix+cmpStorageKey(transactionID))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var transaction cmpTransactionEntry
+	if err := entry.DecodeJSON(&transaction); err != nil {
+		return nil, fmt.Errorf("failed to decode CMP transaction: %w", err)
+	}
+
+	if time.Now().After(transaction.Expiration) {
+		return nil, nil
+	}
+
+	return &transaction, nil
+}
+
+func (sc *storageContext) putCmpTransaction(transactionID []byte, transaction *cmpTransactionEntry) error {
+	json, err := logical.StorageEntryJSON(cmpTransactionPrefix+cmpStorageKey(transactionID), transaction)
+	if err != nil {
+		return fmt.Errorf("failed creating storage entry: %w", err)
+	}
+
+	return sc.Storage.Put(sc.Context, json)
+}
+
+func (sc *storageContext) deleteCmpTransaction(transactionID []byte) error {
+	return sc.Storage.Delete(sc.Context, cmpTransactionPrefix+cmpStorageKey(transactionID))
+}
+
+// cmpStorageKey hashes the given secret reference or transaction ID, so
+// that they are safe storage keys.
+func cmpStorageKey(value []byte) string {
+	hash := sha256.Sum256(value)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,625 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openbao/openbao/api/v2"
+	vaulthttp "github.com/openbao/openbao/http"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+)
+
+func TestCmpConfig(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBRead(b, s, "config/cmp")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, false, resp.Data["enabled"])
+	require.Equal(t, "forbid", resp.Data["default_path_policy"])
+	require.Equal(t, int64(86400), resp.Data["secret_ttl"])
+
+	resp, err = CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "CMP Root",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	rootPem := resp.Data["certificate"].(string)
+
+	_, err = CBWrite(b, s, "roles/device", map[string]interface{}{
+		"allow_any_name": true,
+	})
+	require.NoError(t, err)
+	_, err = CBWrite(b, s, "roles/nostore", map[string]interface{}{
+		"allow_any_name": true,
+		"no_store":       true,
+	})
+	require.NoError(t, err)
+
+	resp, err = CBWrite(b, s, "issue/device", map[string]interface{}{
+		"common_name": "leaf.example.com",
+		"ttl":         "1h",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	leafPem := resp.Data["certificate"].(string)
+
+	for _, tc := range []struct {
+		name  string
+		data  map[string]interface{}
+		valid bool
+	}{
+		{"missing-role", map[string]interface{}{"default_path_policy": "role:missing"}, false},
+		{"no-store", map[string]interface{}{"default_path_policy": "role:nostore"}, false},
+		{"bad-policy", map[string]interface{}{"default_path_policy": "device"}, false},
+		{"bad-label", map[string]interface{}{"label_to_path_policy": map[string]string{"a/b": "role:device"}}, false},
+		{"bad-label-policy", map[string]interface{}{"label_to_path_policy": map[string]string{"device": "role:nostore"}}, false},
+		{"bad-trusted", map[string]interface{}{"trusted_certificates": "not a certificate"}, false},
+		{"non-ca-trusted", map[string]interface{}{"trusted_certificates": leafPem}, false},
+		{"bad-ttl", map[string]interface{}{"secret_ttl": "-1s"}, false},
+		{"valid", map[string]interface{}{
+			"enabled":              true,
+			"default_path_policy":  "sign-verbatim",
+			"label_to_path_policy": map[string]string{"device": "role:device"},
+			"trusted_certificates": rootPem,
+			"secret_ttl":           "1h",
+		}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := CBWrite(b, s, "config/cmp", tc.data)
+			if tc.valid {
+				requireSuccessNonNilResponse(t, resp, err)
+			} else if err == nil && (resp == nil || !resp.IsError()) {
+				t.Fatalf("expected config to be rejected, got %#v", resp)
+			}
+		})
+	}
+
+	resp, err = CBRead(b, s, "config/cmp")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, true, resp.Data["enabled"])
+	require.Equal(t, "sign-verbatim", resp.Data["default_path_policy"])
+	require.Equal(t, map[string]string{"device": "role:device"}, resp.Data["label_to_path_policy"])
+	require.Equal(t, rootPem, resp.Data["trusted_certificates"])
+	require.Equal(t, int64(3600), resp.Data["secret_ttl"])
+
+	// Secrets are one-time
+	resp, err = CBWrite(b, s, "cmp/secret", map[string]interface{}{
+		"reference": "device-1",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, "device-1", resp.Data["reference"])
+	require.NotEmpty(t, resp.Data["secret"])
+	require.NotEmpty(t, resp.Data["expiration"])
+
+	sc := b.makeStorageContext(ctx, s)
+	secret, err := sc.getCmpSecret([]byte("device-1"))
+	require.NoError(t, err)
+	require.Equal(t, resp.Data["secret"], string(secret))
+
+	available, err := b.consumeCmpSecret(sc, []byte("device-1"))
+	require.NoError(t, err)
+	require.True(t, available)
+	available, err = b.consumeCmpSecret(sc, []byte("device-1"))
+	require.NoError(t, err)
+	require.False(t, available)
+
+	// Random references are generated when none is given
+	resp, err = CBWrite(b, s, "cmp/secret", nil)
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Len(t, resp.Data["reference"], 2*cmpReferenceLength)
+}
+
+func TestCmpWorkflow(t *testing.T) {
+	t.Parallel()
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"pki": Factory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	client := cluster.Cores[0].Client
+	mountPKIEndpoint(t, client, "pki")
+
+	resp, err := client.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+		"common_name": "CMP Root CA",
+		"key_type":    "ec",
+		"ttl":         "87600h",
+	})
+	require.NoError(t, err)
+	caCert := parseCert(t, resp.Data["certificate"].(string))
+
+	_, err = client.Logical().Write("pki/roles/device", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "any",
+		"ttl":            "24h",
+	})
+	require.NoError(t, err)
+
+	cmp := &cmpTestClient{
+		url:       "https://" + cluster.Cores[0].Listeners[0].Address.String() + "/v1/pki/.well-known/cmp",
+		tlsConfig: cluster.Cores[0].TLSConfig(),
+		caCert:    caCert,
+	}
+
+	// CMP is disabled by default
+	status, _ := cmp.post(t, "", []byte{0x30, 0x00})
+	require.Equal(t, http.StatusNotFound, status)
+
+	_, err = client.Logical().Write("pki/config/cmp", map[string]interface{}{
+		"enabled":              true,
+		"default_path_policy":  "role:device",
+		"label_to_path_policy": map[string]string{"device": "role:device"},
+	})
+	require.NoError(t, err)
+
+	status, _ = cmp.post(t, "/p/unknown", []byte{0x30, 0x00})
+	require.Equal(t, http.StatusNotFound, status)
+	status, _ = cmp.post(t, "", []byte("not a CMP message"))
+	require.Equal(t, http.StatusBadRequest, status)
+
+	resp, err = client.Logical().Write("pki/cmp/secret", nil)
+	require.NoError(t, err)
+	macCreds := &cmpTestCredentials{
+		reference: []byte(resp.Data["reference"].(string)),
+		secret:    []byte(resp.Data["secret"].(string)),
+	}
+
+	// Initialization requires a valid shared secret
+	key1 := generateCmpKey(t)
+	wrongCreds := &cmpTestCredentials{reference: macCreds.reference, secret: []byte("wrong")}
+	rep := cmp.exchange(t, "", wrongCreds, cmpBodyIR, generateCmpCertReqMessages(t, key1, "device-1"), nil, nil, false)
+	require.Equal(t, cmpFailBadMessageCheck, rep.failInfo(t))
+
+	// ir, then certConf
+	txn := generateCmpTransactionID(t)
+	rep = cmp.exchange(t, "", macCreds, cmpBodyIR, generateCmpCertReqMessages(t, key1, "device-1"), txn, nil, false)
+	cert1, caPubs := rep.certificate(t, cmpBodyIP)
+	require.Equal(t, "device-1", cert1.Subject.CommonName)
+	requireSignedBy(t, cert1, caCert)
+	requireMatchingPublicKeys(t, cert1, key1.Public())
+	require.Len(t, caPubs, 1)
+	require.True(t, caPubs[0].Equal(caCert))
+	require.False(t, rep.header.hasImplicitConfirm())
+
+	rep = cmp.exchange(t, "", macCreds, cmpBodyCertConf, generateCmpCertConf(t, cert1, cmpStatusAccepted), txn, rep.header.SenderNonce, false)
+	require.Equal(t, cmpBodyPKIConf, rep.msg.Body.Tag)
+
+	// The transaction is over
+	rep = cmp.exchange(t, "", macCreds, cmpBodyPollReq, generateCmpPollReq(t), txn, rep.header.SenderNonce, false)
+	require.Equal(t, cmpFailBadRequest, rep.failInfo(t))
+
+	// Secrets are one-time
+	rep = cmp.exchange(t, "", macCreds, cmpBodyIR, generateCmpCertReqMessages(t, key1, "device-1"), nil, nil, false)
+	require.Equal(t, cmpFailBadMessageCheck, rep.failInfo(t))
+
+	// cr signed with the issued certificate, polled before being confirmed.
+	// Certificates of the mount only enroll their own names, whatever the
+	// template requests.
+	creds1 := &cmpTestCredentials{key: key1, cert: cert1}
+	key2 := generateCmpKey(t)
+	txn = generateCmpTransactionID(t)
+	rep = cmp.exchange(t, "", creds1, cmpBodyCR, generateCmpCertReqMessages(t, key2, "device-2"), txn, nil, false)
+	cert2, caPubs := rep.certificate(t, cmpBodyCP)
+	require.Equal(t, "device-1", cert2.Subject.CommonName)
+	require.Equal(t, cert1.DNSNames, cert2.DNSNames)
+	requireMatchingPublicKeys(t, cert2, key2.Public())
+	require.Empty(t, caPubs)
+	lastNonce := rep.header.SenderNonce
+
+	rep = cmp.exchange(t, "", creds1, cmpBodyCR, generateCmpCertReqMessages(t, key2, "device-2"), txn, nil, false)
+	require.Equal(t, cmpFailTransactionIdInUse, rep.failInfo(t))
+
+	rep = cmp.exchange(t, "", creds1, cmpBodyPollReq, generateCmpPollReq(t), txn, []byte("stale nonce"), false)
+	require.Equal(t, cmpFailBadRecipientNonce, rep.failInfo(t))
+
+	rep = cmp.exchange(t, "", creds1, cmpBodyPollReq, generateCmpPollReq(t), txn, lastNonce, false)
+	polled, _ := rep.certificate(t, cmpBodyCP)
+	require.True(t, polled.Equal(cert2))
+
+	rep = cmp.exchange(t, "", creds1, cmpBodyCertConf, generateCmpCertConf(t, cert2, cmpStatusAccepted), txn, rep.header.SenderNonce, false)
+	require.Equal(t, cmpBodyPKIConf, rep.msg.Body.Tag)
+
+	// Requests signed with untrusted certificates are refused
+	selfSigned := generateCmpSelfSigned(t, key2, "device-2")
+	rep = cmp.exchange(t, "", &cmpTestCredentials{key: key2, cert: selfSigned}, cmpBodyCR, generateCmpCertReqMessages(t, key2, "device-2"), nil, nil, false)
+	require.Equal(t, cmpFailSignerNotTrusted, rep.failInfo(t))
+
+	// Unless they chain to the trusted certificates
+	trustedKey := generateCmpKey(t)
+	trustedCA := generateCmpSelfSigned(t, trustedKey, "Manufacturer CA")
+	_, err = client.Logical().Write("pki/config/cmp", map[string]interface{}{
+		"trusted_certificates": string(pemEncodeCert(trustedCA)),
+	})
+	require.NoError(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "device-2"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, trustedCA, key2.Public(), trustedKey)
+	require.NoError(t, err)
+	manufacturerCert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	rep = cmp.exchange(t, "", &cmpTestCredentials{key: key2, cert: manufacturerCert}, cmpBodyCR, generateCmpCertReqMessages(t, key2, "device-2"), nil, nil, true)
+	manufactured, _ := rep.certificate(t, cmpBodyCP)
+	require.Equal(t, "device-2", manufactured.Subject.CommonName)
+
+	// Leaves of the mount enrolled by other means, e.g. through ACME or
+	// EST, cannot request other names either
+	leafKey := generateCmpKey(t)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "a.example.com"},
+	}, leafKey)
+	require.NoError(t, err)
+	resp, err = client.Logical().Write("pki/sign/device", map[string]interface{}{
+		"csr": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})),
+		"ttl": "1h",
+	})
+	require.NoError(t, err)
+	leafCreds := &cmpTestCredentials{key: leafKey, cert: parseCert(t, resp.Data["certificate"].(string))}
+	rep = cmp.exchange(t, "", leafCreds, cmpBodyIR, generateCmpCertReqMessages(t, key2, "b.example.com"), nil, nil, true)
+	leafRenewed, _ := rep.certificate(t, cmpBodyIP)
+	require.Equal(t, "a.example.com", leafRenewed.Subject.CommonName)
+	require.Equal(t, []string{"a.example.com"}, leafRenewed.DNSNames)
+
+	csr, err = x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "b.example.com"},
+		DNSNames: []string{"b.example.com"},
+	}, key2)
+	require.NoError(t, err)
+	rep = cmp.exchange(t, "", leafCreds, cmpBodyP10CR, csr, nil, nil, true)
+	require.Equal(t, cmpFailBadCertTemplate, rep.failInfo(t))
+
+	// kur keeps the subject of the certificate to update, with implicit
+	// confirmation
+	key3 := generateCmpKey(t)
+	rep = cmp.exchange(t, "/p/device", creds1, cmpBodyKUR, generateCmpCertReqMessages(t, key3, "other"), nil, nil, true)
+	cert3, _ := rep.certificate(t, cmpBodyKUP)
+	require.True(t, rep.header.hasImplicitConfirm())
+	require.Equal(t, "device-1", cert3.Subject.CommonName)
+	require.Equal(t, cert1.DNSNames, cert3.DNSNames)
+	requireMatchingPublicKeys(t, cert3, key3.Public())
+
+	// p10cr, for the names of the signer certificate
+	csr, err = x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "device-1"},
+		DNSNames: cert1.DNSNames,
+	}, key3)
+	require.NoError(t, err)
+	rep = cmp.exchange(t, "/p/device", creds1, cmpBodyP10CR, csr, nil, nil, true)
+	cert4, _ := rep.certificate(t, cmpBodyCP)
+	require.Equal(t, "device-1", cert4.Subject.CommonName)
+	require.Equal(t, cert1.DNSNames, cert4.DNSNames)
+
+	// rr revokes the signer certificate, which can no longer be used
+	rep = cmp.exchange(t, "", creds1, cmpBodyRR, generateCmpRevReq(t, cert2), nil, nil, false)
+	require.Equal(t, cmpFailNotAuthorized, rep.failInfo(t))
+
+	rep = cmp.exchange(t, "", creds1, cmpBodyRR, generateCmpRevReq(t, cert1), nil, nil, false)
+	require.Equal(t, cmpBodyRP, rep.msg.Body.Tag)
+	var revRep cmpRevRepContent
+	_, err = asn1.Unmarshal(rep.msg.Body.Bytes, &revRep)
+	require.NoError(t, err)
+	require.Equal(t, cmpStatusAccepted, revRep.Status[0].Status)
+	requireCmpCertRevoked(t, client, cert1)
+
+	rep = cmp.exchange(t, "", creds1, cmpBodyRR, generateCmpRevReq(t, cert1), nil, nil, false)
+	require.Equal(t, cmpFailCertRevoked, rep.failInfo(t))
+	rep = cmp.exchange(t, "", creds1, cmpBodyKUR, generateCmpCertReqMessages(t, key2, "device-1"), nil, nil, false)
+	require.Equal(t, cmpFailSignerNotTrusted, rep.failInfo(t))
+
+	// Certificates rejected by the client are revoked
+	resp, err = client.Logical().Write("pki/cmp/secret", nil)
+	require.NoError(t, err)
+	macCreds = &cmpTestCredentials{
+		reference: []byte(resp.Data["reference"].(string)),
+		secret:    []byte(resp.Data["secret"].(string)),
+	}
+	txn = generateCmpTransactionID(t)
+	rep = cmp.exchange(t, "", macCreds, cmpBodyIR, generateCmpCertReqMessages(t, key1, "device-5"), txn, nil, false)
+	cert5, _ := rep.certificate(t, cmpBodyIP)
+	rep = cmp.exchange(t, "", macCreds, cmpBodyCertConf, generateCmpCertConf(t, cert5, cmpStatusRejection), txn, rep.header.SenderNonce, false)
+	require.Equal(t, cmpBodyPKIConf, rep.msg.Body.Tag)
+	requireCmpCertRevoked(t, client, cert5)
+}
+
+// cmpTestClient is a minimal CMP client, speaking to the CMP endpoints of a
+// mount of the test cluster.
+type cmpTestClient struct {
+	url       string
+	tlsConfig *tls.Config
+	caCert    *x509.Certificate
+}
+
+// cmpTestCredentials protect the requests of a CMP client, either with a
+// shared secret or with a key and its certificate.
+type cmpTestCredentials struct {
+	reference []byte
+	secret    []byte
+	key       crypto.Signer
+	cert      *x509.Certificate
+}
+
+type cmpTestResponse struct {
+	msg    *cmpMessage
+	header *cmpHeader
+}
+
+func (c *cmpTestClient) post(t *testing.T, path string, message []byte) (int, []byte) {
+	req, err := http.NewRequest(http.MethodPost, c.url+path, bytes.NewReader(message))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", cmpContentType)
+
+	transport := cleanhttp.DefaultTransport()
+	transport.TLSClientConfig = c.tlsConfig
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, body
+}
+
+// exchange sends a message with the given body to the CMP endpoint at the
+// given path, protected with the given credentials, and returns the response
+// once its protection is verified. A new transaction is started when no
+// transaction ID is given.
+func (c *cmpTestClient) exchange(t *testing.T, path string, creds *cmpTestCredentials, bodyType int, content, transactionID, recipNonce []byte, implicitConfirm bool) *cmpTestResponse {
+	if transactionID == nil {
+		transactionID = generateCmpTransactionID(t)
+	}
+	senderNonce := make([]byte, cmpNonceLength)
+	_, err := rand.Read(senderNonce)
+	require.NoError(t, err)
+
+	sender := cmpDirectoryName([]byte{0x30, 0x00})
+	if creds.cert != nil {
+		sender = cmpDirectoryName(creds.cert.RawSubject)
+	}
+	header := &cmpHeader{
+		PVNO:          2,
+		Sender:        sender,
+		Recipient:     cmpDirectoryName(c.caCert.RawSubject),
+		MessageTime:   time.Now().UTC().Truncate(time.Second),
+		TransactionID: transactionID,
+		SenderNonce:   senderNonce,
+		RecipNonce:    recipNonce,
+	}
+	if implicitConfirm {
+		header.GeneralInfo = []cmpInfoTypeAndValue{{InfoType: oidCmpImplicitConfirm, InfoValue: asn1.NullRawValue}}
+	}
+
+	var protection *cmpProtection
+	if creds.secret != nil {
+		protection = &cmpProtection{
+			reference: creds.reference,
+			secret:    creds.secret,
+			pbm: &cmpPBMParameter{
+				OWF:            pkix.AlgorithmIdentifier{Algorithm: oidDigestSHA256},
+				IterationCount: 500,
+				MAC:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256},
+			},
+		}
+	}
+	body := cmpBody(bodyType, content)
+	rawHeader, protectionBits, err := protection.protect(header, body, creds.key)
+	require.NoError(t, err)
+
+	msg := cmpMessage{Header: rawHeader, Body: body, Protection: protectionBits}
+	if creds.cert != nil {
+		msg.ExtraCerts = []asn1.RawValue{{FullBytes: creds.cert.Raw}}
+	}
+	der, err := asn1.Marshal(msg)
+	require.NoError(t, err)
+
+	status, respBody := c.post(t, path, der)
+	require.Equal(t, http.StatusOK, status, string(respBody))
+
+	respMsg, respHeader, err := parseCmpMessage(respBody)
+	require.NoError(t, err)
+	require.Equal(t, transactionID, respHeader.TransactionID)
+	require.Equal(t, senderNonce, respHeader.RecipNonce)
+
+	respProtection, err := verifyCmpProtection(respMsg, respHeader, func(reference []byte) ([]byte, error) {
+		require.Equal(t, creds.reference, reference)
+		return creds.secret, nil
+	})
+	require.NoError(t, err)
+	if respProtection.signer != nil {
+		// Errors about the shared secret cannot be protected with it
+		if respMsg.Body.Tag != cmpBodyError {
+			require.Nil(t, creds.secret, "responses to MAC-protected requests must be MAC-protected")
+		}
+		require.True(t, respProtection.signer.Equal(c.caCert))
+	}
+
+	return &cmpTestResponse{msg: respMsg, header: respHeader}
+}
+
+// certificate returns the certificate of an ip, cp or kup response, and its
+// CA certificates.
+func (r *cmpTestResponse) certificate(t *testing.T, bodyType int) (*x509.Certificate, []*x509.Certificate) {
+	if r.msg.Body.Tag == cmpBodyError {
+		t.Fatalf("unexpected CMP error with failure bit %d", r.failInfo(t))
+	}
+	require.Equal(t, bodyType, r.msg.Body.Tag)
+
+	var content cmpCertRepMessage
+	_, err := asn1.Unmarshal(r.msg.Body.Bytes, &content)
+	require.NoError(t, err)
+	require.Len(t, content.Response, 1)
+	require.Equal(t, cmpStatusAccepted, content.Response[0].Status.Status)
+
+	cert, err := x509.ParseCertificate(content.Response[0].CertifiedKeyPair.Certificate.Bytes)
+	require.NoError(t, err)
+
+	var caPubs []*x509.Certificate
+	for _, raw := range content.CAPubs {
+		caPub, err := x509.ParseCertificate(raw.FullBytes)
+		require.NoError(t, err)
+		caPubs = append(caPubs, caPub)
+	}
+
+	return cert, caPubs
+}
+
+// failInfo returns the failure information bit of an error response.
+func (r *cmpTestResponse) failInfo(t *testing.T) int {
+	require.Equal(t, cmpBodyError, r.msg.Body.Tag)
+
+	var content cmpErrorMsgContent
+	_, err := asn1.Unmarshal(r.msg.Body.Bytes, &content)
+	require.NoError(t, err)
+	require.Equal(t, cmpStatusRejection, content.PKIStatusInfo.Status)
+
+	for bit := 0; bit < content.PKIStatusInfo.FailInfo.BitLength; bit++ {
+		if content.PKIStatusInfo.FailInfo.At(bit) == 1 {
+			return bit
+		}
+	}
+	t.Fatal("CMP error without failure information")
+	return -1
+}
+
+func generateCmpKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func generateCmpTransactionID(t *testing.T) []byte {
+	transactionID := make([]byte, 16)
+	_, err := rand.Read(transactionID)
+	require.NoError(t, err)
+	return transactionID
+}
+
+func generateCmpSelfSigned(t *testing.T, key crypto.Signer, commonName string) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// generateCmpCertReqMessages builds the content of an ir, cr or kur body,
+// requesting a certificate for the given key and common name, with its
+// signature proof of possession.
+func generateCmpCertReqMessages(t *testing.T, key crypto.Signer, commonName string) []byte {
+	subject, err := asn1.Marshal(pkix.Name{CommonName: commonName}.ToRDNSequence())
+	require.NoError(t, err)
+	spki, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	var publicKey asn1.RawValue
+	_, err = asn1.Unmarshal(spki, &publicKey)
+	require.NoError(t, err)
+
+	certReq, err := asn1.Marshal(cmpCertRequest{
+		CertReqId: big.NewInt(0),
+		CertTemplate: cmpCertTemplate{
+			Subject:   asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 5, IsCompound: true, Bytes: subject},
+			PublicKey: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 6, IsCompound: true, Bytes: publicKey.Bytes},
+		},
+	})
+	require.NoError(t, err)
+
+	algorithm, _, err := cmpSignatureAlgorithm(key)
+	require.NoError(t, err)
+	signature, err := cmpSign(key, certReq)
+	require.NoError(t, err)
+	popo, err := asn1.MarshalWithParams(cmpPOPOSigningKey{
+		AlgorithmIdentifier: algorithm,
+		Signature:           asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	}, "tag:1")
+	require.NoError(t, err)
+
+	content, err := asn1.Marshal([]cmpCertReqMsg{{
+		CertReq: asn1.RawValue{FullBytes: certReq},
+		POPO:    asn1.RawValue{FullBytes: popo},
+	}})
+	require.NoError(t, err)
+	return content
+}
+
+func generateCmpCertConf(t *testing.T, cert *x509.Certificate, status int) []byte {
+	certHash, err := cmpCertHash(cert, pkix.AlgorithmIdentifier{})
+	require.NoError(t, err)
+
+	content, err := asn1.Marshal([]cmpCertStatus{{
+		CertHash:   certHash,
+		CertReqId:  big.NewInt(0),
+		StatusInfo: cmpPKIStatusInfo{Status: status},
+	}})
+	require.NoError(t, err)
+	return content
+}
+
+func generateCmpPollReq(t *testing.T) []byte {
+	content, err := asn1.Marshal([]cmpPollReq{{CertReqId: big.NewInt(0)}})
+	require.NoError(t, err)
+	return content
+}
+
+func generateCmpRevReq(t *testing.T, cert *x509.Certificate) []byte {
+	content, err := asn1.Marshal([]cmpRevDetails{{
+		CertDetails: cmpCertTemplate{
+			SerialNumber: cert.SerialNumber,
+			Issuer:       asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 3, IsCompound: true, Bytes: cert.RawIssuer},
+		},
+	}})
+	require.NoError(t, err)
+	return content
+}
+
+// requireCmpCertRevoked checks that the given certificate is revoked, and
+// listed in the CRL of the mount.
+func requireCmpCertRevoked(t *testing.T, client *api.Client, cert *x509.Certificate) {
+	t.Helper()
+
+	resp, err := client.Logical().Read("pki/cert/" + serialFromCert(cert))
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.NotEqual(t, "0", resp.Data["revocation_time"].(json.Number).String())
+
+	crl := getParsedCrl(t, client, "pki")
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return
+		}
+	}
+	t.Fatalf("certificate %v is not in the CRL", serialFromCert(cert))
+}
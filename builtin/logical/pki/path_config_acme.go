@@ -8,8 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/openbao/openbao/api/v2"
 	"github.com/openbao/openbao/sdk/v2/framework"
@@ -20,8 +22,12 @@ import (
 const (
 	storageAcmeConfig      = "config/acme"
 	pathConfigAcmeHelpSyn  = "Configuration of ACME Endpoints"
-	pathConfigAcmeHelpDesc = "Here we configure:\n\nenabled=false, whether ACME is enabled, defaults to false meaning that clusters will by default not get ACME support,\nallowed_issuers=\"default\", which issuers are allowed for use with ACME; by default, this will only be the primary (default) issuer,\nallowed_roles=\"*\", which roles are allowed for use with ACME; by default these will be all roles matching our selection criteria,\ndefault_directory_policy=\"\", either \"forbid\", preventing the default directory from being used at all, \"role:<role_name>\" which is the role to be used for non-role-qualified ACME requests; or \"sign-verbatim\", the default meaning ACME issuance will be equivalent to sign-verbatim.,\ndns_resolver=\"\", which specifies a custom DNS resolver to use for all ACME-related DNS lookups"
+	pathConfigAcmeHelpDesc = "Here we configure:\n\nenabled=false, whether ACME is enabled, defaults to false meaning that clusters will by default not get ACME support,\nallowed_issuers=\"default\", which issuers are allowed for use with ACME; by default, this will only be the primary (default) issuer,\nallowed_roles=\"*\", which roles are allowed for use with ACME; by default these will be all roles matching our selection criteria,\ndefault_directory_policy=\"\", either \"forbid\", preventing the default directory from being used at all, \"role:<role_name>\" which is the role to be used for non-role-qualified ACME requests; or \"sign-verbatim\", the default meaning ACME issuance will be equivalent to sign-verbatim.,\ndns_resolver=\"\", which specifies a custom DNS resolver to use for all ACME-related DNS lookups,\nari_window_start=66 and ari_window_end=80, the percentages of the certificate lifetime delimiting the renewal window suggested to ACME clients,\nari_retry_after=\"6h\", how long ACME clients should wait before checking the renewal information again,\nari_explanation_url=\"\", a page explaining why certificates have to be renewed early"
 	disableAcmeEnvVar      = "BAO_DISABLE_PUBLIC_ACME"
+
+	defaultAcmeARIWindowStart = 66
+	defaultAcmeARIWindowEnd   = 80
+	defaultAcmeARIRetryAfter  = 6 * time.Hour
 )
 
 type acmeConfigEntry struct {
@@ -32,6 +38,10 @@ type acmeConfigEntry struct {
 	DefaultDirectoryPolicy string        `json:"default_directory_policy"`
 	DNSResolver            string        `json:"dns_resolver"`
 	EabPolicyName          EabPolicyName `json:"eab_policy_name"`
+	ARIWindowStart         int           `json:"ari_window_start"`
+	ARIWindowEnd           int           `json:"ari_window_end"`
+	ARIRetryAfter          time.Duration `json:"ari_retry_after"`
+	ARIExplanationURL      string        `json:"ari_explanation_url"`
 }
 
 var defaultAcmeConfig = acmeConfigEntry{
@@ -42,6 +52,9 @@ var defaultAcmeConfig = acmeConfigEntry{
 	DefaultDirectoryPolicy: "sign-verbatim",
 	DNSResolver:            "",
 	EabPolicyName:          eabPolicyNotRequired,
+	ARIWindowStart:         defaultAcmeARIWindowStart,
+	ARIWindowEnd:           defaultAcmeARIWindowEnd,
+	ARIRetryAfter:          defaultAcmeARIRetryAfter,
 }
 
 func (sc *storageContext) getAcmeConfig() (*acmeConfigEntry, error) {
@@ -60,6 +73,16 @@ func (sc *storageContext) getAcmeConfig() (*acmeConfigEntry, error) {
 		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode ACME configuration: %v", err)}
 	}
 
+	// Configurations written before renewal information was supported
+	// lack its settings.
+	if mapping.ARIWindowEnd == 0 {
+		mapping.ARIWindowStart = defaultAcmeARIWindowStart
+		mapping.ARIWindowEnd = defaultAcmeARIWindowEnd
+	}
+	if mapping.ARIRetryAfter == 0 {
+		mapping.ARIRetryAfter = defaultAcmeARIRetryAfter
+	}
+
 	return &mapping, nil
 }
 
@@ -121,6 +144,26 @@ func pathAcmeConfig(b *backend) *framework.Path {
 				Description: `Specify the policy to use for external account binding behaviour, 'not-required', 'new-account-required' or 'always-required'`,
 				Default:     "always-required",
 			},
+			"ari_window_start": {
+				Type:        framework.TypeInt,
+				Description: `the percentage of the certificate lifetime at which the renewal window suggested to ACME clients starts, defaults to 66`,
+				Default:     defaultAcmeARIWindowStart,
+			},
+			"ari_window_end": {
+				Type:        framework.TypeInt,
+				Description: `the percentage of the certificate lifetime at which the renewal window suggested to ACME clients ends, defaults to 80`,
+				Default:     defaultAcmeARIWindowEnd,
+			},
+			"ari_retry_after": {
+				Type:        framework.TypeDurationSecond,
+				Description: `how long ACME clients should wait before fetching the renewal information of a certificate again, defaults to 6 hours`,
+				Default:     "6h",
+			},
+			"ari_explanation_url": {
+				Type:        framework.TypeString,
+				Description: `the URL of a page explaining why certificates have to be renewed early, returned to ACME clients along with the renewal windows of revoked certificates and forced renewals`,
+				Default:     "",
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -175,6 +218,10 @@ func genResponseFromAcmeConfig(config *acmeConfigEntry, warnings []string) *logi
 			"enabled":                  config.Enabled,
 			"dns_resolver":             config.DNSResolver,
 			"eab_policy":               config.EabPolicyName,
+			"ari_window_start":         config.ARIWindowStart,
+			"ari_window_end":           config.ARIWindowEnd,
+			"ari_retry_after":          int64(config.ARIRetryAfter.Seconds()),
+			"ari_explanation_url":      config.ARIExplanationURL,
 		},
 		Warnings: warnings,
 	}
@@ -243,6 +290,34 @@ func (b *backend) pathAcmeWrite(ctx context.Context, req *logical.Request, d *fr
 		config.EabPolicyName = eabPolicy.Name
 	}
 
+	if ariWindowStartRaw, ok := d.GetOk("ari_window_start"); ok {
+		config.ARIWindowStart = ariWindowStartRaw.(int)
+	}
+
+	if ariWindowEndRaw, ok := d.GetOk("ari_window_end"); ok {
+		config.ARIWindowEnd = ariWindowEndRaw.(int)
+	}
+
+	if config.ARIWindowStart < 0 || config.ARIWindowStart >= config.ARIWindowEnd || config.ARIWindowEnd > 100 {
+		return nil, fmt.Errorf("invalid renewal window from %d%% to %d%% of the certificate lifetime: ari_window_start must be lower than ari_window_end, both between 0 and 100", config.ARIWindowStart, config.ARIWindowEnd)
+	}
+
+	if ariRetryAfterRaw, ok := d.GetOk("ari_retry_after"); ok {
+		config.ARIRetryAfter = time.Duration(ariRetryAfterRaw.(int)) * time.Second
+		if config.ARIRetryAfter <= 0 {
+			return nil, errors.New("ari_retry_after must be positive")
+		}
+	}
+
+	if ariExplanationURLRaw, ok := d.GetOk("ari_explanation_url"); ok {
+		config.ARIExplanationURL = ariExplanationURLRaw.(string)
+		if config.ARIExplanationURL != "" {
+			if _, err := url.ParseRequestURI(config.ARIExplanationURL); err != nil {
+				return nil, fmt.Errorf("failed to parse ari_explanation_url: %w", err)
+			}
+		}
+	}
+
 	// Validate Default Directory Behavior:
 	defaultDirectoryPolicyType, err := getDefaultDirectoryPolicyType(config.DefaultDirectoryPolicy)
 	if err != nil {
@@ -0,0 +1,255 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	storageCmpConfig      = "config/cmp"
+	pathConfigCmpHelpSyn  = "Configuration of the CMP Endpoints"
+	pathConfigCmpHelpDesc = "Here we configure:\n\nenabled=false, whether CMP is enabled, defaults to false meaning that clusters will by default not get CMP support,\ndefault_path_policy=\"forbid\", either \"forbid\", preventing the default CMP path from being used at all, \"role:<role_name>\" which is the role to be used for requests to the default CMP path; or \"sign-verbatim\", meaning CMP issuance will be equivalent to sign-verbatim,\nlabel_to_path_policy={}, a map of CMP labels to path policies of the same format as default_path_policy,\ntrusted_certificates=\"\", PEM-encoded CA certificates trusted to issue the certificates signing ir and cr requests, besides the issuers of this mount,\nsecret_ttl=\"24h\", the validity of the shared secrets generated by the cmp/secret endpoint"
+
+	defaultCmpSecretTTL = 24 * time.Hour
+)
+
+type cmpConfigEntry struct {
+	Enabled             bool              `json:"enabled"`
+	DefaultPathPolicy   string            `json:"default_path_policy"`
+	LabelToPathPolicy   map[string]string `json:"label_to_path_policy"`
+	TrustedCertificates string            `json:"trusted_certificates"`
+	SecretTTL           time.Duration     `json:"secret_ttl"`
+}
+
+var defaultCmpConfig = cmpConfigEntry{
+	Enabled:           false,
+	DefaultPathPolicy: "forbid",
+	LabelToPathPolicy: map[string]string{},
+	SecretTTL:         defaultCmpSecretTTL,
+}
+
+func (sc *storageContext) getCmpConfig() (*cmpConfigEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, storageCmpConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping cmpConfigEntry
+	if entry == nil {
+		mapping = defaultCmpConfig
+		mapping.LabelToPathPolicy = map[string]string{}
+		return &mapping, nil
+	}
+
+	if err := entry.DecodeJSON(&mapping); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode CMP configuration: %v", err)}
+	}
+	if mapping.LabelToPathPolicy == nil {
+		mapping.LabelToPathPolicy = map[string]string{}
+	}
+
+	return &mapping, nil
+}
+
+func (sc *storageContext) setCmpConfig(entry *cmpConfigEntry) error {
+	json, err := logical.StorageEntryJSON(storageCmpConfig, entry)
+	if err != nil {
+		return fmt.Errorf("failed creating storage entry: %w", err)
+	}
+
+	if err := sc.Storage.Put(sc.Context, json); err != nil {
+		return fmt.Errorf("failed writing storage entry: %w", err)
+	}
+
+	return nil
+}
+
+func pathCmpConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/cmp",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": {
+				Type:        framework.TypeBool,
+				Description: `whether CMP is enabled, defaults to false meaning that clusters will by default not get CMP support`,
+				Default:     false,
+			},
+			"default_path_policy": {
+				Type:        framework.TypeString,
+				Description: `the policy to be used for requests to /.well-known/cmp; either "forbid", the default, preventing their use, "sign-verbatim" for issuance equivalent to the sign-verbatim endpoint, or "role:<role_name>" to issue certificates with the given role and its issuer`,
+				Default:     "forbid",
+			},
+			"label_to_path_policy": {
+				Type:        framework.TypeKVPairs,
+				Description: `a map of CMP labels, as in /.well-known/cmp/p/<label>, to the policy to be used for requests to this label; policies have the same format as default_path_policy`,
+			},
+			"trusted_certificates": {
+				Type:        framework.TypeString,
+				Description: `PEM-encoded CA certificates trusted to issue the certificates signing ir and cr requests, such as the manufacturer certificates of devices, besides the issuers of this mount`,
+			},
+			"secret_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: `the validity of the shared secrets generated by the cmp/secret endpoint, defaults to 24 hours`,
+				Default:     "24h",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "cmp-configuration",
+				},
+				Callback: b.pathCmpConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathCmpConfigWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "configure",
+					OperationSuffix: "cmp",
+				},
+				// Read more about why these flags are set in backend.go.
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathConfigCmpHelpSyn,
+		HelpDescription: pathConfigCmpHelpDesc,
+	}
+}
+
+func (b *backend) pathCmpConfigRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := sc.getCmpConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return genResponseFromCmpConfig(config), nil
+}
+
+func genResponseFromCmpConfig(config *cmpConfigEntry) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":              config.Enabled,
+			"default_path_policy":  config.DefaultPathPolicy,
+			"label_to_path_policy": config.LabelToPathPolicy,
+			"trusted_certificates": config.TrustedCertificates,
+			"secret_ttl":           int64(config.SecretTTL.Seconds()),
+		},
+	}
+}
+
+func (b *backend) pathCmpConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+
+	config, err := sc.getCmpConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+
+	if defaultPathPolicyRaw, ok := d.GetOk("default_path_policy"); ok {
+		config.DefaultPathPolicy = defaultPathPolicyRaw.(string)
+	}
+
+	if labelToPathPolicyRaw, ok := d.GetOk("label_to_path_policy"); ok {
+		config.LabelToPathPolicy = labelToPathPolicyRaw.(map[string]string)
+	}
+
+	if trustedCertificatesRaw, ok := d.GetOk("trusted_certificates"); ok {
+		config.TrustedCertificates = trustedCertificatesRaw.(string)
+	}
+
+	if secretTTLRaw, ok := d.GetOk("secret_ttl"); ok {
+		config.SecretTTL = time.Duration(secretTTLRaw.(int)) * time.Second
+	}
+	if config.SecretTTL <= 0 {
+		return logical.ErrorResponse("secret_ttl must be positive"), nil
+	}
+
+	if err := validateCmpPathPolicy(sc, config.DefaultPathPolicy); err != nil {
+		return logical.ErrorResponse("invalid default_path_policy: %v", err), nil
+	}
+
+	for label, policy := range config.LabelToPathPolicy {
+		if !enrollmentLabelRegex.MatchString(label) {
+			return logical.ErrorResponse("invalid CMP label %q", label), nil
+		}
+		if err := validateCmpPathPolicy(sc, policy); err != nil {
+			return logical.ErrorResponse("invalid path policy for label %q: %v", label, err), nil
+		}
+	}
+
+	if _, err := parseCmpTrustedCertificates(config.TrustedCertificates); err != nil {
+		return logical.ErrorResponse("invalid trusted_certificates: %v", err), nil
+	}
+
+	if err := sc.setCmpConfig(config); err != nil {
+		return nil, err
+	}
+
+	return genResponseFromCmpConfig(config), nil
+}
+
+// validateCmpPathPolicy checks that the role of the given path policy
+// stores its certificates, as they are looked up by later CMP messages of
+// their transaction, key update and revocation requests.
+func validateCmpPathPolicy(sc *storageContext, policy string) error {
+	role, err := getEnrollmentPathPolicyRole(sc, policy)
+	if err != nil {
+		return err
+	}
+	if role != nil && role.NoStore {
+		return errors.New("CMP requires no_store=false to be set on the role")
+	}
+
+	return nil
+}
+
+// parseCmpTrustedCertificates parses the PEM-encoded trusted_certificates of
+// the CMP configuration.
+func parseCmpTrustedCertificates(pemBundle string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(pemBundle)
+	for len(strings.TrimSpace(string(rest))) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("failed to decode PEM data")
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block of type %q", block.Type)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		if !cert.IsCA {
+			return nil, fmt.Errorf("certificate %q is not a CA certificate", cert.Subject.String())
+		}
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	storageEstConfig      = "config/est"
+	pathConfigEstHelpSyn  = "Configuration of EST Endpoints"
+	pathConfigEstHelpDesc = "Here we configure:\n\nenabled=false, whether EST is enabled, defaults to false meaning that clusters will by default not get EST support,\ndefault_path_policy=\"forbid\", either \"forbid\", preventing the default EST label from being used at all, \"role:<role_name>\" which is the role to be used for non-labelled EST requests; or \"sign-verbatim\", meaning EST issuance will be equivalent to sign-verbatim,\nlabel_to_path_policy={}, a map of EST labels to path policies of the same format as default_path_policy,\nauthenticators={}, the auth methods authenticating EST clients, either \"cert\" with an \"accessor\" and an optional \"cert_role\", or \"userpass\" with an \"accessor\""
+)
+
+type estConfigEntry struct {
+	Enabled           bool              `json:"enabled"`
+	DefaultPathPolicy string            `json:"default_path_policy"`
+	LabelToPathPolicy map[string]string `json:"label_to_path_policy"`
+	Authenticators    estAuthenticators `json:"authenticators"`
+}
+
+type estAuthenticators struct {
+	Cert     *estCertAuthenticator     `json:"cert,omitempty" mapstructure:"cert"`
+	Userpass *estUserpassAuthenticator `json:"userpass,omitempty" mapstructure:"userpass"`
+}
+
+type estCertAuthenticator struct {
+	Accessor string `json:"accessor" mapstructure:"accessor"`
+	CertRole string `json:"cert_role,omitempty" mapstructure:"cert_role"`
+}
+
+type estUserpassAuthenticator struct {
+	Accessor string `json:"accessor" mapstructure:"accessor"`
+}
+
+var defaultEstConfig = estConfigEntry{
+	Enabled:           false,
+	DefaultPathPolicy: "forbid",
+	LabelToPathPolicy: map[string]string{},
+}
+
+func (sc *storageContext) getEstConfig() (*estConfigEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, storageEstConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping estConfigEntry
+	if entry == nil {
+		mapping = defaultEstConfig
+		mapping.LabelToPathPolicy = map[string]string{}
+		return &mapping, nil
+	}
+
+	if err := entry.DecodeJSON(&mapping); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode EST configuration: %v", err)}
+	}
+	if mapping.LabelToPathPolicy == nil {
+		mapping.LabelToPathPolicy = map[string]string{}
+	}
+
+	return &mapping, nil
+}
+
+func (sc *storageContext) setEstConfig(entry *estConfigEntry) error {
+	json, err := logical.StorageEntryJSON(storageEstConfig, entry)
+	if err != nil {
+		return fmt.Errorf("failed creating storage entry: %w", err)
+	}
+
+	if err := sc.Storage.Put(sc.Context, json); err != nil {
+		return fmt.Errorf("failed writing storage entry: %w", err)
+	}
+
+	return nil
+}
+
+func pathEstConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/est",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": {
+				Type:        framework.TypeBool,
+				Description: `whether EST is enabled, defaults to false meaning that clusters will by default not get EST support`,
+				Default:     false,
+			},
+			"default_path_policy": {
+				Type:        framework.TypeString,
+				Description: `the policy to be used for non-labelled EST requests under /.well-known/est/; either "forbid", the default, preventing their use, "sign-verbatim" for issuance equivalent to the sign-verbatim endpoint, or "role:<role_name>" to issue certificates with the given role and its issuer`,
+				Default:     "forbid",
+			},
+			"label_to_path_policy": {
+				Type:        framework.TypeKVPairs,
+				Description: `a map of EST labels, as in /.well-known/est/<label>/, to the policy to be used for requests to this label; policies have the same format as default_path_policy`,
+			},
+			"authenticators": {
+				Type:        framework.TypeMap,
+				Description: `the auth methods authenticating EST clients; "cert" with the "accessor" of a cert auth method and an optional "cert_role" to log in with, authenticating clients by their TLS client certificate, and "userpass" with the "accessor" of a userpass auth method, authenticating clients with HTTP Basic authentication`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "est-configuration",
+				},
+				Callback: b.pathEstConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathEstConfigWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "configure",
+					OperationSuffix: "est",
+				},
+				// Read more about why these flags are set in backend.go.
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathConfigEstHelpSyn,
+		HelpDescription: pathConfigEstHelpDesc,
+	}
+}
+
+func (b *backend) pathEstConfigRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := sc.getEstConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return genResponseFromEstConfig(config), nil
+}
+
+func genResponseFromEstConfig(config *estConfigEntry) *logical.Response {
+	authenticators := map[string]interface{}{}
+	if config.Authenticators.Cert != nil {
+		authenticators["cert"] = map[string]interface{}{
+			"accessor":  config.Authenticators.Cert.Accessor,
+			"cert_role": config.Authenticators.Cert.CertRole,
+		}
+	}
+	if config.Authenticators.Userpass != nil {
+		authenticators["userpass"] = map[string]interface{}{
+			"accessor": config.Authenticators.Userpass.Accessor,
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":              config.Enabled,
+			"default_path_policy":  config.DefaultPathPolicy,
+			"label_to_path_policy": config.LabelToPathPolicy,
+			"authenticators":       authenticators,
+		},
+	}
+}
+
+func (b *backend) pathEstConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+
+	config, err := sc.getEstConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+
+	if defaultPathPolicyRaw, ok := d.GetOk("default_path_policy"); ok {
+		config.DefaultPathPolicy = defaultPathPolicyRaw.(string)
+	}
+
+	if labelToPathPolicyRaw, ok := d.GetOk("label_to_path_policy"); ok {
+		config.LabelToPathPolicy = labelToPathPolicyRaw.(map[string]string)
+	}
+
+	if authenticatorsRaw, ok := d.GetOk("authenticators"); ok {
+		var authenticators estAuthenticators
+		if err := mapstructure.Decode(authenticatorsRaw, &authenticators); err != nil {
+			return logical.ErrorResponse("failed to parse authenticators: %v", err), nil
+		}
+		config.Authenticators = authenticators
+	}
+
+	if _, err := getEnrollmentPathPolicyRole(sc, config.DefaultPathPolicy); err != nil {
+		return logical.ErrorResponse("invalid default_path_policy: %v", err), nil
+	}
+
+	for label, policy := range config.LabelToPathPolicy {
+		if !enrollmentLabelRegex.MatchString(label) {
+			return logical.ErrorResponse("invalid EST label %q", label), nil
+		}
+		if _, err := getEnrollmentPathPolicyRole(sc, policy); err != nil {
+			return logical.ErrorResponse("invalid path policy for label %q: %v", label, err), nil
+		}
+	}
+
+	if config.Authenticators.Cert != nil && config.Authenticators.Cert.Accessor == "" {
+		return logical.ErrorResponse("the cert authenticator requires an accessor"), nil
+	}
+	if config.Authenticators.Userpass != nil && config.Authenticators.Userpass.Accessor == "" {
+		return logical.ErrorResponse("the userpass authenticator requires an accessor"), nil
+	}
+	if config.Enabled && config.Authenticators.Cert == nil && config.Authenticators.Userpass == nil {
+		return logical.ErrorResponse("EST requires at least one of the cert or userpass authenticators to be configured"), nil
+	}
+
+	if err := sc.setEstConfig(config); err != nil {
+		return nil, err
+	}
+
+	return genResponseFromEstConfig(config), nil
+}
@@ -0,0 +1,286 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	storageScepConfig      = "config/scep"
+	pathConfigScepHelpSyn  = "Configuration of the SCEP Endpoint"
+	pathConfigScepHelpDesc = "Here we configure:\n\nenabled=false, whether SCEP is enabled, defaults to false meaning that clusters will by default not get SCEP support,\nrole=\"\", the role SCEP certificates are issued with, required when SCEP is enabled,\nissuer=\"\", the issuer of SCEP certificates, defaulting to the issuer of the role,\nra_key=\"\", the key of the SCEP registration authority, which SCEP clients encrypt their requests to and which signs the responses, required when SCEP is enabled; it must be an RSA key held by the mount,\nra_certificate=\"\", the certificate of the registration authority, for ra_key, required when SCEP is enabled,\nchallenge_ttl=\"1h\", the validity of the one-time challenge passwords generated by the scep/challenge endpoint"
+
+	defaultScepChallengeTTL = 1 * time.Hour
+)
+
+type scepConfigEntry struct {
+	Enabled       bool          `json:"enabled"`
+	Role          string        `json:"role"`
+	Issuer        string        `json:"issuer"`
+	RAKey         string        `json:"ra_key"`
+	RACertificate string        `json:"ra_certificate"`
+	ChallengeTTL  time.Duration `json:"challenge_ttl"`
+}
+
+var defaultScepConfig = scepConfigEntry{
+	Enabled:      false,
+	ChallengeTTL: defaultScepChallengeTTL,
+}
+
+func (sc *storageContext) getScepConfig() (*scepConfigEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, storageScepConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping scepConfigEntry
+	if entry == nil {
+		mapping = defaultScepConfig
+		return &mapping, nil
+	}
+
+	if err := entry.DecodeJSON(&mapping); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode SCEP configuration: %v", err)}
+	}
+
+	return &mapping, nil
+}
+
+func (sc *storageContext) setScepConfig(entry *scepConfigEntry) error {
+	json, err := logical.StorageEntryJSON(storageScepConfig, entry)
+	if err != nil {
+		return fmt.Errorf("failed creating storage entry: %w", err)
+	}
+
+	if err := sc.Storage.Put(sc.Context, json); err != nil {
+		return fmt.Errorf("failed writing storage entry: %w", err)
+	}
+
+	return nil
+}
+
+func pathScepConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/scep",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": {
+				Type:        framework.TypeBool,
+				Description: `whether SCEP is enabled, defaults to false meaning that clusters will by default not get SCEP support`,
+				Default:     false,
+			},
+			"role": {
+				Type:        framework.TypeString,
+				Description: `the role SCEP certificates are issued with, required when SCEP is enabled`,
+			},
+			"issuer": {
+				Type:        framework.TypeString,
+				Description: `the issuer of SCEP certificates, defaulting to the issuer of the role`,
+			},
+			"ra_key": {
+				Type:        framework.TypeString,
+				Description: `the key of the SCEP registration authority, which SCEP clients encrypt their requests to and which signs the responses, required when SCEP is enabled; it must be an RSA key held by the mount`,
+			},
+			"ra_certificate": {
+				Type:        framework.TypeString,
+				Description: `the PEM-encoded certificate of the registration authority, for ra_key, required when SCEP is enabled`,
+			},
+			"challenge_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: `the validity of the one-time challenge passwords generated by the scep/challenge endpoint, defaults to 1 hour`,
+				Default:     "1h",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "scep-configuration",
+				},
+				Callback: b.pathScepConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathScepConfigWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "configure",
+					OperationSuffix: "scep",
+				},
+				// Read more about why these flags are set in backend.go.
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathConfigScepHelpSyn,
+		HelpDescription: pathConfigScepHelpDesc,
+	}
+}
+
+func (b *backend) pathScepConfigRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := sc.getScepConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return genResponseFromScepConfig(config), nil
+}
+
+func genResponseFromScepConfig(config *scepConfigEntry) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":        config.Enabled,
+			"role":           config.Role,
+			"issuer":         config.Issuer,
+			"ra_key":         config.RAKey,
+			"ra_certificate": config.RACertificate,
+			"challenge_ttl":  int64(config.ChallengeTTL.Seconds()),
+		},
+	}
+}
+
+func (b *backend) pathScepConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+
+	config, err := sc.getScepConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+
+	if roleRaw, ok := d.GetOk("role"); ok {
+		config.Role = roleRaw.(string)
+	}
+
+	if issuerRaw, ok := d.GetOk("issuer"); ok {
+		config.Issuer = issuerRaw.(string)
+	}
+
+	if raKeyRaw, ok := d.GetOk("ra_key"); ok {
+		config.RAKey = raKeyRaw.(string)
+	}
+
+	if raCertificateRaw, ok := d.GetOk("ra_certificate"); ok {
+		config.RACertificate = raCertificateRaw.(string)
+	}
+
+	if challengeTTLRaw, ok := d.GetOk("challenge_ttl"); ok {
+		config.ChallengeTTL = time.Duration(challengeTTLRaw.(int)) * time.Second
+	}
+	if config.ChallengeTTL <= 0 {
+		return logical.ErrorResponse("challenge_ttl must be positive"), nil
+	}
+
+	if config.Enabled && config.Role == "" {
+		return logical.ErrorResponse("SCEP requires a role to be configured"), nil
+	}
+	if config.Enabled && (config.RAKey == "" || config.RACertificate == "") {
+		return logical.ErrorResponse("SCEP requires a registration authority key and certificate to be configured"), nil
+	}
+
+	if config.Role != "" {
+		role, _, err := getScepRoleAndIssuer(sc, config)
+		if err != nil {
+			return logical.ErrorResponse("invalid SCEP role or issuer: %v", err), nil
+		}
+		if role.NoStore {
+			return logical.ErrorResponse("SCEP requires no_store=false to be set on role %q", config.Role), nil
+		}
+	}
+
+	if config.RAKey != "" || config.RACertificate != "" {
+		if _, _, err := getScepRegistrationAuthority(sc, config); err != nil {
+			return logical.ErrorResponse("invalid SCEP registration authority: %v", err), nil
+		}
+	}
+
+	if err := sc.setScepConfig(config); err != nil {
+		return nil, err
+	}
+
+	return genResponseFromScepConfig(config), nil
+}
+
+// getScepRoleAndIssuer returns the role and issuer of SCEP certificates.
+func getScepRoleAndIssuer(sc *storageContext, config *scepConfigEntry) (*roleEntry, *issuerEntry, error) {
+	role, err := sc.Backend.getRole(sc.Context, sc.Storage, config.Role)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role == nil {
+		return nil, nil, fmt.Errorf("role %q does not exist", config.Role)
+	}
+
+	issuerName := config.Issuer
+	if issuerName == "" {
+		issuerName = role.Issuer
+	}
+	issuer, err := getEnrollmentIssuer(sc, issuerName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return role, issuer, nil
+}
+
+// getScepRegistrationAuthority returns the certificate and key of the SCEP
+// registration authority. Clients encrypt their requests to it rather than to
+// the issuer, whose key is only used to issue certificates.
+func getScepRegistrationAuthority(sc *storageContext, config *scepConfigEntry) (*x509.Certificate, *rsa.PrivateKey, error) {
+	if config.RAKey == "" || config.RACertificate == "" {
+		return nil, nil, errors.New("both ra_key and ra_certificate must be set")
+	}
+
+	cert, err := parseCertificateFromBytes([]byte(config.RACertificate))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse ra_certificate: %w", err)
+	}
+
+	keyId, err := sc.resolveKeyReference(config.RAKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := sc.fetchKeyById(keyId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if key.isManagedPrivateKey() {
+		return nil, nil, fmt.Errorf("key %q is held by a managed key, which cannot decrypt SCEP requests", config.RAKey)
+	}
+	signer, _, _, err := getSignerFromKeyEntryBytes(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	rsaKey, ok := signer.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("key %q is not an RSA key, as SCEP clients encrypt their requests to it", config.RAKey)
+	}
+
+	equal, err := comparePublicKey(key, cert.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !equal {
+		return nil, nil, fmt.Errorf("ra_certificate is not a certificate for key %q", config.RAKey)
+	}
+
+	return cert, rsaKey, nil
+}
@@ -0,0 +1,420 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strings"
+
+	"github.com/smallstep/pkcs7"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/certutil"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	pathEstHelpSyn  = `An endpoint implementing the EST protocol`
+	pathEstHelpDesc = `This API endpoint implements the EST protocol defined in
+ RFC 7030, with its own authentication and argument syntax that does not
+ follow conventional OpenBao operations. An EST client tool or library
+ should be used to interact with these endpoints.`
+
+	estContentTypeCerts    = "application/pkcs7-mime; smime-type=certs-only"
+	estContentTypeCSRAttrs = "application/csrattrs"
+	estContentTypePKCS8    = "application/pkcs8"
+
+	// estMaxRequestSize bounds the size of the base64-encoded CSRs
+	estMaxRequestSize = 64 * 1024
+)
+
+var (
+	oidPublicKeyRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidPublicKeyECDSA   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidPublicKeyEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+	oidNamedCurves = map[int]asn1.ObjectIdentifier{
+		224: {1, 3, 132, 0, 33},
+		256: {1, 2, 840, 10045, 3, 1, 7},
+		384: {1, 3, 132, 0, 34},
+		521: {1, 3, 132, 0, 35},
+	}
+)
+
+// estAttribute is an Attribute of CsrAttrs (RFC 7030 Section 4.5.2).
+type estAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+func pathEst(b *backend) []*framework.Path {
+	var paths []*framework.Path
+	paths = append(paths, buildEstFrameworkPaths(b, "cacerts", logical.ReadOperation, b.estWrapper(false, b.estCACertsHandler))...)
+	paths = append(paths, buildEstFrameworkPaths(b, "csrattrs", logical.ReadOperation, b.estWrapper(false, b.estCSRAttrsHandler))...)
+	paths = append(paths, buildEstFrameworkPaths(b, "simpleenroll", logical.UpdateOperation, b.estWrapper(true, b.estSimpleEnrollHandler))...)
+	paths = append(paths, buildEstFrameworkPaths(b, "simplereenroll", logical.UpdateOperation, b.estWrapper(true, b.estSimpleReenrollHandler))...)
+	paths = append(paths, buildEstFrameworkPaths(b, "serverkeygen", logical.UpdateOperation, b.estWrapper(true, b.estServerKeygenHandler))...)
+	return paths
+}
+
+// buildEstFrameworkPaths builds the default and labelled paths of the given
+// EST operation.
+func buildEstFrameworkPaths(b *backend, estOp string, op logical.Operation, callback framework.OperationFunc) []*framework.Path {
+	var paths []*framework.Path
+	for _, pattern := range []string{
+		`\.well-known/est/` + estOp,
+		`\.well-known/est/` + framework.GenericNameRegex("label") + "/" + estOp,
+	} {
+		fields := map[string]*framework.FieldSchema{}
+		if strings.Contains(pattern, "label") {
+			fields["label"] = &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The EST label, mapped to a role by the label_to_path_policy of the EST configuration",
+			}
+		}
+
+		paths = append(paths, &framework.Path{
+			Pattern: pattern,
+			Fields:  fields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				op: &framework.PathOperation{
+					Callback:                    callback,
+					ForwardPerformanceSecondary: false,
+					ForwardPerformanceStandby:   op == logical.UpdateOperation,
+				},
+			},
+
+			HelpSynopsis:    pathEstHelpSyn,
+			HelpDescription: pathEstHelpDesc,
+		})
+	}
+
+	return paths
+}
+
+func (b *backend) estCACertsHandler(estCtx *estContext, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	var chain []byte
+	for _, certPem := range estCtx.issuer.CAChain {
+		block, _ := pem.Decode([]byte(certPem))
+		if block == nil {
+			return nil, fmt.Errorf("failed to decode the CA chain of issuer %v", estCtx.issuer.ID)
+		}
+		chain = append(chain, block.Bytes...)
+	}
+
+	certs, err := pkcs7.DegenerateCertificate(chain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CA certificates: %w", err)
+	}
+
+	return estBase64Response(estContentTypeCerts, certs), nil
+}
+
+func (b *backend) estCSRAttrsHandler(estCtx *estContext, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	var attrs []asn1.RawValue
+
+	// Let clients know which type of key to generate, if the role requires
+	// one in particular
+	var attr interface{}
+	switch estCtx.role.KeyType {
+	case "rsa":
+		bits, err := asn1.Marshal(estCtx.role.KeyBits)
+		if err != nil {
+			return nil, err
+		}
+		attr = estAttribute{Type: oidPublicKeyRSA, Values: []asn1.RawValue{{FullBytes: bits}}}
+	case "ec":
+		curve, err := asn1.Marshal(oidNamedCurves[estCtx.role.KeyBits])
+		if err != nil {
+			return nil, err
+		}
+		attr = estAttribute{Type: oidPublicKeyECDSA, Values: []asn1.RawValue{{FullBytes: curve}}}
+	case "ed25519":
+		attr = oidPublicKeyEd25519
+	}
+	if attr != nil {
+		der, err := asn1.Marshal(attr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode CSR attributes: %w", err)
+		}
+		attrs = append(attrs, asn1.RawValue{FullBytes: der})
+	}
+
+	if len(attrs) == 0 {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPStatusCode: http.StatusNoContent,
+			},
+		}, nil
+	}
+
+	der, err := asn1.Marshal(attrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CSR attributes: %w", err)
+	}
+
+	return estBase64Response(estContentTypeCSRAttrs, der), nil
+}
+
+func (b *backend) estSimpleEnrollHandler(estCtx *estContext, r *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	csr, err := readEstCsr(r)
+	if err != nil {
+		return estErrorResponse(http.StatusBadRequest, "%s", err), nil
+	}
+
+	return b.estIssue(estCtx, r, csr)
+}
+
+// estSimpleReenrollHandler renews the TLS client certificate of the request,
+// which must have been issued by this mount, as described in RFC 7030
+// Section 4.2.2.
+func (b *backend) estSimpleReenrollHandler(estCtx *estContext, r *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	cert := estPeerCertificate(r)
+	if cert == nil {
+		return estErrorResponse(http.StatusForbidden, "re-enrollment requires the certificate to renew as TLS client certificate"), nil
+	}
+
+	if resp, err := verifyEstRenewedCertificate(estCtx.sc, cert); resp != nil || err != nil {
+		return resp, err
+	}
+
+	csr, err := readEstCsr(r)
+	if err != nil {
+		return estErrorResponse(http.StatusBadRequest, "%s", err), nil
+	}
+
+	if !bytes.Equal(csr.RawSubject, cert.RawSubject) || !sameSubjectAltNames(csr, cert) {
+		return estErrorResponse(http.StatusBadRequest, "the subject and subject alternative names of the CSR must match the certificate to renew"), nil
+	}
+
+	return b.estIssue(estCtx, r, csr)
+}
+
+// estServerKeygenHandler generates the key of the certificate on the server,
+// with the key type of the role or, when the role allows any, of the CSR. The
+// CSR of the client only supplies the requested subject and subject
+// alternative names.
+func (b *backend) estServerKeygenHandler(estCtx *estContext, r *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	csr, err := readEstCsr(r)
+	if err != nil {
+		return estErrorResponse(http.StatusBadRequest, "%s", err), nil
+	}
+
+	keyType, keyBits := estCtx.role.KeyType, estCtx.role.KeyBits
+	if keyType == "any" {
+		switch csr.PublicKey.(type) {
+		case *rsa.PublicKey:
+			keyType = "rsa"
+		case *ecdsa.PublicKey:
+			keyType = "ec"
+		case ed25519.PublicKey:
+			keyType = "ed25519"
+		default:
+			return estErrorResponse(http.StatusBadRequest, "unsupported CSR key type %v", csr.PublicKeyAlgorithm), nil
+		}
+		keyBits = certutil.GetPublicKeySize(csr.PublicKey)
+	}
+
+	keyBundle, err := certutil.CreateKeyBundle(keyType, keyBits, rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s key: %w", keyType, err)
+	}
+
+	csrDer, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		RawSubject:     csr.RawSubject,
+		DNSNames:       csr.DNSNames,
+		EmailAddresses: csr.EmailAddresses,
+		IPAddresses:    csr.IPAddresses,
+		URIs:           csr.URIs,
+	}, keyBundle.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSR for the generated key: %w", err)
+	}
+	keyCsr, err := x509.ParseCertificateRequest(csrDer)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedBundle, resp, err := b.estSignCsr(estCtx, r, keyCsr)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	keyDer, err := x509.MarshalPKCS8PrivateKey(keyBundle.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the generated key: %w", err)
+	}
+	certs, err := pkcs7.DegenerateCertificate(parsedBundle.CertificateBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode certificate: %w", err)
+	}
+
+	// The key and the certificate are returned as a multipart response, as
+	// described in RFC 7030 Section 4.4.2
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		der         []byte
+	}{
+		{estContentTypePKCS8, keyDer},
+		{estContentTypeCerts, certs},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := partWriter.Write(estBase64Encode(part.der)); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:         http.StatusOK,
+			logical.HTTPContentType:        "multipart/mixed; boundary=" + writer.Boundary(),
+			logical.HTTPRawBody:            body.Bytes(),
+			logical.HTTPCacheControlHeader: "no-store",
+		},
+	}, nil
+}
+
+// estIssue signs the given CSR and returns the certificate as EST response.
+func (b *backend) estIssue(estCtx *estContext, r *logical.Request, csr *x509.CertificateRequest) (*logical.Response, error) {
+	parsedBundle, resp, err := b.estSignCsr(estCtx, r, csr)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	certs, err := pkcs7.DegenerateCertificate(parsedBundle.CertificateBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode certificate: %w", err)
+	}
+
+	return estBase64Response(estContentTypeCerts, certs), nil
+}
+
+// estSignCsr signs the given CSR with the role and issuer of the EST path,
+// returning signing failures caused by the CSR as EST errors.
+func (b *backend) estSignCsr(estCtx *estContext, r *logical.Request, csr *x509.CertificateRequest) (*certutil.ParsedCertBundle, *logical.Response, error) {
+	parsedBundle, err := signEnrollmentCsr(b, estCtx.sc, r, estCtx.role, estCtx.issuer, estCtx.verbatim, csr)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, estErrorResponse(http.StatusBadRequest, "refusing to sign CSR: %s", err), nil
+		default:
+			return nil, nil, err
+		}
+	}
+
+	return parsedBundle, nil, nil
+}
+
+// readEstCsr reads the base64-encoded PKCS#10 CSR of the request body.
+func readEstCsr(r *logical.Request) (*x509.CertificateRequest, error) {
+	if r.HTTPRequest == nil || r.HTTPRequest.Body == nil {
+		return nil, errors.New("no CSR in request body, expected application/pkcs10 content")
+	}
+	defer r.HTTPRequest.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(r.HTTPRequest.Body, estMaxRequestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(body) > estMaxRequestSize {
+		return nil, errors.New("request is too large")
+	}
+
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(body)), ""))
+	if err != nil {
+		// Some clients send the DER encoding as is
+		der = body
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSR: %w", err)
+	}
+	if err := validateEnrollmentCsr(csr); err != nil {
+		return nil, err
+	}
+
+	return csr, nil
+}
+
+// estPeerCertificate returns the TLS client certificate of the request, if
+// any.
+func estPeerCertificate(r *logical.Request) *x509.Certificate {
+	if r.Connection == nil || r.Connection.ConnState == nil || len(r.Connection.ConnState.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.Connection.ConnState.PeerCertificates[0]
+}
+
+// verifyEstRenewedCertificate checks that the given certificate to renew was
+// issued by one of the issuers of the mount, and is neither expired nor
+// revoked.
+func verifyEstRenewedCertificate(sc *storageContext, cert *x509.Certificate) (*logical.Response, error) {
+	reason, err := checkRenewableCertificate(sc, cert)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		return estErrorResponse(http.StatusForbidden, "%s", reason), nil
+	}
+
+	return nil, nil
+}
+
+// sameSubjectAltNames returns whether the CSR requests the same subject
+// alternative names as the certificate has.
+func sameSubjectAltNames(csr *x509.CertificateRequest, cert *x509.Certificate) bool {
+	sameStrings := func(a, b []string) bool {
+		a, b = slices.Clone(a), slices.Clone(b)
+		slices.Sort(a)
+		slices.Sort(b)
+		return slices.Equal(a, b)
+	}
+
+	var csrIPs, certIPs, csrURIs, certURIs []string
+	for _, ip := range csr.IPAddresses {
+		csrIPs = append(csrIPs, ip.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		certIPs = append(certIPs, ip.String())
+	}
+	for _, uri := range csr.URIs {
+		csrURIs = append(csrURIs, uri.String())
+	}
+	for _, uri := range cert.URIs {
+		certURIs = append(certURIs, uri.String())
+	}
+
+	return sameStrings(csr.DNSNames, cert.DNSNames) &&
+		sameStrings(csr.EmailAddresses, cert.EmailAddresses) &&
+		sameStrings(csrIPs, certIPs) &&
+		sameStrings(csrURIs, certURIs)
+}
@@ -0,0 +1,475 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/smallstep/pkcs7"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openbao/openbao/api/v2"
+	"github.com/openbao/openbao/audit"
+	"github.com/openbao/openbao/builtin/credential/cert"
+	"github.com/openbao/openbao/builtin/credential/userpass"
+	"github.com/openbao/openbao/helper/testhelpers/corehelpers"
+	vaulthttp "github.com/openbao/openbao/http"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+)
+
+func TestEstConfig(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBRead(b, s, "config/est")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, false, resp.Data["enabled"])
+	require.Equal(t, "forbid", resp.Data["default_path_policy"])
+
+	_, err = CBWrite(b, s, "roles/device", map[string]interface{}{
+		"allow_any_name": true,
+	})
+	require.NoError(t, err)
+
+	authenticators := map[string]interface{}{
+		"userpass": map[string]interface{}{"accessor": "auth_userpass_1234"},
+	}
+	for _, tc := range []struct {
+		name  string
+		data  map[string]interface{}
+		valid bool
+	}{
+		{"role", map[string]interface{}{"enabled": true, "default_path_policy": "role:device", "authenticators": authenticators}, true},
+		{"sign-verbatim", map[string]interface{}{"enabled": true, "default_path_policy": "sign-verbatim", "authenticators": authenticators}, true},
+		{"labels", map[string]interface{}{"enabled": true, "label_to_path_policy": map[string]interface{}{"routers": "role:device", "iot": "sign-verbatim"}, "authenticators": authenticators}, true},
+		{"missing-role", map[string]interface{}{"default_path_policy": "role:missing"}, false},
+		{"bad-policy", map[string]interface{}{"default_path_policy": "bad"}, false},
+		{"bad-label", map[string]interface{}{"label_to_path_policy": map[string]interface{}{"a/b": "sign-verbatim"}}, false},
+		{"bad-label-policy", map[string]interface{}{"label_to_path_policy": map[string]interface{}{"iot": "role:missing"}}, false},
+		{"no-authenticators", map[string]interface{}{"enabled": true, "authenticators": map[string]interface{}{}}, false},
+		{"no-accessor", map[string]interface{}{"authenticators": map[string]interface{}{"cert": map[string]interface{}{"cert_role": "devices"}}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := CBWrite(b, s, "config/est", tc.data)
+			if tc.valid {
+				requireSuccessNonNilResponse(t, resp, err)
+			} else if err == nil && (resp == nil || !resp.IsError()) {
+				t.Fatalf("expected config to be rejected, got %#v", resp)
+			}
+		})
+	}
+
+	resp, err = CBRead(b, s, "config/est")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, map[string]string{"routers": "role:device", "iot": "sign-verbatim"}, resp.Data["label_to_path_policy"])
+	require.Equal(t, map[string]interface{}{
+		"userpass": map[string]interface{}{"accessor": "auth_userpass_1234"},
+	}, resp.Data["authenticators"])
+}
+
+func TestEstWorkflow(t *testing.T) {
+	t.Parallel()
+	cluster, client := setupEstCluster(t, nil)
+	defer cluster.Cleanup()
+
+	resp, err := client.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+		"common_name": "EST Root CA",
+		"key_type":    "ec",
+		"ttl":         "87600h",
+	})
+	require.NoError(t, err)
+	caCert := parseCert(t, resp.Data["certificate"].(string))
+
+	_, err = client.Logical().Write("pki/roles/device", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "ec",
+		"key_bits":       256,
+		"ttl":            "24h",
+	})
+	require.NoError(t, err)
+
+	// Only devices with the est policy may enroll
+	require.NoError(t, client.Sys().PutPolicy("est", `
+path "pki/.well-known/est/*" {
+  capabilities = ["update"]
+}`))
+	require.NoError(t, client.Sys().EnableAuthWithOptions("userpass", &api.EnableAuthOptions{Type: "userpass"}))
+	_, err = client.Logical().Write("auth/userpass/users/device", map[string]interface{}{
+		"password": "secret",
+		"policies": "est",
+	})
+	require.NoError(t, err)
+	_, err = client.Logical().Write("auth/userpass/users/other", map[string]interface{}{
+		"password": "secret",
+	})
+	require.NoError(t, err)
+
+	// Basic credentials must reach the mount
+	require.NoError(t, client.Sys().TuneMount("pki", api.MountConfigInput{
+		PassthroughRequestHeaders: []string{"Authorization"},
+	}))
+
+	auths, err := client.Sys().ListAuth()
+	require.NoError(t, err)
+
+	_, err = client.Logical().Write("pki/config/est", map[string]interface{}{
+		"enabled":              true,
+		"default_path_policy":  "role:device",
+		"label_to_path_policy": map[string]interface{}{"verbatim": "sign-verbatim"},
+		"authenticators": map[string]interface{}{
+			"userpass": map[string]interface{}{"accessor": auths["userpass/"].Accessor},
+		},
+	})
+	require.NoError(t, err)
+
+	est := newEstTestClient(t, cluster, "pki")
+
+	// cacerts does not require authentication
+	status, _, body := est.do(t, http.MethodGet, "cacerts", nil, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+	certs := parseEstCerts(t, body)
+	require.Len(t, certs, 1)
+	require.True(t, certs[0].Equal(caCert))
+
+	// The role requires P-256 keys
+	status, _, body = est.do(t, http.MethodGet, "csrattrs", nil, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+	var attrs []asn1.RawValue
+	_, err = asn1.Unmarshal(decodeEstBase64(t, body), &attrs)
+	require.NoError(t, err)
+	require.Len(t, attrs, 1)
+	var attr estAttribute
+	_, err = asn1.Unmarshal(attrs[0].FullBytes, &attr)
+	require.NoError(t, err)
+	require.True(t, attr.Type.Equal(oidPublicKeyECDSA))
+
+	key, csr := generateEstCsr(t, "device-1.example.com")
+
+	// Enrollment requires authentication, and the est policy
+	status, header, _ := est.do(t, http.MethodPost, "simpleenroll", csr, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+	require.Contains(t, header.Get("WWW-Authenticate"), "Basic")
+	status, _, _ = est.do(t, http.MethodPost, "simpleenroll", csr, &estBasicAuth{"device", "wrong"})
+	require.Equal(t, http.StatusForbidden, status)
+	status, _, _ = est.do(t, http.MethodPost, "simpleenroll", csr, &estBasicAuth{"other", "secret"})
+	require.Equal(t, http.StatusForbidden, status)
+
+	status, _, body = est.do(t, http.MethodPost, "simpleenroll", csr, &estBasicAuth{"device", "secret"})
+	require.Equal(t, http.StatusOK, status, string(body))
+	certs = parseEstCerts(t, body)
+	require.Len(t, certs, 1)
+	leaf := certs[0]
+	require.Equal(t, "device-1.example.com", leaf.Subject.CommonName)
+	requireSignedBy(t, leaf, caCert)
+	requireMatchingPublicKeys(t, leaf, key.Public())
+
+	// The certificate is stored
+	resp, err = client.Logical().Read("pki/cert/" + certutilSerial(leaf))
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	// Labels map to other path policies, and unknown labels are rejected
+	status, _, body = est.do(t, http.MethodPost, "verbatim/simpleenroll", csr, &estBasicAuth{"device", "secret"})
+	require.Equal(t, http.StatusOK, status, string(body))
+	status, _, _ = est.do(t, http.MethodGet, "unknown/cacerts", nil, nil)
+	require.Equal(t, http.StatusNotFound, status)
+
+	// Re-enrollment requires the certificate to renew, with the same subject
+	status, _, _ = est.do(t, http.MethodPost, "simplereenroll", csr, &estBasicAuth{"device", "secret"})
+	require.Equal(t, http.StatusForbidden, status)
+
+	renewing := est.withClientCertificate(t, leaf, key)
+	_, otherCsr := generateEstCsr(t, "device-2.example.com")
+	status, _, body = renewing.do(t, http.MethodPost, "simplereenroll", otherCsr, &estBasicAuth{"device", "secret"})
+	require.Equal(t, http.StatusBadRequest, status, string(body))
+
+	newKey, newCsr := generateEstCsr(t, "device-1.example.com")
+	status, _, body = renewing.do(t, http.MethodPost, "simplereenroll", newCsr, &estBasicAuth{"device", "secret"})
+	require.Equal(t, http.StatusOK, status, string(body))
+	renewed := parseEstCerts(t, body)[0]
+	require.Equal(t, "device-1.example.com", renewed.Subject.CommonName)
+	requireMatchingPublicKeys(t, renewed, newKey.Public())
+
+	// Revoked certificates cannot be renewed
+	_, err = client.Logical().Write("pki/revoke", map[string]interface{}{
+		"serial_number": certutilSerial(leaf),
+	})
+	require.NoError(t, err)
+	status, _, _ = renewing.do(t, http.MethodPost, "simplereenroll", newCsr, &estBasicAuth{"device", "secret"})
+	require.Equal(t, http.StatusForbidden, status)
+
+	// Server-side key generation returns the key with the certificate
+	status, header, body = est.do(t, http.MethodPost, "serverkeygen", csr, &estBasicAuth{"device", "secret"})
+	require.Equal(t, http.StatusOK, status, string(body))
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	keyPart, err := reader.NextPart()
+	require.NoError(t, err)
+	require.Equal(t, "application/pkcs8", keyPart.Header.Get("Content-Type"))
+	keyBody, err := io.ReadAll(keyPart)
+	require.NoError(t, err)
+	generatedKey, err := x509.ParsePKCS8PrivateKey(decodeEstBase64(t, keyBody))
+	require.NoError(t, err)
+	certPart, err := reader.NextPart()
+	require.NoError(t, err)
+	certBody, err := io.ReadAll(certPart)
+	require.NoError(t, err)
+	generated := parseEstCerts(t, certBody)[0]
+	require.Equal(t, "device-1.example.com", generated.Subject.CommonName)
+	requireMatchingPublicKeys(t, generated, generatedKey.(crypto.Signer).Public())
+
+	// Devices may also authenticate with their TLS client certificate
+	require.NoError(t, client.Sys().EnableAuthWithOptions("cert", &api.EnableAuthOptions{Type: "cert"}))
+	_, err = client.Logical().Write("auth/cert/certs/devices", map[string]interface{}{
+		"certificate":   string(pemEncodeCert(caCert)),
+		"policies":      "est",
+		"allowed_names": "device-1.example.com",
+	})
+	require.NoError(t, err)
+	auths, err = client.Sys().ListAuth()
+	require.NoError(t, err)
+	_, err = client.Logical().Write("pki/config/est", map[string]interface{}{
+		"authenticators": map[string]interface{}{
+			"cert": map[string]interface{}{"accessor": auths["cert/"].Accessor, "cert_role": "devices"},
+		},
+	})
+	require.NoError(t, err)
+
+	status, _, _ = est.do(t, http.MethodPost, "simpleenroll", newCsr, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, _, body = est.withClientCertificate(t, renewed, newKey).do(t, http.MethodPost, "simpleenroll", newCsr, nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	// Disabling EST disables all the endpoints
+	_, err = client.Logical().Write("pki/config/est", map[string]interface{}{
+		"enabled": false,
+	})
+	require.NoError(t, err)
+	status, _, _ = est.do(t, http.MethodGet, "cacerts", nil, nil)
+	require.Equal(t, http.StatusNotFound, status)
+}
+
+func TestEstLogin(t *testing.T) {
+	t.Parallel()
+	noop := corehelpers.TestNoopAudit(t, nil)
+	cluster, client := setupEstCluster(t, map[string]audit.Factory{
+		"noop": func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+			return noop, nil
+		},
+	})
+	defer cluster.Cleanup()
+	require.NoError(t, client.Sys().EnableAuditWithOptions("noop", &api.EnableAuditOptions{Type: "noop"}))
+
+	_, err := client.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+		"common_name": "EST Root CA",
+		"key_type":    "ec",
+		"ttl":         "87600h",
+	})
+	require.NoError(t, err)
+	_, err = client.Logical().Write("pki/roles/device", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "ec",
+		"ttl":            "24h",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Sys().PutPolicy("est", `
+path "pki/.well-known/est/*" {
+  capabilities = ["update"]
+}`))
+	require.NoError(t, client.Sys().EnableAuthWithOptions("userpass", &api.EnableAuthOptions{Type: "userpass"}))
+	require.NoError(t, client.Sys().TuneMount("auth/userpass", api.MountConfigInput{
+		UserLockoutConfig: &api.UserLockoutConfigInput{
+			LockoutThreshold: "2",
+			LockoutDuration:  "1h",
+		},
+	}))
+	_, err = client.Logical().Write("auth/userpass/users/device", map[string]interface{}{
+		"password": "secret",
+		"policies": "est",
+	})
+	require.NoError(t, err)
+	_, err = client.Logical().Write("auth/userpass/users/bound", map[string]interface{}{
+		"password":          "secret",
+		"policies":          "est",
+		"token_bound_cidrs": "192.0.2.0/24",
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Sys().TuneMount("pki", api.MountConfigInput{
+		PassthroughRequestHeaders: []string{"Authorization"},
+	}))
+
+	auths, err := client.Sys().ListAuth()
+	require.NoError(t, err)
+	_, err = client.Logical().Write("pki/config/est", map[string]interface{}{
+		"enabled":             true,
+		"default_path_policy": "role:device",
+		"authenticators": map[string]interface{}{
+			"userpass": map[string]interface{}{"accessor": auths["userpass/"].Accessor},
+		},
+	})
+	require.NoError(t, err)
+
+	est := newEstTestClient(t, cluster, "pki")
+	_, csr := generateEstCsr(t, "device.example.com")
+
+	// The login is audited, and does not create a token
+	status, _, body := est.do(t, http.MethodPost, "simpleenroll", csr, &estBasicAuth{"device", "secret"})
+	require.Equal(t, http.StatusOK, status, string(body))
+	var audited bool
+	for i, req := range noop.RespReq {
+		if req.Path == "auth/userpass/login/device" {
+			audited = true
+			require.NotNil(t, noop.RespAuth[i])
+			require.Empty(t, noop.RespAuth[i].ClientToken)
+		}
+	}
+	require.True(t, audited, "login was not audited")
+
+	// Tokens bound to other CIDRs would not be usable by the client
+	status, _, _ = est.do(t, http.MethodPost, "simpleenroll", csr, &estBasicAuth{"bound", "secret"})
+	require.Equal(t, http.StatusForbidden, status)
+
+	// Failed logins lock the user out
+	for i := 0; i < 2; i++ {
+		status, _, _ = est.do(t, http.MethodPost, "simpleenroll", csr, &estBasicAuth{"device", "wrong"})
+		require.Equal(t, http.StatusForbidden, status)
+	}
+	status, _, _ = est.do(t, http.MethodPost, "simpleenroll", csr, &estBasicAuth{"device", "secret"})
+	require.Equal(t, http.StatusForbidden, status)
+}
+
+func setupEstCluster(t *testing.T, auditBackends map[string]audit.Factory) (*vault.TestCluster, *api.Client) {
+	coreConfig := &vault.CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"cert":     cert.Factory,
+			"userpass": userpass.Factory,
+		},
+		LogicalBackends: map[string]logical.Factory{
+			"pki": Factory,
+		},
+		AuditBackends: auditBackends,
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	client := cluster.Cores[0].Client
+	mountPKIEndpoint(t, client, "pki")
+	return cluster, client
+}
+
+type estBasicAuth struct {
+	username string
+	password string
+}
+
+// estTestClient is a minimal EST client, speaking to the EST endpoints of a
+// mount of the test cluster.
+type estTestClient struct {
+	baseURL   string
+	tlsConfig *tls.Config
+}
+
+func newEstTestClient(t *testing.T, cluster *vault.TestCluster, mount string) *estTestClient {
+	// Do not present the client certificate of the cluster
+	tlsConfig := cluster.Cores[0].TLSConfig()
+	tlsConfig.Certificates = nil
+	return &estTestClient{
+		baseURL:   fmt.Sprintf("https://%s/v1/%s/.well-known/est/", cluster.Cores[0].Listeners[0].Address, mount),
+		tlsConfig: tlsConfig,
+	}
+}
+
+func (c *estTestClient) withClientCertificate(t *testing.T, cert *x509.Certificate, key crypto.Signer) *estTestClient {
+	tlsConfig := c.tlsConfig.Clone()
+	// The listeners of the test cluster only advertise the CA of the
+	// cluster, so always present the certificate to not have it filtered out
+	tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return &tls.Certificate{
+			Certificate: [][]byte{cert.Raw},
+			PrivateKey:  key,
+		}, nil
+	}
+	return &estTestClient{baseURL: c.baseURL, tlsConfig: tlsConfig}
+}
+
+func (c *estTestClient) do(t *testing.T, method, op string, csr []byte, auth *estBasicAuth) (int, http.Header, []byte) {
+	var body io.Reader
+	if csr != nil {
+		body = strings.NewReader(base64.StdEncoding.EncodeToString(csr))
+	}
+	req, err := http.NewRequest(method, c.baseURL+op, body)
+	require.NoError(t, err)
+	if csr != nil {
+		req.Header.Set("Content-Type", "application/pkcs10")
+		req.Header.Set("Content-Transfer-Encoding", "base64")
+	}
+	if auth != nil {
+		req.SetBasicAuth(auth.username, auth.password)
+	}
+
+	transport := cleanhttp.DefaultTransport()
+	transport.TLSClientConfig = c.tlsConfig
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, resp.Header, respBody
+}
+
+func generateEstCsr(t *testing.T, commonName string) (crypto.Signer, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: []string{commonName},
+	}, key)
+	require.NoError(t, err)
+	return key, csr
+}
+
+func decodeEstBase64(t *testing.T, body []byte) []byte {
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(body)), ""))
+	require.NoError(t, err)
+	return der
+}
+
+func parseEstCerts(t *testing.T, body []byte) []*x509.Certificate {
+	p7, err := pkcs7.Parse(decodeEstBase64(t, body))
+	require.NoError(t, err)
+	return p7.Certificates
+}
+
+func certutilSerial(cert *x509.Certificate) string {
+	return normalizeSerialFromBigInt(cert.SerialNumber)
+}
+
+func pemEncodeCert(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
@@ -180,6 +180,53 @@ hyphen-separated octal`,
 	}
 }
 
+// Returns the custom metadata of a stored cert. Unlike the cert itself, this
+// path is authenticated.
+func pathFetchCertMetadata(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `cert/(?P<serial>[0-9A-Fa-f-:]+)/metadata`,
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+			OperationSuffix: "cert-metadata",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"serial": {
+				Type: framework.TypeString,
+				Description: `Certificate serial number, in colon- or
+hyphen-separated octal`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathFetchCertMetadataRead,
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: "OK",
+						Fields: map[string]*framework.FieldSchema{
+							"serial_number": {
+								Type:        framework.TypeString,
+								Description: `Certificate serial number`,
+								Required:    true,
+							},
+							"cert_metadata": {
+								Type:        framework.TypeKVPairs,
+								Description: `Custom metadata stored alongside the certificate`,
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    pathFetchCertMetadataHelpSyn,
+		HelpDescription: pathFetchCertMetadataHelpDesc,
+	}
+}
+
 // This returns the CRL in a non-raw format
 func pathFetchCRLViaCertPath(b *backend) *framework.Path {
 	pattern := `cert/(crl|delta-crl)`
@@ -648,3 +695,49 @@ Using "ca_chain" as the value fetches the certificate authority trust chain in P
 
 Otherwise, specify a serial number to fetch the specified certificate. Add "/raw" to get just the certificate in DER form, "/raw/pem" to get the PEM encoded certificate.
 `
+
+func (b *backend) pathFetchCertMetadataRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	serial := data.Get("serial").(string)
+	if len(serial) == 0 {
+		return logical.ErrorResponse("The serial number must be provided"), nil
+	}
+
+	sc := b.makeStorageContext(ctx, req.Storage)
+	certEntry, err := fetchCertBySerial(sc, "certs/", serial)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+	if certEntry == nil {
+		return nil, nil
+	}
+
+	certMetadata, err := sc.fetchCertMetadata(serial)
+	if err != nil {
+		return nil, err
+	}
+	if certMetadata == nil {
+		certMetadata = map[string]string{}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"serial_number": denormalizeSerial(normalizeSerial(serial)),
+			"cert_metadata": certMetadata,
+		},
+	}, nil
+}
+
+const pathFetchCertMetadataHelpSyn = `
+Fetch the custom metadata of a stored certificate.
+`
+
+const pathFetchCertMetadataHelpDesc = `
+This allows reading the custom metadata given when issuing the certificate
+with the specified serial number. Unlike reading the certificate itself, this
+requires authentication.
+`
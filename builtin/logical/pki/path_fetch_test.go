@@ -122,6 +122,62 @@ func TestListCertificatesWithDetails(t *testing.T) {
 	}
 }
 
+// TestFetchCertMetadataAuthenticated validates that certificates can be read
+// without a token, but that their custom metadata cannot.
+func TestFetchCertMetadataAuthenticated(t *testing.T) {
+	t.Parallel()
+
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"pki": Factory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	client := cluster.Cores[0].Client
+	mountPKIEndpoint(t, client, "pki")
+
+	_, err := client.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+		"common_name": "Root",
+		"key_type":    "ec",
+		"ttl":         "40h",
+	})
+	require.NoError(t, err)
+	_, err = client.Logical().Write("pki/roles/web", map[string]interface{}{
+		"allow_any_name":        true,
+		"allowed_cert_metadata": "service",
+	})
+	require.NoError(t, err)
+	resp, err := client.Logical().Write("pki/issue/web", map[string]interface{}{
+		"common_name":   "web.example.com",
+		"ttl":           "10m",
+		"cert_metadata": "service=web",
+	})
+	require.NoError(t, err)
+	serial := resp.Data["serial_number"].(string)
+
+	unauthClient, err := client.Clone()
+	require.NoError(t, err)
+	unauthClient.ClearToken()
+
+	resp, err = unauthClient.Logical().Read("pki/cert/" + serial)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.NotEmpty(t, resp.Data["certificate"])
+	require.NotContains(t, resp.Data, "cert_metadata")
+
+	_, err = unauthClient.Logical().Read("pki/cert/" + serial + "/metadata")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "permission denied")
+
+	resp, err = client.Logical().Read("pki/cert/" + serial + "/metadata")
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, map[string]interface{}{"service": "web"}, resp.Data["cert_metadata"])
+}
+
 func checkCertificateDetails(t *testing.T, certData, expectedDetails map[string]interface{}) {
 	actualDNSNames, ok := certData["dns_names"].([]interface{})
 	require.True(t, ok, "Expected dns_names to be a list")
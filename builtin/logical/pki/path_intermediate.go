@@ -197,7 +197,14 @@ func (b *backend) pathGenerateIntermediate(ctx context.Context, req *logical.Req
 		}
 	}
 
-	myKey, _, err := sc.importKey(csrb.PrivateKey, keyName, csrb.PrivateKeyType)
+	var myKey *keyEntry
+	if csrb.PrivateKey == "" {
+		// CSRs generated with a managed key carry no private key; the key
+		// was registered before the CSR was generated.
+		myKey, err = sc.findManagedKeyEntry(parsedBundle.CSR.PublicKey)
+	} else {
+		myKey, _, err = sc.importKey(csrb.PrivateKey, keyName, csrb.PrivateKeyType)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -435,6 +435,11 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 			`the "format" path parameter must be "pem", "der", or "pem_bundle"`), nil
 	}
 
+	certMetadata := data.Get("cert_metadata").(map[string]string)
+	if err := validateCertMetadata(role, certMetadata); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
 	var caErr error
 	sc := b.makeStorageContext(ctx, req.Storage)
 	signingBundle, caErr := sc.fetchCAInfo(issuerName, IssuanceUsage)
@@ -567,6 +572,10 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 			return nil, fmt.Errorf("unable to store certificate locally: %w", err)
 		}
 		b.ifCountEnabledIncrementTotalCertificatesCount(certsCounted, key)
+
+		if err := sc.storeCertMetadata(cb.SerialNumber, certMetadata); err != nil {
+			return nil, err
+		}
 	}
 
 	if useCSR {
@@ -580,6 +589,12 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 
 	resp = addWarnings(resp, warnings)
 
+	b.SendEvent(ctx, eventTypeCertIssue, "cert/"+normalizeSerial(cb.SerialNumber), map[string]string{
+		"serial_number": cb.SerialNumber,
+		"role":          role.Name,
+		"issuer":        issuerName,
+	})
+
 	return resp, nil
 }
 
@@ -13,6 +13,7 @@ import (
 
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/helper/certutil"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
 )
 
@@ -26,7 +27,7 @@ func pathGenerateKey(b *backend) *framework.Path {
 			OperationSuffix: "internal-key|exported-key|kms-key",
 		},
 
-		Fields: map[string]*framework.FieldSchema{
+		Fields: addManagedKeyFields(map[string]*framework.FieldSchema{
 			keyNameParam: {
 				Type:        framework.TypeString,
 				Description: "Optional name to be used for this key",
@@ -49,7 +50,7 @@ func pathGenerateKey(b *backend) *framework.Path {
 4096; with ec key_type: 224, 256 (default), 384, or 521; ignored with
 ed25519.`,
 			},
-		},
+		}),
 
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
@@ -79,6 +80,11 @@ ed25519.`,
 								Description: `The private key string`,
 								Required:    false,
 							},
+							managedKeyIdArg: {
+								Type:        framework.TypeString,
+								Description: `UUID of the managed key holding the private key, for kms keys.`,
+								Required:    false,
+							},
 						},
 					}},
 				},
@@ -95,7 +101,12 @@ ed25519.`,
 
 const (
 	pathGenerateKeyHelpSyn  = `Generate a new private key used for signing.`
-	pathGenerateKeyHelpDesc = `This endpoint will generate a new key pair of the specified type (internal, exported, or kms).`
+	pathGenerateKeyHelpDesc = `This endpoint will generate a new key pair of the specified type (internal, exported, or kms).
+
+With kms, the key pair is held by the managed key given by managed_key_name
+or managed_key_id, generating it in its KMS when it does not exist yet. The
+private key never leaves the KMS; the mount must list the managed key in its
+allowed_managed_keys.`
 )
 
 func (b *backend) pathGenerateKeyHandler(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -137,6 +148,23 @@ func (b *backend) pathGenerateKeyHandler(ctx context.Context, req *logical.Reque
 		}
 
 		actualPrivateKeyType = keyBundle.PrivateKeyType
+	case strings.HasSuffix(req.Path, "/kms"):
+		key, _, err := sc.importManagedKey(data, keyName)
+		if err != nil {
+			if _, ok := err.(errutil.UserError); ok {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				keyIdParam:      key.ID,
+				keyNameParam:    key.Name,
+				keyTypeParam:    string(key.PrivateKeyType),
+				managedKeyIdArg: key.ManagedKeyUUID,
+			},
+		}, nil
 	default:
 		return logical.ErrorResponse("Unknown type of key to generate"), nil
 	}
@@ -379,7 +379,7 @@ func getOcspIssuerParsedBundle(sc *storageContext, issuerId issuerID) (*certutil
 		return nil, nil, ErrIssuerHasNoKey
 	}
 
-	caBundle, err := bundle.ToParsedCertBundle()
+	caBundle, err := sc.parseCABundle(issuer, bundle)
 	if err != nil {
 		return nil, nil, err
 	}
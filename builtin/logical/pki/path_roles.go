@@ -198,6 +198,10 @@ Any valid URI is accepted, these values support globbing.`,
 			Type:        framework.TypeCommaStringSlice,
 			Description: `If set, an array of allowed user-ids to put in user system login name specified here: https://www.rfc-editor.org/rfc/rfc1274#section-9.3.1`,
 		},
+		"allowed_cert_metadata": {
+			Type:        framework.TypeCommaStringSlice,
+			Description: `If set, an array of allowed cert_metadata keys which may be attached to certificates issued by this role. These values support globbing. Defaults to none.`,
+		},
 		"server_flag": {
 			Type:    framework.TypeBool,
 			Default: true,
@@ -575,6 +579,14 @@ Any valid URI is accepted, these values support globbing.`,
 				Description: `If set, an array of allowed user-ids to put in user system login name specified here: https://www.rfc-editor.org/rfc/rfc1274#section-9.3.1`,
 			},
 
+			"allowed_cert_metadata": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `If set, an array of allowed cert_metadata keys which may be attached to certificates issued by this role. These values support globbing. Defaults to none.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Allowed Certificate Metadata",
+				},
+			},
+
 			"server_flag": {
 				Type:    framework.TypeBool,
 				Default: true,
@@ -1127,6 +1139,7 @@ func (b *backend) pathRoleCreate(ctx context.Context, req *logical.Request, data
 		CNValidations:                 data.Get("cn_validations").([]string),
 		AllowedSerialNumbers:          data.Get("allowed_serial_numbers").([]string),
 		AllowedUserIDs:                data.Get("allowed_user_ids").([]string),
+		AllowedCertMetadata:           data.Get("allowed_cert_metadata").([]string),
 		PolicyIdentifiers:             getPolicyIdentifier(data, nil),
 		BasicConstraintsValidForNonCA: data.Get("basic_constraints_valid_for_non_ca").(bool),
 		NotBeforeDuration:             time.Duration(data.Get("not_before_duration").(int)) * time.Second,
@@ -1328,6 +1341,7 @@ func (b *backend) pathRolePatch(ctx context.Context, req *logical.Request, data
 		CNValidations:                 getWithExplicitDefault(data, "cn_validations", oldEntry.CNValidations).([]string),
 		AllowedSerialNumbers:          getWithExplicitDefault(data, "allowed_serial_numbers", oldEntry.AllowedSerialNumbers).([]string),
 		AllowedUserIDs:                getWithExplicitDefault(data, "allowed_user_ids", oldEntry.AllowedUserIDs).([]string),
+		AllowedCertMetadata:           getWithExplicitDefault(data, "allowed_cert_metadata", oldEntry.AllowedCertMetadata).([]string),
 		PolicyIdentifiers:             getPolicyIdentifier(data, &oldEntry.PolicyIdentifiers),
 		BasicConstraintsValidForNonCA: getWithExplicitDefault(data, "basic_constraints_valid_for_non_ca", oldEntry.BasicConstraintsValidForNonCA).(bool),
 		NotBeforeDuration:             getTimeWithExplicitDefault(data, "not_before_duration", oldEntry.NotBeforeDuration),
@@ -1536,6 +1550,7 @@ type roleEntry struct {
 	AllowedOtherSANs              []string      `json:"allowed_other_sans"`
 	AllowedSerialNumbers          []string      `json:"allowed_serial_numbers"`
 	AllowedUserIDs                []string      `json:"allowed_user_ids"`
+	AllowedCertMetadata           []string      `json:"allowed_cert_metadata"`
 	AllowedURISANs                []string      `json:"allowed_uri_sans"`
 	AllowedURISANsTemplate        bool          `json:"allowed_uri_sans_template"`
 	PolicyIdentifiers             []string      `json:"policy_identifiers"`
@@ -1589,6 +1604,7 @@ func (r *roleEntry) ToResponseData() map[string]interface{} {
 		"allowed_other_sans":                 r.AllowedOtherSANs,
 		"allowed_serial_numbers":             r.AllowedSerialNumbers,
 		"allowed_user_ids":                   r.AllowedUserIDs,
+		"allowed_cert_metadata":              r.AllowedCertMetadata,
 		"allowed_uri_sans":                   r.AllowedURISANs,
 		"require_cn":                         r.RequireCN,
 		"cn_validations":                     r.CNValidations,
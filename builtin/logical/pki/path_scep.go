@@ -0,0 +1,547 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/smallstep/pkcs7"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	pathScepHelpSyn  = `An endpoint implementing the SCEP protocol`
+	pathScepHelpDesc = `This API endpoint implements the SCEP protocol defined in
+ RFC 8894, with its own authentication and argument syntax that does not
+ follow conventional OpenBao operations. A SCEP client tool or library
+ should be used to interact with this endpoint.`
+
+	scepContentTypeCARACert = "application/x-x509-ca-ra-cert"
+	scepContentTypeMessage  = "application/x-pki-message"
+
+	// scepCACaps are the capabilities of the SCEP server (RFC 8894 Section
+	// 3.5.2)
+	scepCACaps = "AES\nPOSTPKIOperation\nRenewal\nSCEPStandard\nSHA-256\nSHA-512\n"
+
+	// scepMaxRequestSize bounds the size of the SCEP messages
+	scepMaxRequestSize = 64 * 1024
+)
+
+// SCEP message types (RFC 8894 Section 3.2.1.2)
+const (
+	scepMessageTypeCertRep    = "3"
+	scepMessageTypeRenewalReq = "17"
+	scepMessageTypePKCSReq    = "19"
+	scepMessageTypeCertPoll   = "20"
+	scepMessageTypeGetCert    = "21"
+)
+
+// SCEP PKI statuses and failure reasons (RFC 8894 Sections 3.2.1.3 and
+// 3.2.1.4)
+const (
+	scepStatusSuccess = "0"
+	scepStatusFailure = "2"
+
+	scepFailBadAlg          = "0"
+	scepFailBadMessageCheck = "1"
+	scepFailBadRequest      = "2"
+	scepFailBadCertID       = "4"
+)
+
+var (
+	oidScepMessageType    = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 2}
+	oidScepPKIStatus      = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 3}
+	oidScepFailInfo       = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 4}
+	oidScepSenderNonce    = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 5}
+	oidScepRecipientNonce = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 6}
+	oidScepTransactionID  = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 7}
+
+	oidChallengePassword = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 7}
+)
+
+func init() {
+	// SCEP responses are encrypted with AES, as advertised in the
+	// capabilities of the server, rather than with the DES default.
+	pkcs7.ContentEncryptionAlgorithm = pkcs7.EncryptionAlgorithmAES128CBC
+}
+
+type scepContext struct {
+	sc     *storageContext
+	config *scepConfigEntry
+	role   *roleEntry
+	issuer *issuerEntry
+	raCert *x509.Certificate
+	raKey  *rsa.PrivateKey
+}
+
+// scepRequest is a decoded PKIOperation message.
+type scepRequest struct {
+	messageType   string
+	transactionID string
+	senderNonce   []byte
+	signer        *x509.Certificate
+	content       []byte
+}
+
+func pathScep(b *backend) []*framework.Path {
+	var paths []*framework.Path
+	for _, pattern := range []string{"scep", `scep/pkiclient\.exe`} {
+		paths = append(paths, &framework.Path{
+			Pattern: pattern,
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixPKI,
+				OperationSuffix: "scep",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"operation": {
+					Type:        framework.TypeString,
+					Description: "The SCEP operation, GetCACaps, GetCACert or PKIOperation",
+					Query:       true,
+				},
+				"message": {
+					Type:        framework.TypeString,
+					Description: "The base64-encoded SCEP message of a GET PKIOperation",
+					Query:       true,
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback:                    b.scepWrapper(b.scepGetHandler),
+					ForwardPerformanceSecondary: false,
+					ForwardPerformanceStandby:   false,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                    b.scepWrapper(b.scepPostHandler),
+					ForwardPerformanceSecondary: false,
+					ForwardPerformanceStandby:   true,
+				},
+			},
+
+			HelpSynopsis:    pathScepHelpSyn,
+			HelpDescription: pathScepHelpDesc,
+		})
+	}
+
+	return paths
+}
+
+type scepOperation func(scepCtx *scepContext, r *logical.Request, data *framework.FieldData) (*logical.Response, error)
+
+// scepWrapper loads the role and issuer of SCEP certificates, and the
+// registration authority, before calling the given SCEP handler.
+func (b *backend) scepWrapper(op scepOperation) framework.OperationFunc {
+	return func(ctx context.Context, r *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		sc := b.makeStorageContext(ctx, r.Storage)
+
+		config, err := sc.getScepConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch SCEP configuration: %w", err)
+		}
+		if !config.Enabled {
+			return scepErrorResponse(http.StatusNotFound, "SCEP is not enabled on this mount"), nil
+		}
+
+		role, issuer, err := getScepRoleAndIssuer(sc, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the SCEP role and issuer: %w", err)
+		}
+
+		raCert, raKey, err := getScepRegistrationAuthority(sc, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the SCEP registration authority: %w", err)
+		}
+
+		return op(&scepContext{
+			sc:     sc,
+			config: config,
+			role:   role,
+			issuer: issuer,
+			raCert: raCert,
+			raKey:  raKey,
+		}, r, data)
+	}
+}
+
+func (b *backend) scepGetHandler(scepCtx *scepContext, r *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	switch operation := data.Get("operation").(string); operation {
+	case "GetCACaps":
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPStatusCode:  http.StatusOK,
+				logical.HTTPContentType: "text/plain",
+				logical.HTTPRawBody:     []byte(scepCACaps),
+			},
+		}, nil
+	case "GetCACert":
+		return b.scepGetCACert(scepCtx)
+	case "PKIOperation":
+		// Clients which do not escape the message have its '+' decoded as
+		// spaces by the query parsing
+		message := strings.ReplaceAll(data.Get("message").(string), " ", "+")
+		message = strings.Join(strings.Fields(message), "")
+		der, err := base64.StdEncoding.DecodeString(message)
+		if err != nil {
+			return scepErrorResponse(http.StatusBadRequest, "failed to decode message: %s", err), nil
+		}
+		return b.scepPKIOperation(scepCtx, r, der)
+	default:
+		return scepErrorResponse(http.StatusBadRequest, "unsupported SCEP operation %q", operation), nil
+	}
+}
+
+func (b *backend) scepPostHandler(scepCtx *scepContext, r *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	if r.HTTPRequest == nil || r.HTTPRequest.Body == nil {
+		return scepErrorResponse(http.StatusBadRequest, "no message in request body, expected %s content", scepContentTypeMessage), nil
+	}
+	defer r.HTTPRequest.Body.Close()
+
+	if operation := r.HTTPRequest.URL.Query().Get("operation"); operation != "PKIOperation" {
+		return scepErrorResponse(http.StatusBadRequest, "unsupported SCEP operation %q", operation), nil
+	}
+
+	der, err := io.ReadAll(io.LimitReader(r.HTTPRequest.Body, scepMaxRequestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(der) > scepMaxRequestSize {
+		return scepErrorResponse(http.StatusRequestEntityTooLarge, "request is too large"), nil
+	}
+
+	return b.scepPKIOperation(scepCtx, r, der)
+}
+
+// scepGetCACert returns the registration authority certificate and the chain
+// of the issuer as a degenerate PKCS#7 structure (RFC 8894 Section 4.2.1.2).
+func (b *backend) scepGetCACert(scepCtx *scepContext) (*logical.Response, error) {
+	chain := scepCtx.raCert.Raw
+	for _, certPem := range scepCtx.issuer.CAChain {
+		block, _ := pem.Decode([]byte(certPem))
+		if block == nil {
+			return nil, fmt.Errorf("failed to decode the CA chain of issuer %v", scepCtx.issuer.ID)
+		}
+		chain = append(chain, block.Bytes...)
+	}
+
+	certs, err := pkcs7.DegenerateCertificate(chain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CA certificates: %w", err)
+	}
+
+	return scepRawResponse(scepContentTypeCARACert, certs), nil
+}
+
+// scepPKIOperation handles the given PKIOperation message, and returns a
+// CertRep message (RFC 8894 Section 3.3.2).
+func (b *backend) scepPKIOperation(scepCtx *scepContext, r *logical.Request, der []byte) (*logical.Response, error) {
+	req, err := parseScepRequest(der)
+	if err != nil {
+		return scepErrorResponse(http.StatusBadRequest, "%s", err), nil
+	}
+
+	envelope, err := pkcs7.Parse(req.content)
+	if err != nil {
+		return b.scepFailure(scepCtx, req, scepFailBadMessageCheck)
+	}
+	content, err := envelope.Decrypt(scepCtx.raCert, scepCtx.raKey)
+	if err != nil {
+		b.Logger().Debug("failed to decrypt SCEP message", "error", err)
+		return b.scepFailure(scepCtx, req, scepFailBadMessageCheck)
+	}
+
+	var cert []byte
+	switch req.messageType {
+	case scepMessageTypePKCSReq, scepMessageTypeRenewalReq:
+		var failInfo string
+		cert, failInfo, err = b.scepEnroll(scepCtx, r, req, content)
+		if err != nil {
+			return nil, err
+		}
+		if failInfo != "" {
+			return b.scepFailure(scepCtx, req, failInfo)
+		}
+	case scepMessageTypeCertPoll:
+		// Certificates are issued right away, so polling only fetches the
+		// certificate of a past transaction
+		transaction, err := scepCtx.sc.getScepTransaction(req.transactionID)
+		if err != nil {
+			return nil, err
+		}
+		if transaction == nil {
+			return b.scepFailure(scepCtx, req, scepFailBadCertID)
+		}
+		cert, err = fetchScepCertificate(scepCtx.sc, transaction.SerialNumber)
+		if err != nil {
+			return nil, err
+		}
+	case scepMessageTypeGetCert:
+		var issuerAndSerial struct {
+			Issuer       asn1.RawValue
+			SerialNumber *big.Int
+		}
+		if _, err := asn1.Unmarshal(content, &issuerAndSerial); err != nil {
+			return b.scepFailure(scepCtx, req, scepFailBadRequest)
+		}
+		cert, err = fetchScepCertificate(scepCtx.sc, normalizeSerialFromBigInt(issuerAndSerial.SerialNumber))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return b.scepFailure(scepCtx, req, scepFailBadRequest)
+	}
+	if cert == nil {
+		return b.scepFailure(scepCtx, req, scepFailBadCertID)
+	}
+
+	return b.scepSuccess(scepCtx, req, cert)
+}
+
+// scepEnroll signs the CSR of a PKCSReq or RenewalReq message. PKCSReq
+// messages must carry a valid challenge password generated for the common
+// name of the CSR, while RenewalReq messages must be signed with a valid
+// certificate issued by this mount, whose subject and subject alternative
+// names the CSR must request. A failure reason is returned when the request
+// is refused.
+func (b *backend) scepEnroll(scepCtx *scepContext, r *logical.Request, req *scepRequest, content []byte) ([]byte, string, error) {
+	csr, err := x509.ParseCertificateRequest(content)
+	if err != nil {
+		return nil, scepFailBadRequest, nil
+	}
+	if err := validateEnrollmentCsr(csr); err != nil {
+		b.Logger().Debug("refusing SCEP CSR", "error", err)
+		return nil, scepFailBadRequest, nil
+	}
+
+	switch req.messageType {
+	case scepMessageTypePKCSReq:
+		challenge, err := getCsrChallengePassword(csr)
+		if err != nil || challenge == "" {
+			return nil, scepFailBadRequest, nil
+		}
+		valid, err := b.consumeScepChallenge(scepCtx.sc, challenge, csr)
+		if err != nil {
+			return nil, "", err
+		}
+		if !valid {
+			return nil, scepFailBadRequest, nil
+		}
+	case scepMessageTypeRenewalReq:
+		reason, err := checkRenewableCertificate(scepCtx.sc, req.signer)
+		if err != nil {
+			return nil, "", err
+		}
+		if reason != "" {
+			b.Logger().Debug("refusing SCEP renewal", "reason", reason)
+			return nil, scepFailBadRequest, nil
+		}
+		if !bytes.Equal(csr.RawSubject, req.signer.RawSubject) || !sameSubjectAltNames(csr, req.signer) {
+			b.Logger().Debug("refusing SCEP renewal", "reason", "the subject and subject alternative names of the CSR do not match the certificate to renew")
+			return nil, scepFailBadRequest, nil
+		}
+	}
+
+	parsedBundle, err := signEnrollmentCsr(b, scepCtx.sc, r, scepCtx.role, scepCtx.issuer, false, csr)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			b.Logger().Debug("refusing to sign SCEP CSR", "error", err)
+			return nil, scepFailBadRequest, nil
+		default:
+			return nil, "", err
+		}
+	}
+
+	if err := scepCtx.sc.putScepTransaction(req.transactionID, parsedBundle.Certificate); err != nil {
+		return nil, "", err
+	}
+
+	return parsedBundle.CertificateBytes, "", nil
+}
+
+// parseScepRequest parses and verifies the signed envelope of a PKIOperation
+// message.
+func parseScepRequest(der []byte) (*scepRequest, error) {
+	p7, err := pkcs7.Parse(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	if err := p7.Verify(); err != nil {
+		return nil, fmt.Errorf("invalid message signature: %w", err)
+	}
+
+	req := &scepRequest{
+		signer:  p7.GetOnlySigner(),
+		content: p7.Content,
+	}
+	if req.signer == nil {
+		return nil, errors.New("message must have a single signer")
+	}
+
+	if err := p7.UnmarshalSignedAttribute(oidScepMessageType, &req.messageType); err != nil {
+		return nil, fmt.Errorf("failed to read message type: %w", err)
+	}
+	if err := p7.UnmarshalSignedAttribute(oidScepTransactionID, &req.transactionID); err != nil || req.transactionID == "" {
+		return nil, errors.New("missing transaction ID")
+	}
+	if err := p7.UnmarshalSignedAttribute(oidScepSenderNonce, &req.senderNonce); err != nil {
+		return nil, errors.New("missing sender nonce")
+	}
+
+	return req, nil
+}
+
+// getCsrChallengePassword returns the challengePassword attribute of the given
+// CSR, which is not parsed by crypto/x509.
+func getCsrChallengePassword(csr *x509.CertificateRequest) (string, error) {
+	var tbs struct {
+		Raw           asn1.RawContent
+		Version       int
+		Subject       asn1.RawValue
+		PublicKey     asn1.RawValue
+		RawAttributes []asn1.RawValue `asn1:"tag:0"`
+	}
+	if _, err := asn1.Unmarshal(csr.RawTBSCertificateRequest, &tbs); err != nil {
+		return "", err
+	}
+
+	for _, rawAttr := range tbs.RawAttributes {
+		var attr struct {
+			Type   asn1.ObjectIdentifier
+			Values []asn1.RawValue `asn1:"set"`
+		}
+		if _, err := asn1.Unmarshal(rawAttr.FullBytes, &attr); err != nil {
+			return "", err
+		}
+		if !attr.Type.Equal(oidChallengePassword) || len(attr.Values) != 1 {
+			continue
+		}
+
+		var challenge string
+		if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &challenge); err != nil {
+			return "", err
+		}
+		return challenge, nil
+	}
+
+	return "", nil
+}
+
+func fetchScepCertificate(sc *storageContext, serial string) ([]byte, error) {
+	certEntry, err := fetchCertBySerial(sc, "certs/", serial)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+	if certEntry == nil {
+		return nil, nil
+	}
+
+	return certEntry.Value, nil
+}
+
+// scepSuccess returns a CertRep message with the given certificate, encrypted
+// to the signer of the request.
+func (b *backend) scepSuccess(scepCtx *scepContext, req *scepRequest, cert []byte) (*logical.Response, error) {
+	if _, ok := req.signer.PublicKey.(*rsa.PublicKey); !ok {
+		// Responses can only be encrypted to RSA keys
+		return b.scepFailure(scepCtx, req, scepFailBadAlg)
+	}
+
+	certs, err := pkcs7.DegenerateCertificate(cert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode certificate: %w", err)
+	}
+	envelope, err := pkcs7.Encrypt(certs, []*x509.Certificate{req.signer})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt SCEP response: %w", err)
+	}
+
+	return b.scepCertRep(scepCtx, req, envelope, []pkcs7.Attribute{
+		{Type: oidScepPKIStatus, Value: scepStatusSuccess},
+	})
+}
+
+// scepFailure returns a CertRep message with the given failure reason.
+func (b *backend) scepFailure(scepCtx *scepContext, req *scepRequest, failInfo string) (*logical.Response, error) {
+	return b.scepCertRep(scepCtx, req, nil, []pkcs7.Attribute{
+		{Type: oidScepPKIStatus, Value: scepStatusFailure},
+		{Type: oidScepFailInfo, Value: failInfo},
+	})
+}
+
+func (b *backend) scepCertRep(scepCtx *scepContext, req *scepRequest, content []byte, attrs []pkcs7.Attribute) (*logical.Response, error) {
+	senderNonce := make([]byte, 16)
+	if _, err := rand.Read(senderNonce); err != nil {
+		return nil, err
+	}
+
+	signedData, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		return nil, err
+	}
+	signedData.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+
+	attrs = append(attrs,
+		pkcs7.Attribute{Type: oidScepMessageType, Value: scepMessageTypeCertRep},
+		pkcs7.Attribute{Type: oidScepTransactionID, Value: req.transactionID},
+		pkcs7.Attribute{Type: oidScepSenderNonce, Value: senderNonce},
+		pkcs7.Attribute{Type: oidScepRecipientNonce, Value: req.senderNonce},
+	)
+	if err := signedData.AddSigner(scepCtx.raCert, scepCtx.raKey, pkcs7.SignerInfoConfig{
+		ExtraSignedAttributes: attrs,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to sign SCEP response: %w", err)
+	}
+
+	der, err := signedData.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign SCEP response: %w", err)
+	}
+
+	return scepRawResponse(scepContentTypeMessage, der), nil
+}
+
+func scepRawResponse(contentType string, der []byte) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPContentType: contentType,
+			logical.HTTPRawBody:     der,
+		},
+	}
+}
+
+// scepErrorResponse returns a plain text error, for requests which cannot be
+// answered with a CertRep message.
+func scepErrorResponse(status int, format string, args ...interface{}) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  status,
+			logical.HTTPContentType: "text/plain",
+			logical.HTTPRawBody:     []byte(fmt.Sprintf(format, args...) + "\n"),
+		},
+	}
+}
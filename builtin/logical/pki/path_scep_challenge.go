@@ -0,0 +1,256 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	scepPathPrefix            = "scep/"
+	scepChallengePrefix       = scepPathPrefix + "challenges/"
+	scepTransactionPrefix     = scepPathPrefix + "transactions/"
+	scepChallengeLength       = 16
+	scepTransactionRetention  = 24 * time.Hour
+	pathScepChallengeHelpSyn  = "Generate a one-time SCEP challenge password"
+	pathScepChallengeHelpDesc = "Generate a one-time challenge password, to be given to a SCEP client in order to enroll a single certificate with the given common name and no other subject alternative name. It expires after the challenge_ttl of the SCEP configuration."
+)
+
+type scepChallengeEntry struct {
+	CommonName string    `json:"common_name"`
+	Expiration time.Time `json:"expiration"`
+}
+
+type scepTransactionEntry struct {
+	SerialNumber string    `json:"serial_number"`
+	Expiration   time.Time `json:"expiration"`
+}
+
+func pathScepChallenge(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "scep/challenge",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixPKI,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"common_name": {
+				Type:        framework.TypeString,
+				Description: `the common name of the subject of the certificate the challenge may enroll`,
+				Required:    true,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathScepChallengeGenerate,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "generate",
+					OperationSuffix: "scep-challenge",
+				},
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathScepChallengeHelpSyn,
+		HelpDescription: pathScepChallengeHelpDesc,
+	}
+}
+
+func (b *backend) pathScepChallengeGenerate(ctx context.Context, r *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, r.Storage)
+
+	commonName := d.Get("common_name").(string)
+	if commonName == "" {
+		return logical.ErrorResponse("the common_name the challenge is bound to is required"), nil
+	}
+
+	config, err := sc.getScepConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return logical.ErrorResponse("SCEP is not enabled on this mount"), nil
+	}
+
+	// Expired challenges and transactions are removed as new challenges
+	// are generated, which bounds their number
+	if err := b.tidyScepStorage(sc); err != nil {
+		return nil, err
+	}
+
+	challengeBytes := make([]byte, scepChallengeLength)
+	if _, err := rand.Read(challengeBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	challenge := hex.EncodeToString(challengeBytes)
+
+	expiration := time.Now().Add(config.ChallengeTTL)
+	json, err := logical.StorageEntryJSON(scepChallengePrefix+scepStorageKey(challenge), &scepChallengeEntry{
+		CommonName: commonName,
+		Expiration: expiration,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed creating storage entry: %w", err)
+	}
+	if err := sc.Storage.Put(sc.Context, json); err != nil {
+		return nil, fmt.Errorf("failed writing storage entry: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"challenge":   challenge,
+			"common_name": commonName,
+			"expiration":  expiration.Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// consumeScepChallenge checks that the given challenge password was generated
+// by this mount for the common name of the given CSR and has not expired,
+// removing it so it cannot be reused. The CSR may not request any subject
+// alternative name other than that common name.
+func (b *backend) consumeScepChallenge(sc *storageContext, challenge string, csr *x509.CertificateRequest) (bool, error) {
+	b.scepChallengeLock.Lock()
+	defer b.scepChallengeLock.Unlock()
+
+	key := scepChallengePrefix + scepStorageKey(challenge)
+	entry, err := sc.Storage.Get(sc.Context, key)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+
+	var challengeEntry scepChallengeEntry
+	if err := entry.DecodeJSON(&challengeEntry); err != nil {
+		return false, fmt.Errorf("failed to decode SCEP challenge: %w", err)
+	}
+
+	if err := sc.Storage.Delete(sc.Context, key); err != nil {
+		return false, err
+	}
+
+	if challengeEntry.CommonName == "" || challengeEntry.CommonName != csr.Subject.CommonName {
+		return false, nil
+	}
+	if !csrRequestsOnlyName(csr, challengeEntry.CommonName) {
+		return false, nil
+	}
+
+	return time.Now().Before(challengeEntry.Expiration), nil
+}
+
+// csrRequestsOnlyName returns whether every subject alternative name
+// requested by the CSR is the given name.
+func csrRequestsOnlyName(csr *x509.CertificateRequest, name string) bool {
+	for _, dnsName := range csr.DNSNames {
+		if dnsName != name {
+			return false
+		}
+	}
+	for _, email := range csr.EmailAddresses {
+		if email != name {
+			return false
+		}
+	}
+	for _, ip := range csr.IPAddresses {
+		if ip.String() != name {
+			return false
+		}
+	}
+
+	return len(csr.URIs) == 0
+}
+
+// tidyScepStorage removes the expired challenges and transactions.
+func (b *backend) tidyScepStorage(sc *storageContext) error {
+	b.scepChallengeLock.Lock()
+	defer b.scepChallengeLock.Unlock()
+
+	now := time.Now()
+	for _, prefix := range []string{scepChallengePrefix, scepTransactionPrefix} {
+		keys, err := sc.Storage.List(sc.Context, prefix)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			entry, err := sc.Storage.Get(sc.Context, prefix+key)
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				continue
+			}
+
+			var expiring struct {
+				Expiration time.Time `json:"expiration"`
+			}
+			if err := entry.DecodeJSON(&expiring); err != nil {
+				return fmt.Errorf("failed to decode SCEP entry %v: %w", key, err)
+			}
+			if now.After(expiring.Expiration) {
+				if err := sc.Storage.Delete(sc.Context, prefix+key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// putScepTransaction records the certificate issued for the given SCEP
+// transaction, for clients polling for it.
+func (sc *storageContext) putScepTransaction(transactionID string, cert *x509.Certificate) error {
+	json, err := logical.StorageEntryJSON(scepTransactionPrefix+scepStorageKey(transactionID), &scepTransactionEntry{
+		SerialNumber: serialFromCert(cert),
+		Expiration:   time.Now().Add(scepTransactionRetention),
+	})
+	if err != nil {
+		return fmt.Errorf("failed creating storage entry: %w", err)
+	}
+
+	return sc.Storage.Put(sc.Context, json)
+}
+
+func (sc *storageContext) getScepTransaction(transactionID string) (*scepTransactionEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, scepTransactionPrefix+scepStorageKey(transactionID))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var transaction scepTransactionEntry
+	if err := entry.DecodeJSON(&transaction); err != nil {
+		return nil, fmt.Errorf("failed to decode SCEP transaction: %w", err)
+	}
+
+	if time.Now().After(transaction.Expiration) {
+		return nil, nil
+	}
+
+	return &transaction, nil
+}
+
+// scepStorageKey hashes the given challenge or transaction ID, so that
+// challenges are not kept in clear and IDs are safe storage keys.
+func scepStorageKey(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
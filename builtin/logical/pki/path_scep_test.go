@@ -0,0 +1,559 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/smallstep/pkcs7"
+	"github.com/stretchr/testify/require"
+
+	vaulthttp "github.com/openbao/openbao/http"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+)
+
+func TestScepConfig(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBRead(b, s, "config/scep")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, false, resp.Data["enabled"])
+	require.Equal(t, int64(3600), resp.Data["challenge_ttl"])
+
+	resp, err = CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "SCEP EC Root",
+		"key_type":    "ec",
+		"issuer_name": "ec",
+		"key_name":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	ecRoot := resp.Data["certificate"].(string)
+	resp, err = CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "SCEP RSA Root",
+		"key_type":    "rsa",
+		"issuer_name": "rsa",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	rsaRoot := resp.Data["certificate"].(string)
+
+	_, err = CBWrite(b, s, "roles/device", map[string]interface{}{
+		"allow_any_name": true,
+		"issuer_ref":     "ec",
+	})
+	require.NoError(t, err)
+	_, err = CBWrite(b, s, "roles/nostore", map[string]interface{}{
+		"allow_any_name": true,
+		"no_store":       true,
+	})
+	require.NoError(t, err)
+	raCert := issueScepRegistrationAuthority(t, func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return CBWrite(b, s, path, data)
+	}, "rsa")
+
+	ra := func(data map[string]interface{}) map[string]interface{} {
+		data["ra_key"] = "scep-ra"
+		data["ra_certificate"] = raCert
+		return data
+	}
+	for _, tc := range []struct {
+		name  string
+		data  map[string]interface{}
+		valid bool
+	}{
+		{"no-role", ra(map[string]interface{}{"enabled": true}), false},
+		{"missing-role", ra(map[string]interface{}{"enabled": true, "role": "missing"}), false},
+		{"no-ra", map[string]interface{}{"enabled": true, "role": "device"}, false},
+		{"ec-ra", map[string]interface{}{"role": "device", "ra_key": "ec", "ra_certificate": ecRoot}, false},
+		{"mismatched-ra", map[string]interface{}{"role": "device", "ra_key": "scep-ra", "ra_certificate": rsaRoot}, false},
+		{"no-store", ra(map[string]interface{}{"enabled": true, "role": "nostore", "issuer": "rsa"}), false},
+		{"bad-ttl", ra(map[string]interface{}{"role": "device", "challenge_ttl": "-1s"}), false},
+		{"valid", ra(map[string]interface{}{"enabled": true, "role": "device", "challenge_ttl": "10m"}), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := CBWrite(b, s, "config/scep", tc.data)
+			if tc.valid {
+				requireSuccessNonNilResponse(t, resp, err)
+			} else if err == nil && (resp == nil || !resp.IsError()) {
+				t.Fatalf("expected config to be rejected, got %#v", resp)
+			}
+		})
+	}
+
+	resp, err = CBRead(b, s, "config/scep")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, true, resp.Data["enabled"])
+	require.Equal(t, "device", resp.Data["role"])
+	require.Equal(t, "", resp.Data["issuer"])
+	require.Equal(t, "scep-ra", resp.Data["ra_key"])
+	require.Equal(t, raCert, resp.Data["ra_certificate"])
+	require.Equal(t, int64(600), resp.Data["challenge_ttl"])
+
+	// Challenges are bound to a common name
+	resp, err = CBWrite(b, s, "scep/challenge", nil)
+	require.True(t, err != nil || resp.IsError(), "expected challenge without common name to be rejected")
+
+	resp, err = CBWrite(b, s, "scep/challenge", map[string]interface{}{
+		"common_name": "device-1",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	challenge := resp.Data["challenge"].(string)
+	require.NotEmpty(t, challenge)
+	require.Equal(t, "device-1", resp.Data["common_name"])
+
+	// Challenges are one-time, and consumed by requests for another subject
+	sc := b.makeStorageContext(ctx, s)
+	device1 := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "device-1"}, DNSNames: []string{"device-1"}}
+	valid, err := b.consumeScepChallenge(sc, challenge, device1)
+	require.NoError(t, err)
+	require.True(t, valid)
+	valid, err = b.consumeScepChallenge(sc, challenge, device1)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	resp, err = CBWrite(b, s, "scep/challenge", map[string]interface{}{
+		"common_name": "device-1",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	challenge = resp.Data["challenge"].(string)
+	valid, err = b.consumeScepChallenge(sc, challenge, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "device-2"}})
+	require.NoError(t, err)
+	require.False(t, valid)
+	valid, err = b.consumeScepChallenge(sc, challenge, device1)
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	// and by requests for other subject alternative names
+	for _, csr := range []*x509.CertificateRequest{
+		{Subject: pkix.Name{CommonName: "device-1"}, DNSNames: []string{"device-1", "a.example"}},
+		{Subject: pkix.Name{CommonName: "device-1"}, EmailAddresses: []string{"admin@a.example"}},
+		{Subject: pkix.Name{CommonName: "device-1"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}},
+		{Subject: pkix.Name{CommonName: "device-1"}, URIs: []*url.URL{{Scheme: "spiffe", Host: "a.example"}}},
+	} {
+		resp, err = CBWrite(b, s, "scep/challenge", map[string]interface{}{
+			"common_name": "device-1",
+		})
+		requireSuccessNonNilResponse(t, resp, err)
+		challenge = resp.Data["challenge"].(string)
+		valid, err = b.consumeScepChallenge(sc, challenge, csr)
+		require.NoError(t, err)
+		require.False(t, valid)
+		valid, err = b.consumeScepChallenge(sc, challenge, device1)
+		require.NoError(t, err)
+		require.False(t, valid)
+	}
+}
+
+func TestScepWorkflow(t *testing.T) {
+	t.Parallel()
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"pki": Factory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	client := cluster.Cores[0].Client
+	mountPKIEndpoint(t, client, "pki")
+
+	resp, err := client.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+		"common_name": "SCEP Root CA",
+		"key_type":    "rsa",
+		"ttl":         "87600h",
+	})
+	require.NoError(t, err)
+	caCert := parseCert(t, resp.Data["certificate"].(string))
+
+	_, err = client.Logical().Write("pki/roles/device", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "rsa",
+		"ttl":            "24h",
+	})
+	require.NoError(t, err)
+	raCertPem := issueScepRegistrationAuthority(t, func(path string, data map[string]interface{}) (*logical.Response, error) {
+		secret, err := client.Logical().Write("pki/"+path, data)
+		if err != nil || secret == nil {
+			return nil, err
+		}
+		return &logical.Response{Data: secret.Data}, nil
+	}, "default")
+	raCert := parseCert(t, raCertPem)
+
+	scep := &scepTestClient{
+		url:       fmt.Sprintf("https://%s/v1/pki/scep/pkiclient.exe", cluster.Cores[0].Listeners[0].Address),
+		tlsConfig: cluster.Cores[0].TLSConfig(),
+		raCert:    raCert,
+	}
+
+	// SCEP is disabled by default
+	status, _, _ := scep.get(t, "GetCACaps", "")
+	require.Equal(t, http.StatusNotFound, status)
+
+	_, err = client.Logical().Write("pki/config/scep", map[string]interface{}{
+		"enabled":        true,
+		"role":           "device",
+		"ra_key":         "scep-ra",
+		"ra_certificate": raCertPem,
+	})
+	require.NoError(t, err)
+
+	status, _, body := scep.get(t, "GetCACaps", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Contains(t, string(body), "POSTPKIOperation")
+	require.Contains(t, string(body), "Renewal")
+
+	// Clients encrypt their requests to the registration authority, which is
+	// returned with the CA
+	status, header, body := scep.get(t, "GetCACert", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "application/x-x509-ca-ra-cert", header.Get("Content-Type"))
+	degenerate, err := pkcs7.Parse(body)
+	require.NoError(t, err)
+	require.Len(t, degenerate.Certificates, 2)
+	require.True(t, degenerate.Certificates[0].Equal(raCert))
+	require.True(t, degenerate.Certificates[1].Equal(caCert))
+
+	key, signer := generateScepIdentity(t, "device-1")
+
+	// Enrollment requires a valid challenge password
+	rep := scep.pkiOperation(t, key, signer, scepMessageTypePKCSReq, "txn-0", generateScepCsr(t, key, "device-1", "wrong"))
+	require.Equal(t, scepStatusFailure, rep.status)
+	require.Equal(t, scepFailBadRequest, rep.failInfo)
+
+	// and challenges only enroll the subject they were generated for
+	resp, err = client.Logical().Write("pki/scep/challenge", map[string]interface{}{
+		"common_name": "device-1",
+	})
+	require.NoError(t, err)
+	rep = scep.pkiOperation(t, key, signer, scepMessageTypePKCSReq, "txn-0", generateScepCsr(t, key, "device-2", resp.Data["challenge"].(string)))
+	require.Equal(t, scepStatusFailure, rep.status)
+	require.Equal(t, scepFailBadRequest, rep.failInfo)
+
+	// with no other subject alternative name
+	resp, err = client.Logical().Write("pki/scep/challenge", map[string]interface{}{
+		"common_name": "device-1",
+	})
+	require.NoError(t, err)
+	rep = scep.pkiOperation(t, key, signer, scepMessageTypePKCSReq, "txn-0", generateScepCsr(t, key, "device-1", resp.Data["challenge"].(string), "device-1", "a.example"))
+	require.Equal(t, scepStatusFailure, rep.status)
+	require.Equal(t, scepFailBadRequest, rep.failInfo)
+
+	resp, err = client.Logical().Write("pki/scep/challenge", map[string]interface{}{
+		"common_name": "device-1",
+	})
+	require.NoError(t, err)
+	challenge := resp.Data["challenge"].(string)
+
+	rep = scep.pkiOperation(t, key, signer, scepMessageTypePKCSReq, "txn-1", generateScepCsr(t, key, "device-1", challenge, "device-1"))
+	require.Equal(t, scepStatusSuccess, rep.status, rep.failInfo)
+	cert := rep.cert
+	require.Equal(t, "device-1", cert.Subject.CommonName)
+	require.Equal(t, []string{"device-1"}, cert.DNSNames)
+	requireSignedBy(t, cert, caCert)
+	requireMatchingPublicKeys(t, cert, key.Public())
+
+	// The certificate is stored
+	resp, err = client.Logical().Read("pki/cert/" + serialFromCert(cert))
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	// Challenges are one-time
+	rep = scep.pkiOperation(t, key, signer, scepMessageTypePKCSReq, "txn-2", generateScepCsr(t, key, "device-1", challenge))
+	require.Equal(t, scepStatusFailure, rep.status)
+
+	// Polling for the transaction and fetching the certificate return it
+	rep = scep.pkiOperation(t, key, signer, scepMessageTypeCertPoll, "txn-1", issuerAndSubject(t, caCert, cert))
+	require.Equal(t, scepStatusSuccess, rep.status, rep.failInfo)
+	require.True(t, cert.Equal(rep.cert))
+	rep = scep.pkiOperation(t, key, signer, scepMessageTypeCertPoll, "txn-unknown", issuerAndSubject(t, caCert, cert))
+	require.Equal(t, scepStatusFailure, rep.status)
+	require.Equal(t, scepFailBadCertID, rep.failInfo)
+
+	issuerAndSerial, err := asn1.Marshal(struct {
+		Issuer       asn1.RawValue
+		SerialNumber *big.Int
+	}{asn1.RawValue{FullBytes: caCert.RawSubject}, cert.SerialNumber})
+	require.NoError(t, err)
+	rep = scep.pkiOperation(t, key, signer, scepMessageTypeGetCert, "txn-3", issuerAndSerial)
+	require.Equal(t, scepStatusSuccess, rep.status, rep.failInfo)
+	require.True(t, cert.Equal(rep.cert))
+
+	// Renewals are signed with the certificate to renew, without challenge
+	newKey, _ := generateScepIdentity(t, "device-1")
+	rep = scep.pkiOperation(t, key, signer, scepMessageTypeRenewalReq, "txn-4", generateScepCsr(t, newKey, "device-1", ""))
+	require.Equal(t, scepStatusFailure, rep.status)
+
+	// Renewals keep the subject and subject alternative names of the
+	// certificate to renew
+	rep = scep.pkiOperation(t, key, cert, scepMessageTypeRenewalReq, "txn-5", generateScepCsr(t, newKey, "device-2", "", "device-2"))
+	require.Equal(t, scepStatusFailure, rep.status)
+	require.Equal(t, scepFailBadRequest, rep.failInfo)
+	rep = scep.pkiOperation(t, key, cert, scepMessageTypeRenewalReq, "txn-5", generateScepCsr(t, newKey, "device-1", "", "device-1", "a.example"))
+	require.Equal(t, scepStatusFailure, rep.status)
+	require.Equal(t, scepFailBadRequest, rep.failInfo)
+
+	rep = scep.pkiOperation(t, key, cert, scepMessageTypeRenewalReq, "txn-5", generateScepCsr(t, newKey, "device-1", "", "device-1"))
+	require.Equal(t, scepStatusSuccess, rep.status, rep.failInfo)
+	requireMatchingPublicKeys(t, rep.cert, newKey.Public())
+
+	_, err = client.Logical().Write("pki/revoke", map[string]interface{}{
+		"serial_number": serialFromCert(cert),
+	})
+	require.NoError(t, err)
+	rep = scep.pkiOperation(t, key, cert, scepMessageTypeRenewalReq, "txn-6", generateScepCsr(t, newKey, "device-1", "", "device-1"))
+	require.Equal(t, scepStatusFailure, rep.status)
+
+	// Messages may also be sent with GET requests
+	resp, err = client.Logical().Write("pki/scep/challenge", map[string]interface{}{
+		"common_name": "device-1",
+	})
+	require.NoError(t, err)
+	message := scep.buildMessage(t, key, signer, scepMessageTypePKCSReq, "txn-7", generateScepCsr(t, key, "device-1", resp.Data["challenge"].(string), "device-1"))
+	status, _, body = scep.get(t, "PKIOperation", base64.StdEncoding.EncodeToString(message))
+	require.Equal(t, http.StatusOK, status, string(body))
+	rep = scep.parseCertRep(t, key, signer, body)
+	require.Equal(t, scepStatusSuccess, rep.status, rep.failInfo)
+}
+
+// scepTestClient is a minimal SCEP client, speaking to the SCEP endpoint of a
+// mount of the test cluster.
+type scepTestClient struct {
+	url       string
+	tlsConfig *tls.Config
+	raCert    *x509.Certificate
+}
+
+type scepTestCertRep struct {
+	status   string
+	failInfo string
+	cert     *x509.Certificate
+}
+
+func (c *scepTestClient) do(t *testing.T, req *http.Request) (int, http.Header, []byte) {
+	transport := cleanhttp.DefaultTransport()
+	transport.TLSClientConfig = c.tlsConfig
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, resp.Header, body
+}
+
+func (c *scepTestClient) get(t *testing.T, operation, message string) (int, http.Header, []byte) {
+	query := url.Values{"operation": {operation}}
+	if message != "" {
+		query.Set("message", message)
+	}
+	req, err := http.NewRequest(http.MethodGet, c.url+"?"+query.Encode(), nil)
+	require.NoError(t, err)
+	return c.do(t, req)
+}
+
+// pkiOperation sends the given PKIOperation message, signed by the given
+// certificate, and returns the decoded response.
+func (c *scepTestClient) pkiOperation(t *testing.T, key *rsa.PrivateKey, signer *x509.Certificate, messageType, transactionID string, content []byte) *scepTestCertRep {
+	message := c.buildMessage(t, key, signer, messageType, transactionID, content)
+
+	req, err := http.NewRequest(http.MethodPost, c.url+"?operation=PKIOperation", bytes.NewReader(message))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", scepContentTypeMessage)
+	status, _, body := c.do(t, req)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	return c.parseCertRep(t, key, signer, body)
+}
+
+func (c *scepTestClient) buildMessage(t *testing.T, key *rsa.PrivateKey, signer *x509.Certificate, messageType, transactionID string, content []byte) []byte {
+	envelope, err := pkcs7.Encrypt(content, []*x509.Certificate{c.raCert})
+	require.NoError(t, err)
+
+	signedData, err := pkcs7.NewSignedData(envelope)
+	require.NoError(t, err)
+	signedData.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	require.NoError(t, signedData.AddSigner(signer, key, pkcs7.SignerInfoConfig{
+		ExtraSignedAttributes: []pkcs7.Attribute{
+			{Type: oidScepMessageType, Value: messageType},
+			{Type: oidScepTransactionID, Value: transactionID},
+			{Type: oidScepSenderNonce, Value: []byte("0123456789abcdef")},
+		},
+	}))
+	message, err := signedData.Finish()
+	require.NoError(t, err)
+	return message
+}
+
+func (c *scepTestClient) parseCertRep(t *testing.T, key *rsa.PrivateKey, signer *x509.Certificate, body []byte) *scepTestCertRep {
+	p7, err := pkcs7.Parse(body)
+	require.NoError(t, err)
+	require.NoError(t, p7.Verify())
+	require.True(t, p7.GetOnlySigner().Equal(c.raCert))
+
+	var messageType string
+	var recipientNonce []byte
+	rep := &scepTestCertRep{}
+	require.NoError(t, p7.UnmarshalSignedAttribute(oidScepMessageType, &messageType))
+	require.Equal(t, scepMessageTypeCertRep, messageType)
+	require.NoError(t, p7.UnmarshalSignedAttribute(oidScepRecipientNonce, &recipientNonce))
+	require.Equal(t, []byte("0123456789abcdef"), recipientNonce)
+	require.NoError(t, p7.UnmarshalSignedAttribute(oidScepPKIStatus, &rep.status))
+	if rep.status != scepStatusSuccess {
+		require.NoError(t, p7.UnmarshalSignedAttribute(oidScepFailInfo, &rep.failInfo))
+		return rep
+	}
+
+	envelope, err := pkcs7.Parse(p7.Content)
+	require.NoError(t, err)
+	certs, err := envelope.Decrypt(signer, key)
+	require.NoError(t, err)
+	degenerate, err := pkcs7.Parse(certs)
+	require.NoError(t, err)
+	require.Len(t, degenerate.Certificates, 1)
+	rep.cert = degenerate.Certificates[0]
+	return rep
+}
+
+// issueScepRegistrationAuthority issues a certificate for the SCEP
+// registration authority with the given issuer, imports its key as scep-ra,
+// and returns the PEM-encoded certificate.
+func issueScepRegistrationAuthority(t *testing.T, write func(string, map[string]interface{}) (*logical.Response, error), issuer string) string {
+	_, err := write("roles/scep-ra", map[string]interface{}{
+		"allow_any_name":    true,
+		"enforce_hostnames": false,
+		"issuer_ref":        issuer,
+		"key_type":          "rsa",
+		"key_usage":         "DigitalSignature,KeyEncipherment",
+		"server_flag":       false,
+		"client_flag":       false,
+	})
+	require.NoError(t, err)
+
+	resp, err := write("issue/scep-ra", map[string]interface{}{
+		"common_name": "SCEP RA",
+		"ttl":         "1h",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	_, err = write("keys/import", map[string]interface{}{
+		"key_name":   "scep-ra",
+		"pem_bundle": resp.Data["private_key"],
+	})
+	require.NoError(t, err)
+	return resp.Data["certificate"].(string)
+}
+
+// generateScepIdentity generates the key of a SCEP client, and the
+// self-signed certificate it signs its first requests with.
+func generateScepIdentity(t *testing.T, commonName string) (*rsa.PrivateKey, *x509.Certificate) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+	}, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return key, cert
+}
+
+// generateScepCsr builds a CSR with the given challenge password, which
+// crypto/x509 cannot encode, requesting the given DNS names.
+func generateScepCsr(t *testing.T, key *rsa.PrivateKey, commonName, challenge string, dnsNames ...string) []byte {
+	subject, err := asn1.Marshal(pkix.Name{CommonName: commonName}.ToRDNSequence())
+	require.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+
+	var attributes []asn1.RawValue
+	if challenge != "" {
+		value, err := asn1.MarshalWithParams(challenge, "printable")
+		require.NoError(t, err)
+		attribute, err := asn1.Marshal(struct {
+			Type   asn1.ObjectIdentifier
+			Values []asn1.RawValue `asn1:"set"`
+		}{oidChallengePassword, []asn1.RawValue{{FullBytes: value}}})
+		require.NoError(t, err)
+		attributes = append(attributes, asn1.RawValue{FullBytes: attribute})
+	}
+	if len(dnsNames) > 0 {
+		var generalNames []asn1.RawValue
+		for _, dnsName := range dnsNames {
+			generalNames = append(generalNames, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte(dnsName)})
+		}
+		sans, err := asn1.Marshal(generalNames)
+		require.NoError(t, err)
+		extensions, err := asn1.Marshal([]pkix.Extension{{Id: asn1.ObjectIdentifier{2, 5, 29, 17}, Value: sans}})
+		require.NoError(t, err)
+		attribute, err := asn1.Marshal(struct {
+			Type   asn1.ObjectIdentifier
+			Values []asn1.RawValue `asn1:"set"`
+		}{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}, []asn1.RawValue{{FullBytes: extensions}}})
+		require.NoError(t, err)
+		attributes = append(attributes, asn1.RawValue{FullBytes: attribute})
+	}
+
+	tbs, err := asn1.Marshal(struct {
+		Version    int
+		Subject    asn1.RawValue
+		PublicKey  asn1.RawValue
+		Attributes []asn1.RawValue `asn1:"tag:0,set"`
+	}{0, asn1.RawValue{FullBytes: subject}, asn1.RawValue{FullBytes: publicKey}, attributes})
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(tbs)
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	csr, err := asn1.Marshal(struct {
+		TBS       asn1.RawValue
+		Algorithm pkix.AlgorithmIdentifier
+		Signature asn1.BitString
+	}{
+		asn1.RawValue{FullBytes: tbs},
+		pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, Parameters: asn1.NullRawValue},
+		asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+	require.NoError(t, err)
+	return csr
+}
+
+// issuerAndSubject builds the content of a CertPoll message.
+func issuerAndSubject(t *testing.T, caCert, cert *x509.Certificate) []byte {
+	der, err := asn1.Marshal(struct {
+		Issuer  asn1.RawValue
+		Subject asn1.RawValue
+	}{asn1.RawValue{FullBytes: caCert.RawSubject}, asn1.RawValue{FullBytes: cert.RawSubject}})
+	require.NoError(t, err)
+	return der
+}
@@ -971,7 +971,7 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 
 		if certEntry == nil {
 			logger.Warn("certificate entry is nil; tidying up since it is no longer useful for any server operations", "serial", serial)
-			if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+			if err := tidyDeleteCert(ctx, req.Storage, serial); err != nil {
 				return false, fmt.Errorf("error deleting nil entry with serial %s: %w", serial, err)
 			}
 			b.tidyStatusIncCertStoreCount()
@@ -980,7 +980,7 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 
 		if certEntry.Value == nil || len(certEntry.Value) == 0 {
 			logger.Warn("certificate entry has no value; tidying up since it is no longer useful for any server operations", "serial", serial)
-			if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+			if err := tidyDeleteCert(ctx, req.Storage, serial); err != nil {
 				return false, fmt.Errorf("error deleting entry with nil value with serial %s: %w", serial, err)
 			}
 			b.tidyStatusIncCertStoreCount()
@@ -1006,7 +1006,7 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 			// config.InvalidCerts=true, we can skip deleting revoked certs
 			// here.
 			if config.InvalidCerts {
-				if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+				if err := tidyDeleteCert(ctx, req.Storage, serial); err != nil {
 					return false, fmt.Errorf("error deleting invalid certificate %s: %w", serial, err)
 				}
 				b.tidyStatusIncCertStoreCount()
@@ -1030,12 +1030,12 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 		}
 
 		if revokedResp == nil && time.Since(cert.NotAfter) > config.SafetyBuffer {
-			if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+			if err := tidyDeleteCert(ctx, req.Storage, serial); err != nil {
 				return false, fmt.Errorf("error deleting serial %q from storage: %w", serial, err)
 			}
 			b.tidyStatusIncCertStoreCount()
 		} else if revokedResp != nil && time.Since(cert.NotAfter) > revokedSafetyBuffer {
-			if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+			if err := tidyDeleteCert(ctx, req.Storage, serial); err != nil {
 				return false, fmt.Errorf("error deleting serial %q from store when tidying revoked: %w", serial, err)
 			}
 			// Only tidy revoked certs if requested.
@@ -1072,6 +1072,16 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 	return revokedDeleted, nil
 }
 
+// tidyDeleteCert removes the stored certificate with the given serial
+// number, along with its custom metadata if any.
+func tidyDeleteCert(ctx context.Context, s logical.Storage, serial string) error {
+	if err := s.Delete(ctx, "certs/"+serial); err != nil {
+		return err
+	}
+
+	return s.Delete(ctx, certMetadataPrefix+serial)
+}
+
 func (b *backend) doTidyRevocationStore(ctx context.Context, req *logical.Request, logger hclog.Logger, config *tidyConfig, revokedDeleted uint) (bool, error) {
 	b.revokeStorageLock.Lock()
 	defer b.revokeStorageLock.Unlock()
@@ -1205,7 +1215,7 @@ func (b *backend) doTidyRevocationStore(ctx context.Context, req *logical.Reques
 				if err := req.Storage.Delete(ctx, "revoked/"+serial); err != nil {
 					return false, fmt.Errorf("error deleting serial %q from revoked list: %w", serial, err)
 				}
-				if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
+				if err := tidyDeleteCert(ctx, req.Storage, serial); err != nil {
 					return false, fmt.Errorf("error deleting serial %q from store when tidying revoked: %w", serial, err)
 				}
 				rebuildCRL = true
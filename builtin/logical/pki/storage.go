@@ -6,6 +6,7 @@ package pki
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -38,6 +39,8 @@ const (
 	autoTidyConfigPath = "config/auto-tidy"
 	clusterConfigPath  = "config/cluster"
 
+	certMetadataPrefix = "cert-metadata/"
+
 	// Used as a quick sanity check for a reference id lookups...
 	uuidLength = 36
 
@@ -75,6 +78,16 @@ type keyEntry struct {
 	Name           string                  `json:"name"`
 	PrivateKeyType certutil.PrivateKeyType `json:"private_key_type"`
 	PrivateKey     string                  `json:"private_key"`
+
+	// ManagedKeyUUID references the managed key holding the private key, in
+	// which case PrivateKey is empty and PublicKey holds the PEM encoded
+	// public key.
+	ManagedKeyUUID string `json:"managed_key_id,omitempty"`
+	PublicKey      string `json:"public_key,omitempty"`
+}
+
+func (e keyEntry) isManagedPrivateKey() bool {
+	return e.ManagedKeyUUID != ""
 }
 
 type issuerUsage uint
@@ -360,17 +373,30 @@ func (sc *storageContext) importKey(keyValue string, keyName string, keyType cer
 	// Normalize whitespace before beginning.  See note in importIssuer as to
 	// why we do this.
 	keyValue = strings.TrimSpace(keyValue) + "\n"
-	//
-	// Before we can import a known key, we first need to know if the key
-	// exists in storage already. This means iterating through all known
-	// keys and comparing their private value against this value.
-	knownKeys, err := sc.listKeys()
+
+	// Get our public key from the current inbound key, to compare against all the other keys.
+	pkForImportingKey, err := getPublicKeyFromBytes([]byte(keyValue))
 	if err != nil {
 		return nil, false, err
 	}
 
-	// Get our public key from the current inbound key, to compare against all the other keys.
-	pkForImportingKey, err := getPublicKeyFromBytes([]byte(keyValue))
+	return sc.importKeyEntry(keyEntry{
+		Name:           keyName,
+		PrivateKey:     keyValue,
+		PrivateKeyType: keyType,
+	}, pkForImportingKey)
+}
+
+// importKeyEntry stores the key entry, whose ID is assigned here, unless a
+// key with the same public key already exists. Issuers missing their key
+// are linked to the new key. The return values are the same as importKey.
+func (sc *storageContext) importKeyEntry(result keyEntry, pkForImportingKey crypto.PublicKey) (*keyEntry, bool, error) {
+	keyName := result.Name
+
+	// Before we can import a known key, we first need to know if the key
+	// exists in storage already. This means iterating through all known
+	// keys and comparing their public value against this value.
+	knownKeys, err := sc.listKeys()
 	if err != nil {
 		return nil, false, err
 	}
@@ -405,11 +431,7 @@ func (sc *storageContext) importKey(keyValue string, keyName string, keyType cer
 	}
 
 	// Haven't found a key, so we've gotta create it and write it into storage.
-	var result keyEntry
 	result.ID = genKeyId()
-	result.Name = keyName
-	result.PrivateKey = keyValue
-	result.PrivateKeyType = keyType
 
 	// Finally, we can write the key to storage.
 	if err := sc.writeKey(result); err != nil {
@@ -1231,7 +1253,20 @@ func (sc *storageContext) fetchCertBundleByIssuerId(id issuerID, loadKey bool) (
 }
 
 func (sc *storageContext) writeCaBundle(caBundle *certutil.CertBundle, issuerName string, keyName string) (*issuerEntry, *keyEntry, error) {
-	myKey, _, err := sc.importKey(caBundle.PrivateKey, keyName, caBundle.PrivateKeyType)
+	var myKey *keyEntry
+	var err error
+	if caBundle.PrivateKey == "" && caBundle.Certificate != "" {
+		// Bundles generated with a managed key carry no private key; the
+		// key was registered before the certificate was generated.
+		var cert *x509.Certificate
+		cert, err = parseCertificateFromBytes([]byte(caBundle.Certificate))
+		if err != nil {
+			return nil, nil, err
+		}
+		myKey, err = sc.findManagedKeyEntry(cert.PublicKey)
+	} else {
+		myKey, _, err = sc.importKey(caBundle.PrivateKey, keyName, caBundle.PrivateKeyType)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1487,3 +1522,45 @@ func (sc *storageContext) fetchRevocationInfo(serial string) (*revocationInfo, e
 
 	return revInfo, nil
 }
+
+// certMetadataEntry holds the custom metadata attached to an issued
+// certificate, stored alongside it under the same serial number.
+type certMetadataEntry struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+func (sc *storageContext) storeCertMetadata(serial string, metadata map[string]string) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	entry, err := logical.StorageEntryJSON(certMetadataPrefix+normalizeSerial(serial), certMetadataEntry{
+		Metadata: metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := sc.Storage.Put(sc.Context, entry); err != nil {
+		return fmt.Errorf("unable to store certificate metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (sc *storageContext) fetchCertMetadata(serial string) (map[string]string, error) {
+	entry, err := sc.Storage.Get(sc.Context, certMetadataPrefix+normalizeSerial(serial))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching certificate metadata for %s: %w", serial, err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result certMetadataEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, fmt.Errorf("error decoding certificate metadata for %s: %w", serial, err)
+	}
+
+	return result.Metadata, nil
+}
@@ -41,6 +41,10 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 	var b backend
 	b.Backend = &framework.Backend{
 		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"jwks/*",
+			},
+
 			SealWrapStorage: []string{
 				"archive/",
 				"policy/",
@@ -69,8 +73,12 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			b.pathRandom(),
 			b.pathHash(),
 			b.pathHMAC(),
+			b.pathCMAC(),
+			b.pathCMACVerify(),
 			b.pathSign(),
 			b.pathVerify(),
+			b.pathJWTSign(),
+			b.pathJWKS(),
 			b.pathBackup(),
 			b.pathRestore(),
 			b.pathTrim(),
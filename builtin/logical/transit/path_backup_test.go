@@ -22,6 +22,10 @@ func TestTransit_BackupRestore(t *testing.T) {
 	testBackupRestore(t, "rsa-2048", "encrypt-decrypt")
 	testBackupRestore(t, "rsa-3072", "encrypt-decrypt")
 	testBackupRestore(t, "rsa-4096", "encrypt-decrypt")
+	testBackupRestore(t, "ml-kem-768", "encrypt-decrypt")
+	testBackupRestore(t, "ml-kem-1024", "encrypt-decrypt")
+	testBackupRestore(t, "aes256-siv", "encrypt-decrypt")
+	testBackupRestore(t, "aes256-gcm-siv", "encrypt-decrypt")
 
 	// Test signing/verification after a restore for supported keys
 	testBackupRestore(t, "ecdsa-p256", "sign-verify")
@@ -31,6 +35,9 @@ func TestTransit_BackupRestore(t *testing.T) {
 	testBackupRestore(t, "rsa-2048", "sign-verify")
 	testBackupRestore(t, "rsa-3072", "sign-verify")
 	testBackupRestore(t, "rsa-4096", "sign-verify")
+	testBackupRestore(t, "ml-dsa-44", "sign-verify")
+	testBackupRestore(t, "ml-dsa-65", "sign-verify")
+	testBackupRestore(t, "ml-dsa-87", "sign-verify")
 
 	// Test HMAC/verification after a restore for all key types
 	testBackupRestore(t, "aes128-gcm96", "hmac-verify")
@@ -44,6 +51,10 @@ func TestTransit_BackupRestore(t *testing.T) {
 	testBackupRestore(t, "rsa-2048", "hmac-verify")
 	testBackupRestore(t, "rsa-3072", "hmac-verify")
 	testBackupRestore(t, "rsa-4096", "hmac-verify")
+	testBackupRestore(t, "ml-dsa-65", "hmac-verify")
+	testBackupRestore(t, "ml-kem-768", "hmac-verify")
+	testBackupRestore(t, "aes256-siv", "hmac-verify")
+	testBackupRestore(t, "aes256-gcm-siv", "hmac-verify")
 	testBackupRestore(t, "hmac", "hmac-verify")
 }
 
@@ -174,7 +174,9 @@ func getBYOKExportKey(dstP *keysutil.Policy, srcP *keysutil.Policy, key *keysuti
 
 	var targetKey interface{}
 	switch srcP.Type {
-	case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_XChaCha20_Poly1305, keysutil.KeyType_HMAC:
+	case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_XChaCha20_Poly1305, keysutil.KeyType_HMAC,
+		keysutil.KeyType_AES128_CMAC, keysutil.KeyType_AES256_CMAC, keysutil.KeyType_KMAC128, keysutil.KeyType_KMAC256,
+		keysutil.KeyType_AES256_SIV, keysutil.KeyType_AES256_GCM_SIV:
 		targetKey = key.Key
 	case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
 		targetKey = key.RSAKey
@@ -199,6 +201,8 @@ func getBYOKExportKey(dstP *keysutil.Policy, srcP *keysutil.Policy, key *keysuti
 		}
 	case keysutil.KeyType_ED25519:
 		targetKey = ed25519.PrivateKey(key.Key)
+	case keysutil.KeyType_ML_DSA_44, keysutil.KeyType_ML_DSA_65, keysutil.KeyType_ML_DSA_87, keysutil.KeyType_ML_KEM_768, keysutil.KeyType_ML_KEM_1024:
+		targetKey = &keysutil.PQCPrivateKey{Type: srcP.Type, Seed: key.Key}
 	default:
 		return "", fmt.Errorf("unable to export to unknown key type: %v", srcP.Type)
 	}
@@ -0,0 +1,385 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/helper/keysutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// batchRequestCMACItem represents a request item for batch processing.
+// A map type allows us to distinguish between empty and missing values.
+type batchRequestCMACItem map[string]string
+
+// batchResponseCMACItem represents a response item for batch processing
+type batchResponseCMACItem struct {
+	// CMAC for the input present in the corresponding batch request item
+	CMAC string `json:"cmac,omitempty" mapstructure:"cmac"`
+
+	// Valid indicates whether the CMAC matches the one derived from the input
+	Valid bool `json:"valid,omitempty" mapstructure:"valid"`
+
+	// Error, if set represents a failure encountered while processing a
+	// corresponding batch request item
+	Error string `json:"error,omitempty" mapstructure:"error"`
+
+	// For batch processing to successfully mimic previous handling for simple 'input',
+	// both output values are needed - though 'err' should never be serialized.
+	err error
+
+	// Reference is an arbitrary caller supplied string value that will be placed on the
+	// batch response to ease correlation between inputs and outputs
+	Reference string `json:"reference" mapstructure:"reference"`
+}
+
+func cmacFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"name": {
+			Type:        framework.TypeString,
+			Description: "The key to use for the CMAC function",
+		},
+
+		"input": {
+			Type:        framework.TypeString,
+			Description: "The base64-encoded input data",
+		},
+
+		"customization": {
+			Type: framework.TypeString,
+			Description: `The customization string to use with KMAC keys, distinguishing
+MACs computed for different purposes with the same key. Not supported
+by AES-CMAC keys.`,
+		},
+
+		"batch_input": {
+			Type: framework.TypeSlice,
+			Description: `
+Specifies a list of items to be processed in a single batch. When this parameter
+is set, if the parameter 'input' is also set, it will be ignored.
+Any batch output will preserve the order of the batch input.`,
+		},
+	}
+}
+
+func (b *backend) pathCMAC() *framework.Path {
+	fields := cmacFields()
+	fields["key_version"] = &framework.FieldSchema{
+		Type: framework.TypeInt,
+		Description: `The version of the key to use for generating the CMAC.
+Must be 0 (for latest) or a value greater than or equal
+to the min_encryption_version configured on the key.`,
+	}
+
+	return &framework.Path{
+		Pattern: "cmac/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTransit,
+			OperationVerb:   "generate",
+			OperationSuffix: "cmac",
+		},
+
+		Fields: fields,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathCMACWrite,
+			},
+		},
+
+		HelpSynopsis:    pathCMACHelpSyn,
+		HelpDescription: pathCMACHelpDesc,
+	}
+}
+
+func (b *backend) pathCMACVerify() *framework.Path {
+	fields := cmacFields()
+	fields["cmac"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "The CMAC, including vault header/key version",
+	}
+
+	return &framework.Path{
+		Pattern: "cmac/verify/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTransit,
+			OperationVerb:   "verify",
+			OperationSuffix: "cmac",
+		},
+
+		Fields: fields,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathCMACVerifyWrite,
+			},
+		},
+
+		HelpSynopsis:    pathCMACVerifyHelpSyn,
+		HelpDescription: pathCMACVerifyHelpDesc,
+	}
+}
+
+// getCMACPolicy returns the named key, locked for reading, if it supports
+// CMAC.
+func (b *backend) getCMACPolicy(ctx context.Context, req *logical.Request, name string) (*keysutil.Policy, *logical.Response, error) {
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return nil, logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+
+	if !p.Type.CMACSupported() {
+		p.Unlock()
+		return nil, logical.ErrorResponse("CMAC not supported for key type %v", p.Type), logical.ErrInvalidRequest
+	}
+
+	return p, nil, nil
+}
+
+// parseCMACBatchInput returns the batch input of the request, or the single
+// input given by the named fields.
+func parseCMACBatchInput(d *framework.FieldData, fields ...string) ([]batchRequestCMACItem, *logical.Response, error) {
+	batchInputRaw := d.Raw["batch_input"]
+	if batchInputRaw != nil {
+		var batchInputItems []batchRequestCMACItem
+		if err := mapstructure.Decode(batchInputRaw, &batchInputItems); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse batch input: %w", err)
+		}
+
+		if len(batchInputItems) == 0 {
+			return nil, logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+		}
+		return batchInputItems, nil, nil
+	}
+
+	item := batchRequestCMACItem{}
+	for _, field := range fields {
+		if value, ok := d.GetOk(field); ok {
+			item[field] = value.(string)
+		}
+	}
+	return []batchRequestCMACItem{item}, nil, nil
+}
+
+// cmacResponse builds the response from the batch results, or from the single
+// result if the request had no batch input.
+func cmacResponse(d *framework.FieldData, items []batchRequestCMACItem, response []batchResponseCMACItem, single func(batchResponseCMACItem) map[string]interface{}) (*logical.Response, error) {
+	resp := &logical.Response{}
+	if d.Raw["batch_input"] != nil {
+		// Copy the references
+		for i := range items {
+			response[i].Reference = items[i]["reference"]
+		}
+		resp.Data = map[string]interface{}{
+			"batch_results": response,
+		}
+		return resp, nil
+	}
+
+	if response[0].Error != "" || response[0].err != nil {
+		if response[0].Error != "" {
+			return logical.ErrorResponse(response[0].Error), response[0].err
+		}
+		return nil, response[0].err
+	}
+	resp.Data = single(response[0])
+	return resp, nil
+}
+
+// cmacItemError sets the error of a batch response item from err, which is
+// a user error unless it is an internal one.
+func cmacItemError(item *batchResponseCMACItem, err error) {
+	switch err.(type) {
+	case errutil.InternalError:
+		item.err = err
+	default:
+		item.Error = err.Error()
+		item.err = logical.ErrInvalidRequest
+	}
+}
+
+func (b *backend) pathCMACWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	ver := d.Get("key_version").(int)
+
+	p, resp, err := b.getCMACPolicy(ctx, req, name)
+	if p == nil {
+		return resp, err
+	}
+	defer p.Unlock()
+
+	switch {
+	case ver == 0:
+		// Allowed, will use latest; set explicitly here to ensure the string
+		// is generated properly
+		ver = p.LatestVersion
+	case ver == p.LatestVersion:
+		// Allowed
+	case p.MinEncryptionVersion > 0 && ver < p.MinEncryptionVersion:
+		return logical.ErrorResponse("cannot generate CMAC: version is too old (disallowed by policy)"), logical.ErrInvalidRequest
+	}
+
+	batchInputItems, resp, err := parseCMACBatchInput(d, "input", "customization")
+	if batchInputItems == nil {
+		return resp, err
+	}
+
+	response := make([]batchResponseCMACItem, len(batchInputItems))
+
+	for i, item := range batchInputItems {
+		rawInput, ok := item["input"]
+		if !ok {
+			response[i].Error = "missing input for CMAC"
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		input, err := base64.StdEncoding.DecodeString(rawInput)
+		if err != nil {
+			response[i].Error = fmt.Sprintf("unable to decode input as base64: %s", err)
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		mac, err := p.CMAC(ver, input, []byte(item["customization"]))
+		if err != nil {
+			cmacItemError(&response[i], err)
+			continue
+		}
+
+		response[i].CMAC = fmt.Sprintf("vault:v%s:%s", strconv.Itoa(ver), base64.StdEncoding.EncodeToString(mac))
+	}
+
+	return cmacResponse(d, batchInputItems, response, func(item batchResponseCMACItem) map[string]interface{} {
+		return map[string]interface{}{
+			"cmac": item.CMAC,
+		}
+	})
+}
+
+func (b *backend) pathCMACVerifyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	p, resp, err := b.getCMACPolicy(ctx, req, name)
+	if p == nil {
+		return resp, err
+	}
+	defer p.Unlock()
+
+	batchInputItems, resp, err := parseCMACBatchInput(d, "input", "customization", "cmac")
+	if batchInputItems == nil {
+		return resp, err
+	}
+
+	response := make([]batchResponseCMACItem, len(batchInputItems))
+
+	for i, item := range batchInputItems {
+		rawInput, ok := item["input"]
+		if !ok {
+			response[i].Error = "missing input"
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		input, err := base64.StdEncoding.DecodeString(rawInput)
+		if err != nil {
+			response[i].Error = fmt.Sprintf("unable to decode input as base64: %s", err)
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		verificationCMAC, ok := item["cmac"]
+		if !ok {
+			response[i].Error = "missing cmac"
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		// Verify the prefix
+		if !strings.HasPrefix(verificationCMAC, "vault:v") {
+			response[i].Error = "invalid CMAC to verify: no prefix"
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		splitVerificationCMAC := strings.SplitN(strings.TrimPrefix(verificationCMAC, "vault:v"), ":", 2)
+		if len(splitVerificationCMAC) != 2 {
+			response[i].Error = "invalid CMAC: wrong number of fields"
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		ver, err := strconv.Atoi(splitVerificationCMAC[0])
+		if err != nil {
+			response[i].Error = "invalid CMAC: version number could not be decoded"
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		verBytes, err := base64.StdEncoding.DecodeString(splitVerificationCMAC[1])
+		if err != nil {
+			response[i].Error = fmt.Sprintf("unable to decode verification CMAC as base64: %s", err)
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		if ver > p.LatestVersion {
+			response[i].Error = "invalid CMAC: version is too new"
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		if p.MinDecryptionVersion > 0 && ver < p.MinDecryptionVersion {
+			response[i].Error = "cannot verify CMAC: version is too old (disallowed by policy)"
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
+
+		valid, err := p.VerifyCMAC(ver, input, []byte(item["customization"]), verBytes)
+		if err != nil {
+			cmacItemError(&response[i], err)
+			continue
+		}
+		response[i].Valid = valid
+	}
+
+	return cmacResponse(d, batchInputItems, response, func(item batchResponseCMACItem) map[string]interface{} {
+		return map[string]interface{}{
+			"valid": item.Valid,
+		}
+	})
+}
+
+const pathCMACHelpSyn = `Generate a CMAC for input data using the named key`
+
+const pathCMACHelpDesc = `
+Generates an AES-CMAC (NIST SP 800-38B) or KMAC (NIST SP 800-185) of the given
+input data, depending on the type of the named key.
+`
+
+const pathCMACVerifyHelpSyn = `Verify a CMAC of input data using the named key`
+
+const pathCMACVerifyHelpDesc = `
+Verifies an AES-CMAC (NIST SP 800-38B) or KMAC (NIST SP 800-185) of the given
+input data, as generated by the cmac endpoint, using the named key.
+`
@@ -0,0 +1,198 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package transit
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/openbao/openbao/sdk/v2/helper/keysutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestTransit_CMAC(t *testing.T) {
+	b, storage := createBackendWithSysView(t)
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+		Data: map[string]interface{}{
+			"type": "aes128-cmac",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	// Now, change the key value to the one of the RFC 4493 test vectors
+	p, _, err := b.GetPolicy(context.Background(), keysutil.PolicyRequest{
+		Storage: storage,
+		Name:    "foo",
+	}, b.GetRandomReader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	latestVersion := strconv.Itoa(p.LatestVersion)
+	keyEntry := p.Keys[latestVersion]
+	keyEntry.Key = []byte{0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6, 0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c}
+	p.Keys[latestVersion] = keyEntry
+	if err = p.Persist(context.Background(), storage); err != nil {
+		t.Fatal(err)
+	}
+
+	doRequest := func(path string, data map[string]interface{}, errExpected bool) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if errExpected {
+			if err == nil && (resp == nil || !resp.IsError()) {
+				t.Fatalf("expected error for %s, got resp:%#v", path, resp)
+			}
+			return resp
+		}
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return resp
+	}
+
+	resp = doRequest("cmac/foo", map[string]interface{}{
+		"input": "a8G+4i5An5bpPX4Rc5MXKg==",
+	}, false)
+	if resp.Data["cmac"] != "vault:v1:BwoWtGtNQUT3m92d0EoofA==" {
+		t.Fatalf("bad CMAC: %#v", resp.Data)
+	}
+
+	resp = doRequest("cmac/verify/foo", map[string]interface{}{
+		"input": "a8G+4i5An5bpPX4Rc5MXKg==",
+		"cmac":  "vault:v1:BwoWtGtNQUT3m92d0EoofA==",
+	}, false)
+	if resp.Data["valid"] != true {
+		t.Fatalf("expected CMAC to be valid: %#v", resp.Data)
+	}
+
+	resp = doRequest("cmac/verify/foo", map[string]interface{}{
+		"input": "a8G+4i5An5bpPX4Rc5MXKg==",
+		"cmac":  "vault:v1:ux1pKelZNyh/o30Sm3VnRg==",
+	}, false)
+	if resp.Data["valid"] != false {
+		t.Fatalf("expected CMAC to be invalid: %#v", resp.Data)
+	}
+
+	// Customization strings are only supported by KMAC keys
+	doRequest("cmac/foo", map[string]interface{}{
+		"input":         "a8G+4i5An5bpPX4Rc5MXKg==",
+		"customization": "app",
+	}, true)
+
+	// Rotate and check that the older version still verifies until it is
+	// disallowed by policy
+	doRequest("keys/foo/rotate", nil, false)
+	resp = doRequest("cmac/foo", map[string]interface{}{
+		"input": "a8G+4i5An5bpPX4Rc5MXKg==",
+	}, false)
+	if !strings.HasPrefix(resp.Data["cmac"].(string), "vault:v2:") {
+		t.Fatalf("expected CMAC with latest key version: %#v", resp.Data)
+	}
+
+	resp = doRequest("cmac/foo", map[string]interface{}{
+		"input":       "a8G+4i5An5bpPX4Rc5MXKg==",
+		"key_version": 1,
+	}, false)
+	if resp.Data["cmac"] != "vault:v1:BwoWtGtNQUT3m92d0EoofA==" {
+		t.Fatalf("bad CMAC: %#v", resp.Data)
+	}
+
+	doRequest("keys/foo/config", map[string]interface{}{
+		"min_decryption_version": 2,
+	}, false)
+	doRequest("cmac/verify/foo", map[string]interface{}{
+		"input": "a8G+4i5An5bpPX4Rc5MXKg==",
+		"cmac":  "vault:v1:BwoWtGtNQUT3m92d0EoofA==",
+	}, true)
+
+	// Keys of other types are rejected
+	doRequest("keys/enc", nil, false)
+	doRequest("cmac/enc", map[string]interface{}{
+		"input": "a8G+4i5An5bpPX4Rc5MXKg==",
+	}, true)
+}
+
+func TestTransit_batchCMAC(t *testing.T) {
+	b, storage := createBackendWithSysView(t)
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+		Data: map[string]interface{}{
+			"type": "kmac256",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	req.Path = "cmac/foo"
+	req.Data = map[string]interface{}{
+		"batch_input": []batchRequestCMACItem{
+			{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "reference": "one"},
+			{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "customization": "app", "reference": "two"},
+			{"input": ":;.?", "reference": "three"},
+			{},
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	results := resp.Data["batch_results"].([]batchResponseCMACItem)
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %#v", results)
+	}
+	if !strings.HasPrefix(results[0].CMAC, "vault:v1:") || results[0].Reference != "one" {
+		t.Fatalf("bad result: %#v", results[0])
+	}
+	if results[1].CMAC == "" || results[1].CMAC == results[0].CMAC {
+		t.Fatalf("expected customization to change the KMAC: %#v", results)
+	}
+	if results[2].Error != "unable to decode input as base64: illegal base64 data at input byte 0" || results[2].Reference != "three" {
+		t.Fatalf("bad result: %#v", results[2])
+	}
+	if results[3].Error != "missing input for CMAC" {
+		t.Fatalf("bad result: %#v", results[3])
+	}
+
+	req.Path = "cmac/verify/foo"
+	req.Data = map[string]interface{}{
+		"batch_input": []batchRequestCMACItem{
+			{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "cmac": results[0].CMAC},
+			{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "cmac": results[1].CMAC, "customization": "app"},
+			{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "cmac": results[1].CMAC},
+			{"input": "dGhlIHF1aWNrIGJyb3duIGZveA==", "cmac": "vault:v9:" + strings.TrimPrefix(results[1].CMAC, "vault:v1:")},
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	results = resp.Data["batch_results"].([]batchResponseCMACItem)
+	if !results[0].Valid || !results[1].Valid || results[2].Valid {
+		t.Fatalf("bad verification results: %#v", results)
+	}
+	if results[3].Error != "invalid CMAC: version is too new" {
+		t.Fatalf("bad result: %#v", results[3])
+	}
+}
@@ -198,7 +198,7 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 	switch exportType {
 	case exportTypeHMACKey:
 		src := key.HMACKey
-		if policy.Type == keysutil.KeyType_HMAC {
+		if policy.Type == keysutil.KeyType_HMAC || policy.Type.CMACSupported() {
 			src = key.Key
 		}
 		if format == "der" || format == "pem" {
@@ -208,7 +208,8 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 		return strings.TrimSpace(base64.StdEncoding.EncodeToString(src)), nil
 	case exportTypeEncryptionKey:
 		switch policy.Type {
-		case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_XChaCha20_Poly1305:
+		case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_XChaCha20_Poly1305,
+			keysutil.KeyType_AES256_SIV, keysutil.KeyType_AES256_GCM_SIV:
 			if format == "der" || format == "pem" {
 				return "", errors.New("unknown format for HMAC key; supported values are `` or `raw`")
 			}
@@ -220,6 +221,8 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 				return "", err
 			}
 			return rsaKey, nil
+		case keysutil.KeyType_ML_KEM_768, keysutil.KeyType_ML_KEM_1024:
+			return encodePQCPrivateKey(policy.Type, key, format)
 		}
 	case exportTypeSigningKey:
 		switch policy.Type {
@@ -251,6 +254,8 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 				return "", err
 			}
 			return rsaKey, nil
+		case keysutil.KeyType_ML_DSA_44, keysutil.KeyType_ML_DSA_65, keysutil.KeyType_ML_DSA_87:
+			return encodePQCPrivateKey(policy.Type, key, format)
 		}
 	case exportTypePublicKey:
 		switch policy.Type {
@@ -278,6 +283,8 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 				return "", err
 			}
 			return rsaKey, nil
+		case keysutil.KeyType_ML_DSA_44, keysutil.KeyType_ML_DSA_65, keysutil.KeyType_ML_DSA_87, keysutil.KeyType_ML_KEM_768, keysutil.KeyType_ML_KEM_1024:
+			return encodePQCPublicKey(policy.Type, key, format)
 		}
 	case exportTypeCertificateChain:
 		if key.CertificateChain == nil {
@@ -520,6 +527,54 @@ func encodeED25519PublicKey(k *keysutil.KeyEntry, format string) (string, error)
 	return strings.TrimSpace(string(pem.EncodeToMemory(&pemBlock))), nil
 }
 
+func encodePQCPrivateKey(keyType keysutil.KeyType, k *keysutil.KeyEntry, format string) (string, error) {
+	if k.IsPrivateKeyMissing() {
+		return "", nil
+	}
+
+	if format == "" || format == "raw" {
+		return base64.StdEncoding.EncodeToString(k.Key), nil
+	}
+
+	derBytes, err := keysutil.MarshalPKCS8PQCPrivateKey(&keysutil.PQCPrivateKey{Type: keyType, Seed: k.Key})
+	if err != nil {
+		return "", err
+	}
+
+	if format == "der" {
+		return base64.StdEncoding.EncodeToString(derBytes), nil
+	}
+
+	pemBlock := pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: derBytes,
+	}
+
+	return strings.TrimSpace(string(pem.EncodeToMemory(&pemBlock))), nil
+}
+
+func encodePQCPublicKey(keyType keysutil.KeyType, k *keysutil.KeyEntry, format string) (string, error) {
+	if format == "" || format == "pem" {
+		return strings.TrimSpace(k.FormattedPublicKey), nil
+	}
+
+	pubKey, err := k.PQCPublicKey(keyType)
+	if err != nil {
+		return "", err
+	}
+
+	if format == "raw" {
+		return base64.StdEncoding.EncodeToString(pubKey.Key), nil
+	}
+
+	derBytes, err := keysutil.MarshalPKIXPQCPublicKey(pubKey)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(derBytes), nil
+}
+
 const pathExportHelpSyn = `Export named encryption or signing key`
 
 const pathExportHelpDesc = `
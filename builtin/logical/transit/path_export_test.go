@@ -29,6 +29,8 @@ func TestTransit_Export_KeyVersion_ExportsCorrectVersion(t *testing.T) {
 	verifyExportsCorrectVersion(t, "encryption-key", "aes256-gcm96")
 	verifyExportsCorrectVersion(t, "encryption-key", "chacha20-poly1305")
 	verifyExportsCorrectVersion(t, "encryption-key", "xchacha20-poly1305")
+	verifyExportsCorrectVersion(t, "encryption-key", "aes256-siv")
+	verifyExportsCorrectVersion(t, "encryption-key", "aes256-gcm-siv")
 	verifyExportsCorrectVersion(t, "encryption-key", "rsa-2048")
 	verifyExportsCorrectVersion(t, "encryption-key", "rsa-3072")
 	verifyExportsCorrectVersion(t, "encryption-key", "rsa-4096")
@@ -403,6 +405,8 @@ func TestTransit_Export_CorrectFormat(t *testing.T) {
 	verifyExportsCorrectFormat(t, "encryption-key", "aes256-gcm96")
 	verifyExportsCorrectFormat(t, "encryption-key", "chacha20-poly1305")
 	verifyExportsCorrectFormat(t, "encryption-key", "xchacha20-poly1305")
+	verifyExportsCorrectFormat(t, "encryption-key", "aes256-siv")
+	verifyExportsCorrectFormat(t, "encryption-key", "aes256-gcm-siv")
 	verifyExportsCorrectFormat(t, "encryption-key", "rsa-2048")
 	verifyExportsCorrectFormat(t, "encryption-key", "rsa-3072")
 	verifyExportsCorrectFormat(t, "encryption-key", "rsa-4096")
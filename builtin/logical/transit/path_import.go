@@ -45,7 +45,10 @@ func (b *backend) pathImport() *framework.Path {
 				Default: "aes256-gcm96",
 				Description: `The type of key being imported. Currently, "aes128-gcm96" (symmetric), "aes256-gcm96" (symmetric), "ecdsa-p256"
 (asymmetric), "ecdsa-p384" (asymmetric), "ecdsa-p521" (asymmetric), "ed25519" (asymmetric), "rsa-2048" (asymmetric), "rsa-3072"
-(asymmetric), "rsa-4096" (asymmetric) are supported.  Defaults to "aes256-gcm96".
+(asymmetric), "rsa-4096" (asymmetric), "aes128-cmac" (CMAC), "aes256-cmac" (CMAC), "kmac128" (CMAC),
+"kmac256" (CMAC), "ml-dsa-44" (asymmetric), "ml-dsa-65" (asymmetric), "ml-dsa-87" (asymmetric),
+"ml-kem-768" (asymmetric), "ml-kem-1024" (asymmetric), "aes256-siv" (deterministic), "aes256-gcm-siv"
+(deterministic) are supported.  Defaults to "aes256-gcm96".
 `,
 			},
 			"hash_function": {
@@ -220,6 +223,28 @@ func (b *backend) pathImportWrite(ctx context.Context, req *logical.Request, d *
 		polReq.KeyType = keysutil.KeyType_RSA4096
 	case "hmac":
 		polReq.KeyType = keysutil.KeyType_HMAC
+	case "aes128-cmac":
+		polReq.KeyType = keysutil.KeyType_AES128_CMAC
+	case "aes256-cmac":
+		polReq.KeyType = keysutil.KeyType_AES256_CMAC
+	case "kmac128":
+		polReq.KeyType = keysutil.KeyType_KMAC128
+	case "kmac256":
+		polReq.KeyType = keysutil.KeyType_KMAC256
+	case "ml-dsa-44":
+		polReq.KeyType = keysutil.KeyType_ML_DSA_44
+	case "ml-dsa-65":
+		polReq.KeyType = keysutil.KeyType_ML_DSA_65
+	case "ml-dsa-87":
+		polReq.KeyType = keysutil.KeyType_ML_DSA_87
+	case "ml-kem-768":
+		polReq.KeyType = keysutil.KeyType_ML_KEM_768
+	case "ml-kem-1024":
+		polReq.KeyType = keysutil.KeyType_ML_KEM_1024
+	case "aes256-siv":
+		polReq.KeyType = keysutil.KeyType_AES256_SIV
+	case "aes256-gcm-siv":
+		polReq.KeyType = keysutil.KeyType_AES256_GCM_SIV
 	default:
 		return logical.ErrorResponse(fmt.Sprintf("unknown key type: %v", keyType)), logical.ErrInvalidRequest
 	}
@@ -16,10 +16,12 @@ import (
 	"encoding/pem"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/openbao/openbao/sdk/v2/helper/keysutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
 	"github.com/tink-crypto/tink-go/kwp/subtle"
 )
@@ -37,6 +39,8 @@ var keyTypes = []string{
 	"rsa-3072",
 	"rsa-4096",
 	"hmac",
+	"aes256-siv",
+	"aes256-gcm-siv",
 }
 
 var hashFns = []string{
@@ -949,6 +953,122 @@ func TestTransit_ImportVersionWithPublicKeys(t *testing.T) {
 	)
 }
 
+func TestTransit_ImportPQC(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	wrappingKey, err := b.getWrappingKey(context.Background(), s)
+	if err != nil || wrappingKey == nil {
+		t.Fatalf("failed to retrieve public wrapping key: %s", err)
+	}
+	privWrappingKey := wrappingKey.Keys[strconv.Itoa(wrappingKey.LatestVersion)].RSAKey
+	pubWrappingKey := &privWrappingKey.PublicKey
+
+	plaintextB64 := "dGhlIHF1aWNrIGJyb3duIGZveA==" // "the quick brown fox"
+
+	doRequest := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   s,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("request to %s failed, resp: %#v, err: %v", path, resp, err)
+		}
+		return resp
+	}
+
+	for _, keyType := range []keysutil.KeyType{
+		keysutil.KeyType_ML_DSA_44, keysutil.KeyType_ML_DSA_65, keysutil.KeyType_ML_DSA_87,
+		keysutil.KeyType_ML_KEM_768, keysutil.KeyType_ML_KEM_1024,
+	} {
+		t.Run(keyType.String(), func(t *testing.T) {
+			seed := make([]byte, 32)
+			if keyType == keysutil.KeyType_ML_KEM_768 || keyType == keysutil.KeyType_ML_KEM_1024 {
+				seed = make([]byte, 64)
+			}
+			if _, err := rand.Read(seed); err != nil {
+				t.Fatal(err)
+			}
+			privateKey := &keysutil.PQCPrivateKey{Type: keyType, Seed: seed}
+			publicKey, err := privateKey.Public()
+			if err != nil {
+				t.Fatal(err)
+			}
+			derPublicKey, err := keysutil.MarshalPKIXPQCPublicKey(publicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pemPublicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: derPublicKey}))
+
+			derPrivateKey, err := keysutil.MarshalPKCS8PQCPrivateKey(privateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Import the private key and the public key alone
+			doRequest("keys/private-"+keyType.String()+"/import", map[string]interface{}{
+				"ciphertext": wrapTargetPKCS8ForImport(t, pubWrappingKey, derPrivateKey, "SHA256"),
+				"type":       keyType.String(),
+				"exportable": true,
+			})
+			doRequest("keys/public-"+keyType.String()+"/import", map[string]interface{}{
+				"public_key": pemPublicKey,
+				"type":       keyType.String(),
+			})
+
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Storage:   s,
+				Operation: logical.ReadOperation,
+				Path:      "export/public-key/private-" + keyType.String(),
+			})
+			if err != nil || (resp != nil && resp.IsError()) {
+				t.Fatalf("failed to export public key, resp: %#v, err: %v", resp, err)
+			}
+			if exported := resp.Data["keys"].(map[string]string)["1"]; exported != strings.TrimSpace(pemPublicKey) {
+				t.Fatalf("expected exported public key %q, got %q", pemPublicKey, exported)
+			}
+
+			if keyType == keysutil.KeyType_ML_KEM_768 || keyType == keysutil.KeyType_ML_KEM_1024 {
+				// Encrypt with the public key and decrypt with the private key
+				resp = doRequest("encrypt/public-"+keyType.String(), map[string]interface{}{
+					"plaintext": plaintextB64,
+				})
+				resp = doRequest("decrypt/private-"+keyType.String(), map[string]interface{}{
+					"ciphertext": resp.Data["ciphertext"],
+				})
+				if resp.Data["plaintext"] != plaintextB64 {
+					t.Fatalf("bad decrypted plaintext: %#v", resp.Data)
+				}
+
+				// Data keys are wrapped with the key
+				resp = doRequest("datakey/plaintext/public-"+keyType.String(), nil)
+				plaintext := resp.Data["plaintext"]
+				resp = doRequest("decrypt/private-"+keyType.String(), map[string]interface{}{
+					"ciphertext": resp.Data["ciphertext"],
+				})
+				if resp.Data["plaintext"] != plaintext {
+					t.Fatalf("bad decrypted data key: %#v", resp.Data)
+				}
+				return
+			}
+
+			// Sign with the private key and verify with the public key
+			resp = doRequest("sign/private-"+keyType.String(), map[string]interface{}{
+				"input": plaintextB64,
+			})
+			resp = doRequest("verify/public-"+keyType.String(), map[string]interface{}{
+				"input":     plaintextB64,
+				"signature": resp.Data["signature"],
+			})
+			if resp.Data["valid"] != true {
+				t.Fatalf("expected signature to be valid: %#v", resp.Data)
+			}
+		})
+	}
+}
+
 func wrapTargetKeyForImport(t *testing.T, wrappingKey *rsa.PublicKey, targetKey interface{}, targetKeyType string, hashFnName string) string {
 	t.Helper()
 
@@ -957,7 +1077,7 @@ func wrapTargetKeyForImport(t *testing.T, wrappingKey *rsa.PublicKey, targetKey
 	var ok bool
 	var err error
 	switch targetKeyType {
-	case "aes128-gcm96", "aes256-gcm96", "chacha20-poly1305", "xchacha20-poly1305", "hmac":
+	case "aes128-gcm96", "aes256-gcm96", "chacha20-poly1305", "xchacha20-poly1305", "hmac", "aes256-siv", "aes256-gcm-siv":
 		preppedTargetKey, ok = targetKey.([]byte)
 		if !ok {
 			t.Fatal("failed to wrap target key for import: symmetric key not provided in byte format")
@@ -1016,8 +1136,10 @@ func generateKey(keyType string) (interface{}, error) {
 		return uuid.GenerateRandomBytes(16)
 	case "aes256-gcm96", "hmac":
 		return uuid.GenerateRandomBytes(32)
-	case "chacha20-poly1305", "xchacha20-poly1305":
+	case "chacha20-poly1305", "xchacha20-poly1305", "aes256-gcm-siv":
 		return uuid.GenerateRandomBytes(32)
+	case "aes256-siv":
+		return uuid.GenerateRandomBytes(64)
 	case "ed25519":
 		_, priv, err := ed25519.GenerateKey(rand.Reader)
 		return priv, err
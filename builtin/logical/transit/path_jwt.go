@@ -0,0 +1,368 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package transit
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"github.com/openbao/openbao/sdk/v2/helper/keysutil"
+	"github.com/openbao/openbao/sdk/v2/helper/strutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// jwtAlgorithm describes how a JWS algorithm (RFC 7518) signs with transit
+// keys.
+type jwtAlgorithm struct {
+	hashAlgorithm keysutil.HashType
+	sigAlgorithm  string
+}
+
+var jwtAlgorithms = map[string]jwtAlgorithm{
+	"ES256": {hashAlgorithm: keysutil.HashTypeSHA2256},
+	"ES384": {hashAlgorithm: keysutil.HashTypeSHA2384},
+	"ES512": {hashAlgorithm: keysutil.HashTypeSHA2512},
+	"EdDSA": {hashAlgorithm: keysutil.HashTypeNone},
+	"RS256": {hashAlgorithm: keysutil.HashTypeSHA2256, sigAlgorithm: "pkcs1v15"},
+	"RS384": {hashAlgorithm: keysutil.HashTypeSHA2384, sigAlgorithm: "pkcs1v15"},
+	"RS512": {hashAlgorithm: keysutil.HashTypeSHA2512, sigAlgorithm: "pkcs1v15"},
+	"PS256": {hashAlgorithm: keysutil.HashTypeSHA2256, sigAlgorithm: "pss"},
+	"PS384": {hashAlgorithm: keysutil.HashTypeSHA2384, sigAlgorithm: "pss"},
+	"PS512": {hashAlgorithm: keysutil.HashTypeSHA2512, sigAlgorithm: "pss"},
+}
+
+// jwtAlgorithmsForKeyType returns the JWS algorithms supported by the given
+// key type, the first one being the default.
+func jwtAlgorithmsForKeyType(keyType keysutil.KeyType) []string {
+	switch keyType {
+	case keysutil.KeyType_ECDSA_P256:
+		return []string{"ES256"}
+	case keysutil.KeyType_ECDSA_P384:
+		return []string{"ES384"}
+	case keysutil.KeyType_ECDSA_P521:
+		return []string{"ES512"}
+	case keysutil.KeyType_ED25519:
+		return []string{"EdDSA"}
+	case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
+		return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	}
+	return nil
+}
+
+// jwtHeader is the protected header of the JWS signed by the jwt/sign
+// endpoint.
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Type      string `json:"typ"`
+}
+
+func (b *backend) pathJWTSign() *framework.Path {
+	return &framework.Path{
+		Pattern: "jwt/sign/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTransit,
+			OperationVerb:   "sign",
+			OperationSuffix: "jwt",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to use",
+			},
+
+			"claims": {
+				Type:        framework.TypeMap,
+				Description: "The claims set of the JWT",
+			},
+
+			"algorithm": {
+				Type: framework.TypeString,
+				Description: `The JWS algorithm to sign with. ECDSA and Ed25519 keys only support
+the algorithm matching their curve ("ES256", "ES384", "ES512" or "EdDSA").
+RSA keys support "RS256" (the default), "RS384", "RS512", "PS256", "PS384"
+and "PS512".`,
+			},
+
+			"key_version": {
+				Type: framework.TypeInt,
+				Description: `The version of the key to use for signing.
+Must be 0 (for latest) or a value greater than or equal
+to the min_encryption_version configured on the key.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathJWTSignWrite,
+			},
+		},
+
+		HelpSynopsis:    pathJWTSignHelpSyn,
+		HelpDescription: pathJWTSignHelpDesc,
+	}
+}
+
+func (b *backend) pathJWKS() *framework.Path {
+	return &framework.Path{
+		Pattern: "jwks/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTransit,
+			OperationVerb:   "read",
+			OperationSuffix: "jwks",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to list the public keys of",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathJWKSRead,
+			},
+		},
+
+		HelpSynopsis:    pathJWKSHelpSyn,
+		HelpDescription: pathJWKSHelpDesc,
+	}
+}
+
+// getJWTPolicy returns the named key, locked for reading, if it can be used
+// to sign JWTs.
+func (b *backend) getJWTPolicy(ctx context.Context, req *logical.Request, name string) (*keysutil.Policy, *logical.Response, error) {
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return nil, logical.ErrorResponse("signing key not found"), logical.ErrInvalidRequest
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+
+	if len(jwtAlgorithmsForKeyType(p.Type)) == 0 {
+		p.Unlock()
+		return nil, logical.ErrorResponse("JWT signing not supported for key type %v", p.Type), logical.ErrInvalidRequest
+	}
+	if p.Derived {
+		p.Unlock()
+		return nil, logical.ErrorResponse("JWT signing not supported for keys using derivation"), logical.ErrInvalidRequest
+	}
+
+	return p, nil, nil
+}
+
+func (b *backend) pathJWTSignWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	claims, ok := d.GetOk("claims")
+	if !ok {
+		return logical.ErrorResponse("missing claims to sign"), logical.ErrInvalidRequest
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	p, resp, err := b.getJWTPolicy(ctx, req, d.Get("name").(string))
+	if p == nil {
+		return resp, err
+	}
+	defer p.Unlock()
+
+	supported := jwtAlgorithmsForKeyType(p.Type)
+	alg := d.Get("algorithm").(string)
+	if alg == "" {
+		alg = supported[0]
+	} else if !strutil.StrListContains(supported, alg) {
+		return logical.ErrorResponse("unsupported JWS algorithm %q for key type %v, expected one of %s", alg, p.Type, strings.Join(supported, ", ")), logical.ErrInvalidRequest
+	}
+	algorithm := jwtAlgorithms[alg]
+
+	ver := d.Get("key_version").(int)
+	if ver == 0 {
+		ver = p.LatestVersion
+	}
+
+	header, err := json.Marshal(jwtHeader{
+		Algorithm: alg,
+		KeyID:     strconv.Itoa(ver),
+		Type:      "JWT",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode header: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	input := []byte(signingInput)
+	if p.Type.HashSignatureInput() {
+		hf := keysutil.HashFuncMap[algorithm.hashAlgorithm]()
+		hf.Write(input)
+		input = hf.Sum(nil)
+	}
+
+	sig, err := p.SignWithOptions(ver, nil, input, &keysutil.SigningOptions{
+		HashAlgorithm: algorithm.hashAlgorithm,
+		Marshaling:    keysutil.MarshalingTypeJWS,
+		SaltLength:    rsa.PSSSaltLengthEqualsHash,
+		SigAlgorithm:  algorithm.sigAlgorithm,
+	})
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	// The signature is already base64url-encoded, after its version prefix
+	signature := sig.Signature[strings.LastIndex(sig.Signature, ":")+1:]
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"token":       signingInput + "." + signature,
+			"key_version": ver,
+		},
+	}, nil
+}
+
+// jwksSupported returns whether the public keys of the given key may be
+// published as a JWK set.
+func jwksSupported(p *keysutil.Policy) bool {
+	return len(jwtAlgorithmsForKeyType(p.Type)) > 0 && !p.Derived
+}
+
+// pathJWKSRead returns the JWK set of the named key. As the endpoint is
+// unauthenticated, keys which do not exist, have not opted in or cannot be
+// published all result in the same empty 404 response, so that the names
+// and types of keys cannot be probed.
+func (b *backend) pathJWKSRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    d.Get("name").(string),
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, nil
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	defer p.Unlock()
+
+	if !p.JWKSEnabled || !jwksSupported(p) {
+		return nil, nil
+	}
+
+	jwks, err := policyJWKS(p)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  200,
+			logical.HTTPRawBody:     data,
+			logical.HTTPContentType: "application/json",
+		},
+	}, nil
+}
+
+// policyJWKS returns the public keys of all the versions of the given key as
+// a JWK set, identified by their version as in the JWTs signed by the key.
+func policyJWKS(p *keysutil.Policy) (*jose.JSONWebKeySet, error) {
+	var versions []int
+	for k := range p.Keys {
+		ver, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key version %q: %w", k, err)
+		}
+		versions = append(versions, ver)
+	}
+	sort.Ints(versions)
+
+	// Only the algorithm of ECDSA and Ed25519 keys is known in advance
+	var alg string
+	if supported := jwtAlgorithmsForKeyType(p.Type); len(supported) == 1 {
+		alg = supported[0]
+	}
+
+	jwks := &jose.JSONWebKeySet{
+		Keys: make([]jose.JSONWebKey, 0, len(versions)),
+	}
+	for _, ver := range versions {
+		key := p.Keys[strconv.Itoa(ver)]
+
+		// Reuse the encoding of public keys of the export endpoint
+		der, err := getExportKey(p, &key, exportTypePublicKey, "der")
+		if err != nil {
+			return nil, fmt.Errorf("failed to export public key of version %d: %w", ver, err)
+		}
+		derBytes, err := base64.StdEncoding.DecodeString(der)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(derBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key of version %d: %w", ver, err)
+		}
+
+		jwk := jose.JSONWebKey{
+			Key:       publicKey,
+			KeyID:     strconv.Itoa(ver),
+			Algorithm: alg,
+			Use:       "sig",
+		}
+		if !jwk.Valid() {
+			return nil, errors.New("invalid JWK for public key of version " + strconv.Itoa(ver))
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks, nil
+}
+
+const pathJWTSignHelpSyn = `Sign a JWT using the named key`
+
+const pathJWTSignHelpDesc = `
+Signs the given claims set with the named key, returning a compact JWS. The
+"kid" header of the token is the version of the key it was signed with, as
+listed by the jwks endpoint.
+`
+
+const pathJWKSHelpSyn = `List the public keys of the named key as a JWK set`
+
+const pathJWKSHelpDesc = `
+Returns the public keys of all the versions of the named key as a JWK set
+(RFC 7517), allowing relying parties to verify the JWTs signed by the key.
+This endpoint does not require authentication, and only serves keys whose
+jwks_enabled configuration is set; any other key results in a 404.
+`
@@ -0,0 +1,199 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package transit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestTransit_JWT(t *testing.T) {
+	cases := []struct {
+		keyType   string
+		algorithm string
+		expected  string
+	}{
+		{"ecdsa-p256", "", "ES256"},
+		{"ecdsa-p384", "", "ES384"},
+		{"ecdsa-p521", "", "ES512"},
+		{"ed25519", "", "EdDSA"},
+		{"rsa-2048", "", "RS256"},
+		{"rsa-2048", "PS256", "PS256"},
+		{"rsa-3072", "RS512", "RS512"},
+		{"rsa-4096", "PS384", "PS384"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.keyType+"/"+tc.expected, func(t *testing.T) {
+			b, s := createBackendWithStorage(t)
+
+			doRequest := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+				t.Helper()
+				resp, err := b.HandleRequest(context.Background(), &logical.Request{
+					Operation: operation,
+					Path:      path,
+					Storage:   s,
+					Data:      data,
+				})
+				if err != nil || (resp != nil && resp.IsError()) {
+					t.Fatalf("err:%v resp:%#v", err, resp)
+				}
+				return resp
+			}
+
+			doRequest(logical.UpdateOperation, "keys/jwt", map[string]interface{}{"type": tc.keyType})
+			doRequest(logical.UpdateOperation, "keys/jwt/rotate", nil)
+			doRequest(logical.UpdateOperation, "keys/jwt/config", map[string]interface{}{"jwks_enabled": true})
+
+			data := map[string]interface{}{
+				"claims": map[string]interface{}{
+					"iss": "https://issuer.example.com",
+					"sub": "alice",
+				},
+			}
+			if tc.algorithm != "" {
+				data["algorithm"] = tc.algorithm
+			}
+			resp := doRequest(logical.UpdateOperation, "jwt/sign/jwt", data)
+			token := resp.Data["token"].(string)
+			if resp.Data["key_version"] != 2 {
+				t.Fatalf("bad key version: %#v", resp.Data)
+			}
+
+			resp = doRequest(logical.ReadOperation, "jwks/jwt", nil)
+			if resp.Data[logical.HTTPContentType] != "application/json" {
+				t.Fatalf("bad content type: %#v", resp.Data)
+			}
+			var jwks jose.JSONWebKeySet
+			if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &jwks); err != nil {
+				t.Fatal(err)
+			}
+			if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "1" || jwks.Keys[1].KeyID != "2" {
+				t.Fatalf("bad JWK set: %#v", jwks)
+			}
+
+			jws, err := jose.ParseSigned(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := jws.Signatures[0].Protected
+			if header.Algorithm != tc.expected || header.KeyID != "2" || header.ExtraHeaders["typ"] != "JWT" {
+				t.Fatalf("bad header: %#v", header)
+			}
+			keys := jwks.Key(header.KeyID)
+			if len(keys) != 1 {
+				t.Fatalf("no JWK for kid %s", header.KeyID)
+			}
+			payload, err := jws.Verify(keys[0])
+			if err != nil {
+				t.Fatalf("failed to verify token: %v", err)
+			}
+			var claims map[string]interface{}
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if claims["sub"] != "alice" || claims["iss"] != "https://issuer.example.com" {
+				t.Fatalf("bad claims: %#v", claims)
+			}
+
+			// The token does not verify with the older version of the key
+			if _, err := jws.Verify(jwks.Key("1")[0]); err == nil {
+				t.Fatal("expected token to not verify with key version 1")
+			}
+
+			// Trimmed versions are not listed anymore
+			doRequest(logical.UpdateOperation, "keys/jwt/config", map[string]interface{}{"min_decryption_version": 2, "min_encryption_version": 2})
+			doRequest(logical.UpdateOperation, "keys/jwt/trim", map[string]interface{}{"min_available_version": 2})
+			resp = doRequest(logical.ReadOperation, "jwks/jwt", nil)
+			jwks = jose.JSONWebKeySet{}
+			if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &jwks); err != nil {
+				t.Fatal(err)
+			}
+			if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "2" {
+				t.Fatalf("bad JWK set after trimming: %#v", jwks)
+			}
+		})
+	}
+}
+
+func TestTransit_JWTErrors(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	doRequest := func(operation logical.Operation, path string, data map[string]interface{}, errExpected bool) {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		failed := err != nil || (resp != nil && resp.IsError())
+		if failed != errExpected {
+			t.Fatalf("expected error: %v, err:%v resp:%#v", errExpected, err, resp)
+		}
+	}
+
+	claims := map[string]interface{}{"sub": "alice"}
+
+	doRequest(logical.UpdateOperation, "keys/aes", nil, false)
+	doRequest(logical.UpdateOperation, "jwt/sign/aes", map[string]interface{}{"claims": claims}, true)
+	doRequest(logical.UpdateOperation, "keys/aes/config", map[string]interface{}{"jwks_enabled": true}, true)
+
+	doRequest(logical.UpdateOperation, "keys/ec", map[string]interface{}{"type": "ecdsa-p256"}, false)
+	doRequest(logical.UpdateOperation, "jwt/sign/ec", map[string]interface{}{"claims": claims, "algorithm": "ES384"}, true)
+	doRequest(logical.UpdateOperation, "jwt/sign/ec", map[string]interface{}{"claims": claims, "key_version": 2}, true)
+	doRequest(logical.UpdateOperation, "jwt/sign/ec", nil, true)
+	doRequest(logical.UpdateOperation, "jwt/sign/missing", map[string]interface{}{"claims": claims}, true)
+
+	doRequest(logical.UpdateOperation, "keys/derived", map[string]interface{}{"type": "ed25519", "derived": true}, false)
+	doRequest(logical.UpdateOperation, "jwt/sign/derived", map[string]interface{}{"claims": claims}, true)
+	doRequest(logical.UpdateOperation, "keys/derived/config", map[string]interface{}{"jwks_enabled": true}, true)
+}
+
+// The JWK set endpoint is unauthenticated, so keys which do not exist, have
+// not opted in or cannot be published must not be told apart.
+func TestTransit_JWKSNotFound(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	doRequest := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return resp
+	}
+
+	doRequest(logical.UpdateOperation, "keys/aes", nil)
+	doRequest(logical.UpdateOperation, "keys/ec", map[string]interface{}{"type": "ecdsa-p256"})
+
+	for _, name := range []string{"missing", "aes", "ec"} {
+		if resp := doRequest(logical.ReadOperation, "jwks/"+name, nil); resp != nil {
+			t.Fatalf("expected no JWK set for %s, got: %#v", name, resp)
+		}
+	}
+
+	// Keys must opt in, and can opt out again.
+	resp := doRequest(logical.UpdateOperation, "keys/ec/config", map[string]interface{}{"jwks_enabled": true})
+	if resp.Data["jwks_enabled"] != true {
+		t.Fatalf("expected jwks_enabled to be set: %#v", resp.Data)
+	}
+	if resp := doRequest(logical.ReadOperation, "jwks/ec", nil); resp == nil || resp.Data[logical.HTTPStatusCode] != 200 {
+		t.Fatalf("expected JWK set for ec, got: %#v", resp)
+	}
+
+	doRequest(logical.UpdateOperation, "keys/ec/config", map[string]interface{}{"jwks_enabled": false})
+	if resp := doRequest(logical.ReadOperation, "jwks/ec", nil); resp != nil {
+		t.Fatalf("expected no JWK set for ec after opting out, got: %#v", resp)
+	}
+}
@@ -74,7 +74,10 @@ func (b *backend) pathKeys() *framework.Path {
 				Description: `
 The type of key to create. Currently, "aes128-gcm96" (symmetric), "aes256-gcm96" (symmetric), "ecdsa-p256"
 (asymmetric), "ecdsa-p384" (asymmetric), "ecdsa-p521" (asymmetric), "ed25519" (asymmetric), "rsa-2048" (asymmetric), "rsa-3072"
-(asymmetric), "rsa-4096" (asymmetric) are supported.  Defaults to "aes256-gcm96".
+(asymmetric), "rsa-4096" (asymmetric), "aes128-cmac" (CMAC), "aes256-cmac" (CMAC), "kmac128" (CMAC),
+"kmac256" (CMAC), "ml-dsa-44" (asymmetric), "ml-dsa-65" (asymmetric), "ml-dsa-87" (asymmetric),
+"ml-kem-768" (asymmetric), "ml-kem-1024" (asymmetric), "aes256-siv" (deterministic), "aes256-gcm-siv"
+(deterministic) are supported.  Defaults to "aes256-gcm96".
 `,
 			},
 
@@ -293,6 +296,28 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 		polReq.KeyType = keysutil.KeyType_RSA4096
 	case "hmac":
 		polReq.KeyType = keysutil.KeyType_HMAC
+	case "aes128-cmac":
+		polReq.KeyType = keysutil.KeyType_AES128_CMAC
+	case "aes256-cmac":
+		polReq.KeyType = keysutil.KeyType_AES256_CMAC
+	case "kmac128":
+		polReq.KeyType = keysutil.KeyType_KMAC128
+	case "kmac256":
+		polReq.KeyType = keysutil.KeyType_KMAC256
+	case "ml-dsa-44":
+		polReq.KeyType = keysutil.KeyType_ML_DSA_44
+	case "ml-dsa-65":
+		polReq.KeyType = keysutil.KeyType_ML_DSA_65
+	case "ml-dsa-87":
+		polReq.KeyType = keysutil.KeyType_ML_DSA_87
+	case "ml-kem-768":
+		polReq.KeyType = keysutil.KeyType_ML_KEM_768
+	case "ml-kem-1024":
+		polReq.KeyType = keysutil.KeyType_ML_KEM_1024
+	case "aes256-siv":
+		polReq.KeyType = keysutil.KeyType_AES256_SIV
+	case "aes256-gcm-siv":
+		polReq.KeyType = keysutil.KeyType_AES256_GCM_SIV
 	default:
 		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
 	}
@@ -385,6 +410,7 @@ func (b *backend) formatKeyPolicy(p *keysutil.Policy, context []byte) (*logical.
 			"supports_signing":       p.Type.SigningSupported(),
 			"supports_derivation":    p.Type.DerivationSupported(),
 			"auto_rotate_period":     int64(p.AutoRotatePeriod.Seconds()),
+			"jwks_enabled":           p.JWKSEnabled,
 			"imported_key":           p.Imported,
 			"soft_deleted":           p.SoftDeleted,
 		},
@@ -425,14 +451,17 @@ func (b *backend) formatKeyPolicy(p *keysutil.Policy, context []byte) (*logical.
 	}
 
 	switch p.Type {
-	case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_XChaCha20_Poly1305:
+	case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_XChaCha20_Poly1305,
+		keysutil.KeyType_AES128_CMAC, keysutil.KeyType_AES256_CMAC, keysutil.KeyType_KMAC128, keysutil.KeyType_KMAC256,
+		keysutil.KeyType_AES256_SIV, keysutil.KeyType_AES256_GCM_SIV:
 		retKeys := map[string]int64{}
 		for k, v := range p.Keys {
 			retKeys[k] = v.DeprecatedCreationTime
 		}
 		resp.Data["keys"] = retKeys
 
-	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521, keysutil.KeyType_ED25519, keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
+	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521, keysutil.KeyType_ED25519, keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096,
+		keysutil.KeyType_ML_DSA_44, keysutil.KeyType_ML_DSA_65, keysutil.KeyType_ML_DSA_87, keysutil.KeyType_ML_KEM_768, keysutil.KeyType_ML_KEM_1024:
 		retKeys := map[string]map[string]interface{}{}
 		for k, v := range p.Keys {
 			key := asymKey{
@@ -496,6 +525,8 @@ func (b *backend) formatKeyPolicy(p *keysutil.Policy, context []byte) (*logical.
 					return nil, err
 				}
 				key.PublicKey = pubKey
+			default:
+				key.Name = p.Type.String()
 			}
 
 			retKeys[k] = structs.New(key).Map()
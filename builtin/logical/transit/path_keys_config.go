@@ -65,6 +65,13 @@ the latest version of the key is allowed.`,
 being automatically rotated. A value of 0
 disables automatic rotation for the key.`,
 			},
+
+			"jwks_enabled": {
+				Type: framework.TypeBool,
+				Description: `Publishes the public keys of the key as a JWK
+set on the unauthenticated jwks endpoint. Only
+keys which can sign JWTs may be published.`,
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -112,6 +119,7 @@ func (b *backend) pathKeysConfigWrite(ctx context.Context, req *logical.Request,
 	originalDeletionAllowed := p.DeletionAllowed
 	originalExportable := p.Exportable
 	originalAllowPlaintextBackup := p.AllowPlaintextBackup
+	originalJWKSEnabled := p.JWKSEnabled
 
 	defer func() {
 		if retErr != nil || (resp != nil && resp.IsError()) {
@@ -120,6 +128,7 @@ func (b *backend) pathKeysConfigWrite(ctx context.Context, req *logical.Request,
 			p.DeletionAllowed = originalDeletionAllowed
 			p.Exportable = originalExportable
 			p.AllowPlaintextBackup = originalAllowPlaintextBackup
+			p.JWKSEnabled = originalJWKSEnabled
 		}
 	}()
 
@@ -228,6 +237,18 @@ func (b *backend) pathKeysConfigWrite(ctx context.Context, req *logical.Request,
 		}
 	}
 
+	jwksEnabledRaw, ok := d.GetOk("jwks_enabled")
+	if ok {
+		jwksEnabled := jwksEnabledRaw.(bool)
+		if jwksEnabled && !jwksSupported(p) {
+			return logical.ErrorResponse("JWK set publication not supported for key type %v or for keys using derivation", p.Type), nil
+		}
+		if jwksEnabled != p.JWKSEnabled {
+			p.JWKSEnabled = jwksEnabled
+			persistNeeded = true
+		}
+	}
+
 	if !persistNeeded {
 		resp, err := b.formatKeyPolicy(p, nil)
 		if err != nil {
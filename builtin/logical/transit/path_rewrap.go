@@ -40,6 +40,15 @@ func (b *backend) pathRewrap() *framework.Path {
 				Description: "Base64 encoded context for key derivation. Required for derived keys.",
 			},
 
+			"associated_data": {
+				Type: framework.TypeString,
+				Description: `
+When using an AEAD cipher mode, such as AES-GCM or AES-SIV, the associated
+data (AD/AAD) the ciphertext was encrypted with; it is used to decrypt the
+ciphertext and to encrypt it again with the new key version.
+				`,
+			},
+
 			"key_version": {
 				Type: framework.TypeInt,
 				Description: `The version of the key to use for encryption.
@@ -88,9 +97,10 @@ func (b *backend) pathRewrapWrite(ctx context.Context, req *logical.Request, d *
 
 		batchInputItems = make([]BatchRequestItem, 1)
 		batchInputItems[0] = BatchRequestItem{
-			Ciphertext: ciphertext,
-			Context:    d.Get("context").(string),
-			KeyVersion: d.Get("key_version").(int),
+			Ciphertext:     ciphertext,
+			Context:        d.Get("context").(string),
+			KeyVersion:     d.Get("key_version").(int),
+			AssociatedData: d.Get("associated_data").(string),
 		}
 	}
 
@@ -138,7 +148,17 @@ func (b *backend) pathRewrapWrite(ctx context.Context, req *logical.Request, d *
 			continue
 		}
 
-		plaintext, err := p.Decrypt(item.DecodedContext, nil, item.Ciphertext)
+		var factory interface{}
+		if item.AssociatedData != "" {
+			if !p.Type.AssociatedDataSupported() {
+				batchResponseItems[i].Error = fmt.Sprintf("'[%d].associated_data' provided for non-AEAD cipher suite %v", i, p.Type.String())
+				continue
+			}
+
+			factory = AssocDataFactory{item.AssociatedData}
+		}
+
+		plaintext, err := p.DecryptWithFactory(item.DecodedContext, nil, item.Ciphertext, factory)
 		if err != nil {
 			switch err.(type) {
 			case errutil.UserError:
@@ -149,7 +169,7 @@ func (b *backend) pathRewrapWrite(ctx context.Context, req *logical.Request, d *
 			}
 		}
 
-		ciphertext, err := p.Encrypt(item.KeyVersion, item.DecodedContext, nil, plaintext)
+		ciphertext, err := p.EncryptWithFactory(item.KeyVersion, item.DecodedContext, nil, plaintext, factory)
 		if err != nil {
 			switch err.(type) {
 			case errutil.UserError:
@@ -326,3 +326,82 @@ func TestTransit_BatchRewrapCase3(t *testing.T) {
 
 	}
 }
+
+// Check that deterministic keys give the same ciphertext for the same input,
+// and that rewrapping them keeps their associated data
+func TestTransit_RewrapDeterministic(t *testing.T) {
+	for _, keyType := range []string{"aes256-siv", "aes256-gcm-siv"} {
+		t.Run(keyType, func(t *testing.T) {
+			b, s := createBackendWithStorage(t)
+
+			doRequest := func(path string, data map[string]interface{}) *logical.Response {
+				t.Helper()
+				resp, err := b.HandleRequest(context.Background(), &logical.Request{
+					Operation: logical.UpdateOperation,
+					Path:      path,
+					Storage:   s,
+					Data:      data,
+				})
+				if err != nil || (resp != nil && resp.IsError()) {
+					t.Fatalf("err:%v resp:%#v", err, resp)
+				}
+				return resp
+			}
+
+			doRequest("keys/siv", map[string]interface{}{"type": keyType})
+
+			resp := doRequest("encrypt/siv", map[string]interface{}{
+				"batch_input": []interface{}{
+					map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==", "associated_data": "cm93LTE="},
+					map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==", "associated_data": "cm93LTE="},
+					map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==", "associated_data": "cm93LTI="},
+				},
+			})
+			results := resp.Data["batch_results"].([]EncryptBatchResponseItem)
+			if results[0].Ciphertext != results[1].Ciphertext {
+				t.Fatalf("expected deterministic ciphertexts: %#v", results)
+			}
+			if results[0].Ciphertext == results[2].Ciphertext {
+				t.Fatalf("expected associated data to change the ciphertext: %#v", results)
+			}
+			ciphertext := results[0].Ciphertext
+
+			doRequest("keys/siv/rotate", nil)
+			resp = doRequest("rewrap/siv", map[string]interface{}{
+				"ciphertext":      ciphertext,
+				"associated_data": "cm93LTE=",
+			})
+			rewrapped := resp.Data["ciphertext"].(string)
+			if !strings.HasPrefix(rewrapped, "vault:v2:") {
+				t.Fatalf("bad rewrapped ciphertext: %s", rewrapped)
+			}
+
+			resp = doRequest("encrypt/siv", map[string]interface{}{
+				"plaintext":       "dGhlIHF1aWNrIGJyb3duIGZveA==",
+				"associated_data": "cm93LTE=",
+			})
+			if resp.Data["ciphertext"] != rewrapped {
+				t.Fatalf("expected rewrapped ciphertext %s to match the encryption with the latest version, got %s", rewrapped, resp.Data["ciphertext"])
+			}
+
+			resp = doRequest("decrypt/siv", map[string]interface{}{
+				"ciphertext":      rewrapped,
+				"associated_data": "cm93LTE=",
+			})
+			if resp.Data["plaintext"] != "dGhlIHF1aWNrIGJyb3duIGZveA==" {
+				t.Fatalf("bad plaintext: %#v", resp.Data)
+			}
+
+			// Without its associated data, the ciphertext cannot be rewrapped
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "rewrap/siv",
+				Storage:   s,
+				Data:      map[string]interface{}{"ciphertext": ciphertext},
+			})
+			if err == nil && (resp == nil || !resp.IsError()) {
+				t.Fatalf("expected rewrap without associated data to fail, got: %#v", resp)
+			}
+		})
+	}
+}
@@ -266,6 +266,17 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) map[string]cli.Co
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"events": func() (cli.Command, error) {
+			return &EventsCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"events subscribe": func() (cli.Command, error) {
+			return &EventsSubscribeCommand{
+				BaseCommand: getBaseCommand(),
+				ShutdownCh:  MakeShutdownCh(),
+			}, nil
+		},
 		"lease": func() (cli.Command, error) {
 			return &LeaseCommand{
 				BaseCommand: getBaseCommand(),
@@ -723,6 +734,31 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) map[string]cli.Co
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"kv cp": func() (cli.Command, error) {
+			return &KVCopyCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"kv mv": func() (cli.Command, error) {
+			return &KVMoveCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"kv export": func() (cli.Command, error) {
+			return &KVExportCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"kv import": func() (cli.Command, error) {
+			return &KVImportCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"kv search": func() (cli.Command, error) {
+			return &KVSearchCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"kv rollback": func() (cli.Command, error) {
 			return &KVRollbackCommand{
 				BaseCommand: getBaseCommand(),
@@ -0,0 +1,41 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"strings"
+
+	"github.com/hashicorp/cli"
+)
+
+var _ cli.Command = (*EventsCommand)(nil)
+
+type EventsCommand struct {
+	*BaseCommand
+}
+
+func (c *EventsCommand) Synopsis() string {
+	return "Interact with OpenBao's event notifications"
+}
+
+func (c *EventsCommand) Help() string {
+	helpText := `
+Usage: bao events <subcommand> [options] [args]
+
+  This command groups subcommands for interacting with the events published
+  by OpenBao when secrets, leases and mounts change.
+
+  Subscribe to all KV v2 events:
+
+      $ bao events subscribe "kv-v2/*"
+
+  Please see the individual subcommand help for detailed usage information.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *EventsCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
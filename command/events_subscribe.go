@@ -0,0 +1,110 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/cli"
+	"github.com/posener/complete"
+)
+
+var (
+	_ cli.Command             = (*EventsSubscribeCommand)(nil)
+	_ cli.CommandAutocomplete = (*EventsSubscribeCommand)(nil)
+)
+
+type EventsSubscribeCommand struct {
+	*BaseCommand
+
+	// ShutdownCh is used to capture interrupt signal and end streaming
+	ShutdownCh chan struct{}
+}
+
+func (c *EventsSubscribeCommand) Synopsis() string {
+	return "Stream events from an OpenBao server"
+}
+
+func (c *EventsSubscribeCommand) Help() string {
+	helpText := `
+Usage: bao events subscribe [options] PATTERN
+
+  Stream the events whose type matches the given glob pattern. Each event is
+  printed as a single line of JSON. Only events relating to paths the token
+  is allowed to read are delivered.
+
+  Subscribe to all KV v2 writes:
+
+      $ bao events subscribe "kv-v2/data-write"
+
+  Subscribe to all events in the current namespace and its children:
+
+      $ bao events subscribe "*"
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *EventsSubscribeCommand) Flags() *FlagSets {
+	return c.flagSet(FlagSetHTTP)
+}
+
+func (c *EventsSubscribeCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *EventsSubscribeCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *EventsSubscribeCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	switch {
+	case len(args) < 1:
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected 1, got %d)", len(args)))
+		return 1
+	case len(args) > 1:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	// Remove the default 60 second timeout so we can stream indefinitely
+	client.SetClientTimeout(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eventCh, err := client.Sys().SubscribeEvents(ctx, strings.TrimSpace(args[0]))
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error subscribing to events: %s", err))
+		return 2
+	}
+
+	for {
+		select {
+		case event, ok := <-eventCh:
+			if !ok {
+				return 0
+			}
+			c.UI.Output(event)
+		case <-c.ShutdownCh:
+			return 0
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/cli"
+	"github.com/posener/complete"
+)
+
+var (
+	_ cli.Command             = (*KVCopyCommand)(nil)
+	_ cli.CommandAutocomplete = (*KVCopyCommand)(nil)
+)
+
+type KVCopyCommand struct {
+	*BaseCommand
+
+	flagMount     string
+	flagRecursive bool
+}
+
+func (c *KVCopyCommand) Synopsis() string {
+	return "Copies secrets along with their metadata and versions"
+}
+
+func (c *KVCopyCommand) Help() string {
+	helpText := `
+Usage: bao kv cp [options] SOURCE DESTINATION
+
+  *NOTE*: This is only supported for KV v2 engine mounts.
+
+  Copies the secret at SOURCE to DESTINATION within the same mount, including
+  its metadata, custom metadata and all of its versions. The operation fails if
+  a secret already exists at DESTINATION.
+
+      $ bao kv cp -mount=secret creds creds-old
+
+  With -recursive, SOURCE and DESTINATION are treated as directories and every
+  secret within SOURCE is copied. Either all secrets are copied or none is.
+
+      $ bao kv cp -mount=secret -recursive app/ legacy/app/
+
+  The deprecated path-like syntax can also be used, but this should be avoided,
+  as the fact that it is not actually the full API path to
+  the secret (secret/data/foo) can cause confusion:
+
+      $ bao kv cp secret/creds secret/creds-old
+
+  Additional flags and more advanced use cases are detailed below.
+
+` + c.Flags().Help()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVCopyCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	// Common Options
+	f := set.NewFlagSet("Common Options")
+
+	f.BoolVar(&BoolVar{
+		Name:    "recursive",
+		Target:  &c.flagRecursive,
+		Default: false,
+		Usage: `If set, SOURCE and DESTINATION are treated as directories and all
+		secrets within SOURCE are copied.`,
+	})
+
+	f.StringVar(&StringVar{
+		Name:    "mount",
+		Target:  &c.flagMount,
+		Default: "", // no default, because the handling of the next args is determined by whether this flag has a value
+		Usage: `Specifies the path where the KV backend is mounted. If specified,
+		the next arguments will be interpreted as the secret paths. If this flag is
+		not specified, the next arguments will be interpreted as the combined mount
+		path and secret paths.`,
+	})
+
+	return set
+}
+
+func (c *KVCopyCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *KVCopyCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *KVCopyCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) != 2 {
+		c.UI.Error(fmt.Sprintf("Invalid number of arguments (expected 2, got %d)", len(args)))
+		return 1
+	}
+
+	return kvRelocate(c.BaseCommand, "copy", c.flagMount, args[0], args[1], c.flagRecursive)
+}
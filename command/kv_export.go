@@ -0,0 +1,173 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"fmt"
+	"os"
+	paths "path"
+	"strings"
+
+	"github.com/hashicorp/cli"
+	"github.com/openbao/openbao/helper/pgpkeys"
+	"github.com/posener/complete"
+)
+
+var (
+	_ cli.Command             = (*KVExportCommand)(nil)
+	_ cli.CommandAutocomplete = (*KVExportCommand)(nil)
+)
+
+type KVExportCommand struct {
+	*BaseCommand
+
+	flagMount      string
+	flagPGPKeys    []string
+	flagPassphrase string
+	flagOutput     string
+}
+
+func (c *KVExportCommand) Synopsis() string {
+	return "Exports secrets to an encrypted archive"
+}
+
+func (c *KVExportCommand) Help() string {
+	helpText := `
+Usage: bao kv export [options] PATH
+
+  *NOTE*: This is only supported for KV v2 engine mounts.
+
+  Exports every secret within a directory, including its metadata and all of
+  its versions, to an archive encrypted either to PGP keys or with a
+  passphrase. The archive is printed base64-encoded, or written to the file
+  given with -output. If PATH is the mount itself, the whole mount is exported.
+
+  Export the secrets within "app/" encrypted with a passphrase:
+
+      $ bao kv export -mount=secret -passphrase=... -output=app.archive app/
+
+  Export the whole mount encrypted to a PGP key:
+
+      $ bao kv export -pgp-keys=ops.asc -output=secret.archive secret
+
+  Archives encrypted to PGP keys are decrypted by their holders, for example
+  with "base64 -d < secret.archive | gpg -dq", before being imported.
+
+  Additional flags and more advanced use cases are detailed below.
+
+` + c.Flags().Help()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVExportCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	// Common Options
+	f := set.NewFlagSet("Common Options")
+
+	f.VarFlag(&VarFlag{
+		Name:       "pgp-keys",
+		Value:      (*pgpkeys.PubKeyFilesFlag)(&c.flagPGPKeys),
+		Completion: complete.PredictAnything,
+		Usage: "Comma-separated list of paths to files on disk containing " +
+			"public PGP keys OR a comma-separated list of Keybase usernames using " +
+			"the format \"keybase:<username>\". The archive is encrypted to all of " +
+			"these keys.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:   "passphrase",
+		Target: &c.flagPassphrase,
+		Usage:  "Passphrase to encrypt the archive with, instead of PGP keys.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "output",
+		Target:     &c.flagOutput,
+		Completion: complete.PredictFiles("*"),
+		Usage:      "Path of the file to write the archive to. By default, it is printed.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:    "mount",
+		Target:  &c.flagMount,
+		Default: "", // no default, because the handling of the next arg is determined by whether this flag has a value
+		Usage: `Specifies the path where the KV backend is mounted. If specified,
+		the next argument will be interpreted as the directory path. If this flag
+		is not specified, the next argument will be interpreted as the combined
+		mount path and directory path.`,
+	})
+
+	return set
+}
+
+func (c *KVExportCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictVaultFolders()
+}
+
+func (c *KVExportCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *KVExportCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) != 1 {
+		c.UI.Error(fmt.Sprintf("Invalid number of arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+	if (len(c.flagPGPKeys) == 0) == (c.flagPassphrase == "") {
+		c.UI.Error("Exactly one of -pgp-keys or -passphrase must be specified")
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	mountPath, prefix, code := kvArchivePath(c.BaseCommand, client, "export", c.flagMount, args[0])
+	if code != 0 {
+		return code
+	}
+
+	fullPath := paths.Join(mountPath, "export")
+	secret, err := client.Logical().Write(fullPath, map[string]interface{}{
+		"path":       prefix,
+		"pgp_keys":   c.flagPGPKeys,
+		"passphrase": c.flagPassphrase,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error exporting %s: %s", paths.Join(mountPath, prefix), err))
+		return 2
+	}
+	if secret == nil || secret.Data == nil {
+		c.UI.Error(fmt.Sprintf("No value found at %s", fullPath))
+		return 2
+	}
+
+	archive, _ := secret.Data["archive"].(string)
+	if c.flagOutput == "" {
+		if Format(c.UI) != "table" {
+			return OutputSecret(c.UI, secret)
+		}
+		c.UI.Output(archive)
+		return 0
+	}
+
+	if err := os.WriteFile(c.flagOutput, []byte(archive+"\n"), 0o600); err != nil {
+		c.UI.Error(fmt.Sprintf("Error writing archive to %s: %s", c.flagOutput, err))
+		return 2
+	}
+
+	c.UI.Info(fmt.Sprintf("Success! Exported %v secrets to %s", secret.Data["secrets"], c.flagOutput))
+	return 0
+}
@@ -267,3 +267,141 @@ func walkSecretsTree(ctx context.Context, client *api.Client, path string, visit
 
 	return nil
 }
+
+// kvRelocate copies or moves the secret at source to destination using the
+// given endpoint, "copy" or "move", of the KV v2 mount both are on. When
+// mount is empty, both paths are expected to include the mount path.
+func kvRelocate(c *BaseCommand, endpoint, mount, source, destination string, recursive bool) int {
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	var (
+		mountPath  string
+		sourcePath string
+		destPath   string
+		v2         bool
+	)
+
+	if mount != "" {
+		// In this case, both args are secret paths (e.g. "foo").
+		mountPath, v2, err = isKVv2(sanitizePath(mount), client)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 2
+		}
+		sourcePath = sanitizePath(source)
+		destPath = sanitizePath(destination)
+	} else {
+		// In this case, both args are path-like combinations of
+		// mountPath/secretPath (e.g. "secret/foo").
+		mountPath, v2, err = isKVv2(sanitizePath(source), client)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 2
+		}
+		destMount, destIsKVv2, err := isKVv2(sanitizePath(destination), client)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 2
+		}
+		if destMount != mountPath || destIsKVv2 != v2 {
+			c.UI.Error("Source and destination must be within the same K/V mount")
+			return 1
+		}
+
+		_, destPath = kvSplitMountPath(sanitizePath(destination), mountPath)
+		mountPath, sourcePath = kvSplitMountPath(sanitizePath(source), mountPath)
+	}
+
+	if !v2 {
+		c.UI.Error(fmt.Sprintf("K/V engine mount must be version 2 for %s support", endpoint))
+		return 2
+	}
+	if sourcePath == "" || destPath == "" {
+		c.UI.Error("Source and destination must be secret paths within the mount")
+		return 1
+	}
+
+	fullPath := paths.Join(mountPath, endpoint)
+	secret, err := client.Logical().Write(fullPath, map[string]interface{}{
+		"source":      sourcePath,
+		"destination": destPath,
+		"recursive":   recursive,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error writing data to %s: %s", fullPath, err))
+		if secret != nil {
+			OutputSecret(c.UI, secret)
+		}
+		return 2
+	}
+	if secret == nil || secret.Data == nil {
+		c.UI.Error(fmt.Sprintf("No value found at %s", fullPath))
+		return 2
+	}
+
+	if Format(c.UI) != "table" {
+		return OutputSecret(c.UI, secret)
+	}
+
+	keys, _ := secret.Data["keys"].([]interface{})
+	out := []string{"Source | Destination | Version"}
+	for _, keyRaw := range keys {
+		key, ok := keyRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%v | %v | %v", key["source"], key["destination"], key["version"]))
+	}
+	c.UI.Output(tableOutput(out, nil))
+	return 0
+}
+
+// kvArchivePath returns the path of the KV v2 mount and of the directory
+// within it given by path, or by mount and path if mount is set, for the
+// export and import commands. A non-zero exit code is returned on failure.
+func kvArchivePath(c *BaseCommand, client *api.Client, endpoint, mount, path string) (string, string, int) {
+	if mount == "" {
+		mount = path
+		path = ""
+	}
+
+	mountPath, v2, err := isKVv2(sanitizePath(mount), client)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return "", "", 2
+	}
+	if !v2 {
+		c.UI.Error(fmt.Sprintf("K/V engine mount must be version 2 for %s support", endpoint))
+		return "", "", 2
+	}
+
+	if path != "" {
+		return mountPath, sanitizePath(path), 0
+	}
+	mountPath, path = kvSplitMountPath(sanitizePath(mount), mountPath)
+	return mountPath, path, 0
+}
+
+// kvSplitMountPath splits the given path into the mount path, without any
+// namespaces not included in the path, and the path of the secret relative to
+// the mount. The secret path is empty if the path is not within the mount.
+func kvSplitMountPath(path, mountPath string) (string, string) {
+	for {
+		if strings.HasPrefix(path, mountPath) {
+			return mountPath, strings.Trim(strings.TrimPrefix(path, mountPath), "/")
+		}
+
+		// Trim the parts of the mountPath that are not included in the
+		// path, for example, in cases where the mountPath contains
+		// namespaces which are not included in the path.
+		partialMountPath := strings.SplitN(mountPath, "/", 2)
+		if len(partialMountPath) <= 1 || partialMountPath[1] == "" {
+			return mountPath, ""
+		}
+		mountPath = partialMountPath[1]
+	}
+}
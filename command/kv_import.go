@@ -0,0 +1,284 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	paths "path"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/cli"
+	"github.com/posener/complete"
+)
+
+var (
+	_ cli.Command             = (*KVImportCommand)(nil)
+	_ cli.CommandAutocomplete = (*KVImportCommand)(nil)
+)
+
+type KVImportCommand struct {
+	*BaseCommand
+
+	flagMount            string
+	flagPassphrase       string
+	flagPGPKey           string
+	flagPGPKeyPassphrase string
+	flagFlatten          bool
+	testStdin            io.Reader // for tests
+}
+
+func (c *KVImportCommand) Synopsis() string {
+	return "Imports secrets from an archive"
+}
+
+func (c *KVImportCommand) Help() string {
+	helpText := `
+Usage: bao kv import [options] PATH FILE
+
+  *NOTE*: This is only supported for KV v2 engine mounts.
+
+  Imports the secrets of an archive produced by "bao kv export" into the
+  directory PATH. If PATH is the mount itself, the secrets are imported into
+  the directory they were exported from. If FILE is "-", the archive is read
+  from stdin.
+
+  By default, secrets are imported with all of their versions and metadata,
+  and nothing is imported if any of them already exists. With -flatten, only
+  the latest version of each secret is imported, as a new version of any
+  existing secret.
+
+  Import an archive encrypted with a passphrase into "app/":
+
+      $ bao kv import -mount=secret -passphrase=... app/ app.archive
+
+  Import the latest versions of an archive encrypted to a PGP key, which is
+  decrypted locally with the private key:
+
+      $ bao kv import -pgp-key=ops.key -flatten secret secret.archive
+
+  Additional flags and more advanced use cases are detailed below.
+
+` + c.Flags().Help()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVImportCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	// Common Options
+	f := set.NewFlagSet("Common Options")
+
+	f.StringVar(&StringVar{
+		Name:   "passphrase",
+		Target: &c.flagPassphrase,
+		Usage:  "Passphrase the archive was encrypted with.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "pgp-key",
+		Target:     &c.flagPGPKey,
+		Completion: complete.PredictFiles("*"),
+		Usage: "Path to the PGP private key, armored, binary or base64-encoded, " +
+			"to decrypt an archive encrypted to PGP keys with. The archive is " +
+			"decrypted locally and only kept in memory.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:   "pgp-key-passphrase",
+		Target: &c.flagPGPKeyPassphrase,
+		Usage:  "Passphrase protecting the PGP private key, if any.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "flatten",
+		Target:  &c.flagFlatten,
+		Default: false,
+		Usage: "If set, only the latest version of each secret is imported, as a " +
+			"new version of any existing secret.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:    "mount",
+		Target:  &c.flagMount,
+		Default: "", // no default, because the handling of the next arg is determined by whether this flag has a value
+		Usage: `Specifies the path where the KV backend is mounted. If specified,
+		the next argument will be interpreted as the directory path. If this flag
+		is not specified, the next argument will be interpreted as the combined
+		mount path and directory path.`,
+	})
+
+	return set
+}
+
+func (c *KVImportCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*")
+}
+
+func (c *KVImportCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *KVImportCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) != 2 {
+		c.UI.Error(fmt.Sprintf("Invalid number of arguments (expected 2, got %d)", len(args)))
+		return 1
+	}
+
+	var raw []byte
+	var err error
+	if args[1] == "-" {
+		stdin := (io.Reader)(os.Stdin)
+		if c.testStdin != nil {
+			stdin = c.testStdin
+		}
+		raw, err = io.ReadAll(stdin)
+	} else {
+		raw, err = os.ReadFile(args[1])
+	}
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading archive: %s", err))
+		return 1
+	}
+
+	if c.flagPGPKey != "" && c.flagPassphrase != "" {
+		c.UI.Error("Only one of -pgp-key or -passphrase may be specified")
+		return 1
+	}
+
+	// Archives are exported base64-encoded, and are sent as such; decrypted
+	// archives are encoded before being sent.
+	raw = bytes.TrimSpace(raw)
+	archive := string(raw)
+	if _, err := base64.StdEncoding.DecodeString(archive); err != nil {
+		archive = base64.StdEncoding.EncodeToString(raw)
+	}
+
+	// Archives encrypted to PGP keys are decrypted here, as the server does
+	// not hold the private keys.
+	if c.flagPGPKey != "" {
+		plaintext, err := decryptPGPArchive(archive, c.flagPGPKey, c.flagPGPKeyPassphrase)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error decrypting archive: %s", err))
+			return 1
+		}
+		archive = base64.StdEncoding.EncodeToString(plaintext)
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	mountPath, prefix, code := kvArchivePath(c.BaseCommand, client, "import", c.flagMount, args[0])
+	if code != 0 {
+		return code
+	}
+
+	data := map[string]interface{}{
+		"archive":    archive,
+		"passphrase": c.flagPassphrase,
+		"flatten":    c.flagFlatten,
+	}
+	if prefix != "" {
+		data["path"] = prefix
+	}
+
+	fullPath := paths.Join(mountPath, "import")
+	secret, err := client.Logical().Write(fullPath, data)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error importing into %s: %s", mountPath, err))
+		return 2
+	}
+	if secret == nil || secret.Data == nil {
+		c.UI.Error(fmt.Sprintf("No value found at %s", fullPath))
+		return 2
+	}
+
+	if Format(c.UI) != "table" {
+		return OutputSecret(c.UI, secret)
+	}
+
+	for _, warning := range secret.Warnings {
+		c.UI.Warn(fmt.Sprintf("WARNING! %s", warning))
+	}
+
+	keys, _ := secret.Data["keys"].([]interface{})
+	out := []string{"Path | Version"}
+	for _, keyRaw := range keys {
+		key, ok := keyRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%v | %v", key["path"], key["version"]))
+	}
+	c.UI.Output(tableOutput(out, nil))
+	return 0
+}
+
+// decryptPGPArchive decrypts the base64-encoded archive with the PGP private
+// key at keyPath, protected by passphrase if it is set.
+func decryptPGPArchive(archive, keyPath, passphrase string) ([]byte, error) {
+	keyring, err := readPGPPrivateKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, entity := range keyring {
+		if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+			if passphrase == "" {
+				return nil, errors.New("the PGP private key is protected, set -pgp-key-passphrase")
+			}
+			return nil, fmt.Errorf("error decrypting PGP private key: %w", err)
+		}
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(archive)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding archive: %w", err)
+	}
+
+	md, err := openpgp.ReadMessage(bytes.NewReader(encrypted), keyring, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(md.UnverifiedBody)
+}
+
+// readPGPPrivateKey reads the PGP private key at path, which may be armored,
+// binary or base64-encoded.
+func readPGPPrivateKey(path string) (openpgp.EntityList, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PGP private key: %w", err)
+	}
+
+	if keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(raw)); err == nil {
+		return keyring, nil
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(raw))); err == nil {
+		raw = decoded
+	}
+
+	keyring, err := openpgp.ReadKeyRing(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing PGP private key: %w", err)
+	}
+
+	return keyring, nil
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"fmt"
+	paths "path"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/cli"
+	"github.com/posener/complete"
+)
+
+var (
+	_ cli.Command             = (*KVSearchCommand)(nil)
+	_ cli.CommandAutocomplete = (*KVSearchCommand)(nil)
+)
+
+type KVSearchCommand struct {
+	*BaseCommand
+
+	flagMount          string
+	flagCustomMetadata map[string]string
+	flagMinVersionAge  time.Duration
+	flagMaxVersionAge  time.Duration
+	flagUpdatedAfter   time.Time
+	flagUpdatedBefore  time.Time
+	flagAfter          string
+	flagLimit          int
+}
+
+func (c *KVSearchCommand) Synopsis() string {
+	return "Searches secrets by their metadata"
+}
+
+func (c *KVSearchCommand) Help() string {
+	helpText := `
+Usage: bao kv search [options] [MOUNT]
+
+  *NOTE*: This is only supported for KV v2 engine mounts.
+
+  Lists the paths of the secrets in the given mount matching all of the given
+  criteria. Only secrets within directories the token is allowed to list are
+  returned.
+
+  Find the secrets owned by the payments team:
+
+      $ bao kv search -mount=secret -custom-metadata=owner=payments
+
+  Find the secrets whose current version is older than 90 days:
+
+      $ bao kv search -min-version-age=2160h secret
+
+  Additional flags and more advanced use cases are detailed below.
+
+` + c.Flags().Help()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVSearchCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	// Common Options
+	f := set.NewFlagSet("Common Options")
+
+	f.StringMapVar(&StringMapVar{
+		Name:    "custom-metadata",
+		Target:  &c.flagCustomMetadata,
+		Default: map[string]string{},
+		Usage: "Specifies a key=value pair of custom metadata matching secrets must have. " +
+			"This can be specified multiple times to require multiple pieces of metadata.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:   "min-version-age",
+		Target: &c.flagMinVersionAge,
+		Usage:  "Only returns secrets whose current version was written at least this long ago.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:   "max-version-age",
+		Target: &c.flagMaxVersionAge,
+		Usage:  "Only returns secrets whose current version was written at most this long ago.",
+	})
+
+	f.TimeVar(&TimeVar{
+		Name:    "updated-after",
+		Target:  &c.flagUpdatedAfter,
+		Formats: TimeVar_TimeOrDay,
+		Usage:   "Only returns secrets last updated after this time.",
+	})
+
+	f.TimeVar(&TimeVar{
+		Name:    "updated-before",
+		Target:  &c.flagUpdatedBefore,
+		Formats: TimeVar_TimeOrDay,
+		Usage:   "Only returns secrets last updated before this time.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:   "after",
+		Target: &c.flagAfter,
+		Usage:  "Only returns secrets whose path sorts after this one, to paginate results.",
+	})
+
+	f.IntVar(&IntVar{
+		Name:   "limit",
+		Target: &c.flagLimit,
+		Usage:  "Specifies the maximum number of secrets to return. By default, all are returned.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:    "mount",
+		Target:  &c.flagMount,
+		Default: "", // no default, because the handling of the next arg is determined by whether this flag has a value
+		Usage: `Specifies the path where the KV backend is mounted. If this flag is
+		not specified, the next argument will be interpreted as the mount path.`,
+	})
+
+	return set
+}
+
+func (c *KVSearchCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *KVSearchCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *KVSearchCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	mount := c.flagMount
+	switch {
+	case mount != "" && len(args) > 0:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	case len(args) > 1:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	case mount == "" && len(args) < 1:
+		c.UI.Error("Not enough arguments (expected 1, got 0)")
+		return 1
+	case mount == "":
+		mount = args[0]
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	mountPath, v2, err := isKVv2(sanitizePath(mount), client)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+	if !v2 {
+		c.UI.Error("K/V engine mount must be version 2 for search support")
+		return 2
+	}
+	mountPath, _ = kvSplitMountPath(sanitizePath(mount), mountPath)
+
+	data := map[string]interface{}{}
+	if len(c.flagCustomMetadata) > 0 {
+		data["custom_metadata"] = c.flagCustomMetadata
+	}
+	if c.flagMinVersionAge > 0 {
+		data["min_version_age"] = c.flagMinVersionAge.String()
+	}
+	if c.flagMaxVersionAge > 0 {
+		data["max_version_age"] = c.flagMaxVersionAge.String()
+	}
+	if !c.flagUpdatedAfter.IsZero() {
+		data["updated_after"] = c.flagUpdatedAfter.Format(time.RFC3339Nano)
+	}
+	if !c.flagUpdatedBefore.IsZero() {
+		data["updated_before"] = c.flagUpdatedBefore.Format(time.RFC3339Nano)
+	}
+	if c.flagAfter != "" {
+		data["after"] = c.flagAfter
+	}
+	if c.flagLimit > 0 {
+		data["limit"] = c.flagLimit
+	}
+
+	fullPath := paths.Join(mountPath, "search")
+	secret, err := client.Logical().Write(fullPath, data)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error searching %s: %s", mountPath, err))
+		return 2
+	}
+
+	_, ok := extractListData(secret)
+	if Format(c.UI) != "table" {
+		if secret == nil || secret.Data == nil || !ok {
+			OutputData(c.UI, map[string]interface{}{})
+			return 2
+		}
+	}
+
+	if secret == nil || secret.Data == nil || !ok {
+		c.UI.Error(fmt.Sprintf("No secrets found in %s", mountPath))
+		return 2
+	}
+
+	return OutputList(c.UI, secret)
+}
@@ -5,14 +5,18 @@ package command
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/cli"
 	"github.com/openbao/openbao/api/v2"
+	"github.com/openbao/openbao/helper/pgpkeys"
 )
 
 func testKVPutCommand(tb testing.TB) (*cli.MockUi, *KVPutCommand) {
@@ -1544,3 +1548,353 @@ func createTokenForPolicy(t *testing.T, client *api.Client, policy string) (*api
 
 	return secret.Auth, err
 }
+
+func testKVCopyCommand(tb testing.TB) (*cli.MockUi, *KVCopyCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &KVCopyCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestKVCopyCommand(t *testing.T) {
+	testCases := []struct {
+		name       string
+		args       []string
+		outStrings []string
+		code       int
+	}{
+		{
+			name:       "not_enough_args",
+			args:       []string{"kv/foo"},
+			outStrings: []string{"Invalid number of arguments"},
+			code:       1,
+		},
+		{
+			name:       "default",
+			args:       []string{"kv/foo", "kv/bar"},
+			outStrings: []string{"foo", "bar", "1"},
+			code:       0,
+		},
+		{
+			name:       "with_mount",
+			args:       []string{"-mount", "kv", "foo", "bar"},
+			outStrings: []string{"foo", "bar", "1"},
+			code:       0,
+		},
+		{
+			name:       "recursive",
+			args:       []string{"-mount", "kv", "-recursive", "my-prefix", "other-prefix"},
+			outStrings: []string{"my-prefix/secret", "other-prefix/secret", "1"},
+			code:       0,
+		},
+		{
+			name:       "different_mounts",
+			args:       []string{"kv/foo", "secret/bar"},
+			outStrings: []string{"Source and destination must be within the same K/V mount"},
+			code:       1,
+		},
+		{
+			name:       "not_found",
+			args:       []string{"kv/nope", "kv/bar"},
+			outStrings: []string{"no secret found"},
+			code:       2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, closer := testVaultServer(t)
+			defer closer()
+
+			if err := client.Sys().Mount("kv/", &api.MountInput{
+				Type: "kv-v2",
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, path := range []string{"kv/foo", "kv/my-prefix/secret"} {
+				if code, combined := kvPutWithRetry(t, client, []string{path, "foo=bar"}); code != 0 {
+					t.Fatalf("write failed, expected %d to be 0, output: %s", code, combined)
+				}
+			}
+
+			ui, cmd := testKVCopyCommand(t)
+			cmd.client = client
+
+			code := cmd.Run(testCase.args)
+			if code != testCase.code {
+				t.Errorf("expected %d to be %d", code, testCase.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			for _, str := range testCase.outStrings {
+				if !strings.Contains(combined, str) {
+					t.Errorf("expected %q to contain %q", combined, str)
+				}
+			}
+		})
+	}
+}
+
+func testKVSearchCommand(tb testing.TB) (*cli.MockUi, *KVSearchCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &KVSearchCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestKVSearchCommand(t *testing.T) {
+	testCases := []struct {
+		name       string
+		args       []string
+		outStrings []string
+		code       int
+	}{
+		{
+			name:       "not_enough_args",
+			args:       []string{},
+			outStrings: []string{"Not enough arguments"},
+			code:       1,
+		},
+		{
+			name:       "too_many_args",
+			args:       []string{"-mount", "kv", "foo"},
+			outStrings: []string{"Too many arguments"},
+			code:       1,
+		},
+		{
+			name:       "default",
+			args:       []string{"kv"},
+			outStrings: []string{"foo", "my-prefix/secret"},
+			code:       0,
+		},
+		{
+			name:       "custom_metadata",
+			args:       []string{"-mount", "kv", "-custom-metadata", "owner=payments"},
+			outStrings: []string{"my-prefix/secret"},
+			code:       0,
+		},
+		{
+			name:       "not_found",
+			args:       []string{"-min-version-age", "24h", "kv"},
+			outStrings: []string{"No secrets found"},
+			code:       2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, closer := testVaultServer(t)
+			defer closer()
+
+			if err := client.Sys().Mount("kv/", &api.MountInput{
+				Type: "kv-v2",
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, path := range []string{"kv/foo", "kv/my-prefix/secret"} {
+				if code, combined := kvPutWithRetry(t, client, []string{path, "foo=bar"}); code != 0 {
+					t.Fatalf("write failed, expected %d to be 0, output: %s", code, combined)
+				}
+			}
+			if _, err := client.Logical().Write("kv/metadata/my-prefix/secret", map[string]interface{}{
+				"custom_metadata": map[string]interface{}{"owner": "payments"},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			ui, cmd := testKVSearchCommand(t)
+			cmd.client = client
+
+			code := cmd.Run(testCase.args)
+			if code != testCase.code {
+				t.Errorf("expected %d to be %d", code, testCase.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			for _, str := range testCase.outStrings {
+				if !strings.Contains(combined, str) {
+					t.Errorf("expected %q to contain %q", combined, str)
+				}
+			}
+		})
+	}
+}
+
+func testKVExportCommand(tb testing.TB) (*cli.MockUi, *KVExportCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &KVExportCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func testKVImportCommand(tb testing.TB) (*cli.MockUi, *KVImportCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &KVImportCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestKVExportImportCommand(t *testing.T) {
+	t.Parallel()
+
+	client, closer := testVaultServer(t)
+	defer closer()
+
+	if err := client.Sys().Mount("kv/", &api.MountInput{
+		Type: "kv-v2",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{"foo=bar", "foo=baz"} {
+		if code, combined := kvPutWithRetry(t, client, []string{"kv/app/secret", value}); code != 0 {
+			t.Fatalf("write failed, expected %d to be 0, output: %s", code, combined)
+		}
+	}
+
+	ui, cmd := testKVExportCommand(t)
+	cmd.client = client
+	code := cmd.Run([]string{"-mount", "kv", "app"})
+	if code != 1 {
+		t.Errorf("expected %d to be 1", code)
+	}
+	if combined := ui.OutputWriter.String() + ui.ErrorWriter.String(); !strings.Contains(combined, "Exactly one of -pgp-keys or -passphrase") {
+		t.Errorf("expected %q to require encryption", combined)
+	}
+
+	output := filepath.Join(t.TempDir(), "app.archive")
+	ui, cmd = testKVExportCommand(t)
+	cmd.client = client
+	code = cmd.Run([]string{"-passphrase", "correct horse", "-output", output, "kv/app"})
+	if code != 0 {
+		t.Fatalf("expected %d to be 0, output: %s", code, ui.OutputWriter.String()+ui.ErrorWriter.String())
+	}
+	if combined := ui.OutputWriter.String(); !strings.Contains(combined, "Exported 1 secrets") {
+		t.Errorf("expected %q to report the exported secrets", combined)
+	}
+
+	testCases := []struct {
+		name       string
+		args       []string
+		outStrings []string
+		code       int
+	}{
+		{
+			name:       "not_enough_args",
+			args:       []string{output},
+			outStrings: []string{"Invalid number of arguments"},
+			code:       1,
+		},
+		{
+			name:       "wrong_passphrase",
+			args:       []string{"-passphrase", "battery staple", "kv/wrong", output},
+			outStrings: []string{"incorrect passphrase"},
+			code:       2,
+		},
+		{
+			name:       "default",
+			args:       []string{"-passphrase", "correct horse", "kv/copy", output},
+			outStrings: []string{"copy/secret", "2"},
+			code:       0,
+		},
+		{
+			name:       "flatten",
+			args:       []string{"-passphrase", "correct horse", "-flatten", "-mount", "kv", "app", output},
+			outStrings: []string{"app/secret", "3"},
+			code:       0,
+		},
+	}
+
+	// Archives encrypted to PGP keys are decrypted with the private key.
+	dir := t.TempDir()
+	pubKey, err := base64.StdEncoding.DecodeString(pgpkeys.TestPubKey1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKeyFile := filepath.Join(dir, "pubkey")
+	if err := os.WriteFile(pubKeyFile, pubKey, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	privKeyFile := filepath.Join(dir, "privkey")
+	if err := os.WriteFile(privKeyFile, []byte(pgpkeys.TestPrivKey1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pgpOutput := filepath.Join(dir, "pgp.archive")
+	ui, cmd = testKVExportCommand(t)
+	cmd.client = client
+	code = cmd.Run([]string{"-pgp-keys", pubKeyFile, "-output", pgpOutput, "kv/app"})
+	if code != 0 {
+		t.Fatalf("expected %d to be 0, output: %s", code, ui.OutputWriter.String()+ui.ErrorWriter.String())
+	}
+
+	testCases = append(testCases, []struct {
+		name       string
+		args       []string
+		outStrings []string
+		code       int
+	}{
+		{
+			name:       "pgp_without_key",
+			args:       []string{"kv/pgp", pgpOutput},
+			outStrings: []string{"encrypted to PGP keys"},
+			code:       2,
+		},
+		{
+			name:       "pgp_and_passphrase",
+			args:       []string{"-pgp-key", privKeyFile, "-passphrase", "correct horse", "kv/pgp", pgpOutput},
+			outStrings: []string{"Only one of -pgp-key or -passphrase"},
+			code:       1,
+		},
+		{
+			name:       "pgp",
+			args:       []string{"-pgp-key", privKeyFile, "kv/pgp", pgpOutput},
+			outStrings: []string{"pgp/secret", "2"},
+			code:       0,
+		},
+	}...)
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ui, cmd := testKVImportCommand(t)
+			cmd.client = client
+
+			code := cmd.Run(testCase.args)
+			if code != testCase.code {
+				t.Errorf("expected %d to be %d", code, testCase.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			for _, str := range testCase.outStrings {
+				if !strings.Contains(combined, str) {
+					t.Errorf("expected %q to contain %q", combined, str)
+				}
+			}
+		})
+	}
+}
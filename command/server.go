@@ -1071,6 +1071,19 @@ func (c *ServerCommand) Run(args []string) int {
 	}
 	metricsHelper := metricsutil.NewMetricsHelper(inmemMetrics, prometheusEnabled)
 
+	shutdownTracing, err := configutil.SetupTracing(context.Background(), config.Telemetry)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error initializing tracing: %s", err))
+		return 1
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			c.logger.Warn("failed to flush traces", "error", err)
+		}
+	}()
+
 	// Initialize the storage backend
 	var backend physical.Backend
 	if !c.flagDev || config.Storage != nil {
@@ -2311,7 +2324,9 @@ func setSeal(c *ServerCommand, config *server.Config, infoKeys []string, info ma
 		}
 	}
 	var createdSeals []vault.Seal = make([]vault.Seal, len(config.Seals))
-	for _, configSeal := range config.Seals {
+	var sealWrappers []*vaultseal.SealWrapper
+	var skippedSealErrs *multierror.Error
+	for i, configSeal := range config.Seals {
 		sealType := wrapping.WrapperTypeShamir.String()
 		if !configSeal.Disabled && api.ReadBaoVariable("BAO_SEAL_TYPE") != "" {
 			sealType = api.ReadBaoVariable("BAO_SEAL_TYPE")
@@ -2327,6 +2342,36 @@ func setSeal(c *ServerCommand, config *server.Config, infoKeys []string, info ma
 		var sealInfoKeys []string
 		sealInfoMap := map[string]string{}
 		wrapper, sealConfigError = configutil.ConfigureWrapper(configSeal, &sealInfoKeys, &sealInfoMap, sealLogger)
+		if config.EnableMultiSeal && !configSeal.Disabled {
+			// With several seals, a seal which cannot be configured, for
+			// instance because its KMS is unreachable, is skipped as long
+			// as another seal can be used.
+			name := configSeal.Name
+			if name == "" {
+				name = sealType
+			}
+			if sealConfigError != nil {
+				sealLogger.Error("failed to configure seal, continuing without it", "name", name, "error", sealConfigError)
+				c.UI.Warn(fmt.Sprintf("WARNING! Seal %q could not be configured and will not be used: %s", name, sealConfigError))
+				skippedSealErrs = multierror.Append(skippedSealErrs, fmt.Errorf("seal %q: %w", name, sealConfigError))
+				sealConfigError = nil
+				continue
+			}
+			priority := configSeal.Priority
+			if priority == 0 {
+				priority = i + 1
+			}
+			sealWrappers = append(sealWrappers, &vaultseal.SealWrapper{
+				Wrapper:  wrapper,
+				Name:     name,
+				Priority: priority,
+			})
+			for _, k := range sealInfoKeys {
+				infoKeys = append(infoKeys, fmt.Sprintf("Seal %q %s", name, k))
+				info[fmt.Sprintf("Seal %q %s", name, k)] = sealInfoMap[k]
+			}
+			continue
+		}
 		if sealConfigError != nil {
 			if !errwrap.ContainsType(sealConfigError, new(logical.KeyNotFoundError)) {
 				return barrierSeal, barrierWrapper, unwrapSeal, createdSeals, sealConfigError, fmt.Errorf(
@@ -2356,6 +2401,25 @@ func setSeal(c *ServerCommand, config *server.Config, infoKeys []string, info ma
 		}
 		createdSeals = append(createdSeals, seal)
 	}
+
+	if config.EnableMultiSeal {
+		if len(sealWrappers) == 0 {
+			if skippedSealErrs != nil {
+				return nil, nil, nil, nil, nil, fmt.Errorf("Error configuring seals: none of the seals could be configured: %w", skippedSealErrs)
+			}
+			return nil, nil, nil, nil, nil, errors.New("Error configuring seals: no enabled seal is configured")
+		}
+		access, err := vaultseal.NewMultiAccess(sealWrappers)
+		if err != nil {
+			return nil, nil, nil, nil, nil, fmt.Errorf("Error configuring seals: %w", err)
+		}
+		barrierSeal, err = vault.NewAutoSeal(access)
+		if err != nil {
+			return nil, nil, nil, nil, nil, err
+		}
+		barrierWrapper = access
+		createdSeals = append(createdSeals, barrierSeal)
+	}
 	return barrierSeal, barrierWrapper, unwrapSeal, createdSeals, sealConfigError, nil
 }
 
@@ -2589,9 +2653,20 @@ func createCoreConfig(c *ServerCommand, config *server.Config, backend physical.
 		SecureRandomReader:             secureRandomReader,
 		EnableResponseHeaderHostname:   config.EnableResponseHeaderHostname,
 		EnableResponseHeaderRaftNodeID: config.EnableResponseHeaderRaftNodeID,
+		EnableStandbyReads:             config.EnableStandbyReads,
 		AdministrativeNamespacePath:    config.AdministrativeNamespacePath,
 	}
 
+	for _, l := range config.KMSLibraries {
+		if l.Type != "pkcs11" {
+			continue
+		}
+		if coreConfig.PKCS11Libraries == nil {
+			coreConfig.PKCS11Libraries = make(map[string]string)
+		}
+		coreConfig.PKCS11Libraries[l.Name] = l.Library
+	}
+
 	if config.DisableSSCTokens != nil {
 		coreConfig.DisableSSCTokens = *config.DisableSSCTokens
 	} else {
@@ -11,6 +11,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +21,7 @@ import (
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/mitchellh/mapstructure"
+	wrapping "github.com/openbao/go-kms-wrapping/v2"
 	"github.com/openbao/openbao/api/v2"
 	"github.com/openbao/openbao/helper/osutil"
 	"github.com/openbao/openbao/internalshared/configutil"
@@ -49,6 +51,8 @@ type Config struct {
 
 	ServiceRegistration *ServiceRegistration `hcl:"-"`
 
+	KMSLibraries []*KMSLibrary `hcl:"-"`
+
 	CacheSize                int         `hcl:"cache_size"`
 	DisableCache             bool        `hcl:"-"`
 	DisableCacheRaw          interface{} `hcl:"disable_cache"`
@@ -98,6 +102,12 @@ type Config struct {
 	EnableResponseHeaderHostname    bool        `hcl:"-"`
 	EnableResponseHeaderHostnameRaw interface{} `hcl:"enable_response_header_hostname"`
 
+	EnableMultiSeal    bool        `hcl:"-"`
+	EnableMultiSealRaw interface{} `hcl:"enable_multiseal"`
+
+	EnableStandbyReads    bool        `hcl:"-"`
+	EnableStandbyReadsRaw interface{} `hcl:"enable_standby_reads"`
+
 	LogRequestsLevel    string      `hcl:"-"`
 	LogRequestsLevelRaw interface{} `hcl:"log_requests_level"`
 
@@ -123,6 +133,9 @@ func (c *Config) Validate(sourceFilePath string) []configutil.ConfigError {
 	if c.ServiceRegistration != nil {
 		results = append(results, c.ServiceRegistration.Validate(sourceFilePath)...)
 	}
+	for _, l := range c.KMSLibraries {
+		results = append(results, l.Validate(sourceFilePath)...)
+	}
 	for _, l := range c.Listeners {
 		results = append(results, l.Validate(sourceFilePath)...)
 	}
@@ -249,6 +262,24 @@ func (b *ServiceRegistration) GoString() string {
 	return fmt.Sprintf("*%#v", *b)
 }
 
+// KMSLibrary is a library, such as a PKCS#11 module, which managed keys may
+// reference by name. Only libraries listed in the server configuration are
+// ever loaded.
+type KMSLibrary struct {
+	UnusedKeys configutil.UnusedKeyMap `hcl:",unusedKeyPositions"`
+	Type       string                  `hcl:"-"`
+	Name       string                  `hcl:"name"`
+	Library    string                  `hcl:"library"`
+}
+
+func (l *KMSLibrary) Validate(source string) []configutil.ConfigError {
+	return configutil.ValidateUnusedFields(l.UnusedKeys, source)
+}
+
+func (l *KMSLibrary) GoString() string {
+	return fmt.Sprintf("*%#v", *l)
+}
+
 func NewConfig() *Config {
 	return &Config{
 		SharedConfig: new(configutil.SharedConfig),
@@ -283,6 +314,17 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.ServiceRegistration = c2.ServiceRegistration
 	}
 
+	// Libraries of the second configuration replace those of the same
+	// type and name
+	for _, l := range c.KMSLibraries {
+		if !slices.ContainsFunc(c2.KMSLibraries, func(l2 *KMSLibrary) bool {
+			return l2.Type == l.Type && l2.Name == l.Name
+		}) {
+			result.KMSLibraries = append(result.KMSLibraries, l)
+		}
+	}
+	result.KMSLibraries = append(result.KMSLibraries, c2.KMSLibraries...)
+
 	result.CacheSize = c.CacheSize
 	if c2.CacheSize != 0 {
 		result.CacheSize = c2.CacheSize
@@ -389,6 +431,16 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.EnableResponseHeaderHostname = c2.EnableResponseHeaderHostname
 	}
 
+	result.EnableMultiSeal = c.EnableMultiSeal
+	if c2.EnableMultiSeal {
+		result.EnableMultiSeal = c2.EnableMultiSeal
+	}
+
+	result.EnableStandbyReads = c.EnableStandbyReads
+	if c2.EnableStandbyReads {
+		result.EnableStandbyReads = c2.EnableStandbyReads
+	}
+
 	result.LogRequestsLevel = c.LogRequestsLevel
 	if c2.LogRequestsLevel != "" {
 		result.LogRequestsLevel = c2.LogRequestsLevel
@@ -482,12 +534,43 @@ func CheckConfig(c *Config, e error) (*Config, error) {
 		return c, e
 	}
 
+	if c.EnableMultiSeal {
+		var enabled, disabled int
+		names := make(map[string]struct{}, len(c.Seals))
+		for _, seal := range c.Seals {
+			if seal.Disabled {
+				disabled++
+				continue
+			}
+			enabled++
+
+			if seal.Type == wrapping.WrapperTypeShamir.String() {
+				return nil, errors.New("seals: shamir seals cannot be used with enable_multiseal")
+			}
+			name := seal.Name
+			if name == "" {
+				name = seal.Type
+			}
+			if _, ok := names[name]; ok {
+				return nil, fmt.Errorf("seals: duplicate seal name %q; seals of the same type must be given a name", name)
+			}
+			names[name] = struct{}{}
+		}
+		switch {
+		case enabled == 0 && len(c.Seals) > 0:
+			return nil, errors.New("seals: all seals provided are disabled")
+		case disabled > 1:
+			return nil, errors.New("seals: only one disabled seal may be provided")
+		}
+		return c, nil
+	}
+
 	if len(c.Seals) == 2 {
 		switch {
 		case c.Seals[0].Disabled && c.Seals[1].Disabled:
 			return nil, errors.New("seals: two seals provided but both are disabled")
 		case !c.Seals[0].Disabled && !c.Seals[1].Disabled:
-			return nil, errors.New("seals: two seals provided but neither is disabled")
+			return nil, errors.New("seals: two seals provided but neither is disabled; set enable_multiseal to use several seals at once")
 		}
 	}
 
@@ -672,6 +755,18 @@ func ParseConfig(d, source string) (*Config, error) {
 		}
 	}
 
+	if result.EnableMultiSealRaw != nil {
+		if result.EnableMultiSeal, err = parseutil.ParseBool(result.EnableMultiSealRaw); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.EnableStandbyReadsRaw != nil {
+		if result.EnableStandbyReads, err = parseutil.ParseBool(result.EnableStandbyReadsRaw); err != nil {
+			return nil, err
+		}
+	}
+
 	if result.LogRequestsLevelRaw != nil {
 		result.LogRequestsLevel = strings.ToLower(strings.TrimSpace(result.LogRequestsLevelRaw.(string)))
 		result.LogRequestsLevelRaw = ""
@@ -733,6 +828,13 @@ func ParseConfig(d, source string) (*Config, error) {
 		}
 	}
 
+	if o := list.Filter("kms_library"); len(o.Items) > 0 {
+		delete(result.UnusedKeys, "kms_library")
+		if err := parseKMSLibraries(result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'kms_library': %w", err)
+		}
+	}
+
 	// Remove all unused keys from Config that were satisfied by SharedConfig.
 	result.UnusedKeys = configutil.UnusedFieldDifference(result.UnusedKeys, nil, append(result.FoundKeys, sharedConfig.FoundKeys...))
 	// Assign file info
@@ -1008,6 +1110,39 @@ func parseServiceRegistration(result *Config, list *ast.ObjectList, name string)
 	return nil
 }
 
+func parseKMSLibraries(result *Config, list *ast.ObjectList) error {
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return errors.New("kms_library must have exactly one type")
+		}
+		libType := strings.ToLower(item.Keys[0].Token.Value().(string))
+		if libType != "pkcs11" {
+			return fmt.Errorf("unsupported kms_library type %q", libType)
+		}
+
+		var l KMSLibrary
+		if err := hcl.DecodeObject(&l, item.Val); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("kms_library.%s:", libType))
+		}
+		l.Type = libType
+
+		switch {
+		case l.Name == "":
+			return fmt.Errorf("kms_library.%s: missing name", libType)
+		case l.Library == "":
+			return fmt.Errorf("kms_library.%s.%s: missing library", libType, l.Name)
+		}
+		for _, existing := range result.KMSLibraries {
+			if existing.Type == l.Type && existing.Name == l.Name {
+				return fmt.Errorf("kms_library.%s.%s: duplicate name", libType, l.Name)
+			}
+		}
+
+		result.KMSLibraries = append(result.KMSLibraries, &l)
+	}
+	return nil
+}
+
 // Sanitized returns a copy of the config with all values that are considered
 // sensitive stripped. It also strips all `*Raw` values that are mainly
 // used for parsing.
@@ -1059,6 +1194,10 @@ func (c *Config) Sanitized() map[string]interface{} {
 
 		"enable_response_header_hostname": c.EnableResponseHeaderHostname,
 
+		"enable_multiseal": c.EnableMultiSeal,
+
+		"enable_standby_reads": c.EnableStandbyReads,
+
 		"enable_response_header_raft_node_id": c.EnableResponseHeaderRaftNodeID,
 
 		"log_requests_level": c.LogRequestsLevel,
@@ -1117,6 +1256,19 @@ func (c *Config) Sanitized() map[string]interface{} {
 		result["service_registration"] = sanitizedServiceRegistration
 	}
 
+	// Sanitize kms_library stanzas
+	if len(c.KMSLibraries) > 0 {
+		sanitizedKMSLibraries := make([]interface{}, 0, len(c.KMSLibraries))
+		for _, l := range c.KMSLibraries {
+			sanitizedKMSLibraries = append(sanitizedKMSLibraries, map[string]interface{}{
+				"type":    l.Type,
+				"name":    l.Name,
+				"library": l.Library,
+			})
+		}
+		result["kms_library"] = sanitizedKMSLibraries
+	}
+
 	return result
 }
 
@@ -58,6 +58,14 @@ func TestParseSeals(t *testing.T) {
 	testParseSeals(t)
 }
 
+func TestParseMultiSeal(t *testing.T) {
+	testParseMultiSeal(t)
+}
+
+func TestParseKMSLibraries(t *testing.T) {
+	testParseKMSLibraries(t)
+}
+
 func TestParseStorage(t *testing.T) {
 	testParseStorageTemplate(t)
 }
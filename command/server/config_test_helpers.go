@@ -710,6 +710,8 @@ func testConfig_Sanitized(t *testing.T) {
 		"detect_deadlocks":                    "",
 		"enable_ui":                           true,
 		"enable_response_header_hostname":     false,
+		"enable_multiseal":                    false,
+		"enable_standby_reads":                false,
 		"enable_response_header_raft_node_id": false,
 		"log_requests_level":                  "basic",
 		"ha_storage": map[string]interface{}{
@@ -1080,6 +1082,83 @@ func testParseSeals(t *testing.T) {
 	require.Equal(t, config, expected)
 }
 
+func testParseMultiSeal(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/config_multiseal.hcl", nil)
+	require.NoError(t, err)
+	config, err = CheckConfig(config, nil)
+	require.NoError(t, err)
+
+	require.True(t, config.EnableMultiSeal)
+	require.Len(t, config.Seals, 2)
+	for i, name := range []string{"primary", "secondary"} {
+		seal := config.Seals[i]
+		require.Equal(t, "transit", seal.Type)
+		require.Equal(t, name, seal.Name)
+		require.Equal(t, i+1, seal.Priority)
+		require.False(t, seal.Disabled)
+		require.NotContains(t, seal.Config, "name")
+		require.NotContains(t, seal.Config, "priority")
+	}
+
+	// Seals of the same type must be named apart.
+	config.Seals[1].Name = "primary"
+	_, err = CheckConfig(config, nil)
+	require.Error(t, err)
+
+	// Without enable_multiseal, two enabled seals are a configuration error.
+	config.Seals[1].Name = "secondary"
+	config.EnableMultiSeal = false
+	_, err = CheckConfig(config, nil)
+	require.Error(t, err)
+}
+
+func testParseKMSLibraries(t *testing.T) {
+	config, err := LoadConfigFile("./test-fixtures/config_kms_library.hcl", nil)
+	require.NoError(t, err)
+	require.Empty(t, config.Validate("config_kms_library.hcl"))
+
+	require.Equal(t, []*KMSLibrary{
+		{Type: "pkcs11", Name: "softhsm", Library: "/usr/lib/softhsm/libsofthsm2.so"},
+		{Type: "pkcs11", Name: "luna", Library: "/usr/safenet/lunaclient/lib/libCryptoki2_64.so"},
+	}, config.KMSLibraries)
+
+	// Later configuration files replace libraries of the same name.
+	merged := config.Merge(&Config{
+		SharedConfig: new(configutil.SharedConfig),
+		KMSLibraries: []*KMSLibrary{
+			{Type: "pkcs11", Name: "softhsm", Library: "/usr/lib64/softhsm/libsofthsm2.so"},
+		},
+	})
+	require.Equal(t, []*KMSLibrary{
+		{Type: "pkcs11", Name: "luna", Library: "/usr/safenet/lunaclient/lib/libCryptoki2_64.so"},
+		{Type: "pkcs11", Name: "softhsm", Library: "/usr/lib64/softhsm/libsofthsm2.so"},
+	}, merged.KMSLibraries)
+
+	_, err = ParseConfig(`
+kms_library "pkcs11" {
+  name    = "softhsm"
+  library = "/usr/lib/softhsm/libsofthsm2.so"
+}
+kms_library "pkcs11" {
+  name    = "softhsm"
+  library = "/usr/lib64/softhsm/libsofthsm2.so"
+}`, "")
+	require.ErrorContains(t, err, "duplicate name")
+
+	_, err = ParseConfig(`
+kms_library "pkcs11" {
+  library = "/usr/lib/softhsm/libsofthsm2.so"
+}`, "")
+	require.ErrorContains(t, err, "missing name")
+
+	_, err = ParseConfig(`
+kms_library "kmip" {
+  name    = "kmip"
+  library = "/usr/lib/kmip.so"
+}`, "")
+	require.ErrorContains(t, err, "unsupported kms_library type")
+}
+
 func testLoadConfigFileLeaseMetrics(t *testing.T) {
 	config, err := LoadConfigFile("./test-fixtures/config5.hcl", nil)
 	if err != nil {
@@ -0,0 +1,19 @@
+# Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+# SPDX-License-Identifier: MPL-2.0
+
+listener "tcp" {
+  address = "127.0.0.1:443"
+}
+
+backend "consul" {
+}
+
+kms_library "pkcs11" {
+  name    = "softhsm"
+  library = "/usr/lib/softhsm/libsofthsm2.so"
+}
+
+kms_library "pkcs11" {
+  name    = "luna"
+  library = "/usr/safenet/lunaclient/lib/libCryptoki2_64.so"
+}
@@ -0,0 +1,27 @@
+# Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+# SPDX-License-Identifier: MPL-2.0
+
+listener "tcp" {
+  address = "127.0.0.1:443"
+}
+
+backend "consul" {
+}
+
+enable_multiseal = true
+
+seal "transit" {
+  name = "primary"
+  priority = 1
+  address = "https://bao-a.example.com:8200"
+  key_name = "autounseal"
+  mount_path = "transit/"
+}
+
+seal "transit" {
+  name = "secondary"
+  priority = "2"
+  address = "https://bao-b.example.com:8200"
+  key_name = "autounseal"
+  mount_path = "transit/"
+}
@@ -13,7 +13,9 @@ package command
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
@@ -21,8 +23,13 @@ import (
 	"time"
 
 	"github.com/hashicorp/cli"
+	"github.com/hashicorp/go-hclog"
+	wrapping "github.com/openbao/go-kms-wrapping/v2"
+	"github.com/openbao/openbao/command/server"
+	"github.com/openbao/openbao/internalshared/configutil"
 	"github.com/openbao/openbao/sdk/v2/physical"
 	physInmem "github.com/openbao/openbao/sdk/v2/physical/inmem"
+	vaultseal "github.com/openbao/openbao/vault/seal"
 	"github.com/stretchr/testify/require"
 )
 
@@ -376,3 +383,38 @@ func TestConfigureDevTLS(t *testing.T) {
 		require.Equal(t, testcase.ErrNotNil, (err != nil), "test description %s", testcase.TestDescription)
 	}
 }
+
+func TestSetSeal_MultiSealSkipsFailedSeals(t *testing.T) {
+	ui, cmd := testServerCommand(t)
+	cmd.logger = hclog.NewInterceptLogger(&hclog.LoggerOptions{Output: io.Discard})
+
+	aeadSeal := func(name string) *configutil.KMS {
+		return &configutil.KMS{
+			Type: wrapping.WrapperTypeAead.String(),
+			Name: name,
+			Config: map[string]string{
+				"key":    base64.StdEncoding.EncodeToString(make([]byte, 32)),
+				"key_id": name,
+			},
+		}
+	}
+	brokenSeal := func(name string) *configutil.KMS {
+		return &configutil.KMS{Type: "nonexistent", Name: name}
+	}
+
+	config := server.NewConfig()
+	config.EnableMultiSeal = true
+	config.Seals = []*configutil.KMS{aeadSeal("primary"), brokenSeal("secondary")}
+	barrierSeal, _, _, _, _, err := setSeal(cmd, config, nil, make(map[string]string))
+	require.NoError(t, err)
+	require.Equal(t, vaultseal.WrapperTypeMultiSeal, barrierSeal.BarrierType())
+	require.Contains(t, ui.ErrorWriter.String(), `Seal "secondary" could not be configured`)
+
+	config = server.NewConfig()
+	config.EnableMultiSeal = true
+	config.Seals = []*configutil.KMS{brokenSeal("primary"), brokenSeal("secondary")}
+	_, _, _, _, _, err = setSeal(cmd, config, nil, make(map[string]string))
+	require.ErrorContains(t, err, "none of the seals could be configured")
+	require.ErrorContains(t, err, `seal "primary"`)
+	require.ErrorContains(t, err, `seal "secondary"`)
+}
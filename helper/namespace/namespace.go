@@ -18,6 +18,10 @@ type Namespace struct {
 	ID             string            `json:"id" mapstructure:"id"`
 	Path           string            `json:"path" mapstructure:"path"`
 	CustomMetadata map[string]string `json:"custom_metadata" mapstructure:"custom_metadata"`
+
+	// UnlockKey is set while API access to the namespace and its
+	// descendants is locked, and is required to unlock it again.
+	UnlockKey string `json:"unlock_key,omitempty" mapstructure:"unlock_key"`
 }
 
 func (n *Namespace) String() string {
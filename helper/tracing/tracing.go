@@ -0,0 +1,118 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+// Package tracing wraps the OpenTelemetry API used to instrument request
+// handling. Until a tracer provider is installed by the server, the global
+// provider is a no-op and starting spans is cheap.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by OpenBao.
+const instrumentationName = "github.com/openbao/openbao"
+
+// Attribute keys shared across spans.
+const (
+	AttrMountType   = attribute.Key("openbao.mount.type")
+	AttrMountPoint  = attribute.Key("openbao.mount.point")
+	AttrNamespace   = attribute.Key("openbao.namespace")
+	AttrOperation   = attribute.Key("openbao.operation")
+	AttrPath        = attribute.Key("openbao.path")
+	AttrAuditDevice = attribute.Key("openbao.audit.device")
+	AttrHTTPMethod  = attribute.Key("http.request.method")
+	AttrHTTPPath    = attribute.Key("url.path")
+	AttrHTTPStatus  = attribute.Key("http.response.status_code")
+)
+
+// propagator reads and writes W3C trace context headers, such as
+// traceparent, regardless of the globally installed propagator.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// trustRemoteParent controls whether incoming requests continue the trace
+// described by their headers. Clients are not trusted by default, as the
+// sampling decision of a trace would otherwise be theirs to make.
+var trustRemoteParent atomic.Bool
+
+// trustedParentKey marks contexts of requests whose trace context is always
+// trusted, such as requests forwarded by other nodes of the cluster.
+type trustedParentKey struct{}
+
+// SetTrustRemoteParent sets whether incoming requests continue the trace
+// described by their headers, following its sampling decision.
+func SetTrustRemoteParent(trust bool) {
+	trustRemoteParent.Store(trust)
+}
+
+// ContextWithTrustedParent marks the context of a request whose trace
+// context comes from a trusted source, so that it is continued regardless
+// of SetTrustRemoteParent.
+func ContextWithTrustedParent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trustedParentKey{}, true)
+}
+
+// Tracer returns the tracer used to create OpenBao spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartSpan starts a span as a child of the span in the context, if any.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServerSpan starts a span for an incoming request. If the trace
+// context in the request headers is trusted, the span continues that trace.
+// Otherwise it starts a new trace, subject to the local sampler only, and
+// links to the caller's span.
+func StartServerSpan(ctx context.Context, header http.Header, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...)}
+
+	carrier := propagation.HeaderCarrier(header)
+	if trusted, _ := ctx.Value(trustedParentKey{}).(bool); trusted || trustRemoteParent.Load() {
+		ctx = propagator.Extract(ctx, carrier)
+	} else if remote := trace.SpanContextFromContext(propagator.Extract(context.Background(), carrier)); remote.IsValid() {
+		opts = append(opts, trace.WithNewRoot(), trace.WithLinks(trace.Link{SpanContext: remote}))
+	}
+
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// StartClientSpan starts a span for an outgoing request.
+func StartClientSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// Inject writes the trace context of the span in the context to the
+// headers, so that the receiver continues the same trace.
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// EndSpan records the error, if any, on the span and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// EndServerSpan records the status code of the response to an incoming
+// request on the span and ends it. Server errors mark the span as failed.
+func EndServerSpan(span trace.Span, statusCode int) {
+	span.SetAttributes(AttrHTTPStatus.Int(statusCode))
+	if statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+	span.End()
+}
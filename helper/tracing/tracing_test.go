@@ -0,0 +1,125 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	return recorder
+}
+
+func TestTracing_Propagation(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	header := make(http.Header)
+	header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	ctx, server := StartServerSpan(ContextWithTrustedParent(context.Background()), header, "http.request")
+	_, child := StartSpan(ctx, "core.handleRequest")
+	EndSpan(child, errors.New("permission denied"))
+
+	// The context forwarded to another node carries the same trace
+	forwarded := make(http.Header)
+	Inject(ctx, forwarded)
+	require.Contains(t, forwarded.Get("traceparent"), traceID)
+
+	EndServerSpan(server, http.StatusOK)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "core.handleRequest", spans[0].Name())
+	require.Equal(t, traceID, spans[0].SpanContext().TraceID().String())
+	require.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+
+	require.Equal(t, "http.request", spans[1].Name())
+	require.Equal(t, traceID, spans[1].SpanContext().TraceID().String())
+	require.True(t, spans[1].Parent().IsRemote())
+	require.Equal(t, codes.Unset, spans[1].Status().Code)
+}
+
+func TestTracing_UntrustedParent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0))),
+	)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	header := make(http.Header)
+	header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	// A client asking for its trace to be sampled cannot override the
+	// local sampler
+	_, span := StartServerSpan(context.Background(), header, "http.request")
+	EndServerSpan(span, http.StatusOK)
+	require.Empty(t, recorder.Ended())
+
+	// unless remote parents are trusted
+	SetTrustRemoteParent(true)
+	t.Cleanup(func() { SetTrustRemoteParent(false) })
+	_, span = StartServerSpan(context.Background(), header, "http.request")
+	EndServerSpan(span, http.StatusOK)
+	require.Len(t, recorder.Ended(), 1)
+	require.Equal(t, traceID, recorder.Ended()[0].SpanContext().TraceID().String())
+}
+
+func TestTracing_UntrustedParentLink(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	header := make(http.Header)
+	header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	_, span := StartServerSpan(context.Background(), header, "http.request")
+	EndServerSpan(span, http.StatusOK)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.False(t, spans[0].Parent().IsValid())
+	require.NotEqual(t, traceID, spans[0].SpanContext().TraceID().String())
+	require.Len(t, spans[0].Links(), 1)
+	require.Equal(t, traceID, spans[0].Links()[0].SpanContext.TraceID().String())
+}
+
+func TestTracing_ServerError(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	_, span := StartServerSpan(context.Background(), http.Header{}, "http.request")
+	EndServerSpan(span, http.StatusInternalServerError)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.False(t, spans[0].Parent().IsValid())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+}
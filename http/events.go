@@ -0,0 +1,183 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/errwrap"
+	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+)
+
+// eventsSubscribePrefix is the path prefix, relative to the namespace of the
+// request, on which clients subscribe to events.
+const eventsSubscribePrefix = "sys/events/subscribe/"
+
+// eventsRevalidateInterval is the interval at which the token of an open
+// subscription is checked again, so that streams of revoked or expired
+// tokens are closed.
+var eventsRevalidateInterval = 5 * time.Second
+
+var eventsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// isEventsSubscribeRequest returns whether the request subscribes to the
+// event stream rather than being routed to a backend.
+func isEventsSubscribeRequest(req *logical.Request) bool {
+	return req.Operation == logical.ReadOperation && strings.HasPrefix(req.Path, eventsSubscribePrefix)
+}
+
+// handleEventsSubscribe streams events matching the requested pattern to the
+// client, either over a WebSocket if the client asked for an upgrade or as
+// server-sent events otherwise. The stream ends when the client goes away or
+// the core is sealed or the token is no longer valid.
+func handleEventsSubscribe(core *vault.Core, w http.ResponseWriter, r *http.Request, req *logical.Request) {
+	// The subscription is authorized, audited and subject to quotas like
+	// any other request before the stream is opened.
+	resp, err := core.HandleRequest(r.Context(), req)
+	if errwrap.Contains(err, consts.ErrStandby.Error()) {
+		forwardRequest(core, w, r)
+		return
+	}
+	if respondErrorCommon(w, req, resp, err) {
+		return
+	}
+
+	sub, err := core.SubscribeEvents(r.Context(), req)
+	if err != nil {
+		if errwrap.Contains(err, consts.ErrStandby.Error()) {
+			forwardRequest(core, w, r)
+			return
+		}
+		respondErrorCommon(w, req, nil, err)
+		return
+	}
+	defer sub.Close()
+
+	if websocket.IsWebSocketUpgrade(r) {
+		streamEventsWebSocket(core, w, r, sub)
+		return
+	}
+
+	streamEventsSSE(core, w, r, sub)
+}
+
+func streamEventsWebSocket(core *vault.Core, w http.ResponseWriter, r *http.Request, sub *vault.EventSubscription) {
+	conn, err := eventsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded to the client
+		core.Logger().Debug("failed to upgrade events subscription", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	// Drain messages sent by the client so that control frames are
+	// processed, and notice when the client closes the connection.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	revalidate := time.NewTicker(eventsRevalidateInterval)
+	defer revalidate.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if core.Sealed() {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "core sealed"))
+				return
+			}
+		case <-revalidate.C:
+			if err := sub.Revalidate(r.Context()); err != nil {
+				core.Logger().Debug("closing events subscription", "error", err)
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "permission denied"))
+				return
+			}
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				core.Logger().Debug("error writing event to subscriber", "error", err)
+				return
+			}
+		}
+	}
+}
+
+func streamEventsSSE(core *vault.Core, w http.ResponseWriter, r *http.Request, sub *vault.EventSubscription) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		if nw, ok := w.(logical.WrappingResponseWriter); ok {
+			flusher, ok = nw.Wrapped().(http.Flusher)
+		}
+	}
+	if flusher == nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	revalidate := time.NewTicker(eventsRevalidateInterval)
+	defer revalidate.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if core.Sealed() {
+				return
+			}
+		case <-revalidate.C:
+			if err := sub.Revalidate(r.Context()); err != nil {
+				core.Logger().Debug("closing events subscription", "error", err)
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				core.Logger().Error("failed to encode event", "error", err)
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.EventType, data); err != nil {
+				core.Logger().Debug("error writing event to subscriber", "error", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"github.com/openbao/openbao/vault"
+	"github.com/openbao/openbao/vault/eventbus"
+	"github.com/stretchr/testify/require"
+)
+
+func testEventsSubscribeSSE(t *testing.T, addr, token, pattern string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, addr+"/v1/sys/events/subscribe/"+pattern, nil)
+	require.NoError(t, err)
+	req.Header.Set(consts.AuthHeaderName, token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// readSSEEvent reads the next event from a server-sent event stream.
+func readSSEEvent(t *testing.T, reader *bufio.Reader) *eventbus.Event {
+	t.Helper()
+
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var event eventbus.Event
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			return &event
+		}
+	}
+}
+
+func testCreateRootChildToken(t *testing.T, addr, token string) string {
+	t.Helper()
+
+	resp := testHttpPost(t, token, addr+"/v1/auth/token/create", map[string]interface{}{
+		"policies": []string{"root"},
+	})
+	testResponseStatus(t, resp, 200)
+
+	var out map[string]interface{}
+	testResponseBody(t, resp, &out)
+	return out["auth"].(map[string]interface{})["client_token"].(string)
+}
+
+func TestSysEventsSubscribe_SSE(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	// Subscriptions are authorized like any other request
+	resp := testEventsSubscribeSSE(t, addr, "", "*")
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = testEventsSubscribeSSE(t, addr, token, "sys/mount-*")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	testResponseStatus(t, testHttpPost(t, token, addr+"/v1/sys/mounts/foo", map[string]interface{}{
+		"type": "kv",
+	}), 204)
+
+	event := readSSEEvent(t, bufio.NewReader(resp.Body))
+	require.Equal(t, "sys/mount-enable", event.EventType)
+	require.Equal(t, "sys/mounts/foo/", event.Path)
+}
+
+func TestSysEventsSubscribe_WebSocket(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	wsAddr := "ws" + strings.TrimPrefix(addr, "http") + "/v1/sys/events/subscribe/sys/mount-*"
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsAddr, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsAddr, http.Header{
+		consts.AuthHeaderName: []string{token},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	testResponseStatus(t, testHttpPost(t, token, addr+"/v1/sys/mounts/foo", map[string]interface{}{
+		"type": "kv",
+	}), 204)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var event eventbus.Event
+	require.NoError(t, conn.ReadJSON(&event))
+	require.Equal(t, "sys/mount-enable", event.EventType)
+	require.Equal(t, "sys/mounts/foo/", event.Path)
+}
+
+func TestSysEventsSubscribe_RevokedToken(t *testing.T) {
+	oldInterval := eventsRevalidateInterval
+	eventsRevalidateInterval = 100 * time.Millisecond
+	defer func() { eventsRevalidateInterval = oldInterval }()
+
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	sseToken := testCreateRootChildToken(t, addr, token)
+	wsToken := testCreateRootChildToken(t, addr, token)
+
+	resp := testEventsSubscribeSSE(t, addr, sseToken, "*")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	wsAddr := "ws" + strings.TrimPrefix(addr, "http") + "/v1/sys/events/subscribe/*"
+	conn, _, err := websocket.DefaultDialer.Dial(wsAddr, http.Header{
+		consts.AuthHeaderName: []string{wsToken},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, revoke := range []string{sseToken, wsToken} {
+		testResponseStatus(t, testHttpPost(t, token, addr+"/v1/auth/token/revoke", map[string]interface{}{
+			"token": revoke,
+		}), 204)
+	}
+
+	// Both streams are closed once the revocation is noticed
+	done := make(chan error, 1)
+	go func() {
+		_, err := bufio.NewReader(resp.Body).ReadString('\n')
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("SSE stream was not closed after token revocation")
+	}
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "unexpected error: %v", err)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/hashicorp/go-uuid"
 	gziphandler "github.com/klauspost/compress/gzhttp"
 	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/helper/tracing"
 	"github.com/openbao/openbao/internalshared/configutil"
 	"github.com/openbao/openbao/internalshared/listenerutil"
 	"github.com/openbao/openbao/sdk/v2/helper/consts"
@@ -108,6 +109,7 @@ func init() {
 	alwaysRedirectPaths.AddPaths([]string{
 		"sys/storage/raft/snapshot",
 		"sys/storage/raft/snapshot-force",
+		"sys/events/subscribe/",
 	})
 }
 
@@ -170,10 +172,10 @@ func handler(props *vault.HandlerProperties) http.Handler {
 		mux.Handle("/v1/sys/internal/ui/feature-flags", handleSysInternalFeatureFlags(core))
 
 		for _, path := range injectDataIntoTopRoutes {
-			mux.Handle(path, handleRequestForwarding(core, handleLogicalWithInjector(core)))
+			mux.Handle(path, handleStandbyReads(core, handleLogicalWithInjector(core)))
 		}
-		mux.Handle("/v1/sys/", handleRequestForwarding(core, handleLogical(core)))
-		mux.Handle("/v1/", handleRequestForwarding(core, handleLogical(core)))
+		mux.Handle("/v1/sys/", handleStandbyReads(core, handleLogical(core)))
+		mux.Handle("/v1/", handleStandbyReads(core, handleLogical(core)))
 		if core.UIEnabled() {
 			if uiBuiltIn {
 				mux.Handle("/ui/", http.StripPrefix("/ui/", gziphandler.GzipHandler(handleUIHeaders(core, handleUI(http.FileServer(&UIAssetWrapper{FileSystem: assetFS()}))))))
@@ -347,6 +349,16 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 		r = r.WithContext(ctx)
 		r = r.WithContext(namespace.ContextWithNamespace(r.Context(), namespace.RootNamespace))
 
+		// Continue the trace of requests forwarded by standby nodes, and
+		// link to the caller's trace otherwise unless configured to trust it
+		ctx, span := tracing.StartServerSpan(r.Context(), r.Header, "http.request",
+			tracing.AttrHTTPMethod.String(r.Method),
+			tracing.AttrHTTPPath.String(r.URL.Path))
+		r = r.WithContext(ctx)
+		defer func() {
+			tracing.EndServerSpan(span, nw.StatusCode)
+		}()
+
 		// Set some response headers with raft node id (if applicable) and hostname, if available
 		if core.RaftNodeIDHeaderEnabled() {
 			nodeID := core.GetRaftNodeID()
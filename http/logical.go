@@ -20,6 +20,7 @@ import (
 	"github.com/hashicorp/go-uuid"
 	"github.com/openbao/openbao/helper/namespace"
 	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"github.com/openbao/openbao/sdk/v2/helper/strutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
 	"github.com/openbao/openbao/vault"
 	"go.uber.org/atomic"
@@ -126,11 +127,13 @@ func buildLogicalRequestNoAuth(w http.ResponseWriter, r *http.Request) (*logical
 		bufferedBody := newBufferedReader(r.Body)
 		r.Body = bufferedBody
 
-		// If we are uploading a snapshot or receiving an ocsp-request (which
-		// is der encoded) we don't want to parse it. Instead, we will simply
-		// add the HTTP request to the logical request object for later consumption.
+		// If we are uploading a snapshot or receiving an ocsp-request, an
+		// EST enrollment or a SCEP message (which are der encoded) we don't
+		// want to parse it.
+		// Instead, we will simply add the HTTP request to the logical request
+		// object for later consumption.
 		contentType := r.Header.Get("Content-Type")
-		if path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" || isOcspRequest(contentType) {
+		if path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" || isRawBodyRequest(contentType) {
 			passHTTPReq = true
 			origBody = r.Body
 		} else {
@@ -242,13 +245,22 @@ func buildLogicalRequestNoAuth(w http.ResponseWriter, r *http.Request) (*logical
 	return req, origBody, 0, nil
 }
 
-func isOcspRequest(contentType string) bool {
+// rawBodyContentTypes are the content types of the requests whose body is
+// passed as is to the backends.
+var rawBodyContentTypes = []string{
+	"application/ocsp-request",
+	"application/pkcs10",
+	"application/pkixcmp",
+	"application/x-pki-message",
+}
+
+func isRawBodyRequest(contentType string) bool {
 	contentType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
 		return false
 	}
 
-	return contentType == "application/ocsp-request"
+	return strutil.StrListContains(rawBodyContentTypes, contentType)
 }
 
 func buildLogicalPath(r *http.Request) (string, int, error) {
@@ -395,6 +407,11 @@ func handleLogicalInternal(core *vault.Core, injectDataIntoTopLevel bool, noForw
 			return
 		}
 
+		if isEventsSubscribeRequest(req) {
+			handleEventsSubscribe(core, w, r, req)
+			return
+		}
+
 		// Make the internal request. We attach the connection info
 		// as well in case this is an authentication request that requires
 		// it. Vault core handles stripping this if we need to. This also
@@ -409,6 +426,7 @@ func handleLogicalInternal(core *vault.Core, injectDataIntoTopLevel bool, noForw
 			if origBody != nil {
 				r.Body = origBody
 			}
+			restoreStandbyReadBody(r)
 			forwardRequest(core, w, r)
 			return
 		case !ok:
@@ -474,6 +492,7 @@ func respondLogical(core *vault.Core, w http.ResponseWriter, r *http.Request, re
 		}
 	}
 
+	setStorageIndexHeader(core, w)
 	adjustResponse(core, w, req)
 
 	// Respond
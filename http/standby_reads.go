@@ -0,0 +1,98 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/openbao/openbao/api/v2"
+	"github.com/openbao/openbao/vault"
+)
+
+// standbyReadBodyKey is the context key under which the body of a request
+// served by a standby node is kept, in case it has to be forwarded after all.
+type standbyReadBodyKey struct{}
+
+// handleStandbyReads determines whether a standby node serves a request
+// itself or forwards it to the active node. Nodes not serving reads behave
+// as with handleRequestForwarding.
+func handleStandbyReads(core *vault.Core, handler http.Handler) http.Handler {
+	forwardingHandler := handleRequestForwarding(core, handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !core.StandbyReadsActive() {
+			forwardingHandler.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Header.Get(api.HeaderForward) == "active-node" {
+			core.RecordStandbyReadForwarded("header")
+			forwardRequest(core, w, r)
+			return
+		}
+
+		var required uint64
+		for _, value := range r.Header.Values(api.HeaderIndex) {
+			index, err := core.DecodeStorageIndex(value)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, err)
+				return
+			}
+			if index > required {
+				required = index
+			}
+		}
+		if required != 0 && !core.WaitForStorageIndex(r.Context(), required) {
+			if r.Header.Get(api.HeaderInconsistent) == "forward-active-node" {
+				core.RecordStandbyReadForwarded("index")
+				forwardRequest(core, w, r)
+				return
+			}
+			respondError(w, http.StatusPreconditionFailed, vault.ErrStandbyReadsIndexNotSatisfied)
+			return
+		}
+
+		// Keep the body around, as we may find out that the request needs
+		// forwarding only after having parsed it.
+		if r.Body != nil && r.Body != http.NoBody {
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				status := http.StatusBadRequest
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					status = http.StatusRequestEntityTooLarge
+				}
+				respondError(w, status, fmt.Errorf("failed to read request body: %w", err))
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), standbyReadBodyKey{}, body))
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// restoreStandbyReadBody resets the body of a request which a standby node
+// attempted to serve before forwarding it.
+func restoreStandbyReadBody(r *http.Request) {
+	if body, ok := r.Context().Value(standbyReadBodyKey{}).([]byte); ok {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+}
+
+// setStorageIndexHeader reports the storage index visible to the request just
+// served, which clients can require of subsequent requests.
+func setStorageIndexHeader(core *vault.Core, w http.ResponseWriter) {
+	index, ok := core.StorageIndex()
+	if !ok {
+		return
+	}
+	w.Header().Set(api.HeaderIndex, core.EncodeStorageIndex(index))
+}
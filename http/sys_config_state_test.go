@@ -164,6 +164,8 @@ func TestSysConfigState_Sanitized(t *testing.T) {
 				"plugin_file_permissions":             json.Number("0"),
 				"enable_response_header_hostname":     false,
 				"enable_response_header_raft_node_id": false,
+				"enable_multiseal":                    false,
+				"enable_standby_reads":                false,
 				"log_requests_level":                  "",
 				"listeners": []interface{}{
 					map[string]interface{}{
@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/openbao/openbao/sdk/v2/helper/consts"
 	"github.com/openbao/openbao/sdk/v2/logical"
 
 	"github.com/openbao/openbao/helper/namespace"
@@ -22,6 +23,22 @@ import (
 
 var (
 	adjustRequest = func(c *vault.Core, r *http.Request) (*http.Request, int) {
+		nsHeader := namespace.Canonicalize(r.Header.Get(consts.NamespaceHeaderName))
+		ns, relativePath := c.ResolveNamespaceFromRequest(nsHeader, strings.TrimPrefix(r.URL.Path, "/v1/"))
+		if ns == nil {
+			return nil, http.StatusNotFound
+		}
+		if ns.ID == namespace.RootNamespaceID && nsHeader == "" {
+			return r, 0
+		}
+
+		// Fold the namespace header into the request path, which carries
+		// the full namespace path from here on, including when forwarded.
+		r = r.WithContext(namespace.ContextWithNamespace(r.Context(), ns))
+		r.URL.Path = "/v1/" + ns.Path + relativePath
+		r.URL.RawPath = ""
+		r.Header.Del(consts.NamespaceHeaderName)
+
 		return r, 0
 	}
 
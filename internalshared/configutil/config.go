@@ -105,14 +105,14 @@ func ParseConfig(d string) (*SharedConfig, error) {
 
 	if o := list.Filter("seal"); len(o.Items) > 0 {
 		result.found("seal", "Seal")
-		if err := parseKMS(&result.Seals, o, "seal", 3); err != nil {
+		if err := parseKMS(&result.Seals, o, "seal", maxSeals); err != nil {
 			return nil, fmt.Errorf("error parsing 'seal': %w", err)
 		}
 	}
 
 	if o := list.Filter("kms"); len(o.Items) > 0 {
 		result.found("kms", "Seal")
-		if err := parseKMS(&result.Seals, o, "kms", 3); err != nil {
+		if err := parseKMS(&result.Seals, o, "kms", maxSeals); err != nil {
 			return nil, fmt.Errorf("error parsing 'kms': %w", err)
 		}
 	}
@@ -213,6 +213,12 @@ func (c *SharedConfig) Sanitized() map[string]interface{} {
 				"type":     s.Type,
 				"disabled": s.Disabled,
 			}
+			if s.Name != "" {
+				cleanSeal["name"] = s.Name
+			}
+			if s.Priority != 0 {
+				cleanSeal["priority"] = s.Priority
+			}
 			sanitizedSeals = append(sanitizedSeals, cleanSeal)
 		}
 		result["seals"] = sanitizedSeals
@@ -56,15 +56,24 @@ type KMS struct {
 
 	Disabled bool
 	Config   map[string]string
+
+	// Name and Priority identify and order the seal when several seals
+	// are enabled at once.
+	Name     string
+	Priority int
 }
 
+// maxSeals is the maximum number of seal blocks: several enabled seals, and
+// a disabled one being migrated away from.
+const maxSeals = 6
+
 func (k *KMS) GoString() string {
 	return fmt.Sprintf("*%#v", *k)
 }
 
 func parseKMS(result *[]*KMS, list *ast.ObjectList, blockName string, maxKMS int) error {
 	if len(list.Items) > maxKMS {
-		return fmt.Errorf("only two or less %q blocks are permitted", blockName)
+		return fmt.Errorf("only %d or less %q blocks are permitted", maxKMS, blockName)
 	}
 
 	seals := make([]*KMS, 0, len(list.Items))
@@ -103,6 +112,24 @@ func parseKMS(result *[]*KMS, list *ast.ObjectList, blockName string, maxKMS int
 			delete(m, "disabled")
 		}
 
+		var name string
+		if v, ok := m["name"]; ok {
+			if name, err = parseutil.ParseString(v); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+			}
+			delete(m, "name")
+		}
+
+		var priority int
+		if v, ok := m["priority"]; ok {
+			p, err := parseutil.ParseInt(v)
+			if err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+			}
+			priority = int(p)
+			delete(m, "priority")
+		}
+
 		strMap := make(map[string]string, len(m))
 		for k, v := range m {
 			s, err := parseutil.ParseString(v)
@@ -116,6 +143,8 @@ func parseKMS(result *[]*KMS, list *ast.ObjectList, blockName string, maxKMS int
 			Type:     strings.ToLower(key),
 			Purpose:  purpose,
 			Disabled: disabled,
+			Name:     name,
+			Priority: priority,
 		}
 		if len(strMap) > 0 {
 			seal.Config = strMap
@@ -150,13 +179,13 @@ func ParseKMSes(d string) ([]*KMS, error) {
 	}
 
 	if o := list.Filter("seal"); len(o.Items) > 0 {
-		if err := parseKMS(&result.Seals, o, "seal", 3); err != nil {
+		if err := parseKMS(&result.Seals, o, "seal", maxSeals); err != nil {
 			return nil, fmt.Errorf("error parsing 'seal': %w", err)
 		}
 	}
 
 	if o := list.Filter("kms"); len(o.Items) > 0 {
-		if err := parseKMS(&result.Seals, o, "kms", 3); err != nil {
+		if err := parseKMS(&result.Seals, o, "kms", maxSeals); err != nil {
 			return nil, fmt.Errorf("error parsing 'kms': %w", err)
 		}
 	}
@@ -162,6 +162,34 @@ type Telemetry struct {
 	// PrefixFilter is a list of filter rules to apply for allowing
 	// or blocking metrics by prefix.
 	PrefixFilter []string `hcl:"prefix_filter"`
+
+	// OpenTelemetry tracing:
+	// OTLPEndpoint is the address of the OTLP collector spans are exported
+	// to. Tracing is disabled if empty.
+	OTLPEndpoint string `hcl:"otlp_endpoint"`
+	// OTLPProtocol is the protocol used to export spans, either "grpc" or
+	// "http".
+	// Default: grpc
+	OTLPProtocol string `hcl:"otlp_protocol"`
+	// OTLPInsecure disables TLS when connecting to the collector.
+	OTLPInsecure bool `hcl:"otlp_insecure"`
+	// OTLPHeaders are additional headers sent with exported spans, such as
+	// authentication headers required by the collector.
+	OTLPHeaders map[string]string `hcl:"otlp_headers"`
+	// OTLPServiceName is the service name attached to exported spans.
+	// Default: openbao
+	OTLPServiceName string `hcl:"otlp_service_name"`
+	// OTLPSampleRatio is the ratio of traces started by this node which are
+	// sampled. Traces continued from an incoming request follow the
+	// sampling decision of the caller.
+	// Default: 1.0
+	OTLPSampleRatio *float64 `hcl:"otlp_sample_ratio"`
+	// OTLPTrustRemoteParent continues the trace described by the
+	// traceparent header of client requests, including its sampling
+	// decision. Otherwise, client requests start a new trace linked to the
+	// caller's. Requests forwarded by other nodes always continue the trace.
+	// Default: false
+	OTLPTrustRemoteParent bool `hcl:"otlp_trust_remote_parent"`
 }
 
 func (t *Telemetry) Validate(source string) []ConfigError {
@@ -234,6 +262,16 @@ func parseTelemetry(result *SharedConfig, list *ast.ObjectList) error {
 		result.Telemetry.NumLeaseMetricsTimeBuckets = NumLeaseMetricsTimeBucketsDefault
 	}
 
+	switch result.Telemetry.OTLPProtocol {
+	case "", OTLPProtocolGRPC, OTLPProtocolHTTP:
+	default:
+		return fmt.Errorf("telemetry: unknown otlp_protocol %q", result.Telemetry.OTLPProtocol)
+	}
+
+	if ratio := result.Telemetry.OTLPSampleRatio; ratio != nil && (*ratio < 0 || *ratio > 1) {
+		return errors.New("telemetry: otlp_sample_ratio must be between 0 and 1")
+	}
+
 	return nil
 }
 
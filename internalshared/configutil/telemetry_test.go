@@ -54,3 +54,40 @@ func TestParsePrefixFilters(t *testing.T) {
 		}
 	})
 }
+
+func TestParseTelemetry_OTLP(t *testing.T) {
+	t.Parallel()
+
+	config, err := ParseConfig(`
+telemetry {
+  otlp_endpoint     = "collector:4317"
+  otlp_insecure     = true
+  otlp_sample_ratio = 0.25
+  otlp_trust_remote_parent = true
+  otlp_headers = {
+    "x-api-key" = "secret"
+  }
+}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "collector:4317", config.Telemetry.OTLPEndpoint)
+	assert.True(t, config.Telemetry.OTLPInsecure)
+	assert.True(t, config.Telemetry.OTLPTrustRemoteParent)
+	assert.Equal(t, map[string]string{"x-api-key": "secret"}, config.Telemetry.OTLPHeaders)
+	if assert.NotNil(t, config.Telemetry.OTLPSampleRatio) {
+		assert.Equal(t, 0.25, *config.Telemetry.OTLPSampleRatio)
+	}
+
+	_, err = ParseConfig(`
+telemetry {
+  otlp_endpoint = "collector:4317"
+  otlp_protocol = "thrift"
+}`)
+	assert.ErrorContains(t, err, "unknown otlp_protocol")
+
+	_, err = ParseConfig(`
+telemetry {
+  otlp_endpoint     = "collector:4317"
+  otlp_sample_ratio = 2
+}`)
+	assert.ErrorContains(t, err, "otlp_sample_ratio")
+}
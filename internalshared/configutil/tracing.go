@@ -0,0 +1,106 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package configutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/openbao/openbao/helper/tracing"
+	"github.com/openbao/openbao/version"
+)
+
+const (
+	OTLPProtocolGRPC = "grpc"
+	OTLPProtocolHTTP = "http"
+
+	OTLPServiceNameDefault = "openbao"
+)
+
+// SetupTracing installs the global OpenTelemetry tracer provider exporting
+// spans to the configured OTLP collector. It returns a function flushing and
+// stopping the exporter, which must be called on shutdown. If no collector
+// is configured, tracing stays disabled and the returned function is a
+// no-op.
+func SetupTracing(ctx context.Context, config *Telemetry) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	if config == nil || config.OTLPEndpoint == "" {
+		return noop, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.OTLPProtocol {
+	case "", OTLPProtocolGRPC:
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithHeaders(config.OTLPHeaders),
+		}
+		if strings.Contains(config.OTLPEndpoint, "://") {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(config.OTLPEndpoint))
+		} else {
+			opts = append(opts, otlptracegrpc.WithEndpoint(config.OTLPEndpoint))
+		}
+		if config.OTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case OTLPProtocolHTTP:
+		opts := []otlptracehttp.Option{
+			otlptracehttp.WithHeaders(config.OTLPHeaders),
+		}
+		if strings.Contains(config.OTLPEndpoint, "://") {
+			opts = append(opts, otlptracehttp.WithEndpointURL(config.OTLPEndpoint))
+		} else {
+			opts = append(opts, otlptracehttp.WithEndpoint(config.OTLPEndpoint))
+		}
+		if config.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown otlp_protocol %q", config.OTLPProtocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	serviceName := config.OTLPServiceName
+	if serviceName == "" {
+		serviceName = OTLPServiceNameDefault
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version.GetVersion().VersionNumber()),
+	))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to create tracing resource: %w", err), exporter.Shutdown(ctx))
+	}
+
+	ratio := 1.0
+	if config.OTLPSampleRatio != nil {
+		ratio = *config.OTLPSampleRatio
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	tracing.SetTrustRemoteParent(config.OTLPTrustRemoteParent)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
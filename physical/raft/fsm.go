@@ -63,6 +63,12 @@ var (
 
 type restoreCallback func(context.Context) error
 
+// invalidateCallback is called with the index of the latest applied log and
+// the storage keys modified by the logs applied since the previous call. A
+// nil key slice means that the entire contents of the FSM may have changed,
+// e.g. after a snapshot was installed.
+type invalidateCallback func(index uint64, keys []string)
+
 // fsmEntryTxErrorKey is the value for a FSMEntry to signal that it is
 // not the result of a regular Get operation (which cannot occur in a
 // transaction) but is instead contains the response value from failing to
@@ -128,6 +134,10 @@ type FSM struct {
 	// retoreCb is called after we've restored a snapshot
 	restoreCb restoreCallback
 
+	// invalidateCb is called after logs were applied to the FSM or a
+	// snapshot was installed
+	invalidateCb invalidateCallback
+
 	chunker *raftchunking.ChunkingBatchingFSM
 
 	localID         string
@@ -197,6 +207,15 @@ func (r *RaftBackend) SetFSMApplyCallback(f func()) {
 	r.fsm.l.Unlock()
 }
 
+// SetInvalidateCallback sets the callback informed of the storage keys
+// modified by logs applied to the FSM. Standby nodes serving reads use this
+// to invalidate their caches. Passing nil removes the callback.
+func (r *RaftBackend) SetInvalidateCallback(f func(index uint64, keys []string)) {
+	r.fsm.l.Lock()
+	r.fsm.invalidateCb = f
+	r.fsm.l.Unlock()
+}
+
 func (f *FSM) openDBFile(dbPath string) error {
 	if len(dbPath) == 0 {
 		return errors.New("can not open empty filename")
@@ -814,6 +833,16 @@ func (f *FSM) ApplyBatch(logs []*raft.Log) []interface{} {
 		}
 	}
 
+	// Inform the invalidation callback of the modified keys once we've
+	// released the lock, as it may want to read from the FSM.
+	var invalidateCb invalidateCallback
+	var invalidateKeys []string
+	defer func() {
+		if invalidateCb != nil {
+			invalidateCb(lastLog.Index, invalidateKeys)
+		}
+	}()
+
 	f.l.RLock()
 	defer f.l.RUnlock()
 
@@ -924,6 +953,11 @@ func (f *FSM) ApplyBatch(logs []*raft.Log) []interface{} {
 		f.latestConfig.Store(latestConfiguration)
 	}
 
+	if f.invalidateCb != nil {
+		invalidateCb = f.invalidateCb
+		invalidateKeys = modifiedKeys(commands)
+	}
+
 	// Build the responses. The logs array is used here to ensure we reply to
 	// all command values; even if they are not of the types we expect. This
 	// should futureproof this function from more log types being provided.
@@ -938,6 +972,28 @@ func (f *FSM) ApplyBatch(logs []*raft.Log) []interface{} {
 	return resp
 }
 
+// modifiedKeys returns the keys written or deleted by the given commands.
+// Keys of transactions which failed to commit are included as well; callers
+// only use these to invalidate caches, for which a spurious key is harmless.
+func modifiedKeys(commands []interface{}) []string {
+	keys := make([]string, 0, len(commands))
+	for _, commandRaw := range commands {
+		command, ok := commandRaw.(*LogData)
+		if !ok {
+			continue
+		}
+
+		for _, op := range command.Operations {
+			switch op.OpType {
+			case putOp, deleteOp:
+				keys = append(keys, op.Key)
+			}
+		}
+	}
+
+	return keys
+}
+
 // Apply will apply a log value to the FSM. This is called from the raft
 // library.
 func (f *FSM) Apply(log *raft.Log) interface{} {
@@ -1035,6 +1091,18 @@ func (f *FSM) Restore(r io.ReadCloser) error {
 		}
 	}
 
+	// Once the snapshot is installed, anything in storage may have changed.
+	defer func() {
+		f.l.RLock()
+		invalidateCb := f.invalidateCb
+		f.l.RUnlock()
+
+		if invalidateCb != nil {
+			latestIndex, _ := f.LatestState()
+			invalidateCb(latestIndex.Index, nil)
+		}
+	}()
+
 	f.l.Lock()
 	defer f.l.Unlock()
 
@@ -161,3 +161,48 @@ func TestFSM_List(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestFSM_InvalidateCallback(t *testing.T) {
+	fsm, dir := getFSM(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	var gotIndex uint64
+	var gotKeys []string
+	fsm.l.Lock()
+	fsm.invalidateCb = func(index uint64, keys []string) {
+		gotIndex = index
+		gotKeys = append(gotKeys, keys...)
+	}
+	fsm.l.Unlock()
+
+	getLog := func(index uint64, ops ...*LogOperation) *raft.Log {
+		commandBytes, err := proto.Marshal(&LogData{Operations: ops})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &raft.Log{
+			Index: index,
+			Term:  1,
+			Type:  raft.LogCommand,
+			Data:  commandBytes,
+		}
+	}
+
+	resp := fsm.ApplyBatch([]*raft.Log{
+		getLog(1, &LogOperation{OpType: putOp, Key: "foo", Value: []byte("bar")}),
+		getLog(2,
+			&LogOperation{OpType: putOp, Key: "baz", Value: []byte("qux")},
+			&LogOperation{OpType: deleteOp, Key: "foo"},
+		),
+	})
+	if len(resp) != 2 {
+		t.Fatalf("incorrect response length: got %d expected 2", len(resp))
+	}
+
+	if gotIndex != 2 {
+		t.Fatalf("bad index: got %d expected 2", gotIndex)
+	}
+	if diff := deep.Equal([]string{"foo", "baz", "foo"}, gotKeys); len(diff) > 0 {
+		t.Fatal(diff)
+	}
+}
@@ -1190,6 +1190,19 @@ func (b *RaftBackend) AppliedIndex() uint64 {
 	return indexState.Index
 }
 
+// IsLeader returns whether this node is currently the leader of the raft
+// cluster.
+func (b *RaftBackend) IsLeader() bool {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return false
+	}
+
+	return b.raft.State() == raft.Leader
+}
+
 // Term returns the raft term of this node.
 func (b *RaftBackend) Term() uint64 {
 	b.l.RLock()
@@ -430,6 +430,24 @@ func (b *Backend) System() logical.SystemView {
 	return b.system
 }
 
+// SendEvent publishes an event through the backend's system view. Events
+// are best-effort: failures are logged rather than returned, so that they
+// never affect the outcome of the request that triggered them.
+func (b *Backend) SendEvent(ctx context.Context, eventType logical.EventType, dataPath string, metadata map[string]string) {
+	if b.system == nil {
+		return
+	}
+
+	err := b.system.SendEvent(ctx, eventType, dataPath, metadata)
+	switch {
+	case err == nil:
+	case errors.Is(err, logical.ErrEventsNotSupported):
+		b.Logger().Trace("events not supported, dropping event", "event_type", eventType)
+	default:
+		b.Logger().Warn("failed to send event", "event_type", eventType, "error", err)
+	}
+}
+
 // Type returns the backend type
 func (b *Backend) Type() logical.BackendType {
 	return b.BackendType
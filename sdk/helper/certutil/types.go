@@ -807,6 +807,11 @@ type CreationBundle struct {
 	Params        *CreationParameters
 	SigningBundle *CAInfoBundle
 	CSR           *x509.CertificateRequest
+
+	// SkipCSRSignatureCheck is set for requests which are not signed by
+	// their subject, such as CRMF certificate templates, whose proof of
+	// possession was verified otherwise.
+	SkipCSRSignatureCheck bool
 }
 
 // addKeyUsages adds appropriate key usages to the template given the creation
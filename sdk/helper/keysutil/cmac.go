@@ -0,0 +1,185 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// kmac128OutputSize and kmac256OutputSize are the sizes of the MACs
+	// produced with KMAC128 and KMAC256 keys, matching their security
+	// strengths as recommended by NIST SP 800-185.
+	kmac128OutputSize = 256 / 8
+	kmac256OutputSize = 512 / 8
+)
+
+// CMAC computes the message authentication code of input with the given
+// version of the key: AES-CMAC (NIST SP 800-38B) for AES CMAC keys, or KMAC
+// (NIST SP 800-185) with the given customization string for KMAC keys.
+func (p *Policy) CMAC(ver int, input, customization []byte) ([]byte, error) {
+	if p.SoftDeleted {
+		return nil, errutil.UserError{Err: ErrSoftDeleted}
+	}
+	if !p.Type.CMACSupported() {
+		return nil, errutil.UserError{Err: fmt.Sprintf("CMAC not supported for key type %v", p.Type)}
+	}
+
+	switch {
+	case ver <= 0:
+		return nil, errutil.UserError{Err: "key version does not exist (must be positive)"}
+	case ver > p.LatestVersion:
+		return nil, errutil.UserError{Err: fmt.Sprintf("key version does not exist; latest key version is %d", p.LatestVersion)}
+	}
+
+	keyEntry, err := p.safeGetKeyEntry(ver)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.Type {
+	case KeyType_AES128_CMAC, KeyType_AES256_CMAC:
+		if len(customization) > 0 {
+			return nil, errutil.UserError{Err: "customization is only supported by KMAC keys"}
+		}
+		return aesCMAC(keyEntry.Key, input)
+	case KeyType_KMAC128:
+		return kmac(sha3.NewCShake128, keyEntry.Key, input, customization, kmac128OutputSize, 168), nil
+	default:
+		return kmac(sha3.NewCShake256, keyEntry.Key, input, customization, kmac256OutputSize, 136), nil
+	}
+}
+
+// VerifyCMAC reports whether mac is the message authentication code of input
+// with the given version of the key.
+func (p *Policy) VerifyCMAC(ver int, input, customization, mac []byte) (bool, error) {
+	expected, err := p.CMAC(ver, input, customization)
+	if err != nil {
+		return false, err
+	}
+	if len(expected) == 0 {
+		return false, errors.New("CMAC could not be computed")
+	}
+	return subtle.ConstantTimeCompare(expected, mac) == 1, nil
+}
+
+// aesCMAC computes the AES-CMAC of msg as specified in NIST SP 800-38B and
+// RFC 4493.
+func aesCMAC(key, msg []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate the subkeys from the encryption of the zero block.
+	l := make([]byte, aes.BlockSize)
+	block.Encrypt(l, l)
+	k1 := cmacDouble(l)
+	k2 := cmacDouble(k1)
+
+	// Process all blocks but the last, which is padded if incomplete and
+	// masked with the corresponding subkey.
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	complete := n > 0 && len(msg)%aes.BlockSize == 0
+	if n == 0 {
+		n = 1
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		subtle.XORBytes(x, x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x, x)
+	}
+
+	last := make([]byte, aes.BlockSize)
+	copy(last, msg[(n-1)*aes.BlockSize:])
+	if complete {
+		subtle.XORBytes(last, last, k1)
+	} else {
+		last[len(msg)-(n-1)*aes.BlockSize] = 0x80
+		subtle.XORBytes(last, last, k2)
+	}
+
+	subtle.XORBytes(x, x, last)
+	block.Encrypt(x, x)
+	return x, nil
+}
+
+// cmacDouble multiplies the block by x in GF(2^128), as used to derive the
+// CMAC subkeys.
+func cmacDouble(in []byte) []byte {
+	out := make([]byte, len(in))
+	var carry byte
+	for i := len(in) - 1; i >= 0; i-- {
+		out[i] = in[i]<<1 | carry
+		carry = in[i] >> 7
+	}
+	// Constant time reduction by the polynomial x^128 + x^7 + x^2 + x + 1.
+	out[len(out)-1] ^= byte(subtle.ConstantTimeByteEq(carry, 1)) * 0x87
+	return out
+}
+
+// kmac computes KMAC128 or KMAC256, depending on newCShake and its rate, of
+// msg with a fixed output size in bytes, as specified in NIST SP 800-185.
+func kmac(newCShake func(n, s []byte) sha3.ShakeHash, key, msg, customization []byte, size, rate int) []byte {
+	h := newCShake([]byte("KMAC"), customization)
+	h.Write(bytepad(encodeString(key), rate))
+	h.Write(msg)
+	h.Write(rightEncode(uint64(size * 8)))
+
+	out := make([]byte, size)
+	h.Read(out)
+	return out
+}
+
+// bytepad prepends the encoding of w to x and pads the result to a multiple
+// of w bytes.
+func bytepad(x []byte, w int) []byte {
+	out := append(leftEncode(uint64(w)), x...)
+	if pad := len(out) % w; pad != 0 {
+		out = append(out, make([]byte, w-pad)...)
+	}
+	return out
+}
+
+// encodeString prepends the encoding of the bit length of s to it.
+func encodeString(s []byte) []byte {
+	return append(leftEncode(uint64(len(s)*8)), s...)
+}
+
+// leftEncode encodes x as its minimal big-endian representation preceded by
+// its length in bytes.
+func leftEncode(x uint64) []byte {
+	encoded := integerBytes(x)
+	return append([]byte{byte(len(encoded))}, encoded...)
+}
+
+// rightEncode encodes x as its minimal big-endian representation followed by
+// its length in bytes.
+func rightEncode(x uint64) []byte {
+	encoded := integerBytes(x)
+	return append(encoded, byte(len(encoded)))
+}
+
+// integerBytes returns the minimal big-endian representation of x, which is
+// a single zero byte for zero.
+func integerBytes(x uint64) []byte {
+	n := 1
+	for v := x >> 8; v > 0; v >>= 8 {
+		n++
+	}
+
+	out := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		out[i] = byte(x)
+		x >>= 8
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Test vectors from RFC 4493 and NIST SP 800-38B.
+func TestAESCMAC(t *testing.T) {
+	const (
+		key128 = "2b7e151628aed2a6abf7158809cf4f3c"
+		key256 = "603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4"
+		msg    = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+	)
+
+	cases := []struct {
+		key      string
+		msgLen   int
+		expected string
+	}{
+		{key128, 0, "bb1d6929e95937287fa37d129b756746"},
+		{key128, 16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{key128, 40, "dfa66747de9ae63030ca32611497c827"},
+		{key128, 64, "51f0bebf7e3b9d92fc49741779363cfe"},
+		{key256, 0, "028962f61b7bf89efc6b551f4667d983"},
+		{key256, 16, "28a7023f452e8f82bd4bf28d8c37c35c"},
+	}
+
+	for _, c := range cases {
+		mac, err := aesCMAC(mustDecodeHex(t, c.key), mustDecodeHex(t, msg)[:c.msgLen])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(mac) != c.expected {
+			t.Fatalf("bad CMAC for %d byte message with key %s: expected %s, got %x", c.msgLen, c.key, c.expected, mac)
+		}
+	}
+}
+
+// Test vectors from the NIST SP 800-185 KMAC samples.
+func TestKMAC(t *testing.T) {
+	key := mustDecodeHex(t, "404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f")
+	data := mustDecodeHex(t, "00010203")
+
+	p := &Policy{
+		Name:          "test",
+		LatestVersion: 1,
+		Keys: keyEntryMap{
+			"1": KeyEntry{Key: key},
+		},
+	}
+
+	cases := []struct {
+		typ           KeyType
+		customization string
+		expected      string
+	}{
+		{KeyType_KMAC128, "", "e5780b0d3ea6f7d3a429c5706aa43a00fadbd7d49628839e3187243f456ee14e"},
+		{KeyType_KMAC128, "My Tagged Application", "3b1fba963cd8b0b59e8c1a6d71888b7143651af8ba0a7070c0979e2811324aa5"},
+		{KeyType_KMAC256, "My Tagged Application", "20c570c31346f703c9ac36c61c03cb64c3970d0cfc787e9b79599d273a68d2f7f69d4cc3de9d104a351689f27cf6f5951f0103f33f4f24871024d9c27773a8dd"},
+	}
+
+	for _, c := range cases {
+		p.Type = c.typ
+		mac, err := p.CMAC(1, data, []byte(c.customization))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(mac) != c.expected {
+			t.Fatalf("bad %v with customization %q: expected %s, got %x", c.typ, c.customization, c.expected, mac)
+		}
+
+		valid, err := p.VerifyCMAC(1, data, []byte(c.customization), mac)
+		if err != nil || !valid {
+			t.Fatalf("expected %v to verify, err: %v", c.typ, err)
+		}
+		valid, err = p.VerifyCMAC(1, data, []byte("other"), mac)
+		if err != nil || valid {
+			t.Fatalf("expected %v with other customization not to verify, err: %v", c.typ, err)
+		}
+	}
+
+	p.Type = KeyType_AES256_CMAC
+	if _, err := p.CMAC(1, data, []byte("My Tagged Application")); err == nil {
+		t.Fatal("expected customization to be rejected for AES-CMAC keys")
+	}
+	if _, err := p.CMAC(2, data, nil); err == nil {
+		t.Fatal("expected nonexistent key version to be rejected")
+	}
+}
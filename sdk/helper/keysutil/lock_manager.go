@@ -364,6 +364,11 @@ func (lm *LockManager) GetPolicy(ctx context.Context, req PolicyRequest, rand io
 				return nil, false, errors.New("convergent encryption requires derivation to be enabled")
 			}
 
+		case KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
+			if req.Convergent {
+				return nil, false, fmt.Errorf("convergent encryption not supported for keys of type %v, which are always deterministic", req.KeyType)
+			}
+
 		case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
 			if req.Derived || req.Convergent {
 				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
@@ -379,7 +384,8 @@ func (lm *LockManager) GetPolicy(ctx context.Context, req PolicyRequest, rand io
 				cleanup()
 				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
 			}
-		case KeyType_HMAC:
+		case KeyType_HMAC, KeyType_AES128_CMAC, KeyType_AES256_CMAC, KeyType_KMAC128, KeyType_KMAC256,
+			KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87, KeyType_ML_KEM_768, KeyType_ML_KEM_1024:
 			if req.Derived || req.Convergent {
 				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
 			}
@@ -71,6 +71,17 @@ const (
 	KeyType_RSA3072
 	KeyType_HMAC
 	KeyType_XChaCha20_Poly1305
+	KeyType_AES128_CMAC
+	KeyType_AES256_CMAC
+	KeyType_KMAC128
+	KeyType_KMAC256
+	KeyType_ML_DSA_44
+	KeyType_ML_DSA_65
+	KeyType_ML_DSA_87
+	KeyType_ML_KEM_768
+	KeyType_ML_KEM_1024
+	KeyType_AES256_SIV
+	KeyType_AES256_GCM_SIV
 )
 
 const (
@@ -124,7 +135,8 @@ type KeyType int
 
 func (kt KeyType) EncryptionSupported() bool {
 	switch kt {
-	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096,
+		KeyType_ML_KEM_768, KeyType_ML_KEM_1024, KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
 		return true
 	}
 	return false
@@ -132,7 +144,8 @@ func (kt KeyType) EncryptionSupported() bool {
 
 func (kt KeyType) DecryptionSupported() bool {
 	switch kt {
-	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096,
+		KeyType_ML_KEM_768, KeyType_ML_KEM_1024, KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
 		return true
 	}
 	return false
@@ -140,7 +153,8 @@ func (kt KeyType) DecryptionSupported() bool {
 
 func (kt KeyType) SigningSupported() bool {
 	switch kt {
-	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096,
+		KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87:
 		return true
 	}
 	return false
@@ -156,7 +170,8 @@ func (kt KeyType) HashSignatureInput() bool {
 
 func (kt KeyType) DerivationSupported() bool {
 	switch kt {
-	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_ED25519:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_ED25519,
+		KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
 		return true
 	}
 	return false
@@ -172,7 +187,8 @@ func (kt KeyType) KeyAgreementSupported() bool {
 
 func (kt KeyType) AssociatedDataSupported() bool {
 	switch kt {
-	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305,
+		KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
 		return true
 	}
 	return false
@@ -180,7 +196,16 @@ func (kt KeyType) AssociatedDataSupported() bool {
 
 func (kt KeyType) ImportPublicKeySupported() bool {
 	switch kt {
-	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096, KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519:
+	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096, KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519,
+		KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87, KeyType_ML_KEM_768, KeyType_ML_KEM_1024:
+		return true
+	}
+	return false
+}
+
+func (kt KeyType) CMACSupported() bool {
+	switch kt {
+	case KeyType_AES128_CMAC, KeyType_AES256_CMAC, KeyType_KMAC128, KeyType_KMAC256:
 		return true
 	}
 	return false
@@ -212,6 +237,28 @@ func (kt KeyType) String() string {
 		return "rsa-4096"
 	case KeyType_HMAC:
 		return "hmac"
+	case KeyType_AES128_CMAC:
+		return "aes128-cmac"
+	case KeyType_AES256_CMAC:
+		return "aes256-cmac"
+	case KeyType_KMAC128:
+		return "kmac128"
+	case KeyType_KMAC256:
+		return "kmac256"
+	case KeyType_ML_DSA_44:
+		return "ml-dsa-44"
+	case KeyType_ML_DSA_65:
+		return "ml-dsa-65"
+	case KeyType_ML_DSA_87:
+		return "ml-dsa-87"
+	case KeyType_ML_KEM_768:
+		return "ml-kem-768"
+	case KeyType_ML_KEM_1024:
+		return "ml-kem-1024"
+	case KeyType_AES256_SIV:
+		return "aes256-siv"
+	case KeyType_AES256_GCM_SIV:
+		return "aes256-gcm-siv"
 	}
 
 	return "[unknown]"
@@ -463,6 +510,10 @@ type Policy struct {
 	// rotate. Setting this to zero disables automatic rotation for the key.
 	AutoRotatePeriod time.Duration `json:"auto_rotate_period"`
 
+	// JWKSEnabled publishes the public keys of the policy as a JWK set on
+	// the unauthenticated JWKS endpoint.
+	JWKSEnabled bool `json:"jwks_enabled"`
+
 	// versionPrefixCache stores caches of version prefix strings and the split
 	// version template.
 	versionPrefixCache sync.Map
@@ -863,7 +914,8 @@ func (p *Policy) DeriveKey(context, salt []byte, ver int, numBytes int) ([]byte,
 		}
 
 		switch p.Type {
-		case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305:
+		case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305,
+			KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
 			n, err := derBytes.ReadFrom(limReader)
 			if err != nil {
 				return nil, errutil.InternalError{Err: fmt.Sprintf("error reading returned derived bytes: %v", err)}
@@ -1130,6 +1182,31 @@ func (p *Policy) DecryptWithFactory(context, nonce []byte, value string, factori
 		if err != nil {
 			return "", errutil.InternalError{Err: fmt.Sprintf("failed to RSA decrypt the ciphertext: %v", err)}
 		}
+	case KeyType_ML_KEM_768, KeyType_ML_KEM_1024:
+		keyEntry, err := p.safeGetKeyEntry(ver)
+		if err != nil {
+			return "", err
+		}
+		if keyEntry.IsPrivateKeyMissing() {
+			return "", errutil.InternalError{Err: "cannot decrypt ciphertext, key version does not have a private counterpart"}
+		}
+		plain, err = decryptMLKEM(&PQCPrivateKey{Type: p.Type, Seed: keyEntry.Key}, decoded)
+		if err != nil {
+			return "", err
+		}
+	case KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
+		key, err := p.GetKey(context, ver, p.Type.sivKeySize())
+		if err != nil {
+			return "", err
+		}
+		associatedData, err := associatedDataFromFactories(factories)
+		if err != nil {
+			return "", err
+		}
+		plain, err = decryptSIV(p.Type, key, decoded, associatedData)
+		if err != nil {
+			return "", errutil.UserError{Err: err.Error()}
+		}
 
 	default:
 		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
@@ -1341,6 +1418,13 @@ func (p *Policy) SignWithOptions(ver int, context, input []byte, options *Signin
 			return nil, errutil.InternalError{Err: fmt.Sprintf("unsupported rsa signature algorithm %s", sigAlgorithm)}
 		}
 
+	case KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87:
+		// ML-DSA is used in its pure form, signing the input directly
+		sig, err = signMLDSA(&PQCPrivateKey{Type: p.Type, Seed: keyParams.Key}, input)
+		if err != nil {
+			return nil, err
+		}
+
 	default:
 		return nil, fmt.Errorf("unsupported key type %v", p.Type)
 	}
@@ -1534,6 +1618,18 @@ func (p *Policy) VerifySignatureWithOptions(context, input []byte, sig string, o
 
 		return err == nil, nil
 
+	case KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87:
+		keyEntry, err := p.safeGetKeyEntry(ver)
+		if err != nil {
+			return false, err
+		}
+		publicKey, err := keyEntry.PQCPublicKey(p.Type)
+		if err != nil {
+			return false, errutil.InternalError{Err: err.Error()}
+		}
+
+		return verifyMLDSA(publicKey, input, sigBytes)
+
 	default:
 		return false, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
@@ -1578,14 +1674,16 @@ func (p *Policy) ImportPublicOrPrivate(ctx context.Context, storage logical.Stor
 		return errors.New("unable to import only public key for derived Ed25519 key: imported key should not be an Ed25519 key pair but is instead an HKDF key")
 	}
 
-	if (p.Type == KeyType_AES128_GCM96 && len(key) != 16) ||
+	if ((p.Type == KeyType_AES128_GCM96 || p.Type == KeyType_AES128_CMAC) && len(key) != 16) ||
 		((p.Type == KeyType_AES256_GCM96 || p.Type == KeyType_ChaCha20_Poly1305 ||
-			p.Type == KeyType_XChaCha20_Poly1305) && len(key) != 32) ||
+			p.Type == KeyType_XChaCha20_Poly1305 || p.Type == KeyType_AES256_CMAC) && len(key) != 32) ||
+		((p.Type == KeyType_KMAC128 || p.Type == KeyType_KMAC256) && len(key) < 32) ||
+		(p.Type.isSIV() && len(key) != p.Type.sivKeySize()) ||
 		(p.Type == KeyType_HMAC && (len(key) < HmacMinKeySize || len(key) > HmacMaxKeySize)) {
 		return fmt.Errorf("invalid key size %d bytes for key type %s", len(key), p.Type)
 	}
 
-	if p.Type == KeyType_AES128_GCM96 || p.Type == KeyType_AES256_GCM96 || p.Type == KeyType_ChaCha20_Poly1305 || p.Type == KeyType_XChaCha20_Poly1305 || p.Type == KeyType_HMAC {
+	if p.Type == KeyType_AES128_GCM96 || p.Type == KeyType_AES256_GCM96 || p.Type == KeyType_ChaCha20_Poly1305 || p.Type == KeyType_XChaCha20_Poly1305 || p.Type == KeyType_HMAC || p.Type.CMACSupported() || p.Type.isSIV() {
 		entry.Key = key
 		if p.Type == KeyType_HMAC {
 			p.KeySize = len(key)
@@ -1594,7 +1692,12 @@ func (p *Policy) ImportPublicOrPrivate(ctx context.Context, storage logical.Stor
 	} else {
 		var parsedKey any
 		var err error
-		if isPrivateKey {
+		if p.Type.isPQC() {
+			parsedKey, err = parsePQCKey(key, isPrivateKey)
+			if err != nil {
+				return err
+			}
+		} else if isPrivateKey {
 			parsedKey, err = x509.ParsePKCS8PrivateKey(key)
 			if err != nil {
 				if strings.Contains(err.Error(), "unknown elliptic curve") {
@@ -1712,11 +1815,14 @@ func (p *Policy) RotateInMemory(randReader io.Reader) (retErr error) {
 	entry.HMACKey = hmacKey
 
 	switch p.Type {
-	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_HMAC:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_HMAC,
+		KeyType_AES128_CMAC, KeyType_AES256_CMAC, KeyType_KMAC128, KeyType_KMAC256, KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
 		// Default to 256 bit key
 		numBytes := 32
-		if p.Type == KeyType_AES128_GCM96 {
+		if p.Type == KeyType_AES128_GCM96 || p.Type == KeyType_AES128_CMAC {
 			numBytes = 16
+		} else if p.Type.isSIV() {
+			numBytes = p.Type.sivKeySize()
 		} else if p.Type == KeyType_HMAC {
 			numBytes = p.KeySize
 			if numBytes < HmacMinKeySize || numBytes > HmacMaxKeySize {
@@ -1793,6 +1899,15 @@ func (p *Policy) RotateInMemory(randReader io.Reader) (retErr error) {
 		if err != nil {
 			return err
 		}
+
+	case KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87, KeyType_ML_KEM_768, KeyType_ML_KEM_1024:
+		privateKey, err := generatePQCKey(p.Type, randReader)
+		if err != nil {
+			return err
+		}
+		if err := entry.parseFromKey(p.Type, privateKey); err != nil {
+			return err
+		}
 	}
 
 	if p.ConvergentEncryption {
@@ -2194,6 +2309,35 @@ func (p *Policy) EncryptWithFactory(ver int, context []byte, nonce []byte, value
 		if err != nil {
 			return "", errutil.InternalError{Err: fmt.Sprintf("failed to RSA encrypt the plaintext: %v", err)}
 		}
+	case KeyType_ML_KEM_768, KeyType_ML_KEM_1024:
+		keyEntry, err := p.safeGetKeyEntry(ver)
+		if err != nil {
+			return "", err
+		}
+		publicKey, err := keyEntry.PQCPublicKey(p.Type)
+		if err != nil {
+			return "", errutil.InternalError{Err: err.Error()}
+		}
+		ciphertext, err = encryptMLKEM(publicKey, plaintext)
+		if err != nil {
+			return "", err
+		}
+	case KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
+		if len(nonce) > 0 {
+			return "", errutil.UserError{Err: "nonce provided when not allowed"}
+		}
+		key, err := p.GetKey(context, ver, p.Type.sivKeySize())
+		if err != nil {
+			return "", err
+		}
+		associatedData, err := associatedDataFromFactories(factories)
+		if err != nil {
+			return "", err
+		}
+		ciphertext, err = encryptSIV(p.Type, key, plaintext, associatedData)
+		if err != nil {
+			return "", errutil.InternalError{Err: err.Error()}
+		}
 
 	default:
 		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
@@ -2241,7 +2385,15 @@ func (p *Policy) ImportPrivateKeyForVersion(ctx context.Context, storage logical
 	}
 
 	// Parse key
-	parsedPrivateKey, err := x509.ParsePKCS8PrivateKey(key)
+	var parsedPrivateKey any
+	if p.Type.isPQC() {
+		parsedPrivateKey, err = parsePQCKey(key, true)
+		if err != nil {
+			return err
+		}
+	} else {
+		parsedPrivateKey, err = x509.ParsePKCS8PrivateKey(key)
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "unknown elliptic curve") {
 			var edErr error
@@ -2292,6 +2444,22 @@ func (p *Policy) ImportPrivateKeyForVersion(ctx context.Context, storage logical
 		if !ed25519.PublicKey(publicKey).Equal(ed25519Key.Public()) {
 			return errors.New("cannot import key, key pair does not match")
 		}
+	case *PQCPrivateKey:
+		pqcKey := parsedPrivateKey.(*PQCPrivateKey)
+		if pqcKey.Type != p.Type {
+			return fmt.Errorf("invalid key type: expected %s, got %s", p.Type, pqcKey.Type)
+		}
+		publicKey, err := keyEntry.PQCPublicKey(p.Type)
+		if err != nil {
+			return err
+		}
+		derivedPublicKey, err := pqcKey.Public()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(publicKey.Key, derivedPublicKey.Key) {
+			return errors.New("cannot import key, key pair does not match")
+		}
 	}
 
 	err = keyEntry.parseFromKey(p.Type, parsedPrivateKey)
@@ -2400,6 +2568,32 @@ func (ke *KeyEntry) parseFromKey(PolKeyType KeyType, parsedKey any) error {
 			}
 			ke.RSAPublicKey = rsaKey
 		}
+	case *PQCPrivateKey, *PQCPublicKey:
+		var publicKey *PQCPublicKey
+		privateKey, ok := parsedKey.(*PQCPrivateKey)
+		if ok {
+			if privateKey.Type != PolKeyType {
+				return fmt.Errorf("invalid key type: expected %s, got %s", PolKeyType, privateKey.Type)
+			}
+
+			var err error
+			publicKey, err = privateKey.Public()
+			if err != nil {
+				return err
+			}
+			ke.Key = privateKey.Seed
+		} else {
+			publicKey = parsedKey.(*PQCPublicKey)
+			if publicKey.Type != PolKeyType {
+				return fmt.Errorf("invalid key type: expected %s, got %s", PolKeyType, publicKey.Type)
+			}
+		}
+
+		formattedPublicKey, err := formatPQCPublicKey(publicKey)
+		if err != nil {
+			return err
+		}
+		ke.FormattedPublicKey = formattedPublicKey
 	default:
 		return fmt.Errorf("invalid key type: expected %s, got %T", PolKeyType, parsedKey)
 	}
@@ -2447,12 +2641,23 @@ func (ke *KeyEntry) WrapKey(targetKey interface{}, targetKeyType KeyType, hash h
 
 	var preppedTargetKey []byte
 	switch targetKeyType {
-	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_HMAC:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_XChaCha20_Poly1305, KeyType_HMAC,
+		KeyType_AES128_CMAC, KeyType_AES256_CMAC, KeyType_KMAC128, KeyType_KMAC256, KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
 		var ok bool
 		preppedTargetKey, ok = targetKey.([]byte)
 		if !ok {
 			return "", fmt.Errorf("failed to wrap target key for import: symmetric key not provided in byte format (%T)", targetKey)
 		}
+	case KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87, KeyType_ML_KEM_768, KeyType_ML_KEM_1024:
+		pqcKey, ok := targetKey.(*PQCPrivateKey)
+		if !ok {
+			return "", fmt.Errorf("failed to wrap target key for import: %s key not provided (%T)", targetKeyType, targetKey)
+		}
+		var err error
+		preppedTargetKey, err = MarshalPKCS8PQCPrivateKey(pqcKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to wrap target key for import: %w", err)
+		}
 	default:
 		var err error
 		preppedTargetKey, err = x509.MarshalPKCS8PrivateKey(targetKey)
@@ -0,0 +1,465 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem1024"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/cloudflare/circl/sign"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa87"
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+)
+
+var (
+	// OIDs assigned by NIST to ML-DSA (FIPS 204) and ML-KEM (FIPS 203) keys,
+	// see RFC 9881 and draft-ietf-lamps-kyber-certificates.
+	oidMLDSA44   = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 17}
+	oidMLDSA65   = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 18}
+	oidMLDSA87   = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 19}
+	oidMLKEM768  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}
+	oidMLKEM1024 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 3}
+)
+
+// PQCPrivateKey is an ML-DSA or ML-KEM private key. As recommended by FIPS 203
+// and FIPS 204, it is represented by the seed the key pair is derived from.
+type PQCPrivateKey struct {
+	Type KeyType
+	Seed []byte
+}
+
+// PQCPublicKey is an ML-DSA or ML-KEM public key in its raw encoding.
+type PQCPublicKey struct {
+	Type KeyType
+	Key  []byte
+}
+
+// subjectPublicKeyInfo reflects an ASN.1 SubjectPublicKeyInfo as specified
+// in RFC 5280.
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// pqcBothPrivateKey reflects the "both" choice of the ML-DSA and ML-KEM
+// private key encodings, holding the seed along with the expanded key.
+type pqcBothPrivateKey struct {
+	Seed        []byte
+	ExpandedKey []byte
+}
+
+func (kt KeyType) isMLDSA() bool {
+	switch kt {
+	case KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87:
+		return true
+	}
+	return false
+}
+
+func (kt KeyType) isMLKEM() bool {
+	switch kt {
+	case KeyType_ML_KEM_768, KeyType_ML_KEM_1024:
+		return true
+	}
+	return false
+}
+
+func (kt KeyType) isPQC() bool {
+	return kt.isMLDSA() || kt.isMLKEM()
+}
+
+func (kt KeyType) pqcOID() asn1.ObjectIdentifier {
+	switch kt {
+	case KeyType_ML_DSA_44:
+		return oidMLDSA44
+	case KeyType_ML_DSA_65:
+		return oidMLDSA65
+	case KeyType_ML_DSA_87:
+		return oidMLDSA87
+	case KeyType_ML_KEM_768:
+		return oidMLKEM768
+	case KeyType_ML_KEM_1024:
+		return oidMLKEM1024
+	}
+	return nil
+}
+
+func pqcKeyTypeFromOID(oid asn1.ObjectIdentifier) (KeyType, error) {
+	for _, kt := range []KeyType{KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87, KeyType_ML_KEM_768, KeyType_ML_KEM_1024} {
+		if oid.Equal(kt.pqcOID()) {
+			return kt, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown ML-DSA or ML-KEM algorithm: %s", oid)
+}
+
+func (kt KeyType) mldsaScheme() sign.Scheme {
+	switch kt {
+	case KeyType_ML_DSA_44:
+		return mldsa44.Scheme()
+	case KeyType_ML_DSA_65:
+		return mldsa65.Scheme()
+	case KeyType_ML_DSA_87:
+		return mldsa87.Scheme()
+	}
+	return nil
+}
+
+func (kt KeyType) mlkemScheme() kem.Scheme {
+	switch kt {
+	case KeyType_ML_KEM_768:
+		return mlkem768.Scheme()
+	case KeyType_ML_KEM_1024:
+		return mlkem1024.Scheme()
+	}
+	return nil
+}
+
+func (kt KeyType) pqcSeedSize() int {
+	if kt.isMLKEM() {
+		return kt.mlkemScheme().SeedSize()
+	}
+	return kt.mldsaScheme().SeedSize()
+}
+
+// generatePQCKey generates a new ML-DSA or ML-KEM private key.
+func generatePQCKey(kt KeyType, randReader io.Reader) (*PQCPrivateKey, error) {
+	seed := make([]byte, kt.pqcSeedSize())
+	if _, err := io.ReadFull(randReader, seed); err != nil {
+		return nil, err
+	}
+	return &PQCPrivateKey{Type: kt, Seed: seed}, nil
+}
+
+// Public returns the public key of the key pair derived from the seed.
+func (k *PQCPrivateKey) Public() (*PQCPublicKey, error) {
+	if len(k.Seed) != k.Type.pqcSeedSize() {
+		return nil, fmt.Errorf("invalid seed size %d bytes for key type %s", len(k.Seed), k.Type)
+	}
+
+	var raw []byte
+	var err error
+	if k.Type.isMLKEM() {
+		pub, _ := k.Type.mlkemScheme().DeriveKeyPair(k.Seed)
+		raw, err = pub.MarshalBinary()
+	} else {
+		pub, _ := k.Type.mldsaScheme().DeriveKey(k.Seed)
+		raw, err = pub.MarshalBinary()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &PQCPublicKey{Type: k.Type, Key: raw}, nil
+}
+
+// MarshalPKCS8PQCPrivateKey encodes the private key in its PKCS #8 seed form.
+func MarshalPKCS8PQCPrivateKey(k *PQCPrivateKey) ([]byte, error) {
+	oid := k.Type.pqcOID()
+	if oid == nil {
+		return nil, fmt.Errorf("key type %s is not an ML-DSA or ML-KEM key type", k.Type)
+	}
+
+	seed, err := asn1.Marshal(asn1.RawValue{
+		Class: asn1.ClassContextSpecific,
+		Tag:   0,
+		Bytes: k.Seed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oid},
+		PrivateKey: seed,
+	})
+}
+
+// ParsePKCS8PQCPrivateKey parses an ML-DSA or ML-KEM private key in PKCS #8
+// form. Only encodings containing the seed of the key are supported.
+func ParsePKCS8PQCPrivateKey(der []byte) (*PQCPrivateKey, error) {
+	var privKey pkcs8
+	if _, err := asn1.Unmarshal(der, &privKey); err != nil {
+		return nil, err
+	}
+
+	kt, err := pqcKeyTypeFromOID(privKey.Algo.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(privKey.PrivateKey, &raw); err != nil {
+		return nil, fmt.Errorf("invalid %s private key: %w", kt, err)
+	} else if len(rest) != 0 {
+		return nil, fmt.Errorf("invalid %s private key: trailing data", kt)
+	}
+
+	key := &PQCPrivateKey{Type: kt}
+	switch {
+	case raw.Class == asn1.ClassContextSpecific && raw.Tag == 0 && !raw.IsCompound:
+		key.Seed = raw.Bytes
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence:
+		var both pqcBothPrivateKey
+		if _, err := asn1.Unmarshal(raw.FullBytes, &both); err != nil {
+			return nil, fmt.Errorf("invalid %s private key: %w", kt, err)
+		}
+		key.Seed = both.Seed
+
+		expanded, err := key.expandedKey()
+		if err != nil {
+			return nil, err
+		}
+		if subtle.ConstantTimeCompare(expanded, both.ExpandedKey) != 1 {
+			return nil, fmt.Errorf("invalid %s private key: expanded key does not match seed", kt)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported %s private key: only keys including their seed are supported", kt)
+	}
+
+	if len(key.Seed) != kt.pqcSeedSize() {
+		return nil, fmt.Errorf("invalid seed size %d bytes for key type %s", len(key.Seed), kt)
+	}
+
+	return key, nil
+}
+
+func (k *PQCPrivateKey) expandedKey() ([]byte, error) {
+	if len(k.Seed) != k.Type.pqcSeedSize() {
+		return nil, fmt.Errorf("invalid seed size %d bytes for key type %s", len(k.Seed), k.Type)
+	}
+	if k.Type.isMLKEM() {
+		_, priv := k.Type.mlkemScheme().DeriveKeyPair(k.Seed)
+		return priv.MarshalBinary()
+	}
+	_, priv := k.Type.mldsaScheme().DeriveKey(k.Seed)
+	return priv.MarshalBinary()
+}
+
+// MarshalPKIXPQCPublicKey encodes the public key as a SubjectPublicKeyInfo.
+func MarshalPKIXPQCPublicKey(k *PQCPublicKey) ([]byte, error) {
+	oid := k.Type.pqcOID()
+	if oid == nil {
+		return nil, fmt.Errorf("key type %s is not an ML-DSA or ML-KEM key type", k.Type)
+	}
+
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PublicKey: asn1.BitString{Bytes: k.Key, BitLength: len(k.Key) * 8},
+	})
+}
+
+// ParsePKIXPQCPublicKey parses an ML-DSA or ML-KEM SubjectPublicKeyInfo.
+func ParsePKIXPQCPublicKey(der []byte) (*PQCPublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after public key")
+	}
+
+	kt, err := pqcKeyTypeFromOID(spki.Algorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &PQCPublicKey{Type: kt, Key: spki.PublicKey.RightAlign()}
+	if kt.isMLKEM() {
+		_, err = kt.mlkemScheme().UnmarshalBinaryPublicKey(key.Key)
+	} else {
+		_, err = kt.mldsaScheme().UnmarshalBinaryPublicKey(key.Key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s public key: %w", kt, err)
+	}
+
+	return key, nil
+}
+
+// parsePQCKey parses a PKCS #8 private key or a PEM-encoded public key of an
+// ML-DSA or ML-KEM key.
+func parsePQCKey(key []byte, isPrivateKey bool) (any, error) {
+	if isPrivateKey {
+		parsedKey, err := ParsePKCS8PQCPrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing asymmetric key: %w", err)
+		}
+		return parsedKey, nil
+	}
+
+	pemBlock, _ := pem.Decode(key)
+	if pemBlock == nil {
+		return nil, errors.New("error parsing public key: not in PEM format")
+	}
+
+	parsedKey, err := ParsePKIXPQCPublicKey(pemBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key: %w", err)
+	}
+	return parsedKey, nil
+}
+
+func formatPQCPublicKey(k *PQCPublicKey) (string, error) {
+	derBytes, err := MarshalPKIXPQCPublicKey(k)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling public key: %w", err)
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	})
+	if len(pemBytes) == 0 {
+		return "", errors.New("error PEM-encoding public key")
+	}
+	return string(pemBytes), nil
+}
+
+// PQCPublicKey returns the ML-DSA or ML-KEM public key of the key entry.
+func (ke *KeyEntry) PQCPublicKey(kt KeyType) (*PQCPublicKey, error) {
+	pemBlock, _ := pem.Decode([]byte(ke.FormattedPublicKey))
+	if pemBlock == nil {
+		return nil, errors.New("failed to parse key entry public key: invalid PEM blob")
+	}
+
+	pub, err := ParsePKIXPQCPublicKey(pemBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key entry public key: %w", err)
+	}
+	if pub.Type != kt {
+		return nil, fmt.Errorf("invalid key entry public key: expected %s, got %s", kt, pub.Type)
+	}
+	return pub, nil
+}
+
+// signMLDSA computes a hedged ML-DSA signature of msg, as recommended by
+// FIPS 204.
+func signMLDSA(priv *PQCPrivateKey, msg []byte) ([]byte, error) {
+	if len(priv.Seed) != priv.Type.pqcSeedSize() {
+		return nil, errutil.InternalError{Err: "invalid ML-DSA private key"}
+	}
+
+	_, sk := priv.Type.mldsaScheme().DeriveKey(priv.Seed)
+	sig := make([]byte, priv.Type.mldsaScheme().SignatureSize())
+
+	var err error
+	switch sk := sk.(type) {
+	case *mldsa44.PrivateKey:
+		err = mldsa44.SignTo(sk, msg, nil, true, sig)
+	case *mldsa65.PrivateKey:
+		err = mldsa65.SignTo(sk, msg, nil, true, sig)
+	case *mldsa87.PrivateKey:
+		err = mldsa87.SignTo(sk, msg, nil, true, sig)
+	default:
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", priv.Type)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+func verifyMLDSA(pub *PQCPublicKey, msg, sig []byte) (bool, error) {
+	scheme := pub.Type.mldsaScheme()
+	pk, err := scheme.UnmarshalBinaryPublicKey(pub.Key)
+	if err != nil {
+		return false, errutil.InternalError{Err: fmt.Sprintf("invalid ML-DSA public key: %v", err)}
+	}
+	if len(sig) != scheme.SignatureSize() {
+		return false, nil
+	}
+	return scheme.Verify(pk, msg, sig, nil), nil
+}
+
+// encryptMLKEM encrypts plaintext to the ML-KEM public key. The shared key
+// encapsulated to the public key is used as an AES-256-GCM key, producing the
+// concatenation of the ML-KEM ciphertext, the nonce and the AES-GCM
+// ciphertext.
+func encryptMLKEM(pub *PQCPublicKey, plaintext []byte) ([]byte, error) {
+	scheme := pub.Type.mlkemScheme()
+	pk, err := scheme.UnmarshalBinaryPublicKey(pub.Key)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("invalid ML-KEM public key: %v", err)}
+	}
+
+	kemCiphertext, sharedKey, err := scheme.Encapsulate(pk)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("failed to encapsulate shared key: %v", err)}
+	}
+
+	aead, err := newMLKEMAEAD(sharedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("failed to generate nonce: %v", err)}
+	}
+
+	out := append(kemCiphertext, nonce...)
+	return aead.Seal(out, nonce, plaintext, nil), nil
+}
+
+// decryptMLKEM decrypts a ciphertext produced by encryptMLKEM with the ML-KEM
+// private key.
+func decryptMLKEM(priv *PQCPrivateKey, ciphertext []byte) ([]byte, error) {
+	if len(priv.Seed) != priv.Type.pqcSeedSize() {
+		return nil, errutil.InternalError{Err: "invalid ML-KEM private key"}
+	}
+
+	scheme := priv.Type.mlkemScheme()
+	_, sk := scheme.DeriveKeyPair(priv.Seed)
+
+	kemSize := scheme.CiphertextSize()
+	if len(ciphertext) < kemSize {
+		return nil, errutil.UserError{Err: "invalid ciphertext: too short"}
+	}
+
+	sharedKey, err := scheme.Decapsulate(sk, ciphertext[:kemSize])
+	if err != nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("invalid ciphertext: %v", err)}
+	}
+
+	aead, err := newMLKEMAEAD(sharedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext = ciphertext[kemSize:]
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errutil.UserError{Err: "invalid ciphertext: too short"}
+	}
+
+	plain, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errutil.UserError{Err: "invalid ciphertext: unable to decrypt"}
+	}
+	return plain, nil
+}
+
+func newMLKEMAEAD(sharedKey []byte) (cipher.AEAD, error) {
+	aesCipher, err := aes.NewCipher(sharedKey)
+	if err != nil {
+		return nil, errutil.InternalError{Err: err.Error()}
+	}
+	aead, err := cipher.NewGCM(aesCipher)
+	if err != nil {
+		return nil, errutil.InternalError{Err: err.Error()}
+	}
+	return aead, nil
+}
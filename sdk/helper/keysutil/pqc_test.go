@@ -0,0 +1,233 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func newPQCPolicy(t *testing.T, storage logical.Storage, name string, kt KeyType) *Policy {
+	t.Helper()
+
+	lm, err := NewLockManager(true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _, err := lm.GetPolicy(context.Background(), PolicyRequest{
+		Upsert:  true,
+		Storage: storage,
+		KeyType: kt,
+		Name:    name,
+	}, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPolicy_MLDSA(t *testing.T) {
+	ctx := context.Background()
+	input := []byte("the quick brown fox")
+
+	for _, kt := range []KeyType{KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87} {
+		t.Run(kt.String(), func(t *testing.T) {
+			storage := &logical.InmemStorage{}
+			p := newPQCPolicy(t, storage, "test", kt)
+
+			sig, err := p.Sign(0, nil, input, HashTypeNone, "", MarshalingTypeASN1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			valid, err := p.VerifySignature(nil, input, HashTypeNone, "", MarshalingTypeASN1, sig.Signature)
+			if err != nil || !valid {
+				t.Fatalf("expected signature to verify, err: %v", err)
+			}
+			valid, err = p.VerifySignature(nil, []byte("the lazy dog"), HashTypeNone, "", MarshalingTypeASN1, sig.Signature)
+			if err != nil || valid {
+				t.Fatalf("expected signature of other input not to verify, err: %v", err)
+			}
+
+			// Signatures are hedged, so signing twice gives distinct signatures
+			sig2, err := p.Sign(0, nil, input, HashTypeNone, "", MarshalingTypeASN1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sig.Signature == sig2.Signature {
+				t.Fatal("expected hedged signatures to differ")
+			}
+
+			// Import the public key only and verify with it
+			keyEntry := p.Keys["1"]
+			pub := &Policy{Name: "test-pub", Type: kt}
+			if err := pub.ImportPublicOrPrivate(ctx, storage, []byte(keyEntry.FormattedPublicKey), false, rand.Reader); err != nil {
+				t.Fatal(err)
+			}
+			valid, err = pub.VerifySignature(nil, input, HashTypeNone, "", MarshalingTypeASN1, sig.Signature)
+			if err != nil || !valid {
+				t.Fatalf("expected signature to verify with imported public key, err: %v", err)
+			}
+			if _, err := pub.Sign(0, nil, input, HashTypeNone, "", MarshalingTypeASN1); err == nil {
+				t.Fatal("expected signing without private key to fail")
+			}
+
+			// Complete the key version with its private key
+			der, err := MarshalPKCS8PQCPrivateKey(&PQCPrivateKey{Type: kt, Seed: keyEntry.Key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := pub.ImportPrivateKeyForVersion(ctx, storage, 1, der); err != nil {
+				t.Fatal(err)
+			}
+			sig, err = pub.Sign(0, nil, input, HashTypeNone, "", MarshalingTypeJWS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			valid, err = p.VerifySignature(nil, input, HashTypeNone, "", MarshalingTypeJWS, sig.Signature)
+			if err != nil || !valid {
+				t.Fatalf("expected signature of imported private key to verify, err: %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_MLKEM(t *testing.T) {
+	ctx := context.Background()
+	plaintext := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+
+	for _, kt := range []KeyType{KeyType_ML_KEM_768, KeyType_ML_KEM_1024} {
+		t.Run(kt.String(), func(t *testing.T) {
+			storage := &logical.InmemStorage{}
+			p := newPQCPolicy(t, storage, "test", kt)
+
+			ciphertext, err := p.Encrypt(0, nil, nil, plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decrypted, err := p.Decrypt(nil, nil, ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decrypted != plaintext {
+				t.Fatalf("expected %s, got %s", plaintext, decrypted)
+			}
+
+			// Tampering with the ciphertext is detected
+			raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, "vault:v1:"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw[len(raw)-1] ^= 1
+			if _, err := p.Decrypt(nil, nil, "vault:v1:"+base64.StdEncoding.EncodeToString(raw)); err == nil {
+				t.Fatal("expected tampered ciphertext not to decrypt")
+			}
+
+			// Import the private key in another policy, which decrypts the
+			// ciphertext of the original one
+			der, err := MarshalPKCS8PQCPrivateKey(&PQCPrivateKey{Type: kt, Seed: p.Keys["1"].Key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			imported := &Policy{Name: "imported", Type: kt}
+			if err := imported.Import(ctx, storage, der, rand.Reader); err != nil {
+				t.Fatal(err)
+			}
+			if imported.Keys["1"].FormattedPublicKey != p.Keys["1"].FormattedPublicKey {
+				t.Fatal("expected imported key to have the same public key")
+			}
+			decrypted, err = imported.Decrypt(nil, nil, ciphertext)
+			if err != nil || decrypted != plaintext {
+				t.Fatalf("expected imported key to decrypt, got %q, err: %v", decrypted, err)
+			}
+
+			// Keys of another type are rejected
+			other := &Policy{Name: "other", Type: KeyType_ML_DSA_65}
+			if err := other.Import(ctx, storage, der, rand.Reader); err == nil {
+				t.Fatal("expected import of ML-KEM key into ML-DSA policy to fail")
+			}
+		})
+	}
+}
+
+func TestPQC_KeyEncoding(t *testing.T) {
+	for _, kt := range []KeyType{KeyType_ML_DSA_44, KeyType_ML_DSA_65, KeyType_ML_DSA_87, KeyType_ML_KEM_768, KeyType_ML_KEM_1024} {
+		priv, err := generatePQCKey(kt, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		der, err := MarshalPKCS8PQCPrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParsePKCS8PQCPrivateKey(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Type != kt || string(parsed.Seed) != string(priv.Seed) {
+			t.Fatalf("bad parsed %s private key: %#v", kt, parsed)
+		}
+
+		pub, err := priv.Public()
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, err := formatPQCPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode([]byte(formatted))
+		if block == nil || block.Type != "PUBLIC KEY" {
+			t.Fatalf("bad PEM-encoded %s public key: %s", kt, formatted)
+		}
+		parsedPub, err := ParsePKIXPQCPublicKey(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsedPub.Type != kt || string(parsedPub.Key) != string(pub.Key) {
+			t.Fatalf("bad parsed %s public key", kt)
+		}
+
+		// The expanded form of the private key is only accepted along with
+		// its seed
+		expanded, err := parsed.expandedKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		both, err := asn1MarshalPKCS8(kt, pqcBothPrivateKey{Seed: priv.Seed, ExpandedKey: expanded})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParsePKCS8PQCPrivateKey(both); err != nil {
+			t.Fatalf("expected %s private key with seed and expanded key to parse: %v", kt, err)
+		}
+		expandedOnly, err := asn1MarshalPKCS8(kt, expanded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParsePKCS8PQCPrivateKey(expandedOnly); err == nil {
+			t.Fatalf("expected %s private key without seed to be rejected", kt)
+		}
+	}
+}
+
+// asn1MarshalPKCS8 encodes the given private key value in PKCS #8 form.
+func asn1MarshalPKCS8(kt KeyType, value any) ([]byte, error) {
+	privateKey, err := asn1.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: kt.pqcOID()},
+		PrivateKey: privateKey,
+	})
+}
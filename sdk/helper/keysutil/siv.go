@@ -0,0 +1,171 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	aeadsubtle "github.com/tink-crypto/tink-go/aead/subtle"
+	daeadsubtle "github.com/tink-crypto/tink-go/daead/subtle"
+
+	"github.com/openbao/openbao/sdk/v2/helper/errutil"
+)
+
+// AES-GCM-SIV keys are used deterministically, by always sealing with the
+// all-zero nonce: as the scheme is nonce-misuse resistant, this only reveals
+// whether two plaintexts (and their associated data) are equal. The nonce is
+// not part of the resulting ciphertext.
+var gcmSIVNonce = make([]byte, aeadsubtle.AESGCMSIVNonceSize)
+
+const gcmSIVTagSize = 16
+
+// isSIV returns whether the key type is a deterministic authenticated
+// encryption key type.
+func (kt KeyType) isSIV() bool {
+	switch kt {
+	case KeyType_AES256_SIV, KeyType_AES256_GCM_SIV:
+		return true
+	}
+	return false
+}
+
+// sivKeySize returns the size in bytes of keys of the given deterministic
+// authenticated encryption key type.
+func (kt KeyType) sivKeySize() int {
+	if kt == KeyType_AES256_SIV {
+		// RFC 5297 uses a MAC key and an encryption key of the same size
+		return daeadsubtle.AESSIVKeySize
+	}
+	return 32
+}
+
+// encryptSIV deterministically encrypts the plaintext, authenticating it
+// along with the associated data.
+func encryptSIV(kt KeyType, key, plaintext, associatedData []byte) ([]byte, error) {
+	switch kt {
+	case KeyType_AES256_SIV:
+		siv, err := daeadsubtle.NewAESSIV(key)
+		if err != nil {
+			return nil, err
+		}
+		return siv.EncryptDeterministically(plaintext, associatedData)
+	case KeyType_AES256_GCM_SIV:
+		return sealGCMSIV(key, gcmSIVNonce, plaintext, associatedData)
+	default:
+		return nil, fmt.Errorf("unsupported key type %v", kt)
+	}
+}
+
+// decryptSIV decrypts a ciphertext obtained from encryptSIV, checking its
+// authenticity along with the associated data.
+func decryptSIV(kt KeyType, key, ciphertext, associatedData []byte) ([]byte, error) {
+	switch kt {
+	case KeyType_AES256_SIV:
+		siv, err := daeadsubtle.NewAESSIV(key)
+		if err != nil {
+			return nil, err
+		}
+		return siv.DecryptDeterministically(ciphertext, associatedData)
+	case KeyType_AES256_GCM_SIV:
+		gcmSIV, err := aeadsubtle.NewAESGCMSIV(key)
+		if err != nil {
+			return nil, err
+		}
+		return gcmSIV.Decrypt(append(append([]byte{}, gcmSIVNonce...), ciphertext...), associatedData)
+	default:
+		return nil, fmt.Errorf("unsupported key type %v", kt)
+	}
+}
+
+// sealGCMSIV encrypts the plaintext with AES-GCM-SIV as specified by RFC
+// 8452, with the given nonce, returning the ciphertext followed by the tag.
+func sealGCMSIV(key, nonce, plaintext, associatedData []byte) ([]byte, error) {
+	if len(key) != 16 && len(key) != 32 {
+		return nil, fmt.Errorf("invalid AES-GCM-SIV key size %d", len(key))
+	}
+	if len(nonce) != aeadsubtle.AESGCMSIVNonceSize {
+		return nil, errors.New("invalid AES-GCM-SIV nonce size")
+	}
+
+	// Derive the per-nonce authentication and encryption keys
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	var input, output [aes.BlockSize]byte
+	copy(input[4:], nonce)
+	derived := make([]byte, 16+len(key))
+	for i := 0; i < len(derived)/8; i++ {
+		binary.LittleEndian.PutUint32(input[:4], uint32(i))
+		block.Encrypt(output[:], input[:])
+		copy(derived[8*i:], output[:8])
+	}
+	authKey, encKey := derived[:16], derived[16:]
+
+	// Compute the tag over the associated data, the plaintext and their
+	// lengths
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(associatedData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+	polyval, err := aeadsubtle.NewPolyval(authKey)
+	if err != nil {
+		return nil, err
+	}
+	polyval.Update(associatedData)
+	polyval.Update(plaintext)
+	polyval.Update(lengths[:])
+	s := polyval.Finish()
+	subtle.XORBytes(s[:len(nonce)], s[:len(nonce)], nonce)
+	s[15] &= 0x7f
+
+	encBlock, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	tag := make([]byte, gcmSIVTagSize)
+	encBlock.Encrypt(tag, s[:])
+
+	// Encrypt the plaintext in counter mode, starting from the tag with its
+	// most significant bit set and incrementing its first 32 bits
+	ciphertext := make([]byte, len(plaintext), len(plaintext)+gcmSIVTagSize)
+	var counter, keystream [aes.BlockSize]byte
+	copy(counter[:], tag)
+	counter[15] |= 0x80
+	ctr := binary.LittleEndian.Uint32(counter[:4])
+	for i := 0; i < len(plaintext); i += aes.BlockSize {
+		encBlock.Encrypt(keystream[:], counter[:])
+		subtle.XORBytes(ciphertext[i:], plaintext[i:], keystream[:])
+		ctr++
+		binary.LittleEndian.PutUint32(counter[:4], ctr)
+	}
+
+	return append(ciphertext, tag...), nil
+}
+
+// associatedDataFromFactories returns the associated data provided by the
+// given factories, if any. Externally provided AEADs are not supported by
+// deterministic authenticated encryption keys.
+func associatedDataFromFactories(factories []interface{}) ([]byte, error) {
+	var associatedData []byte
+	for index, rawFactory := range factories {
+		if rawFactory == nil {
+			continue
+		}
+		switch factory := rawFactory.(type) {
+		case AssociatedDataFactory:
+			var err error
+			associatedData, err = factory.GetAssociatedData()
+			if err != nil {
+				return nil, errutil.InternalError{Err: fmt.Sprintf("unable to get associated_data/additional_data from factory[%d]: %v", index, err)}
+			}
+		default:
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unknown type of factory[%d]: %T", index, rawFactory)}
+		}
+	}
+	return associatedData, nil
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package keysutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	aeadsubtle "github.com/tink-crypto/tink-go/aead/subtle"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+type testAssociatedData []byte
+
+func (a testAssociatedData) GetAssociatedData() ([]byte, error) {
+	return a, nil
+}
+
+// Check our AES-GCM-SIV sealing against the implementation opening the
+// ciphertexts, for all the boundary conditions of the counter mode.
+func TestSealGCMSIV(t *testing.T) {
+	for _, keySize := range []int{16, 32} {
+		key := make([]byte, keySize)
+		nonce := make([]byte, aeadsubtle.AESGCMSIVNonceSize)
+		rand.Read(key)
+		rand.Read(nonce)
+
+		gcmSIV, err := aeadsubtle.NewAESGCMSIV(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, size := range []int{0, 1, 15, 16, 17, 32, 100} {
+			plaintext := make([]byte, size)
+			associatedData := make([]byte, size/2)
+			rand.Read(plaintext)
+			rand.Read(associatedData)
+
+			sealed, err := sealGCMSIV(key, nonce, plaintext, associatedData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opened, err := gcmSIV.Decrypt(append(append([]byte{}, nonce...), sealed...), associatedData)
+			if err != nil {
+				t.Fatalf("failed to open %d byte plaintext sealed with %d byte key: %v", size, keySize, err)
+			}
+			if !bytes.Equal(opened, plaintext) {
+				t.Fatalf("bad opened %d byte plaintext sealed with %d byte key", size, keySize)
+			}
+		}
+	}
+}
+
+// Check our AES-GCM-SIV sealing against the test vectors of RFC 8452,
+// appendix C.
+func TestSealGCMSIV_RFC8452(t *testing.T) {
+	testCases := []struct {
+		key            string
+		nonce          string
+		plaintext      string
+		associatedData string
+		result         string
+	}{
+		// AEAD_AES_128_GCM_SIV
+		{
+			key:    "01000000000000000000000000000000",
+			nonce:  "030000000000000000000000",
+			result: "dc20e2d83f25705bb49e439eca56de25",
+		},
+		{
+			key:       "01000000000000000000000000000000",
+			nonce:     "030000000000000000000000",
+			plaintext: "0100000000000000",
+			result:    "b5d839330ac7b786578782fff6013b815b287c22493a364c",
+		},
+		{
+			key:       "01000000000000000000000000000000",
+			nonce:     "030000000000000000000000",
+			plaintext: "010000000000000000000000",
+			result:    "7323ea61d05932260047d942a4978db357391a0bc4fdec8b0d106639",
+		},
+		{
+			key:       "01000000000000000000000000000000",
+			nonce:     "030000000000000000000000",
+			plaintext: "01000000000000000000000000000000",
+			result:    "743f7c8077ab25f8624e2e948579cf77303aaf90f6fe21199c6068577437a0c4",
+		},
+		{
+			key:            "01000000000000000000000000000000",
+			nonce:          "030000000000000000000000",
+			plaintext:      "0200000000000000",
+			associatedData: "01",
+			result:         "1e6daba35669f4273b0a1a2560969cdf790d99759abd1508",
+		},
+		// AEAD_AES_256_GCM_SIV
+		{
+			key:    "0100000000000000000000000000000000000000000000000000000000000000",
+			nonce:  "030000000000000000000000",
+			result: "07f5f4169bbf55a8400cd47ea6fd400f",
+		},
+		{
+			key:       "0100000000000000000000000000000000000000000000000000000000000000",
+			nonce:     "030000000000000000000000",
+			plaintext: "0100000000000000",
+			result:    "c2ef328e5c71c83b843122130f7364b761e0b97427e3df28",
+		},
+		{
+			key:       "0100000000000000000000000000000000000000000000000000000000000000",
+			nonce:     "030000000000000000000000",
+			plaintext: "010000000000000000000000",
+			result:    "9aab2aeb3faa0a34aea8e2b18ca50da9ae6559e48fd10f6e5c9ca17e",
+		},
+		{
+			key:       "0100000000000000000000000000000000000000000000000000000000000000",
+			nonce:     "030000000000000000000000",
+			plaintext: "01000000000000000000000000000000",
+			result:    "85a01b63025ba19b7fd3ddfc033b3e76c9eac6fa700942702e90862383c6c366",
+		},
+	}
+
+	decode := func(s string) []byte {
+		t.Helper()
+
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	for i, tc := range testCases {
+		sealed, err := sealGCMSIV(decode(tc.key), decode(tc.nonce), decode(tc.plaintext), decode(tc.associatedData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(sealed); got != tc.result {
+			t.Fatalf("case %d: expected %s, got %s", i, tc.result, got)
+		}
+	}
+}
+
+func TestPolicy_SIV(t *testing.T) {
+	ctx := context.Background()
+	plaintext := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+
+	for _, kt := range []KeyType{KeyType_AES256_SIV, KeyType_AES256_GCM_SIV} {
+		t.Run(kt.String(), func(t *testing.T) {
+			storage := &logical.InmemStorage{}
+			lm, err := NewLockManager(true, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, _, err := lm.GetPolicy(ctx, PolicyRequest{
+				Upsert:  true,
+				Storage: storage,
+				KeyType: kt,
+				Name:    "test",
+			}, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Keys["1"].Key) != kt.sivKeySize() {
+				t.Fatalf("bad key size %d", len(p.Keys["1"].Key))
+			}
+
+			// Identical plaintexts and associated data give identical
+			// ciphertexts
+			ciphertext, err := p.EncryptWithFactory(0, nil, nil, plaintext, testAssociatedData("row-1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := p.EncryptWithFactory(0, nil, nil, plaintext, testAssociatedData("row-1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ciphertext != again {
+				t.Fatalf("expected deterministic ciphertexts, got %s and %s", ciphertext, again)
+			}
+			other, err := p.EncryptWithFactory(0, nil, nil, plaintext, testAssociatedData("row-2"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other == ciphertext {
+				t.Fatal("expected associated data to change the ciphertext")
+			}
+
+			decrypted, err := p.DecryptWithFactory(nil, nil, ciphertext, testAssociatedData("row-1"))
+			if err != nil || decrypted != plaintext {
+				t.Fatalf("bad decryption %q, err: %v", decrypted, err)
+			}
+			if _, err := p.DecryptWithFactory(nil, nil, ciphertext, testAssociatedData("row-2")); err == nil {
+				t.Fatal("expected decryption with other associated data to fail")
+			}
+			if _, err := p.Encrypt(0, nil, []byte("nonce"), plaintext); err == nil {
+				t.Fatal("expected nonce to be rejected")
+			}
+
+			// Rotation changes the ciphertexts, and older ones still decrypt
+			if err := p.Rotate(ctx, storage, rand.Reader); err != nil {
+				t.Fatal(err)
+			}
+			rotated, err := p.EncryptWithFactory(0, nil, nil, plaintext, testAssociatedData("row-1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rotated[:9] != "vault:v2:" || rotated[9:] == ciphertext[9:] {
+				t.Fatalf("bad ciphertext after rotation: %s", rotated)
+			}
+			decrypted, err = p.DecryptWithFactory(nil, nil, ciphertext, testAssociatedData("row-1"))
+			if err != nil || decrypted != plaintext {
+				t.Fatalf("bad decryption of older version %q, err: %v", decrypted, err)
+			}
+
+			// Derived keys are deterministic per context
+			derived, _, err := lm.GetPolicy(ctx, PolicyRequest{
+				Upsert:  true,
+				Storage: storage,
+				KeyType: kt,
+				Name:    "derived",
+				Derived: true,
+			}, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			one, err := derived.Encrypt(0, []byte("tenant-1"), nil, plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			two, err := derived.Encrypt(0, []byte("tenant-2"), nil, plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if one == two {
+				t.Fatal("expected contexts to give distinct ciphertexts")
+			}
+			decrypted, err = derived.Decrypt([]byte("tenant-1"), nil, one)
+			if err != nil || decrypted != plaintext {
+				t.Fatalf("bad derived decryption %q, err: %v", decrypted, err)
+			}
+			if _, err := derived.Decrypt([]byte("tenant-2"), nil, one); err == nil {
+				t.Fatal("expected decryption with other context to fail")
+			}
+
+			if _, _, err := lm.GetPolicy(ctx, PolicyRequest{
+				Upsert:     true,
+				Storage:    storage,
+				KeyType:    kt,
+				Name:       "convergent",
+				Derived:    true,
+				Convergent: true,
+			}, rand.Reader); err == nil {
+				t.Fatal("expected convergent encryption to be rejected")
+			}
+		})
+	}
+}
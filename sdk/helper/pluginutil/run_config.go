@@ -14,6 +14,7 @@ import (
 	"github.com/hashicorp/go-plugin"
 	"github.com/openbao/openbao/api/v2"
 	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"google.golang.org/grpc"
 )
 
 type PluginClientConfig struct {
@@ -38,6 +39,9 @@ type runConfig struct {
 	// Initialized with what's in PluginRunner.Env, but can be added to
 	env []string
 
+	// Additional options used when dialing the plugin
+	grpcDialOptions []grpc.DialOption
+
 	PluginClientConfig
 }
 
@@ -111,7 +115,8 @@ func (rc runConfig) makeConfig(ctx context.Context) (*plugin.ClientConfig, error
 		AllowedProtocols: []plugin.Protocol{
 			plugin.ProtocolGRPC,
 		},
-		AutoMTLS: rc.AutoMTLS,
+		AutoMTLS:        rc.AutoMTLS,
+		GRPCDialOptions: rc.grpcDialOptions,
 	}
 	return clientConfig, nil
 }
@@ -176,6 +181,14 @@ func MLock(mlock bool) RunOpt {
 	}
 }
 
+// GRPCDialOptions adds options used when dialing the plugin, such as
+// interceptors or stats handlers.
+func GRPCDialOptions(opts ...grpc.DialOption) RunOpt {
+	return func(rc *runConfig) {
+		rc.grpcDialOptions = append(rc.grpcDialOptions, opts...)
+	}
+}
+
 func (r *PluginRunner) RunConfig(ctx context.Context, opts ...RunOpt) (*plugin.Client, error) {
 	rc := runConfig{
 		command: r.Command,
@@ -0,0 +1,18 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package logical
+
+import "errors"
+
+// EventType identifies the kind of an event, namespaced by the plugin or
+// subsystem publishing it, such as "kv-v2/data-write".
+type EventType string
+
+// ErrEventsNotSupported is returned when publishing events is not supported
+// by the system view, such as from within an external plugin.
+var ErrEventsNotSupported = errors.New("sending events is not supported")
+
+// EventMetadataOperation is the metadata key under which the operation
+// that triggered an event is conventionally recorded.
+const EventMetadataOperation = "operation"
@@ -96,6 +96,11 @@ type SystemView interface {
 	// write forwarding (WriteForwardedPaths). This value will be templated
 	// in for the {{cluterId}} sentinel.
 	ClusterID(ctx context.Context) (string, error)
+
+	// SendEvent publishes an event of the given type to the event bus. The
+	// data path is relative to the mount and is used to restrict delivery to
+	// subscribers allowed to read it.
+	SendEvent(ctx context.Context, eventType EventType, dataPath string, metadata map[string]string) error
 }
 
 type PasswordPolicy interface {
@@ -110,6 +115,25 @@ type ExtendedSystemView interface {
 	// APILockShouldBlockRequest returns whether a namespace for the requested
 	// mount is locked and should be blocked
 	APILockShouldBlockRequest() (bool, error)
+
+	// CheckSubRequest returns whether the client of the request being handled
+	// may perform the given operation with the given data on a path relative
+	// to the mount, for handlers acting on several paths at once.
+	CheckSubRequest(ctx context.Context, op Operation, path string, data map[string]interface{}) (bool, error)
+
+	// HandleSubRequest handles a request, on a path relative to the namespace
+	// of the mount, on behalf of the client of the request being handled, as
+	// if the client had sent it: it is audited, subject to quotas and allowed
+	// only if the client's policies allow it.
+	HandleSubRequest(ctx context.Context, req *Request) (*Response, error)
+
+	// LoginSubRequest authenticates the client of the request being handled
+	// by logging it in with the given login request to the auth method with
+	// the given mount accessor, in the namespace of the mount. The login is
+	// audited and subject to quotas, user lockout and MFA like any other.
+	// The resulting auth is returned, without creating a token, provided that
+	// its policies allow the given operation on a path relative to the mount.
+	LoginSubRequest(ctx context.Context, mountAccessor string, login *Request, op Operation, path string) (*Auth, error)
 }
 
 type PasswordGenerator func() (password string, err error)
@@ -258,6 +282,10 @@ func (d StaticSystemView) ClusterID(ctx context.Context) (string, error) {
 	return d.ClusterUUID, nil
 }
 
+func (d StaticSystemView) SendEvent(_ context.Context, _ EventType, _ string, _ map[string]string) error {
+	return nil
+}
+
 func (d StaticSystemView) APILockShouldBlockRequest() (bool, error) {
 	return d.APILockShouldBlockRequestVal, nil
 }
@@ -103,6 +103,7 @@ type cacheTransaction struct {
 // Verify Cache satisfies the correct interfaces
 var (
 	_ ToggleablePurgemonster = &cache{}
+	_ CacheInvalidator       = &cache{}
 	_ Backend                = &cache{}
 
 	_ ToggleablePurgemonster = &transactionalCache{}
@@ -266,6 +267,16 @@ func (c *cache) Delete(ctx context.Context, key string) error {
 	return err
 }
 
+// Invalidate removes the given key from the cache, e.g. after it was
+// modified by another node.
+func (c *cache) Invalidate(ctx context.Context, key string) {
+	lock := locksutil.LockForKey(c.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.lru.Remove(key)
+}
+
 func (c *cache) List(ctx context.Context, prefix string) ([]string, error) {
 	// Always pass-through as this would be difficult to cache. For the same
 	// reason we don't lock as we can't reasonably know which locks to readlock
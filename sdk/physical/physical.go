@@ -143,6 +143,13 @@ type ToggleablePurgemonster interface {
 	SetEnabled(bool)
 }
 
+// CacheInvalidator is an optional interface for caches which can drop
+// individual keys. This is only used for the cache, on standby nodes which
+// learn of changes made by the active node.
+type CacheInvalidator interface {
+	Invalidate(ctx context.Context, key string)
+}
+
 // RedirectDetect is an optional interface that an HABackend
 // can implement. If they do, a redirect address can be automatically
 // detected.
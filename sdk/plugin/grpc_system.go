@@ -210,6 +210,10 @@ func (s gRPCSystemViewClient) ClusterID(ctx context.Context) (string, error) {
 	return reply.ClusterID, nil
 }
 
+func (s gRPCSystemViewClient) SendEvent(_ context.Context, _ logical.EventType, _ string, _ map[string]string) error {
+	return logical.ErrEventsNotSupported
+}
+
 type gRPCSystemViewServer struct {
 	pb.UnimplementedSystemViewServer
 
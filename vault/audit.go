@@ -112,6 +112,11 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	view.SetReadOnlyErr(logical.ErrSetupReadOnly)
 	defer view.SetReadOnlyErr(origViewReadOnlyErr)
 
+	filter, err := newAuditFilter(entry.Options[auditFilterOption])
+	if err != nil {
+		return err
+	}
+
 	// Lookup the new backend
 	backend, err := c.newAuditBackend(ctx, entry, view, entry.Options)
 	if err != nil {
@@ -154,7 +159,7 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	c.audit = newTable
 
 	// Register the backend
-	c.auditBroker.Register(entry.Path, backend, view, entry.Local)
+	c.auditBroker.Register(entry.Path, backend, view, entry.Local, filter)
 	if c.logger.IsInfo() {
 		c.logger.Info("enabled audit backend", "path", entry.Path, "type", entry.Type)
 	}
@@ -411,8 +416,13 @@ func (c *Core) setupAudits(ctx context.Context) error {
 			continue
 		}
 
+		filter, err := newAuditFilter(entry.Options[auditFilterOption])
+		if err != nil {
+			c.logger.Error("failed to parse audit filter, sending all entries to device", "path", entry.Path, "error", err)
+		}
+
 		// Mount the backend
-		broker.Register(entry.Path, backend, view, entry.Local)
+		broker.Register(entry.Path, backend, view, entry.Local, filter)
 
 		successCount++
 	}
@@ -472,10 +482,14 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 		Location: salt.DefaultLocation,
 	}
 
+	auditLogger := c.baseLogger.Named("audit")
+	c.AddLogger(auditLogger)
+
 	be, err := f(ctx, &audit.BackendConfig{
 		SaltView:   view,
 		SaltConfig: saltConfig,
 		Config:     conf,
+		Logger:     auditLogger.With("path", entry.Path),
 	})
 	if err != nil {
 		return nil, err
@@ -484,9 +498,6 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 		return nil, fmt.Errorf("nil backend returned from %q factory function", entry.Type)
 	}
 
-	auditLogger := c.baseLogger.Named("audit")
-	c.AddLogger(auditLogger)
-
 	switch entry.Type {
 	case "file":
 		key := "audit_file|" + entry.Path
@@ -15,6 +15,7 @@ import (
 	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/openbao/openbao/audit"
+	"github.com/openbao/openbao/helper/tracing"
 	"github.com/openbao/openbao/sdk/v2/logical"
 )
 
@@ -22,6 +23,7 @@ type backendEntry struct {
 	backend audit.Backend
 	view    BarrierView
 	local   bool
+	filter  *auditFilter
 }
 
 // AuditBroker is used to provide a single ingest interface to auditable
@@ -41,14 +43,16 @@ func NewAuditBroker(log log.Logger) *AuditBroker {
 	return b
 }
 
-// Register is used to add new audit backend to the broker
-func (a *AuditBroker) Register(name string, b audit.Backend, v BarrierView, local bool) {
+// Register is used to add new audit backend to the broker. A nil filter
+// sends all entries to the backend.
+func (a *AuditBroker) Register(name string, b audit.Backend, v BarrierView, local bool, filter *auditFilter) {
 	a.Lock()
 	defer a.Unlock()
 	a.backends[name] = backendEntry{
 		backend: b,
 		view:    v,
 		local:   local,
+		filter:  filter,
 	}
 }
 
@@ -134,9 +138,18 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	// Ensure at least one backend logs. Backends whose filter excludes the
+	// entry are not expected to log it; entries excluded by every backend
+	// are counted so that they are not silently lost.
 	anyLogged := false
+	filtered := 0
 	for name, be := range a.backends {
+		if !a.filterMatches(ctx, name, be, in) {
+			metrics.IncrCounter([]string{"audit", name, "log_request_filtered"}, 1)
+			filtered++
+			continue
+		}
+
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
@@ -146,7 +159,9 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 		in.Request.Headers = transHeaders
 
 		start := time.Now()
-		lrErr := be.backend.LogRequest(ctx, in)
+		spanCtx, span := tracing.StartSpan(ctx, "audit.logRequest", tracing.AttrAuditDevice.String(name))
+		lrErr := be.backend.LogRequest(spanCtx, in)
+		tracing.EndSpan(span, lrErr)
 		metrics.MeasureSince([]string{"audit", name, "log_request"}, start)
 		if lrErr != nil {
 			a.logger.Error("backend failed to log request", "backend", name, "error", lrErr)
@@ -154,7 +169,11 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	switch {
+	case len(a.backends) == 0:
+	case filtered == len(a.backends):
+		metrics.IncrCounter([]string{"audit", "log_request_filtered_all"}, 1)
+	case !anyLogged:
 		retErr = multierror.Append(retErr, errors.New("no audit backend succeeded in logging the request"))
 	}
 
@@ -199,9 +218,18 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	// Ensure at least one backend logs. Backends whose filter excludes the
+	// entry are not expected to log it; entries excluded by every backend
+	// are counted so that they are not silently lost.
 	anyLogged := false
+	filtered := 0
 	for name, be := range a.backends {
+		if !a.filterMatches(ctx, name, be, in) {
+			metrics.IncrCounter([]string{"audit", name, "log_response_filtered"}, 1)
+			filtered++
+			continue
+		}
+
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
@@ -211,7 +239,9 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 		in.Request.Headers = transHeaders
 
 		start := time.Now()
-		lrErr := be.backend.LogResponse(ctx, in)
+		spanCtx, span := tracing.StartSpan(ctx, "audit.logResponse", tracing.AttrAuditDevice.String(name))
+		lrErr := be.backend.LogResponse(spanCtx, in)
+		tracing.EndSpan(span, lrErr)
 		metrics.MeasureSince([]string{"audit", name, "log_response"}, start)
 		if lrErr != nil {
 			a.logger.Error("backend failed to log response", "backend", name, "error", lrErr)
@@ -219,13 +249,30 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	switch {
+	case len(a.backends) == 0:
+	case filtered == len(a.backends):
+		metrics.IncrCounter([]string{"audit", "log_response_filtered_all"}, 1)
+	case !anyLogged:
 		retErr = multierror.Append(retErr, errors.New("no audit backend succeeded in logging the response"))
 	}
 
 	return retErr.ErrorOrNil()
 }
 
+// filterMatches reports whether the entry should be sent to the backend.
+// Entries are sent if the filter cannot be evaluated, as dropping them would
+// be worse than logging too much.
+func (a *AuditBroker) filterMatches(ctx context.Context, name string, be backendEntry, in *logical.LogInput) bool {
+	matches, err := be.filter.matches(ctx, in)
+	if err != nil {
+		a.logger.Warn("failed to evaluate audit filter, logging entry", "backend", name, "error", err)
+		return true
+	}
+
+	return matches
+}
+
 func (a *AuditBroker) Invalidate(ctx context.Context, key string) {
 	// For now we ignore the key as this would only apply to salts. We just
 	// sort of brute force it on each one.
@@ -0,0 +1,104 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-bexpr"
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// auditFilterOption is the audit device option holding the filter
+// expression deciding which entries are sent to the device.
+const auditFilterOption = "filter"
+
+// auditFilter decides whether an audit entry is sent to a device.
+type auditFilter struct {
+	evaluator *bexpr.Evaluator
+}
+
+// auditFilterDatum holds the fields of an audit entry a filter expression
+// may reference.
+type auditFilterDatum struct {
+	// Type is either "request" or "response".
+	Type           string `bexpr:"type"`
+	MountType      string `bexpr:"mount_type"`
+	MountPoint     string `bexpr:"mount_point"`
+	MountAccessor  string `bexpr:"mount_accessor"`
+	Namespace      string `bexpr:"namespace"`
+	Operation      string `bexpr:"operation"`
+	Path           string `bexpr:"path"`
+	ClientEntityID string `bexpr:"client_entity_id"`
+	TokenType      string `bexpr:"token_type"`
+	RemoteAddress  string `bexpr:"remote_address"`
+	Error          bool   `bexpr:"error"`
+}
+
+// newAuditFilter parses the filter expression. An empty expression returns
+// a nil filter, which matches all entries.
+func newAuditFilter(expression string) (*auditFilter, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, nil
+	}
+
+	evaluator, err := bexpr.CreateEvaluator(expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse audit filter: %w", err)
+	}
+
+	// Evaluate against an empty entry so that references to unknown fields
+	// are rejected when the device is enabled rather than on every request.
+	if _, err := evaluator.Evaluate(auditFilterDatum{}); err != nil {
+		return nil, fmt.Errorf("invalid audit filter: %w", err)
+	}
+
+	return &auditFilter{
+		evaluator: evaluator,
+	}, nil
+}
+
+// matches reports whether the entry should be sent to the device.
+func (f *auditFilter) matches(ctx context.Context, in *logical.LogInput) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+
+	return f.evaluator.Evaluate(newAuditFilterDatum(ctx, in))
+}
+
+func newAuditFilterDatum(ctx context.Context, in *logical.LogInput) auditFilterDatum {
+	datum := auditFilterDatum{
+		Type:  in.Type,
+		Error: in.OuterErr != nil || (in.Response != nil && in.Response.IsError()),
+	}
+
+	if ns, err := namespace.FromContext(ctx); err == nil {
+		datum.Namespace = ns.Path
+	}
+
+	if req := in.Request; req != nil {
+		datum.MountType = req.MountType
+		datum.MountPoint = req.MountPoint
+		datum.MountAccessor = req.MountAccessor
+		datum.Operation = string(req.Operation)
+		datum.Path = req.Path
+		datum.ClientEntityID = req.EntityID
+		if req.Connection != nil {
+			datum.RemoteAddress = req.Connection.RemoteAddr
+		}
+	}
+
+	if in.Auth != nil {
+		datum.TokenType = in.Auth.TokenType.String()
+		if datum.ClientEntityID == "" {
+			datum.ClientEntityID = in.Auth.EntityID
+		}
+	}
+
+	return datum
+}
@@ -339,8 +339,8 @@ func TestAuditBroker_LogRequest(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := corehelpers.TestNoopAudit(t, nil)
 	a2 := corehelpers.TestNoopAudit(t, nil)
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -426,8 +426,8 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := corehelpers.TestNoopAudit(t, nil)
 	a2 := corehelpers.TestNoopAudit(t, nil)
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		NumUses:     10,
@@ -533,8 +533,8 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 	view := NewBarrierView(barrier, "headers/")
 	a1 := corehelpers.TestNoopAudit(t, nil)
 	a2 := corehelpers.TestNoopAudit(t, nil)
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -609,3 +609,95 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 }
+
+func TestAuditBroker_Filter(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+	pki := corehelpers.TestNoopAudit(t, nil)
+	rest := corehelpers.TestNoopAudit(t, nil)
+
+	pkiFilter, err := newAuditFilter(`mount_type == "pki" and operation == "update"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restFilter, err := newAuditFilter(`mount_type != "pki" and path != "auth/token/renew-self"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Register("pki", pki, nil, false, pkiFilter)
+	b.Register("rest", rest, nil, false, restFilter)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	ctx := namespace.RootContext(context.Background())
+
+	logRequest := func(mountType string, op logical.Operation, path string) error {
+		return b.LogRequest(ctx, &logical.LogInput{
+			Type: "request",
+			Request: &logical.Request{
+				Operation: op,
+				Path:      path,
+				MountType: mountType,
+			},
+		}, headersConf)
+	}
+
+	if err := logRequest("pki", logical.UpdateOperation, "pki/issue/example"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(pki.Req) != 1 || len(rest.Req) != 0 {
+		t.Fatalf("expected issuance to be sent to the pki device only, got %d and %d", len(pki.Req), len(rest.Req))
+	}
+
+	if err := logRequest("kv", logical.ReadOperation, "secret/foo"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(pki.Req) != 1 || len(rest.Req) != 1 {
+		t.Fatalf("expected kv read to be sent to the other device only, got %d and %d", len(pki.Req), len(rest.Req))
+	}
+
+	// Entries filtered from every device are not an error
+	if err := logRequest("token", logical.UpdateOperation, "auth/token/renew-self"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(pki.Req) != 1 || len(rest.Req) != 1 {
+		t.Fatalf("expected renewal to be dropped, got %d and %d", len(pki.Req), len(rest.Req))
+	}
+
+	// Failures of the devices the entry was sent to are still reported,
+	// even if other devices filtered it
+	rest.ReqErr = errors.New("failed")
+	err = logRequest("kv", logical.ReadOperation, "secret/foo")
+	if !errwrap.Contains(err, "no audit backend succeeded in logging the request") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestAuditFilter_Parse(t *testing.T) {
+	filter, err := newAuditFilter("")
+	if err != nil || filter != nil {
+		t.Fatalf("expected empty filter to match everything, got %v, %v", filter, err)
+	}
+
+	for _, expr := range []string{
+		`mount_type ==`,
+		`unknown_field == "foo"`,
+	} {
+		if _, err := newAuditFilter(expr); err == nil {
+			t.Fatalf("expected error parsing %q", expr)
+		}
+	}
+
+	filter, err = newAuditFilter(`namespace == "" and error == true and client_entity_id == "entity"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := filter.matches(namespace.RootContext(context.Background()), &logical.LogInput{
+		Request:  &logical.Request{EntityID: "entity"},
+		OuterErr: errors.New("permission denied"),
+	})
+	if err != nil || !matches {
+		t.Fatalf("expected filter to match, got %v, %v", matches, err)
+	}
+}
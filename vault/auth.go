@@ -59,6 +59,11 @@ var (
 
 // enableCredential is used to enable a new credential backend
 func (c *Core) enableCredential(ctx context.Context, entry *MountEntry) error {
+	// Namespaced token stores are only created alongside their namespace
+	if entry.Type == mountTypeNSToken {
+		return logical.CodedError(403, fmt.Sprintf("auth type of %q is not mountable", entry.Type))
+	}
+
 	// Enable credential internally
 	if err := c.enableCredentialInternal(ctx, entry, MountTableUpdateStorage); err != nil {
 		return err
@@ -1074,7 +1079,11 @@ func (c *Core) setupCredentials(ctx context.Context) error {
 				postUnsealLogger.Error("skipping initialization for nil auth backend")
 				return
 			}
-			if !strutil.StrListContains(singletonMounts, localEntry.Type) {
+			switch {
+			case c.ReplicationState().HasState(consts.ReplicationPerformanceStandby):
+				// Standbys serving reads must leave writes to the active node
+				view.SetReadOnlyErr(logical.ErrReadOnly)
+			case !strutil.StrListContains(singletonMounts, localEntry.Type):
 				view.SetReadOnlyErr(origViewReadOnlyErr)
 			}
 
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/openbao/openbao/helper/tracing"
 	"github.com/openbao/openbao/sdk/v2/logical"
 	"github.com/openbao/openbao/sdk/v2/physical"
 	"go.uber.org/atomic"
@@ -761,7 +762,12 @@ func (b *AESGCMBarrier) updateRootKeyCommon(key []byte) (*Keyring, error) {
 }
 
 // Put is used to insert or update an entry
-func (b *AESGCMBarrier) Put(ctx context.Context, entry *logical.StorageEntry) error {
+func (b *AESGCMBarrier) Put(ctx context.Context, entry *logical.StorageEntry) (retErr error) {
+	ctx, span := tracing.StartSpan(ctx, "barrier.put")
+	defer func() {
+		tracing.EndSpan(span, retErr)
+	}()
+
 	return b.putWithBackend(ctx, b.backend, entry)
 }
 
@@ -797,7 +803,12 @@ func (b *AESGCMBarrier) putInternal(ctx context.Context, backend physical.Backen
 }
 
 // Get is used to fetch an entry
-func (b *AESGCMBarrier) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+func (b *AESGCMBarrier) Get(ctx context.Context, key string) (_ *logical.StorageEntry, retErr error) {
+	ctx, span := tracing.StartSpan(ctx, "barrier.get")
+	defer func() {
+		tracing.EndSpan(span, retErr)
+	}()
+
 	return b.lockSwitchedGet(ctx, b.backend, key, true)
 }
 
@@ -867,7 +878,12 @@ func (b *AESGCMBarrier) lockSwitchedGet(ctx context.Context, backend physical.Ba
 }
 
 // Delete is used to permanently delete an entry
-func (b *AESGCMBarrier) Delete(ctx context.Context, key string) error {
+func (b *AESGCMBarrier) Delete(ctx context.Context, key string) (retErr error) {
+	ctx, span := tracing.StartSpan(ctx, "barrier.delete")
+	defer func() {
+		tracing.EndSpan(span, retErr)
+	}()
+
 	return b.deleteWithBackend(ctx, b.backend, key)
 }
 
@@ -885,8 +901,14 @@ func (b *AESGCMBarrier) deleteWithBackend(ctx context.Context, backend physical.
 
 // List is used to list all the keys under a given
 // prefix, up to the next prefix.
-func (b *AESGCMBarrier) List(ctx context.Context, prefix string) ([]string, error) {
+func (b *AESGCMBarrier) List(ctx context.Context, prefix string) (_ []string, retErr error) {
 	defer metrics.MeasureSince([]string{"barrier", "list"}, time.Now())
+
+	ctx, span := tracing.StartSpan(ctx, "barrier.list")
+	defer func() {
+		tracing.EndSpan(span, retErr)
+	}()
+
 	b.l.RLock()
 	sealed := b.sealed
 	b.l.RUnlock()
@@ -899,7 +921,12 @@ func (b *AESGCMBarrier) List(ctx context.Context, prefix string) ([]string, erro
 
 // ListPage is used to list a subset of the keys under a given
 // prefix, up to the next prefix.
-func (b *AESGCMBarrier) ListPage(ctx context.Context, prefix string, after string, limit int) ([]string, error) {
+func (b *AESGCMBarrier) ListPage(ctx context.Context, prefix string, after string, limit int) (_ []string, retErr error) {
+	ctx, span := tracing.StartSpan(ctx, "barrier.list")
+	defer func() {
+		tracing.EndSpan(span, retErr)
+	}()
+
 	return b.listPageWithBackend(ctx, b.backend, prefix, after, limit)
 }
 
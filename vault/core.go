@@ -55,6 +55,7 @@ import (
 	"github.com/openbao/openbao/sdk/v2/physical"
 	sr "github.com/openbao/openbao/serviceregistration"
 	"github.com/openbao/openbao/vault/cluster"
+	"github.com/openbao/openbao/vault/eventbus"
 	"github.com/openbao/openbao/vault/quotas"
 	vaultseal "github.com/openbao/openbao/vault/seal"
 	"github.com/openbao/openbao/version"
@@ -373,6 +374,9 @@ type Core struct {
 	// policy store is used to manage named ACL policies
 	policyStore *PolicyStore
 
+	// namespace store is used to manage namespaces
+	namespaceStore *NamespaceStore
+
 	// token store is used to manage authentication tokens
 	tokenStore *TokenStore
 
@@ -554,6 +558,9 @@ type Core struct {
 	raftFollowerStates *raft.FollowerStates
 	// Stop channel for raft TLS rotations
 	raftTLSRotationStopCh chan struct{}
+	// raftAutoSnapshots runs the automatic snapshot schedules on the active
+	// node.
+	raftAutoSnapshots *raftAutoSnapshotManager
 
 	// Stores the root key for generating challenges for pending peers we are
 	// waiting to give answers. This is constant size unlike the earlier
@@ -574,6 +581,10 @@ type Core struct {
 
 	quotaManager *quotas.Manager
 
+	// events is the bus to which backends and core subsystems publish
+	// events, and from which clients subscribe to them
+	events *eventbus.Bus
+
 	clusterHeartbeatInterval time.Duration
 
 	// activeTime is set on active nodes indicating the time at which this node
@@ -603,6 +614,16 @@ type Core struct {
 	// disableSSCTokens is used to disable server side consistent token creation/usage
 	disableSSCTokens bool
 
+	// enableStandbyReads allows standby nodes on integrated storage to serve
+	// read requests locally; standbyReads holds their state while doing so
+	enableStandbyReads bool
+	standbyReads       *standbyReads
+
+	// pkcs11Libraries maps the names of the PKCS#11 libraries configured
+	// through kms_library stanzas to their paths. Managed keys reference
+	// libraries by name, so that only these libraries are ever loaded.
+	pkcs11Libraries map[string]string
+
 	// versionHistory is a map of vault versions to VaultVersion. The
 	// VaultVersion.TimestampInstalled when the version will denote when the version
 	// was first run. Note that because perf standbys should be upgraded first, and
@@ -768,6 +789,14 @@ type CoreConfig struct {
 	// DisableSSCTokens is used to disable the use of server side consistent tokens
 	DisableSSCTokens bool
 
+	// EnableStandbyReads lets standby nodes on integrated storage serve
+	// read requests locally instead of forwarding them to the active node
+	EnableStandbyReads bool
+
+	// PKCS11Libraries maps the names of the PKCS#11 libraries which managed
+	// keys may use to their paths
+	PKCS11Libraries map[string]string
+
 	EffectiveSDKVersion string
 
 	RollbackPeriod time.Duration
@@ -949,6 +978,9 @@ func CreateCore(conf *CoreConfig) (*Core, error) {
 		disableAutopilot:               conf.DisableAutopilot,
 		enableResponseHeaderHostname:   conf.EnableResponseHeaderHostname,
 		enableResponseHeaderRaftNodeID: conf.EnableResponseHeaderRaftNodeID,
+		enableStandbyReads:             conf.EnableStandbyReads,
+		standbyReads:                   newStandbyReads(),
+		pkcs11Libraries:                conf.PKCS11Libraries,
 		mountMigrationTracker:          &sync.Map{},
 		disableSSCTokens:               conf.DisableSSCTokens,
 		effectiveSDKVersion:            effectiveSDKVersion,
@@ -1142,6 +1174,10 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		return nil, err
 	}
 
+	eventsLogger := conf.Logger.Named("events")
+	c.allLoggers = append(c.allLoggers, eventsLogger)
+	c.events = eventbus.NewBus(eventsLogger)
+
 	err = c.adjustForSealMigration(conf.UnwrapSeal)
 	if err != nil {
 		return nil, err
@@ -1217,6 +1253,15 @@ func (c *Core) configureCredentialsBackends(backends map[string]logical.Factory,
 		return NewTokenStore(ctx, tsLogger, c, config)
 	}
 
+	// Namespaced token stores share the root token store, which keeps the
+	// tokens of each namespace in that namespace's storage.
+	credentialBackends[mountTypeNSToken] = func(ctx context.Context, config *logical.BackendConfig) (logical.Backend, error) {
+		if c.tokenStore == nil {
+			return nil, errors.New("token store must be set up before namespaced token mounts")
+		}
+		return c.tokenStore, nil
+	}
+
 	c.credentialBackends = credentialBackends
 }
 
@@ -1237,6 +1282,7 @@ func (c *Core) configureLogicalBackends(backends map[string]logical.Factory, log
 
 	// Cubbyhole
 	logicalBackends[mountTypeCubbyhole] = CubbyholeBackendFactory
+	logicalBackends[mountTypeNSCubbyhole] = CubbyholeBackendFactory
 
 	// System
 	logicalBackends[mountTypeSystem] = func(ctx context.Context, config *logical.BackendConfig) (logical.Backend, error) {
@@ -1256,6 +1302,21 @@ func (c *Core) configureLogicalBackends(backends map[string]logical.Factory, log
 		return NewIdentityStore(ctx, c, config, identityLogger)
 	}
 
+	// The system and identity backends of a namespace are shared with the
+	// root namespace; both resolve the namespace from the request context.
+	logicalBackends[mountTypeNSSystem] = func(ctx context.Context, config *logical.BackendConfig) (logical.Backend, error) {
+		if c.systemBackend == nil {
+			return nil, errors.New("system backend must be set up before namespaced system mounts")
+		}
+		return c.systemBackend, nil
+	}
+	logicalBackends[mountTypeNSIdentity] = func(ctx context.Context, config *logical.BackendConfig) (logical.Backend, error) {
+		if c.identityStore == nil {
+			return nil, errors.New("identity store must be set up before namespaced identity mounts")
+		}
+		return c.identityStore, nil
+	}
+
 	c.logicalBackends = logicalBackends
 }
 
@@ -2165,6 +2226,12 @@ func (c *Core) sealInternalWithOptions(grabStateLock, keepHALock, performCleanup
 		<-c.standbyDoneCh
 		atomic.StoreUint32(c.keepHALockOnStepDown, 0)
 		c.logger.Debug("runStandby done")
+
+		if c.standbyReads.ready.Load() {
+			if err := c.teardownStandbyReads(); err != nil {
+				c.logger.Error("error tearing down standby reads state", "error", err)
+			}
+		}
 	}
 
 	// Perform additional cleanup upon sealing.
@@ -2244,6 +2311,9 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 	if err := c.setupPluginCatalog(ctx); err != nil {
 		return err
 	}
+	if err := c.setupNamespaceStore(ctx); err != nil {
+		return err
+	}
 	if err := c.loadMounts(ctx); err != nil {
 		return err
 	}
@@ -2472,6 +2542,9 @@ func (c *Core) preSeal() error {
 	if err := c.unloadMounts(context.Background()); err != nil {
 		result = multierror.Append(result, fmt.Errorf("error unloading mounts: %w", err))
 	}
+	if err := c.teardownNamespaceStore(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("error tearing down namespace store: %w", err))
+	}
 
 	if c.autoRotateCancel != nil {
 		c.autoRotateCancel()
@@ -2613,9 +2686,11 @@ func (c *Core) adjustForSealMigration(unwrapSeal Seal) error {
 		// from shamir.
 
 		switch {
-		case existBarrierSealConfig.Type == c.seal.BarrierType().String():
-			// We have the same barrier type and the unwrap seal is nil so we're not
-			// migrating from same to same, IOW we assume it's not a migration.
+		case sealTypeMatches(existBarrierSealConfig.Type, c.seal):
+			// We have the same barrier type, or seals were added to or
+			// removed from a multi-seal configuration, and the unwrap seal is
+			// nil so we're not migrating from same to same, IOW we assume
+			// it's not a migration.
 			return nil
 		case c.seal.BarrierType() == wrapping.WrapperTypeShamir:
 			// The stored barrier config is not shamir, there is no disabled seal
@@ -2627,6 +2702,11 @@ func (c *Core) adjustForSealMigration(unwrapSeal Seal) error {
 			// The configured seal is not Shamir, the stored seal config is Shamir.
 			// This is a migration away from Shamir.
 			unwrapSeal = NewDefaultSeal(vaultseal.NewAccess(aeadwrapper.NewShamirWrapper()))
+		case existBarrierSealConfig.Type == vaultseal.WrapperTypeMultiSeal.String():
+			// The keys are wrapped under several seals, which a single seal
+			// cannot unwrap.
+			return fmt.Errorf("cannot use a single %q seal with keys wrapped under several seals, set enable_multiseal to remove seals",
+				c.seal.BarrierType())
 		default:
 			// We know at this point that there is a configured non-Shamir seal,
 			// that it does not match the stored non-Shamir seal config, and that
@@ -3089,7 +3169,17 @@ func (c *Core) setupQuotas(ctx context.Context) error {
 		return nil
 	}
 
-	return c.quotaManager.Setup(ctx, c.systemBarrierView)
+	return c.quotaManager.Setup(ctx, c.systemBarrierView, c.walkLeasesForQuotas)
+}
+
+// walkLeasesForQuotas walks the leases of the expiration manager, if set up, to
+// count them against lease count quotas.
+func (c *Core) walkLeasesForQuotas(ctx context.Context, walkFn func(*quotas.Request) bool) error {
+	if c.expiration == nil {
+		return nil
+	}
+
+	return c.expiration.walkLeasesForQuotas(ctx, walkFn)
 }
 
 // ApplyRateLimitQuota checks the request against all the applicable quota rules.
@@ -28,11 +28,15 @@ func coreInit(c *Core, conf *CoreConfig) error {
 }
 
 func (c *Core) barrierViewForNamespace(namespaceId string) (BarrierView, error) {
-	if namespaceId != namespace.RootNamespaceID {
+	if namespaceId == namespace.RootNamespaceID {
+		return c.systemBarrierView, nil
+	}
+
+	if c.namespaceStore == nil || c.namespaceStore.GetNamespace(namespaceId) == nil {
 		return nil, errors.New("failed to find barrier view for non-root namespace")
 	}
 
-	return c.systemBarrierView, nil
+	return NewBarrierView(c.barrier, namespaceBarrierPrefix+namespaceId+"/"+systemBarrierPrefix), nil
 }
 
 func preSealPhysical(c *Core) {
@@ -46,7 +50,13 @@ func postUnsealPhysical(c *Core) error {
 }
 
 func (c *Core) collectNamespaces() []*namespace.Namespace {
-	return []*namespace.Namespace{
+	result := []*namespace.Namespace{
 		namespace.RootNamespace,
 	}
+
+	if c.namespaceStore != nil {
+		result = append(result, c.namespaceStore.ListNamespaces(namespace.RootNamespace, true)...)
+	}
+
+	return result
 }
@@ -7,16 +7,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/go-uuid"
 	"github.com/openbao/openbao/helper/identity"
 	"github.com/openbao/openbao/helper/namespace"
 	"github.com/openbao/openbao/helper/random"
+	"github.com/openbao/openbao/sdk/v2/helper/cidrutil"
 	"github.com/openbao/openbao/sdk/v2/helper/consts"
 	"github.com/openbao/openbao/sdk/v2/helper/license"
 	"github.com/openbao/openbao/sdk/v2/helper/pluginutil"
+	"github.com/openbao/openbao/sdk/v2/helper/policyutil"
 	"github.com/openbao/openbao/sdk/v2/helper/wrapping"
 	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault/quotas"
 	"github.com/openbao/openbao/version"
 )
 
@@ -137,6 +142,118 @@ func (e extendedSystemViewImpl) APILockShouldBlockRequest() (bool, error) {
 	return false, nil
 }
 
+// CheckSubRequest checks the given operation on a path of this mount against
+// the policies of the client whose request is currently being routed.
+func (e extendedSystemViewImpl) CheckSubRequest(ctx context.Context, op logical.Operation, path string, data map[string]interface{}) (bool, error) {
+	requester, ok := ctx.Value(ctxKeySubRequest{}).(*subRequester)
+	if !ok {
+		return false, errors.New("no client request to check sub-request against")
+	}
+
+	return requester.check(ctx, &logical.Request{
+		Operation: op,
+		Path:      e.mountEntry.Path + strings.TrimPrefix(path, "/"),
+		Data:      data,
+	})
+}
+
+// HandleSubRequest handles the given request, on a path relative to the
+// namespace of this mount, on behalf of the client whose request is currently
+// being routed. The request is audited and subject to quotas like any other.
+func (e extendedSystemViewImpl) HandleSubRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	requester, ok := ctx.Value(ctxKeySubRequest{}).(*subRequester)
+	if !ok {
+		return nil, errors.New("no client request to handle sub-request for")
+	}
+
+	return requester.route(ctx, req)
+}
+
+// LoginSubRequest logs the client in with the given login request, on a path
+// relative to the auth method with the given accessor, and checks the policies
+// of the resulting auth against the given operation on a path of this mount.
+// It lets backends authenticate clients which cannot send a token, such as EST
+// clients. The login goes through the same quotas, audit, lockout, identity
+// and MFA handling as any other login, but no token is created.
+func (e extendedSystemViewImpl) LoginSubRequest(ctx context.Context, mountAccessor string, login *logical.Request, op logical.Operation, path string) (*logical.Auth, error) {
+	entry := e.core.router.MatchingMountByAccessor(mountAccessor)
+	if entry == nil || entry.Table != credentialTableType || entry.NamespaceID != e.mountEntry.NamespaceID {
+		return nil, fmt.Errorf("no auth method with accessor %q in the namespace of the mount", mountAccessor)
+	}
+
+	ns := e.mountEntry.Namespace()
+	ctx = namespace.ContextWithNamespace(ctx, ns)
+
+	// Only login paths may be reached without a token
+	login.Path = entry.APIPathNoNamespace() + strings.TrimPrefix(login.Path, "/")
+	if !e.core.router.LoginPath(ctx, login.Path) {
+		return nil, fmt.Errorf("path %q is not a login path", login.Path)
+	}
+
+	quotaReq := &quotas.Request{
+		Path:          login.Path,
+		MountPath:     strings.TrimPrefix(e.core.router.MatchingMount(ctx, login.Path), ns.Path),
+		NamespacePath: ns.Path,
+	}
+	if login.Connection != nil {
+		quotaReq.ClientAddress = login.Connection.RemoteAddr
+	}
+	quotaResp, err := e.core.ApplyRateLimitQuota(ctx, quotaReq)
+	if err != nil {
+		return nil, err
+	}
+	if !quotaResp.Allowed {
+		return nil, logical.ErrRateLimitQuotaExceeded
+	}
+
+	login.ID, err = uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	login.ClientToken = ""
+
+	resp, err := e.core.handleCancelableRequest(context.WithValue(ctx, ctxKeyAuthOnlyLogin{}, true), login)
+	if err != nil {
+		if errors.Is(err, logical.ErrInvalidRequest) || errors.Is(err, logical.ErrInvalidCredentials) || errors.Is(err, logical.ErrPermissionDenied) {
+			return nil, logical.ErrPermissionDenied
+		}
+		return nil, err
+	}
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		return nil, logical.ErrPermissionDenied
+	}
+	auth := resp.Auth
+
+	// The auth is bound to the CIDRs a token created from it would be
+	var remoteAddr string
+	if login.Connection != nil {
+		remoteAddr = login.Connection.RemoteAddr
+	}
+	if !cidrutil.RemoteAddrIsOk(remoteAddr, auth.BoundCIDRs) {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	_, identityPolicies, err := e.core.fetchEntityAndDerivedPolicies(ctx, ns, auth.EntityID, false)
+	if err != nil {
+		return nil, err
+	}
+	policies := policyutil.SanitizePolicies(append(auth.Policies, identityPolicies[ns.ID]...), !auth.NoDefaultPolicy)
+	acl, err := e.core.policyStore.ACL(ctx, nil, map[string][]string{ns.ID: policies})
+	if err != nil {
+		return nil, err
+	}
+
+	authResults := acl.AllowOperation(ctx, &logical.Request{
+		Operation: op,
+		Path:      e.mountEntry.Path + strings.TrimPrefix(path, "/"),
+	}, false)
+	if !authResults.Allowed {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	return auth, nil
+}
+
 func (d dynamicSystemView) DefaultLeaseTTL() time.Duration {
 	def, _ := d.fetchTTLs()
 	return def
@@ -0,0 +1,188 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package eventbus
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-uuid"
+	"github.com/ryanuber/go-glob"
+)
+
+const (
+	// defaultBufferSize is the number of events buffered per subscription
+	// before further events are dropped for that subscriber.
+	defaultBufferSize = 128
+)
+
+// ErrBusClosed is returned when subscribing to an event bus which has
+// already been shut down.
+var ErrBusClosed = errors.New("event bus is closed")
+
+// Event is a single notification published to the event bus.
+type Event struct {
+	// ID uniquely identifies the event.
+	ID string `json:"id"`
+
+	// EventType is the type of the event, e.g. "kv-v2/data-write".
+	EventType string `json:"event_type"`
+
+	// Timestamp is the time at which the event was published.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Namespace is the path of the namespace the event originated in.
+	Namespace string `json:"namespace"`
+
+	// MountPath is the path of the mount which published the event, if
+	// any, relative to its namespace.
+	MountPath string `json:"mount,omitempty"`
+
+	// PluginType is the type of the plugin which published the event.
+	PluginType string `json:"plugin_type,omitempty"`
+
+	// Path is the API path the event relates to, relative to its
+	// namespace. It is used to restrict delivery to subscribers which may
+	// read it.
+	Path string `json:"path,omitempty"`
+
+	// Metadata holds additional, event type specific information.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// FilterFunc decides whether an event may be delivered to a subscriber.
+type FilterFunc func(*Event) bool
+
+// Bus fans out published events to all matching subscribers. Publishing
+// never blocks: slow subscribers miss events once their buffer is full.
+type Bus struct {
+	logger log.Logger
+
+	lock          sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+	closed        bool
+}
+
+// Subscription receives the events matching its pattern on Events until
+// it is closed.
+type Subscription struct {
+	bus       *Bus
+	pattern   string
+	namespace string
+	filter    FilterFunc
+	ch        chan *Event
+	once      sync.Once
+
+	// Events delivers the matching events. It is closed once the
+	// subscription or the bus is closed.
+	Events <-chan *Event
+}
+
+// NewBus creates a new, empty event bus.
+func NewBus(logger log.Logger) *Bus {
+	return &Bus{
+		logger:        logger,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// NewEvent creates an event of the given type with a fresh ID and
+// timestamp.
+func NewEvent(eventType string) (*Event, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		ID:        id,
+		EventType: eventType,
+		Timestamp: time.Now().UTC(),
+		Metadata:  make(map[string]string),
+	}, nil
+}
+
+// Subscribe registers a subscription for all events whose type matches the
+// glob pattern and which originate in the given namespace path or any of
+// its children. If set, the filter is consulted for every matching event.
+func (b *Bus) Subscribe(pattern, namespacePath string, filter FilterFunc) (*Subscription, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+
+	ch := make(chan *Event, defaultBufferSize)
+	sub := &Subscription{
+		bus:       b,
+		pattern:   pattern,
+		namespace: namespacePath,
+		filter:    filter,
+		ch:        ch,
+		Events:    ch,
+	}
+	b.subscriptions[sub] = struct{}{}
+
+	return sub, nil
+}
+
+// Publish delivers the event to all matching subscribers.
+func (b *Bus) Publish(event *Event) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for sub := range b.subscriptions {
+		if !sub.matches(event) {
+			continue
+		}
+
+		select {
+		case sub.ch <- event:
+		default:
+			b.logger.Debug("subscriber buffer full, dropping event", "event_type", event.EventType, "event_id", event.ID)
+		}
+	}
+}
+
+// Close closes all subscriptions and rejects new ones.
+func (b *Bus) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for sub := range b.subscriptions {
+		delete(b.subscriptions, sub)
+		sub.once.Do(func() { close(sub.ch) })
+	}
+}
+
+// Close removes the subscription from the bus and closes its channel.
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+
+	delete(s.bus.subscriptions, s)
+	s.once.Do(func() { close(s.ch) })
+}
+
+func (s *Subscription) matches(event *Event) bool {
+	if len(event.Namespace) < len(s.namespace) || event.Namespace[:len(s.namespace)] != s.namespace {
+		return false
+	}
+	if !glob.Glob(s.pattern, event.EventType) {
+		return false
+	}
+	if s.filter != nil && !s.filter(event) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package eventbus
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent(t *testing.T, eventType, ns, path string) *Event {
+	t.Helper()
+
+	event, err := NewEvent(eventType)
+	require.NoError(t, err)
+	event.Namespace = ns
+	event.Path = path
+	return event
+}
+
+func requireEvent(t *testing.T, sub *Subscription, expected *Event) {
+	t.Helper()
+
+	select {
+	case event := <-sub.Events:
+		require.Equal(t, expected.ID, event.ID)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for event %q", expected.EventType)
+	}
+}
+
+func requireNoEvent(t *testing.T, sub *Subscription) {
+	t.Helper()
+
+	select {
+	case event := <-sub.Events:
+		t.Fatalf("unexpected event %q", event.EventType)
+	default:
+	}
+}
+
+func TestBus_PatternAndNamespace(t *testing.T) {
+	bus := NewBus(log.NewNullLogger())
+
+	kv, err := bus.Subscribe("kv-v2/*", "", nil)
+	require.NoError(t, err)
+	defer kv.Close()
+
+	nsAll, err := bus.Subscribe("*", "foo/", nil)
+	require.NoError(t, err)
+	defer nsAll.Close()
+
+	rootWrite := testEvent(t, "kv-v2/data-write", "", "secret/data/a")
+	bus.Publish(rootWrite)
+	requireEvent(t, kv, rootWrite)
+	requireNoEvent(t, nsAll)
+
+	childIssue := testEvent(t, "pki/issue", "foo/bar/", "pki/cert/01")
+	bus.Publish(childIssue)
+	requireNoEvent(t, kv)
+	requireEvent(t, nsAll, childIssue)
+
+	// Sibling namespaces sharing a prefix are not matched
+	siblingWrite := testEvent(t, "kv-v2/data-write", "foobar/", "secret/data/a")
+	bus.Publish(siblingWrite)
+	requireEvent(t, kv, siblingWrite)
+	requireNoEvent(t, nsAll)
+}
+
+func TestBus_Filter(t *testing.T) {
+	bus := NewBus(log.NewNullLogger())
+
+	sub, err := bus.Subscribe("*", "", func(event *Event) bool {
+		return event.Path == "secret/data/allowed"
+	})
+	require.NoError(t, err)
+	defer sub.Close()
+
+	bus.Publish(testEvent(t, "kv-v2/data-write", "", "secret/data/denied"))
+	requireNoEvent(t, sub)
+
+	allowed := testEvent(t, "kv-v2/data-write", "", "secret/data/allowed")
+	bus.Publish(allowed)
+	requireEvent(t, sub, allowed)
+}
+
+func TestBus_Close(t *testing.T) {
+	bus := NewBus(log.NewNullLogger())
+
+	sub, err := bus.Subscribe("*", "", nil)
+	require.NoError(t, err)
+
+	// Slow subscribers drop events rather than blocking publishers
+	for i := 0; i < defaultBufferSize+10; i++ {
+		bus.Publish(testEvent(t, "sys/mount-enable", "", "sys/mounts/a"))
+	}
+	require.Len(t, sub.Events, defaultBufferSize)
+
+	sub.Close()
+	sub.Close()
+
+	bus.Close()
+	_, err = bus.Subscribe("*", "", nil)
+	require.ErrorIs(t, err, ErrBusClosed)
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault/eventbus"
+)
+
+const (
+	// eventsSubscribePath is the path, relative to a namespace, on which
+	// clients subscribe to events.
+	eventsSubscribePath = "sys/events/subscribe/"
+
+	// Event types published by core subsystems.
+	eventTypeMountEnable  logical.EventType = "sys/mount-enable"
+	eventTypeMountDisable logical.EventType = "sys/mount-disable"
+	eventTypeAuthEnable   logical.EventType = "sys/auth-enable"
+	eventTypeAuthDisable  logical.EventType = "sys/auth-disable"
+	eventTypeLeaseRevoke  logical.EventType = "sys/lease-revoke"
+)
+
+// sendEvent publishes an event originating in the given namespace. The
+// path is relative to the namespace and is used to restrict delivery to
+// subscribers allowed to read it.
+func (c *Core) sendEvent(ns *namespace.Namespace, eventType logical.EventType, entry *MountEntry, eventPath string, metadata map[string]string) error {
+	if c.events == nil {
+		return nil
+	}
+	if ns == nil {
+		ns = namespace.RootNamespace
+	}
+
+	event, err := eventbus.NewEvent(string(eventType))
+	if err != nil {
+		return err
+	}
+
+	event.Namespace = ns.Path
+	event.Path = eventPath
+	if entry != nil {
+		event.MountPath = entry.APIPathNoNamespace()
+		event.PluginType = entry.Type
+	}
+	for k, v := range metadata {
+		event.Metadata[k] = v
+	}
+
+	c.events.Publish(event)
+	return nil
+}
+
+// sendCoreEvent publishes an event from a core subsystem, logging rather
+// than returning failures as events are best-effort.
+func (c *Core) sendCoreEvent(ctx context.Context, eventType logical.EventType, entry *MountEntry, eventPath string, metadata map[string]string) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		ns = namespace.RootNamespace
+	}
+
+	if err := c.sendEvent(ns, eventType, entry, eventPath, metadata); err != nil {
+		c.logger.Warn("failed to send event", "event_type", eventType, "error", err)
+	}
+}
+
+// SendEvent publishes an event on behalf of the backend mounted at the
+// system view's mount entry.
+func (d dynamicSystemView) SendEvent(ctx context.Context, eventType logical.EventType, dataPath string, metadata map[string]string) error {
+	if d.mountEntry == nil {
+		return errors.New("no mount entry associated with system view")
+	}
+
+	return d.core.sendEvent(d.mountEntry.Namespace(), eventType, d.mountEntry, d.mountEntry.APIPathNoNamespace()+strings.TrimPrefix(dataPath, "/"), metadata)
+}
+
+// EventSubscription is a subscription to the event bus on behalf of a
+// token. Only events the token may read are delivered, and the token must
+// be revalidated periodically for the subscription to stay open.
+type EventSubscription struct {
+	*eventbus.Subscription
+
+	core *Core
+	ns   *namespace.Namespace
+	req  *logical.Request
+
+	lock sync.RWMutex
+	acl  *ACL
+}
+
+// SubscribeEvents registers a subscription to the event pattern in the
+// request path which only receives events the request's token is allowed
+// to read. The request must already have been authorized, audited and
+// checked against quotas through HandleRequest.
+func (c *Core) SubscribeEvents(ctx context.Context, req *logical.Request) (*EventSubscription, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	if c.Sealed() {
+		return nil, consts.ErrSealed
+	}
+	if c.standby {
+		return nil, consts.ErrStandby
+	}
+
+	pattern := strings.TrimPrefix(req.Path, eventsSubscribePath)
+	if pattern == "" || pattern == req.Path {
+		return nil, logical.CodedError(400, "missing event type pattern")
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &EventSubscription{
+		core: c,
+		ns:   ns,
+		req: &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        req.Path,
+			ClientToken: req.ClientToken,
+			Connection:  req.Connection,
+		},
+	}
+	if err := sub.revalidateLocked(ctx); err != nil {
+		return nil, err
+	}
+
+	sub.Subscription, err = c.events.Subscribe(pattern, ns.Path, sub.allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// Revalidate checks that the subscription's token is still valid and
+// refreshes the policies used to filter events, as they may have changed
+// since the subscription was made. The subscription must be closed when
+// an error is returned.
+func (s *EventSubscription) Revalidate(ctx context.Context) error {
+	s.core.stateLock.RLock()
+	defer s.core.stateLock.RUnlock()
+
+	if s.core.Sealed() {
+		return consts.ErrSealed
+	}
+
+	return s.revalidateLocked(ctx)
+}
+
+func (s *EventSubscription) revalidateLocked(ctx context.Context) error {
+	// Use a fresh request so that the token entry is looked up again
+	// rather than taken from the cache of a previous check.
+	req := &logical.Request{
+		Operation:   s.req.Operation,
+		Path:        s.req.Path,
+		ClientToken: s.req.ClientToken,
+		Connection:  s.req.Connection,
+	}
+
+	ctx = namespace.ContextWithNamespace(ctx, s.ns)
+	if _, _, err := s.core.CheckToken(ctx, req, false); err != nil {
+		return err
+	}
+
+	acl, _, _, _, err := s.core.fetchACLTokenEntryAndEntity(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	s.acl = acl
+	s.lock.Unlock()
+
+	return nil
+}
+
+// allowed reports whether the subscription's token may read the path of
+// the event within the namespace it originated in.
+func (s *EventSubscription) allowed(event *eventbus.Event) bool {
+	if event.Path == "" || s.core.namespaceStore == nil {
+		return false
+	}
+
+	eventNS := s.core.namespaceStore.GetNamespaceByPath(event.Namespace)
+	if eventNS == nil {
+		return false
+	}
+
+	s.lock.RLock()
+	acl := s.acl
+	s.lock.RUnlock()
+
+	eventCtx := namespace.ContextWithNamespace(context.Background(), eventNS)
+	return acl.AllowOperation(eventCtx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      event.Path,
+	}, false).Allowed
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func testSubscribeEvents(t *testing.T, ctx context.Context, c *Core, token, pattern string) *EventSubscription {
+	t.Helper()
+
+	req := logical.TestRequest(t, logical.ReadOperation, eventsSubscribePath+pattern)
+	req.ClientToken = token
+	sub, err := c.SubscribeEvents(ctx, req)
+	require.NoError(t, err)
+	t.Cleanup(sub.Close)
+	return sub
+}
+
+func requireEventPath(t *testing.T, sub *EventSubscription, expectedPath string) {
+	t.Helper()
+
+	select {
+	case event := <-sub.Events:
+		require.Equal(t, expectedPath, event.Path)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for event on %q", expectedPath)
+	}
+}
+
+func requireNoEvents(t *testing.T, sub *EventSubscription) {
+	t.Helper()
+
+	select {
+	case event := <-sub.Events:
+		t.Fatalf("unexpected event on %q", event.Path)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCore_SubscribeEvents_ACLFiltering(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/policy/events")
+	req.ClientToken = root
+	req.Data["policy"] = `
+path "sys/events/subscribe/*" { capabilities = ["read"] }
+path "secret/allowed/*" { capabilities = ["read"] }
+path "foo/secret/allowed/*" { capabilities = ["read"] }
+`
+	_, err := c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	testMakeServiceTokenViaCore(t, c, root, "subscriber", "", []string{"events"})
+
+	ns := testCreateNamespace(t, ctx, c, root, "foo", nil)
+
+	sub := testSubscribeEvents(t, ctx, c, "subscriber", "kv*")
+
+	// Only events on readable paths are delivered
+	require.NoError(t, c.sendEvent(namespace.RootNamespace, "kv-v2/data-write", nil, "secret/denied/a", nil))
+	require.NoError(t, c.sendEvent(namespace.RootNamespace, "kv-v2/data-write", nil, "secret/allowed/a", nil))
+	requireEventPath(t, sub, "secret/allowed/a")
+
+	// Events of child namespaces are checked against the real namespace
+	require.NoError(t, c.sendEvent(ns, "kv-v2/data-write", nil, "secret/denied/b", nil))
+	require.NoError(t, c.sendEvent(ns, "kv-v2/data-write", nil, "secret/allowed/b", nil))
+	requireEventPath(t, sub, "secret/allowed/b")
+
+	// Events of types not matching the pattern are not delivered
+	require.NoError(t, c.sendEvent(namespace.RootNamespace, "pki/issue", nil, "secret/allowed/c", nil))
+	requireNoEvents(t, sub)
+
+	// Subscriptions in a child namespace do not see events of its parent
+	nsSub := testSubscribeEvents(t, namespace.ContextWithNamespace(ctx, ns), c, root, "*")
+	require.NoError(t, c.sendEvent(namespace.RootNamespace, "kv-v2/data-write", nil, "secret/allowed/d", nil))
+	requireNoEvents(t, nsSub)
+	require.NoError(t, c.sendEvent(ns, "kv-v2/data-write", nil, "secret/allowed/e", nil))
+	requireEventPath(t, nsSub, "secret/allowed/e")
+}
+
+func TestCore_SubscribeEvents_Revalidate(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/policy/events")
+	req.ClientToken = root
+	req.Data["policy"] = `
+path "sys/events/subscribe/*" { capabilities = ["read"] }
+path "secret/*" { capabilities = ["read"] }
+`
+	_, err := c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	testMakeServiceTokenViaCore(t, c, root, "subscriber", "", []string{"events"})
+
+	sub := testSubscribeEvents(t, ctx, c, "subscriber", "*")
+	require.NoError(t, sub.Revalidate(ctx))
+
+	// Policy changes apply to open subscriptions once revalidated
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/policy/events")
+	req.ClientToken = root
+	req.Data["policy"] = `
+path "sys/events/subscribe/*" { capabilities = ["read"] }
+path "other/*" { capabilities = ["read"] }
+`
+	_, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.NoError(t, sub.Revalidate(ctx))
+
+	require.NoError(t, c.sendEvent(namespace.RootNamespace, "kv-v2/data-write", nil, "secret/a", nil))
+	requireNoEvents(t, sub)
+
+	// Revoked tokens fail revalidation
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/revoke")
+	req.ClientToken = root
+	req.Data["token"] = "subscriber"
+	_, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, sub.Revalidate(ctx), logical.ErrPermissionDenied)
+
+	// and cannot subscribe anew
+	req = logical.TestRequest(t, logical.ReadOperation, eventsSubscribePath+"*")
+	req.ClientToken = "subscriber"
+	_, err = c.SubscribeEvents(ctx, req)
+	require.Error(t, err)
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/openbao/openbao/sdk/v2/helper/jsonutil"
 	"github.com/openbao/openbao/sdk/v2/helper/locksutil"
 	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault/quotas"
 	uberAtomic "go.uber.org/atomic"
 )
 
@@ -502,6 +503,7 @@ func (m *ExpirationManager) invalidate(key string) {
 				pending.timer.Stop()
 				m.pending.Delete(leaseID)
 				m.leaseCount--
+				m.releaseLeaseCountQuota(leaseID)
 			default:
 				// Update the lease in memory
 				m.updatePendingInternal(le)
@@ -518,6 +520,7 @@ func (m *ExpirationManager) invalidate(key string) {
 					m.irrevocableLeaseCount--
 
 					m.leaseCount--
+					m.releaseLeaseCountQuota(leaseID)
 				}
 				return
 			}
@@ -1044,6 +1047,15 @@ func (m *ExpirationManager) revokeCommon(ctx context.Context, leaseID string, fo
 		}
 		m.logger.Warn("finished revoking incorrectly non-expiring lease", "leaseID", le.LeaseID, "accessor", accessor)
 	}
+
+	if le.Secret != nil {
+		if err := m.core.sendEvent(le.namespace, eventTypeLeaseRevoke, nil, le.Path, map[string]string{
+			"lease_id": le.LeaseID,
+		}); err != nil {
+			m.logger.Warn("failed to send lease revocation event", "lease_id", le.LeaseID, "error", err)
+		}
+	}
+
 	return nil
 }
 
@@ -1493,6 +1505,10 @@ func (m *ExpirationManager) Register(ctx context.Context, req *logical.Request,
 				retErr = multierror.Append(retErr, fmt.Errorf("an additional error was encountered removing lease indexes associated with the newly-generated secret: %w", err))
 			}
 
+			m.pendingLock.Lock()
+			m.releaseLeaseCountQuota(leaseID)
+			m.pendingLock.Unlock()
+
 			m.deleteLockForLease(leaseID)
 		}
 	}()
@@ -1512,6 +1528,12 @@ func (m *ExpirationManager) Register(ctx context.Context, req *logical.Request,
 		}
 	}
 
+	// Count the lease against its lease count quota. It is released again
+	// below if the lease fails to be registered.
+	if err := m.applyLeaseCountQuota(ctx, leaseID, loginRole); err != nil {
+		return "", err
+	}
+
 	// Acquire the lock here so persistEntry and updatePending are atomic,
 	// although it is *very unlikely* that anybody could grab the lease ID
 	// before this function returns. (They could find it in an index, or
@@ -1606,12 +1628,20 @@ func (m *ExpirationManager) RegisterAuth(ctx context.Context, te *logical.TokenE
 		Version:     1,
 	}
 
+	// Count the lease against its lease count quota
+	if err := m.applyLeaseCountQuota(ctx, leaseID, loginRole); err != nil {
+		return err
+	}
+
 	leaseLock := m.lockForLeaseID(leaseID)
 	leaseLock.Lock()
 	defer leaseLock.Unlock()
 
 	// Encode the entry
 	if err := m.persistEntry(ctx, &le); err != nil {
+		m.pendingLock.Lock()
+		m.releaseLeaseCountQuota(leaseID)
+		m.pendingLock.Unlock()
 		return err
 	}
 
@@ -1820,6 +1850,7 @@ func (m *ExpirationManager) updatePendingInternal(le *leaseEntry) {
 			info.(pendingInfo).timer.Stop()
 			m.pending.Delete(le.LeaseID)
 			m.leaseCount--
+			m.releaseLeaseCountQuota(le.LeaseID)
 		}
 		return
 	}
@@ -1871,6 +1902,7 @@ func (m *ExpirationManager) updatePendingInternal(le *leaseEntry) {
 	}
 	if leaseCreated {
 		m.leaseCount++
+		m.countLeaseForQuotas(le)
 	}
 }
 
@@ -2469,8 +2501,123 @@ func (m *ExpirationManager) removeFromPending(ctx context.Context, leaseID strin
 		m.pending.Delete(leaseID)
 		if decrementCounters {
 			m.leaseCount--
+			m.releaseLeaseCountQuota(leaseID)
+		}
+	}
+}
+
+// leaseQuotaRequest returns the request used to find the lease count quota
+// applicable to the lease with the given ID. The path and namespace of the
+// lease are derived from its ID.
+func (m *ExpirationManager) leaseQuotaRequest(ctx context.Context, leaseID, loginRole string) (*quotas.Request, error) {
+	leaseNS, err := m.getNamespaceFromLeaseID(ctx, leaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	leasePath, _ := namespace.SplitIDFromString(leaseID)
+	leasePath = path.Dir(leasePath)
+	mountPath := m.core.router.MatchingMount(namespace.ContextWithNamespace(ctx, leaseNS), leasePath)
+
+	return &quotas.Request{
+		Type:          quotas.TypeLeaseCount,
+		Path:          leasePath,
+		Role:          loginRole,
+		NamespacePath: leaseNS.Path,
+		MountPath:     strings.TrimPrefix(mountPath, leaseNS.Path),
+		LeaseID:       leaseID,
+	}, nil
+}
+
+// applyLeaseCountQuota counts a new lease against the lease count quota
+// applicable to it, failing if the quota is exceeded.
+func (m *ExpirationManager) applyLeaseCountQuota(ctx context.Context, leaseID, loginRole string) error {
+	if m.core.quotaManager == nil {
+		return nil
+	}
+
+	req, err := m.leaseQuotaRequest(ctx, leaseID, loginRole)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.core.quotaManager.ApplyLeaseCountQuota(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.Allowed {
+		return fmt.Errorf("request path %q: %w", req.Path, quotas.ErrLeaseCountQuotaExceeded)
+	}
+	return nil
+}
+
+// countLeaseForQuotas counts a loaded or created lease against the lease
+// count quota applicable to it, if not counted already.
+// note: must be called with m.pendingLock held
+func (m *ExpirationManager) countLeaseForQuotas(le *leaseEntry) {
+	if m.core.quotaManager == nil {
+		return
+	}
+
+	action := quotas.LeaseActionCreated
+	if m.inRestoreMode() {
+		action = quotas.LeaseActionLoaded
+	}
+
+	req, err := m.leaseQuotaRequest(m.quitContext, le.LeaseID, le.LoginRole)
+	if err == nil {
+		err = m.core.quotaManager.HandleLeaseAction(m.quitContext, req, action)
+	}
+	if err != nil {
+		m.logger.Error("failed to count lease for lease count quotas", "lease_id", le.LeaseID, "error", err)
+	}
+}
+
+// releaseLeaseCountQuota releases a deleted lease from the lease count quota
+// it was counted against.
+// note: must be called with m.pendingLock held
+func (m *ExpirationManager) releaseLeaseCountQuota(leaseID string) {
+	if m.core.quotaManager == nil {
+		return
+	}
+
+	req := &quotas.Request{
+		Type:    quotas.TypeLeaseCount,
+		LeaseID: leaseID,
+	}
+	if err := m.core.quotaManager.HandleLeaseAction(m.quitContext, req, quotas.LeaseActionDeleted); err != nil {
+		m.logger.Error("failed to release lease from lease count quotas", "lease_id", leaseID, "error", err)
+	}
+}
+
+// walkLeasesForQuotas walks the leases counted by the expiration manager,
+// passing the lease count quota request of each lease to walkFn. Leases cannot
+// be created or deleted while they are walked.
+func (m *ExpirationManager) walkLeasesForQuotas(ctx context.Context, walkFn func(*quotas.Request) bool) error {
+	m.pendingLock.RLock()
+	defer m.pendingLock.RUnlock()
+
+	callback := func(key, value interface{}) bool {
+		var loginRole string
+		switch info := value.(type) {
+		case pendingInfo:
+			loginRole = info.loginRole
+		case *leaseEntry:
+			loginRole = info.LoginRole
+		}
+
+		req, err := m.leaseQuotaRequest(ctx, key.(string), loginRole)
+		if err != nil {
+			m.logger.Warn("failed to count lease for lease count quotas", "lease_id", key, "error", err)
+			return true
 		}
+		return walkFn(req)
 	}
+
+	m.pending.Range(callback)
+	m.irrevocable.Range(callback)
+
+	return nil
 }
 
 // Marks a pending lease as irrevocable. Because the lease is being moved from
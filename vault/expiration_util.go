@@ -10,22 +10,36 @@ import (
 	"github.com/openbao/openbao/sdk/v2/logical"
 )
 
-func (m *ExpirationManager) leaseView(*namespace.Namespace) BarrierView {
-	return m.idView
+func (m *ExpirationManager) namespaceView(ns *namespace.Namespace) BarrierView {
+	return NewBarrierView(m.core.barrier, namespaceBarrierPrefix+ns.ID+"/"+systemBarrierPrefix+expirationSubPath)
 }
 
-func (m *ExpirationManager) tokenIndexView(*namespace.Namespace) BarrierView {
-	return m.tokenView
+func (m *ExpirationManager) leaseView(ns *namespace.Namespace) BarrierView {
+	if ns == nil || ns.ID == namespace.RootNamespaceID {
+		return m.idView
+	}
+
+	return m.namespaceView(ns).SubView(leaseViewPrefix)
+}
+
+func (m *ExpirationManager) tokenIndexView(ns *namespace.Namespace) BarrierView {
+	if ns == nil || ns.ID == namespace.RootNamespaceID {
+		return m.tokenView
+	}
+
+	return m.namespaceView(ns).SubView(tokenViewPrefix)
 }
 
 func (m *ExpirationManager) collectLeases() (map[*namespace.Namespace][]string, int, error) {
 	leaseCount := 0
 	existing := make(map[*namespace.Namespace][]string)
-	keys, err := logical.CollectKeys(m.quitContext, m.leaseView(namespace.RootNamespace))
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to scan for leases: %w", err)
+	for _, ns := range m.core.collectNamespaces() {
+		keys, err := logical.CollectKeys(m.quitContext, m.leaseView(ns))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan for leases in namespace %q: %w", ns.Path, err)
+		}
+		existing[ns] = keys
+		leaseCount += len(keys)
 	}
-	existing[namespace.RootNamespace] = keys
-	leaseCount += len(keys)
 	return existing, leaseCount, nil
 }
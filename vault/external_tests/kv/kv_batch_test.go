@@ -0,0 +1,191 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/openbao/openbao/api/v2"
+	logicalKv "github.com/openbao/openbao/builtin/logical/kv"
+	vaulthttp "github.com/openbao/openbao/http"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+)
+
+// TestKV_Batch applies batches of operations to a KVv2 mount and verifies
+// that they are applied atomically and that each operation is authorized
+// separately.
+func TestKV_Batch(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": logicalKv.VersionedKVFactory,
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	c := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	// Mount a KVv2 backend
+	err := c.Sys().Mount("kv", &api.MountInput{
+		Type: "kv-v2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretRaw, err := kvRequestWithRetry(t, func() (interface{}, error) {
+		return c.Logical().Write("kv/data/db/connection", map[string]interface{}{
+			"data": map[string]interface{}{
+				"url":  "postgres://app:old@db",
+				"pool": "10",
+			},
+		})
+	})
+	if err != nil {
+		t.Fatalf("write failed - err :%#v, resp: %#v\n", err, secretRaw)
+	}
+
+	// A successful batch creates and patches secrets at once.
+	secret, err := c.Logical().Write("kv/batch", map[string]interface{}{
+		"operations": []interface{}{
+			map[string]interface{}{
+				"operation": "put",
+				"path":      "db/password",
+				"data":      map[string]interface{}{"password": "new"},
+				"options":   map[string]interface{}{"cas": 0},
+			},
+			map[string]interface{}{
+				"operation": "patch",
+				"path":      "db/connection",
+				"data":      map[string]interface{}{"url": "postgres://app:new@db"},
+				"options":   map[string]interface{}{"cas": 1},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses := secret.Data["responses"].([]interface{})
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got: %#v", responses)
+	}
+	if v := responses[1].(map[string]interface{})["version"]; v.(json.Number).String() != "2" {
+		t.Fatalf("expected patch to create version 2, got: %v", v)
+	}
+
+	secret, err = c.Logical().Read("kv/data/db/connection")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := secret.Data["data"].(map[string]interface{})
+	if data["url"] != "postgres://app:new@db" || data["pool"] != "10" {
+		t.Fatalf("unexpected patched data: %#v", data)
+	}
+
+	// A failing check-and-set rolls back the operations before it.
+	_, err = c.Logical().Write("kv/batch", map[string]interface{}{
+		"operations": []interface{}{
+			map[string]interface{}{
+				"operation": "put",
+				"path":      "db/password",
+				"data":      map[string]interface{}{"password": "newer"},
+			},
+			map[string]interface{}{
+				"operation": "delete",
+				"path":      "db/connection",
+			},
+			map[string]interface{}{
+				"operation": "put",
+				"path":      "db/connection",
+				"data":      map[string]interface{}{"url": "postgres://app:newer@db"},
+				"options":   map[string]interface{}{"cas": 1},
+			},
+		},
+	})
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected check-and-set failure, got: %v", err)
+	}
+	assertKVVersion(t, c, "kv/data/db/password", "1")
+	assertKVVersion(t, c, "kv/data/db/connection", "2")
+
+	// Operations on paths the token may not write to are denied, failing
+	// the whole batch.
+	err = c.Sys().PutPolicy("batch", `
+path "kv/batch" {
+	capabilities = ["update"]
+}
+path "kv/data/db/password" {
+	capabilities = ["create", "update"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenSecret, err := c.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"batch"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted, err := c.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted.SetToken(tokenSecret.Auth.ClientToken)
+
+	_, err = restricted.Logical().Write("kv/batch", map[string]interface{}{
+		"operations": []interface{}{
+			map[string]interface{}{
+				"operation": "put",
+				"path":      "db/password",
+				"data":      map[string]interface{}{"password": "newer"},
+			},
+			map[string]interface{}{
+				"operation": "delete",
+				"path":      "db/connection",
+			},
+		},
+	})
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+	assertKVVersion(t, c, "kv/data/db/password", "1")
+
+	_, err = restricted.Logical().Write("kv/batch", map[string]interface{}{
+		"operations": []interface{}{
+			map[string]interface{}{
+				"operation": "put",
+				"path":      "db/password",
+				"data":      map[string]interface{}{"password": "newer"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKVVersion(t, c, "kv/data/db/password", "2")
+}
+
+func assertKVVersion(t *testing.T, c *api.Client, path, version string) {
+	t.Helper()
+
+	secret, err := c.Logical().Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := secret.Data["metadata"].(map[string]interface{})
+	if v := metadata["version"].(json.Number).String(); v != version {
+		t.Fatalf("expected version %s of %s, got %s", version, path, v)
+	}
+}
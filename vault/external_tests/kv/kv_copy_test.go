@@ -0,0 +1,188 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/openbao/openbao/api/v2"
+	logicalKv "github.com/openbao/openbao/builtin/logical/kv"
+	vaulthttp "github.com/openbao/openbao/http"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+)
+
+// TestKV_CopyMove copies and moves secrets within a KVv2 mount and verifies
+// that their metadata and versions are preserved, and that the source and
+// destination paths are authorized separately.
+func TestKV_CopyMove(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": logicalKv.VersionedKVFactory,
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	c := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	// Mount a KVv2 backend
+	err := c.Sys().Mount("kv", &api.MountInput{
+		Type: "kv-v2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, password := range []string{"first", "second"} {
+		secretRaw, err := kvRequestWithRetry(t, func() (interface{}, error) {
+			return c.Logical().Write("kv/data/app/db", map[string]interface{}{
+				"data": map[string]interface{}{
+					"password": password,
+				},
+			})
+		})
+		if err != nil {
+			t.Fatalf("write failed - err :%#v, resp: %#v\n", err, secretRaw)
+		}
+	}
+	_, err = c.Logical().Write("kv/metadata/app/db", map[string]interface{}{
+		"custom_metadata": map[string]interface{}{
+			"owner": "team-a",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Logical().Write("kv/data/app/api-key", map[string]interface{}{
+		"data": map[string]interface{}{
+			"key": "abc",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A copy preserves all versions and the custom metadata.
+	_, err = c.Logical().Write("kv/copy", map[string]interface{}{
+		"source":      "app/db",
+		"destination": "backup/db",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKVVersion(t, c, "kv/data/backup/db", "2")
+	assertKVVersion(t, c, "kv/data/app/db", "2")
+
+	secret, err := c.Logical().ReadWithData("kv/data/backup/db", map[string][]string{
+		"version": {"1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password := secret.Data["data"].(map[string]interface{})["password"]; password != "first" {
+		t.Fatalf("unexpected data of version 1: %v", password)
+	}
+
+	secret, err = c.Logical().Read("kv/metadata/backup/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner := secret.Data["custom_metadata"].(map[string]interface{})["owner"]; owner != "team-a" {
+		t.Fatalf("unexpected custom metadata: %#v", secret.Data["custom_metadata"])
+	}
+
+	// Existing secrets are never overwritten.
+	_, err = c.Logical().Write("kv/copy", map[string]interface{}{
+		"source":      "app/db",
+		"destination": "backup/db",
+	})
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected existing destination to be rejected, got: %v", err)
+	}
+
+	// A recursive move relocates every secret within the directory.
+	secret, err = c.Logical().Write("kv/move", map[string]interface{}{
+		"source":      "app",
+		"destination": "legacy/app",
+		"recursive":   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := secret.Data["keys"].([]interface{}); len(keys) != 2 {
+		t.Fatalf("expected 2 moved secrets, got: %#v", keys)
+	}
+	assertKVVersion(t, c, "kv/data/legacy/app/db", "2")
+	assertKVVersion(t, c, "kv/data/legacy/app/api-key", "1")
+
+	secret, err = c.Logical().Read("kv/metadata/app/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Fatalf("expected moved secret to be gone, got: %#v", secret.Data)
+	}
+
+	// Moving requires deleting the source besides reading it.
+	err = c.Sys().PutPolicy("relocate", `
+path "kv/copy" {
+	capabilities = ["update"]
+}
+path "kv/move" {
+	capabilities = ["update"]
+}
+path "kv/data/legacy/*" {
+	capabilities = ["read"]
+}
+path "kv/metadata/legacy/*" {
+	capabilities = ["read"]
+}
+path "kv/data/restored/*" {
+	capabilities = ["create"]
+}
+path "kv/metadata/restored/*" {
+	capabilities = ["create"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenSecret, err := c.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"relocate"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted, err := c.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted.SetToken(tokenSecret.Auth.ClientToken)
+
+	_, err = restricted.Logical().Write("kv/move", map[string]interface{}{
+		"source":      "legacy/app/db",
+		"destination": "restored/db",
+	})
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	_, err = restricted.Logical().Write("kv/copy", map[string]interface{}{
+		"source":      "legacy/app/db",
+		"destination": "restored/db",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertKVVersion(t, c, "kv/data/restored/db", "2")
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/openbao/openbao/api/v2"
+	logicalKv "github.com/openbao/openbao/builtin/logical/kv"
+	"github.com/openbao/openbao/helper/pgpkeys"
+	vaulthttp "github.com/openbao/openbao/http"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+)
+
+// TestKV_ExportImport exports secrets of a KVv2 mount to encrypted archives
+// and imports them into another mount, both preserving versions and
+// flattening them, and verifies that the client must be allowed to read the
+// exported secrets and to write the imported ones.
+func TestKV_ExportImport(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": logicalKv.VersionedKVFactory,
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	c := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	for _, mount := range []string{"staging", "prod"} {
+		err := c.Sys().Mount(mount, &api.MountInput{
+			Type: "kv-v2",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeSecret := func(path, password string) {
+		t.Helper()
+
+		secretRaw, err := kvRequestWithRetry(t, func() (interface{}, error) {
+			return c.Logical().Write(path, map[string]interface{}{
+				"data": map[string]interface{}{
+					"password": password,
+				},
+			})
+		})
+		if err != nil {
+			t.Fatalf("write failed - err :%#v, resp: %#v\n", err, secretRaw)
+		}
+	}
+	assertVersion := func(path string, version int, password string) {
+		t.Helper()
+
+		secret, err := c.Logical().ReadWithData(path, map[string][]string{
+			"version": {strconv.Itoa(version)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil {
+			t.Fatalf("no secret read from %s", path)
+		}
+		if p := secret.Data["data"].(map[string]interface{})["password"]; p != password {
+			t.Fatalf("expected version %d of %s to be %s, got: %v", version, path, password, p)
+		}
+	}
+
+	writeSecret("staging/data/app/db", "db-1")
+	writeSecret("staging/data/app/db", "db-2")
+	writeSecret("staging/data/app/api/key", "key-1")
+	writeSecret("staging/data/other/db", "other-1")
+	_, err := c.Logical().Write("staging/metadata/app/db", map[string]interface{}{
+		"custom_metadata": map[string]interface{}{
+			"owner": "payments",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Export with a passphrase and import preserving versions.
+	secret, err := c.Logical().Write("staging/export", map[string]interface{}{
+		"path":       "app",
+		"passphrase": "correct horse",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(secret.Data["secrets"]) != "2" {
+		t.Fatalf("unexpected export response: %#v", secret.Data)
+	}
+	archive := secret.Data["archive"].(string)
+
+	_, err = c.Logical().Write("prod/import", map[string]interface{}{
+		"archive":    archive,
+		"passphrase": "battery staple",
+	})
+	if err == nil || !strings.Contains(err.Error(), "incorrect passphrase") {
+		t.Fatalf("expected incorrect passphrase to be rejected, got: %v", err)
+	}
+
+	_, err = c.Logical().Write("prod/import", map[string]interface{}{
+		"archive":    archive,
+		"passphrase": "correct horse",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersion("prod/data/app/db", 1, "db-1")
+	assertVersion("prod/data/app/db", 2, "db-2")
+	assertVersion("prod/data/app/api/key", 1, "key-1")
+
+	metadata, err := c.Logical().Read("prod/metadata/app/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner := metadata.Data["custom_metadata"].(map[string]interface{})["owner"]; owner != "payments" {
+		t.Fatalf("expected custom metadata to be imported, got: %#v", metadata.Data)
+	}
+	secret, err = c.Logical().Read("prod/data/other/db")
+	if err != nil || secret != nil {
+		t.Fatalf("expected secrets outside of the exported directory not to be imported, got: %v, %#v", err, secret)
+	}
+
+	// Importing again fails, as the secrets now exist, unless flattening
+	// onto them.
+	_, err = c.Logical().Write("prod/import", map[string]interface{}{
+		"archive":    archive,
+		"passphrase": "correct horse",
+	})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected existing secrets to be rejected, got: %v", err)
+	}
+
+	writeSecret("staging/data/app/db", "db-3")
+	secret, err = c.Logical().Write("staging/export", map[string]interface{}{
+		"path":       "app",
+		"passphrase": "correct horse",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Logical().Write("prod/import", map[string]interface{}{
+		"archive":    secret.Data["archive"],
+		"passphrase": "correct horse",
+		"flatten":    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersion("prod/data/app/db", 3, "db-3")
+	assertVersion("prod/data/app/api/key", 2, "key-1")
+
+	// Secrets can be imported into another directory.
+	_, err = c.Logical().Write("prod/import", map[string]interface{}{
+		"archive":    secret.Data["archive"],
+		"passphrase": "correct horse",
+		"path":       "app-copy",
+		"flatten":    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersion("prod/data/app-copy/db", 1, "db-3")
+
+	// Archives encrypted to PGP keys must be decrypted before importing.
+	secret, err = c.Logical().Write("staging/export", map[string]interface{}{
+		"pgp_keys": []string{pgpkeys.TestPubKey1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Logical().Write("prod/import", map[string]interface{}{
+		"archive": secret.Data["archive"],
+		"path":    "pgp",
+	})
+	if err == nil || !strings.Contains(err.Error(), "encrypted to PGP keys") {
+		t.Fatalf("expected archive encrypted to PGP keys to be rejected, got: %v", err)
+	}
+
+	plaintext, err := pgpkeys.DecryptBytes(secret.Data["archive"].(string), pgpkeys.TestPrivKey1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Logical().Write("prod/import", map[string]interface{}{
+		"archive": base64.StdEncoding.EncodeToString(plaintext.Bytes()),
+		"path":    "pgp",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersion("prod/data/pgp/other/db", 1, "other-1")
+
+	// The client must be allowed to read the exported secrets and to create
+	// the imported ones.
+	err = c.Sys().PutPolicy("export", `
+path "staging/export" {
+	capabilities = ["update"]
+}
+path "staging/+/app/*" {
+	capabilities = ["read"]
+}
+path "prod/import" {
+	capabilities = ["update"]
+}
+path "prod/+/restricted/*" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenSecret, err := c.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"export"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted, err := c.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted.SetToken(tokenSecret.Auth.ClientToken)
+
+	_, err = restricted.Logical().Write("staging/export", map[string]interface{}{
+		"passphrase": "correct horse",
+	})
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected permission denied exporting the whole mount, got: %v", err)
+	}
+	secret, err = restricted.Logical().Write("staging/export", map[string]interface{}{
+		"path":       "app",
+		"passphrase": "correct horse",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = restricted.Logical().Write("prod/import", map[string]interface{}{
+		"archive":    secret.Data["archive"],
+		"passphrase": "correct horse",
+		"path":       "restricted",
+	})
+	if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected permission denied importing, got: %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openbao/openbao/api/v2"
+	"github.com/openbao/openbao/audit"
+	logicalKv "github.com/openbao/openbao/builtin/logical/kv"
+	"github.com/openbao/openbao/helper/testhelpers/corehelpers"
+	vaulthttp "github.com/openbao/openbao/http"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+)
+
+// TestKV_References reads secrets through references within and across
+// KVv2 mounts and verifies that loops are detected, that both the reference
+// and the referenced secret are authorized and that reads of other mounts are
+// audited and limited to KVv2 mounts.
+func TestKV_References(t *testing.T) {
+	noop := corehelpers.TestNoopAudit(t, nil)
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": logicalKv.Factory,
+		},
+		AuditBackends: map[string]audit.Factory{
+			"noop": func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+				return noop, nil
+			},
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	c := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	// Mount two KVv2 backends and a KVv1 backend
+	for mount, mountType := range map[string]string{"kv": "kv-v2", "shared": "kv-v2", "v1": "kv"} {
+		err := c.Sys().Mount(mount, &api.MountInput{
+			Type: mountType,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Sys().EnableAuditWithOptions("noop", &api.EnableAuditOptions{Type: "noop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"kv/data/shared/db", "shared/data/db"} {
+		secretRaw, err := kvRequestWithRetry(t, func() (interface{}, error) {
+			return c.Logical().Write(path, map[string]interface{}{
+				"data": map[string]interface{}{
+					"password": path,
+				},
+			})
+		})
+		if err != nil {
+			t.Fatalf("write failed - err :%#v, resp: %#v\n", err, secretRaw)
+		}
+	}
+
+	writeReference := func(path, reference, mount string) {
+		t.Helper()
+
+		_, err := c.Logical().Write(path, map[string]interface{}{
+			"reference":       reference,
+			"reference_mount": mount,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertResolved := func(client *api.Client, path, password, resolvedMount, resolvedPath string) {
+		t.Helper()
+
+		secret, err := client.Logical().Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil {
+			t.Fatalf("no secret read from %s", path)
+		}
+		if p := secret.Data["data"].(map[string]interface{})["password"]; p != password {
+			t.Fatalf("expected %s to resolve to the data of %s, got: %v", path, password, p)
+		}
+		metadata := secret.Data["metadata"].(map[string]interface{})
+		if metadata["resolved_mount"] != resolvedMount || metadata["resolved_path"] != resolvedPath {
+			t.Fatalf("unexpected resolution of %s: %#v", path, metadata)
+		}
+	}
+
+	// References within the mount and chains of references are followed.
+	writeReference("kv/metadata/app/prod/db", "shared/db", "")
+	writeReference("kv/metadata/app/staging/db", "app/prod/db", "")
+	assertResolved(c, "kv/data/app/prod/db", "kv/data/shared/db", "", "shared/db")
+	assertResolved(c, "kv/data/app/staging/db", "kv/data/shared/db", "", "shared/db")
+
+	// References to other mounts are followed too.
+	writeReference("kv/metadata/app/dev/db", "db", "shared")
+	writeReference("kv/metadata/app/test/db", "app/dev/db", "")
+	assertResolved(c, "kv/data/app/dev/db", "shared/data/db", "shared", "db")
+	assertResolved(c, "kv/data/app/test/db", "shared/data/db", "shared", "db")
+
+	// Reads of other mounts are audited like any request.
+	var audited bool
+	for _, req := range noop.Req {
+		if req.Path == "shared/data/db" {
+			audited = true
+		}
+	}
+	if !audited {
+		t.Fatal("expected the read of the referenced secret to be audited")
+	}
+
+	// Only KVv2 mounts may be referenced.
+	_, err := c.Logical().Write("v1/db", map[string]interface{}{
+		"password": "v1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeReference("kv/metadata/app/v1/db", "db", "v1")
+	_, err = c.Logical().Read("kv/data/app/v1/db")
+	if err == nil || !strings.Contains(err.Error(), "not a KV version 2 mount") {
+		t.Fatalf("expected reference to a KVv1 mount to be refused, got: %v", err)
+	}
+
+	// Loops are detected, also across mounts.
+	writeReference("kv/metadata/loop/a", "loop/b", "")
+	writeReference("kv/metadata/loop/b", "loop/a", "")
+	writeReference("kv/metadata/loop/c", "loop/d", "shared")
+	writeReference("shared/metadata/loop/d", "loop/c", "kv")
+	for _, path := range []string{"kv/data/loop/a", "kv/data/loop/c"} {
+		_, err := c.Logical().Read(path)
+		if err == nil || !strings.Contains(err.Error(), "reference loop detected") {
+			t.Fatalf("expected loop to be detected reading %s, got: %v", path, err)
+		}
+	}
+
+	// Long chains are cut short.
+	for i := 0; i < 11; i++ {
+		writeReference(fmt.Sprintf("kv/metadata/chain/%d", i), fmt.Sprintf("chain/%d", i+1), "")
+	}
+	_, err = c.Logical().Read("kv/data/chain/0")
+	if err == nil || !strings.Contains(err.Error(), "references to follow") {
+		t.Fatalf("expected maximum depth to be exceeded, got: %v", err)
+	}
+
+	// The client must be allowed to read both the reference and the
+	// referenced secret.
+	err = c.Sys().PutPolicy("references", `
+path "kv/data/app/*" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenSecret, err := c.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"references"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted, err := c.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted.SetToken(tokenSecret.Auth.ClientToken)
+
+	for _, path := range []string{"kv/data/app/prod/db", "kv/data/app/dev/db"} {
+		_, err = restricted.Logical().Read(path)
+		if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected permission denied reading %s, got: %v", path, err)
+		}
+	}
+
+	err = c.Sys().PutPolicy("references", `
+path "kv/data/app/*" {
+	capabilities = ["read"]
+}
+path "kv/data/shared/*" {
+	capabilities = ["read"]
+}
+path "shared/data/*" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResolved(restricted, "kv/data/app/staging/db", "kv/data/shared/db", "", "shared/db")
+	assertResolved(restricted, "kv/data/app/test/db", "shared/data/db", "shared", "db")
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openbao/openbao/api/v2"
+	logicalKv "github.com/openbao/openbao/builtin/logical/kv"
+	vaulthttp "github.com/openbao/openbao/http"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+)
+
+// TestKV_Search searches secrets of a KVv2 mount by their metadata and
+// verifies that only secrets in directories the token may list are returned.
+func TestKV_Search(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": logicalKv.VersionedKVFactory,
+		},
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	c := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	err := c.Sys().Mount("kv", &api.MountInput{
+		Type: "kv-v2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"payments/db", "payments/api", "search/db", "search/api"} {
+		secretRaw, err := kvRequestWithRetry(t, func() (interface{}, error) {
+			return c.Logical().Write("kv/data/"+path, map[string]interface{}{
+				"data": map[string]interface{}{
+					"password": path,
+				},
+			})
+		})
+		if err != nil {
+			t.Fatalf("write failed - err :%#v, resp: %#v\n", err, secretRaw)
+		}
+	}
+
+	writeCustomMetadata := func(path string, customMetadata map[string]interface{}) {
+		t.Helper()
+
+		_, err := c.Logical().Write("kv/metadata/"+path, map[string]interface{}{
+			"custom_metadata": customMetadata,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertSearch := func(client *api.Client, data map[string]interface{}, expected []string) {
+		t.Helper()
+
+		secret, err := client.Logical().Write("kv/search", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := []string{}
+		if secret != nil && secret.Data["keys"] != nil {
+			for _, key := range secret.Data["keys"].([]interface{}) {
+				keys = append(keys, key.(string))
+			}
+		}
+		if !reflect.DeepEqual(keys, expected) {
+			t.Fatalf("expected %v searching %v, got %v", expected, data, keys)
+		}
+	}
+
+	all := []string{"payments/api", "payments/db", "search/api", "search/db"}
+	assertSearch(c, nil, all)
+
+	writeCustomMetadata("payments/db", map[string]interface{}{"owner": "payments", "env": "prod"})
+	writeCustomMetadata("payments/api", map[string]interface{}{"owner": "payments", "env": "dev"})
+	writeCustomMetadata("search/db", map[string]interface{}{"owner": "search", "env": "prod"})
+
+	assertSearch(c, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"owner": "payments"},
+	}, []string{"payments/api", "payments/db"})
+	assertSearch(c, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"env": "prod"},
+	}, []string{"payments/db", "search/db"})
+	assertSearch(c, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"owner": "payments", "env": "prod"},
+	}, []string{"payments/db"})
+
+	// Results are paginated like lists.
+	assertSearch(c, map[string]interface{}{
+		"limit": 2,
+	}, []string{"payments/api", "payments/db"})
+	assertSearch(c, map[string]interface{}{
+		"after": "payments/db",
+		"limit": 2,
+	}, []string{"search/api", "search/db"})
+
+	// Secrets are filtered by the time they were updated and by the age of
+	// their current version.
+	cutoff := time.Now()
+	time.Sleep(time.Second)
+	_, err = c.Logical().Write("kv/data/search/api", map[string]interface{}{
+		"data": map[string]interface{}{
+			"password": "rotated",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSearch(c, map[string]interface{}{
+		"updated_after": cutoff.Format(time.RFC3339Nano),
+	}, []string{"search/api"})
+	assertSearch(c, map[string]interface{}{
+		"updated_before": cutoff.Format(time.RFC3339Nano),
+	}, []string{"payments/api", "payments/db", "search/db"})
+	assertSearch(c, map[string]interface{}{
+		"min_version_age": "1h",
+	}, []string{})
+	assertSearch(c, map[string]interface{}{
+		"max_version_age": "1h",
+	}, all)
+
+	// Deleted secrets are no longer found.
+	_, err = c.Logical().Delete("kv/metadata/search/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSearch(c, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"env": "prod"},
+	}, []string{"payments/db"})
+
+	// Only secrets in directories the token may list are returned.
+	err = c.Sys().PutPolicy("search", `
+path "kv/search" {
+	capabilities = ["update"]
+}
+path "kv/metadata/payments/" {
+	capabilities = ["list"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenSecret, err := c.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"search"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted, err := c.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted.SetToken(tokenSecret.Auth.ClientToken)
+
+	assertSearch(restricted, nil, []string{"payments/api", "payments/db"})
+	assertSearch(restricted, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"owner": "search"},
+	}, []string{})
+}
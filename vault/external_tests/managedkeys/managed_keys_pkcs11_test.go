@@ -0,0 +1,147 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build hsm && linux
+
+package managedkeys
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	softHSMTokenLabel = "openbao"
+	softHSMPIN        = "1234"
+)
+
+// setupSoftHSM initializes a SoftHSM token in a temporary directory. The
+// library is taken from BAO_TEST_SOFTHSM_LIBRARY, defaulting to the path of
+// the Debian package, and the test is skipped when it is not installed.
+func setupSoftHSM(t *testing.T) string {
+	t.Helper()
+
+	library := os.Getenv("BAO_TEST_SOFTHSM_LIBRARY")
+	if library == "" {
+		library = softHSMLibrary
+	}
+	if _, err := os.Stat(library); err != nil {
+		t.Skipf("SoftHSM is not available: %v", err)
+	}
+
+	// SoftHSM reads its configuration when the library is first
+	// initialized, which happens once per process.
+	dir := t.TempDir()
+	tokens := filepath.Join(dir, "tokens")
+	require.NoError(t, os.Mkdir(tokens, 0o700))
+	conf := filepath.Join(dir, "softhsm2.conf")
+	require.NoError(t, os.WriteFile(conf, []byte(fmt.Sprintf("directories.tokendir = %s\nobjectstore.backend = file\n", tokens)), 0o600))
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	module := pkcs11.New(library)
+	require.NotNil(t, module)
+	err := module.Initialize()
+	if err != nil && !strings.Contains(err.Error(), "CKR_CRYPTOKI_ALREADY_INITIALIZED") {
+		require.NoError(t, err)
+	}
+
+	slots, err := module.GetSlotList(false)
+	require.NoError(t, err)
+	require.NotEmpty(t, slots)
+	require.NoError(t, module.InitToken(slots[0], "5678", softHSMTokenLabel))
+
+	// SoftHSM moves initialized tokens to a new slot.
+	slots, err = module.GetSlotList(true)
+	require.NoError(t, err)
+	var slot uint
+	for _, s := range slots {
+		info, err := module.GetTokenInfo(s)
+		require.NoError(t, err)
+		if strings.TrimSpace(info.Label) == softHSMTokenLabel {
+			slot = s
+		}
+	}
+
+	session, err := module.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	require.NoError(t, err)
+	require.NoError(t, module.Login(session, pkcs11.CKU_SO, "5678"))
+	require.NoError(t, module.InitPIN(session, softHSMPIN))
+	require.NoError(t, module.Logout(session))
+	require.NoError(t, module.CloseSession(session))
+
+	return library
+}
+
+func TestManagedKeys_PKCS11(t *testing.T) {
+	library := setupSoftHSM(t)
+	client := setupCluster(t, map[string]string{"softhsm": library})
+
+	for name, keyType := range map[string]string{"pki-root": "ec", "pki-rsa": "rsa"} {
+		_, err := client.Logical().Write("sys/managed-keys/pkcs11/"+name, map[string]interface{}{
+			"library":            "softhsm",
+			"token_label":        softHSMTokenLabel,
+			"pin":                softHSMPIN,
+			"key_label":          name,
+			"key_type":           keyType,
+			"allow_generate_key": true,
+			"any_mount":          keyType == "rsa",
+		})
+		require.NoError(t, err)
+	}
+
+	// The key is generated on the token on first use.
+	resp, err := client.Logical().Write("pki/keys/generate/kms", map[string]interface{}{
+		"key_name":         "root-key",
+		"managed_key_name": "pki-root",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ec", resp.Data["key_type"])
+	require.NotEmpty(t, resp.Data["managed_key_id"])
+
+	// Sessions are reused across uses of the keys.
+	for i := 0; i < 3; i++ {
+		_, err = client.Logical().Write("sys/managed-keys/pkcs11/pki-root/test/sign", nil)
+		require.NoError(t, err)
+	}
+
+	// Issue a root and a leaf signed by the key held on the token.
+	resp, err = client.Logical().Write("pki/root/generate/kms", map[string]interface{}{
+		"common_name":      "root.example.com",
+		"managed_key_name": "pki-root",
+	})
+	require.NoError(t, err)
+	root := parseCert(t, resp.Data["certificate"].(string))
+
+	_, err = client.Logical().Write("pki/roles/example", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+	})
+	require.NoError(t, err)
+
+	resp, err = client.Logical().Write("pki/issue/example", map[string]interface{}{
+		"common_name": "leaf.example.com",
+		"ttl":         "1h",
+	})
+	require.NoError(t, err)
+	leaf := parseCert(t, resp.Data["certificate"].(string))
+	require.NoError(t, leaf.CheckSignatureFrom(root))
+
+	// Mounts which do not allow the EC key may not use it, while the RSA
+	// key may be used by any mount.
+	_, err = client.Logical().Write("pki-denied/keys/generate/kms", map[string]interface{}{
+		"managed_key_name": "pki-root",
+	})
+	require.Error(t, err)
+
+	resp, err = client.Logical().Write("pki-denied/keys/generate/kms", map[string]interface{}{
+		"managed_key_name": "pki-rsa",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "rsa", resp.Data["key_type"])
+}
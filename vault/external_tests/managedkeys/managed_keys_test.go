@@ -0,0 +1,187 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package managedkeys
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/openbao/openbao/api/v2"
+	"github.com/openbao/openbao/builtin/logical/pki"
+	"github.com/openbao/openbao/builtin/logical/transit"
+	"github.com/openbao/openbao/helper/testhelpers/teststorage"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault"
+	"github.com/stretchr/testify/require"
+)
+
+// softHSMLibrary is the default path of the SoftHSM PKCS#11 library.
+const softHSMLibrary = "/usr/lib/softhsm/libsofthsm2.so"
+
+func setupCluster(t *testing.T, pkcs11Libraries map[string]string) *api.Client {
+	t.Helper()
+
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"pki":     pki.Factory,
+			"transit": transit.Factory,
+		},
+		PKCS11Libraries: pkcs11Libraries,
+	}
+	conf, opts := teststorage.ClusterSetup(coreConfig, nil, nil)
+	cluster := vault.NewTestCluster(t, conf, opts)
+	cluster.Start()
+	t.Cleanup(cluster.Cleanup)
+	vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+	client := cluster.Cores[0].Client
+	require.NoError(t, client.Sys().Mount("transit", &api.MountInput{Type: "transit"}))
+	require.NoError(t, client.Sys().Mount("pki", &api.MountInput{
+		Type: "pki",
+		Config: api.MountConfigInput{
+			AllowedManagedKeys: []string{"pki-root"},
+		},
+	}))
+	require.NoError(t, client.Sys().Mount("pki-denied", &api.MountInput{Type: "pki"}))
+
+	return client
+}
+
+func TestManagedKeys_Transit(t *testing.T) {
+	t.Parallel()
+	client := setupCluster(t, nil)
+
+	_, err := client.Logical().Write("sys/managed-keys/transit/pki-root", map[string]interface{}{
+		"mount_path":         "transit",
+		"key_name":           "pki-root",
+		"key_type":           "ecdsa-p256",
+		"allow_generate_key": true,
+	})
+	require.NoError(t, err)
+
+	resp, err := client.Logical().Read("sys/managed-keys/transit/pki-root")
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, "transit", resp.Data["type"])
+	require.NotEmpty(t, resp.Data["uuid"])
+	require.ElementsMatch(t, []interface{}{"sign", "verify"}, resp.Data["usages"])
+
+	resp, err = client.Logical().List("sys/managed-keys/transit")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"pki-root"}, resp.Data["keys"])
+
+	// Names are unique across all types of managed keys.
+	_, err = client.Logical().Write("sys/managed-keys/pkcs11/pki-root", map[string]interface{}{
+		"library":   "softhsm",
+		"slot":      "0",
+		"pin":       "1234",
+		"key_label": "pki-root",
+	})
+	require.Error(t, err)
+
+	// The key is generated in transit on first use.
+	resp, err = client.Logical().Write("pki/keys/generate/kms", map[string]interface{}{
+		"key_name":         "root-key",
+		"managed_key_name": "pki-root",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ec", resp.Data["key_type"])
+	require.NotEmpty(t, resp.Data["managed_key_id"])
+
+	resp, err = client.Logical().Read("pki/key/root-key")
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Data["managed_key_id"])
+	require.Nil(t, resp.Data["private_key"])
+
+	_, err = client.Logical().Write("sys/managed-keys/transit/pki-root/test/sign", nil)
+	require.NoError(t, err)
+
+	// Mounts which do not allow the managed key may not use it.
+	_, err = client.Logical().Write("pki-denied/keys/generate/kms", map[string]interface{}{
+		"managed_key_name": "pki-root",
+	})
+	require.Error(t, err)
+
+	// Issue a root and a leaf signed by the key held in transit.
+	resp, err = client.Logical().Write("pki/root/generate/kms", map[string]interface{}{
+		"common_name":      "root.example.com",
+		"managed_key_name": "pki-root",
+	})
+	require.NoError(t, err)
+	root := parseCert(t, resp.Data["certificate"].(string))
+
+	_, err = client.Logical().Write("pki/roles/example", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+	})
+	require.NoError(t, err)
+
+	resp, err = client.Logical().Write("pki/issue/example", map[string]interface{}{
+		"common_name": "leaf.example.com",
+		"ttl":         "1h",
+	})
+	require.NoError(t, err)
+	leaf := parseCert(t, resp.Data["certificate"].(string))
+	require.NoError(t, leaf.CheckSignatureFrom(root))
+
+	// The managed key may not be deleted while a mount allows it.
+	_, err = client.Logical().Delete("sys/managed-keys/transit/pki-root")
+	require.Error(t, err)
+}
+
+func TestManagedKeys_RedactsPIN(t *testing.T) {
+	t.Parallel()
+	client := setupCluster(t, map[string]string{"softhsm": softHSMLibrary})
+
+	_, err := client.Logical().Write("sys/managed-keys/pkcs11/hsm", map[string]interface{}{
+		"library":     "softhsm",
+		"token_label": "openbao",
+		"pin":         "1234",
+		"key_label":   "hsm",
+		"key_type":    "rsa",
+		"key_bits":    "2048",
+	})
+	require.NoError(t, err)
+
+	resp, err := client.Logical().Read("sys/managed-keys/pkcs11/hsm")
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.NotContains(t, resp.Data, "pin")
+	require.Equal(t, "openbao", resp.Data["token_label"])
+
+	require.Equal(t, "softhsm", resp.Data["library"])
+
+	_, err = client.Logical().Write("sys/managed-keys/pkcs11/invalid", map[string]interface{}{
+		"library":   "softhsm",
+		"pin":       "1234",
+		"key_label": "invalid",
+	})
+	require.Error(t, err)
+
+	// Libraries may only be referenced by the name given in the server
+	// configuration, never loaded from an arbitrary path.
+	for _, library := range []string{softHSMLibrary, "unknown"} {
+		_, err = client.Logical().Write("sys/managed-keys/pkcs11/unknown-library", map[string]interface{}{
+			"library":     library,
+			"token_label": "openbao",
+			"pin":         "1234",
+			"key_label":   "unknown-library",
+		})
+		require.ErrorContains(t, err, "kms_library")
+	}
+
+	_, err = client.Logical().Delete("sys/managed-keys/pkcs11/hsm")
+	require.NoError(t, err)
+}
+
+func parseCert(t *testing.T, certPEM string) *x509.Certificate {
+	t.Helper()
+
+	block, _ := pem.Decode([]byte(certPEM))
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert
+}
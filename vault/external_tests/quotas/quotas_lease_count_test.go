@@ -0,0 +1,193 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package quotas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/openbao/openbao/api/v2"
+	"github.com/openbao/openbao/helper/testhelpers/teststorage"
+	"github.com/openbao/openbao/sdk/v2/helper/testhelpers/schema"
+	"github.com/openbao/openbao/vault"
+	"github.com/stretchr/testify/require"
+)
+
+func issueCert(client *api.Client) (string, error) {
+	resp, err := client.Logical().Write("pki/issue/test", map[string]interface{}{
+		"common_name": "test.testvault.com",
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.LeaseID, nil
+}
+
+func requireLeaseCountQuotaExceeded(t *testing.T, err error) {
+	t.Helper()
+
+	var respErr *api.ResponseError
+	require.Error(t, err)
+	require.True(t, errors.As(err, &respErr), "unexpected error: %v", err)
+	require.Equal(t, http.StatusTooManyRequests, respErr.StatusCode)
+}
+
+func leaseCountQuotaCounter(t *testing.T, client *api.Client, name string) int {
+	t.Helper()
+
+	resp, err := client.Logical().Read("sys/quotas/lease-count/" + name)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	counter, err := resp.Data["counter"].(json.Number).Int64()
+	require.NoError(t, err)
+	return int(counter)
+}
+
+func TestQuotas_LeaseCount_DupPath(t *testing.T) {
+	conf, opts := teststorage.ClusterSetup(coreConfig, nil, nil)
+	opts.NoDefaultQuotas = true
+	opts.RequestResponseCallback = schema.ResponseValidatingCallback(t)
+	cluster := vault.NewTestCluster(t, conf, opts)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	client := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	_, err := client.Logical().Write("sys/quotas/lease-count/global-lcq", map[string]interface{}{
+		"max_leases": 10,
+	})
+	require.NoError(t, err)
+
+	// A quota with the same scope under another name is rejected
+	_, err = client.Logical().Write("sys/quotas/lease-count/other-lcq", map[string]interface{}{
+		"max_leases": 10,
+	})
+	require.Error(t, err)
+
+	// Lease count quotas do not conflict with rate limit quotas
+	_, err = client.Logical().Write("sys/quotas/rate-limit/global-lcq", map[string]interface{}{
+		"rate": 10,
+	})
+	require.NoError(t, err)
+
+	_, err = client.Logical().Write("sys/quotas/lease-count/invalid-lcq", map[string]interface{}{
+		"max_leases": 0,
+	})
+	require.Error(t, err)
+
+	s, err := client.Logical().List("sys/quotas/lease-count")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"global-lcq"}, s.Data["keys"])
+}
+
+func TestQuotas_LeaseCountQuota_Mount(t *testing.T) {
+	conf, opts := teststorage.ClusterSetup(coreConfig, nil, nil)
+	opts.NoDefaultQuotas = true
+	opts.RequestResponseCallback = schema.ResponseValidatingCallback(t)
+	cluster := vault.NewTestCluster(t, conf, opts)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	client := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	setupMounts(t, client)
+
+	// Leases issued before the quota exists count against it
+	leaseID, err := issueCert(client)
+	require.NoError(t, err)
+	require.NotEmpty(t, leaseID)
+
+	_, err = client.Logical().Write("sys/quotas/lease-count/pki-lcq", map[string]interface{}{
+		"path":       "pki/",
+		"max_leases": 3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, leaseCountQuotaCounter(t, client, "pki-lcq"))
+
+	for i := 0; i < 2; i++ {
+		_, err := issueCert(client)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, leaseCountQuotaCounter(t, client, "pki-lcq"))
+
+	_, err = issueCert(client)
+	requireLeaseCountQuotaExceeded(t, err)
+	require.Equal(t, 3, leaseCountQuotaCounter(t, client, "pki-lcq"))
+
+	// Revoking a lease makes room for another one
+	require.NoError(t, client.Sys().Revoke(leaseID))
+	require.Equal(t, 2, leaseCountQuotaCounter(t, client, "pki-lcq"))
+
+	_, err = issueCert(client)
+	require.NoError(t, err)
+
+	// A more specific quota takes over the leases of its path
+	_, err = client.Logical().Write("sys/quotas/lease-count/pki-issue-lcq", map[string]interface{}{
+		"path":       "pki/issue/test",
+		"max_leases": 4,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, leaseCountQuotaCounter(t, client, "pki-lcq"))
+	require.Equal(t, 3, leaseCountQuotaCounter(t, client, "pki-issue-lcq"))
+
+	_, err = issueCert(client)
+	require.NoError(t, err)
+	_, err = issueCert(client)
+	requireLeaseCountQuotaExceeded(t, err)
+
+	// Deleting it moves the leases back to the mount quota, which is now
+	// exceeded
+	_, err = client.Logical().Delete("sys/quotas/lease-count/pki-issue-lcq")
+	require.NoError(t, err)
+	require.Equal(t, 4, leaseCountQuotaCounter(t, client, "pki-lcq"))
+
+	_, err = issueCert(client)
+	requireLeaseCountQuotaExceeded(t, err)
+}
+
+func TestQuotas_LeaseCountQuota_Restore(t *testing.T) {
+	conf, opts := teststorage.ClusterSetup(coreConfig, nil, nil)
+	opts.NoDefaultQuotas = true
+	opts.NumCores = 1
+	cluster := vault.NewTestCluster(t, conf, opts)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	client := cluster.Cores[0].Client
+	vault.TestWaitActive(t, core)
+
+	setupMounts(t, client)
+
+	_, err := client.Logical().Write("sys/quotas/lease-count/pki-lcq", map[string]interface{}{
+		"path":       "pki/",
+		"max_leases": 2,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err := issueCert(client)
+		require.NoError(t, err)
+	}
+
+	cluster.EnsureCoresSealed(t)
+	cluster.UnsealCores(t)
+	vault.TestWaitActive(t, core)
+
+	// The leases are counted again as they are restored
+	require.Eventually(t, func() bool {
+		return leaseCountQuotaCounter(t, client, "pki-lcq") == 2
+	}, 10*time.Second, 100*time.Millisecond)
+
+	_, err = issueCert(client)
+	requireLeaseCountQuotaExceeded(t, err)
+}
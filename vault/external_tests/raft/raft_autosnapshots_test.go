@@ -0,0 +1,87 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package rafttests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openbao/openbao/helper/testhelpers"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRaft_AutoSnapshots runs two schedules sharing a path prefix, whose
+// default file names overlap, and checks that each takes snapshots and
+// only prunes its own.
+func TestRaft_AutoSnapshots(t *testing.T) {
+	t.Parallel()
+	cluster, _ := raftCluster(t, &RaftClusterOpts{NumCores: 1})
+	defer cluster.Cleanup()
+	testhelpers.WaitForActiveNode(t, cluster)
+
+	client := cluster.Cores[0].Client
+	dir := t.TempDir()
+
+	for _, name := range []string{"prod", "prod-eu"} {
+		_, err := client.Logical().Write("sys/storage/raft/snapshot-auto/config/"+name, map[string]interface{}{
+			"interval":    1,
+			"retain":      2,
+			"path_prefix": dir,
+		})
+		require.NoError(t, err)
+	}
+
+	snapshots := func(name string) []string {
+		matches, err := filepath.Glob(filepath.Join(dir, name, name+"-*.snap"))
+		require.NoError(t, err)
+		return matches
+	}
+
+	// Wait for enough snapshots that retention has applied to both
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		status, err := client.Logical().Read("sys/storage/raft/snapshot-auto/status/prod")
+		require.NoError(t, err)
+		if status.Data["last_error"] != "" {
+			t.Fatalf("snapshot failed: %v", status.Data["last_error"])
+		}
+
+		if len(snapshots("prod")) == 2 && len(snapshots("prod-eu")) == 2 {
+			// Both configs must have taken further snapshots since
+			time.Sleep(2 * time.Second)
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for snapshots: prod=%v prod-eu=%v", snapshots("prod"), snapshots("prod-eu"))
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for _, name := range []string{"prod", "prod-eu"} {
+		files := snapshots(name)
+		require.Len(t, files, 2, "snapshots of %q", name)
+		for _, file := range files {
+			info, err := os.Stat(file)
+			require.NoError(t, err)
+			require.NotZero(t, info.Size())
+		}
+
+		status, err := client.Logical().Read("sys/storage/raft/snapshot-auto/status/" + name)
+		require.NoError(t, err)
+		require.NotEmpty(t, status.Data["last_success_time"])
+		require.Contains(t, status.Data["last_snapshot_path"], filepath.Join(dir, name)+string(filepath.Separator))
+		require.Equal(t, "", status.Data["last_error"])
+	}
+
+	// Deleting a config stops its schedule and leaves its snapshots
+	_, err := client.Logical().Delete("sys/storage/raft/snapshot-auto/config/prod")
+	require.NoError(t, err)
+	time.Sleep(2 * time.Second)
+	before := snapshots("prod")
+	time.Sleep(2 * time.Second)
+	require.Equal(t, before, snapshots("prod"))
+	require.Len(t, before, 2)
+}
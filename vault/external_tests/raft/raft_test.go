@@ -42,6 +42,7 @@ type RaftClusterOpts struct {
 	EnableAutopilot                bool
 	PhysicalFactoryConfig          map[string]interface{}
 	EnableResponseHeaderRaftNodeID bool
+	EnableStandbyReads             bool
 	NumCores                       int
 	Seal                           vault.Seal
 	VersionMap                     map[int]string
@@ -59,6 +60,7 @@ func raftCluster(t testing.TB, ropts *RaftClusterOpts) (*vault.TestCluster, *vau
 		},
 		DisableAutopilot:               !ropts.EnableAutopilot,
 		EnableResponseHeaderRaftNodeID: ropts.EnableResponseHeaderRaftNodeID,
+		EnableStandbyReads:             ropts.EnableStandbyReads,
 		Seal:                           ropts.Seal,
 	}
 
@@ -428,6 +430,67 @@ func TestRaft_NodeIDHeader(t *testing.T) {
 	}
 }
 
+func TestRaft_StandbyReads(t *testing.T) {
+	t.Parallel()
+	cluster, _ := raftCluster(t, &RaftClusterOpts{
+		EnableStandbyReads: true,
+	})
+	defer cluster.Cleanup()
+
+	leaderClient := cluster.Cores[0].Client
+	standby := cluster.Cores[1]
+
+	corehelpers.RetryUntil(t, 30*time.Second, func() error {
+		if !standby.Core.StandbyReadsActive() {
+			return errors.New("standby not serving reads yet")
+		}
+		return nil
+	})
+
+	health, err := standby.Client.Sys().Health()
+	require.NoError(t, err)
+	require.True(t, health.Standby)
+	require.True(t, health.PerformanceStandby)
+
+	// A read following a write on the active node observes it when
+	// requiring the returned index.
+	var state string
+	_, err = leaderClient.WithResponseCallbacks(api.RecordState(&state)).Logical().Write("secret/foo", map[string]interface{}{
+		"value": "bar",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, state)
+
+	secret, err := standby.Client.WithRequestCallbacks(api.RequireState(state)).Logical().Read("secret/foo")
+	require.NoError(t, err)
+	require.NotNil(t, secret)
+	require.Equal(t, "bar", secret.Data["value"])
+
+	// Writes and token creation made against the standby are forwarded.
+	_, err = standby.Client.Logical().Write("secret/foo", map[string]interface{}{
+		"value": "baz",
+	})
+	require.NoError(t, err)
+
+	tokenSecret, err := standby.Client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"default"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, tokenSecret.Auth.ClientToken)
+
+	// The new token is usable on the standby once it observed its creation.
+	tokenClient, err := standby.Client.Clone()
+	require.NoError(t, err)
+	tokenClient.SetToken(tokenSecret.Auth.ClientToken)
+	lookup, err := tokenClient.Auth().Token().LookupSelf()
+	require.NoError(t, err)
+	require.Equal(t, tokenSecret.Auth.Accessor, lookup.Data["accessor"])
+
+	// Indexes must be authenticated.
+	_, err = standby.Client.WithRequestCallbacks(api.RequireState("djE6MTAwMDAwOmZvbw==")).Logical().Read("secret/foo")
+	require.Error(t, err)
+}
+
 func TestRaft_Configuration(t *testing.T) {
 	t.Parallel()
 	cluster, _ := raftCluster(t, nil)
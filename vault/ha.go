@@ -440,6 +440,18 @@ func (c *Core) runStandby(doneCh, manualStepDownCh, stopCh chan struct{}) {
 			c.logger.Debug("shutting down periodic metrics")
 		})
 	}
+	if c.standbyReadsEnabled() {
+		// Serve reads locally while on standby
+		standbyReadsStop := make(chan struct{})
+
+		g.Add(func() error {
+			c.periodicSetupStandbyReads(standbyReadsStop)
+			return nil
+		}, func(error) {
+			close(standbyReadsStop)
+			c.logger.Debug("shutting down standby reads setup")
+		})
+	}
 	{
 		// Wait for leadership
 		leaderStopCh := make(chan struct{})
@@ -547,6 +559,13 @@ func (c *Core) waitForLeadership(newLeaderCh chan func(), manualStepDownCh, stop
 			return
 		}
 
+		// Stop serving reads as a standby before setting up the active state
+		if c.standbyReads.ready.Load() {
+			if err := c.teardownStandbyReads(); err != nil {
+				c.logger.Warn("error tearing down standby reads state", "error", err)
+			}
+		}
+
 		// Store the lock so that we can manually clear it later if needed
 		c.heldHALock = lock
 
@@ -693,6 +712,9 @@ func (c *Core) waitForLeadership(newLeaderCh chan func(), manualStepDownCh, stop
 				c.logger.Error("pre-seal teardown failed", "error", err)
 			}
 
+			// Start serving reads as a standby again without waiting
+			c.standbyReads.requestReload()
+
 			// If we are not meant to keep the HA lock, clear it
 			if atomic.LoadUint32(c.keepHALockOnStepDown) == 0 {
 				if err := c.clearLeader(uuid); err != nil {
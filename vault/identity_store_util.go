@@ -2406,3 +2406,71 @@ func (i *IdentityStore) countEntitiesByMountAccessor(ctx context.Context) (map[s
 
 	return byMountAccessor, nil
 }
+
+// deleteNamespaceArtifacts removes all entities and groups belonging to
+// the given namespace, used when the namespace itself is deleted.
+func (i *IdentityStore) deleteNamespaceArtifacts(ctx context.Context, ns *namespace.Namespace) error {
+	ctx = namespace.ContextWithNamespace(ctx, ns)
+
+	i.lock.Lock()
+	txn := i.db.Txn(true)
+
+	iter, err := txn.Get(entitiesTable, "namespace_id", ns.ID)
+	if err != nil {
+		txn.Abort()
+		i.lock.Unlock()
+		return fmt.Errorf("failed to fetch iterator for entities in memdb: %w", err)
+	}
+
+	var entities []*identity.Entity
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		entities = append(entities, raw.(*identity.Entity))
+	}
+
+	for _, entity := range entities {
+		if err := i.handleEntityDeleteCommon(ctx, txn, entity, true); err != nil {
+			txn.Abort()
+			i.lock.Unlock()
+			return err
+		}
+	}
+
+	txn.Commit()
+	i.lock.Unlock()
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	txn = i.db.Txn(true)
+	defer txn.Abort()
+
+	iter, err = txn.Get(groupsTable, "namespace_id", ns.ID)
+	if err != nil {
+		return fmt.Errorf("failed to lookup groups using namespace ID: %w", err)
+	}
+
+	var groups []*identity.Group
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		groups = append(groups, raw.(*identity.Group))
+	}
+
+	for _, group := range groups {
+		if group.Type == groupTypeExternal && group.Alias != nil {
+			if err := i.MemDBDeleteAliasByIDInTxn(txn, group.Alias.ID, true); err != nil {
+				return err
+			}
+		}
+
+		if err := i.MemDBDeleteGroupByIDInTxn(txn, group.ID); err != nil {
+			return err
+		}
+
+		if err := i.groupPacker.DeleteItem(ctx, group.ID); err != nil {
+			return err
+		}
+	}
+
+	txn.Commit()
+
+	return nil
+}
@@ -47,6 +47,7 @@ import (
 	"github.com/openbao/openbao/sdk/v2/helper/roottoken"
 	"github.com/openbao/openbao/sdk/v2/helper/wrapping"
 	"github.com/openbao/openbao/sdk/v2/logical"
+	vaultseal "github.com/openbao/openbao/vault/seal"
 	"github.com/openbao/openbao/version"
 	"golang.org/x/crypto/sha3"
 )
@@ -157,6 +158,9 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 	b.Backend.Paths = append(b.Backend.Paths, b.inFlightRequestPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.hostInfoPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.quotasPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.namespacePaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.eventsPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.managedKeyPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.loginMFAPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.introspectionPaths()...)
 
@@ -1137,6 +1141,8 @@ func (b *SystemBackend) handleMount(ctx context.Context, req *logical.Request, d
 		return handleError(err)
 	}
 
+	b.Core.sendCoreEvent(ctx, eventTypeMountEnable, me, "sys/mounts/"+me.Path, nil)
+
 	return resp, nil
 }
 
@@ -1248,6 +1254,10 @@ func (b *SystemBackend) handleUnmount(ctx context.Context, req *logical.Request,
 		return handleError(err)
 	}
 
+	b.Core.sendCoreEvent(ctx, eventTypeMountDisable, nil, "sys/mounts/"+path, map[string]string{
+		"path": path,
+	})
+
 	return nil, nil
 }
 
@@ -2557,6 +2567,9 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 		b.Backend.Logger().Error("error occurred during enable credential", "path", me.Path, "error", err)
 		return handleError(err)
 	}
+
+	b.Core.sendCoreEvent(ctx, eventTypeAuthEnable, me, "sys/auth/"+me.Path, nil)
+
 	return resp, nil
 }
 
@@ -2654,6 +2667,10 @@ func (b *SystemBackend) handleDisableAuth(ctx context.Context, req *logical.Requ
 		return handleError(err)
 	}
 
+	b.Core.sendCoreEvent(ctx, eventTypeAuthDisable, nil, "sys/auth/"+path, map[string]string{
+		"path": fullPath,
+	})
+
 	return nil, nil
 }
 
@@ -4510,6 +4527,47 @@ type SealStatusResponse struct {
 	RecoverySeal bool     `json:"recovery_seal"`
 	StorageType  string   `json:"storage_type,omitempty"`
 	Warnings     []string `json:"warnings,omitempty"`
+
+	// Seals reports the health of each seal when several seals are
+	// configured.
+	Seals []SealBackendStatus `json:"seals,omitempty"`
+}
+
+// SealBackendStatus is the health of one of several configured seals.
+type SealBackendStatus struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Priority        int    `json:"priority"`
+	Healthy         bool   `json:"healthy"`
+	LastHealthCheck string `json:"last_health_check,omitempty"`
+	UnhealthySince  string `json:"unhealthy_since,omitempty"`
+}
+
+// sealBackendStatus returns the health of each seal when several seals are
+// configured, and nil otherwise.
+func (core *Core) sealBackendStatus(ctx context.Context) []SealBackendStatus {
+	multi, ok := core.SealAccess().GetAccess().(*vaultseal.MultiAccess)
+	if !ok {
+		return nil
+	}
+
+	var ret []SealBackendStatus
+	for _, s := range multi.Status(ctx) {
+		status := SealBackendStatus{
+			Name:     s.Name,
+			Type:     s.Type,
+			Priority: s.Priority,
+			Healthy:  s.Healthy,
+		}
+		if !s.LastHealthCheck.IsZero() {
+			status.LastHealthCheck = s.LastHealthCheck.UTC().Format(time.RFC3339)
+		}
+		if !s.Healthy && !s.LastSeenHealthy.IsZero() {
+			status.UnhealthySince = s.LastSeenHealthy.UTC().Format(time.RFC3339)
+		}
+		ret = append(ret, status)
+	}
+	return ret
 }
 
 func (core *Core) GetSealStatus(ctx context.Context, lock bool) (*SealStatusResponse, error) {
@@ -4539,6 +4597,7 @@ func (core *Core) GetSealStatus(ctx context.Context, lock bool) (*SealStatusResp
 			StorageType:  core.StorageType(),
 			Version:      version.GetVersion().VersionNumber(),
 			BuildDate:    version.BuildDate,
+			Seals:        core.sealBackendStatus(ctx),
 		}
 
 		return s, nil
@@ -4575,6 +4634,7 @@ func (core *Core) GetSealStatus(ctx context.Context, lock bool) (*SealStatusResp
 		ClusterID:    clusterID,
 		RecoverySeal: core.SealAccess().RecoveryKeySupported(),
 		StorageType:  core.StorageType(),
+		Seals:        core.sealBackendStatus(ctx),
 	}
 
 	return s, nil
@@ -0,0 +1,68 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// eventsPaths returns paths that describe the event subscription endpoint.
+// Subscriptions are authorized, audited and checked against quotas through
+// this path, after which the HTTP layer attaches the long-lived stream.
+func (b *SystemBackend) eventsPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "events/subscribe/(?P<pattern>.+)",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "events",
+				OperationVerb:   "subscribe",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"pattern": {
+					Type:        framework.TypeString,
+					Description: "Glob pattern of the event types to subscribe to, e.g. kv-v2/*.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleEventsSubscribe,
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+						}},
+					},
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(eventsHelp["events-subscribe"][0]),
+			HelpDescription: strings.TrimSpace(eventsHelp["events-subscribe"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleEventsSubscribe(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	// Nothing to do here: reaching the handler means the subscription has
+	// passed authorization, auditing and quotas. The stream itself is
+	// served by the HTTP layer.
+	return &logical.Response{}, nil
+}
+
+var eventsHelp = map[string][2]string{
+	"events-subscribe": {
+		"Subscribe to events matching a pattern.",
+		`Streams events whose type matches the glob pattern, such as "kv-v2/*",
+as server-sent events, or as JSON messages when the connection is upgraded
+to a WebSocket. Only events originating in the request namespace or its
+children, and relating to paths the token is allowed to read, are
+delivered.`,
+	},
+}
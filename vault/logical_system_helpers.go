@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/openbao/openbao/helper/namespace"
 	"github.com/openbao/openbao/sdk/v2/framework"
 	"github.com/openbao/openbao/sdk/v2/logical"
 )
@@ -35,7 +36,20 @@ var pathInternalUINamespacesRead = func(b *SystemBackend) framework.OperationFun
 			return nil, logical.ErrPermissionDenied
 		}
 
-		return logical.ListResponse([]string{""}), nil
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// List the namespaces below the request namespace, relative to it
+		keys := []string{""}
+		if b.Core.namespaceStore != nil {
+			for _, child := range b.Core.namespaceStore.ListNamespaces(ns, true) {
+				keys = append(keys, strings.TrimPrefix(child.Path, ns.Path))
+			}
+		}
+
+		return logical.ListResponse(keys), nil
 	}
 }
 
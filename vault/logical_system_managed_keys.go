@@ -0,0 +1,462 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// managedKeyPaths returns paths that enable managed key management
+func (b *SystemBackend) managedKeyPaths() []*framework.Path {
+	typeField := &framework.FieldSchema{
+		Type:          framework.TypeString,
+		Description:   "Type of the managed key, either pkcs11 or transit.",
+		AllowedValues: []interface{}{managedKeyTypePKCS11, managedKeyTypeTransit},
+	}
+	typeRegex := "(?P<type>" + strings.Join(managedKeyTypes, "|") + ")"
+
+	keyResponseFields := map[string]*framework.FieldSchema{
+		"name": {
+			Type:     framework.TypeString,
+			Required: true,
+		},
+		"type": {
+			Type:     framework.TypeString,
+			Required: true,
+		},
+		"uuid": {
+			Type:     framework.TypeString,
+			Required: true,
+		},
+		"usages": {
+			Type:     framework.TypeStringSlice,
+			Required: true,
+		},
+		"allow_generate_key": {
+			Type:     framework.TypeBool,
+			Required: true,
+		},
+		"any_mount": {
+			Type:     framework.TypeBool,
+			Required: true,
+		},
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "managed-keys/" + typeRegex + "/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "managed-keys",
+				OperationVerb:   "list",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"type": typeField,
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeysList(),
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"keys": {
+									Type:     framework.TypeStringSlice,
+									Required: true,
+								},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(managedKeysHelp["managed-keys-list"][0]),
+			HelpDescription: strings.TrimSpace(managedKeysHelp["managed-keys-list"][1]),
+		},
+		{
+			Pattern: "managed-keys/" + typeRegex + "/" + framework.GenericNameRegex("name") + "$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "managed-keys",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"type": typeField,
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the managed key.",
+				},
+				"usages": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Operations the key may be used for: encrypt, decrypt, sign, verify, wrap, unwrap or generate_random.",
+				},
+				"allow_generate_key": {
+					Type:        framework.TypeBool,
+					Description: "If set, the key may be generated in the KMS by OpenBao when it does not exist yet.",
+				},
+				"any_mount": {
+					Type:        framework.TypeBool,
+					Description: "If set, the key may be used by any mount of the namespace, regardless of its allowed_managed_keys.",
+				},
+				"library": {
+					Type:        framework.TypeString,
+					Description: "PKCS#11: name of the PKCS#11 library of the HSM, as configured by a kms_library stanza of the server configuration.",
+				},
+				"slot": {
+					Type:        framework.TypeString,
+					Description: "PKCS#11: number of the slot holding the token. Mutually exclusive with token_label.",
+				},
+				"token_label": {
+					Type:        framework.TypeString,
+					Description: "PKCS#11: label of the token holding the key. Mutually exclusive with slot.",
+				},
+				"pin": {
+					Type:        framework.TypeString,
+					Description: "PKCS#11: PIN of the user of the token. This is never returned.",
+				},
+				"key_label": {
+					Type:        framework.TypeString,
+					Description: "PKCS#11: label of the key on the token.",
+				},
+				"key_id": {
+					Type:        framework.TypeString,
+					Description: "PKCS#11: hex encoded ID of the key on the token.",
+				},
+				"key_bits": {
+					Type:        framework.TypeString,
+					Description: "PKCS#11: size of RSA keys generated by OpenBao: 2048 (default), 3072 or 4096.",
+				},
+				"curve": {
+					Type:        framework.TypeString,
+					Description: "PKCS#11: curve of EC keys generated by OpenBao: P-224, P-256 (default), P-384 or P-521.",
+				},
+				"mount_path": {
+					Type:        framework.TypeString,
+					Description: "Transit: path of the transit mount holding the key, in the same namespace.",
+				},
+				"key_name": {
+					Type:        framework.TypeString,
+					Description: "Transit: name of the key in the transit mount.",
+				},
+				"key_type": {
+					Type: framework.TypeString,
+					Description: `Type of keys generated by OpenBao. For pkcs11, either rsa (default) or
+ec. For transit, any asymmetric transit key type, such as rsa-2048 (default).`,
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeysRead(),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "read",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      keyResponseFields,
+						}},
+					},
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeysWrite(),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "write",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      keyResponseFields,
+						}},
+					},
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeysDelete(),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "delete",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: "OK",
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(managedKeysHelp["managed-keys"][0]),
+			HelpDescription: strings.TrimSpace(managedKeysHelp["managed-keys"][1]),
+		},
+		{
+			Pattern: "managed-keys/" + typeRegex + "/" + framework.GenericNameRegex("name") + "/test/sign$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "managed-keys",
+				OperationVerb:   "test",
+				OperationSuffix: "signing",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"type": typeField,
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the managed key.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeysTestSign(),
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: "OK",
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(managedKeysHelp["managed-keys-test-sign"][0]),
+			HelpDescription: strings.TrimSpace(managedKeysHelp["managed-keys-test-sign"][1]),
+		},
+	}
+}
+
+// managedKeyResponseData returns the API representation of a managed key,
+// without its sensitive configuration.
+func managedKeyResponseData(entry *managedKeyEntry) map[string]interface{} {
+	data := map[string]interface{}{
+		"name":               entry.Name,
+		"type":               entry.Type,
+		"uuid":               entry.UUID,
+		"usages":             entry.Usages,
+		"allow_generate_key": entry.AllowGenerateKey,
+		"any_mount":          entry.AnyMount,
+	}
+	for k, v := range entry.Config {
+		if !slices.Contains(managedKeySensitiveConfig, k) {
+			data[k] = v
+		}
+	}
+	return data
+}
+
+// handleManagedKeysList handles the "/sys/managed-keys/<type>" endpoint to
+// list the managed keys of a type in the namespace of the request.
+func (b *SystemBackend) handleManagedKeysList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		keys, err := b.Core.listManagedKeys(ctx, ns, d.Get("type").(string))
+		if err != nil {
+			return nil, err
+		}
+		return logical.ListResponse(keys), nil
+	}
+}
+
+// handleManagedKeysRead handles the "/sys/managed-keys/<type>/<name>"
+// endpoint to read a managed key.
+func (b *SystemBackend) handleManagedKeysRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := b.Core.readManagedKey(ctx, ns, d.Get("type").(string), d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: managedKeyResponseData(entry),
+		}, nil
+	}
+}
+
+// handleManagedKeysWrite handles the "/sys/managed-keys/<type>/<name>"
+// endpoint to create or update a managed key. Parameters which are not
+// provided keep their current value.
+func (b *SystemBackend) handleManagedKeysWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		keyType := d.Get("type").(string)
+		name := d.Get("name").(string)
+
+		entry, err := b.Core.readManagedKey(ctx, ns, keyType, name)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			entry = &managedKeyEntry{
+				Name:   name,
+				Type:   keyType,
+				Usages: []string{"sign", "verify"},
+				Config: make(map[string]string),
+			}
+		}
+
+		if raw, ok := d.GetOk("usages"); ok {
+			entry.Usages = raw.([]string)
+		}
+		if raw, ok := d.GetOk("allow_generate_key"); ok {
+			entry.AllowGenerateKey = raw.(bool)
+		}
+		if raw, ok := d.GetOk("any_mount"); ok {
+			entry.AnyMount = raw.(bool)
+		}
+
+		var params []string
+		switch keyType {
+		case managedKeyTypePKCS11:
+			params = pkcs11ManagedKeyConfig
+		case managedKeyTypeTransit:
+			params = transitManagedKeyConfig
+		}
+		for _, param := range params {
+			raw, ok := d.GetOk(param)
+			if !ok {
+				continue
+			}
+			if value := raw.(string); value != "" {
+				entry.Config[param] = value
+			} else {
+				delete(entry.Config, param)
+			}
+		}
+
+		if err := b.Core.writeManagedKey(ctx, ns, entry); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		return &logical.Response{
+			Data: managedKeyResponseData(entry),
+		}, nil
+	}
+}
+
+// handleManagedKeysDelete handles the "/sys/managed-keys/<type>/<name>"
+// endpoint to delete a managed key.
+func (b *SystemBackend) handleManagedKeysDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := b.Core.readManagedKey(ctx, ns, d.Get("type").(string), d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, nil
+		}
+
+		if err := b.Core.deleteManagedKey(ctx, ns, entry); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		return nil, nil
+	}
+}
+
+// handleManagedKeysTestSign handles the
+// "/sys/managed-keys/<type>/<name>/test/sign" endpoint to check that a
+// managed key is reachable by signing and verifying random data.
+func (b *SystemBackend) handleManagedKeysTestSign() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		name := d.Get("name").(string)
+		entry, err := b.Core.readManagedKey(ctx, ns, d.Get("type").(string), name)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return logical.ErrorResponse("managed key %q does not exist", name), logical.ErrInvalidRequest
+		}
+
+		key, err := b.Core.instantiateManagedKey(ctx, entry)
+		if err != nil {
+			return logical.ErrorResponse("failed to instantiate managed key: %s", err), logical.ErrInvalidRequest
+		}
+		defer key.close()
+
+		publicKey, err := key.GetPublicKey(ctx)
+		if err != nil {
+			return logical.ErrorResponse("failed to read public key: %s", err), logical.ErrInvalidRequest
+		}
+
+		data := make([]byte, 32)
+		if _, err := rand.Read(data); err != nil {
+			return nil, err
+		}
+
+		var opts crypto.SignerOpts = crypto.SHA256
+		value := data
+		if _, ok := publicKey.(ed25519.PublicKey); ok {
+			// Ed25519 keys sign messages rather than digests
+			opts = crypto.Hash(0)
+		} else {
+			digest := sha256.Sum256(data)
+			value = digest[:]
+		}
+
+		signature, err := key.Sign(ctx, value, rand.Reader, opts)
+		if err != nil {
+			return logical.ErrorResponse("failed to sign: %s", err), logical.ErrInvalidRequest
+		}
+		valid, err := key.Verify(ctx, signature, value, opts)
+		if err != nil {
+			return logical.ErrorResponse("failed to verify: %s", err), logical.ErrInvalidRequest
+		}
+		if !valid {
+			return logical.ErrorResponse("signature made by the managed key did not verify"), logical.ErrInvalidRequest
+		}
+
+		return logical.RespondWithStatusCode(nil, req, http.StatusNoContent)
+	}
+}
+
+var managedKeysHelp = map[string][2]string{
+	"managed-keys-list": {
+		"List the managed keys of a type.",
+		"",
+	},
+	"managed-keys": {
+		"Create, read, update or delete a managed key.",
+		fmt.Sprintf(`Managed keys are held by an external KMS, such as an HSM accessed through
+PKCS#11 or a transit mount, and are used by mounts without the key material
+ever entering OpenBao storage. A mount may only use the keys listed in its
+allowed_managed_keys, unless the key is configured with any_mount.
+Supported types are: %s.`, strings.Join(managedKeyTypes, ", ")),
+	},
+	"managed-keys-test-sign": {
+		"Test a managed key by signing random data.",
+		`Signs random data with the managed key and verifies the signature against
+its public key, to check that the key is reachable and usable.`,
+	},
+}
@@ -0,0 +1,468 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/sdk/v2/framework"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// namespacePaths returns paths that enable namespace management
+func (b *SystemBackend) namespacePaths() []*framework.Path {
+	namespaceResponseFields := map[string]*framework.FieldSchema{
+		"id": {
+			Type:     framework.TypeString,
+			Required: true,
+		},
+		"path": {
+			Type:     framework.TypeString,
+			Required: true,
+		},
+		"custom_metadata": {
+			Type:     framework.TypeMap,
+			Required: true,
+		},
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "namespaces/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "namespaces",
+				OperationVerb:   "list",
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesList(),
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"keys": {
+									Type:     framework.TypeStringSlice,
+									Required: true,
+								},
+								"key_info": {
+									Type:     framework.TypeMap,
+									Required: true,
+								},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(namespacesHelp["namespaces-list"][0]),
+			HelpDescription: strings.TrimSpace(namespacesHelp["namespaces-list"][1]),
+		},
+		{
+			Pattern: "namespaces/api-lock/lock" + framework.OptionalParamRegex("path"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "namespaces",
+				OperationVerb:   "lock",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Path of the namespace to lock, relative to the namespace of the request. Defaults to the namespace of the request.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesLock(),
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"unlock_key": {
+									Type:     framework.TypeString,
+									Required: true,
+								},
+							},
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(namespacesHelp["namespaces-lock"][0]),
+			HelpDescription: strings.TrimSpace(namespacesHelp["namespaces-lock"][1]),
+		},
+		{
+			Pattern: "namespaces/api-lock/unlock" + framework.OptionalParamRegex("path"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "namespaces",
+				OperationVerb:   "unlock",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Path of the namespace to unlock, relative to the namespace of the request. Defaults to the namespace of the request.",
+				},
+				"unlock_key": {
+					Type:        framework.TypeString,
+					Description: "Key returned when the namespace was locked. Not required when using a root token.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesUnlock(),
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: "OK",
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(namespacesHelp["namespaces-unlock"][0]),
+			HelpDescription: strings.TrimSpace(namespacesHelp["namespaces-unlock"][1]),
+		},
+		{
+			Pattern: "namespaces/(?P<path>.+)",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "namespaces",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Path of the namespace, relative to the namespace of the request.",
+				},
+				"custom_metadata": {
+					Type:        framework.TypeKVPairs,
+					Description: "User-provided key-value pairs that are used to describe the namespace.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesRead(),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "read",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      namespaceResponseFields,
+						}},
+					},
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesSet(),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "create",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      namespaceResponseFields,
+						}},
+					},
+				},
+				logical.PatchOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesPatch(),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "patch",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields:      namespaceResponseFields,
+						}},
+					},
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesDelete(),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "delete",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: "OK",
+						}},
+					},
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(namespacesHelp["namespaces"][0]),
+			HelpDescription: strings.TrimSpace(namespacesHelp["namespaces"][1]),
+		},
+	}
+}
+
+// namespaceResponseData returns the API representation of a namespace.
+func namespaceResponseData(ns *namespace.Namespace) map[string]interface{} {
+	customMetadata := make(map[string]interface{}, len(ns.CustomMetadata))
+	for k, v := range ns.CustomMetadata {
+		customMetadata[k] = v
+	}
+
+	return map[string]interface{}{
+		"id":              ns.ID,
+		"path":            ns.Path,
+		"custom_metadata": customMetadata,
+	}
+}
+
+// namespaceFromRequestPath resolves the namespace addressed by the path
+// parameter, relative to the namespace of the request.
+func (b *SystemBackend) namespaceFromRequestPath(ctx context.Context, d *framework.FieldData) (*namespace.Namespace, string, error) {
+	parent, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	relativePath := namespace.Canonicalize(d.Get("path").(string))
+	if relativePath == "" {
+		return nil, "", errors.New("missing namespace path")
+	}
+
+	return b.Core.namespaceStore.GetNamespaceByPath(parent.Path + relativePath), relativePath, nil
+}
+
+// handleNamespacesList handles "/sys/namespaces" endpoint to list the
+// child namespaces of the request namespace.
+func (b *SystemBackend) handleNamespacesList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		parent, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []string
+		keyInfo := make(map[string]interface{})
+		for _, ns := range b.Core.namespaceStore.ListNamespaces(parent, false) {
+			key := strings.TrimPrefix(ns.Path, parent.Path)
+			keys = append(keys, key)
+			keyInfo[key] = namespaceResponseData(ns)
+		}
+
+		return logical.ListResponseWithInfo(keys, keyInfo), nil
+	}
+}
+
+// handleNamespacesRead handles the "/sys/namespaces/<path>" endpoint to
+// read a namespace.
+func (b *SystemBackend) handleNamespacesRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, _, err := b.namespaceFromRequestPath(ctx, d)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if ns == nil {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: namespaceResponseData(ns),
+		}, nil
+	}
+}
+
+// handleNamespacesSet handles the "/sys/namespaces/<path>" endpoint to
+// create a namespace.
+func (b *SystemBackend) handleNamespacesSet() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		existing, relativePath, err := b.namespaceFromRequestPath(ctx, d)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		customMetadata := d.Get("custom_metadata").(map[string]string)
+		if existing != nil {
+			if _, ok := d.GetOk("custom_metadata"); !ok {
+				return &logical.Response{
+					Data: namespaceResponseData(existing),
+				}, nil
+			}
+
+			ns, err := b.Core.namespaceStore.PatchNamespace(ctx, existing.Path, customMetadata)
+			if err != nil {
+				return nil, err
+			}
+			return &logical.Response{
+				Data: namespaceResponseData(ns),
+			}, nil
+		}
+
+		ns, _, err := b.Core.namespaceStore.CreateNamespace(ctx, relativePath, customMetadata)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		return &logical.Response{
+			Data: namespaceResponseData(ns),
+		}, nil
+	}
+}
+
+// handleNamespacesPatch handles the "/sys/namespaces/<path>" endpoint to
+// update the custom metadata of a namespace using a JSON merge patch.
+func (b *SystemBackend) handleNamespacesPatch() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		existing, _, err := b.namespaceFromRequestPath(ctx, d)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if existing == nil {
+			return logical.ErrorResponse("namespace %q does not exist", d.Get("path").(string)), logical.ErrInvalidRequest
+		}
+
+		customMetadata := make(map[string]string, len(existing.CustomMetadata))
+		for k, v := range existing.CustomMetadata {
+			customMetadata[k] = v
+		}
+
+		if raw, ok := req.Data["custom_metadata"]; ok && raw != nil {
+			patch, ok := raw.(map[string]interface{})
+			if !ok {
+				return logical.ErrorResponse("custom_metadata must be a map"), logical.ErrInvalidRequest
+			}
+
+			for k, v := range patch {
+				switch value := v.(type) {
+				case nil:
+					delete(customMetadata, k)
+				case string:
+					customMetadata[k] = value
+				default:
+					return logical.ErrorResponse(fmt.Sprintf("custom_metadata value for %q must be a string", k)), logical.ErrInvalidRequest
+				}
+			}
+		}
+
+		ns, err := b.Core.namespaceStore.PatchNamespace(ctx, existing.Path, customMetadata)
+		if err != nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: namespaceResponseData(ns),
+		}, nil
+	}
+}
+
+// handleNamespacesDelete handles the "/sys/namespaces/<path>" endpoint to
+// delete a namespace.
+func (b *SystemBackend) handleNamespacesDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		existing, _, err := b.namespaceFromRequestPath(ctx, d)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if existing == nil {
+			return nil, nil
+		}
+
+		if _, err := b.Core.namespaceStore.DeleteNamespace(ctx, existing.Path); err != nil {
+			if errors.Is(err, errNamespaceHasChildren) || errors.Is(err, errNamespaceBeingDeleted) {
+				return logical.ErrorResponse(fmt.Sprintf("cannot delete namespace %q: %s", existing.Path, err)), logical.ErrInvalidRequest
+			}
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+// apiLockTargetPath returns the absolute path of the namespace addressed by
+// an api-lock request, which defaults to the namespace of the request.
+func apiLockTargetPath(ctx context.Context, d *framework.FieldData) (string, error) {
+	parent, err := namespace.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return parent.Path + namespace.Canonicalize(d.Get("path").(string)), nil
+}
+
+// handleNamespacesLock handles the "/sys/namespaces/api-lock/lock"
+// endpoint to lock API access to a namespace and its descendants.
+func (b *SystemBackend) handleNamespacesLock() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		nsPath, err := apiLockTargetPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+
+		unlockKey, err := b.Core.namespaceStore.LockNamespace(ctx, nsPath)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"unlock_key": unlockKey,
+			},
+		}, nil
+	}
+}
+
+// handleNamespacesUnlock handles the "/sys/namespaces/api-lock/unlock"
+// endpoint to unlock API access to a namespace. Root tokens may unlock a
+// namespace without providing its unlock key.
+func (b *SystemBackend) handleNamespacesUnlock() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		nsPath, err := apiLockTargetPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+
+		var force bool
+		if req.ClientToken != "" {
+			acl, _, _, _, err := b.Core.fetchACLTokenEntryAndEntity(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			force = acl != nil && acl.root
+		}
+
+		unlockKey := d.Get("unlock_key").(string)
+		if unlockKey == "" && !force {
+			return logical.ErrorResponse("missing unlock_key"), logical.ErrInvalidRequest
+		}
+
+		if err := b.Core.namespaceStore.UnlockNamespace(ctx, nsPath, unlockKey, force); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		return nil, nil
+	}
+}
+
+var namespacesHelp = map[string][2]string{
+	"namespaces-list": {
+		"List the child namespaces of the current namespace.",
+		"",
+	},
+	"namespaces-lock": {
+		"Lock API access to a namespace and its descendants.",
+		`Once locked, all requests to the namespace and its descendants are
+rejected, except for requests to unlock it. The returned unlock_key is
+required to unlock the namespace again, unless a root token is used.`,
+	},
+	"namespaces-unlock": {
+		"Unlock API access to a namespace and its descendants.",
+		`The unlock_key returned when locking the namespace must be provided,
+unless the request is made with a root token.`,
+	},
+	"namespaces": {
+		"Create, read, patch or delete a namespace.",
+		`Namespaces isolate mounts, policies, tokens and identities from one
+another. A namespace is created relative to the namespace of the request
+and may only be deleted once it no longer has any child namespaces.`,
+	},
+}
@@ -0,0 +1,354 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func testCreateNamespace(t *testing.T, ctx context.Context, c *Core, root, path string, customMetadata map[string]string) *namespace.Namespace {
+	t.Helper()
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/"+path)
+	req.ClientToken = root
+	if customMetadata != nil {
+		req.Data["custom_metadata"] = customMetadata
+	}
+	resp, err := c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.False(t, resp.IsError(), "unexpected error response: %#v", resp)
+
+	ns := c.namespaceStore.GetNamespace(resp.Data["id"].(string))
+	require.NotNil(t, ns)
+	return ns
+}
+
+func TestSystemBackend_Namespaces_CRUD(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	ns := testCreateNamespace(t, ctx, c, root, "foo", map[string]string{"team": "a"})
+	require.Equal(t, "foo/", ns.Path)
+	require.Len(t, ns.ID, namespaceIDLength)
+
+	// Creating the namespace again returns the existing one
+	again := testCreateNamespace(t, ctx, c, root, "foo", nil)
+	require.Equal(t, ns.ID, again.ID)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/namespaces/foo")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"id":              ns.ID,
+		"path":            "foo/",
+		"custom_metadata": map[string]interface{}{"team": "a"},
+	}, resp.Data)
+
+	req = logical.TestRequest(t, logical.PatchOperation, "sys/namespaces/foo")
+	req.ClientToken = root
+	req.Data["custom_metadata"] = map[string]interface{}{
+		"team":  nil,
+		"owner": "b",
+	}
+	resp, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"owner": "b"}, resp.Data["custom_metadata"])
+
+	req = logical.TestRequest(t, logical.ListOperation, "sys/namespaces")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo/"}, resp.Data["keys"])
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/foo")
+	req.ClientToken = root
+	_, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.Nil(t, c.namespaceStore.GetNamespaceByPath("foo/"))
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/namespaces/foo")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.Nil(t, resp)
+}
+
+func TestSystemBackend_Namespaces_Nested(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	// Parents are not created implicitly
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/foo/bar")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(ctx, req)
+	require.Error(t, err)
+	require.True(t, resp.IsError())
+
+	// Reserved names are rejected
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/sys")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(ctx, req)
+	require.Error(t, err)
+	require.True(t, resp.IsError())
+
+	foo := testCreateNamespace(t, ctx, c, root, "foo", nil)
+	fooCtx := namespace.ContextWithNamespace(ctx, foo)
+	bar := testCreateNamespace(t, fooCtx, c, root, "bar", nil)
+	require.Equal(t, "foo/bar/", bar.Path)
+
+	resolved, relativePath := c.ResolveNamespaceFromRequest("foo", "bar/sys/mounts")
+	require.Equal(t, bar.ID, resolved.ID)
+	require.Equal(t, "sys/mounts", relativePath)
+
+	resolved, _ = c.ResolveNamespaceFromRequest("missing", "sys/mounts")
+	require.Nil(t, resolved)
+
+	// A namespace with children cannot be deleted
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/foo")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(ctx, req)
+	require.Error(t, err)
+	require.True(t, resp.IsError())
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/bar")
+	req.ClientToken = root
+	_, err = c.HandleRequest(fooCtx, req)
+	require.NoError(t, err)
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/foo")
+	req.ClientToken = root
+	_, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+}
+
+func TestSystemBackend_Namespaces_Isolation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	ns := testCreateNamespace(t, ctx, c, root, "foo", nil)
+	nsCtx := namespace.ContextWithNamespace(ctx, ns)
+
+	// The namespace has its own singleton mounts and default policy
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(nsCtx, req)
+	require.NoError(t, err)
+	require.Contains(t, resp.Data, "sys/")
+	require.Contains(t, resp.Data, "cubbyhole/")
+	require.Contains(t, resp.Data, "identity/")
+	require.NotContains(t, resp.Data, "secret/")
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/policy/default")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(nsCtx, req)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	// Mounts in the namespace are not visible from the root namespace
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/kv")
+	req.ClientToken = root
+	req.Data["type"] = "kv"
+	_, err = c.HandleRequest(nsCtx, req)
+	require.NoError(t, err)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "kv/secret")
+	req.ClientToken = root
+	req.Data["value"] = "bar"
+	_, err = c.HandleRequest(nsCtx, req)
+	require.NoError(t, err)
+
+	require.Empty(t, c.router.MatchingMount(ctx, "kv/secret"))
+
+	// Server-wide paths are not available within a namespace
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/config/cors")
+	req.ClientToken = root
+	_, err = c.HandleRequest(nsCtx, req)
+	require.ErrorIs(t, err, logical.ErrUnsupportedPath)
+
+	// A mount may not shadow an existing namespace
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/foo")
+	req.ClientToken = root
+	req.Data["type"] = "kv"
+	_, err = c.HandleRequest(ctx, req)
+	require.Error(t, err)
+
+	// Deleting the namespace removes all of its storage
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/foo")
+	req.ClientToken = root
+	_, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+
+	keys, err := c.barrier.List(ctx, namespaceBarrierPrefix+ns.ID+"/")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	for _, entry := range c.mounts.Entries {
+		require.NotEqual(t, ns.ID, entry.NamespaceID)
+	}
+	for _, entry := range c.auth.Entries {
+		require.NotEqual(t, ns.ID, entry.NamespaceID)
+	}
+}
+
+func TestSystemBackend_Namespaces_APILock(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	foo := testCreateNamespace(t, ctx, c, root, "foo", nil)
+	fooCtx := namespace.ContextWithNamespace(ctx, foo)
+	bar := testCreateNamespace(t, fooCtx, c, root, "bar", nil)
+	barCtx := namespace.ContextWithNamespace(ctx, bar)
+
+	// api-lock is not a namespace name
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/api-lock")
+	req.ClientToken = root
+	_, err := c.HandleRequest(ctx, req)
+	require.Error(t, err)
+
+	// The root namespace cannot be locked
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/api-lock/lock")
+	req.ClientToken = root
+	_, err = c.HandleRequest(ctx, req)
+	require.Error(t, err)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/api-lock/lock/foo")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	unlockKey := resp.Data["unlock_key"].(string)
+	require.NotEmpty(t, unlockKey)
+	require.Nil(t, c.namespaceStore.GetNamespaceByPath("api-lock/lock/foo/"))
+
+	// The namespace and its descendants are locked
+	for _, nsCtx := range []context.Context{fooCtx, barCtx} {
+		req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+		req.ClientToken = root
+		_, err = c.HandleRequest(nsCtx, req)
+		require.ErrorIs(t, err, logical.ErrPermissionDenied)
+	}
+
+	// The parent namespace is not
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	_, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+
+	// The unlock endpoint remains reachable within the locked namespace
+	// and requires the unlock key for non-root tokens
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/policy/unlocker")
+	req.ClientToken = root
+	req.Data["policy"] = `path "foo/sys/namespaces/api-lock/unlock" { capabilities = ["update"] }`
+	_, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	testMakeServiceTokenViaCore(t, c, root, "unlocker", "", []string{"unlocker"})
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/api-lock/unlock")
+	req.ClientToken = "unlocker"
+	_, err = c.HandleRequest(fooCtx, req)
+	require.Error(t, err)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/api-lock/unlock")
+	req.ClientToken = "unlocker"
+	req.Data["unlock_key"] = "wrong"
+	_, err = c.HandleRequest(fooCtx, req)
+	require.Error(t, err)
+	require.True(t, c.namespaceStore.IsLocked(foo))
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/api-lock/unlock")
+	req.ClientToken = "unlocker"
+	req.Data["unlock_key"] = unlockKey
+	_, err = c.HandleRequest(fooCtx, req)
+	require.NoError(t, err)
+	require.False(t, c.namespaceStore.IsLocked(bar))
+
+	// Root tokens may unlock without the key
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/api-lock/lock/bar")
+	req.ClientToken = root
+	_, err = c.HandleRequest(fooCtx, req)
+	require.NoError(t, err)
+	require.True(t, c.namespaceStore.IsLocked(bar))
+	require.False(t, c.namespaceStore.IsLocked(foo))
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/api-lock/unlock/foo/bar")
+	req.ClientToken = root
+	_, err = c.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.False(t, c.namespaceStore.IsLocked(bar))
+}
+
+func TestNamespaceStore_PendingNamespace(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	store := c.namespaceStore
+
+	// A namespace still being initialized resolves by ID only
+	pending := &namespace.Namespace{ID: "abcde", Path: "foo/", CustomMetadata: map[string]string{}}
+	store.lock.Lock()
+	store.namespacesByID[pending.ID] = pending
+	store.pendingByPath[pending.Path] = pending
+	store.lock.Unlock()
+
+	require.NotNil(t, store.GetNamespace(pending.ID))
+	require.Nil(t, store.GetNamespaceByPath("foo/"))
+	resolved, _ := c.ResolveNamespaceFromRequest("", "foo/sys/mounts")
+	require.Equal(t, namespace.RootNamespaceID, resolved.ID)
+	require.Empty(t, store.ListNamespaces(namespace.RootNamespace, true))
+	require.Equal(t, "foo/", store.MountConflict(namespace.RootNamespace, "foo"))
+
+	_, _, err := store.CreateNamespace(ctx, "foo", nil)
+	require.ErrorIs(t, err, errNamespaceBeingCreated)
+}
+
+func TestNamespaceStore_DeletingNamespace(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	store := c.namespaceStore
+
+	foo, _, err := store.CreateNamespace(ctx, "foo", nil)
+	require.NoError(t, err)
+	fooCtx := namespace.ContextWithNamespace(ctx, foo)
+
+	// A child still being created blocks the deletion of its parent
+	pending := &namespace.Namespace{ID: "abcde", Path: "foo/bar/", CustomMetadata: map[string]string{}}
+	store.lock.Lock()
+	store.pendingByPath[pending.Path] = pending
+	store.lock.Unlock()
+
+	_, err = store.DeleteNamespace(ctx, "foo/")
+	require.ErrorIs(t, err, errNamespaceHasChildren)
+
+	store.lock.Lock()
+	delete(store.pendingByPath, pending.Path)
+	store.lock.Unlock()
+
+	// No child can be created under a namespace being torn down, and it
+	// cannot be deleted twice
+	store.lock.Lock()
+	store.deletingByPath[foo.Path] = foo
+	store.lock.Unlock()
+
+	_, _, err = store.CreateNamespace(fooCtx, "bar", nil)
+	require.ErrorIs(t, err, errNamespaceBeingDeleted)
+	_, _, err = store.CreateNamespace(ctx, "foo", nil)
+	require.ErrorIs(t, err, errNamespaceBeingDeleted)
+	_, err = store.DeleteNamespace(ctx, "foo/")
+	require.ErrorIs(t, err, errNamespaceBeingDeleted)
+
+	store.lock.Lock()
+	delete(store.deletingByPath, foo.Path)
+	store.lock.Unlock()
+
+	deleted, err := store.DeleteNamespace(ctx, "foo/")
+	require.NoError(t, err)
+	require.True(t, deleted)
+	require.Empty(t, store.deletingByPath)
+	require.Nil(t, store.GetNamespaceByPath("foo/"))
+}
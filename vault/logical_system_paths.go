@@ -1234,6 +1234,10 @@ func (b *SystemBackend) rekeyPaths() []*framework.Path {
 									Type:     framework.TypeString,
 									Required: false,
 								},
+								"seals": {
+									Type:     framework.TypeSlice,
+									Required: false,
+								},
 							},
 						}},
 					},
@@ -1386,6 +1390,10 @@ func (b *SystemBackend) statusPaths() []*framework.Path {
 									Type:     framework.TypeString,
 									Required: false,
 								},
+								"seals": {
+									Type:     framework.TypeSlice,
+									Required: false,
+								},
 							},
 						}},
 					},
@@ -201,6 +201,92 @@ func (b *SystemBackend) raftStoragePaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-autopilot-configuration"][0]),
 			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-autopilot-configuration"][1]),
 		},
+		{
+			Pattern: "storage/raft/snapshot-auto/config/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigList(),
+					Summary:  "Lists the automatic snapshot configurations.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config-list"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config-list"][1]),
+		},
+		{
+			Pattern: "storage/raft/snapshot-auto/config/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the automatic snapshot configuration.",
+				},
+				"interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Time between snapshots.",
+				},
+				"retain": {
+					Type:        framework.TypeInt,
+					Default:     defaultRaftAutoSnapshotRetain,
+					Description: "Number of snapshots to keep; older snapshots are deleted after a successful snapshot.",
+				},
+				"storage_type": {
+					Type:        framework.TypeString,
+					Default:     raftAutoSnapshotStorageLocal,
+					Description: `Where to store snapshots. Currently only "local" is supported.`,
+				},
+				"path_prefix": {
+					Type:        framework.TypeString,
+					Description: "Directory on the active node under which snapshots are written, for local storage. Each configuration uses a subdirectory named after it.",
+				},
+				"file_name_template": {
+					Type:        framework.TypeString,
+					Default:     defaultRaftAutoSnapshotFileNameTemplate,
+					Description: "Template for the snapshot file names. May reference {{.Name}}, {{.Timestamp}}, {{.Index}} and {{.NodeID}}, and must include {{.Timestamp}} or {{.Index}}.",
+				},
+			},
+
+			ExistenceCheck: b.handleStorageRaftSnapshotAutoConfigExistenceCheck(),
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigRead(),
+					Summary:  "Reads an automatic snapshot configuration.",
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigWrite(),
+					Summary:  "Creates an automatic snapshot configuration.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigWrite(),
+					Summary:  "Updates an automatic snapshot configuration.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigDelete(),
+					Summary:  "Deletes an automatic snapshot configuration.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config"][1]),
+		},
+		{
+			Pattern: "storage/raft/snapshot-auto/status/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the automatic snapshot configuration.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoStatusRead(),
+					Summary:  "Reports the status of an automatic snapshot configuration.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-status"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-status"][1]),
+		},
 	}
 }
 
@@ -672,6 +758,167 @@ func (b *SystemBackend) handleStorageRaftSnapshotWrite(force bool) framework.Ope
 	}
 }
 
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if b.Core.getRaftBackend() == nil {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		names, err := b.Core.barrier.List(ctx, raftAutoSnapshotConfigPath)
+		if err != nil {
+			return nil, err
+		}
+
+		return logical.ListResponse(names), nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigExistenceCheck() framework.ExistenceFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+		config, err := b.Core.readRaftAutoSnapshotConfig(ctx, d.Get("name").(string))
+		if err != nil {
+			return false, err
+		}
+
+		return config != nil, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if b.Core.getRaftBackend() == nil {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		config, err := b.Core.readRaftAutoSnapshotConfig(ctx, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"name":               config.Name,
+				"interval":           int64(config.Interval.Seconds()),
+				"retain":             config.Retain,
+				"storage_type":       config.StorageType,
+				"path_prefix":        config.PathPrefix,
+				"file_name_template": config.FileNameTemplate,
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if b.Core.getRaftBackend() == nil || b.Core.isRaftHAOnly() {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		name := d.Get("name").(string)
+		config, err := b.Core.readRaftAutoSnapshotConfig(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			if _, ok := d.GetOk("interval"); !ok {
+				return logical.ErrorResponse("interval is required"), logical.ErrInvalidRequest
+			}
+			config = &raftAutoSnapshotConfig{
+				Name:             name,
+				Retain:           d.Get("retain").(int),
+				StorageType:      d.Get("storage_type").(string),
+				FileNameTemplate: d.Get("file_name_template").(string),
+			}
+		}
+
+		if interval, ok := d.GetOk("interval"); ok {
+			config.Interval = time.Duration(interval.(int)) * time.Second
+		}
+		if retain, ok := d.GetOk("retain"); ok {
+			config.Retain = retain.(int)
+		}
+		if storageType, ok := d.GetOk("storage_type"); ok {
+			config.StorageType = storageType.(string)
+		}
+		if pathPrefix, ok := d.GetOk("path_prefix"); ok {
+			config.PathPrefix = pathPrefix.(string)
+		}
+		if fileNameTemplate, ok := d.GetOk("file_name_template"); ok {
+			config.FileNameTemplate = fileNameTemplate.(string)
+		}
+
+		if err := config.validate(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		if err := b.Core.putRaftAutoSnapshotConfig(ctx, config); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if err := b.Core.deleteRaftAutoSnapshotConfig(ctx, d.Get("name").(string)); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoStatusRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if b.Core.getRaftBackend() == nil {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		name := d.Get("name").(string)
+		config, err := b.Core.readRaftAutoSnapshotConfig(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return nil, nil
+		}
+
+		var status *raftAutoSnapshotStatus
+		if b.Core.raftAutoSnapshots != nil {
+			status = b.Core.raftAutoSnapshots.Status(name)
+		}
+		if status == nil {
+			status = &raftAutoSnapshotStatus{}
+		}
+
+		formatTime := func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"name":                config.Name,
+				"last_snapshot_start": formatTime(status.LastSnapshotStart),
+				"last_snapshot_end":   formatTime(status.LastSnapshotEnd),
+				"last_snapshot_path":  status.LastSnapshotPath,
+				"last_success_time":   formatTime(status.LastSuccess),
+				"last_failure_time":   formatTime(status.LastFailure),
+				"last_error":          status.LastError,
+				"consecutive_errors":  status.ConsecutiveErrors,
+				"next_snapshot_time":  formatTime(status.NextSnapshot),
+				"snapshots_retained":  status.Retained,
+			},
+		}, nil
+	}
+}
+
 var sysRaftHelp = map[string][2]string{
 	"raft-bootstrap-challenge": {
 		"Creates a challenge for the new peer to be joined to the raft cluster.",
@@ -705,4 +952,18 @@ var sysRaftHelp = map[string][2]string{
 		"Returns autopilot configuration.",
 		"",
 	},
+	"raft-snapshot-auto-config-list": {
+		"Lists the automatic snapshot configurations.",
+		"",
+	},
+	"raft-snapshot-auto-config": {
+		"Configures automatic snapshots of the raft cluster.",
+		`The active node takes a snapshot every interval and writes it to the
+configured storage, deleting all but the newest retained snapshots which
+match the file name template.`,
+	},
+	"raft-snapshot-auto-status": {
+		"Reports the status of an automatic snapshot configuration.",
+		"",
+	},
 }
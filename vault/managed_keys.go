@@ -0,0 +1,580 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	// managedKeysSubPath is the sub-path of the system view of a namespace
+	// under which managed keys are stored, as <type>/<name>.
+	managedKeysSubPath = "managed-keys/"
+
+	// managedKeysUUIDPrefix is the prefix of the index of managed keys by
+	// UUID, mapping each UUID to the <type>/<name> path of its key.
+	managedKeysUUIDPrefix = "uuid/"
+
+	managedKeyTypePKCS11  = "pkcs11"
+	managedKeyTypeTransit = "transit"
+)
+
+// managedKeyTypes lists the supported types of managed keys.
+var managedKeyTypes = []string{managedKeyTypePKCS11, managedKeyTypeTransit}
+
+// managedKeyUsages maps the usages accepted by the API to key usages.
+var managedKeyUsages = map[string]logical.KeyUsage{
+	"encrypt":         logical.KeyUsageEncrypt,
+	"decrypt":         logical.KeyUsageDecrypt,
+	"sign":            logical.KeyUsageSign,
+	"verify":          logical.KeyUsageVerify,
+	"wrap":            logical.KeyUsageWrap,
+	"unwrap":          logical.KeyUsageUnwrap,
+	"generate_random": logical.KeyUsageGenerateRandom,
+}
+
+// managedKeySensitiveConfig lists the configuration parameters of managed
+// keys which are never returned by the API.
+var managedKeySensitiveConfig = []string{"pin"}
+
+// managedKeyEntry is the stored configuration of a managed key. The key
+// material itself never leaves the KMS; only the information needed to
+// reach it is stored.
+type managedKeyEntry struct {
+	UUID             string            `json:"uuid"`
+	Name             string            `json:"name"`
+	Type             string            `json:"type"`
+	Usages           []string          `json:"usages"`
+	AllowGenerateKey bool              `json:"allow_generate_key"`
+	AnyMount         bool              `json:"any_mount"`
+	Config           map[string]string `json:"config"`
+}
+
+// keyUsages returns the usages allowed for the key.
+func (e *managedKeyEntry) keyUsages() []logical.KeyUsage {
+	usages := make([]logical.KeyUsage, 0, len(e.Usages))
+	for _, usage := range e.Usages {
+		usages = append(usages, managedKeyUsages[usage])
+	}
+	return usages
+}
+
+// validate checks the configuration of the key for its type.
+func (e *managedKeyEntry) validate() error {
+	for _, usage := range e.Usages {
+		if _, ok := managedKeyUsages[usage]; !ok {
+			return fmt.Errorf("unknown key usage %q", usage)
+		}
+	}
+
+	switch e.Type {
+	case managedKeyTypePKCS11:
+		return validatePKCS11ManagedKeyConfig(e.Config)
+	case managedKeyTypeTransit:
+		return validateTransitManagedKeyConfig(e.Config)
+	default:
+		return fmt.Errorf("unknown managed key type %q", e.Type)
+	}
+}
+
+// pkcs11ManagedKeyConfig lists the configuration parameters of managed keys
+// held by a PKCS#11 token.
+var pkcs11ManagedKeyConfig = []string{"library", "slot", "token_label", "pin", "key_label", "key_id", "key_type", "key_bits", "curve"}
+
+// validatePKCS11ManagedKeyConfig checks the configuration of a PKCS#11
+// managed key. This does not require PKCS#11 support in the build, so that
+// keys may be registered before all nodes are able to use them.
+func validatePKCS11ManagedKeyConfig(config map[string]string) error {
+	for name := range config {
+		if !slices.Contains(pkcs11ManagedKeyConfig, name) {
+			return fmt.Errorf("unknown configuration parameter %q for pkcs11 managed keys", name)
+		}
+	}
+
+	if config["library"] == "" {
+		return errors.New("missing library")
+	}
+	switch {
+	case config["slot"] == "" && config["token_label"] == "":
+		return errors.New("one of slot or token_label is required")
+	case config["slot"] != "" && config["token_label"] != "":
+		return errors.New("only one of slot or token_label may be set")
+	case config["slot"] != "":
+		if _, err := strconv.ParseUint(config["slot"], 10, 0); err != nil {
+			return fmt.Errorf("invalid slot: %w", err)
+		}
+	}
+	if config["pin"] == "" {
+		return errors.New("missing pin")
+	}
+	if config["key_label"] == "" && config["key_id"] == "" {
+		return errors.New("one of key_label or key_id is required")
+	}
+	if config["key_id"] != "" {
+		if _, err := hex.DecodeString(config["key_id"]); err != nil {
+			return fmt.Errorf("invalid key_id: %w", err)
+		}
+	}
+
+	switch config["key_type"] {
+	case "", "rsa":
+		if config["curve"] != "" {
+			return errors.New("curve is only valid for ec keys")
+		}
+		if raw := config["key_bits"]; raw != "" {
+			bits, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("invalid key_bits: %w", err)
+			}
+			if bits != 2048 && bits != 3072 && bits != 4096 {
+				return fmt.Errorf("unsupported key_bits %d for rsa keys", bits)
+			}
+		}
+	case "ec":
+		if config["key_bits"] != "" {
+			return errors.New("key_bits is only valid for rsa keys")
+		}
+		switch config["curve"] {
+		case "", "P-224", "P-256", "P-384", "P-521":
+		default:
+			return fmt.Errorf("unsupported curve %q", config["curve"])
+		}
+	default:
+		return fmt.Errorf("unsupported key_type %q", config["key_type"])
+	}
+
+	return nil
+}
+
+// managedKey is an instantiated managed key. A managed key may hold
+// resources, such as a PKCS#11 session, which are released by close once
+// the consumer of the key returns.
+type managedKey interface {
+	logical.ManagedSigningKey
+	logical.ManagedKeyLifecycle
+	close() error
+}
+
+// managedKeyBase implements the parts of logical.ManagedKey which only
+// depend on the stored configuration.
+type managedKeyBase struct {
+	entry *managedKeyEntry
+}
+
+func (k *managedKeyBase) Name() string {
+	return k.entry.Name
+}
+
+func (k *managedKeyBase) UUID() string {
+	return k.entry.UUID
+}
+
+func (k *managedKeyBase) AllowsAll(usages []logical.KeyUsage) bool {
+	allowed := k.entry.keyUsages()
+	for _, usage := range usages {
+		if !slices.Contains(allowed, usage) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkUsage returns an error if the key is not allowed for the usage.
+func (k *managedKeyBase) checkUsage(usage logical.KeyUsage) error {
+	if !k.AllowsAll([]logical.KeyUsage{usage}) {
+		return fmt.Errorf("managed key %q does not allow this usage", k.entry.Name)
+	}
+	return nil
+}
+
+// checkGenerateKey returns an error if the key may not be generated in the
+// KMS through OpenBao.
+func (k *managedKeyBase) checkGenerateKey() error {
+	if !k.entry.AllowGenerateKey {
+		return fmt.Errorf("managed key %q does not allow key generation", k.entry.Name)
+	}
+	return nil
+}
+
+// managedKeySigner implements crypto.Signer for a managed signing key.
+type managedKeySigner struct {
+	ctx       context.Context
+	key       logical.ManagedSigningKey
+	publicKey crypto.PublicKey
+}
+
+var _ crypto.Signer = (*managedKeySigner)(nil)
+
+func newManagedKeySigner(ctx context.Context, key logical.ManagedSigningKey) (*managedKeySigner, error) {
+	publicKey, err := key.GetPublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &managedKeySigner{
+		ctx:       ctx,
+		key:       key,
+		publicKey: publicKey,
+	}, nil
+}
+
+func (s *managedKeySigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+func (s *managedKeySigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(s.ctx, digest, rand, opts)
+}
+
+// managedKeyView returns the storage of the managed keys of a namespace.
+func (c *Core) managedKeyView(ns *namespace.Namespace) (BarrierView, error) {
+	view, err := c.barrierViewForNamespace(ns.ID)
+	if err != nil {
+		return nil, err
+	}
+	return view.SubView(managedKeysSubPath), nil
+}
+
+// readManagedKey returns the managed key of the given type and name in the
+// namespace, or nil if it does not exist.
+func (c *Core) readManagedKey(ctx context.Context, ns *namespace.Namespace, keyType, name string) (*managedKeyEntry, error) {
+	view, err := c.managedKeyView(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := view.Get(ctx, keyType+"/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read managed key: %w", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entry managedKeyEntry
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode managed key: %w", err)
+	}
+	return &entry, nil
+}
+
+// listManagedKeys returns the names of the managed keys of the given type in
+// the namespace.
+func (c *Core) listManagedKeys(ctx context.Context, ns *namespace.Namespace, keyType string) ([]string, error) {
+	view, err := c.managedKeyView(ns)
+	if err != nil {
+		return nil, err
+	}
+	return view.List(ctx, keyType+"/")
+}
+
+// readManagedKeyByName returns the managed key of any type with the given
+// name in the namespace, or nil if it does not exist.
+func (c *Core) readManagedKeyByName(ctx context.Context, ns *namespace.Namespace, name string) (*managedKeyEntry, error) {
+	for _, keyType := range managedKeyTypes {
+		entry, err := c.readManagedKey(ctx, ns, keyType, name)
+		if err != nil || entry != nil {
+			return entry, err
+		}
+	}
+	return nil, nil
+}
+
+// readManagedKeyByUUID returns the managed key with the given UUID in the
+// namespace, or nil if it does not exist.
+func (c *Core) readManagedKeyByUUID(ctx context.Context, ns *namespace.Namespace, keyUUID string) (*managedKeyEntry, error) {
+	view, err := c.managedKeyView(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := view.Get(ctx, managedKeysUUIDPrefix+keyUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read managed key index: %w", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	keyType, name, ok := strings.Cut(string(raw.Value), "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid managed key index entry for %q", keyUUID)
+	}
+	entry, err := c.readManagedKey(ctx, ns, keyType, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The index may be stale if the key was deleted midway
+	if entry == nil || entry.UUID != keyUUID {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// writeManagedKey validates and stores the managed key, assigning it a UUID
+// when it is first created. Key names are unique across types in a
+// namespace, as mounts reference keys by name alone.
+func (c *Core) writeManagedKey(ctx context.Context, ns *namespace.Namespace, entry *managedKeyEntry) error {
+	if err := entry.validate(); err != nil {
+		return err
+	}
+
+	if entry.Type == managedKeyTypePKCS11 {
+		if _, ok := c.pkcs11Libraries[entry.Config["library"]]; !ok {
+			return fmt.Errorf("unknown PKCS#11 library %q, which must be configured with a kms_library stanza", entry.Config["library"])
+		}
+	}
+
+	existing, err := c.readManagedKeyByName(ctx, ns, entry.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Type != entry.Type {
+		return fmt.Errorf("a managed key named %q already exists with type %q", entry.Name, existing.Type)
+	}
+
+	view, err := c.managedKeyView(ns)
+	if err != nil {
+		return err
+	}
+
+	if entry.UUID == "" {
+		entry.UUID, err = uuid.GenerateUUID()
+		if err != nil {
+			return err
+		}
+		if err := view.Put(ctx, &logical.StorageEntry{
+			Key:   managedKeysUUIDPrefix + entry.UUID,
+			Value: []byte(entry.Type + "/" + entry.Name),
+		}); err != nil {
+			return fmt.Errorf("failed to persist managed key index: %w", err)
+		}
+	}
+
+	storageEntry, err := logical.StorageEntryJSON(entry.Type+"/"+entry.Name, entry)
+	if err != nil {
+		return err
+	}
+	if err := view.Put(ctx, storageEntry); err != nil {
+		return fmt.Errorf("failed to persist managed key: %w", err)
+	}
+	return nil
+}
+
+// deleteManagedKey removes the managed key from storage. The key is left
+// untouched in the KMS. A key which is still allowed on a mount cannot be
+// deleted, as the mount may depend on it, such as for a PKI issuer.
+func (c *Core) deleteManagedKey(ctx context.Context, ns *namespace.Namespace, entry *managedKeyEntry) error {
+	if paths := c.mountsAllowingManagedKey(ns, entry); len(paths) > 0 {
+		return fmt.Errorf("managed key %q is still allowed on mounts: %s", entry.Name, strings.Join(paths, ", "))
+	}
+
+	view, err := c.managedKeyView(ns)
+	if err != nil {
+		return err
+	}
+	if err := view.Delete(ctx, entry.Type+"/"+entry.Name); err != nil {
+		return fmt.Errorf("failed to delete managed key: %w", err)
+	}
+	if err := view.Delete(ctx, managedKeysUUIDPrefix+entry.UUID); err != nil {
+		return fmt.Errorf("failed to delete managed key index: %w", err)
+	}
+	return nil
+}
+
+// mountsAllowingManagedKey returns the paths of the mounts in the namespace
+// listing the managed key in their allowed_managed_keys.
+func (c *Core) mountsAllowingManagedKey(ns *namespace.Namespace, entry *managedKeyEntry) []string {
+	c.mountsLock.RLock()
+	defer c.mountsLock.RUnlock()
+
+	var paths []string
+	if c.mounts == nil {
+		return paths
+	}
+	for _, mount := range c.mounts.Entries {
+		if mount.NamespaceID != ns.ID {
+			continue
+		}
+		if slices.Contains(mount.Config.AllowedManagedKeys, entry.Name) || slices.Contains(mount.Config.AllowedManagedKeys, entry.UUID) {
+			paths = append(paths, mount.Path)
+		}
+	}
+	return paths
+}
+
+// instantiateManagedKey returns a usable instance of the managed key. The
+// caller must close the key once done.
+func (c *Core) instantiateManagedKey(ctx context.Context, entry *managedKeyEntry) (managedKey, error) {
+	switch entry.Type {
+	case managedKeyTypePKCS11:
+		library, ok := c.pkcs11Libraries[entry.Config["library"]]
+		if !ok {
+			return nil, fmt.Errorf("PKCS#11 library %q is not configured on this node", entry.Config["library"])
+		}
+		return newPKCS11ManagedKey(library, entry)
+	case managedKeyTypeTransit:
+		return newTransitManagedKey(ctx, c, entry)
+	default:
+		return nil, fmt.Errorf("unknown managed key type %q", entry.Type)
+	}
+}
+
+// withManagedKey resolves the managed key referenced by name or UUID for
+// the mount of the system view and passes an instance of it to f. The key
+// must be allowed on the mount through allowed_managed_keys, unless it is
+// configured to be usable by any mount.
+func (d dynamicSystemView) withManagedKey(ctx context.Context, ref string, byUUID bool, backendUUID string, f func(context.Context, managedKey) error) error {
+	if d.mountEntry == nil {
+		return errors.New("managed keys are only available to mounted backends")
+	}
+	if d.mountEntry.BackendAwareUUID != backendUUID {
+		return fmt.Errorf("backend %q does not match the mount", backendUUID)
+	}
+
+	ns := d.mountEntry.Namespace()
+	ctx = namespace.ContextWithNamespace(ctx, ns)
+
+	var entry *managedKeyEntry
+	var err error
+	if byUUID {
+		entry, err = d.core.readManagedKeyByUUID(ctx, ns, ref)
+	} else {
+		entry, err = d.core.readManagedKeyByName(ctx, ns, ref)
+	}
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("managed key %q not found", ref)
+	}
+
+	if !entry.AnyMount {
+		var allowed []string
+		if raw, ok := d.mountEntry.synthesizedConfigCache.Load("allowed_managed_keys"); ok {
+			allowed = raw.([]string)
+		}
+		if !slices.Contains(allowed, entry.Name) && !slices.Contains(allowed, entry.UUID) {
+			return fmt.Errorf("managed key %q is not allowed on mount %q", entry.Name, d.mountEntry.Path)
+		}
+	}
+
+	key, err := d.core.instantiateManagedKey(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate managed key %q: %w", entry.Name, err)
+	}
+	defer func() {
+		if err := key.close(); err != nil {
+			d.core.logger.Warn("failed to release managed key", "name", entry.Name, "error", err)
+		}
+	}()
+
+	return f(ctx, key)
+}
+
+func (d dynamicSystemView) WithManagedKeyByName(ctx context.Context, keyName, backendUUID string, f logical.ManagedKeyConsumer) error {
+	return d.withManagedKey(ctx, keyName, false, backendUUID, func(ctx context.Context, key managedKey) error {
+		return f(ctx, key)
+	})
+}
+
+func (d dynamicSystemView) WithManagedKeyByUUID(ctx context.Context, keyUuid, backendUUID string, f logical.ManagedKeyConsumer) error {
+	return d.withManagedKey(ctx, keyUuid, true, backendUUID, func(ctx context.Context, key managedKey) error {
+		return f(ctx, key)
+	})
+}
+
+func (d dynamicSystemView) WithManagedSigningKeyByName(ctx context.Context, keyName, backendUUID string, f logical.ManagedSigningKeyConsumer) error {
+	return d.withManagedKey(ctx, keyName, false, backendUUID, func(ctx context.Context, key managedKey) error {
+		return f(ctx, key)
+	})
+}
+
+func (d dynamicSystemView) WithManagedSigningKeyByUUID(ctx context.Context, keyUuid, backendUUID string, f logical.ManagedSigningKeyConsumer) error {
+	return d.withManagedKey(ctx, keyUuid, true, backendUUID, func(ctx context.Context, key managedKey) error {
+		return f(ctx, key)
+	})
+}
+
+func (d dynamicSystemView) WithManagedEncryptingKeyByName(ctx context.Context, keyName, backendUUID string, f logical.ManagedEncryptingKeyConsumer) error {
+	return d.withManagedKey(ctx, keyName, false, backendUUID, func(ctx context.Context, key managedKey) error {
+		encryptingKey, ok := key.(logical.ManagedEncryptingKey)
+		if !ok {
+			return fmt.Errorf("managed key %q does not support encryption", keyName)
+		}
+		return f(ctx, encryptingKey)
+	})
+}
+
+func (d dynamicSystemView) WithManagedEncryptingKeyByUUID(ctx context.Context, keyUuid, backendUUID string, f logical.ManagedEncryptingKeyConsumer) error {
+	return d.withManagedKey(ctx, keyUuid, true, backendUUID, func(ctx context.Context, key managedKey) error {
+		encryptingKey, ok := key.(logical.ManagedEncryptingKey)
+		if !ok {
+			return fmt.Errorf("managed key %q does not support encryption", key.Name())
+		}
+		return f(ctx, encryptingKey)
+	})
+}
+
+func (d dynamicSystemView) WithManagedMACKeyByName(ctx context.Context, keyName, backendUUID string, f logical.ManagedMACKeyConsumer) error {
+	return d.withManagedKey(ctx, keyName, false, backendUUID, func(ctx context.Context, key managedKey) error {
+		macKey, ok := key.(logical.ManagedMACKey)
+		if !ok {
+			return fmt.Errorf("managed key %q does not support MAC generation", keyName)
+		}
+		return f(ctx, macKey)
+	})
+}
+
+func (d dynamicSystemView) WithManagedMACKeyByUUID(ctx context.Context, keyUUID, backendUUID string, f logical.ManagedMACKeyConsumer) error {
+	return d.withManagedKey(ctx, keyUUID, true, backendUUID, func(ctx context.Context, key managedKey) error {
+		macKey, ok := key.(logical.ManagedMACKey)
+		if !ok {
+			return fmt.Errorf("managed key %q does not support MAC generation", key.Name())
+		}
+		return f(ctx, macKey)
+	})
+}
+
+// verifyManagedKeySignature verifies a signature made by a managed key
+// against its public key.
+func verifyManagedKeySignature(publicKey crypto.PublicKey, signature, value []byte, opts crypto.SignerOpts) (bool, error) {
+	var hash crypto.Hash
+	if opts != nil {
+		hash = opts.HashFunc()
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		var err error
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			err = rsa.VerifyPSS(key, hash, value, signature, pss)
+		} else {
+			err = rsa.VerifyPKCS1v15(key, hash, value, signature)
+		}
+		return err == nil, nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, value, signature), nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, value, signature), nil
+	default:
+		return false, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
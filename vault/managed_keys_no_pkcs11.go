@@ -0,0 +1,12 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !hsm || !linux
+
+package vault
+
+import "errors"
+
+func newPKCS11ManagedKey(library string, entry *managedKeyEntry) (managedKey, error) {
+	return nil, errors.New("this build of OpenBao has PKCS#11 disabled")
+}
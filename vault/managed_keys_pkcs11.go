@@ -0,0 +1,580 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build hsm && linux
+
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+var (
+	// pkcs11Modules caches the initialized PKCS#11 libraries by path. A
+	// library may only be initialized once per process, so modules are
+	// shared by all keys and never finalized.
+	pkcs11Modules     = make(map[string]*pkcs11.Ctx)
+	pkcs11ModulesLock sync.Mutex
+
+	// pkcs11SessionPools caches the pools of sessions opened on each token,
+	// so that uses of managed keys do not open and log into a new session
+	// every time.
+	pkcs11SessionPools     = make(map[pkcs11SessionPoolKey]*pkcs11SessionPool)
+	pkcs11SessionPoolsLock sync.Mutex
+)
+
+// pkcs11MaxIdleSessions is the number of idle sessions kept open per token.
+// Sessions in use beyond this are closed once released.
+const pkcs11MaxIdleSessions = 8
+
+// pkcs11DigestInfoPrefixes are the DER encoded DigestInfo prefixes prepended
+// to digests for PKCS#1 v1.5 signatures, as CKM_RSA_PKCS signs raw data.
+var pkcs11DigestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA224: {0x30, 0x2d, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x04, 0x05, 0x00, 0x04, 0x1c},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// pkcs11PSSHashes maps hash functions to the hash and MGF mechanisms of
+// CKM_RSA_PKCS_PSS parameters.
+var pkcs11PSSHashes = map[crypto.Hash][2]uint{
+	crypto.SHA1:   {pkcs11.CKM_SHA_1, pkcs11.CKG_MGF1_SHA1},
+	crypto.SHA224: {pkcs11.CKM_SHA224, pkcs11.CKG_MGF1_SHA224},
+	crypto.SHA256: {pkcs11.CKM_SHA256, pkcs11.CKG_MGF1_SHA256},
+	crypto.SHA384: {pkcs11.CKM_SHA384, pkcs11.CKG_MGF1_SHA384},
+	crypto.SHA512: {pkcs11.CKM_SHA512, pkcs11.CKG_MGF1_SHA512},
+}
+
+// pkcs11Curves maps the named curves supported for EC keys to the DER
+// encoded OIDs used as CKA_EC_PARAMS.
+var pkcs11Curves = map[string]asn1.ObjectIdentifier{
+	elliptic.P224().Params().Name: {1, 3, 132, 0, 33},
+	elliptic.P256().Params().Name: {1, 2, 840, 10045, 3, 1, 7},
+	elliptic.P384().Params().Name: {1, 3, 132, 0, 34},
+	elliptic.P521().Params().Name: {1, 3, 132, 0, 35},
+}
+
+// pkcs11ManagedKey is a managed key held by a PKCS#11 token. Each instance
+// borrows a session from the pool of the token, returned once the consumer
+// returns.
+type pkcs11ManagedKey struct {
+	managedKeyBase
+	pool    *pkcs11SessionPool
+	module  *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	label   string
+	id      []byte
+}
+
+var _ managedKey = (*pkcs11ManagedKey)(nil)
+
+// pkcs11Module returns the initialized PKCS#11 library at the given path,
+// which must come from a kms_library stanza of the server configuration.
+func pkcs11Module(library string) (*pkcs11.Ctx, error) {
+	pkcs11ModulesLock.Lock()
+	defer pkcs11ModulesLock.Unlock()
+
+	if module, ok := pkcs11Modules[library]; ok {
+		return module, nil
+	}
+
+	module := pkcs11.New(library)
+	if module == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 library %q", library)
+	}
+	if err := module.Initialize(); err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		module.Destroy()
+		return nil, fmt.Errorf("failed to initialize PKCS#11 library %q: %w", library, err)
+	}
+
+	pkcs11Modules[library] = module
+	return module, nil
+}
+
+// pkcs11SessionPoolKey identifies the token and user of a session pool.
+type pkcs11SessionPoolKey struct {
+	library    string
+	slot       string
+	tokenLabel string
+	pin        string
+}
+
+// pkcs11SessionPool holds the idle sessions logged into a token.
+type pkcs11SessionPool struct {
+	module *pkcs11.Ctx
+	slot   uint
+	pin    string
+
+	lock sync.Mutex
+	idle []pkcs11.SessionHandle
+}
+
+// pkcs11GetSessionPool returns the session pool of the token configured by
+// the managed key in the given library.
+func pkcs11GetSessionPool(library string, config map[string]string) (*pkcs11SessionPool, error) {
+	key := pkcs11SessionPoolKey{
+		library:    library,
+		slot:       config["slot"],
+		tokenLabel: config["token_label"],
+		pin:        config["pin"],
+	}
+
+	pkcs11SessionPoolsLock.Lock()
+	defer pkcs11SessionPoolsLock.Unlock()
+
+	if pool, ok := pkcs11SessionPools[key]; ok {
+		return pool, nil
+	}
+
+	module, err := pkcs11Module(library)
+	if err != nil {
+		return nil, err
+	}
+	slot, err := pkcs11FindSlot(module, config)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := &pkcs11SessionPool{
+		module: module,
+		slot:   slot,
+		pin:    config["pin"],
+	}
+	pkcs11SessionPools[key] = pool
+	return pool, nil
+}
+
+// acquire returns an idle session of the pool, or opens and logs into a new
+// session when there is none.
+func (p *pkcs11SessionPool) acquire() (pkcs11.SessionHandle, error) {
+	p.lock.Lock()
+	if n := len(p.idle); n > 0 {
+		session := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.lock.Unlock()
+		return session, nil
+	}
+	p.lock.Unlock()
+
+	session, err := p.module.OpenSession(p.slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open PKCS#11 session: %w", err)
+	}
+	if err := p.module.Login(session, pkcs11.CKU_USER, p.pin); err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+		p.module.CloseSession(session)
+		return 0, fmt.Errorf("failed to log into PKCS#11 token: %w", err)
+	}
+	return session, nil
+}
+
+// release returns the session to the pool. Sessions which are no longer
+// logged in, such as after the token was removed, are closed instead.
+func (p *pkcs11SessionPool) release(session pkcs11.SessionHandle) error {
+	info, err := p.module.GetSessionInfo(session)
+	if err != nil || info.State != pkcs11.CKS_RW_USER_FUNCTIONS {
+		return p.module.CloseSession(session)
+	}
+
+	p.lock.Lock()
+	if len(p.idle) < pkcs11MaxIdleSessions {
+		p.idle = append(p.idle, session)
+		p.lock.Unlock()
+		return nil
+	}
+	p.lock.Unlock()
+
+	return p.module.CloseSession(session)
+}
+
+func newPKCS11ManagedKey(library string, entry *managedKeyEntry) (managedKey, error) {
+	var id []byte
+	if raw := entry.Config["key_id"]; raw != "" {
+		var err error
+		id, err = hex.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key_id: %w", err)
+		}
+	}
+
+	pool, err := pkcs11GetSessionPool(library, entry.Config)
+	if err != nil {
+		return nil, err
+	}
+	session, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pkcs11ManagedKey{
+		managedKeyBase: managedKeyBase{entry: entry},
+		pool:           pool,
+		module:         pool.module,
+		session:        session,
+		label:          entry.Config["key_label"],
+		id:             id,
+	}, nil
+}
+
+// pkcs11FindSlot returns the slot configured by number or token label.
+func pkcs11FindSlot(module *pkcs11.Ctx, config map[string]string) (uint, error) {
+	if raw := config["slot"]; raw != "" {
+		slot, err := strconv.ParseUint(raw, 10, 0)
+		if err != nil {
+			return 0, fmt.Errorf("invalid slot: %w", err)
+		}
+		return uint(slot), nil
+	}
+
+	slots, err := module.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list PKCS#11 slots: %w", err)
+	}
+	for _, slot := range slots {
+		info, err := module.GetTokenInfo(slot)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read PKCS#11 token information: %w", err)
+		}
+		if strings.TrimSpace(info.Label) == config["token_label"] {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("no PKCS#11 token labeled %q", config["token_label"])
+}
+
+// findObject returns the object of the given class matching the configured
+// label and ID of the key, if any.
+func (k *pkcs11ManagedKey) findObject(class uint) (pkcs11.ObjectHandle, bool, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+	}
+	if k.label != "" {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, k.label))
+	}
+	if len(k.id) > 0 {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_ID, k.id))
+	}
+
+	if err := k.module.FindObjectsInit(k.session, template); err != nil {
+		return 0, false, fmt.Errorf("failed to search PKCS#11 objects: %w", err)
+	}
+	objects, _, err := k.module.FindObjects(k.session, 2)
+	if finalErr := k.module.FindObjectsFinal(k.session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to search PKCS#11 objects: %w", err)
+	}
+
+	switch len(objects) {
+	case 0:
+		return 0, false, nil
+	case 1:
+		return objects[0], true, nil
+	default:
+		return 0, false, fmt.Errorf("multiple PKCS#11 objects match managed key %q", k.entry.Name)
+	}
+}
+
+// privateKey returns the handle of the private key on the token.
+func (k *pkcs11ManagedKey) privateKey() (pkcs11.ObjectHandle, error) {
+	object, found, err := k.findObject(pkcs11.CKO_PRIVATE_KEY)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("private key of managed key %q not found on the PKCS#11 token", k.entry.Name)
+	}
+	return object, nil
+}
+
+func (k *pkcs11ManagedKey) Present(ctx context.Context) (bool, error) {
+	_, found, err := k.findObject(pkcs11.CKO_PRIVATE_KEY)
+	return found, err
+}
+
+func (k *pkcs11ManagedKey) GenerateKey(ctx context.Context) (string, error) {
+	if err := k.checkGenerateKey(); err != nil {
+		return "", err
+	}
+
+	ref := k.label
+	if len(k.id) > 0 {
+		ref = hex.EncodeToString(k.id)
+	}
+
+	present, err := k.Present(ctx)
+	if err != nil {
+		return "", err
+	}
+	if present {
+		return ref, nil
+	}
+
+	public := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+	}
+	private := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+	}
+	if k.label != "" {
+		public = append(public, pkcs11.NewAttribute(pkcs11.CKA_LABEL, k.label))
+		private = append(private, pkcs11.NewAttribute(pkcs11.CKA_LABEL, k.label))
+	}
+	if len(k.id) > 0 {
+		public = append(public, pkcs11.NewAttribute(pkcs11.CKA_ID, k.id))
+		private = append(private, pkcs11.NewAttribute(pkcs11.CKA_ID, k.id))
+	}
+
+	var mechanism uint
+	switch k.entry.Config["key_type"] {
+	case "ec":
+		curve := k.entry.Config["curve"]
+		if curve == "" {
+			curve = elliptic.P256().Params().Name
+		}
+		params, err := asn1.Marshal(pkcs11Curves[curve])
+		if err != nil {
+			return "", err
+		}
+		mechanism = pkcs11.CKM_EC_KEY_PAIR_GEN
+		public = append(public, pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, params))
+	default:
+		bits := 2048
+		if raw := k.entry.Config["key_bits"]; raw != "" {
+			bits, err = strconv.Atoi(raw)
+			if err != nil {
+				return "", fmt.Errorf("invalid key_bits: %w", err)
+			}
+		}
+		mechanism = pkcs11.CKM_RSA_PKCS_KEY_PAIR_GEN
+		public = append(public,
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS_BITS, bits),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, []byte{1, 0, 1}),
+		)
+	}
+
+	if _, _, err := k.module.GenerateKeyPair(k.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, public, private); err != nil {
+		return "", fmt.Errorf("failed to generate key pair on the PKCS#11 token: %w", err)
+	}
+	return ref, nil
+}
+
+func (k *pkcs11ManagedKey) GetPublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	// Prefer the public key object, but fall back to the private key, which
+	// also carries the public components of RSA keys.
+	object, found, err := k.findObject(pkcs11.CKO_PUBLIC_KEY)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if object, err = k.privateKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	attrs, err := k.module.GetAttributeValue(k.session, object, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PKCS#11 key type: %w", err)
+	}
+
+	switch bytesToUint(attrs[0].Value) {
+	case pkcs11.CKK_RSA:
+		attrs, err := k.module.GetAttributeValue(k.session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RSA public key: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attrs[0].Value),
+			E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+		}, nil
+	case pkcs11.CKK_EC:
+		if !found {
+			return nil, fmt.Errorf("public key of managed key %q not found on the PKCS#11 token", k.entry.Name)
+		}
+		attrs, err := k.module.GetAttributeValue(k.session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read EC public key: %w", err)
+		}
+		return parsePKCS11ECPublicKey(attrs[0].Value, attrs[1].Value)
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#11 key type for managed key %q", k.entry.Name)
+	}
+}
+
+func (k *pkcs11ManagedKey) Sign(ctx context.Context, value []byte, _ io.Reader, opts crypto.SignerOpts) ([]byte, error) {
+	if err := k.checkUsage(logical.KeyUsageSign); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := k.GetPublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	object, err := k.privateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var hash crypto.Hash
+	if opts != nil {
+		hash = opts.HashFunc()
+	}
+
+	var mechanism *pkcs11.Mechanism
+	input := value
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			params, ok := pkcs11PSSHashes[hash]
+			if !ok {
+				return nil, fmt.Errorf("unsupported hash function %v for RSA-PSS", hash)
+			}
+			// Salt lengths chosen automatically by Go are as large as
+			// possible, which not all tokens support; the length of the
+			// hash is verifiable with any setting.
+			saltLength := pss.SaltLength
+			if saltLength == rsa.PSSSaltLengthAuto || saltLength == rsa.PSSSaltLengthEqualsHash {
+				saltLength = hash.Size()
+			}
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_PSS, pkcs11.NewPSSParams(params[0], params[1], uint(saltLength)))
+		} else {
+			if hash != 0 {
+				prefix, ok := pkcs11DigestInfoPrefixes[hash]
+				if !ok {
+					return nil, fmt.Errorf("unsupported hash function %v for RSA PKCS#1 v1.5", hash)
+				}
+				input = append(append([]byte{}, prefix...), value...)
+			}
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)
+		}
+	case *ecdsa.PublicKey:
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	if err := k.module.SignInit(k.session, []*pkcs11.Mechanism{mechanism}, object); err != nil {
+		return nil, fmt.Errorf("failed to initialize PKCS#11 signature: %w", err)
+	}
+	signature, err := k.module.Sign(k.session, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with PKCS#11 token: %w", err)
+	}
+
+	if _, ok := publicKey.(*ecdsa.PublicKey); ok {
+		// Tokens return the raw concatenation of r and s
+		half := len(signature) / 2
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			R: new(big.Int).SetBytes(signature[:half]),
+			S: new(big.Int).SetBytes(signature[half:]),
+		})
+	}
+	return signature, nil
+}
+
+func (k *pkcs11ManagedKey) Verify(ctx context.Context, signature, value []byte, opts crypto.SignerOpts) (bool, error) {
+	if err := k.checkUsage(logical.KeyUsageVerify); err != nil {
+		return false, err
+	}
+
+	publicKey, err := k.GetPublicKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	return verifyManagedKeySignature(publicKey, signature, value, opts)
+}
+
+func (k *pkcs11ManagedKey) GetSigner(ctx context.Context) (crypto.Signer, error) {
+	return newManagedKeySigner(ctx, k)
+}
+
+func (k *pkcs11ManagedKey) close() error {
+	return k.pool.release(k.session)
+}
+
+// parsePKCS11ECPublicKey parses the CKA_EC_PARAMS and CKA_EC_POINT
+// attributes of an EC public key.
+func parsePKCS11ECPublicKey(params, point []byte) (*ecdsa.PublicKey, error) {
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(params, &oid); err != nil {
+		return nil, fmt.Errorf("failed to parse EC parameters: %w", err)
+	}
+
+	var curve elliptic.Curve
+	for name, curveOID := range pkcs11Curves {
+		if oid.Equal(curveOID) {
+			curve = curveByName(name)
+		}
+	}
+	if curve == nil {
+		return nil, fmt.Errorf("unsupported EC curve %v", oid)
+	}
+
+	// The point should be wrapped in a DER octet string, but some tokens
+	// return it raw.
+	var raw []byte
+	if rest, err := asn1.Unmarshal(point, &raw); err != nil || len(rest) > 0 {
+		raw = point
+	}
+
+	x, y := elliptic.Unmarshal(curve, raw)
+	if x == nil {
+		return nil, errors.New("failed to parse EC point")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func curveByName(name string) elliptic.Curve {
+	for _, curve := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		if curve.Params().Name == name {
+			return curve
+		}
+	}
+	return nil
+}
+
+// bytesToUint decodes an attribute holding a CK_ULONG, which tokens return
+// in native byte order.
+func bytesToUint(b []byte) uint {
+	switch len(b) {
+	case 4:
+		return uint(binary.NativeEndian.Uint32(b))
+	case 8:
+		return uint(binary.NativeEndian.Uint64(b))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	wrapping "github.com/openbao/go-kms-wrapping/v2"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+// transitManagedKeyConfig lists the configuration parameters of managed keys
+// backed by a transit mount.
+var transitManagedKeyConfig = []string{"mount_path", "key_name", "key_type"}
+
+// transitHashAlgorithms maps hash functions to the hash_algorithm parameter
+// of transit signing requests.
+var transitHashAlgorithms = map[crypto.Hash]string{
+	crypto.SHA1:     "sha1",
+	crypto.SHA224:   "sha2-224",
+	crypto.SHA256:   "sha2-256",
+	crypto.SHA384:   "sha2-384",
+	crypto.SHA512:   "sha2-512",
+	crypto.SHA3_224: "sha3-224",
+	crypto.SHA3_256: "sha3-256",
+	crypto.SHA3_384: "sha3-384",
+	crypto.SHA3_512: "sha3-512",
+}
+
+// transitManagedKey is a managed key held by a transit mount of the same
+// namespace. Requests are routed to the mount internally, so the key never
+// leaves the transit barrier.
+type transitManagedKey struct {
+	managedKeyBase
+	core      *Core
+	mountPath string
+	keyName   string
+	keyType   string
+}
+
+var (
+	_ managedKey                   = (*transitManagedKey)(nil)
+	_ logical.ManagedEncryptingKey = (*transitManagedKey)(nil)
+	_ logical.ManagedMACKey        = (*transitManagedKey)(nil)
+)
+
+func validateTransitManagedKeyConfig(config map[string]string) error {
+	for name := range config {
+		if !slices.Contains(transitManagedKeyConfig, name) {
+			return fmt.Errorf("unknown configuration parameter %q for transit managed keys", name)
+		}
+	}
+	if strings.Trim(config["mount_path"], "/") == "" {
+		return errors.New("missing mount_path")
+	}
+	if config["key_name"] == "" {
+		return errors.New("missing key_name")
+	}
+	return nil
+}
+
+func newTransitManagedKey(ctx context.Context, c *Core, entry *managedKeyEntry) (managedKey, error) {
+	mountPath := strings.Trim(entry.Config["mount_path"], "/") + "/"
+	mount := c.router.MatchingMountEntry(ctx, mountPath)
+	if mount == nil {
+		return nil, fmt.Errorf("no mount found at %q", mountPath)
+	}
+	if mount.Type != "transit" {
+		return nil, fmt.Errorf("mount %q is not a transit mount", mountPath)
+	}
+
+	keyType := entry.Config["key_type"]
+	if keyType == "" {
+		keyType = "rsa-2048"
+	}
+
+	return &transitManagedKey{
+		managedKeyBase: managedKeyBase{entry: entry},
+		core:           c,
+		mountPath:      mountPath,
+		keyName:        entry.Config["key_name"],
+		keyType:        keyType,
+	}, nil
+}
+
+// request routes a request to the transit mount backing the key.
+func (k *transitManagedKey) request(ctx context.Context, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+	resp, err := k.core.router.Route(ctx, &logical.Request{
+		Operation: op,
+		Path:      k.mountPath + path,
+		Data:      data,
+	})
+	if resp != nil && resp.IsError() {
+		return nil, resp.Error()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *transitManagedKey) Present(ctx context.Context) (bool, error) {
+	resp, err := k.request(ctx, logical.ReadOperation, "keys/"+k.keyName, nil)
+	if err != nil {
+		return false, err
+	}
+	return resp != nil, nil
+}
+
+func (k *transitManagedKey) GenerateKey(ctx context.Context) (string, error) {
+	if err := k.checkGenerateKey(); err != nil {
+		return "", err
+	}
+
+	present, err := k.Present(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !present {
+		_, err := k.request(ctx, logical.UpdateOperation, "keys/"+k.keyName, map[string]interface{}{
+			"type": k.keyType,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to generate transit key: %w", err)
+		}
+	}
+	return k.keyName, nil
+}
+
+func (k *transitManagedKey) GetPublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	resp, err := k.request(ctx, logical.ReadOperation, "keys/"+k.keyName, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("transit key %q does not exist", k.keyName)
+	}
+
+	latest, ok := resp.Data["latest_version"].(int)
+	if !ok {
+		return nil, errors.New("transit key has no latest version")
+	}
+	versions, ok := resp.Data["keys"].(map[string]map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("transit key %q is not an asymmetric key", k.keyName)
+	}
+	encoded, _ := versions[strconv.Itoa(latest)]["public_key"].(string)
+	if encoded == "" {
+		return nil, fmt.Errorf("transit key %q has no public key", k.keyName)
+	}
+
+	if resp.Data["type"] == "ed25519" {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode public key: %w", err)
+		}
+		return ed25519.PublicKey(raw), nil
+	}
+
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("failed to decode public key PEM")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func (k *transitManagedKey) Sign(ctx context.Context, value []byte, _ io.Reader, opts crypto.SignerOpts) ([]byte, error) {
+	if err := k.checkUsage(logical.KeyUsageSign); err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(value),
+		"marshaling_algorithm": "asn1",
+		"signature_algorithm":  "pkcs1v15",
+	}
+	if opts != nil && opts.HashFunc() != 0 {
+		algorithm, ok := transitHashAlgorithms[opts.HashFunc()]
+		if !ok {
+			return nil, fmt.Errorf("unsupported hash function %v", opts.HashFunc())
+		}
+		data["prehashed"] = true
+		data["hash_algorithm"] = algorithm
+	}
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		data["signature_algorithm"] = "pss"
+		switch pss.SaltLength {
+		case rsa.PSSSaltLengthAuto:
+			data["salt_length"] = "auto"
+		case rsa.PSSSaltLengthEqualsHash:
+			data["salt_length"] = "hash"
+		default:
+			data["salt_length"] = strconv.Itoa(pss.SaltLength)
+		}
+	}
+
+	resp, err := k.request(ctx, logical.UpdateOperation, "sign/"+k.keyName, data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTransitValue(resp, "signature")
+}
+
+func (k *transitManagedKey) Verify(ctx context.Context, signature, value []byte, opts crypto.SignerOpts) (bool, error) {
+	if err := k.checkUsage(logical.KeyUsageVerify); err != nil {
+		return false, err
+	}
+
+	publicKey, err := k.GetPublicKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	return verifyManagedKeySignature(publicKey, signature, value, opts)
+}
+
+func (k *transitManagedKey) GetSigner(ctx context.Context) (crypto.Signer, error) {
+	return newManagedKeySigner(ctx, k)
+}
+
+func (k *transitManagedKey) Encrypt(ctx context.Context, plaintext []byte, options ...wrapping.Option) ([]byte, error) {
+	if err := k.checkUsage(logical.KeyUsageEncrypt); err != nil {
+		return nil, err
+	}
+
+	opts, err := wrapping.GetOpts(options...)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	}
+	if len(opts.WithAad) > 0 {
+		data["associated_data"] = base64.StdEncoding.EncodeToString(opts.WithAad)
+	}
+
+	resp, err := k.request(ctx, logical.UpdateOperation, "encrypt/"+k.keyName, data)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, _ := resp.Data["ciphertext"].(string)
+	if ciphertext == "" {
+		return nil, errors.New("transit returned no ciphertext")
+	}
+	return []byte(ciphertext), nil
+}
+
+func (k *transitManagedKey) Decrypt(ctx context.Context, ciphertext []byte, options ...wrapping.Option) ([]byte, error) {
+	if err := k.checkUsage(logical.KeyUsageDecrypt); err != nil {
+		return nil, err
+	}
+
+	opts, err := wrapping.GetOpts(options...)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"ciphertext": string(ciphertext),
+	}
+	if len(opts.WithAad) > 0 {
+		data["associated_data"] = base64.StdEncoding.EncodeToString(opts.WithAad)
+	}
+
+	resp, err := k.request(ctx, logical.UpdateOperation, "decrypt/"+k.keyName, data)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, _ := resp.Data["plaintext"].(string)
+	return base64.StdEncoding.DecodeString(plaintext)
+}
+
+func (k *transitManagedKey) MAC(ctx context.Context, algorithm string, data []byte) ([]byte, error) {
+	if err := k.checkUsage(logical.KeyUsageSign); err != nil {
+		return nil, err
+	}
+
+	resp, err := k.request(ctx, logical.UpdateOperation, "hmac/"+k.keyName, map[string]interface{}{
+		"input":     base64.StdEncoding.EncodeToString(data),
+		"algorithm": algorithm,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeTransitValue(resp, "hmac")
+}
+
+func (k *transitManagedKey) close() error {
+	return nil
+}
+
+// decodeTransitValue decodes a value of the form vault:v<version>:<base64>
+// returned by transit.
+func decodeTransitValue(resp *logical.Response, field string) ([]byte, error) {
+	if resp == nil {
+		return nil, fmt.Errorf("transit returned no %s", field)
+	}
+	value, _ := resp.Data[field].(string)
+	idx := strings.LastIndex(value, ":")
+	if idx == -1 {
+		return nil, fmt.Errorf("transit returned a malformed %s", field)
+	}
+	return base64.StdEncoding.DecodeString(value[idx+1:])
+}
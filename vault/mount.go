@@ -75,6 +75,7 @@ const (
 	mountTypeNSCubbyhole = "ns_cubbyhole"
 	mountTypeToken       = "token"
 	mountTypeNSToken     = "ns_token"
+	mountTypeNSIdentity  = "ns_identity"
 
 	MountTableUpdateStorage   = true
 	MountTableNoUpdateStorage = false
@@ -114,6 +115,10 @@ var (
 		mountTypeSystem,
 		mountTypeToken,
 		mountTypeIdentity,
+		mountTypeNSCubbyhole,
+		mountTypeNSSystem,
+		mountTypeNSToken,
+		mountTypeNSIdentity,
 	}
 
 	// mountAliases maps old backend names to new backend names, allowing us
@@ -660,6 +665,13 @@ func (c *Core) mountInternal(ctx context.Context, entry *MountEntry, updateStora
 		return logical.CodedError(409, fmt.Sprintf("existing mount at %s", match))
 	}
 
+	// Verify the mount would not be shadowed by a child namespace
+	if c.namespaceStore != nil {
+		if match := c.namespaceStore.MountConflict(ns, entry.Path); match != "" {
+			return logical.CodedError(409, fmt.Sprintf("existing namespace at %s", match))
+		}
+	}
+
 	// Generate a new UUID and view
 	if entry.UUID == "" {
 		entryUUID, err := uuid.GenerateUUID()
@@ -712,7 +724,8 @@ func (c *Core) mountInternal(ctx context.Context, entry *MountEntry, updateStora
 	// Check for the correct backend type
 	backendType := backend.Type()
 	if backendType != logical.TypeLogical {
-		if entry.Type != mountTypeKV && entry.Type != mountTypeSystem && entry.Type != mountTypeCubbyhole {
+		if entry.Type != mountTypeKV && entry.Type != mountTypeSystem && entry.Type != mountTypeCubbyhole &&
+			entry.Type != mountTypeNSSystem && entry.Type != mountTypeNSCubbyhole {
 			return fmt.Errorf(`unknown backend type: "%s"`, entry.Type)
 		}
 	}
@@ -1735,7 +1748,8 @@ func (c *Core) setupMounts(ctx context.Context) error {
 			backendType := backend.Type()
 
 			if backendType != logical.TypeLogical {
-				if entry.Type != mountTypeKV && entry.Type != mountTypeSystem && entry.Type != mountTypeCubbyhole {
+				if entry.Type != mountTypeKV && entry.Type != mountTypeSystem && entry.Type != mountTypeCubbyhole &&
+					entry.Type != mountTypeNSSystem && entry.Type != mountTypeNSCubbyhole {
 					return fmt.Errorf(`unknown backend type: "%s"`, entry.Type)
 				}
 			}
@@ -1759,7 +1773,11 @@ func (c *Core) setupMounts(ctx context.Context) error {
 				postUnsealLogger.Error("skipping initialization for nil backend", "path", localEntry.Path)
 				return
 			}
-			if !strutil.StrListContains(singletonMounts, localEntry.Type) {
+			switch {
+			case c.ReplicationState().HasState(consts.ReplicationPerformanceStandby):
+				// Standbys serving reads must leave writes to the active node
+				view.SetReadOnlyErr(logical.ErrReadOnly)
+			case !strutil.StrListContains(singletonMounts, localEntry.Type):
 				view.SetReadOnlyErr(origReadOnlyErr)
 			}
 
@@ -5,24 +5,33 @@ package vault
 
 import (
 	"path"
+
+	"github.com/openbao/openbao/helper/namespace"
 )
 
 // ViewPath returns storage prefix for the view
 func (e *MountEntry) ViewPath() string {
+	// Storage of mounts within a non-root namespace is nested under the
+	// namespace's own prefix.
+	var prefix string
+	if e.NamespaceID != "" && e.NamespaceID != namespace.RootNamespaceID {
+		prefix = namespaceBarrierPrefix + e.NamespaceID + "/"
+	}
+
 	switch e.Type {
-	case mountTypeSystem:
-		return systemBarrierPrefix
-	case "token":
-		return path.Join(systemBarrierPrefix, tokenSubPath) + "/"
+	case mountTypeSystem, mountTypeNSSystem:
+		return prefix + systemBarrierPrefix
+	case "token", mountTypeNSToken:
+		return prefix + path.Join(systemBarrierPrefix, tokenSubPath) + "/"
 	}
 
 	switch e.Table {
 	case mountTableType:
-		return backendBarrierPrefix + e.UUID + "/"
+		return prefix + backendBarrierPrefix + e.UUID + "/"
 	case credentialTableType:
-		return credentialBarrierPrefix + e.UUID + "/"
+		return prefix + credentialBarrierPrefix + e.UUID + "/"
 	case auditTableType:
-		return auditBarrierPrefix + e.UUID + "/"
+		return prefix + auditBarrierPrefix + e.UUID + "/"
 	}
 
 	panic("invalid mount entry")
@@ -0,0 +1,830 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/helper/versions"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	// namespaceStoreSubPath is the barrier path under which namespace
+	// entries are persisted, keyed by namespace ID.
+	namespaceStoreSubPath = "core/namespaces/"
+
+	// namespaceBarrierPrefix is the prefix under which all storage owned
+	// by a non-root namespace (its mounts, policies, tokens and leases)
+	// is located, keyed by namespace ID.
+	namespaceBarrierPrefix = "namespaces/"
+
+	// namespaceIDLength is the length of the randomly generated
+	// namespace identifiers.
+	namespaceIDLength = 5
+
+	// namespaceUnlockKeyLength is the length of the randomly generated
+	// keys required to unlock API access to a locked namespace.
+	namespaceUnlockKeyLength = 24
+)
+
+var (
+	// namespaceNameRegex restricts the characters usable in a single
+	// namespace path segment.
+	namespaceNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+	// reservedNamespaceNames cannot be used as a namespace path segment
+	// as they would shadow API paths within the parent namespace.
+	reservedNamespaceNames = []string{
+		".",
+		"..",
+		"root",
+		"sys",
+		"auth",
+		"audit",
+		"cubbyhole",
+		"identity",
+		"api-lock",
+	}
+
+	// rootNamespaceOnlyPaths lists the prefixes of system paths that
+	// manage server-wide state and so cannot be used from a namespace.
+	rootNamespaceOnlyPaths = []string{
+		"sys/audit",
+		"sys/config/",
+		"sys/generate-recovery-token",
+		"sys/generate-root",
+		"sys/host-info",
+		"sys/in-flight-req",
+		"sys/init",
+		"sys/internal/counters/",
+		"sys/key-status",
+		"sys/loggers",
+		"sys/metrics",
+		"sys/monitor",
+		"sys/plugins/",
+		"sys/pprof/",
+		"sys/quotas/",
+		"sys/raw",
+		"sys/rekey",
+		"sys/rotate",
+		"sys/seal",
+		"sys/step-down",
+		"sys/storage/",
+		"sys/unseal",
+	}
+
+	errNamespaceHasChildren   = errors.New("namespace has child namespaces")
+	errNamespaceLocked        = errors.New("API access to this namespace has been locked by an administrator")
+	errNamespaceNotLocked     = errors.New("namespace is not locked")
+	errNamespaceAlreadyLocked = errors.New("namespace is already locked")
+	errNamespaceInvalidKey    = errors.New("provided unlock key is incorrect")
+	errNamespaceLockRoot      = errors.New("the root namespace cannot be locked")
+	errNamespaceBeingCreated  = errors.New("namespace is being created")
+	errNamespaceBeingDeleted  = errors.New("namespace is being deleted")
+)
+
+// isRootNamespaceOnlyPath returns whether the given request path may only
+// be accessed from the root namespace.
+func isRootNamespaceOnlyPath(reqPath string) bool {
+	for _, prefix := range rootNamespaceOnlyPaths {
+		if strings.HasPrefix(reqPath, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NamespaceStore is used to provide durable storage of namespaces and to
+// resolve namespaces by ID or path.
+type NamespaceStore struct {
+	core *Core
+
+	// storage is a view on the namespace entries in the barrier.
+	storage BarrierView
+
+	// lock guards the caches below as well as modifications of the
+	// namespace entries in storage.
+	lock sync.RWMutex
+
+	// namespacesByID and namespacesByPath hold every non-root namespace,
+	// keyed by its ID and its canonical path respectively.
+	namespacesByID   map[string]*namespace.Namespace
+	namespacesByPath map[string]*namespace.Namespace
+
+	// pendingByPath holds namespaces which are persisted but whose
+	// default mounts and policies are still being set up. They can be
+	// resolved by ID, as mount setup requires, but not by path, so that
+	// no request is routed into them before they are complete.
+	pendingByPath map[string]*namespace.Namespace
+
+	// deletingByPath holds namespaces which are being torn down. They stay
+	// resolvable while their mounts are removed, but no child namespace may
+	// be created under them and they cannot be deleted twice.
+	deletingByPath map[string]*namespace.Namespace
+
+	logger log.Logger
+}
+
+// NewNamespaceStore creates a new NamespaceStore backed by the given
+// view and loads all persisted namespaces into memory.
+func NewNamespaceStore(ctx context.Context, core *Core, view BarrierView, logger log.Logger) (*NamespaceStore, error) {
+	ns := &NamespaceStore{
+		core:             core,
+		storage:          view,
+		namespacesByID:   make(map[string]*namespace.Namespace),
+		namespacesByPath: make(map[string]*namespace.Namespace),
+		pendingByPath:    make(map[string]*namespace.Namespace),
+		deletingByPath:   make(map[string]*namespace.Namespace),
+		logger:           logger,
+	}
+
+	if err := ns.loadNamespaces(ctx); err != nil {
+		return nil, err
+	}
+
+	return ns, nil
+}
+
+// setupNamespaceStore is used to initialize the namespace store when the
+// vault is being unsealed. It must run before the mount tables are loaded,
+// as mount entries are resolved against their namespace.
+func (c *Core) setupNamespaceStore(ctx context.Context) error {
+	nsLogger := c.baseLogger.Named("namespace")
+	c.AddLogger(nsLogger)
+
+	view := NewBarrierView(c.barrier, namespaceStoreSubPath)
+	store, err := NewNamespaceStore(ctx, c, view, nsLogger)
+	if err != nil {
+		return err
+	}
+
+	c.namespaceStore = store
+	return nil
+}
+
+// teardownNamespaceStore is used to reverse setupNamespaceStore when the
+// vault is being sealed.
+func (c *Core) teardownNamespaceStore() error {
+	c.namespaceStore = nil
+	return nil
+}
+
+// loadNamespaces reads all namespace entries from storage into the
+// in-memory caches.
+func (ns *NamespaceStore) loadNamespaces(ctx context.Context) error {
+	keys, err := logical.CollectKeys(ctx, ns.storage)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+
+	for _, key := range keys {
+		entry, err := ns.storage.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to read namespace %q: %w", key, err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		var item namespace.Namespace
+		if err := entry.DecodeJSON(&item); err != nil {
+			return fmt.Errorf("failed to decode namespace %q: %w", key, err)
+		}
+		if item.CustomMetadata == nil {
+			item.CustomMetadata = make(map[string]string)
+		}
+
+		ns.namespacesByID[item.ID] = &item
+		ns.namespacesByPath[item.Path] = &item
+	}
+
+	ns.logger.Debug("loaded namespaces", "count", len(ns.namespacesByID))
+	return nil
+}
+
+// GetNamespace returns the namespace with the given ID, or nil if no such
+// namespace exists.
+func (ns *NamespaceStore) GetNamespace(nsID string) *namespace.Namespace {
+	if nsID == namespace.RootNamespaceID {
+		return namespace.RootNamespace
+	}
+
+	ns.lock.RLock()
+	defer ns.lock.RUnlock()
+
+	return ns.namespacesByID[nsID]
+}
+
+// GetNamespaceByPath returns the namespace at the given absolute path, or
+// nil if no such namespace exists.
+func (ns *NamespaceStore) GetNamespaceByPath(nsPath string) *namespace.Namespace {
+	nsPath = namespace.Canonicalize(nsPath)
+	if nsPath == "" {
+		return namespace.RootNamespace
+	}
+
+	ns.lock.RLock()
+	defer ns.lock.RUnlock()
+
+	return ns.namespacesByPath[nsPath]
+}
+
+// ResolveNamespaceFromPath finds the deepest namespace whose path is a
+// prefix of the given absolute path. It returns that namespace together
+// with the remainder of the path, relative to the namespace.
+func (ns *NamespaceStore) ResolveNamespaceFromPath(reqPath string) (*namespace.Namespace, string) {
+	ns.lock.RLock()
+	defer ns.lock.RUnlock()
+
+	result := namespace.RootNamespace
+	for {
+		idx := strings.Index(reqPath[len(result.Path):], "/")
+		if idx == -1 {
+			break
+		}
+
+		child, ok := ns.namespacesByPath[reqPath[:len(result.Path)+idx+1]]
+		if !ok {
+			break
+		}
+		result = child
+	}
+
+	return result, strings.TrimPrefix(reqPath, result.Path)
+}
+
+// ResolveNamespaceFromRequest determines the namespace of an API request
+// from the namespace header and the request path, which may itself carry
+// further namespace segments. It returns the namespace and the path
+// relative to it; a nil namespace indicates the header refers to a
+// namespace which does not exist. Before the namespace store is set up,
+// all requests resolve to the root namespace.
+func (c *Core) ResolveNamespaceFromRequest(nsHeader, reqPath string) (*namespace.Namespace, string) {
+	store := c.namespaceStore
+	if store == nil {
+		return namespace.RootNamespace, reqPath
+	}
+
+	base := store.GetNamespaceByPath(nsHeader)
+	if base == nil {
+		return nil, ""
+	}
+
+	ns, relativePath := store.ResolveNamespaceFromPath(base.Path + reqPath)
+	return ns, relativePath
+}
+
+// ListNamespaces returns the descendants of the given parent namespace,
+// sorted by path. Unless recursive is set, only direct children are
+// returned.
+func (ns *NamespaceStore) ListNamespaces(parent *namespace.Namespace, recursive bool) []*namespace.Namespace {
+	ns.lock.RLock()
+	defer ns.lock.RUnlock()
+
+	return ns.listNamespacesLocked(parent, recursive)
+}
+
+func (ns *NamespaceStore) listNamespacesLocked(parent *namespace.Namespace, recursive bool) []*namespace.Namespace {
+	var result []*namespace.Namespace
+	for nsPath, item := range ns.namespacesByPath {
+		if nsPath == parent.Path || !strings.HasPrefix(nsPath, parent.Path) {
+			continue
+		}
+
+		relative := strings.TrimSuffix(strings.TrimPrefix(nsPath, parent.Path), "/")
+		if !recursive && strings.Contains(relative, "/") {
+			continue
+		}
+
+		result = append(result, item)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+
+	return result
+}
+
+// MountConflict returns the path of a namespace which would shadow a mount
+// at the given path within the parent namespace, if any.
+func (ns *NamespaceStore) MountConflict(parent *namespace.Namespace, mountPath string) string {
+	ns.lock.RLock()
+	defer ns.lock.RUnlock()
+
+	segment, _, _ := strings.Cut(mountPath, "/")
+	if _, ok := ns.namespacesByPath[parent.Path+segment+"/"]; ok {
+		return parent.Path + segment + "/"
+	}
+	if _, ok := ns.pendingByPath[parent.Path+segment+"/"]; ok {
+		return parent.Path + segment + "/"
+	}
+
+	return ""
+}
+
+// validateNamespacePath ensures every segment of a path relative to its
+// parent is a valid namespace name.
+func validateNamespacePath(relativePath string) error {
+	relativePath = strings.TrimSuffix(relativePath, "/")
+	if relativePath == "" {
+		return errors.New("namespace path must not be empty")
+	}
+
+	for _, segment := range strings.Split(relativePath, "/") {
+		if !namespaceNameRegex.MatchString(segment) {
+			return fmt.Errorf("invalid namespace name %q", segment)
+		}
+		for _, reserved := range reservedNamespaceNames {
+			if strings.EqualFold(segment, reserved) {
+				return fmt.Errorf("namespace name %q is reserved", segment)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CreateNamespace creates a namespace at the given path relative to the
+// namespace in the context, and sets up its default mounts and policies.
+// If the namespace already exists, it is returned unmodified.
+func (ns *NamespaceStore) CreateNamespace(ctx context.Context, relativePath string, customMetadata map[string]string) (*namespace.Namespace, bool, error) {
+	parent, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := validateNamespacePath(relativePath); err != nil {
+		return nil, false, err
+	}
+
+	nsPath := parent.Path + namespace.Canonicalize(relativePath)
+
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+
+	if _, ok := ns.deletingByPath[nsPath]; ok {
+		return nil, false, errNamespaceBeingDeleted
+	}
+	if existing, ok := ns.namespacesByPath[nsPath]; ok {
+		return existing, false, nil
+	}
+	if _, ok := ns.pendingByPath[nsPath]; ok {
+		return nil, false, errNamespaceBeingCreated
+	}
+
+	// The direct parent of the new namespace must already exist; parents
+	// are not created implicitly.
+	directParentPath := ""
+	if idx := strings.LastIndex(strings.TrimSuffix(nsPath, "/"), "/"); idx != -1 {
+		directParentPath = nsPath[:idx+1]
+	}
+	directParent := namespace.RootNamespace
+	if directParentPath != "" {
+		var ok bool
+		directParent, ok = ns.namespacesByPath[directParentPath]
+		if !ok {
+			return nil, false, fmt.Errorf("parent namespace %q does not exist", directParentPath)
+		}
+		if _, ok := ns.deletingByPath[directParentPath]; ok {
+			return nil, false, fmt.Errorf("parent namespace %q: %w", directParentPath, errNamespaceBeingDeleted)
+		}
+	}
+
+	// A namespace must not shadow an existing mount in its parent.
+	parentCtx := namespace.ContextWithNamespace(ctx, directParent)
+	if match := ns.core.router.MountConflict(parentCtx, strings.TrimPrefix(nsPath, directParent.Path)); match != "" {
+		return nil, false, fmt.Errorf("existing mount at %q", match)
+	}
+
+	id, err := ns.generateID()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if customMetadata == nil {
+		customMetadata = make(map[string]string)
+	}
+
+	entry := &namespace.Namespace{
+		ID:             id,
+		Path:           nsPath,
+		CustomMetadata: customMetadata,
+	}
+
+	if err := ns.writeNamespaceLocked(ctx, entry); err != nil {
+		return nil, false, err
+	}
+	ns.namespacesByID[entry.ID] = entry
+	ns.pendingByPath[entry.Path] = entry
+
+	// Mount setup resolves the namespace through the store, so release the
+	// lock while the namespace is being initialized. Until it is complete,
+	// the namespace stays pending and cannot be resolved by path.
+	nsCtx := namespace.ContextWithNamespace(ctx, entry)
+	ns.lock.Unlock()
+	err = ns.core.initializeNamespace(nsCtx)
+	if err != nil {
+		ns.logger.Error("failed to initialize namespace, removing it", "path", nsPath, "error", err)
+		if tdErr := ns.core.teardownNamespace(nsCtx); tdErr != nil {
+			ns.logger.Error("failed to tear down partially initialized namespace", "path", nsPath, "error", tdErr)
+		}
+	}
+	ns.lock.Lock()
+	delete(ns.pendingByPath, entry.Path)
+	if err != nil {
+		if delErr := ns.deleteNamespaceLocked(ctx, entry); delErr != nil {
+			ns.logger.Error("failed to remove partially initialized namespace", "path", nsPath, "error", delErr)
+		}
+		return nil, false, err
+	}
+	ns.namespacesByPath[entry.Path] = entry
+
+	return entry, true, nil
+}
+
+// PatchNamespace replaces the custom metadata of an existing namespace.
+func (ns *NamespaceStore) PatchNamespace(ctx context.Context, nsPath string, customMetadata map[string]string) (*namespace.Namespace, error) {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+
+	existing, ok := ns.namespacesByPath[namespace.Canonicalize(nsPath)]
+	if !ok {
+		return nil, nil
+	}
+
+	if customMetadata == nil {
+		customMetadata = make(map[string]string)
+	}
+
+	entry := &namespace.Namespace{
+		ID:             existing.ID,
+		Path:           existing.Path,
+		CustomMetadata: customMetadata,
+		UnlockKey:      existing.UnlockKey,
+	}
+	if err := ns.putNamespaceLocked(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// LockNamespace locks API access to the namespace at the given absolute
+// path and all of its descendants. It returns the key required to unlock
+// the namespace again.
+func (ns *NamespaceStore) LockNamespace(ctx context.Context, nsPath string) (string, error) {
+	nsPath = namespace.Canonicalize(nsPath)
+	if nsPath == "" {
+		return "", errNamespaceLockRoot
+	}
+
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+
+	existing, ok := ns.namespacesByPath[nsPath]
+	if !ok {
+		return "", fmt.Errorf("namespace %q does not exist", nsPath)
+	}
+	if existing.UnlockKey != "" {
+		return "", errNamespaceAlreadyLocked
+	}
+
+	unlockKey, err := base62.Random(namespaceUnlockKeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	entry := &namespace.Namespace{
+		ID:             existing.ID,
+		Path:           existing.Path,
+		CustomMetadata: existing.CustomMetadata,
+		UnlockKey:      unlockKey,
+	}
+	if err := ns.putNamespaceLocked(ctx, entry); err != nil {
+		return "", err
+	}
+
+	return unlockKey, nil
+}
+
+// UnlockNamespace unlocks API access to the namespace at the given
+// absolute path. The unlock key returned when locking the namespace must
+// be provided unless force is set.
+func (ns *NamespaceStore) UnlockNamespace(ctx context.Context, nsPath string, unlockKey string, force bool) error {
+	nsPath = namespace.Canonicalize(nsPath)
+	if nsPath == "" {
+		return errNamespaceNotLocked
+	}
+
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+
+	existing, ok := ns.namespacesByPath[nsPath]
+	if !ok {
+		return fmt.Errorf("namespace %q does not exist", nsPath)
+	}
+	if existing.UnlockKey == "" {
+		return errNamespaceNotLocked
+	}
+	if !force && subtle.ConstantTimeCompare([]byte(existing.UnlockKey), []byte(unlockKey)) != 1 {
+		return errNamespaceInvalidKey
+	}
+
+	entry := &namespace.Namespace{
+		ID:             existing.ID,
+		Path:           existing.Path,
+		CustomMetadata: existing.CustomMetadata,
+	}
+	return ns.putNamespaceLocked(ctx, entry)
+}
+
+// IsLocked returns whether API access to the given namespace is locked,
+// either on the namespace itself or on one of its ancestors.
+func (ns *NamespaceStore) IsLocked(item *namespace.Namespace) bool {
+	if item.ID == namespace.RootNamespaceID {
+		return false
+	}
+
+	ns.lock.RLock()
+	defer ns.lock.RUnlock()
+
+	nsPath := item.Path
+	for nsPath != "" {
+		if entry, ok := ns.namespacesByPath[nsPath]; ok && entry.UnlockKey != "" {
+			return true
+		}
+
+		idx := strings.LastIndex(strings.TrimSuffix(nsPath, "/"), "/")
+		nsPath = nsPath[:idx+1]
+	}
+
+	return false
+}
+
+// DeleteNamespace removes the namespace at the given absolute path along
+// with all of its mounts, tokens, leases, policies and identity
+// artifacts. Namespaces which still have children cannot be deleted.
+func (ns *NamespaceStore) DeleteNamespace(ctx context.Context, nsPath string) (bool, error) {
+	nsPath = namespace.Canonicalize(nsPath)
+
+	// The namespace is marked as deleting in the same critical section as
+	// the children check, so that no child can be created under it while
+	// it is torn down without the lock held.
+	ns.lock.Lock()
+	entry, ok := ns.namespacesByPath[nsPath]
+	if !ok {
+		ns.lock.Unlock()
+		return false, nil
+	}
+	if _, ok := ns.deletingByPath[nsPath]; ok {
+		ns.lock.Unlock()
+		return false, errNamespaceBeingDeleted
+	}
+	if ns.hasChildrenLocked(entry) {
+		ns.lock.Unlock()
+		return false, errNamespaceHasChildren
+	}
+	ns.deletingByPath[nsPath] = entry
+	ns.lock.Unlock()
+
+	err := ns.core.teardownNamespace(namespace.ContextWithNamespace(ctx, entry))
+
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	defer delete(ns.deletingByPath, nsPath)
+
+	if err != nil {
+		return false, err
+	}
+
+	if err := ns.deleteNamespaceLocked(ctx, entry); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// hasChildrenLocked returns whether the namespace has any direct child,
+// including children which are still being created. The caller must hold
+// the lock.
+func (ns *NamespaceStore) hasChildrenLocked(parent *namespace.Namespace) bool {
+	if len(ns.listNamespacesLocked(parent, false)) > 0 {
+		return true
+	}
+
+	for nsPath := range ns.pendingByPath {
+		if nsPath != parent.Path && strings.HasPrefix(nsPath, parent.Path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// putNamespaceLocked persists the namespace and updates the caches. The
+// caller must hold the write lock.
+func (ns *NamespaceStore) putNamespaceLocked(ctx context.Context, entry *namespace.Namespace) error {
+	if err := ns.writeNamespaceLocked(ctx, entry); err != nil {
+		return err
+	}
+
+	ns.namespacesByID[entry.ID] = entry
+	ns.namespacesByPath[entry.Path] = entry
+	return nil
+}
+
+// writeNamespaceLocked persists the namespace without updating the caches.
+// The caller must hold the write lock.
+func (ns *NamespaceStore) writeNamespaceLocked(ctx context.Context, entry *namespace.Namespace) error {
+	storageEntry, err := logical.StorageEntryJSON(entry.ID, entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode namespace: %w", err)
+	}
+	if err := ns.storage.Put(ctx, storageEntry); err != nil {
+		return fmt.Errorf("failed to persist namespace: %w", err)
+	}
+
+	return nil
+}
+
+// deleteNamespaceLocked removes the namespace entry along with any
+// remaining storage owned by the namespace. The caller must hold the write
+// lock.
+func (ns *NamespaceStore) deleteNamespaceLocked(ctx context.Context, entry *namespace.Namespace) error {
+	view := NewBarrierView(ns.core.barrier, namespaceBarrierPrefix+entry.ID+"/")
+	if err := logical.ClearViewWithLogging(ctx, view, ns.logger.With("namespace", entry.Path)); err != nil {
+		return fmt.Errorf("failed to clear namespace storage: %w", err)
+	}
+
+	if err := ns.storage.Delete(ctx, entry.ID); err != nil {
+		return fmt.Errorf("failed to delete namespace: %w", err)
+	}
+
+	delete(ns.namespacesByID, entry.ID)
+	delete(ns.namespacesByPath, entry.Path)
+	return nil
+}
+
+// generateID returns a namespace ID not yet in use. The caller must hold
+// the write lock.
+func (ns *NamespaceStore) generateID() (string, error) {
+	for i := 0; i < 10; i++ {
+		id, err := base62.Random(namespaceIDLength)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := ns.namespacesByID[id]; !ok && id != namespace.RootNamespaceID {
+			return id, nil
+		}
+	}
+
+	return "", errors.New("failed to generate a unique namespace ID")
+}
+
+// initializeNamespace sets up the default mounts and policies of a newly
+// created namespace, which is expected to be set in the context.
+func (c *Core) initializeNamespace(ctx context.Context) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	mounts := []*MountEntry{
+		{
+			Table:       mountTableType,
+			Path:        mountPathCubbyhole,
+			Type:        mountTypeNSCubbyhole,
+			Description: "per-token private secret storage",
+			Local:       true,
+		},
+		{
+			Table:       mountTableType,
+			Path:        mountPathSystem,
+			Type:        mountTypeNSSystem,
+			Description: "system endpoints used for control, policy and debugging",
+			Config: MountConfig{
+				PassthroughRequestHeaders: []string{"Accept"},
+			},
+		},
+		{
+			Table:       mountTableType,
+			Path:        mountPathIdentity,
+			Type:        mountTypeNSIdentity,
+			Description: "identity store",
+			Config: MountConfig{
+				PassthroughRequestHeaders: []string{"Authorization"},
+			},
+		},
+	}
+	for _, entry := range mounts {
+		entry.RunningVersion = versions.DefaultBuiltinVersion
+		if err := c.mountInternal(ctx, entry, MountTableUpdateStorage); err != nil {
+			return fmt.Errorf("failed to mount %q in namespace %q: %w", entry.Path, ns.Path, err)
+		}
+	}
+
+	tokenAuth := &MountEntry{
+		Table:          credentialTableType,
+		Path:           "token/",
+		Type:           mountTypeNSToken,
+		Description:    "token based credentials",
+		RunningVersion: versions.DefaultBuiltinVersion,
+	}
+	if err := c.enableCredentialInternal(ctx, tokenAuth, MountTableUpdateStorage); err != nil {
+		return fmt.Errorf("failed to enable token auth in namespace %q: %w", ns.Path, err)
+	}
+
+	if err := c.policyStore.loadACLPolicyInternal(ctx, defaultPolicyName, defaultPolicy); err != nil {
+		return err
+	}
+	if err := c.policyStore.loadACLPolicyInternal(ctx, responseWrappingPolicyName, responseWrappingPolicy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// teardownNamespace removes all mounts, identity artifacts and cached
+// policies of the namespace set in the context. Auth mounts are disabled
+// first so that tokens issued within the namespace are revoked before the
+// secret engines backing their leases are removed.
+func (c *Core) teardownNamespace(ctx context.Context) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The token store and the system backend hold the storage of the
+	// namespace's tokens and policies, so they are removed last.
+	var authPaths, mountPaths []string
+	var hasToken, hasSystem bool
+	c.authLock.RLock()
+	for _, entry := range c.auth.Entries {
+		switch {
+		case entry.NamespaceID != ns.ID:
+		case entry.Type == mountTypeNSToken:
+			hasToken = true
+		default:
+			authPaths = append(authPaths, entry.Path)
+		}
+	}
+	c.authLock.RUnlock()
+	if hasToken {
+		authPaths = append(authPaths, "token/")
+	}
+
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		switch {
+		case entry.NamespaceID != ns.ID:
+		case entry.Type == mountTypeNSSystem:
+			hasSystem = true
+		default:
+			mountPaths = append(mountPaths, entry.Path)
+		}
+	}
+	c.mountsLock.RUnlock()
+	if hasSystem {
+		mountPaths = append(mountPaths, mountPathSystem)
+	}
+
+	for _, authPath := range authPaths {
+		if err := c.disableCredentialInternal(ctx, authPath, MountTableUpdateStorage); err != nil {
+			return fmt.Errorf("failed to disable auth mount %q in namespace %q: %w", authPath, ns.Path, err)
+		}
+	}
+	for _, mountPath := range mountPaths {
+		if err := c.unmountInternal(ctx, mountPath, MountTableUpdateStorage); err != nil {
+			return fmt.Errorf("failed to unmount %q in namespace %q: %w", mountPath, ns.Path, err)
+		}
+	}
+
+	if c.identityStore != nil {
+		if err := c.identityStore.deleteNamespaceArtifacts(ctx, ns); err != nil {
+			return fmt.Errorf("failed to remove identity artifacts of namespace %q: %w", ns.Path, err)
+		}
+	}
+
+	if c.policyStore != nil {
+		c.policyStore.invalidateNamespace(ns)
+	}
+
+	return nil
+}
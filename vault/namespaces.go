@@ -15,5 +15,12 @@ func namespaceByID(ctx context.Context, nsID string, c *Core) (*namespace.Namesp
 	if nsID == namespace.RootNamespaceID {
 		return namespace.RootNamespace, nil
 	}
+
+	if c != nil && c.namespaceStore != nil {
+		if ns := c.namespaceStore.GetNamespace(nsID); ns != nil {
+			return ns, nil
+		}
+	}
+
 	return nil, namespace.ErrNoNamespace
 }
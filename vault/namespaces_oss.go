@@ -14,5 +14,5 @@ func (c *Core) NamespaceByID(ctx context.Context, nsID string) (*namespace.Names
 }
 
 func (c *Core) ListNamespaces(includePath bool) []*namespace.Namespace {
-	return []*namespace.Namespace{namespace.RootNamespace}
+	return c.collectNamespaces()
 }
@@ -28,6 +28,7 @@ import (
 	"github.com/openbao/openbao/sdk/v2/logical"
 	backendplugin "github.com/openbao/openbao/sdk/v2/plugin"
 	"github.com/openbao/openbao/version"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
@@ -384,6 +385,9 @@ func (c *PluginCatalog) newPluginClient(ctx context.Context, pluginRunner *plugi
 			pluginutil.MLock(c.mlockPlugins),
 			pluginutil.AutoMTLS(config.AutoMTLS),
 			pluginutil.Runner(config.Wrapper),
+			// Trace requests to the plugin, propagating the trace context in
+			// the request metadata
+			pluginutil.GRPCDialOptions(grpc.WithStatsHandler(otelgrpc.NewClientHandler())),
 		)
 		if err != nil {
 			return nil, err
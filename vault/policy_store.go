@@ -190,15 +190,17 @@ func NewPolicyStore(ctx context.Context, core *Core, baseView BarrierView, syste
 		ps.tokenPoliciesLRU = cache
 	}
 
-	aclView := ps.getACLView(namespace.RootNamespace)
-	keys, err := logical.CollectKeys(namespace.RootContext(ctx), aclView)
-	if err != nil {
-		ps.logger.Error("error collecting acl policy keys", "error", err)
-		return nil, err
-	}
-	for _, key := range keys {
-		index := ps.cacheKey(namespace.RootNamespace, ps.sanitizeName(key))
-		ps.policyTypeMap.Store(index, PolicyTypeACL)
+	for _, ns := range core.collectNamespaces() {
+		aclView := ps.getACLView(ns)
+		keys, err := logical.CollectKeys(namespace.ContextWithNamespace(ctx, ns), aclView)
+		if err != nil {
+			ps.logger.Error("error collecting acl policy keys", "namespace", ns.Path, "error", err)
+			return nil, err
+		}
+		for _, key := range keys {
+			index := ps.cacheKey(ns, ps.sanitizeName(key))
+			ps.policyTypeMap.Store(index, PolicyTypeACL)
+		}
 	}
 
 	// Special-case root; doesn't exist on disk but does need to be found
@@ -238,6 +240,25 @@ func (c *Core) teardownPolicyStore() error {
 	return nil
 }
 
+// invalidateNamespace removes all cached policies of the given namespace,
+// used once the namespace has been deleted.
+func (ps *PolicyStore) invalidateNamespace(ns *namespace.Namespace) {
+	ps.modifyLock.Lock()
+	defer ps.modifyLock.Unlock()
+
+	prefix := ns.ID + "/"
+	ps.policyTypeMap.Range(func(key, _ interface{}) bool {
+		index := key.(string)
+		if strings.HasPrefix(index, prefix) {
+			ps.policyTypeMap.Delete(index)
+			if ps.tokenPoliciesLRU != nil {
+				ps.tokenPoliciesLRU.Remove(index)
+			}
+		}
+		return true
+	})
+}
+
 func (ps *PolicyStore) invalidate(ctx context.Context, name string, policyType PolicyType) {
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
@@ -9,8 +9,12 @@ import (
 	"github.com/openbao/openbao/helper/namespace"
 )
 
-func (ps *PolicyStore) getACLView(*namespace.Namespace) BarrierView {
-	return ps.aclView
+func (ps *PolicyStore) getACLView(ns *namespace.Namespace) BarrierView {
+	if ns == nil || ns.ID == namespace.RootNamespaceID {
+		return ps.aclView
+	}
+
+	return NewBarrierView(ps.core.barrier, namespaceBarrierPrefix+ns.ID+"/"+systemBarrierPrefix+policyACLSubPath)
 }
 
 func (ps *PolicyStore) getBarrierView(ns *namespace.Namespace, _ PolicyType) BarrierView {
@@ -18,5 +22,11 @@ func (ps *PolicyStore) getBarrierView(ns *namespace.Namespace, _ PolicyType) Bar
 }
 
 func (ps *PolicyStore) loadACLPolicyNamespaces(ctx context.Context, policyName, policyText string) error {
-	return ps.loadACLPolicyInternal(namespace.RootContext(ctx), policyName, policyText)
+	for _, ns := range ps.core.collectNamespaces() {
+		if err := ps.loadACLPolicyInternal(namespace.ContextWithNamespace(ctx, ns), policyName, policyText); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -27,9 +27,6 @@ const (
 	TypeRateLimit Type = "rate-limit"
 
 	// TypeLeaseCount represents the lease count limiting quota type
-	//
-	// This was a Vault Enterprise feature; the constant was left for
-	// historical reasons.
 	TypeLeaseCount Type = "lease-count"
 )
 
@@ -73,13 +70,18 @@ const (
 	LeaseActionAllow
 )
 
-type leaseWalkFunc func(context.Context, func(request *Request) bool) error
+// LeaseWalkFunc walks all leases known to the expiration manager, passing the
+// quota request of each lease to the given function, until it returns false.
+// No lease may be created or deleted while the leases are walked.
+type LeaseWalkFunc func(context.Context, func(request *Request) bool) error
 
 // String converts each quota type into its string equivalent value
 func (q Type) String() string {
 	switch q {
 	case TypeRateLimit:
 		return "rate-limit"
+	case TypeLeaseCount:
+		return "lease-count"
 	}
 	return "unknown"
 }
@@ -176,6 +178,24 @@ type Manager struct {
 
 	// dbAndCacheLock is a lock for db and path caches that need to be reset during Reset()
 	dbAndCacheLock locking.RWMutex
+
+	// leaseQuotas maps the ID of every lease counted by a lease count quota
+	// to the ID of that quota, so that the lease is released from the same
+	// quota when it is deleted.
+	leaseQuotas map[string]string
+
+	// leaseLock is a lock for leaseQuotas, leaseRecount and the counters of
+	// the lease count quotas.
+	leaseLock locking.Mutex
+
+	// leaseRecount holds the counts of the recount of the leases in
+	// progress, if any. recountLock serializes recounts.
+	leaseRecount *leaseRecount
+	recountLock  locking.Mutex
+
+	// leaseWalkFunc walks the existing leases to count them against lease
+	// count quotas when those change.
+	leaseWalkFunc LeaseWalkFunc
 }
 
 // QuotaLeaseInformation contains all of the information lease-count quotas require
@@ -267,6 +287,10 @@ type Request struct {
 	// ClientAddress is client unique addressable string (e.g. IP address). It can
 	// be empty if the quota type does not need it.
 	ClientAddress string
+
+	// LeaseID is the identifier of the lease being counted. It is only used by
+	// lease count quotas.
+	LeaseID string
 }
 
 // NewManager creates and initializes a new quota manager to hold all the quota
@@ -283,9 +307,12 @@ func NewManager(logger log.Logger, ms *metricsutil.ClusterMetricSink, detectDead
 		metricSink:           ms,
 		rateLimitPathManager: pathmanager.New(),
 		config:               new(Config),
+		leaseQuotas:          make(map[string]string),
 		quotaLock:            &locking.SyncRWMutex{},
 		quotaConfigLock:      &locking.SyncRWMutex{},
 		dbAndCacheLock:       &locking.SyncRWMutex{},
+		leaseLock:            &locking.SyncMutex{},
+		recountLock:          &locking.SyncMutex{},
 	}
 
 	if detectDeadlocks {
@@ -293,6 +320,8 @@ func NewManager(logger log.Logger, ms *metricsutil.ClusterMetricSink, detectDead
 		manager.quotaLock = &locking.DeadlockRWMutex{}
 		manager.quotaConfigLock = &locking.DeadlockRWMutex{}
 		manager.dbAndCacheLock = &locking.DeadlockRWMutex{}
+		manager.leaseLock = &locking.DeadlockMutex{}
+		manager.recountLock = &locking.DeadlockMutex{}
 	}
 
 	return manager, nil
@@ -302,9 +331,21 @@ func NewManager(logger log.Logger, ms *metricsutil.ClusterMetricSink, detectDead
 func (m *Manager) SetQuota(ctx context.Context, qType string, quota Quota, loading bool) error {
 	m.quotaLock.Lock()
 	m.dbAndCacheLock.RLock()
-	defer m.quotaLock.Unlock()
-	defer m.dbAndCacheLock.RUnlock()
-	return m.setQuotaLocked(ctx, qType, quota, loading)
+	err := m.setQuotaLocked(ctx, qType, quota, loading)
+	m.dbAndCacheLock.RUnlock()
+	m.quotaLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Leases loaded on unseal are counted as the expiration manager restores
+	// them; otherwise, the existing leases are counted again, as they may now
+	// fall under another quota.
+	if qType == TypeLeaseCount.String() && !loading {
+		return m.recountLeases(ctx)
+	}
+
+	return nil
 }
 
 // setQuotaLocked creates a transaction, passes it into setQuotaLockedWithTxn and manages its lifecycle
@@ -353,6 +394,12 @@ func (m *Manager) setQuotaLockedWithTxn(ctx context.Context, qType string, quota
 		return err
 	}
 
+	// An updated lease count quota keeps counting the leases counted so far,
+	// until they are counted again.
+	if lcq, ok := quota.(*LeaseCountQuota); ok && raw != nil {
+		lcq.counter = raw.(*LeaseCountQuota).LeaseCount()
+	}
+
 	// Add the initialized quota type implementation to the db
 	if err := txn.Insert(qType, quota); err != nil {
 		return err
@@ -626,6 +673,19 @@ func (m *Manager) QueryResolveRoleQuotas(req *Request) (bool, error) {
 
 // DeleteQuota removes a quota rule from the db for a given name
 func (m *Manager) DeleteQuota(ctx context.Context, qType string, name string) error {
+	if err := m.deleteQuota(ctx, qType, name); err != nil {
+		return err
+	}
+
+	// Leases of the deleted quota may fall under another quota.
+	if qType == TypeLeaseCount.String() {
+		return m.recountLeases(ctx)
+	}
+
+	return nil
+}
+
+func (m *Manager) deleteQuota(ctx context.Context, qType string, name string) error {
 	m.quotaLock.Lock()
 	m.dbAndCacheLock.RLock()
 	defer m.quotaLock.Unlock()
@@ -676,6 +736,230 @@ func (m *Manager) ApplyQuota(ctx context.Context, req *Request) (Response, error
 	return quota.allow(ctx, req)
 }
 
+// ApplyLeaseCountQuota checks whether the lease of the request is allowed by
+// the lease count quota applicable to it, if any. The lease is counted against
+// the quota when allowed, until released with HandleLeaseAction.
+func (m *Manager) ApplyLeaseCountQuota(ctx context.Context, req *Request) (Response, error) {
+	req.Type = TypeLeaseCount
+	resp := Response{Allowed: true}
+
+	m.dbAndCacheLock.RLock()
+	defer m.dbAndCacheLock.RUnlock()
+
+	quota, err := m.queryQuota(nil, req)
+	if err != nil {
+		return resp, err
+	}
+	if quota == nil {
+		return resp, nil
+	}
+
+	m.leaseLock.Lock()
+	defer m.leaseLock.Unlock()
+
+	if _, ok := m.leaseQuotas[req.LeaseID]; ok {
+		return resp, nil
+	}
+
+	resp, err = quota.allow(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.Allowed {
+		m.leaseQuotas[req.LeaseID] = quota.quotaID()
+		if m.leaseRecount != nil {
+			m.leaseRecount.add(req.LeaseID, quota.quotaID())
+		}
+	}
+	resp.Access = &access{quotaID: quota.quotaID()}
+
+	return resp, nil
+}
+
+// HandleLeaseAction updates the lease count quotas with an action taken by the
+// expiration manager on a lease. Loaded and created leases are counted against
+// the lease count quota applicable to them, if not counted already, even past
+// its maximum; deleted leases are released from the quota they were counted
+// against. Only the lease ID of the request is used for deleted leases.
+func (m *Manager) HandleLeaseAction(ctx context.Context, req *Request, action LeaseAction) error {
+	m.dbAndCacheLock.RLock()
+	defer m.dbAndCacheLock.RUnlock()
+
+	return m.handleLeaseActionLocked(req, action)
+}
+
+// handleLeaseActionLocked must be called with the db lock held for reading.
+func (m *Manager) handleLeaseActionLocked(req *Request, action LeaseAction) error {
+	txn := m.db.Txn(false)
+
+	switch action {
+	case LeaseActionLoaded, LeaseActionCreated:
+		req.Type = TypeLeaseCount
+		quota, err := m.queryQuota(txn, req)
+		if err != nil {
+			return err
+		}
+		if quota == nil {
+			return nil
+		}
+
+		m.leaseLock.Lock()
+		defer m.leaseLock.Unlock()
+
+		if m.leaseRecount != nil {
+			m.leaseRecount.add(req.LeaseID, quota.quotaID())
+		}
+		if _, ok := m.leaseQuotas[req.LeaseID]; ok {
+			return nil
+		}
+		quota.(*LeaseCountQuota).incCounter(action)
+		m.leaseQuotas[req.LeaseID] = quota.quotaID()
+
+	case LeaseActionDeleted:
+		m.leaseLock.Lock()
+		defer m.leaseLock.Unlock()
+
+		if m.leaseRecount != nil {
+			m.leaseRecount.remove(req.LeaseID)
+		}
+		quotaID, ok := m.leaseQuotas[req.LeaseID]
+		if !ok {
+			return nil
+		}
+		delete(m.leaseQuotas, req.LeaseID)
+
+		raw, err := txn.First(TypeLeaseCount.String(), indexID, quotaID)
+		if err != nil {
+			return err
+		}
+		if raw != nil {
+			raw.(*LeaseCountQuota).decCounter()
+		}
+
+	default:
+		return fmt.Errorf("unsupported lease action: %v", action)
+	}
+
+	return nil
+}
+
+// leaseRecount holds the counts of the leases walked by a recount. Leases
+// created or deleted during the walk are applied to both the live counts and
+// the recount, so that the recount is accurate once swapped in.
+type leaseRecount struct {
+	leaseQuotas map[string]string
+	counters    map[string]int
+	deleted     map[string]struct{}
+}
+
+func newLeaseRecount() *leaseRecount {
+	return &leaseRecount{
+		leaseQuotas: make(map[string]string),
+		counters:    make(map[string]int),
+		deleted:     make(map[string]struct{}),
+	}
+}
+
+// add counts the lease against the quota, unless it was already counted or
+// deleted during the walk.
+func (r *leaseRecount) add(leaseID, quotaID string) {
+	if _, ok := r.deleted[leaseID]; ok {
+		return
+	}
+	if _, ok := r.leaseQuotas[leaseID]; ok {
+		return
+	}
+	r.leaseQuotas[leaseID] = quotaID
+	r.counters[quotaID]++
+}
+
+// remove releases the lease from the quota it was counted against.
+func (r *leaseRecount) remove(leaseID string) {
+	r.deleted[leaseID] = struct{}{}
+	quotaID, ok := r.leaseQuotas[leaseID]
+	if !ok {
+		return
+	}
+	delete(r.leaseQuotas, leaseID)
+	r.counters[quotaID]--
+}
+
+// recountLeases counts all existing leases against the lease count quotas
+// from scratch. The leases are counted against the quota applicable to them
+// at the time they are walked, and the new counts replace the current ones
+// once the walk completes, so that the quotas keep being enforced meanwhile.
+func (m *Manager) recountLeases(ctx context.Context) error {
+	m.recountLock.Lock()
+	defer m.recountLock.Unlock()
+
+	m.dbAndCacheLock.RLock()
+	walkFunc := m.leaseWalkFunc
+	m.dbAndCacheLock.RUnlock()
+	if walkFunc == nil {
+		return nil
+	}
+
+	recount := newLeaseRecount()
+	m.leaseLock.Lock()
+	m.leaseRecount = recount
+	m.leaseLock.Unlock()
+
+	var walkErr error
+	err := walkFunc(ctx, func(req *Request) bool {
+		walkErr = m.recountLease(recount, req)
+		return walkErr == nil
+	})
+	if err == nil {
+		err = walkErr
+	}
+
+	m.dbAndCacheLock.RLock()
+	defer m.dbAndCacheLock.RUnlock()
+	m.leaseLock.Lock()
+	defer m.leaseLock.Unlock()
+
+	m.leaseRecount = nil
+	if err != nil {
+		return err
+	}
+
+	iter, err := m.db.Txn(false).Get(TypeLeaseCount.String(), indexID)
+	if err != nil {
+		return err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		lcq := raw.(*LeaseCountQuota)
+		lcq.lock.Lock()
+		lcq.counter = recount.counters[lcq.ID]
+		lcq.lock.Unlock()
+	}
+	m.leaseQuotas = recount.leaseQuotas
+
+	return nil
+}
+
+// recountLease counts a lease walked by a recount against the quota
+// applicable to it, if any.
+func (m *Manager) recountLease(recount *leaseRecount, req *Request) error {
+	m.dbAndCacheLock.RLock()
+	defer m.dbAndCacheLock.RUnlock()
+
+	req.Type = TypeLeaseCount
+	quota, err := m.queryQuota(m.db.Txn(false), req)
+	if err != nil {
+		return err
+	}
+	if quota == nil {
+		return nil
+	}
+
+	m.leaseLock.Lock()
+	defer m.leaseLock.Unlock()
+
+	recount.add(req.LeaseID, quota.quotaID())
+	return nil
+}
+
 // SetEnableRateLimitAuditLogging updates the operator preference regarding the
 // audit logging behavior.
 func (m *Manager) SetEnableRateLimitAuditLogging(val bool) {
@@ -769,6 +1053,7 @@ func (m *Manager) Reset() error {
 	}
 	m.storage = nil
 	m.ctx = nil
+	m.leaseWalkFunc = nil
 
 	return nil
 }
@@ -804,6 +1089,11 @@ func (m *Manager) resetCache() error {
 		return err
 	}
 	m.db = db
+
+	m.leaseLock.Lock()
+	m.leaseQuotas = make(map[string]string)
+	m.leaseLock.Unlock()
+
 	return nil
 }
 
@@ -1020,6 +1310,8 @@ func Load(ctx context.Context, storage logical.Storage, qType, name string) (Quo
 	switch qType {
 	case TypeRateLimit.String():
 		quota = &RateLimitQuota{}
+	case TypeLeaseCount.String():
+		quota = &LeaseCountQuota{}
 	default:
 		return nil, fmt.Errorf("unsupported type: %v", qType)
 	}
@@ -1033,8 +1325,10 @@ func Load(ctx context.Context, storage logical.Storage, qType, name string) (Quo
 }
 
 // Setup loads the quota configuration and all the quota rules into the
-// quota manager.
-func (m *Manager) Setup(ctx context.Context, storage logical.Storage) error {
+// quota manager. The lease walk function is used to count the existing leases
+// against lease count quotas when those change; leases restored on unseal must
+// be reported with HandleLeaseAction.
+func (m *Manager) Setup(ctx context.Context, storage logical.Storage, leaseWalkFunc LeaseWalkFunc) error {
 	m.quotaLock.Lock()
 	m.quotaConfigLock.Lock()
 	m.dbAndCacheLock.Lock()
@@ -1044,6 +1338,7 @@ func (m *Manager) Setup(ctx context.Context, storage logical.Storage) error {
 
 	m.storage = storage
 	m.ctx = ctx
+	m.leaseWalkFunc = leaseWalkFunc
 
 	// Load the quota configuration from storage and load it into the quota
 	// manager.
@@ -1121,6 +1416,15 @@ func QuotaStoragePath(quotaType, name string) string {
 // took place. Quota manager will trigger the quota specific updates including
 // the mount path update and the namespace update
 func (m *Manager) HandleRemount(ctx context.Context, from, to namespace.MountPathDetails) error {
+	if err := m.updateMountsOnRemount(ctx, from, to); err != nil {
+		return err
+	}
+
+	// The updated lease count quotas start counting from scratch.
+	return m.recountLeases(ctx)
+}
+
+func (m *Manager) updateMountsOnRemount(ctx context.Context, from, to namespace.MountPathDetails) error {
 	m.quotaLock.Lock()
 	m.dbAndCacheLock.RLock()
 	defer m.quotaLock.Unlock()
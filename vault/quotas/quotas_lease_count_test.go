@@ -0,0 +1,164 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package quotas
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/openbao/openbao/helper/metricsutil"
+	"github.com/openbao/openbao/sdk/v2/helper/logging"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaseCountQuota_Allow(t *testing.T) {
+	ctx := context.Background()
+	qm, err := NewManager(logging.NewVaultLogger(log.Trace), metricsutil.BlackholeSink(), true)
+	require.NoError(t, err)
+
+	quota := NewLeaseCountQuota("lcq", "", "database/", "", "", 2)
+	require.NoError(t, qm.SetQuota(ctx, TypeLeaseCount.String(), quota, true))
+
+	leaseReq := func(leaseID string) *Request {
+		return &Request{
+			Path:      "database/creds/ci",
+			MountPath: "database/",
+			LeaseID:   leaseID,
+		}
+	}
+
+	for _, leaseID := range []string{"lease1", "lease2"} {
+		resp, err := qm.ApplyLeaseCountQuota(ctx, leaseReq(leaseID))
+		require.NoError(t, err)
+		require.True(t, resp.Allowed)
+	}
+
+	// Counting a lease again does not count it twice
+	require.NoError(t, qm.HandleLeaseAction(ctx, leaseReq("lease2"), LeaseActionCreated))
+	require.Equal(t, 2, quota.LeaseCount())
+
+	resp, err := qm.ApplyLeaseCountQuota(ctx, leaseReq("lease3"))
+	require.NoError(t, err)
+	require.False(t, resp.Allowed)
+
+	// Leases of other mounts are not limited
+	resp, err = qm.ApplyLeaseCountQuota(ctx, &Request{
+		Path:      "pki/issue/test",
+		MountPath: "pki/",
+		LeaseID:   "lease4",
+	})
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+
+	require.NoError(t, qm.HandleLeaseAction(ctx, &Request{LeaseID: "lease1"}, LeaseActionDeleted))
+	require.Equal(t, 1, quota.LeaseCount())
+
+	resp, err = qm.ApplyLeaseCountQuota(ctx, leaseReq("lease3"))
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+
+	// Leases loaded on unseal are counted even past the maximum
+	require.NoError(t, qm.HandleLeaseAction(ctx, leaseReq("lease5"), LeaseActionLoaded))
+	require.Equal(t, 3, quota.LeaseCount())
+}
+
+func TestLeaseCountQuota_Recount(t *testing.T) {
+	ctx := context.Background()
+	qm, err := NewManager(logging.NewVaultLogger(log.Trace), metricsutil.BlackholeSink(), true)
+	require.NoError(t, err)
+
+	leases := []*Request{
+		{Path: "database/creds/ci", MountPath: "database/", LeaseID: "lease1"},
+		{Path: "database/creds/ci", MountPath: "database/", LeaseID: "lease2"},
+		{Path: "database/creds/app", MountPath: "database/", LeaseID: "lease3"},
+		{Path: "auth/userpass/login/foo", MountPath: "auth/userpass/", Role: "foo", LeaseID: "lease4"},
+	}
+	walkFunc := func(ctx context.Context, walkFn func(*Request) bool) error {
+		for _, lease := range leases {
+			req := *lease
+			if !walkFn(&req) {
+				break
+			}
+		}
+		return nil
+	}
+	require.NoError(t, qm.Setup(ctx, &logical.InmemStorage{}, walkFunc))
+
+	mountQuota := NewLeaseCountQuota("mount", "", "database/", "", "", 10)
+	require.NoError(t, qm.SetQuota(ctx, TypeLeaseCount.String(), mountQuota, false))
+	require.Equal(t, 3, mountQuota.LeaseCount())
+
+	// A more specific quota takes over the leases of its path
+	pathQuota := NewLeaseCountQuota("path", "", "database/", "creds/ci", "", 10)
+	require.NoError(t, qm.SetQuota(ctx, TypeLeaseCount.String(), pathQuota, false))
+	require.Equal(t, 1, mountQuota.LeaseCount())
+	require.Equal(t, 2, pathQuota.LeaseCount())
+
+	roleQuota := NewLeaseCountQuota("role", "", "auth/userpass/", "", "foo", 10)
+	require.NoError(t, qm.SetQuota(ctx, TypeLeaseCount.String(), roleQuota, false))
+	require.Equal(t, 1, roleQuota.LeaseCount())
+
+	// Deleting it hands its leases back to the mount quota
+	require.NoError(t, qm.DeleteQuota(ctx, TypeLeaseCount.String(), "path"))
+	require.Equal(t, 3, mountQuota.LeaseCount())
+
+	// Deleted leases are released from the quota they were counted against
+	require.NoError(t, qm.HandleLeaseAction(ctx, &Request{LeaseID: "lease1"}, LeaseActionDeleted))
+	require.Equal(t, 2, mountQuota.LeaseCount())
+}
+
+func TestLeaseCountQuota_RecountConcurrentLeases(t *testing.T) {
+	ctx := context.Background()
+	qm, err := NewManager(logging.NewVaultLogger(log.Trace), metricsutil.BlackholeSink(), true)
+	require.NoError(t, err)
+
+	leases := []*Request{
+		{Path: "database/creds/ci", MountPath: "database/", LeaseID: "lease1"},
+		{Path: "database/creds/ci", MountPath: "database/", LeaseID: "lease2"},
+		{Path: "database/creds/ci", MountPath: "database/", LeaseID: "lease3"},
+	}
+	var during func()
+	walkFunc := func(ctx context.Context, walkFn func(*Request) bool) error {
+		for i, lease := range leases {
+			req := *lease
+			if !walkFn(&req) {
+				break
+			}
+			if i == 0 && during != nil {
+				during()
+			}
+		}
+		return nil
+	}
+	require.NoError(t, qm.Setup(ctx, &logical.InmemStorage{}, walkFunc))
+
+	quota := NewLeaseCountQuota("mount", "", "database/", "", "", 10)
+	require.NoError(t, qm.SetQuota(ctx, TypeLeaseCount.String(), quota, false))
+	require.Equal(t, 3, quota.LeaseCount())
+
+	// While the leases are counted again, the current counts remain in
+	// place, and leases created or deleted meanwhile are accounted for. An
+	// updated quota takes over the count of the quota it replaces.
+	updated := NewLeaseCountQuota("mount", "", "database/", "", "", 10)
+	updated.ID = quota.ID
+	during = func() {
+		require.Equal(t, 3, updated.LeaseCount())
+
+		resp, err := qm.ApplyLeaseCountQuota(ctx, &Request{Path: "database/creds/ci", MountPath: "database/", LeaseID: "lease4"})
+		require.NoError(t, err)
+		require.True(t, resp.Allowed)
+		require.NoError(t, qm.HandleLeaseAction(ctx, &Request{LeaseID: "lease1"}, LeaseActionDeleted))
+		require.NoError(t, qm.HandleLeaseAction(ctx, &Request{LeaseID: "lease2"}, LeaseActionDeleted))
+		require.Equal(t, 2, updated.LeaseCount())
+	}
+	require.NoError(t, qm.SetQuota(ctx, TypeLeaseCount.String(), updated, false))
+
+	// lease2 was deleted before being walked, lease1 after; lease4 was
+	// created during the walk.
+	require.Equal(t, 2, updated.LeaseCount())
+	require.NoError(t, qm.HandleLeaseAction(ctx, &Request{LeaseID: "lease4"}, LeaseActionDeleted))
+	require.Equal(t, 1, updated.LeaseCount())
+}
@@ -6,5 +6,6 @@ package quotas
 func quotaTypes() []string {
 	return []string{
 		TypeRateLimit.String(),
+		TypeLeaseCount.String(),
 	}
 }
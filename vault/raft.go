@@ -196,6 +196,10 @@ func (c *Core) setupRaftActiveNode(ctx context.Context) error {
 		return err
 	}
 
+	if err := c.startRaftAutoSnapshots(ctx); err != nil {
+		return err
+	}
+
 	return c.startPeriodicRaftTLSRotate(ctx)
 }
 
@@ -211,6 +215,7 @@ func (c *Core) stopRaftActiveNode() {
 		raftBackend.StopAutopilot()
 	}
 
+	c.stopRaftAutoSnapshots()
 	c.stopPeriodicRaftTLSRotate()
 }
 
@@ -817,7 +822,7 @@ func (c *Core) getRaftChallenge(leaderInfo *raft.LeaderJoinInfo) (*raftInformati
 		return nil, err
 	}
 
-	if sealConfig.Type != c.seal.BarrierType().String() {
+	if !sealTypeMatches(sealConfig.Type, c.seal) {
 		return nil, fmt.Errorf("mismatching seal types between raft leader (%s) and follower (%s)", sealConfig.Type, c.seal.BarrierType())
 	}
 
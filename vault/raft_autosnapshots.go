@@ -0,0 +1,638 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/physical/raft"
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+const (
+	// raftAutoSnapshotConfigPath is the storage prefix under which automatic
+	// snapshot configurations are persisted.
+	raftAutoSnapshotConfigPath = "core/raft/snapshot-auto/config/"
+
+	// raftAutoSnapshotStorageLocal writes snapshots to a directory on the
+	// active node.
+	raftAutoSnapshotStorageLocal = "local"
+
+	// raftAutoSnapshotTimestampFormat is used to render the timestamp of a
+	// snapshot in its file name; it sorts lexicographically.
+	raftAutoSnapshotTimestampFormat = "20060102T150405Z"
+
+	defaultRaftAutoSnapshotFileNameTemplate = "{{.Name}}-{{.Timestamp}}.snap"
+	defaultRaftAutoSnapshotRetain           = 1
+)
+
+// raftAutoSnapshotConfig describes a snapshot schedule run by the active node.
+type raftAutoSnapshotConfig struct {
+	Name             string        `json:"name"`
+	Interval         time.Duration `json:"interval"`
+	Retain           int           `json:"retain"`
+	StorageType      string        `json:"storage_type"`
+	PathPrefix       string        `json:"path_prefix"`
+	FileNameTemplate string        `json:"file_name_template"`
+}
+
+// raftAutoSnapshotStatus reports the outcome of the most recent snapshot
+// attempts of a configuration. It is only tracked on the active node.
+type raftAutoSnapshotStatus struct {
+	LastSnapshotStart time.Time
+	LastSnapshotEnd   time.Time
+	LastSnapshotPath  string
+	LastSuccess       time.Time
+	LastFailure       time.Time
+	LastError         string
+	ConsecutiveErrors int
+	NextSnapshot      time.Time
+	Retained          int
+}
+
+// raftAutoSnapshotFileNameData is passed to file name templates.
+type raftAutoSnapshotFileNameData struct {
+	Name      string
+	Timestamp string
+	Index     string
+	NodeID    string
+}
+
+// raftAutoSnapshotFile describes a stored snapshot.
+type raftAutoSnapshotFile struct {
+	Name    string
+	ModTime time.Time
+}
+
+// raftAutoSnapshotStorage is a target snapshots are written to. Names are
+// relative to the target; the target decides how they are laid out.
+type raftAutoSnapshotStorage interface {
+	// Write stores the snapshot read from r under the given name, returning
+	// a description of the written location.
+	Write(ctx context.Context, name string, r io.Reader) (string, error)
+
+	// List returns the stored snapshots whose name matches the glob pattern.
+	List(ctx context.Context, pattern string) ([]raftAutoSnapshotFile, error)
+
+	// Delete removes a stored snapshot.
+	Delete(ctx context.Context, name string) error
+}
+
+// raftAutoSnapshotStorageFactory creates a storage target for a config.
+type raftAutoSnapshotStorageFactory func(config *raftAutoSnapshotConfig) (raftAutoSnapshotStorage, error)
+
+// raftAutoSnapshotStorageFactories holds the supported storage types.
+var raftAutoSnapshotStorageFactories = map[string]raftAutoSnapshotStorageFactory{
+	raftAutoSnapshotStorageLocal: newLocalRaftAutoSnapshotStorage,
+}
+
+// validate checks the config, filling in defaults.
+func (c *raftAutoSnapshotConfig) validate() error {
+	if c.Interval <= 0 {
+		return errors.New("interval must be greater than zero")
+	}
+	if c.Retain < 1 {
+		return errors.New("retain must be at least 1")
+	}
+	if c.StorageType == "" {
+		c.StorageType = raftAutoSnapshotStorageLocal
+	}
+	if _, ok := raftAutoSnapshotStorageFactories[c.StorageType]; !ok {
+		return fmt.Errorf("unsupported storage_type %q", c.StorageType)
+	}
+	if c.StorageType == raftAutoSnapshotStorageLocal && c.PathPrefix == "" {
+		return errors.New("path_prefix is required for local storage")
+	}
+	if c.FileNameTemplate == "" {
+		c.FileNameTemplate = defaultRaftAutoSnapshotFileNameTemplate
+	}
+
+	tmpl, err := template.New("file_name").Option("missingkey=error").Parse(c.FileNameTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid file_name_template: %w", err)
+	}
+
+	// The timestamp must be part of the file name, otherwise subsequent
+	// snapshots overwrite each other and retention cannot tell them apart.
+	pattern, err := raftAutoSnapshotFileNamePattern(tmpl, c.Name)
+	if err != nil {
+		return fmt.Errorf("invalid file_name_template: %w", err)
+	}
+	if !strings.Contains(pattern, "*") {
+		return errors.New("file_name_template must include {{.Timestamp}} or {{.Index}}")
+	}
+	if strings.ContainsAny(pattern, `/\`) {
+		return errors.New("file_name_template must not contain path separators")
+	}
+
+	return nil
+}
+
+// raftAutoSnapshotFileNamePattern renders the template into a glob pattern
+// matching all file names it can produce for the given config name.
+func raftAutoSnapshotFileNamePattern(tmpl *template.Template, name string) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, raftAutoSnapshotFileNameData{
+		Name:      name,
+		Timestamp: "*",
+		Index:     "*",
+		NodeID:    "*",
+	}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// localRaftAutoSnapshotStorage stores snapshots in a local directory.
+type localRaftAutoSnapshotStorage struct {
+	dir string
+}
+
+// newLocalRaftAutoSnapshotStorage stores the snapshots of each config in
+// its own subdirectory of the path prefix, so that configs sharing a path
+// prefix never list, and so never prune, each other's snapshots.
+func newLocalRaftAutoSnapshotStorage(config *raftAutoSnapshotConfig) (raftAutoSnapshotStorage, error) {
+	dir := filepath.Join(config.PathPrefix, config.Name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	return &localRaftAutoSnapshotStorage{
+		dir: dir,
+	}, nil
+}
+
+func (s *localRaftAutoSnapshotStorage) Write(_ context.Context, name string, r io.Reader) (string, error) {
+	// Write to a temporary file first so that partially written snapshots
+	// are never picked up as valid ones.
+	tmp, err := os.CreateTemp(s.dir, "."+name+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(s.dir, name)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func (s *localRaftAutoSnapshotStorage) List(_ context.Context, pattern string) ([]raftAutoSnapshotFile, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []raftAutoSnapshotFile
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, entry.Name()); !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, raftAutoSnapshotFile{
+			Name:    entry.Name(),
+			ModTime: info.ModTime(),
+		})
+	}
+
+	return files, nil
+}
+
+func (s *localRaftAutoSnapshotStorage) Delete(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// raftAutoSnapshotManager runs the configured snapshot schedules on the
+// active node.
+type raftAutoSnapshotManager struct {
+	core   *Core
+	logger hclog.Logger
+
+	lock    sync.Mutex
+	configs map[string]*raftAutoSnapshotConfig
+	stopChs map[string]chan struct{}
+	status  map[string]*raftAutoSnapshotStatus
+	running bool
+	wg      sync.WaitGroup
+}
+
+func newRaftAutoSnapshotManager(c *Core) *raftAutoSnapshotManager {
+	logger := c.logger.Named("raft-snapshot-auto")
+	c.AddLogger(logger)
+
+	return &raftAutoSnapshotManager{
+		core:    c,
+		logger:  logger,
+		configs: make(map[string]*raftAutoSnapshotConfig),
+		stopChs: make(map[string]chan struct{}),
+		status:  make(map[string]*raftAutoSnapshotStatus),
+	}
+}
+
+// startRaftAutoSnapshots loads the persisted configurations and starts
+// their schedules. It is called when a node becomes active.
+func (c *Core) startRaftAutoSnapshots(ctx context.Context) error {
+	if c.getRaftBackend() == nil || c.isRaftHAOnly() {
+		return nil
+	}
+
+	if c.raftAutoSnapshots == nil {
+		c.raftAutoSnapshots = newRaftAutoSnapshotManager(c)
+	}
+
+	configs, err := c.listRaftAutoSnapshotConfigs(ctx)
+	if err != nil {
+		return err
+	}
+
+	m := c.raftAutoSnapshots
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.running = true
+	for _, config := range configs {
+		m.startLocked(config)
+	}
+
+	return nil
+}
+
+// stopRaftAutoSnapshots stops all schedules, waiting for in-flight
+// snapshots to finish.
+func (c *Core) stopRaftAutoSnapshots() {
+	m := c.raftAutoSnapshots
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	m.running = false
+	for name := range m.stopChs {
+		m.stopLocked(name)
+	}
+	m.configs = make(map[string]*raftAutoSnapshotConfig)
+	m.lock.Unlock()
+
+	m.wg.Wait()
+}
+
+func (c *Core) listRaftAutoSnapshotConfigs(ctx context.Context) ([]*raftAutoSnapshotConfig, error) {
+	names, err := c.barrier.List(ctx, raftAutoSnapshotConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]*raftAutoSnapshotConfig, 0, len(names))
+	for _, name := range names {
+		config, err := c.readRaftAutoSnapshotConfig(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if config != nil {
+			configs = append(configs, config)
+		}
+	}
+
+	return configs, nil
+}
+
+func (c *Core) readRaftAutoSnapshotConfig(ctx context.Context, name string) (*raftAutoSnapshotConfig, error) {
+	entry, err := c.barrier.Get(ctx, raftAutoSnapshotConfigPath+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config raftAutoSnapshotConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// putRaftAutoSnapshotConfig persists the config and (re)starts its schedule.
+func (c *Core) putRaftAutoSnapshotConfig(ctx context.Context, config *raftAutoSnapshotConfig) error {
+	entry, err := logical.StorageEntryJSON(raftAutoSnapshotConfigPath+config.Name, config)
+	if err != nil {
+		return err
+	}
+	if err := c.barrier.Put(ctx, entry); err != nil {
+		return err
+	}
+
+	if m := c.raftAutoSnapshots; m != nil {
+		m.lock.Lock()
+		m.stopLocked(config.Name)
+		if m.running {
+			m.startLocked(config)
+		}
+		m.lock.Unlock()
+	}
+
+	return nil
+}
+
+// deleteRaftAutoSnapshotConfig removes the config and stops its schedule.
+// Snapshots that were already written are left in place.
+func (c *Core) deleteRaftAutoSnapshotConfig(ctx context.Context, name string) error {
+	if err := c.barrier.Delete(ctx, raftAutoSnapshotConfigPath+name); err != nil {
+		return err
+	}
+
+	if m := c.raftAutoSnapshots; m != nil {
+		m.lock.Lock()
+		m.stopLocked(name)
+		delete(m.configs, name)
+		delete(m.status, name)
+		m.lock.Unlock()
+	}
+
+	return nil
+}
+
+// Status returns a copy of the status of the named config, if known.
+func (m *raftAutoSnapshotManager) Status(name string) *raftAutoSnapshotStatus {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	status, ok := m.status[name]
+	if !ok {
+		return nil
+	}
+
+	ret := *status
+	return &ret
+}
+
+func (m *raftAutoSnapshotManager) startLocked(config *raftAutoSnapshotConfig) {
+	m.stopLocked(config.Name)
+
+	stopCh := make(chan struct{})
+	m.configs[config.Name] = config
+	m.stopChs[config.Name] = stopCh
+	if _, ok := m.status[config.Name]; !ok {
+		m.status[config.Name] = &raftAutoSnapshotStatus{}
+	}
+
+	m.wg.Add(1)
+	go m.run(config, stopCh)
+}
+
+func (m *raftAutoSnapshotManager) stopLocked(name string) {
+	if stopCh, ok := m.stopChs[name]; ok {
+		close(stopCh)
+		delete(m.stopChs, name)
+	}
+}
+
+func (m *raftAutoSnapshotManager) run(config *raftAutoSnapshotConfig, stopCh chan struct{}) {
+	defer m.wg.Done()
+
+	ctx, cancel := context.WithCancel(namespace.RootContext(nil))
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Resume the schedule relative to the last successful snapshot so that
+	// leadership changes and restarts don't cause bursts of snapshots.
+	delay := config.Interval
+	if last := m.lastSnapshotTime(ctx, config); !last.IsZero() {
+		delay = time.Until(last.Add(config.Interval))
+		if delay < 0 {
+			delay = 0
+		}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	m.setNextSnapshot(config.Name, time.Now().Add(delay))
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-timer.C:
+			m.snapshot(ctx, config)
+			timer.Reset(config.Interval)
+			m.setNextSnapshot(config.Name, time.Now().Add(config.Interval))
+		}
+	}
+}
+
+func (m *raftAutoSnapshotManager) setNextSnapshot(name string, next time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if status, ok := m.status[name]; ok {
+		status.NextSnapshot = next
+	}
+}
+
+// lastSnapshotTime returns the time of the newest stored snapshot.
+func (m *raftAutoSnapshotManager) lastSnapshotTime(ctx context.Context, config *raftAutoSnapshotConfig) time.Time {
+	storage, pattern, err := m.storageFor(config)
+	if err != nil {
+		return time.Time{}
+	}
+
+	files, err := storage.List(ctx, pattern)
+	if err != nil {
+		return time.Time{}
+	}
+
+	var last time.Time
+	for _, file := range files {
+		if file.ModTime.After(last) {
+			last = file.ModTime
+		}
+	}
+	return last
+}
+
+func (m *raftAutoSnapshotManager) storageFor(config *raftAutoSnapshotConfig) (raftAutoSnapshotStorage, string, error) {
+	factory, ok := raftAutoSnapshotStorageFactories[config.StorageType]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported storage_type %q", config.StorageType)
+	}
+
+	storage, err := factory(config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tmpl, err := template.New("file_name").Option("missingkey=error").Parse(config.FileNameTemplate)
+	if err != nil {
+		return nil, "", err
+	}
+	pattern, err := raftAutoSnapshotFileNamePattern(tmpl, config.Name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return storage, pattern, nil
+}
+
+// snapshot takes a single snapshot for the config and applies retention,
+// recording the outcome in the config's status.
+func (m *raftAutoSnapshotManager) snapshot(ctx context.Context, config *raftAutoSnapshotConfig) {
+	start := time.Now()
+	m.lock.Lock()
+	if status, ok := m.status[config.Name]; ok {
+		status.LastSnapshotStart = start
+	}
+	m.lock.Unlock()
+
+	path, retained, err := m.takeSnapshot(ctx, config, start)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	status, ok := m.status[config.Name]
+	if !ok {
+		return
+	}
+	status.LastSnapshotEnd = time.Now()
+	if err != nil {
+		m.logger.Error("automatic snapshot failed", "name", config.Name, "error", err)
+		status.LastFailure = status.LastSnapshotEnd
+		status.LastError = err.Error()
+		status.ConsecutiveErrors++
+		return
+	}
+
+	m.logger.Info("automatic snapshot taken", "name", config.Name, "path", path)
+	status.LastSnapshotPath = path
+	status.LastSuccess = status.LastSnapshotEnd
+	status.LastError = ""
+	status.ConsecutiveErrors = 0
+	status.Retained = retained
+}
+
+func (m *raftAutoSnapshotManager) takeSnapshot(ctx context.Context, config *raftAutoSnapshotConfig, now time.Time) (string, int, error) {
+	raftBackend := m.core.getRaftBackend()
+	if raftBackend == nil {
+		return "", 0, errors.New("raft storage is not in use")
+	}
+
+	storage, pattern, err := m.storageFor(config)
+	if err != nil {
+		return "", 0, err
+	}
+
+	name, err := raftAutoSnapshotFileName(config, raftBackend, now)
+	if err != nil {
+		return "", 0, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(raftBackend.Snapshot(pw, m.core.seal.GetAccess()))
+	}()
+
+	path, err := storage.Write(ctx, name, pr)
+	pr.CloseWithError(err)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	retained, err := m.applyRetention(ctx, storage, pattern, config.Retain)
+	if err != nil {
+		return path, 0, fmt.Errorf("snapshot written to %q but failed to apply retention: %w", path, err)
+	}
+
+	return path, retained, nil
+}
+
+func raftAutoSnapshotFileName(config *raftAutoSnapshotConfig, raftBackend *raft.RaftBackend, now time.Time) (string, error) {
+	tmpl, err := template.New("file_name").Option("missingkey=error").Parse(config.FileNameTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, raftAutoSnapshotFileNameData{
+		Name:      config.Name,
+		Timestamp: now.UTC().Format(raftAutoSnapshotTimestampFormat),
+		Index:     strconv.FormatUint(raftBackend.AppliedIndex(), 10),
+		NodeID:    raftBackend.NodeID(),
+	}); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// applyRetention deletes all but the newest retain snapshots matching the
+// pattern, returning the number of snapshots kept.
+func (m *raftAutoSnapshotManager) applyRetention(ctx context.Context, storage raftAutoSnapshotStorage, pattern string, retain int) (int, error) {
+	files, err := storage.List(ctx, pattern)
+	if err != nil {
+		return 0, err
+	}
+	if len(files) <= retain {
+		return len(files), nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].ModTime.Equal(files[j].ModTime) {
+			return files[i].Name > files[j].Name
+		}
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+
+	for _, file := range files[retain:] {
+		if err := storage.Delete(ctx, file.Name); err != nil {
+			return 0, err
+		}
+	}
+
+	return retain, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaftAutoSnapshotConfig_Validate(t *testing.T) {
+	config := &raftAutoSnapshotConfig{
+		Name:       "hourly",
+		Interval:   time.Hour,
+		Retain:     2,
+		PathPrefix: t.TempDir(),
+	}
+	require.NoError(t, config.validate())
+	require.Equal(t, raftAutoSnapshotStorageLocal, config.StorageType)
+	require.Equal(t, defaultRaftAutoSnapshotFileNameTemplate, config.FileNameTemplate)
+
+	for name, modify := range map[string]func(c *raftAutoSnapshotConfig){
+		"no interval":       func(c *raftAutoSnapshotConfig) { c.Interval = 0 },
+		"no retention":      func(c *raftAutoSnapshotConfig) { c.Retain = 0 },
+		"unknown storage":   func(c *raftAutoSnapshotConfig) { c.StorageType = "ftp" },
+		"no path":           func(c *raftAutoSnapshotConfig) { c.PathPrefix = "" },
+		"constant template": func(c *raftAutoSnapshotConfig) { c.FileNameTemplate = "snapshot.snap" },
+		"nested template":   func(c *raftAutoSnapshotConfig) { c.FileNameTemplate = "a/{{.Timestamp}}" },
+		"invalid template":  func(c *raftAutoSnapshotConfig) { c.FileNameTemplate = "{{.Missing}}" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			invalid := *config
+			modify(&invalid)
+			require.Error(t, invalid.validate())
+		})
+	}
+}
+
+func TestRaftAutoSnapshot_Retention(t *testing.T) {
+	ctx := context.Background()
+	config := &raftAutoSnapshotConfig{
+		Name:       "daily",
+		Interval:   time.Hour,
+		Retain:     2,
+		PathPrefix: t.TempDir(),
+	}
+	require.NoError(t, config.validate())
+
+	storage, err := newLocalRaftAutoSnapshotStorage(config)
+	require.NoError(t, err)
+
+	// Files not produced by this config are never touched
+	unrelated := filepath.Join(config.PathPrefix, config.Name, "other-20240101T000000Z.snap")
+	require.NoError(t, os.WriteFile(unrelated, []byte("other"), 0o600))
+
+	// nor are those of configs sharing the path prefix whose file names
+	// match the pattern of this config
+	sibling := &raftAutoSnapshotConfig{
+		Name:       "daily-eu",
+		Interval:   time.Hour,
+		Retain:     1,
+		PathPrefix: config.PathPrefix,
+	}
+	require.NoError(t, sibling.validate())
+	siblingStorage, err := newLocalRaftAutoSnapshotStorage(sibling)
+	require.NoError(t, err)
+	siblingPath, err := siblingStorage.Write(ctx, "daily-eu-20240101T000000Z.snap", strings.NewReader("sibling"))
+	require.NoError(t, err)
+
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("daily-2024010%dT000000Z.snap", i+1)
+		path, err := storage.Write(ctx, name, strings.NewReader("snapshot"))
+		require.NoError(t, err)
+		require.NoError(t, os.Chtimes(path, now, now.Add(time.Duration(i)*time.Minute)))
+	}
+
+	tmpl, err := template.New("file_name").Parse(config.FileNameTemplate)
+	require.NoError(t, err)
+	pattern, err := raftAutoSnapshotFileNamePattern(tmpl, config.Name)
+	require.NoError(t, err)
+	require.Equal(t, "daily-*.snap", pattern)
+
+	m := &raftAutoSnapshotManager{}
+	retained, err := m.applyRetention(ctx, storage, pattern, config.Retain)
+	require.NoError(t, err)
+	require.Equal(t, 2, retained)
+
+	files, err := storage.List(ctx, pattern)
+	require.NoError(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	require.ElementsMatch(t, []string{"daily-20240103T000000Z.snap", "daily-20240104T000000Z.snap"}, names)
+
+	_, err = os.Stat(unrelated)
+	require.NoError(t, err)
+	_, err = os.Stat(siblingPath)
+	require.NoError(t, err)
+}
@@ -19,8 +19,10 @@ import (
 	"github.com/armon/go-metrics"
 	log "github.com/hashicorp/go-hclog"
 	"github.com/openbao/openbao/helper/forwarding"
+	"github.com/openbao/openbao/helper/tracing"
 	"github.com/openbao/openbao/sdk/v2/helper/consts"
 	"github.com/openbao/openbao/vault/cluster"
+	"go.opentelemetry.io/otel/codes"
 	"golang.org/x/net/http2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
@@ -346,10 +348,27 @@ func (c *Core) ForwardRequest(req *http.Request) (int, http.Header, []byte, erro
 		c.logger.Error("got nil forwarding RPC request")
 		return 0, nil, nil, errors.New("got nil forwarding RPC request")
 	}
-	resp, err := c.rpcForwardingClient.ForwardRequest(req.Context(), freq)
+
+	// Propagate the trace context so that the active node continues the
+	// trace of the request
+	ctx, span := tracing.StartClientSpan(req.Context(), "core.forwardRequest")
+	defer span.End()
+	traceHeader := make(http.Header)
+	tracing.Inject(ctx, traceHeader)
+	if len(traceHeader) > 0 && freq.HeaderEntries == nil {
+		freq.HeaderEntries = make(map[string]*forwarding.HeaderEntry, len(traceHeader))
+	}
+	for k, v := range traceHeader {
+		freq.HeaderEntries[k] = &forwarding.HeaderEntry{
+			Values: v,
+		}
+	}
+
+	resp, err := c.rpcForwardingClient.ForwardRequest(ctx, freq)
 	if err != nil {
 		metrics.IncrCounter([]string{"ha", "rpc", "client", "forward", "errors"}, 1)
 		c.logger.Error("error during forwarded RPC request", "error", err)
+		span.SetStatus(codes.Error, err.Error())
 		return 0, nil, nil, errors.New("error during forwarding RPC request")
 	}
 
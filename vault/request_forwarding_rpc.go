@@ -13,6 +13,7 @@ import (
 
 	"github.com/armon/go-metrics"
 	"github.com/openbao/openbao/helper/forwarding"
+	"github.com/openbao/openbao/helper/tracing"
 	"github.com/openbao/openbao/physical/raft"
 	"github.com/openbao/openbao/sdk/v2/helper/consts"
 )
@@ -32,6 +33,9 @@ func (s *forwardedRequestRPCServer) ForwardRequest(ctx context.Context, freq *fo
 		return nil, err
 	}
 
+	// The trace context was set by the forwarding node, so continue it
+	req = req.WithContext(tracing.ContextWithTrustedParent(req.Context()))
+
 	// A very dummy response writer that doesn't follow normal semantics, just
 	// lets you write a status code (last written wins) and a body. But it
 	// meets the interface requirements.
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -28,6 +29,7 @@ import (
 	"github.com/openbao/openbao/helper/identity/mfa"
 	"github.com/openbao/openbao/helper/metricsutil"
 	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/helper/tracing"
 	"github.com/openbao/openbao/internalshared/configutil"
 	"github.com/openbao/openbao/internalshared/listenerutil"
 	"github.com/openbao/openbao/sdk/v2/framework"
@@ -37,6 +39,7 @@ import (
 	"github.com/openbao/openbao/sdk/v2/helper/policyutil"
 	"github.com/openbao/openbao/sdk/v2/helper/wrapping"
 	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/vault/quotas"
 	"github.com/openbao/openbao/vault/tokens"
 )
 
@@ -325,9 +328,14 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 	return acl, te, entity, identityPolicies, nil
 }
 
-func (c *Core) CheckToken(ctx context.Context, req *logical.Request, unauth bool) (*logical.Auth, *logical.TokenEntry, error) {
+func (c *Core) CheckToken(ctx context.Context, req *logical.Request, unauth bool) (retAuth *logical.Auth, retTokenEntry *logical.TokenEntry, retErr error) {
 	defer metrics.MeasureSince([]string{"core", "check_token"}, time.Now())
 
+	ctx, span := tracing.StartSpan(ctx, "core.checkToken")
+	defer func() {
+		tracing.EndSpan(span, retErr)
+	}()
+
 	var acl *ACL
 	var te *logical.TokenEntry
 	var entity *identity.Entity
@@ -492,7 +500,7 @@ func (c *Core) switchedLockHandleRequest(httpCtx context.Context, req *logical.R
 	if c.Sealed() {
 		return nil, consts.ErrSealed
 	}
-	if c.standby {
+	if c.standby && !c.standbyReads.ready.Load() {
 		return nil, consts.ErrStandby
 	}
 
@@ -531,6 +539,11 @@ func (c *Core) switchedLockHandleRequest(httpCtx context.Context, req *logical.R
 	resp, err = c.handleCancelableRequest(ctx, req)
 	req.SetTokenEntry(nil)
 	cancel()
+
+	if c.StandbyReadsActive() {
+		forwarded := err != nil && errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error())
+		c.recordStandbyRead(forwarded, "request")
+	}
 	return resp, err
 }
 
@@ -557,10 +570,22 @@ func (c *Core) handleCancelableRequest(ctx context.Context, req *logical.Request
 		return nil, err
 	}
 
+	if c.StandbyReadsActive() {
+		if err := c.checkStandbyRead(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse namespace from http context: %w", err)
 	}
+	if ns.ID != namespace.RootNamespaceID && isRootNamespaceOnlyPath(req.Path) {
+		return logical.ErrorResponse("path %q is only available in the root namespace", req.Path), logical.ErrUnsupportedPath
+	}
+	if c.namespaceStore != nil && !strings.HasPrefix(req.Path, "sys/namespaces/api-lock/unlock") && c.namespaceStore.IsLocked(ns) {
+		return logical.ErrorResponse(errNamespaceLocked.Error()), logical.ErrPermissionDenied
+	}
 	var requestBodyToken string
 	var returnRequestAuthToken bool
 
@@ -631,6 +656,9 @@ func (c *Core) handleCancelableRequest(ctx context.Context, req *logical.Request
 				// should receive a 403 bad token error like they do for all other invalid tokens, unless the error
 				// specifies that we should forward the request or retry the request.
 				if err != nil {
+					if errors.Is(err, logical.ErrPerfStandbyPleaseForward) {
+						return nil, err
+					}
 					return logical.ErrorResponse("bad token"), logical.ErrPermissionDenied
 				}
 				req.Data["token"] = token
@@ -678,20 +706,11 @@ func (c *Core) handleCancelableRequest(ctx context.Context, req *logical.Request
 	// Instead, we return an error since we cannot be sure if we have an
 	// active token store to validate the provided token.
 	case strings.HasPrefix(req.Path, "sys/metrics"):
-		if c.standby {
+		if c.standby && !c.StandbyReadsActive() {
 			return nil, ErrCannotForwardLocalOnly
 		}
 	}
 
-	ns, err = namespace.FromContext(ctx)
-	if err != nil {
-		return nil, errwrap.Wrapf("could not parse namespace from http context: {{err}}", err)
-	}
-
-	if ns.Path != "" {
-		return nil, logical.CodedError(403, "namespaces feature not enabled")
-	}
-
 	var auth *logical.Auth
 	if c.isLoginRequest(ctx, req) {
 		resp, auth, err = c.handleLoginRequest(ctx, req)
@@ -699,6 +718,11 @@ func (c *Core) handleCancelableRequest(ctx context.Context, req *logical.Request
 		resp, auth, err = c.handleRequest(ctx, req)
 	}
 
+	// The active node audits requests forwarded by standbys serving reads
+	if err != nil && c.StandbyReadsActive() && errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error()) {
+		return nil, err
+	}
+
 	if err == nil && c.requestResponseCallback != nil {
 		c.requestResponseCallback(c.router.MatchingBackend(ctx, req.Path), req, resp)
 	}
@@ -822,9 +846,100 @@ func (c *Core) handleCancelableRequest(ctx context.Context, req *logical.Request
 
 func (c *Core) doRouting(ctx context.Context, req *logical.Request) (*logical.Response, error) {
 	// If we're replicating and we get a read-only error from a backend, need to forward to primary
-	return c.router.Route(ctx, req)
+	resp, err := c.router.Route(ctx, req)
+	if c.StandbyReadsActive() && standbyReadNeedsForward(resp, err) {
+		return nil, logical.ErrPerfStandbyPleaseForward
+	}
+	return resp, err
+}
+
+// ctxKeySubRequest is the context key under which handleRequest passes a
+// *subRequester to the backend serving the request.
+type ctxKeySubRequest struct{}
+
+// subRequester checks, and handles, requests made by a backend on behalf of
+// the client of the request it is serving. The ACL is only built on first use,
+// as most backends never make any sub-request.
+type subRequester struct {
+	core        *Core
+	clientToken string
+	te          *logical.TokenEntry
+	conn        *logical.Connection
+
+	lock   sync.Mutex
+	acl    *ACL
+	entity *identity.Entity
+}
+
+// check returns whether the client may perform the given request.
+func (s *subRequester) check(ctx context.Context, req *logical.Request) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.acl == nil {
+		tokenReq := &logical.Request{
+			Path:        req.Path,
+			ClientToken: s.clientToken,
+			Connection:  s.conn,
+		}
+		tokenReq.SetTokenEntry(s.te)
+
+		var err error
+		s.acl, _, s.entity, _, err = s.core.fetchACLTokenEntryAndEntity(ctx, tokenReq)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	authResults := s.core.performPolicyChecks(ctx, s.acl, s.te, req, s.entity, &PolicyCheckOpts{
+		RootPrivsRequired: s.core.router.RootPath(ctx, req.Path),
+	})
+	return authResults.Allowed, nil
+}
+
+// route handles the given request on behalf of the client as if the client
+// had sent it: it counts against the rate limit quotas applying to its path,
+// and goes through the same token, policy, audit and lease handling as any
+// other request. The state lock is already held by the request being served.
+func (s *subRequester) route(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	quotaReq := &quotas.Request{
+		Path:          req.Path,
+		MountPath:     strings.TrimPrefix(s.core.router.MatchingMount(ctx, req.Path), ns.Path),
+		NamespacePath: ns.Path,
+	}
+	if s.conn != nil {
+		quotaReq.ClientAddress = s.conn.RemoteAddr
+	}
+	quotaResp, err := s.core.ApplyRateLimitQuota(ctx, quotaReq)
+	if err != nil {
+		return nil, err
+	}
+	if !quotaResp.Allowed {
+		return nil, fmt.Errorf("request path %q: %w", req.Path, quotas.ErrRateLimitQuotaExceeded)
+	}
+
+	req.ID, err = uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	req.ClientToken = s.clientToken
+	req.Connection = s.conn
+
+	resp, err := s.core.handleCancelableRequest(ctx, req)
+	req.SetTokenEntry(nil)
+	return resp, err
 }
 
+// ctxKeyAuthOnlyLogin is the context key marking login requests which are
+// made by a backend to authenticate its client, see LoginSubRequest. Such
+// logins go through the whole login handling but do not create a token.
+type ctxKeyAuthOnlyLogin struct{}
+
 func (c *Core) isLoginRequest(ctx context.Context, req *logical.Request) bool {
 	return c.router.LoginPath(ctx, req.Path)
 }
@@ -832,6 +947,13 @@ func (c *Core) isLoginRequest(ctx context.Context, req *logical.Request) bool {
 func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp *logical.Response, retAuth *logical.Auth, retErr error) {
 	defer metrics.MeasureSince([]string{"core", "handle_request"}, time.Now())
 
+	ctx, span := tracing.StartSpan(ctx, "core.handleRequest",
+		tracing.AttrOperation.String(string(req.Operation)),
+		tracing.AttrPath.String(req.Path))
+	defer func() {
+		tracing.EndSpan(span, retErr)
+	}()
+
 	var nonHMACReqDataKeys []string
 	entry := c.router.MatchingMountEntry(ctx, req.Path)
 	if entry != nil {
@@ -863,6 +985,11 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	if ctErr == logical.ErrPerfStandbyPleaseForward {
 		return nil, nil, ctErr
 	}
+	// Policies and identities may not have caught up on standbys yet, so
+	// let the active node decide on requests denied here.
+	if ctErr != nil && c.StandbyReadsActive() {
+		return nil, nil, logical.ErrPerfStandbyPleaseForward
+	}
 
 	// Updating in-flight request data with client/entity ID
 	inFlightReqID, ok := ctx.Value(logical.CtxKeyInFlightRequestID{}).(string)
@@ -962,6 +1089,17 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		return nil, auth, retErr
 	}
 
+	// Let backends acting on several paths check each of them against the
+	// client's policies
+	if te != nil {
+		ctx = context.WithValue(ctx, ctxKeySubRequest{}, &subRequester{
+			core:        c,
+			clientToken: req.ClientToken,
+			te:          te,
+			conn:        req.Connection,
+		})
+	}
+
 	// Route the request
 	resp, routeErr := c.doRouting(ctx, req)
 	if resp != nil {
@@ -1071,6 +1209,10 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 
 			leaseID, err := c.expiration.Register(ctx, req, resp, "")
 			if err != nil {
+				if errors.Is(err, quotas.ErrLeaseCountQuotaExceeded) {
+					retErr = multierror.Append(retErr, logical.ErrLeaseCountQuotaExceeded)
+					return nil, auth, retErr
+				}
 				c.logger.Error("failed to register lease", "request_path", req.Path, "error", err)
 				retErr = multierror.Append(retErr, ErrInternalError)
 				return nil, auth, retErr
@@ -1156,6 +1298,10 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 					if err := c.tokenStore.revokeOrphan(ctx, resp.Auth.ClientToken); err != nil {
 						c.logger.Warn("failed to clean up token lease during auth/token/ request", "request_path", req.Path, "error", err)
 					}
+					if errors.Is(err, quotas.ErrLeaseCountQuotaExceeded) {
+						retErr = multierror.Append(retErr, logical.ErrLeaseCountQuotaExceeded)
+						return nil, auth, retErr
+					}
 					c.logger.Error("failed to register token lease during auth/token/ request", "request_path", req.Path, "error", err)
 					retErr = multierror.Append(retErr, ErrInternalError)
 					return nil, auth, retErr
@@ -1196,7 +1342,15 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (retResp *logical.Response, retAuth *logical.Auth, retErr error) {
 	defer metrics.MeasureSince([]string{"core", "handle_login_request"}, time.Now())
 
+	ctx, span := tracing.StartSpan(ctx, "core.handleLoginRequest",
+		tracing.AttrOperation.String(string(req.Operation)),
+		tracing.AttrPath.String(req.Path))
+	defer func() {
+		tracing.EndSpan(span, retErr)
+	}()
+
 	req.Unauthenticated = true
+	authOnly, _ := ctx.Value(ctxKeyAuthOnlyLogin{}).(bool)
 
 	var nonHMACReqDataKeys []string
 	entry := c.router.MatchingMountEntry(ctx, req.Path)
@@ -1468,6 +1622,10 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 						return nil, nil, logical.ErrPermissionDenied
 					}
 				}
+			} else if len(matchedMfaEnforcementList) > 0 && authOnly {
+				// The client of a backend has no way to complete a two-phase
+				// MFA validation.
+				return nil, nil, logical.ErrPermissionDenied
 			} else if len(matchedMfaEnforcementList) > 0 && len(req.MFACreds) == 0 {
 				mfaRequestID, err := uuid.GenerateUUID()
 				if err != nil {
@@ -1517,6 +1675,12 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		// Attach the display name, might be used by audit backends
 		req.DisplayName = auth.DisplayName
 
+		// Logins made by backends on behalf of their clients only
+		// authenticate the client and must not leave a token behind.
+		if authOnly {
+			goto LOGIN_DONE
+		}
+
 		requiresLease := resp.Auth.TokenType != logical.TokenTypeBatch
 
 		// If role was not already determined by http.rateLimitQuotaWrapping
@@ -1537,13 +1701,15 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		resp = respTokenCreate
 	}
 
+LOGIN_DONE:
 	// Successful login, remove any entry from userFailedLoginInfo map
-	// if it exists. This is done for batch tokens (for oss & ent)
+	// if it exists. This is done for batch tokens (for oss & ent) and for
+	// logins which do not create a token.
 	// For service tokens on oss it is taken care by core RegisterAuth function.
 	// For service tokens on ent it is taken care by registerAuth RPC calls.
 	// This update is done as part of registerAuth of RPC calls from standby
 	// to active node. This is added there to reduce RPC calls
-	if !isUserLockoutDisabled && (auth.TokenType == logical.TokenTypeBatch) {
+	if !isUserLockoutDisabled && (auth.TokenType == logical.TokenTypeBatch || authOnly && auth.Alias != nil) {
 		loginUserInfoKey := FailedLoginUser{
 			aliasName:     auth.Alias.Name,
 			mountAccessor: auth.Alias.MountAccessor,
@@ -1980,6 +2146,9 @@ func (c *Core) RegisterAuth(ctx context.Context, tokenTTL time.Duration, path st
 			if err := c.tokenStore.revokeOrphan(ctx, te.ID); err != nil {
 				c.logger.Warn("failed to clean up token lease during login request", "request_path", path, "error", err)
 			}
+			if errors.Is(err, quotas.ErrLeaseCountQuotaExceeded) {
+				return logical.ErrLeaseCountQuotaExceeded
+			}
 			c.logger.Error("failed to register token lease during login request", "request_path", path, "error", err)
 			return ErrInternalError
 		}
@@ -2111,6 +2280,9 @@ func (c *Core) PopulateTokenEntry(ctx context.Context, req *logical.Request) err
 		// should receive a 403 bad token error like they do for all other invalid tokens, unless the error
 		// specifies that we should forward the request or retry the request.
 		if err != nil {
+			if errors.Is(err, logical.ErrPerfStandbyPleaseForward) {
+				return err
+			}
 			return logical.ErrPermissionDenied
 		}
 	}
@@ -2246,5 +2418,11 @@ func (c *Core) checkSSCTokenInternal(ctx context.Context, token string) (string,
 		return plainToken.Random, nil
 	}
 
+	// Standbys serving reads only know of the token once they've caught up
+	// with the storage index it was created at.
+	if c.StandbyReadsActive() && plainToken.LocalIndex != 0 && !c.WaitForStorageIndex(ctx, plainToken.LocalIndex) {
+		return "", logical.ErrPerfStandbyPleaseForward
+	}
+
 	return plainToken.Random, nil
 }
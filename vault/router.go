@@ -18,6 +18,7 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/helper/tracing"
 	"github.com/openbao/openbao/sdk/v2/helper/consts"
 	"github.com/openbao/openbao/sdk/v2/helper/salt"
 	"github.com/openbao/openbao/sdk/v2/logical"
@@ -769,11 +770,23 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 	}()
 
 	// Invoke the backend
+	spanName := "router.handleRequest"
 	if existenceCheck {
-		ok, exists, err := re.backend.HandleExistenceCheck(ctx, req)
+		spanName = "router.handleExistenceCheck"
+	}
+	spanCtx, span := tracing.StartSpan(ctx, spanName,
+		tracing.AttrMountType.String(re.mountEntry.Type),
+		tracing.AttrMountPoint.String(re.mountEntry.Path),
+		tracing.AttrNamespace.String(ns.Path),
+		tracing.AttrOperation.String(string(req.Operation)))
+
+	if existenceCheck {
+		ok, exists, err := re.backend.HandleExistenceCheck(spanCtx, req)
+		tracing.EndSpan(span, err)
 		return nil, ok, exists, err
 	} else {
-		resp, err := re.backend.HandleRequest(ctx, req)
+		resp, err := re.backend.HandleRequest(spanCtx, req)
+		tracing.EndSpan(span, err)
 		if resp != nil {
 			if len(allowedResponseHeaders) > 0 {
 				resp.Headers = filteredHeaders(resp.Headers, allowedResponseHeaders, nil)
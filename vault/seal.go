@@ -57,6 +57,23 @@ const (
 	RecoveryTypeShamir      = "shamir"
 )
 
+// sealTypeMatches returns whether a barrier config stored with the given type
+// can be used with the seal without a seal migration. Adding seals switches
+// the barrier type from a single seal type to multiseal, which the seal
+// handles by wrapping its keys again in place. The reverse is not possible,
+// as a single seal cannot unwrap keys wrapped under several seals: seals are
+// removed while keeping enable_multiseal set.
+func sealTypeMatches(storedType string, s Seal) bool {
+	loadedType := s.BarrierType()
+	if storedType == loadedType.String() {
+		return true
+	}
+	if storedType == wrapping.WrapperTypeShamir.String() || loadedType == wrapping.WrapperTypeShamir {
+		return false
+	}
+	return loadedType == seal.WrapperTypeMultiSeal
+}
+
 type Seal interface {
 	SetCore(*Core)
 	Init(context.Context) error
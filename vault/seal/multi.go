@@ -0,0 +1,364 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package seal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	proto "github.com/golang/protobuf/proto"
+	multierror "github.com/hashicorp/go-multierror"
+	wrapping "github.com/openbao/go-kms-wrapping/v2"
+)
+
+// WrapperTypeMultiSeal is the barrier type of seals wrapping their keys
+// under several wrappers at once.
+const WrapperTypeMultiSeal wrapping.WrapperType = "multiseal"
+
+// multiSealMechanism marks blobs produced by a MultiAccess in their key info.
+// Such blobs hold one blob per seal, keyed by seal name.
+const multiSealMechanism uint64 = 0x6d756c7469736561
+
+// SealWrapper is one of the seals of a MultiAccess.
+type SealWrapper struct {
+	wrapping.Wrapper
+
+	// Name uniquely identifies the seal. Blobs record the names of the
+	// seals they are wrapped under.
+	Name string
+
+	// Priority orders the seals when decrypting; lower values are tried
+	// first.
+	Priority int
+
+	l               sync.RWMutex
+	healthy         bool
+	lastHealthCheck time.Time
+	lastSeenHealthy time.Time
+}
+
+// SealWrapperStatus is the health of one of the seals of a MultiAccess.
+type SealWrapperStatus struct {
+	Name            string
+	Type            string
+	Priority        int
+	Healthy         bool
+	LastHealthCheck time.Time
+	LastSeenHealthy time.Time
+}
+
+func (w *SealWrapper) setHealth(healthy bool) {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	now := time.Now()
+	w.healthy = healthy
+	w.lastHealthCheck = now
+	if healthy {
+		w.lastSeenHealthy = now
+	}
+}
+
+// IsHealthy returns whether the last use or health check of the seal
+// succeeded.
+func (w *SealWrapper) IsHealthy() bool {
+	w.l.RLock()
+	defer w.l.RUnlock()
+	return w.healthy
+}
+
+// multiSealBlob is the ciphertext of blobs produced by a MultiAccess.
+type multiSealBlob struct {
+	// Blobs holds the protobuf encoded blob of each seal the value is
+	// wrapped under, keyed by seal name.
+	Blobs map[string][]byte `json:"blobs"`
+}
+
+// MultiAccess is an Access wrapping values under every one of its seals, so
+// that any one of them is enough to unwrap them. Seals which fail are skipped
+// when encrypting, and the key ID of the resulting blob only lists the seals
+// which wrapped it, so that comparing it with KeyId tells whether the value
+// should be wrapped again.
+type MultiAccess struct {
+	wrappers []*SealWrapper
+}
+
+var _ Access = (*MultiAccess)(nil)
+
+// NewMultiAccess returns an Access wrapping values under all of the given
+// seals, which must have unique names.
+func NewMultiAccess(wrappers []*SealWrapper) (*MultiAccess, error) {
+	if len(wrappers) == 0 {
+		return nil, errors.New("at least one seal is required")
+	}
+
+	names := make(map[string]struct{}, len(wrappers))
+	for _, w := range wrappers {
+		if w.Name == "" {
+			return nil, errors.New("seals must be named when using several seals")
+		}
+		if strings.ContainsAny(w.Name, ",=") {
+			return nil, fmt.Errorf("invalid seal name %q", w.Name)
+		}
+		if _, ok := names[w.Name]; ok {
+			return nil, fmt.Errorf("duplicate seal name %q", w.Name)
+		}
+		names[w.Name] = struct{}{}
+
+		// Seals are assumed healthy until first used.
+		w.healthy = true
+	}
+
+	sorted := make([]*SealWrapper, len(wrappers))
+	copy(sorted, wrappers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	return &MultiAccess{wrappers: sorted}, nil
+}
+
+// Wrappers returns the seals of the access, in priority order.
+func (m *MultiAccess) Wrappers() []*SealWrapper {
+	return m.wrappers
+}
+
+// Status returns the health of each seal, in priority order.
+func (m *MultiAccess) Status(ctx context.Context) []SealWrapperStatus {
+	ret := make([]SealWrapperStatus, 0, len(m.wrappers))
+	for _, w := range m.wrappers {
+		wTyp, _ := w.Type(ctx)
+
+		w.l.RLock()
+		ret = append(ret, SealWrapperStatus{
+			Name:            w.Name,
+			Type:            wTyp.String(),
+			Priority:        w.Priority,
+			Healthy:         w.healthy,
+			LastHealthCheck: w.lastHealthCheck,
+			LastSeenHealthy: w.lastSeenHealthy,
+		})
+		w.l.RUnlock()
+	}
+	return ret
+}
+
+// CheckHealth encrypts and decrypts a test value with each seal, recording
+// their health. It returns the errors of the unhealthy seals, keyed by seal
+// name.
+func (m *MultiAccess) CheckHealth(ctx context.Context, testValue []byte, timeout time.Duration) map[string]error {
+	errs := make(map[string]error)
+	for _, w := range m.wrappers {
+		err := func() error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			ciphertext, err := w.Encrypt(ctx, testValue)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt seal health test value: %w", err)
+			}
+			plaintext, err := w.Decrypt(ctx, ciphertext)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt seal health test value: %w", err)
+			}
+			if !bytes.Equal(testValue, plaintext) {
+				return errors.New("seal health test value failed to decrypt to expected value")
+			}
+			return nil
+		}()
+
+		w.setHealth(err == nil)
+		if err != nil {
+			errs[w.Name] = err
+		}
+	}
+	return errs
+}
+
+// KeyId returns the key IDs of all seals whose key ID can be read, as a
+// comma-separated list of name=keyid pairs sorted by name.
+func (m *MultiAccess) KeyId(ctx context.Context) (string, error) {
+	var ids []string
+	for _, w := range m.wrappers {
+		keyId, err := w.KeyId(ctx)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, w.Name+"="+keyId)
+	}
+	if len(ids) == 0 {
+		return "", errors.New("no seal key ID is available")
+	}
+	return joinKeyIds(ids), nil
+}
+
+func joinKeyIds(ids []string) string {
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func (m *MultiAccess) SetConfig(_ context.Context, _ ...wrapping.Option) (*wrapping.WrapperConfig, error) {
+	return nil, errors.New("seals of a multi-seal access are configured individually")
+}
+
+func (m *MultiAccess) GetWrapper() wrapping.Wrapper {
+	return m
+}
+
+func (m *MultiAccess) Type(_ context.Context) (wrapping.WrapperType, error) {
+	return WrapperTypeMultiSeal, nil
+}
+
+func (m *MultiAccess) Init(ctx context.Context, options ...wrapping.Option) error {
+	for _, w := range m.wrappers {
+		if initWrapper, ok := w.Wrapper.(wrapping.InitFinalizer); ok {
+			if err := initWrapper.Init(ctx, options...); err != nil {
+				return fmt.Errorf("failed to initialize seal %q: %w", w.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MultiAccess) Finalize(ctx context.Context, options ...wrapping.Option) error {
+	var retErr *multierror.Error
+	for _, w := range m.wrappers {
+		if finalizeWrapper, ok := w.Wrapper.(wrapping.InitFinalizer); ok {
+			if err := finalizeWrapper.Finalize(ctx, options...); err != nil {
+				retErr = multierror.Append(retErr, fmt.Errorf("failed to finalize seal %q: %w", w.Name, err))
+			}
+		}
+	}
+	return retErr.ErrorOrNil()
+}
+
+// Encrypt wraps the plaintext under every reachable seal. It only fails if
+// no seal could wrap it.
+func (m *MultiAccess) Encrypt(ctx context.Context, plaintext []byte, options ...wrapping.Option) (*wrapping.BlobInfo, error) {
+	blob := multiSealBlob{Blobs: make(map[string][]byte, len(m.wrappers))}
+	var ids []string
+	var retErr *multierror.Error
+	for _, w := range m.wrappers {
+		keyId, err := w.KeyId(ctx)
+		if err != nil {
+			w.setHealth(false)
+			retErr = multierror.Append(retErr, fmt.Errorf("seal %q: %w", w.Name, err))
+			continue
+		}
+
+		wrapped, err := NewAccess(w.Wrapper).Encrypt(ctx, plaintext, options...)
+		if err != nil {
+			w.setHealth(false)
+			retErr = multierror.Append(retErr, fmt.Errorf("seal %q: %w", w.Name, err))
+			continue
+		}
+		w.setHealth(true)
+
+		encoded, err := proto.Marshal(wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode blob of seal %q: %w", w.Name, err)
+		}
+		blob.Blobs[w.Name] = encoded
+		ids = append(ids, w.Name+"="+keyId)
+	}
+	if len(blob.Blobs) == 0 {
+		return nil, fmt.Errorf("failed to encrypt with any seal: %w", retErr.ErrorOrNil())
+	}
+
+	ciphertext, err := json.Marshal(blob)
+	if err != nil {
+		return nil, err
+	}
+	return &wrapping.BlobInfo{
+		Ciphertext: ciphertext,
+		KeyInfo: &wrapping.KeyInfo{
+			Mechanism: multiSealMechanism,
+			KeyId:     joinKeyIds(ids),
+		},
+	}, nil
+}
+
+// Decrypt unwraps the blob with the first seal, in priority order, which
+// succeeds. Blobs produced by a single seal are supported, so that seals can
+// be added to an existing single-seal cluster.
+func (m *MultiAccess) Decrypt(ctx context.Context, data *wrapping.BlobInfo, options ...wrapping.Option) ([]byte, error) {
+	if data == nil {
+		return nil, errors.New("given ciphertext for decryption is nil")
+	}
+
+	if data.KeyInfo == nil || data.KeyInfo.Mechanism != multiSealMechanism {
+		return m.decryptSingle(ctx, data, options...)
+	}
+
+	var blob multiSealBlob
+	if err := json.Unmarshal(data.Ciphertext, &blob); err != nil {
+		return nil, fmt.Errorf("failed to decode multi-seal blob: %w", err)
+	}
+
+	var retErr *multierror.Error
+	for _, w := range m.wrappers {
+		encoded, ok := blob.Blobs[w.Name]
+		if !ok {
+			continue
+		}
+
+		wrapped := &wrapping.BlobInfo{}
+		if err := proto.Unmarshal(encoded, wrapped); err != nil {
+			retErr = multierror.Append(retErr, fmt.Errorf("seal %q: failed to decode blob: %w", w.Name, err))
+			continue
+		}
+
+		pt, err := NewAccess(w.Wrapper).Decrypt(ctx, wrapped, options...)
+		if err != nil {
+			w.setHealth(false)
+			retErr = multierror.Append(retErr, fmt.Errorf("seal %q: %w", w.Name, err))
+			continue
+		}
+		w.setHealth(true)
+		return pt, nil
+	}
+
+	if retErr == nil {
+		return nil, errors.New("value is not wrapped under any configured seal")
+	}
+	return nil, retErr
+}
+
+// decryptSingle unwraps a blob produced by a single seal, trying first the
+// seals whose key ID matches the blob.
+func (m *MultiAccess) decryptSingle(ctx context.Context, data *wrapping.BlobInfo, options ...wrapping.Option) ([]byte, error) {
+	var keyId string
+	if data.KeyInfo != nil {
+		keyId = data.KeyInfo.KeyId
+	}
+
+	candidates := make([]*SealWrapper, 0, len(m.wrappers))
+	var others []*SealWrapper
+	for _, w := range m.wrappers {
+		if wKeyId, err := w.KeyId(ctx); err == nil && wKeyId == keyId {
+			candidates = append(candidates, w)
+		} else {
+			others = append(others, w)
+		}
+	}
+	candidates = append(candidates, others...)
+
+	var retErr *multierror.Error
+	for _, w := range candidates {
+		pt, err := NewAccess(w.Wrapper).Decrypt(ctx, data, options...)
+		if err != nil {
+			retErr = multierror.Append(retErr, fmt.Errorf("seal %q: %w", w.Name, err))
+			continue
+		}
+		return pt, nil
+	}
+	return nil, retErr
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package seal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	wrapping "github.com/openbao/go-kms-wrapping/v2"
+)
+
+func testMultiAccess(t *testing.T) (*MultiAccess, []*ToggleableWrapper) {
+	t.Helper()
+
+	var wrappers []*SealWrapper
+	var toggles []*ToggleableWrapper
+	for i, name := range []string{"primary", "secondary"} {
+		w := wrapping.NewTestWrapper([]byte("secret-" + name))
+		w.SetKeyId("key-" + name)
+		toggle := &ToggleableWrapper{Wrapper: w}
+		toggles = append(toggles, toggle)
+		wrappers = append(wrappers, &SealWrapper{
+			Wrapper:  toggle,
+			Name:     name,
+			Priority: i + 1,
+		})
+	}
+
+	access, err := NewMultiAccess(wrappers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return access, toggles
+}
+
+func TestMultiAccess_DecryptWithAnySeal(t *testing.T) {
+	ctx := context.Background()
+	access, toggles := testMultiAccess(t)
+
+	input := []byte("barrier keys")
+	blob, err := access.Encrypt(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyId != "primary=key-primary,secondary=key-secondary" {
+		t.Fatalf("unexpected key ID %q", blob.KeyInfo.KeyId)
+	}
+
+	for i, toggle := range toggles {
+		// Only the other seal is reachable.
+		toggles[1-i].SetError(errors.New("unreachable"))
+		toggle.SetError(nil)
+
+		output, err := access.Decrypt(ctx, blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(input, output) {
+			t.Fatalf("expected %q, got %q", input, output)
+		}
+	}
+
+	toggles[1].SetError(errors.New("unreachable"))
+	if _, err := access.Decrypt(ctx, blob); err == nil {
+		t.Fatal("expected decryption to fail with no reachable seal")
+	}
+}
+
+func TestMultiAccess_EncryptSkipsUnreachableSeals(t *testing.T) {
+	ctx := context.Background()
+	access, toggles := testMultiAccess(t)
+
+	toggles[0].SetError(errors.New("unreachable"))
+	blob, err := access.Encrypt(ctx, []byte("recovery key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The blob only lists the seal which wrapped it, so that it differs
+	// from the key ID of the access and gets wrapped again.
+	keyId, err := access.KeyId(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyId != "secondary=key-secondary" || blob.KeyInfo.KeyId == keyId {
+		t.Fatalf("unexpected key ID %q", blob.KeyInfo.KeyId)
+	}
+
+	status := access.Status(ctx)
+	if status[0].Name != "primary" || status[0].Healthy {
+		t.Fatalf("expected primary seal to be unhealthy: %#v", status[0])
+	}
+	if status[1].Name != "secondary" || !status[1].Healthy {
+		t.Fatalf("expected secondary seal to be healthy: %#v", status[1])
+	}
+
+	toggles[1].SetError(errors.New("unreachable"))
+	if _, err := access.Encrypt(ctx, []byte("recovery key")); err == nil {
+		t.Fatal("expected encryption to fail with no reachable seal")
+	}
+}
+
+func TestMultiAccess_DecryptSingleSealBlob(t *testing.T) {
+	ctx := context.Background()
+	access, toggles := testMultiAccess(t)
+
+	// Blobs written before the seal was added are still readable.
+	input := []byte("barrier keys")
+	blob, err := toggles[1].Encrypt(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := access.Decrypt(ctx, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(input, output) {
+		t.Fatalf("expected %q, got %q", input, output)
+	}
+}
+
+func TestMultiAccess_CheckHealth(t *testing.T) {
+	ctx := context.Background()
+	access, toggles := testMultiAccess(t)
+
+	toggles[1].SetError(errors.New("unreachable"))
+	errs := access.CheckHealth(ctx, []byte("heartbeat"), time.Second)
+	if len(errs) != 1 || errs["secondary"] == nil {
+		t.Fatalf("expected secondary seal to fail health check: %v", errs)
+	}
+
+	toggles[1].SetError(nil)
+	if errs := access.CheckHealth(ctx, []byte("heartbeat"), time.Second); len(errs) != 0 {
+		t.Fatalf("expected all seals to be healthy: %v", errs)
+	}
+	for _, status := range access.Status(ctx) {
+		if !status.Healthy || status.LastHealthCheck.IsZero() {
+			t.Fatalf("expected seal to be healthy: %#v", status)
+		}
+	}
+}
+
+func TestNewMultiAccess_DuplicateNames(t *testing.T) {
+	_, err := NewMultiAccess([]*SealWrapper{
+		{Wrapper: wrapping.NewTestWrapper(nil), Name: "kms"},
+		{Wrapper: wrapping.NewTestWrapper(nil), Name: "kms"},
+	})
+	if err == nil {
+		t.Fatal("expected duplicate seal names to be rejected")
+	}
+}
@@ -185,6 +185,19 @@ func (d *autoSeal) UpgradeKeys(ctx context.Context) error {
 	if err := d.upgradeStoredKeys(ctx); err != nil {
 		return err
 	}
+
+	// With several seals, the barrier type changes between a single seal
+	// type and multiseal as seals are added or removed.
+	conf, err := d.BarrierConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if conf != nil && conf.Type != d.BarrierType().String() {
+		d.logger.Info("updating barrier seal type", "from", conf.Type, "to", d.BarrierType())
+		if err := d.SetBarrierConfig(ctx, conf); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -226,7 +239,7 @@ func (d *autoSeal) BarrierConfig(ctx context.Context) (*SealConfig, error) {
 		return nil, fmt.Errorf("%q seal validation failed: %w", sealType, err)
 	}
 
-	if conf.Type != d.BarrierType().String() {
+	if !sealTypeMatches(conf.Type, d) {
 		d.logger.Error("barrier seal type does not match loaded type", "seal_type", conf.Type, "loaded_type", d.BarrierType())
 		return nil, fmt.Errorf("barrier seal type of %q does not match loaded type of %q", conf.Type, d.BarrierType())
 	}
@@ -572,6 +585,31 @@ func (d *autoSeal) StartHealthCheck() {
 				healthCheckStop = nil
 				return
 			case t := <-healthCheck.C:
+				if multi, ok := d.Access.(*seal.MultiAccess); ok {
+					testVal := fmt.Sprintf("Heartbeat %d", mathrand.Intn(1000))
+					errs := multi.CheckHealth(ctx, []byte(testVal), sealHealthTestTimeout)
+					for name, err := range errs {
+						fail("seal health test failed, seal backend may be unreachable", "seal", name, "error", err)
+					}
+					if len(errs) == 0 {
+						d.logger.Debug("seal health test passed")
+						if !lastTestOk {
+							d.logger.Info("seal backends are now healthy again", "downtime", t.Sub(lastSeenOk).String())
+							healthCheck.Reset(sealHealthTestIntervalNominal)
+
+							// Wrap the keys again under the seals which
+							// were unreachable when they were last written.
+							if err := d.UpgradeKeys(ctx); err != nil {
+								d.logger.Warn("failed to upgrade seal keys", "error", err)
+							}
+						}
+						lastTestOk = true
+						lastSeenOk = t
+						d.core.MetricSink().SetGauge(autoSealUnavailableDuration, 0)
+					}
+					continue
+				}
+
 				func() {
 					ctx, cancel := context.WithTimeout(ctx, sealHealthTestTimeout)
 					defer cancel()
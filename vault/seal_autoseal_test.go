@@ -176,6 +176,108 @@ func TestAutoSeal_UpgradeKeys(t *testing.T) {
 	check()
 }
 
+func TestAutoSeal_MultiSealAddAndRemoveSeal(t *testing.T) {
+	core, _, _ := TestCoreUnsealed(t)
+	pBackend := newTestBackend(t)
+	core.physical = pBackend
+	ctx := context.Background()
+
+	newWrapper := func(name string) *seal.ToggleableWrapper {
+		w := wrapping.NewTestWrapper([]byte("secret-" + name))
+		w.SetKeyId("key-" + name)
+		return &seal.ToggleableWrapper{Wrapper: w}
+	}
+	newMultiSeal := func(wrappers map[string]*seal.ToggleableWrapper) *autoSeal {
+		var sealWrappers []*seal.SealWrapper
+		for name, w := range wrappers {
+			sealWrappers = append(sealWrappers, &seal.SealWrapper{Wrapper: w, Name: name})
+		}
+		access, err := seal.NewMultiAccess(sealWrappers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		autoSeal, err := NewAutoSeal(access)
+		if err != nil {
+			t.Fatal(err)
+		}
+		autoSeal.SetCore(core)
+		return autoSeal
+	}
+
+	// Start with a single seal.
+	first := newWrapper("first")
+	singleSeal, err := NewAutoSeal(seal.NewAccess(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	singleSeal.SetCore(core)
+
+	inkeys := [][]byte{[]byte("grist"), []byte("house")}
+	if err := singleSeal.SetStoredKeys(ctx, inkeys); err != nil {
+		t.Fatalf("SetStoredKeys: want no error, got %v", err)
+	}
+	inRecoveryKey := []byte("falernum")
+	if err := singleSeal.SetRecoveryKey(ctx, inRecoveryKey); err != nil {
+		t.Fatalf("SetRecoveryKey: want no error, got %v", err)
+	}
+
+	check := func(autoSeal *autoSeal) {
+		t.Helper()
+
+		outkeys, err := autoSeal.GetStoredKeys(ctx)
+		if err != nil {
+			t.Fatalf("GetStoredKeys: want no error, got %v", err)
+		}
+		if !reflect.DeepEqual(inkeys, outkeys) {
+			t.Errorf("incorrect stored keys: want %v, got %v", inkeys, outkeys)
+		}
+		outRecoveryKey, err := autoSeal.RecoveryKey(ctx)
+		if err != nil {
+			t.Fatalf("RecoveryKey: want no error, got %v", err)
+		}
+		if !bytes.Equal(inRecoveryKey, outRecoveryKey) {
+			t.Errorf("incorrect recovery key: want %q, got %q", inRecoveryKey, outRecoveryKey)
+		}
+	}
+
+	// Add a second seal; the keys are wrapped again under both seals.
+	second := newWrapper("second")
+	multiSeal := newMultiSeal(map[string]*seal.ToggleableWrapper{"first": first, "second": second})
+	check(multiSeal)
+	if err := multiSeal.UpgradeKeys(ctx); err != nil {
+		t.Fatalf("UpgradeKeys: want no error, got %v", err)
+	}
+
+	// Either seal is now enough to unwrap the keys.
+	first.SetError(errors.New("unreachable"))
+	check(multiSeal)
+	first.SetError(nil)
+	second.SetError(errors.New("unreachable"))
+	check(multiSeal)
+	second.SetError(nil)
+
+	// Remove the first seal.
+	multiSeal = newMultiSeal(map[string]*seal.ToggleableWrapper{"second": second})
+	if err := multiSeal.UpgradeKeys(ctx); err != nil {
+		t.Fatalf("UpgradeKeys: want no error, got %v", err)
+	}
+	first.SetError(errors.New("unreachable"))
+	check(multiSeal)
+
+	// A single seal configured without enable_multiseal cannot unwrap keys
+	// wrapped under several seals, even when it is one of them.
+	plainSeal, err := NewAutoSeal(seal.NewAccess(second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealTypeMatches(seal.WrapperTypeMultiSeal.String(), plainSeal) {
+		t.Fatal("single seal must not match a multiseal barrier")
+	}
+	if !sealTypeMatches(plainSeal.BarrierType().String(), multiSeal) {
+		t.Fatal("multiseal must match a single seal barrier")
+	}
+}
+
 func TestAutoSeal_HealthCheck(t *testing.T) {
 	inmemSink := metrics.NewInmemSink(
 		1000000*time.Hour,
@@ -0,0 +1,734 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/raft"
+	"github.com/openbao/openbao/helper/namespace"
+	"github.com/openbao/openbao/sdk/v2/helper/consts"
+	"github.com/openbao/openbao/sdk/v2/helper/strutil"
+	"github.com/openbao/openbao/sdk/v2/logical"
+	"github.com/openbao/openbao/sdk/v2/physical"
+	"github.com/openbao/openbao/vault/quotas"
+)
+
+const (
+	// standbyReadsSetupInterval is how often a standby node which is not
+	// serving reads yet, e.g. because a previous attempt failed, tries to
+	// set up the state required to do so.
+	standbyReadsSetupInterval = 5 * time.Second
+
+	// StandbyReadsIndexWaitTimeout is how long a standby node waits for its
+	// storage to catch up with the index requested by a client before
+	// rejecting or forwarding the request.
+	StandbyReadsIndexWaitTimeout = 2 * time.Second
+
+	// standbyReadsIndexVersion prefixes the X-Vault-Index header value, to
+	// allow changing its format later on.
+	standbyReadsIndexVersion = "v1"
+)
+
+// ErrStandbyReadsIndexNotSatisfied is returned when a standby node was asked
+// for a storage index that it has not caught up with in time.
+var ErrStandbyReadsIndexNotSatisfied = errors.New("required index state not present")
+
+var (
+	// standbyReadsMountTypes are the secrets engines which standby nodes may
+	// serve requests for. Other engines either create leases or have side
+	// effects outside of storage on reads, and are always forwarded.
+	standbyReadsMountTypes = []string{
+		mountTypeKV,
+		"transit",
+		"pki",
+		"totp",
+		"ssh",
+		mountTypeCubbyhole,
+		mountTypeNSCubbyhole,
+		mountTypeSystem,
+		mountTypeNSSystem,
+		mountTypeIdentity,
+		mountTypeNSIdentity,
+	}
+
+	// standbyReadsUpdateMountTypes are the secrets engines which standby
+	// nodes also serve update operations for, as these are mostly pure
+	// computations, e.g. transit's encrypt and decrypt endpoints. Updates
+	// that turn out to require a write are forwarded all the same.
+	standbyReadsUpdateMountTypes = []string{
+		"transit",
+	}
+
+	// standbyReadsForwardPaths are system paths that are always forwarded
+	// to the active node, as their responses only make sense there.
+	standbyReadsForwardPaths = []string{
+		"sys/leases/",
+		"sys/storage/",
+		"sys/metrics",
+		"sys/loggers",
+		"sys/pprof/",
+		"sys/in-flight-req",
+		"sys/host-info",
+		"sys/monitor",
+	}
+
+	// standbyReadsForwardMountSegments are path segments which, within a
+	// mount of the given type, mark requests that are always forwarded.
+	// ACME nonces only exist in the memory of the node handing them out,
+	// so any ACME request served by a standby would fail on the active
+	// node with badNonce.
+	standbyReadsForwardMountSegments = map[string][]string{
+		"pki": {"acme"},
+	}
+
+	// standbyReadsReloadPrefixes are storage keys backing state which
+	// standby nodes cannot invalidate piecemeal; a change to any of these
+	// makes the standby reload everything it has set up.
+	standbyReadsReloadPrefixes = []string{
+		coreMountConfigPath,
+		coreLocalMountConfigPath,
+		coreAuthConfigPath,
+		coreLocalAuthConfigPath,
+		coreAuditConfigPath,
+		coreLocalAuditConfigPath,
+		namespaceStoreSubPath,
+		pluginCatalogPath,
+		indexHeaderHMACKeyPath,
+		systemBarrierPrefix + auditedHeadersSubPath,
+	}
+
+	// standbyReadsForwardErrors are errors which, when returned while
+	// serving a request on a standby node, indicate that the active node
+	// has to handle the request instead.
+	standbyReadsForwardErrors = []string{
+		logical.ErrReadOnly.Error(),
+		logical.ErrSetupReadOnly.Error(),
+		logical.ErrPerfStandbyPleaseForward.Error(),
+		raft.ErrNotLeader.Error(),
+		"no decryption key available for term",
+	}
+)
+
+// standbyReadsBatch is a set of storage keys modified by the logs applied to
+// the raft FSM up to index. A nil key set means any key may have changed.
+type standbyReadsBatch struct {
+	index uint64
+	keys  []string
+}
+
+// standbyReads holds the state of a standby node serving read requests from
+// its local copy of storage.
+type standbyReads struct {
+	// ready is set once the standby has loaded the state required to serve
+	// requests, and cleared before it is torn down again. It is only
+	// modified while holding the state lock.
+	ready atomic.Bool
+
+	// index is the latest raft index for which all modified keys have been
+	// invalidated.
+	index atomic.Uint64
+
+	// cancel stops the invalidation worker and in-flight requests.
+	cancel context.CancelFunc
+
+	// reloadCh is used to ask the setup loop to tear down and set up the
+	// standby state again.
+	reloadCh chan struct{}
+
+	l        sync.Mutex
+	pending  []standbyReadsBatch
+	notifyCh chan struct{}
+}
+
+func newStandbyReads() *standbyReads {
+	return &standbyReads{
+		reloadCh: make(chan struct{}, 1),
+	}
+}
+
+// enqueue adds a batch of modified keys to be invalidated. It is called from
+// the raft FSM and must not block.
+func (s *standbyReads) enqueue(index uint64, keys []string) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.pending = append(s.pending, standbyReadsBatch{index: index, keys: keys})
+	select {
+	case s.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// dequeue returns all pending batches.
+func (s *standbyReads) dequeue() []standbyReadsBatch {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	batches := s.pending
+	s.pending = nil
+	return batches
+}
+
+// reset drops all pending batches and returns a fresh notification channel.
+func (s *standbyReads) reset() chan struct{} {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.pending = nil
+	s.notifyCh = make(chan struct{}, 1)
+	return s.notifyCh
+}
+
+// requestReload asks the setup loop to reload the standby state.
+func (s *standbyReads) requestReload() {
+	select {
+	case s.reloadCh <- struct{}{}:
+	default:
+	}
+}
+
+// standbyReadsEnabled returns whether standby nodes may serve reads, which
+// requires integrated storage.
+func (c *Core) standbyReadsEnabled() bool {
+	return c.enableStandbyReads && c.getRaftBackend() != nil && !c.isRaftHAOnly()
+}
+
+// StandbyReadsEnabled returns whether this node serves reads while on
+// standby, and reports storage indexes to clients.
+func (c *Core) StandbyReadsEnabled() bool {
+	return c.standbyReadsEnabled()
+}
+
+// StandbyReadsActive returns whether this node is a standby currently serving
+// read requests locally.
+func (c *Core) StandbyReadsActive() bool {
+	return c.standbyReads.ready.Load()
+}
+
+// periodicSetupStandbyReads runs on standby nodes and sets up the state
+// required to serve reads locally, retrying until it succeeds and reloading
+// it when asked to by the invalidation worker.
+func (c *Core) periodicSetupStandbyReads(stopCh chan struct{}) {
+	for {
+		c.setupStandbyReadsOrStop(stopCh)
+
+		timer := time.NewTimer(standbyReadsSetupInterval)
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return
+		case <-c.standbyReads.reloadCh:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Core) setupStandbyReadsOrStop(stopCh chan struct{}) {
+	raftBackend := c.getRaftBackend()
+	if raftBackend == nil || raftBackend.IsLeader() {
+		// The leader is about to become (or already is) the active node.
+		return
+	}
+
+	if stopped := grabLockOrStop(c.stateLock.Lock, c.stateLock.Unlock, stopCh); stopped {
+		return
+	}
+	defer c.stateLock.Unlock()
+
+	if c.Sealed() || !c.standby {
+		return
+	}
+
+	if c.standbyReads.ready.Load() {
+		// Only reload if the invalidation worker asked for it; otherwise
+		// everything is already set up.
+		if !c.standbyReads.reloadRequested() {
+			return
+		}
+		c.logger.Debug("reloading standby reads state")
+		if err := c.teardownStandbyReads(); err != nil {
+			c.logger.Warn("error tearing down standby reads state", "error", err)
+		}
+	}
+
+	if err := c.setupStandbyReads(); err != nil {
+		c.logger.Warn("failed to set up serving reads on standby, will retry", "error", err)
+		if err := c.teardownStandbyReads(); err != nil {
+			c.logger.Warn("error tearing down standby reads state", "error", err)
+		}
+		return
+	}
+}
+
+// reloadRequested returns whether a reload was requested since the state was
+// last set up.
+func (s *standbyReads) reloadRequested() bool {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	return s.notifyCh == nil
+}
+
+// markReloadRequested flags the current state as stale and wakes the setup
+// loop.
+func (s *standbyReads) markReloadRequested() {
+	s.l.Lock()
+	s.pending = nil
+	s.notifyCh = nil
+	s.l.Unlock()
+
+	s.requestReload()
+}
+
+// setupStandbyReads loads the subset of the state set up by postUnseal which
+// is needed to serve requests without writing to storage. The state lock
+// must be held.
+func (c *Core) setupStandbyReads() (retErr error) {
+	defer metrics.MeasureSince([]string{"core", "standby_reads", "setup"}, time.Now())
+
+	raftBackend := c.getRaftBackend()
+	if raftBackend == nil {
+		return errors.New("standby reads require integrated storage")
+	}
+
+	ctx, cancel := context.WithCancel(namespace.RootContext(nil))
+	c.standbyReads.cancel = cancel
+	c.activeContext = ctx
+	c.activeContextCancelFunc.Store(cancel)
+
+	// Start listening for modified keys before reading anything, so that we
+	// miss no changes applied while we load. Everything applied up to index
+	// is visible to us as we purge the cache afterwards.
+	index := raftBackend.AppliedIndex()
+	notifyCh := c.standbyReads.reset()
+	raftBackend.SetInvalidateCallback(c.standbyReads.enqueue)
+	c.standbyReads.index.Store(index)
+
+	c.physicalCache.Purge(ctx)
+	if !c.cachingDisabled {
+		c.physicalCache.SetEnabled(true)
+	}
+
+	// Let backends know they're running on a standby, so that they don't
+	// attempt writes or background work.
+	atomic.StoreUint32(c.replicationState, uint32(c.ReplicationState()|consts.ReplicationPerformanceStandby))
+
+	c.postUnsealFuncs = nil
+	for _, setup := range []func(context.Context) error{
+		c.setupPluginCatalog,
+		c.setupNamespaceStore,
+		c.loadMounts,
+		c.setupMounts,
+		c.setupPolicyStore,
+		c.loadCORSConfig,
+		c.loadCredentials,
+		c.setupCredentials,
+		c.setupQuotas,
+		c.loadHeaderHMACKey,
+	} {
+		if err := setup(ctx); err != nil {
+			return err
+		}
+	}
+
+	c.setupStandbyExpiration()
+
+	for _, setup := range []func(context.Context) error{
+		c.loadAudits,
+		c.setupAudits,
+		c.loadIdentityStoreArtifacts,
+		c.setupAuditedHeadersConfig,
+	} {
+		if err := setup(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Initialize the backends; their storage views stay read-only.
+	for _, v := range c.postUnsealFuncs {
+		v()
+	}
+	c.postUnsealFuncs = nil
+
+	go c.standbyReadsInvalidationWorker(ctx, notifyCh)
+
+	c.standbyReads.ready.Store(true)
+	c.logger.Info("serving reads on standby", "index", index)
+	return nil
+}
+
+// setupStandbyExpiration creates an expiration manager which only serves
+// lookups of existing leases and tokens; the active node remains the one
+// restoring and revoking leases.
+func (c *Core) setupStandbyExpiration() {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+
+	view := c.systemBarrierView.SubView(expirationSubPath)
+	mgr := NewExpirationManager(c, view, expireNoop, c.baseLogger.Named("expiration"), false)
+	atomic.StoreInt32(mgr.restoreMode, 0)
+
+	c.expiration = mgr
+	c.tokenStore.SetExpirationManager(mgr)
+}
+
+// teardownStandbyReads reverses setupStandbyReads. The state lock must be
+// held.
+func (c *Core) teardownStandbyReads() error {
+	c.standbyReads.ready.Store(false)
+
+	if raftBackend := c.getRaftBackend(); raftBackend != nil {
+		raftBackend.SetInvalidateCallback(nil)
+	}
+	if c.standbyReads.cancel != nil {
+		c.standbyReads.cancel()
+		c.standbyReads.cancel = nil
+	}
+	c.standbyReads.reset()
+
+	c.postUnsealFuncs = nil
+
+	var result error
+	if err := c.teardownAudits(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("error tearing down audits: %w", err))
+	}
+	if err := c.stopExpiration(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("error stopping expiration: %w", err))
+	}
+	if err := c.teardownCredentials(context.Background()); err != nil {
+		result = multierror.Append(result, fmt.Errorf("error tearing down credentials: %w", err))
+	}
+	if err := c.teardownPolicyStore(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("error tearing down policy store: %w", err))
+	}
+	if err := c.unloadMounts(context.Background()); err != nil {
+		result = multierror.Append(result, fmt.Errorf("error unloading mounts: %w", err))
+	}
+	if err := c.teardownNamespaceStore(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("error tearing down namespace store: %w", err))
+	}
+
+	atomic.StoreUint32(c.replicationState, uint32(c.ReplicationState()&^consts.ReplicationPerformanceStandby))
+
+	preSealPhysical(c)
+
+	return result
+}
+
+// standbyReadsInvalidationWorker processes the keys modified by logs applied
+// to the raft FSM, until ctx is canceled.
+func (c *Core) standbyReadsInvalidationWorker(ctx context.Context, notifyCh chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-notifyCh:
+		}
+
+		batches := c.standbyReads.dequeue()
+		if len(batches) == 0 {
+			continue
+		}
+
+		c.stateLock.RLock()
+		if ctx.Err() != nil {
+			c.stateLock.RUnlock()
+			return
+		}
+		reload := c.invalidateStandbyReads(ctx, batches)
+		c.stateLock.RUnlock()
+
+		if reload {
+			c.standbyReads.markReloadRequested()
+			return
+		}
+	}
+}
+
+// invalidateStandbyReads drops the cached state derived from the keys in the
+// given batches and advances the standby's index. It returns true when the
+// state needs to be reloaded entirely instead.
+func (c *Core) invalidateStandbyReads(ctx context.Context, batches []standbyReadsBatch) bool {
+	invalidator, _ := c.physicalCache.(physical.CacheInvalidator)
+
+	for _, batch := range batches {
+		if batch.keys == nil {
+			c.logger.Debug("storage replaced by snapshot, reloading standby reads state")
+			return true
+		}
+
+		for _, key := range batch.keys {
+			if invalidator != nil {
+				invalidator.Invalidate(ctx, key)
+			}
+			if c.invalidateStandbyReadsKey(ctx, key) {
+				c.logger.Debug("reloading standby reads state", "key", key)
+				return true
+			}
+		}
+
+		if batch.index > c.standbyReads.index.Load() {
+			c.standbyReads.index.Store(batch.index)
+		}
+	}
+
+	return false
+}
+
+// invalidateStandbyReadsKey invalidates the in-memory state derived from a
+// single storage key. It returns true when the state needs to be reloaded.
+func (c *Core) invalidateStandbyReadsKey(ctx context.Context, key string) bool {
+	for _, prefix := range standbyReadsReloadPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	// Strip the namespace storage prefix, if any, to find the namespace
+	// owning system keys.
+	ns := namespace.RootNamespace
+	sysKey := key
+	if strings.HasPrefix(key, namespaceBarrierPrefix) {
+		nsID, rest, found := strings.Cut(strings.TrimPrefix(key, namespaceBarrierPrefix), "/")
+		if found && strings.HasPrefix(rest, systemBarrierPrefix) {
+			var err error
+			ns, err = NamespaceByID(ctx, nsID, c)
+			if err != nil || ns == nil {
+				// The namespace may have been deleted in the meantime.
+				return false
+			}
+			sysKey = rest
+		}
+	}
+	nsCtx := namespace.ContextWithNamespace(ctx, ns)
+
+	switch {
+	case strings.HasPrefix(sysKey, systemBarrierPrefix+policyACLSubPath):
+		if c.policyStore != nil {
+			c.policyStore.invalidate(nsCtx, strings.TrimPrefix(sysKey, systemBarrierPrefix+policyACLSubPath), PolicyTypeACL)
+		}
+		return false
+
+	case strings.HasPrefix(sysKey, systemBarrierPrefix+quotas.StoragePrefix):
+		if err := c.setupQuotas(ctx); err != nil {
+			c.logger.Error("failed to reload quotas", "error", err)
+		}
+		return false
+
+	case sysKey == systemBarrierPrefix+"config/cors":
+		if err := c.loadCORSConfig(ctx); err != nil {
+			c.logger.Error("failed to reload CORS config", "error", err)
+		}
+		return false
+	}
+
+	// Everything else is owned by a mount; let its backend know.
+	mountNS, mountPath, prefix, found := c.router.MatchingAPIPrefixByStoragePath(ctx, key)
+	if !found {
+		return false
+	}
+	mountCtx := namespace.ContextWithNamespace(ctx, mountNS)
+	if backend := c.router.MatchingBackend(mountCtx, mountPath); backend != nil {
+		backend.InvalidateKey(mountCtx, strings.TrimPrefix(key, prefix))
+	}
+
+	return false
+}
+
+// checkStandbyRead returns logical.ErrPerfStandbyPleaseForward if a request
+// cannot be served by a standby node.
+func (c *Core) checkStandbyRead(ctx context.Context, req *logical.Request) error {
+	switch req.Operation {
+	case logical.ReadOperation, logical.ListOperation, logical.HelpOperation, logical.HeaderOperation:
+	case logical.UpdateOperation:
+		entry := c.router.MatchingMountEntry(ctx, req.Path)
+		if entry == nil || !strutil.StrListContains(standbyReadsUpdateMountTypes, entry.Type) {
+			return logical.ErrPerfStandbyPleaseForward
+		}
+	default:
+		return logical.ErrPerfStandbyPleaseForward
+	}
+
+	for _, path := range standbyReadsForwardPaths {
+		if strings.HasPrefix(req.Path, path) {
+			return logical.ErrPerfStandbyPleaseForward
+		}
+	}
+
+	entry := c.router.MatchingMountEntry(ctx, req.Path)
+	if entry == nil {
+		return logical.ErrPerfStandbyPleaseForward
+	}
+	if entry.Table != credentialTableType && !strutil.StrListContains(standbyReadsMountTypes, entry.Type) {
+		return logical.ErrPerfStandbyPleaseForward
+	}
+	if standbyReadsForwardMountPath(entry.Type, strings.TrimPrefix(req.Path, entry.Path)) {
+		return logical.ErrPerfStandbyPleaseForward
+	}
+
+	// Wrapping responses writes to the cubbyhole of a new token.
+	if req.WrapInfo != nil && req.WrapInfo.TTL != 0 {
+		return logical.ErrPerfStandbyPleaseForward
+	}
+
+	// Tokens unknown to this node may have been created on the active node
+	// since, and limited use tokens require a write on every use.
+	if req.ClientToken != "" {
+		te := req.TokenEntry()
+		if te == nil || te.NumUses != 0 {
+			return logical.ErrPerfStandbyPleaseForward
+		}
+	}
+
+	return nil
+}
+
+// standbyReadsForwardMountPath returns whether a request for the given path
+// within a mount of the given type must always be forwarded.
+func standbyReadsForwardMountPath(mountType, mountPath string) bool {
+	segments := standbyReadsForwardMountSegments[mountType]
+	if len(segments) == 0 {
+		return false
+	}
+
+	for _, segment := range strings.Split(mountPath, "/") {
+		if strutil.StrListContains(segments, segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// standbyReadNeedsForward returns whether the outcome of a request served on
+// a standby node shows that the active node has to handle it.
+func standbyReadNeedsForward(resp *logical.Response, err error) bool {
+	if resp != nil && (resp.Auth != nil || (resp.Secret != nil && resp.Secret.LeaseID != "")) {
+		return true
+	}
+
+	var msg string
+	switch {
+	case err != nil:
+		msg = err.Error()
+	case resp != nil && resp.IsError():
+		msg = resp.Error().Error()
+	default:
+		return false
+	}
+
+	for _, forwardErr := range standbyReadsForwardErrors {
+		if strings.Contains(msg, forwardErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordStandbyRead counts a request handled by a standby node serving reads.
+func (c *Core) recordStandbyRead(forwarded bool, reason string) {
+	if forwarded {
+		c.metricSink.IncrCounterWithLabels([]string{"core", "standby_reads", "forwarded"}, 1,
+			[]metrics.Label{{Name: "reason", Value: reason}})
+		return
+	}
+	c.metricSink.IncrCounterWithLabels([]string{"core", "standby_reads", "local"}, 1, nil)
+}
+
+// RecordStandbyReadForwarded counts a request which a standby node serving
+// reads forwarded to the active node without attempting it locally.
+func (c *Core) RecordStandbyReadForwarded(reason string) {
+	c.recordStandbyRead(true, reason)
+}
+
+// StorageIndex returns the latest storage index visible to requests served by
+// this node, and whether indexes are tracked at all.
+func (c *Core) StorageIndex() (uint64, bool) {
+	if !c.standbyReadsEnabled() {
+		return 0, false
+	}
+
+	if c.StandbyReadsActive() {
+		return c.standbyReads.index.Load(), true
+	}
+
+	return c.getRaftBackend().AppliedIndex(), true
+}
+
+// WaitForStorageIndex waits until requests served by this node observe the
+// given storage index, up to StandbyReadsIndexWaitTimeout. It returns false if
+// the index was not reached in time.
+func (c *Core) WaitForStorageIndex(ctx context.Context, index uint64) bool {
+	ctx, cancel := context.WithTimeout(ctx, StandbyReadsIndexWaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		current, ok := c.StorageIndex()
+		if !ok || current >= index {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// EncodeStorageIndex returns the X-Vault-Index header value for the given
+// storage index. The value is authenticated, so that clients can only present
+// indexes previously handed out by the cluster.
+func (c *Core) EncodeStorageIndex(index uint64) string {
+	payload := standbyReadsIndexVersion + ":" + strconv.FormatUint(index, 10)
+	return base64.StdEncoding.EncodeToString([]byte(payload + ":" + c.storageIndexHMAC(payload)))
+}
+
+// DecodeStorageIndex parses and authenticates a X-Vault-Index header value.
+func (c *Core) DecodeStorageIndex(value string) (uint64, error) {
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode index header: %w", err)
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || parts[0] != standbyReadsIndexVersion {
+		return 0, errors.New("invalid index header format")
+	}
+
+	payload := parts[0] + ":" + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(c.storageIndexHMAC(payload))) {
+		return 0, errors.New("invalid index header signature")
+	}
+
+	index, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid index in index header: %w", err)
+	}
+
+	return index, nil
+}
+
+func (c *Core) storageIndexHMAC(payload string) string {
+	mac := hmac.New(sha256.New, c.headerHMACKey())
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024 OpenBao a Series of LF Projects, LLC
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/openbao/openbao/sdk/v2/logical"
+)
+
+func TestStandbyReads_StorageIndexHeader(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	encoded := c.EncodeStorageIndex(42)
+	index, err := c.DecodeStorageIndex(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index != 42 {
+		t.Fatalf("bad index: got %d expected 42", index)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range map[string]string{
+		"not base64":    "%%%",
+		"bad format":    base64.StdEncoding.EncodeToString([]byte("v1:42")),
+		"bad version":   base64.StdEncoding.EncodeToString([]byte("v0" + string(raw[2:]))),
+		"tampered":      base64.StdEncoding.EncodeToString([]byte("v1:43" + string(raw[5:]))),
+		"bad signature": base64.StdEncoding.EncodeToString([]byte("v1:42:abcd")),
+	} {
+		if _, err := c.DecodeStorageIndex(value); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+
+	// Without integrated storage, indexes are not tracked.
+	if _, ok := c.StorageIndex(); ok {
+		t.Fatal("expected no storage index")
+	}
+}
+
+func TestStandbyReads_NeedsForward(t *testing.T) {
+	cases := map[string]struct {
+		resp     *logical.Response
+		err      error
+		expected bool
+	}{
+		"nil": {},
+		"data": {
+			resp: &logical.Response{Data: map[string]interface{}{"foo": "bar"}},
+		},
+		"auth": {
+			resp:     &logical.Response{Auth: &logical.Auth{ClientToken: "foo"}},
+			expected: true,
+		},
+		"lease": {
+			resp:     &logical.Response{Secret: &logical.Secret{LeaseID: "foo"}},
+			expected: true,
+		},
+		"read only": {
+			err:      logical.ErrReadOnly,
+			expected: true,
+		},
+		"wrapped read only": {
+			err:      errors.Join(errors.New("failed to persist"), logical.ErrReadOnly),
+			expected: true,
+		},
+		"error response": {
+			resp:     logical.ErrorResponse(logical.ErrSetupReadOnly.Error()),
+			expected: true,
+		},
+		"other error": {
+			err: logical.ErrPermissionDenied,
+		},
+	}
+
+	for name, tc := range cases {
+		if got := standbyReadNeedsForward(tc.resp, tc.err); got != tc.expected {
+			t.Fatalf("%s: got %t expected %t", name, got, tc.expected)
+		}
+	}
+}
+
+func TestStandbyReads_ForwardMountPath(t *testing.T) {
+	cases := []struct {
+		mountType string
+		path      string
+		expected  bool
+	}{
+		{"pki", "cert/ca", false},
+		{"pki", "acme/new-nonce", true},
+		{"pki", "acme/directory", true},
+		{"pki", "issuer/default/acme/new-nonce", true},
+		{"pki", "issuer/default/roles/web/acme/new-nonce", true},
+		{"pki", "roles/acme-web", false},
+		{"kv", "data/acme/new-nonce", false},
+	}
+
+	for _, tc := range cases {
+		if got := standbyReadsForwardMountPath(tc.mountType, tc.path); got != tc.expected {
+			t.Fatalf("%s %s: got %t expected %t", tc.mountType, tc.path, got, tc.expected)
+		}
+	}
+}
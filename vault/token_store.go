@@ -715,7 +715,7 @@ func (c *Core) LookupToken(ctx context.Context, token string) (*logical.TokenEnt
 		return nil, consts.ErrSealed
 	}
 
-	if c.standby {
+	if c.standby && !c.StandbyReadsActive() {
 		return nil, consts.ErrStandby
 	}
 
@@ -1265,7 +1265,11 @@ func (ts *TokenStore) GenerateSSCTokenID(innerToken string, te *logical.TokenEnt
 
 	tokenGenerationCounter := uint32(ts.GetSSCTokensGenerationCounter())
 
-	t := tokens.Token{Random: innerToken, LocalIndex: 0, IndexEpoch: tokenGenerationCounter}
+	// Record the storage index the token was persisted at, so that standbys
+	// serving reads can tell whether they know of the token yet.
+	localIndex, _ := ts.core.StorageIndex()
+
+	t := tokens.Token{Random: innerToken, LocalIndex: localIndex, IndexEpoch: tokenGenerationCounter}
 	marshalledToken, err := proto.Marshal(&t)
 	if err != nil {
 		ts.logger.Error("unable to marshal token", "error", err)
@@ -8,21 +8,41 @@ import (
 )
 
 func (ts *TokenStore) baseView(ns *namespace.Namespace) BarrierView {
-	return ts.baseBarrierView
+	if ns == nil || ns.ID == namespace.RootNamespaceID {
+		return ts.baseBarrierView
+	}
+
+	return NewBarrierView(ts.core.barrier, namespaceBarrierPrefix+ns.ID+"/"+systemBarrierPrefix+tokenSubPath)
 }
 
 func (ts *TokenStore) idView(ns *namespace.Namespace) BarrierView {
-	return ts.idBarrierView
+	if ns == nil || ns.ID == namespace.RootNamespaceID {
+		return ts.idBarrierView
+	}
+
+	return ts.baseView(ns).SubView(idPrefix)
 }
 
 func (ts *TokenStore) accessorView(ns *namespace.Namespace) BarrierView {
-	return ts.accessorBarrierView
+	if ns == nil || ns.ID == namespace.RootNamespaceID {
+		return ts.accessorBarrierView
+	}
+
+	return ts.baseView(ns).SubView(accessorPrefix)
 }
 
 func (ts *TokenStore) parentView(ns *namespace.Namespace) BarrierView {
-	return ts.parentBarrierView
+	if ns == nil || ns.ID == namespace.RootNamespaceID {
+		return ts.parentBarrierView
+	}
+
+	return ts.baseView(ns).SubView(parentPrefix)
 }
 
 func (ts *TokenStore) rolesView(ns *namespace.Namespace) BarrierView {
-	return ts.rolesBarrierView
+	if ns == nil || ns.ID == namespace.RootNamespaceID {
+		return ts.rolesBarrierView
+	}
+
+	return ts.baseView(ns).SubView(rolesPrefix)
 }
@@ -348,6 +348,9 @@ func (c *Core) validateWrappingToken(ctx context.Context, req *logical.Request)
 	}
 
 	if c.standby {
+		if c.StandbyReadsActive() {
+			return false, logical.ErrPerfStandbyPleaseForward
+		}
 		return false, consts.ErrStandby
 	}
 
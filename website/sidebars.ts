@@ -138,17 +138,26 @@ const sidebars: SidebarsConfig = {
                 "commands/debug",
                 "commands/delete",
                 {
+                    events: [
+                        "commands/events/index",
+                        "commands/events/subscribe",
+                    ],
                     kv: [
                         "commands/kv/index",
+                        "commands/kv/cp",
                         "commands/kv/delete",
                         "commands/kv/destroy",
                         "commands/kv/enable-versioning",
+                        "commands/kv/export",
                         "commands/kv/get",
+                        "commands/kv/import",
                         "commands/kv/list",
                         "commands/kv/metadata",
+                        "commands/kv/mv",
                         "commands/kv/patch",
                         "commands/kv/put",
                         "commands/kv/rollback",
+                        "commands/kv/search",
                         "commands/kv/undelete",
                     ],
                     lease: [
@@ -86,6 +86,7 @@ const sidebars: SidebarsConfig = {
         "system/config-state",
         "system/config-ui",
         "system/decode-token",
+        "system/events",
         "system/generate-recovery-token",
         "system/generate-root",
         "system/health",
@@ -109,6 +110,7 @@ const sidebars: SidebarsConfig = {
         "system/ha-status",
         "system/leader",
         "system/leases",
+        "system/managed-keys",
         "system/loggers",
         "system/metrics",
         "system/mfa-validate",
@@ -122,6 +124,7 @@ const sidebars: SidebarsConfig = {
         "system/policies-password",
         "system/pprof",
         "system/quotas-config",
+        "system/lease-count-quotas",
         "system/rate-limit-quotas",
         "system/raw",
         "system/rekey",
@@ -137,6 +140,7 @@ const sidebars: SidebarsConfig = {
             "system/storage/index",
             "system/storage/raft",
             "system/storage/raftautopilot",
+            "system/storage/raftautosnapshots",
           ],
         },
         "system/tools",